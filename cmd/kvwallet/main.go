@@ -0,0 +1,103 @@
+// Command kvwallet serves the user, transaction and balance API of the
+// service from a DynamoDB table instead of Postgres, for regions without
+// managed Postgres, with the rules of the service. See core.WithStore for
+// what it covers.
+//
+//	DYNAMODB_TABLE=wallet AWS_REGION=eu-west-1 kvwallet
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"assignment/internal/clock"
+	"assignment/internal/core"
+	"assignment/internal/fixture"
+	handlers "assignment/internal/http"
+	"assignment/internal/kvledger"
+	"assignment/internal/kvstore"
+)
+
+// shutdownTimeout bounds how long in-flight requests get to finish on SIGINT
+// or SIGTERM.
+const shutdownTimeout = 30 * time.Second
+
+func main() {
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = "us-east-1"
+	}
+	endpoint := os.Getenv("DYNAMODB_ENDPOINT")
+	if endpoint == "" {
+		endpoint = "https://dynamodb." + region + ".amazonaws.com"
+	}
+
+	clk := clock.New()
+	store, err := kvstore.NewDynamoDB(kvstore.DynamoDBConfig{
+		Endpoint:        endpoint,
+		Region:          region,
+		Table:           os.Getenv("DYNAMODB_TABLE"),
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+	}, clk)
+	if err != nil {
+		log.Fatalf("Invalid DynamoDB configuration: %v", err)
+	}
+
+	// Seed users from a fixture file instead of the default users
+	seed := fixture.Default()
+	if path := os.Getenv("SEED_FIXTURE"); path != "" {
+		if seed, err = fixture.Load(path); err != nil {
+			log.Fatalf("Invalid SEED_FIXTURE: %v", err)
+		}
+	}
+	kv := kvledger.NewStore(store)
+	if err := kv.Seed(context.Background(), seed, clk.Now()); err != nil {
+		log.Fatalf("Failed to seed users: %v", err)
+	}
+	service := core.NewTransactionService(nil, clk, core.WithStore(kv))
+	if _, err := service.ApplyFixture(seed); err != nil {
+		log.Fatalf("Failed to seed transactions: %v", err)
+	}
+
+	h := handlers.NewHandlers(service)
+	mux := handlers.NewRouter()
+	mux.HandleFunc("POST", "/user", h.HandleCreateUser)
+	mux.HandleFunc("GET", "/user/{userId}", h.HandleGetUser)
+	mux.HandleFunc("POST", "/user/{userId}/transaction", h.HandleTransaction)
+	mux.HandleFunc("GET", "/user/{userId}/balance", h.HandleGetBalance)
+	mux.HandleFunc("POST", "/balances", h.HandleGetBalances)
+	mux.HandleFunc("GET", "/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+	})
+
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+	srv := &http.Server{Addr: ":" + port, Handler: mux}
+	go func() {
+		log.Printf("Serving on :%s from DynamoDB table %s", port, os.Getenv("DYNAMODB_TABLE"))
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Server failed: %v", err)
+		}
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+
+	log.Printf("Shutting down")
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("Shutdown failed: %v", err)
+	}
+}
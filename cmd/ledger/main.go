@@ -0,0 +1,78 @@
+// Command ledger replays user balances from the transaction ledger and
+// reports balances that diverge from it.
+//
+//	ledger [-repair] [-checkpoint]
+//
+// It exits with status 1 when divergent balances were found and not
+// repaired.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"assignment/internal/clock"
+	"assignment/internal/core"
+	"assignment/internal/db"
+)
+
+func main() {
+	repair := flag.Bool("repair", false, "reset divergent balances to their replayed value")
+	checkpoint := flag.Bool("checkpoint", false, "store replayed balances as checkpoints for later replays")
+	flag.Parse()
+
+	connStr := os.Getenv("DATABASE_URL")
+	if connStr == "" {
+		connStr = "host=postgres user=postgres password=postgres dbname=assignment sslmode=disable"
+	}
+	if schema := os.Getenv("DB_SCHEMA"); schema != "" {
+		if err := db.CreateSchema(connStr, schema); err != nil {
+			log.Fatalf("Failed to create schema: %v", err)
+		}
+		dsn, err := db.SessionConfig{Schema: schema}.DSN(connStr)
+		if err != nil {
+			log.Fatalf("Invalid database settings: %v", err)
+		}
+		connStr = dsn
+	}
+
+	clk := clock.New()
+	database, err := db.NewDB(connStr, clk, nil)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close()
+
+	service := core.NewTransactionService(database.DB, clk)
+
+	replay := service.ReplayLedger
+	if *repair {
+		replay = service.RepairBalances
+	}
+	report, err := replay()
+	if err != nil {
+		log.Fatalf("Replay failed: %v", err)
+	}
+
+	fmt.Printf("Replayed %d users, %d divergent\n", report.UsersChecked, len(report.Divergences))
+	for _, d := range report.Divergences {
+		fmt.Printf("user %d: balance %s, ledger %s, difference %s\n", d.UserID, d.Balance, d.Replayed, d.Difference)
+	}
+	if *repair && len(report.Divergences) > 0 {
+		fmt.Printf("Repaired %d balances\n", len(report.Divergences))
+	}
+
+	if *checkpoint {
+		n, err := service.CreateLedgerCheckpoints()
+		if err != nil {
+			log.Fatalf("Checkpoint failed: %v", err)
+		}
+		fmt.Printf("Created %d checkpoints\n", n)
+	}
+
+	if !*repair && len(report.Divergences) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,134 @@
+// Command migrate applies or reverts schema migrations.
+//
+//	migrate [-dry-run] [up]
+//	migrate [-dry-run] down N
+//	migrate status
+//
+// up applies every pending migration; down N reverts the last N applied
+// ones, newest first. With -dry-run, the SQL that would run is printed and
+// the database is left unchanged. The application applies pending
+// migrations itself when it starts. DB_SCHEMA selects a tenant's schema,
+// which is created if missing unless -dry-run is set.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"assignment/internal/clock"
+	"assignment/internal/db"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "print the SQL that would run instead of running it")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: migrate [-dry-run] [up | down N | status]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	connStr := os.Getenv("DATABASE_URL")
+	if connStr == "" {
+		connStr = "host=postgres user=postgres password=postgres dbname=assignment sslmode=disable"
+	}
+	// A tenant's schema is migrated on its own
+	if schema := os.Getenv("DB_SCHEMA"); schema != "" {
+		if !*dryRun {
+			if err := db.CreateSchema(connStr, schema); err != nil {
+				log.Fatalf("Failed to create schema: %v", err)
+			}
+		}
+		dsn, err := db.SessionConfig{Schema: schema}.DSN(connStr)
+		if err != nil {
+			log.Fatalf("Invalid database settings: %v", err)
+		}
+		connStr = dsn
+	}
+
+	database, err := db.Open(connStr, clock.New())
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	args := flag.Args()
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+	switch {
+	case command == "up" && len(args) <= 1:
+		if *dryRun {
+			pending, err := database.PendingMigrations()
+			if err != nil {
+				log.Fatalf("Failed to list pending migrations: %v", err)
+			}
+			printMigrations(pending, true)
+			return
+		}
+		if err := database.Migrate(); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+
+	case command == "down" && len(args) == 2:
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n <= 0 {
+			log.Fatalf("Invalid number of migrations %q: must be a positive integer", args[1])
+		}
+		if *dryRun {
+			last, err := database.LastMigrations(n)
+			if err != nil {
+				log.Fatalf("Failed to list applied migrations: %v", err)
+			}
+			printMigrations(last, false)
+			return
+		}
+		if err := database.MigrateDown(n); err != nil {
+			log.Fatalf("Rollback failed: %v", err)
+		}
+
+	case command == "status" && len(args) == 1:
+		pending, err := database.PendingMigrations()
+		if err != nil {
+			log.Fatalf("Failed to list pending migrations: %v", err)
+		}
+		isPending := map[int]bool{}
+		for _, m := range pending {
+			isPending[m.Version] = true
+		}
+		for _, m := range db.Migrations() {
+			state := "applied"
+			if isPending[m.Version] {
+				state = "pending"
+			}
+			fmt.Printf("%4d %-40s %s\n", m.Version, m.Name, state)
+		}
+
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+// printMigrations prints the statements of the migrations in the order they
+// would run, as a script.
+func printMigrations(migrations []db.Migration, up bool) {
+	if len(migrations) == 0 {
+		fmt.Println("-- nothing to do")
+		return
+	}
+	for _, m := range migrations {
+		statements, direction := m.Down, "down"
+		if up {
+			statements, direction = m.Up, "up"
+		}
+		fmt.Printf("-- %d %s (%s)\n", m.Version, m.Name, direction)
+		for _, statement := range statements {
+			fmt.Printf("%s;\n\n", strings.TrimSpace(statement))
+		}
+	}
+}
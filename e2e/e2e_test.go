@@ -0,0 +1,249 @@
+//go:build e2e
+
+// Package e2e runs the release-gating scenario matrix over real HTTP against
+// the docker-compose stack (app + Postgres).
+//
+//	go test -tags e2e -v ./e2e/...
+//
+// By default the stack is built, started and torn down by the test run. Set
+// E2E_BASE_URL to run against an already running instance instead.
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var baseURL = "http://localhost:8080"
+
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+func run(m *testing.M) int {
+	if url := os.Getenv("E2E_BASE_URL"); url != "" {
+		baseURL = url
+	} else {
+		if err := compose("up", "-d", "--build"); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to start stack: %v\n", err)
+			return 1
+		}
+		defer func() {
+			if err := compose("down", "-v"); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to stop stack: %v\n", err)
+			}
+		}()
+	}
+
+	if err := waitHealthy(2 * time.Minute); err != nil {
+		fmt.Fprintf(os.Stderr, "service never became healthy: %v\n", err)
+		return 1
+	}
+
+	return m.Run()
+}
+
+func compose(args ...string) error {
+	cmd := exec.Command("docker", append([]string{"compose"}, args...)...)
+	cmd.Dir = filepath.Join("..")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func waitHealthy(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		resp, err := http.Get(baseURL + "/health")
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+			err = fmt.Errorf("status %d", resp.StatusCode)
+		}
+		if time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// uniqueID keeps scenarios independent when run against a long-lived stack.
+func uniqueID(name string) string {
+	return name + "-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+}
+
+type response struct {
+	status int
+	body   map[string]interface{}
+}
+
+func do(t *testing.T, method, path string, headers map[string]string, body interface{}) response {
+	t.Helper()
+
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("Failed to encode body: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req, err := http.NewRequest(method, baseURL+path, reader)
+	if err != nil {
+		t.Fatalf("Failed to build request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	out := response{status: resp.StatusCode}
+	json.NewDecoder(resp.Body).Decode(&out.body)
+	return out
+}
+
+func postTransaction(t *testing.T, userID, sourceType, state, amount, transactionID string) response {
+	t.Helper()
+	headers := map[string]string{}
+	if sourceType != "" {
+		headers["Source-Type"] = sourceType
+	}
+	return do(t, "POST", "/user/"+userID+"/transaction", headers, map[string]string{
+		"state":         state,
+		"amount":        amount,
+		"transactionId": transactionID,
+	})
+}
+
+func balance(t *testing.T, userID string) float64 {
+	t.Helper()
+	resp := do(t, "GET", "/user/"+userID+"/balance", nil, nil)
+	if resp.status != http.StatusOK {
+		t.Fatalf("Expected status 200 for balance, got: %d", resp.status)
+	}
+	b, err := strconv.ParseFloat(resp.body["balance"].(string), 64)
+	if err != nil {
+		t.Fatalf("Invalid balance %v: %v", resp.body["balance"], err)
+	}
+	return b
+}
+
+func expectStatus(t *testing.T, resp response, status int) {
+	t.Helper()
+	if resp.status != status {
+		t.Fatalf("Expected status %d, got: %d (%v)", status, resp.status, resp.body)
+	}
+}
+
+func expectMessage(t *testing.T, resp response, message string) {
+	t.Helper()
+	if resp.body["message"] != message {
+		t.Errorf("Expected message %q, got: %v", message, resp.body["message"])
+	}
+}
+
+func TestHealth(t *testing.T) {
+	resp, err := http.Get(baseURL + "/health")
+	if err != nil {
+		t.Fatalf("Health check failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got: %d", resp.StatusCode)
+	}
+}
+
+func TestWinAndLose(t *testing.T) {
+	before := balance(t, "1")
+
+	resp := postTransaction(t, "1", "game", "win", "10.50", uniqueID("e2e-win"))
+	expectStatus(t, resp, http.StatusOK)
+	expectMessage(t, resp, "Transaction applied successfully")
+
+	resp = postTransaction(t, "1", "server", "lose", "5.25", uniqueID("e2e-lose"))
+	expectStatus(t, resp, http.StatusOK)
+	expectMessage(t, resp, "Transaction applied successfully")
+
+	if after := balance(t, "1"); fmt.Sprintf("%.2f", after) != fmt.Sprintf("%.2f", before+5.25) {
+		t.Errorf("Expected balance %.2f, got: %.2f", before+5.25, after)
+	}
+}
+
+func TestDuplicateTransaction(t *testing.T) {
+	id := uniqueID("e2e-dup")
+
+	first := postTransaction(t, "2", "payment", "win", "1.00", id)
+	expectStatus(t, first, http.StatusOK)
+	before := balance(t, "2")
+
+	second := postTransaction(t, "2", "payment", "win", "1.00", id)
+	expectStatus(t, second, http.StatusOK)
+	expectMessage(t, second, "Duplicate transaction ignored")
+
+	if after := balance(t, "2"); after != before {
+		t.Errorf("Duplicate changed balance from %.2f to %.2f", before, after)
+	}
+}
+
+func TestInsufficientFunds(t *testing.T) {
+	before := balance(t, "3")
+
+	resp := postTransaction(t, "3", "game", "lose", fmt.Sprintf("%.2f", before+1), uniqueID("e2e-insufficient"))
+	expectStatus(t, resp, http.StatusOK)
+	expectMessage(t, resp, "Insufficient funds")
+
+	if after := balance(t, "3"); after != before {
+		t.Errorf("Rejected debit changed balance from %.2f to %.2f", before, after)
+	}
+}
+
+func TestInvalidRequests(t *testing.T) {
+	tests := []struct {
+		name       string
+		userID     string
+		sourceType string
+		state      string
+		amount     string
+	}{
+		{"missing source type", "1", "", "win", "1.00"},
+		{"invalid source type", "1", "casino", "win", "1.00"},
+		{"invalid state", "1", "game", "draw", "1.00"},
+		{"invalid amount", "1", "game", "win", "1.001"},
+		{"negative amount", "1", "game", "win", "-1.00"},
+		{"invalid user id", "abc", "game", "win", "1.00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := postTransaction(t, tt.userID, tt.sourceType, tt.state, tt.amount, uniqueID("e2e-invalid"))
+			expectStatus(t, resp, http.StatusBadRequest)
+			if resp.body["error"] == nil {
+				t.Errorf("Expected error body, got: %v", resp.body)
+			}
+		})
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	expectStatus(t, do(t, "GET", "/user/999999/balance", nil, nil), http.StatusNotFound)
+	expectStatus(t, do(t, "GET", "/does-not-exist", nil, nil), http.StatusNotFound)
+}
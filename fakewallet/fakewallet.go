@@ -0,0 +1,417 @@
+// Package fakewallet is an in-memory stand-in for the wallet service, for
+// services integrating against it to test without Postgres or the real
+// binary. It serves the user, transaction and balance routes with the same
+// paths, validation and JSON as the service:
+//
+//	srv := httptest.NewServer(fakewallet.New(nil))
+//	defer srv.Close()
+//
+// Users 1 to 3 are seeded like the service seeds them. State lives as long
+// as the Wallet; other routes of the service answer 404 Not Found.
+package fakewallet
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"assignment/internal/clock"
+	"assignment/internal/core"
+	"assignment/internal/fixture"
+	handlers "assignment/internal/http"
+	"assignment/internal/models"
+	"assignment/internal/money"
+	"assignment/internal/ulid"
+	"assignment/internal/utils"
+	"assignment/internal/validation"
+)
+
+// Wallet is a fake wallet service. It is an http.Handler and safe for
+// concurrent use.
+type Wallet struct {
+	clock clock.Clock
+	mux   *handlers.Router
+
+	mu     sync.Mutex
+	users  map[int64]*models.User
+	nextID int64
+	// transactions are the applied transactions by transaction ID, with
+	// the responses replayed to retries
+	transactions map[string]appliedTransaction
+}
+
+type appliedTransaction struct {
+	transaction models.Transaction
+	response    models.TransactionResponse
+}
+
+// New returns a fake seeded with the service's default users. Timestamps
+// are read from clk, or the system time if it is nil.
+func New(clk clock.Clock) *Wallet {
+	if clk == nil {
+		clk = clock.New()
+	}
+	w := &Wallet{
+		clock:        clk,
+		users:        map[int64]*models.User{},
+		transactions: map[string]appliedTransaction{},
+	}
+
+	now := clk.Now()
+	for _, u := range fixture.Default().Users {
+		balance, _ := utils.ParseAmount(string(u.Balance))
+		w.users[u.ID] = &models.User{
+			ID:        u.ID,
+			Balance:   money.Format(balance, core.DefaultCurrency),
+			Currency:  core.DefaultCurrency,
+			Status:    core.UserActive,
+			KYCLevel:  core.KYCFull,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		if u.ID > w.nextID {
+			w.nextID = u.ID
+		}
+	}
+
+	w.mux = handlers.NewRouter()
+	w.mux.HandleFunc("POST", "/user", w.handleCreateUser)
+	w.mux.HandleFunc("GET", "/user/{userId}", w.handleGetUser)
+	w.mux.HandleFunc("PATCH", "/user/{userId}", w.handleUpdateUser)
+	w.mux.HandleFunc("DELETE", "/user/{userId}", w.handleDeleteUser)
+	w.mux.HandleFunc("POST", "/user/{userId}/transaction", w.handleTransaction)
+	w.mux.HandleFunc("GET", "/user/{userId}/balance", w.handleGetBalance)
+	w.mux.HandleFunc("POST", "/balances", w.handleGetBalances)
+	w.mux.HandleFunc("GET", "/health", func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+		rw.Write([]byte("OK"))
+	})
+	return w
+}
+
+func (w *Wallet) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	w.mux.ServeHTTP(rw, r)
+}
+
+// Transactions returns the applied transactions of a user, oldest first,
+// for tests to assert on what was booked.
+func (w *Wallet) Transactions(userID int64) []models.Transaction {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var transactions []models.Transaction
+	for _, applied := range w.transactions {
+		if applied.transaction.UserID == userID {
+			transactions = append(transactions, applied.transaction)
+		}
+	}
+	sort.Slice(transactions, func(i, j int) bool { return transactions[i].ID < transactions[j].ID })
+	return transactions
+}
+
+func (w *Wallet) createUser(req models.CreateUserRequest) (*models.User, error) {
+	if req.Balance == "" {
+		req.Balance = "0"
+	}
+	if req.Currency == "" {
+		req.Currency = core.DefaultCurrency
+	}
+	if err := validateProfile(req.Currency, req.ExternalRef, req.DisplayName, req.Country); err != nil {
+		return nil, err
+	}
+	if err := money.ValidateAmount(req.Balance, req.Currency); err != nil {
+		return nil, err
+	}
+	balance, err := utils.ParseAmount(req.Balance)
+	if err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.externalRefInUse(req.ExternalRef, 0) {
+		return nil, core.ErrExternalRefInUse
+	}
+	now := w.clock.Now()
+	w.nextID++
+	user := &models.User{
+		ID:          w.nextID,
+		Balance:     money.Format(balance, req.Currency),
+		Currency:    req.Currency,
+		Status:      core.UserActive,
+		KYCLevel:    core.KYCUnverified,
+		ExternalRef: req.ExternalRef,
+		DisplayName: req.DisplayName,
+		Country:     req.Country,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	w.users[user.ID] = user
+	copied := *user
+	return &copied, nil
+}
+
+func (w *Wallet) getUser(userID int64) (*models.User, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	user, err := w.user(userID)
+	if err != nil {
+		return nil, err
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (w *Wallet) updateUser(userID int64, req models.UpdateUserRequest) (*models.User, error) {
+	if req.Currency != nil {
+		if err := utils.ValidateCurrency(*req.Currency); err != nil {
+			return nil, err
+		}
+	}
+	if err := validateProfile("", stringOr(req.ExternalRef), stringOr(req.DisplayName), stringOr(req.Country)); err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	user, err := w.user(userID)
+	if err != nil {
+		return nil, err
+	}
+	if req.ExternalRef != nil && w.externalRefInUse(*req.ExternalRef, userID) {
+		return nil, core.ErrExternalRefInUse
+	}
+	set := func(field *string, value *string) {
+		if value != nil {
+			*field = *value
+		}
+	}
+	set(&user.Currency, req.Currency)
+	set(&user.ExternalRef, req.ExternalRef)
+	set(&user.DisplayName, req.DisplayName)
+	set(&user.Country, req.Country)
+	user.UpdatedAt = w.clock.Now()
+	copied := *user
+	return &copied, nil
+}
+
+func (w *Wallet) deleteUser(userID int64) (*models.User, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	user, err := w.user(userID)
+	if err != nil {
+		return nil, err
+	}
+	now := w.clock.Now()
+	user.DeletedAt, user.UpdatedAt = &now, now
+	copied := *user
+	return &copied, nil
+}
+
+func (w *Wallet) getBalance(userID int64) (*models.BalanceResponse, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	user, err := w.user(userID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.BalanceResponse{UserID: userID, Balance: user.Balance}, nil
+}
+
+func (w *Wallet) getBalances(userIDs []int64) (*models.BulkBalanceResponse, error) {
+	if len(userIDs) == 0 {
+		return nil, validation.Errorf("userIds", "invalid user IDs: at least one is required")
+	}
+	if len(userIDs) > core.MaxBulkBalances {
+		return nil, validation.Errorf("userIds", "invalid user IDs: at most %d per request", core.MaxBulkBalances)
+	}
+	for _, id := range userIDs {
+		if id <= 0 {
+			return nil, validation.Errorf("userId", "invalid user ID: must be a positive integer")
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	resp := &models.BulkBalanceResponse{Balances: []models.BalanceResponse{}, NotFound: []int64{}}
+	seen := make(map[int64]bool, len(userIDs))
+	for _, id := range userIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if user, err := w.user(id); err == nil {
+			resp.Balances = append(resp.Balances, models.BalanceResponse{UserID: id, Balance: user.Balance})
+		} else {
+			resp.NotFound = append(resp.NotFound, id)
+		}
+	}
+	return resp, nil
+}
+
+// processTransaction applies a transaction like the service: retries of an
+// applied transaction ID get the response given then, and transactions over
+// the user's KYC limits or its balance are rejected with 200 and the
+// service's message, leaving the balance as is.
+func (w *Wallet) processTransaction(userID int64, req models.TransactionRequest, sourceType string) (*models.TransactionResponse, error) {
+	if err := utils.ValidateSourceType(sourceType); err != nil {
+		return nil, err
+	}
+	if err := utils.ValidateState(req.State); err != nil {
+		return nil, err
+	}
+	if err := utils.ValidateAmount(req.Amount); err != nil {
+		return nil, err
+	}
+	amount, err := utils.ParseAmount(req.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := w.clock.Now()
+	if req.TransactionID == "" {
+		if req.TransactionID, err = ulid.New(now); err != nil {
+			return nil, err
+		}
+	}
+	if applied, ok := w.transactions[req.TransactionID]; ok {
+		response := applied.response
+		response.Replayed = true
+		return &response, nil
+	}
+
+	user, err := w.user(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := money.ValidateAmount(req.Amount, user.Currency); err != nil {
+		return nil, err
+	}
+	balance, err := utils.ParseBalance(user.Balance)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse current balance: %w", err)
+	}
+	newBalance := balance + amount
+	if req.State == "lose" {
+		newBalance = balance - amount
+	}
+
+	response := &models.TransactionResponse{
+		UserID:        userID,
+		TransactionID: req.TransactionID,
+		Balance:       user.Balance,
+	}
+	if message := w.kycRejection(user, amount, now); message != "" {
+		response.Message = message
+		return response, nil
+	}
+	if newBalance < 0 {
+		response.Message = "Insufficient funds"
+		return response, nil
+	}
+
+	user.Balance = money.Format(newBalance, user.Currency)
+	user.UpdatedAt = now
+	response.Balance, response.Message = user.Balance, "Transaction applied successfully"
+	w.transactions[req.TransactionID] = appliedTransaction{
+		transaction: models.Transaction{
+			ID:            int64(len(w.transactions) + 1),
+			UserID:        userID,
+			TransactionID: req.TransactionID,
+			State:         req.State,
+			Amount:        money.Format(amount, user.Currency),
+			SourceType:    sourceType,
+			Applied:       true,
+			CreatedAt:     now,
+		},
+		response: *response,
+	}
+	return response, nil
+}
+
+// kycRejection returns the message of the KYC limit a transaction of amount
+// would exceed, or "" if it is within them. w.mu is held.
+func (w *Wallet) kycRejection(user *models.User, amount float64, now time.Time) string {
+	limit := core.KYCLimits[user.KYCLevel]
+	if limit.MaxSingle > 0 && money.ToMinor(amount, user.Currency) > money.ToMinor(limit.MaxSingle, user.Currency) {
+		return "KYC single transaction limit exceeded"
+	}
+	if limit.MaxDaily == 0 {
+		return ""
+	}
+
+	day := now.UTC().Truncate(24 * time.Hour)
+	var spent int64
+	for _, applied := range w.transactions {
+		t := applied.transaction
+		if t.UserID == user.ID && !t.CreatedAt.Before(day) && t.CreatedAt.Before(day.Add(24*time.Hour)) {
+			a, _ := utils.ParseAmount(t.Amount)
+			spent += money.ToMinor(a, user.Currency)
+		}
+	}
+	if spent+money.ToMinor(amount, user.Currency) > money.ToMinor(limit.MaxDaily, user.Currency) {
+		return "KYC daily limit exceeded"
+	}
+	return ""
+}
+
+// user returns a user that is not deleted. w.mu is held.
+func (w *Wallet) user(userID int64) (*models.User, error) {
+	user, ok := w.users[userID]
+	if !ok || user.DeletedAt != nil {
+		return nil, core.ErrUserNotFound
+	}
+	return user, nil
+}
+
+// externalRefInUse reports whether a user other than userID has ref. w.mu
+// is held.
+func (w *Wallet) externalRefInUse(ref string, userID int64) bool {
+	if ref == "" {
+		return false
+	}
+	for _, user := range w.users {
+		if user.ID != userID && user.ExternalRef == ref {
+			return true
+		}
+	}
+	return false
+}
+
+func validateProfile(currency, externalRef, displayName, country string) error {
+	if currency != "" {
+		if err := utils.ValidateCurrency(currency); err != nil {
+			return err
+		}
+	}
+	if err := utils.ValidateExternalRef(externalRef); err != nil {
+		return err
+	}
+	if err := utils.ValidateDisplayName(displayName); err != nil {
+		return err
+	}
+	if country != "" {
+		if err := utils.ValidateCountry(country); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func stringOr(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
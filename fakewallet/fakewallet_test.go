@@ -0,0 +1,101 @@
+package fakewallet
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"assignment/internal/clock"
+	"assignment/internal/models"
+)
+
+func do(t *testing.T, w *Wallet, method, path, body string, header http.Header) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	rec := httptest.NewRecorder()
+	w.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestTransactions(t *testing.T) {
+	w := New(clock.NewFake(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)))
+	game := http.Header{"Source-Type": {"game"}}
+
+	rec := do(t, w, "POST", "/user/1/transaction", `{"state":"lose","amount":"30","transactionId":"tx-1"}`, game)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d %s", rec.Code, rec.Body)
+	}
+	var resp models.TransactionResponse
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.Balance != "70.00" || resp.Message != "Transaction applied successfully" {
+		t.Errorf("Expected balance 70.00 applied, got: %+v", resp)
+	}
+
+	// Retries replay the response given then
+	rec = do(t, w, "POST", "/user/1/transaction", `{"state":"lose","amount":"30","transactionId":"tx-1"}`, game)
+	if rec.Header().Get("Idempotent-Replayed") != "true" || !strings.Contains(rec.Body.String(), `"balance":"70.00"`) {
+		t.Errorf("Expected a replayed response, got: %v %s", rec.Header(), rec.Body)
+	}
+
+	rec = do(t, w, "POST", "/user/2/transaction", `{"state":"lose","amount":"50.01","transactionId":"tx-2"}`, game)
+	if !strings.Contains(rec.Body.String(), `"message":"Insufficient funds"`) {
+		t.Errorf("Expected insufficient funds, got: %s", rec.Body)
+	}
+
+	rec = do(t, w, "POST", "/user/1/transaction", `{"state":"draw","amount":"1","transactionId":"tx-3"}`, game)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got: %d %s", rec.Code, rec.Body)
+	}
+	rec = do(t, w, "POST", "/user/1/transaction", `{"state":"win","amount":"1","transactionId":"tx-4"}`, nil)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "Source-Type header is required") {
+		t.Errorf("Expected a missing Source-Type, got: %d %s", rec.Code, rec.Body)
+	}
+
+	if got := w.Transactions(1); len(got) != 1 || got[0].Amount != "30.00" || got[0].State != "lose" {
+		t.Errorf("Expected one applied transaction, got: %+v", got)
+	}
+}
+
+func TestUsers(t *testing.T) {
+	w := New(nil)
+
+	rec := do(t, w, "POST", "/user", `{"balance":"10","external_ref":"crm-1"}`, nil)
+	var user models.User
+	json.NewDecoder(rec.Body).Decode(&user)
+	if rec.Code != http.StatusOK || user.ID != 4 || user.Balance != "10.00" || user.KYCLevel != "unverified" {
+		t.Fatalf("Expected user 4, got: %d %+v", rec.Code, user)
+	}
+	if rec := do(t, w, "POST", "/user", `{"external_ref":"crm-1"}`, nil); rec.Code != http.StatusConflict {
+		t.Errorf("Expected status 409, got: %d", rec.Code)
+	}
+
+	// Unverified users are held to their KYC limits
+	rec = do(t, w, "POST", "/user/4/transaction", `{"state":"win","amount":"100.01"}`, http.Header{"Source-Type": {"payment"}})
+	if !strings.Contains(rec.Body.String(), "KYC single transaction limit exceeded") {
+		t.Errorf("Expected the KYC limit, got: %s", rec.Body)
+	}
+
+	if rec := do(t, w, "DELETE", "/user/4", "", nil); rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got: %d", rec.Code)
+	}
+	rec = do(t, w, "GET", "/user/4/balance", "", nil)
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), `"error":"user not found"`) {
+		t.Errorf("Expected user not found, got: %d %s", rec.Code, rec.Body)
+	}
+
+	rec = do(t, w, "POST", "/balances", `{"userIds":[1,4]}`, nil)
+	if got := rec.Body.String(); !strings.Contains(got, `"balances":[{"userId":1,"balance":"100.00"}],"notFound":[4]`) {
+		t.Errorf("Expected balance of user 1 only, got: %s", got)
+	}
+
+	if rec := do(t, w, "PUT", "/user/1", "", nil); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got: %d", rec.Code)
+	}
+}
@@ -0,0 +1,186 @@
+package fakewallet
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"assignment/internal/core"
+	"assignment/internal/models"
+	"assignment/internal/schema"
+	"assignment/internal/utils"
+)
+
+// The handlers answer like the service's handlers in internal/http, minus
+// the headers and fields its middlewares add, e.g. X-Request-ID.
+
+func (w *Wallet) handleCreateUser(rw http.ResponseWriter, r *http.Request) {
+	var req models.CreateUserRequest
+	if err := decodeBody(r, "create_user", &req); err != nil {
+		respondError(rw, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	user, err := w.createUser(req)
+	if err != nil {
+		respondUserError(rw, err)
+		return
+	}
+	respondJSON(rw, user)
+}
+
+func (w *Wallet) handleGetUser(rw http.ResponseWriter, r *http.Request) {
+	userID, ok := pathUserID(rw, r)
+	if !ok {
+		return
+	}
+	user, err := w.getUser(userID)
+	if err != nil {
+		respondUserError(rw, err)
+		return
+	}
+	respondJSON(rw, user)
+}
+
+func (w *Wallet) handleUpdateUser(rw http.ResponseWriter, r *http.Request) {
+	userID, ok := pathUserID(rw, r)
+	if !ok {
+		return
+	}
+	var req models.UpdateUserRequest
+	if err := decodeBody(r, "update_user", &req); err != nil {
+		respondError(rw, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	user, err := w.updateUser(userID, req)
+	if err != nil {
+		respondUserError(rw, err)
+		return
+	}
+	respondJSON(rw, user)
+}
+
+func (w *Wallet) handleDeleteUser(rw http.ResponseWriter, r *http.Request) {
+	userID, ok := pathUserID(rw, r)
+	if !ok {
+		return
+	}
+	user, err := w.deleteUser(userID)
+	if err != nil {
+		respondUserError(rw, err)
+		return
+	}
+	respondJSON(rw, user)
+}
+
+func (w *Wallet) handleTransaction(rw http.ResponseWriter, r *http.Request) {
+	userID, ok := pathUserID(rw, r)
+	if !ok {
+		return
+	}
+	sourceType := r.Header.Get("Source-Type")
+	if sourceType == "" {
+		respondError(rw, http.StatusBadRequest, "Source-Type header is required")
+		return
+	}
+	var req models.TransactionRequest
+	if err := decodeBody(r, "transaction", &req); err != nil {
+		respondError(rw, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if err := core.ValidateTransactionID(req.TransactionID); err != nil {
+		respondError(rw, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response, err := w.processTransaction(userID, req, sourceType)
+	if err != nil {
+		if errors.Is(err, core.ErrValidation) {
+			respondError(rw, http.StatusBadRequest, err.Error())
+			return
+		}
+		// Unknown users are an internal error to the service as well
+		respondError(rw, http.StatusInternalServerError, "Internal server error: "+err.Error())
+		return
+	}
+	if response.Replayed {
+		rw.Header().Set("Idempotent-Replayed", "true")
+	}
+	respondJSON(rw, response)
+}
+
+func (w *Wallet) handleGetBalance(rw http.ResponseWriter, r *http.Request) {
+	userID, ok := pathUserID(rw, r)
+	if !ok {
+		return
+	}
+	response, err := w.getBalance(userID)
+	if err != nil {
+		respondUserError(rw, err)
+		return
+	}
+	rw.Header().Set("ETag", core.BalanceTag(response.UserID, response.Balance))
+	respondJSON(rw, response)
+}
+
+func (w *Wallet) handleGetBalances(rw http.ResponseWriter, r *http.Request) {
+	var req models.BulkBalanceRequest
+	if err := decodeBody(r, "bulk_balances", &req); err != nil {
+		respondError(rw, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	response, err := w.getBalances(req.UserIDs)
+	if err != nil {
+		respondError(rw, http.StatusBadRequest, err.Error())
+		return
+	}
+	respondJSON(rw, response)
+}
+
+// pathUserID reads the user ID from /user/{userId}/..., answering 400 Bad
+// Request if it is invalid.
+func pathUserID(rw http.ResponseWriter, r *http.Request) (int64, bool) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	userID, err := utils.ValidateUserID(parts[1])
+	if err != nil {
+		respondError(rw, http.StatusBadRequest, err.Error())
+		return 0, false
+	}
+	return userID, true
+}
+
+// decodeBody validates a request body against the service's schema of the
+// given name and decodes it into v.
+func decodeBody(r *http.Request, schemaName string, v interface{}) error {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	if err := schema.Validate(schemaName, body); err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+func respondUserError(rw http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, core.ErrUserNotFound):
+		respondError(rw, http.StatusNotFound, err.Error())
+	case errors.Is(err, core.ErrExternalRefInUse):
+		respondError(rw, http.StatusConflict, err.Error())
+	default:
+		respondError(rw, http.StatusBadRequest, err.Error())
+	}
+}
+
+func respondJSON(rw http.ResponseWriter, data interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(rw).Encode(data)
+}
+
+func respondError(rw http.ResponseWriter, statusCode int, message string) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(statusCode)
+	json.NewEncoder(rw).Encode(map[string]interface{}{"error": message, "retryable": false})
+}
@@ -0,0 +1,164 @@
+// Package anomaly watches transaction outcomes over a sliding window and
+// alerts ops, e.g. in a Slack channel, when error, insufficient funds or
+// duplicate rates exceed their thresholds.
+package anomaly
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"assignment/internal/clock"
+	"assignment/internal/core"
+)
+
+// numBuckets is the number of buckets the window is split into; outcomes
+// leave the window one bucket at a time.
+const numBuckets = 60
+
+// Sender delivers an alert payload, e.g. a webhook.Client posting to a Slack
+// incoming webhook.
+type Sender interface {
+	Send(ctx context.Context, payload interface{}) error
+}
+
+// Config sets the window (default 5m) and the rate thresholds, as fractions
+// between 0 and 1. A zero threshold disables its condition.
+type Config struct {
+	Window                time.Duration
+	MinSamples            int
+	ErrorRate             float64
+	InsufficientFundsRate float64
+	DuplicateRate         float64
+}
+
+// condition is a rate of one outcome among all transactions.
+type condition struct {
+	name      string
+	outcome   string
+	threshold float64
+}
+
+type bucket struct {
+	start  time.Time
+	counts map[string]int
+}
+
+type Detector struct {
+	cfg        Config
+	clock      clock.Clock
+	sender     Sender
+	conditions []condition
+
+	mu      sync.Mutex
+	buckets [numBuckets]bucket
+	// firing holds the conditions alerted and not yet resolved
+	firing map[string]bool
+}
+
+func NewDetector(cfg Config, clk clock.Clock, sender Sender) *Detector {
+	if cfg.Window <= 0 {
+		cfg.Window = 5 * time.Minute
+	}
+	d := &Detector{cfg: cfg, clock: clk, sender: sender, firing: map[string]bool{}}
+	for _, c := range []condition{
+		{name: "error rate", outcome: core.OutcomeError, threshold: cfg.ErrorRate},
+		{name: "insufficient funds rate", outcome: core.OutcomeInsufficientFunds, threshold: cfg.InsufficientFundsRate},
+		{name: "duplicate rate", outcome: core.OutcomeDuplicate, threshold: cfg.DuplicateRate},
+	} {
+		if c.threshold > 0 {
+			d.conditions = append(d.conditions, c)
+		}
+	}
+	return d
+}
+
+// Observe counts a transaction outcome. It is meant to be passed to
+// core.TransactionService.ObserveOutcomes.
+func (d *Detector) Observe(outcome string) {
+	now := d.clock.Now()
+	width := d.cfg.Window / numBuckets
+	start := now.Truncate(width)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	b := &d.buckets[(start.UnixNano()/int64(width))%numBuckets]
+	if !b.start.Equal(start) {
+		*b = bucket{start: start, counts: map[string]int{}}
+	}
+	b.counts[outcome]++
+}
+
+// counts returns the number of each outcome in the window ending now.
+func (d *Detector) counts(now time.Time) map[string]int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	total := map[string]int{}
+	for _, b := range d.buckets {
+		if b.counts == nil || !b.start.After(now.Add(-d.cfg.Window)) {
+			continue
+		}
+		for outcome, n := range b.counts {
+			total[outcome] += n
+		}
+	}
+	return total
+}
+
+// Run blocks until ctx is cancelled, checking the conditions every interval.
+func (d *Detector) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.Check(ctx)
+		}
+	}
+}
+
+// Check evaluates the conditions over the window and sends an alert for
+// every condition that started or stopped exceeding its threshold. Windows
+// with fewer than MinSamples transactions are not evaluated. A condition
+// whose alert fails to send is retried at the next check.
+func (d *Detector) Check(ctx context.Context) {
+	counts := d.counts(d.clock.Now())
+	total := 0
+	for outcome, n := range counts {
+		// Client errors say nothing about the health of the service
+		if outcome != core.OutcomeInvalid {
+			total += n
+		}
+	}
+	if total == 0 || total < d.cfg.MinSamples {
+		return
+	}
+
+	for _, c := range d.conditions {
+		rate := float64(counts[c.outcome]) / float64(total)
+		breached := rate > c.threshold
+		if breached == d.firing[c.name] {
+			continue
+		}
+
+		state := "ALERT"
+		if !breached {
+			state = "RESOLVED"
+		}
+		text := fmt.Sprintf("%s: %s %.1f%% over the last %s (%d of %d transactions), threshold %.1f%%",
+			state, c.name, rate*100, d.cfg.Window, counts[c.outcome], total, c.threshold*100)
+		if err := d.sender.Send(ctx, map[string]string{"text": text}); err != nil {
+			log.Printf("Failed to send anomaly alert: %v", err)
+			continue
+		}
+		log.Printf("Anomaly alert sent: %s", text)
+		d.firing[c.name] = breached
+	}
+}
@@ -0,0 +1,63 @@
+package anomaly
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"assignment/internal/clock"
+	"assignment/internal/core"
+)
+
+type fakeSender struct {
+	texts []string
+}
+
+func (f *fakeSender) Send(ctx context.Context, payload interface{}) error {
+	f.texts = append(f.texts, payload.(map[string]string)["text"])
+	return nil
+}
+
+func observe(d *Detector, outcome string, n int) {
+	for i := 0; i < n; i++ {
+		d.Observe(outcome)
+	}
+}
+
+func TestDetector(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	sender := &fakeSender{}
+	d := NewDetector(Config{Window: time.Minute, MinSamples: 10, InsufficientFundsRate: 0.2}, clk, sender)
+
+	// Too few samples to judge
+	observe(d, core.OutcomeInsufficientFunds, 5)
+	d.Check(context.Background())
+	if len(sender.texts) != 0 {
+		t.Fatalf("Expected no alert below the minimum sample size, got: %v", sender.texts)
+	}
+
+	// Invalid requests do not count towards the sample
+	observe(d, core.OutcomeInvalid, 50)
+	observe(d, core.OutcomeApplied, 5)
+	d.Check(context.Background())
+	if len(sender.texts) != 1 || !strings.HasPrefix(sender.texts[0], "ALERT: insufficient funds rate 50.0%") {
+		t.Fatalf("Expected one alert, got: %v", sender.texts)
+	}
+
+	// Still breached: no repeated alert
+	d.Check(context.Background())
+	if len(sender.texts) != 1 {
+		t.Fatalf("Expected no repeated alert, got: %v", sender.texts)
+	}
+
+	// The rejections leave the window
+	clk.Advance(45 * time.Second)
+	observe(d, core.OutcomeApplied, 5)
+	clk.Advance(30 * time.Second)
+	observe(d, core.OutcomeApplied, 5)
+	d.Check(context.Background())
+	if len(sender.texts) != 2 || !strings.HasPrefix(sender.texts[1], "RESOLVED: insufficient funds rate 0.0%") {
+		t.Fatalf("Expected resolution, got: %v", sender.texts)
+	}
+}
@@ -0,0 +1,66 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"assignment/internal/clock"
+)
+
+func TestLoggerWritesEventsToFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	sink, err := NewFileSink(path)
+	if err != nil {
+		t.Fatalf("Failed to open sink: %v", err)
+	}
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	logger := NewLogger(sink, clock.NewFake(now))
+
+	logger.Record(Event{Type: AuthFailure, Outcome: "failure", Method: "GET", Path: "/user/1/balance", Status: 401, SourceIP: "10.0.0.1", Reason: "invalid token"})
+	logger.Record(Event{Type: AdminAction, Outcome: "success", Method: "POST", Path: "/admin/users/1/status", Status: 200, UserID: 1, Request: json.RawMessage(`{"status":"frozen"}`)})
+	logger.Drain(context.Background())
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read audit file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 events, got: %q", data)
+	}
+	want := `{"schema":"wallet.security.v1","time":"2024-03-01T12:00:00Z","type":"auth_failure","severity":"warning","outcome":"failure","method":"GET","path":"/user/1/balance","route":"","status":401,"source_ip":"10.0.0.1","reason":"invalid token"}`
+	if lines[0] != want {
+		t.Errorf("Unexpected event:\n got: %s\nwant: %s", lines[0], want)
+	}
+
+	var event Event
+	if err := json.Unmarshal([]byte(lines[1]), &event); err != nil {
+		t.Fatalf("Failed to decode event: %v", err)
+	}
+	if event.Severity != "info" || event.UserID != 1 || string(event.Request) != `{"status":"frozen"}` {
+		t.Errorf("Unexpected admin event: %s", lines[1])
+	}
+}
+
+type failingSink struct{}
+
+func (failingSink) Write(ctx context.Context, line []byte) error {
+	return os.ErrClosed
+}
+
+func TestLoggerDropsEventsWhenQueueIsFull(t *testing.T) {
+	logger := NewLogger(failingSink{}, clock.New())
+	for i := 0; i < queueSize+10; i++ {
+		logger.Record(Event{Type: AccessDenied})
+	}
+	if len(logger.events) != queueSize {
+		t.Errorf("Expected %d queued events, got %d", queueSize, len(logger.events))
+	}
+	// Write errors are logged, not fatal
+	logger.Drain(context.Background())
+}
@@ -0,0 +1,66 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/syslog"
+	"os"
+
+	"assignment/internal/webhook"
+)
+
+// FileSink appends events to a file as JSON lines, for a log shipper to
+// forward.
+type FileSink struct {
+	file *os.File
+}
+
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit file: %w", err)
+	}
+	return &FileSink{file: file}, nil
+}
+
+func (s *FileSink) Write(ctx context.Context, line []byte) error {
+	_, err := s.file.Write(append(line, '\n'))
+	return err
+}
+
+// SyslogSink sends events to syslog with the auth facility.
+type SyslogSink struct {
+	writer *syslog.Writer
+}
+
+// NewSyslogSink connects to the syslog server at addr over network ("udp"
+// or "tcp"), or to the local syslog daemon if addr is empty.
+func NewSyslogSink(network, addr string) (*SyslogSink, error) {
+	if addr == "" {
+		network = ""
+	}
+	writer, err := syslog.Dial(network, addr, syslog.LOG_AUTH|syslog.LOG_NOTICE, "wallet-audit")
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to syslog: %w", err)
+	}
+	return &SyslogSink{writer: writer}, nil
+}
+
+func (s *SyslogSink) Write(ctx context.Context, line []byte) error {
+	_, err := s.writer.Write(line)
+	return err
+}
+
+// HTTPSink posts each event to a collector, with the client's retries.
+type HTTPSink struct {
+	client *webhook.Client
+}
+
+func NewHTTPSink(client *webhook.Client) *HTTPSink {
+	return &HTTPSink{client: client}
+}
+
+func (s *HTTPSink) Write(ctx context.Context, line []byte) error {
+	return s.client.Send(ctx, json.RawMessage(line))
+}
@@ -0,0 +1,189 @@
+// Package cdc streams committed changes to the users and transactions tables
+// to downstream consumers. Changes are read from a Postgres logical
+// replication slot (pgoutput plugin) after they commit, so the write path
+// is not slowed down, and are published as Debezium-style change events.
+//
+// Delivery is at least once: the slot only advances after a batch has been
+// published. The server must run with wal_level=logical.
+package cdc
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"regexp"
+	"time"
+)
+
+// Tables whose changes are published.
+var publishedTables = []string{"users", "transactions"}
+
+var identifierRegex = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// Event is a change event in the Debezium envelope format (payload only,
+// with NUMERIC values as strings).
+type Event struct {
+	Before map[string]interface{} `json:"before"`
+	After  map[string]interface{} `json:"after"`
+	Source Source                 `json:"source"`
+	// Op is "c" (insert), "u" (update) or "d" (delete).
+	Op   string `json:"op"`
+	TsMs int64  `json:"ts_ms"`
+}
+
+type Source struct {
+	Connector string `json:"connector"`
+	DB        string `json:"db"`
+	Schema    string `json:"schema"`
+	Table     string `json:"table"`
+	TxID      uint32 `json:"txId"`
+	LSN       uint64 `json:"lsn"`
+	TsMs      int64  `json:"ts_ms"`
+}
+
+// Publisher delivers a batch of events, e.g. *webhook.Client.
+type Publisher interface {
+	Send(ctx context.Context, payload interface{}) error
+}
+
+type Config struct {
+	Slot        string
+	Publication string
+	// BatchSize bounds the number of changes read per poll.
+	BatchSize int
+}
+
+type Streamer struct {
+	db        *sql.DB
+	cfg       Config
+	publisher Publisher
+	dbName    string
+}
+
+// NewStreamer creates the publication and replication slot if they do not
+// exist yet.
+func NewStreamer(db *sql.DB, cfg Config, publisher Publisher) (*Streamer, error) {
+	if !identifierRegex.MatchString(cfg.Slot) || !identifierRegex.MatchString(cfg.Publication) {
+		return nil, fmt.Errorf("invalid CDC slot %q or publication %q", cfg.Slot, cfg.Publication)
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 1000
+	}
+
+	s := &Streamer{db: db, cfg: cfg, publisher: publisher}
+	if err := db.QueryRow(`SELECT current_database()`).Scan(&s.dbName); err != nil {
+		return nil, fmt.Errorf("failed to get database name: %w", err)
+	}
+
+	var exists bool
+	err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM pg_publication WHERE pubname = $1)`, cfg.Publication).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up publication: %w", err)
+	}
+	if !exists {
+		query := fmt.Sprintf("CREATE PUBLICATION %s FOR TABLE", cfg.Publication)
+		for i, table := range publishedTables {
+			if i > 0 {
+				query += ","
+			}
+			query += " " + table
+		}
+		if _, err := db.Exec(query); err != nil {
+			return nil, fmt.Errorf("failed to create publication: %w", err)
+		}
+	}
+
+	err = db.QueryRow(`SELECT EXISTS (SELECT 1 FROM pg_replication_slots WHERE slot_name = $1)`, cfg.Slot).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up replication slot: %w", err)
+	}
+	if !exists {
+		if _, err := db.Exec(`SELECT pg_create_logical_replication_slot($1, 'pgoutput')`, cfg.Slot); err != nil {
+			return nil, fmt.Errorf("failed to create replication slot: %w", err)
+		}
+	}
+
+	return s, nil
+}
+
+// Run polls the slot every interval until ctx is cancelled.
+func (s *Streamer) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for {
+				n, err := s.Poll(ctx)
+				if err != nil {
+					log.Printf("CDC poll failed: %v", err)
+					break
+				}
+				if n < s.cfg.BatchSize {
+					break
+				}
+			}
+		}
+	}
+}
+
+// Poll publishes the changes of the transactions committed since the last
+// poll, up to about BatchSize changes, and returns how many changes were read.
+func (s *Streamer) Poll(ctx context.Context) (int, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT data FROM pg_logical_slot_peek_binary_changes($1, NULL, $2,
+			'proto_version', '1', 'publication_names', $3)`,
+		s.cfg.Slot,
+		s.cfg.BatchSize,
+		s.cfg.Publication,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read replication slot: %w", err)
+	}
+	defer rows.Close()
+
+	dec := newDecoder()
+	events := []Event{}
+	var confirmed uint64
+	n := 0
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return 0, fmt.Errorf("failed to scan change: %w", err)
+		}
+		n++
+		c, err := dec.decode(data)
+		if err != nil {
+			return 0, err
+		}
+		if c != nil {
+			for _, e := range c.events {
+				e.Source.DB = s.dbName
+				events = append(events, e)
+			}
+			confirmed = c.endLSN
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read changes: %w", err)
+	}
+	if confirmed == 0 {
+		return n, nil
+	}
+
+	if len(events) > 0 {
+		if err := s.publisher.Send(ctx, events); err != nil {
+			return 0, fmt.Errorf("failed to publish %d changes: %w", len(events), err)
+		}
+	}
+
+	lsn := fmt.Sprintf("%X/%X", confirmed>>32, uint32(confirmed))
+	if _, err := s.db.ExecContext(ctx, `SELECT pg_replication_slot_advance($1, $2::pg_lsn)`, s.cfg.Slot, lsn); err != nil {
+		return 0, fmt.Errorf("failed to advance replication slot: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,70 @@
+package cdc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"assignment/internal/clock"
+	"assignment/internal/core"
+	"assignment/internal/testutil"
+)
+
+type capturePublisher struct {
+	batches [][]Event
+}
+
+func (p *capturePublisher) Send(ctx context.Context, payload interface{}) error {
+	// Round-trip through JSON as a real publisher would
+	data, _ := json.Marshal(payload)
+	var events []Event
+	json.Unmarshal(data, &events)
+	p.batches = append(p.batches, events)
+	return nil
+}
+
+func TestStreamer(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	var walLevel string
+	if err := db.QueryRow(`SHOW wal_level`).Scan(&walLevel); err != nil || walLevel != "logical" {
+		t.Skipf("Skipping test: wal_level is %q, logical decoding unavailable", walLevel)
+	}
+
+	publisher := &capturePublisher{}
+	streamer, err := NewStreamer(db, Config{Slot: "cdc_test", Publication: "cdc_test"}, publisher)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	defer db.Exec(`SELECT pg_drop_replication_slot('cdc_test')`)
+	defer db.Exec(`DROP PUBLICATION IF EXISTS cdc_test`)
+
+	service := core.NewTransactionService(db, clock.New())
+	if _, err := service.ProcessTransaction(1, testutil.NewTransactionRequest("win", "10.00"), "game"); err != nil {
+		t.Fatalf("Failed to process transaction: %v", err)
+	}
+
+	if _, err := streamer.Poll(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(publisher.batches) != 1 {
+		t.Fatalf("Expected 1 batch, got: %d", len(publisher.batches))
+	}
+
+	tables := map[string]string{}
+	for _, e := range publisher.batches[0] {
+		tables[e.Source.Table] = e.Op
+	}
+	if tables["users"] != "u" || tables["transactions"] != "c" {
+		t.Errorf("Expected a users update and a transactions insert, got: %v", tables)
+	}
+
+	// The slot advanced, so nothing is published twice
+	if _, err := streamer.Poll(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(publisher.batches) != 1 {
+		t.Errorf("Expected no further batches, got: %d", len(publisher.batches))
+	}
+}
@@ -0,0 +1,214 @@
+package cdc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// pgEpoch is the origin of pgoutput timestamps.
+var pgEpoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Type OIDs whose text values are emitted as JSON numbers or booleans.
+// Everything else, including NUMERIC, is emitted as a string.
+const (
+	oidBool = 16
+	oidInt8 = 20
+	oidInt2 = 21
+	oidInt4 = 23
+)
+
+type column struct {
+	name    string
+	typeOID uint32
+}
+
+type relation struct {
+	namespace string
+	name      string
+	columns   []column
+}
+
+// decoder turns pgoutput (protocol version 1) messages into change events.
+// Changes are buffered until their transaction commits.
+type decoder struct {
+	relations map[uint32]relation
+	xid       uint32
+	pending   []Event
+}
+
+func newDecoder() *decoder {
+	return &decoder{relations: map[uint32]relation{}}
+}
+
+// commit describes a decoded Commit message.
+type commit struct {
+	endLSN uint64
+	events []Event
+}
+
+// decode processes one message. It returns a non-nil commit when msg ends a
+// transaction, carrying the transaction's events.
+func (d *decoder) decode(msg []byte) (*commit, error) {
+	r := &reader{buf: msg}
+	switch kind := r.byte(); kind {
+	case 'B':
+		r.uint64() // final LSN
+		r.uint64() // commit timestamp
+		d.xid = r.uint32()
+		d.pending = nil
+	case 'C':
+		r.byte()   // flags
+		r.uint64() // commit LSN
+		endLSN := r.uint64()
+		ts := pgEpoch.Add(time.Duration(r.uint64()) * time.Microsecond)
+		for i := range d.pending {
+			d.pending[i].Source.LSN = endLSN
+			d.pending[i].Source.TsMs = ts.UnixMilli()
+			d.pending[i].TsMs = ts.UnixMilli()
+		}
+		c := &commit{endLSN: endLSN, events: d.pending}
+		d.pending = nil
+		return c, r.err
+	case 'R':
+		id := r.uint32()
+		rel := relation{namespace: r.string(), name: r.string()}
+		r.byte() // replica identity
+		n := int(r.uint16())
+		for i := 0; i < n && r.err == nil; i++ {
+			r.byte() // flags
+			col := column{name: r.string(), typeOID: r.uint32()}
+			r.uint32() // type modifier
+			rel.columns = append(rel.columns, col)
+		}
+		d.relations[id] = rel
+	case 'I', 'U', 'D':
+		rel, ok := d.relations[r.uint32()]
+		if !ok {
+			return nil, errors.New("change for unknown relation")
+		}
+		event := Event{Op: map[byte]string{'I': "c", 'U': "u", 'D': "d"}[kind]}
+		event.Source = Source{
+			Connector: "postgresql",
+			Schema:    rel.namespace,
+			Table:     rel.name,
+			TxID:      d.xid,
+		}
+		for r.err == nil && r.remaining() > 0 {
+			switch tag := r.byte(); tag {
+			case 'K', 'O':
+				event.Before = rel.tuple(r)
+			case 'N':
+				event.After = rel.tuple(r)
+			default:
+				return nil, fmt.Errorf("unexpected tuple tag %q", tag)
+			}
+		}
+		d.pending = append(d.pending, event)
+	case 'O', 'Y', 'T', 'M':
+		// Origin, type, truncate and logical messages are not published
+	default:
+		return nil, fmt.Errorf("unknown pgoutput message %q", kind)
+	}
+	return nil, r.err
+}
+
+// tuple reads TupleData into a column map.
+func (rel relation) tuple(r *reader) map[string]interface{} {
+	n := int(r.uint16())
+	row := make(map[string]interface{}, n)
+	for i := 0; i < n && r.err == nil; i++ {
+		var col column
+		if i < len(rel.columns) {
+			col = rel.columns[i]
+		}
+		switch r.byte() {
+		case 'n':
+			row[col.name] = nil
+		case 'u':
+			// Unchanged TOASTed value; not sent by the server
+		case 't':
+			row[col.name] = textValue(col.typeOID, string(r.bytes(int(r.uint32()))))
+		}
+	}
+	return row
+}
+
+func textValue(typeOID uint32, s string) interface{} {
+	switch typeOID {
+	case oidInt2, oidInt4, oidInt8:
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n
+		}
+	case oidBool:
+		return s == "t"
+	}
+	return s
+}
+
+// reader decodes big-endian pgoutput fields, remembering the first error.
+type reader struct {
+	buf []byte
+	err error
+}
+
+func (r *reader) remaining() int {
+	return len(r.buf)
+}
+
+func (r *reader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.buf) {
+		r.err = errors.New("truncated pgoutput message")
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *reader) byte() byte {
+	if b := r.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *reader) uint16() uint16 {
+	if b := r.bytes(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *reader) uint32() uint32 {
+	if b := r.bytes(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *reader) uint64() uint64 {
+	if b := r.bytes(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (r *reader) string() string {
+	for i, c := range r.buf {
+		if c == 0 {
+			s := string(r.buf[:i])
+			r.buf = r.buf[i+1:]
+			return s
+		}
+	}
+	if r.err == nil {
+		r.err = errors.New("unterminated string in pgoutput message")
+	}
+	return ""
+}
@@ -0,0 +1,91 @@
+package cdc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// message builds pgoutput messages field by field.
+type message struct{ bytes.Buffer }
+
+func (m *message) u8(v byte) *message    { m.WriteByte(v); return m }
+func (m *message) u16(v uint16) *message { binary.Write(m, binary.BigEndian, v); return m }
+func (m *message) u32(v uint32) *message { binary.Write(m, binary.BigEndian, v); return m }
+func (m *message) u64(v uint64) *message { binary.Write(m, binary.BigEndian, v); return m }
+func (m *message) str(s string) *message { m.WriteString(s); m.WriteByte(0); return m }
+
+func (m *message) text(s string) *message {
+	return m.u8('t').u32(uint32(len(s))).u8s([]byte(s))
+}
+
+func (m *message) u8s(b []byte) *message { m.Write(b); return m }
+
+func TestDecoder(t *testing.T) {
+	commitTime := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	relationMsg := (&message{}).u8('R').u32(16384).str("public").str("users").u8('d').u16(3)
+	relationMsg.u8(1).str("id").u32(oidInt8).u32(0xFFFFFFFF)
+	relationMsg.u8(0).str("balance").u32(1700).u32(0)
+	relationMsg.u8(0).str("active").u32(oidBool).u32(0)
+
+	update := (&message{}).u8('U').u32(16384)
+	update.u8('O').u16(3).text("1").text("100.00").text("t")
+	update.u8('N').u16(3).text("1").text("110.50").u8('n')
+
+	messages := [][]byte{
+		(&message{}).u8('B').u64(0x16B3748).u64(0).u32(742).Bytes(),
+		relationMsg.Bytes(),
+		update.Bytes(),
+		(&message{}).u8('C').u8(0).u64(0x16B3700).u64(0x16B3748).u64(uint64(commitTime.Sub(pgEpoch).Microseconds())).Bytes(),
+	}
+
+	dec := newDecoder()
+	var c *commit
+	for i, msg := range messages {
+		got, err := dec.decode(msg)
+		if err != nil {
+			t.Fatalf("Message %d: expected no error, got: %v", i, err)
+		}
+		if got != nil {
+			c = got
+		}
+	}
+
+	if c == nil || c.endLSN != 0x16B3748 {
+		t.Fatalf("Expected commit with end LSN 0x16B3748, got: %+v", c)
+	}
+	if len(c.events) != 1 {
+		t.Fatalf("Expected 1 event, got: %d", len(c.events))
+	}
+
+	event := c.events[0]
+	if event.Op != "u" || event.Source.Table != "users" || event.Source.TxID != 742 {
+		t.Errorf("Unexpected event metadata: %+v", event)
+	}
+	if event.TsMs != commitTime.UnixMilli() || event.Source.LSN != 0x16B3748 {
+		t.Errorf("Unexpected commit metadata: ts_ms=%d lsn=%d", event.TsMs, event.Source.LSN)
+	}
+	if want := map[string]interface{}{"id": int64(1), "balance": "100.00", "active": true}; !reflect.DeepEqual(event.Before, want) {
+		t.Errorf("Before = %v, want %v", event.Before, want)
+	}
+	if want := map[string]interface{}{"id": int64(1), "balance": "110.50", "active": nil}; !reflect.DeepEqual(event.After, want) {
+		t.Errorf("After = %v, want %v", event.After, want)
+	}
+}
+
+func TestDecoder_Errors(t *testing.T) {
+	dec := newDecoder()
+
+	if _, err := dec.decode((&message{}).u8('I').u32(1).Bytes()); err == nil {
+		t.Error("Expected error for unknown relation, got none")
+	}
+	if _, err := dec.decode((&message{}).u8('C').u8(0).Bytes()); err == nil {
+		t.Error("Expected error for truncated message, got none")
+	}
+	if _, err := dec.decode([]byte{'?'}); err == nil {
+		t.Error("Expected error for unknown message type, got none")
+	}
+}
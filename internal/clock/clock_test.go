@@ -0,0 +1,31 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFake(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	f := NewFake(start)
+
+	if !f.Now().Equal(start) {
+		t.Errorf("Expected %v, got: %v", start, f.Now())
+	}
+
+	f.Advance(90 * time.Minute)
+	if want := start.Add(90 * time.Minute); !f.Now().Equal(want) {
+		t.Errorf("Expected %v, got: %v", want, f.Now())
+	}
+
+	f.Set(start)
+	if !f.Now().Equal(start) {
+		t.Errorf("Expected %v, got: %v", start, f.Now())
+	}
+}
+
+func TestNew_ReturnsUTC(t *testing.T) {
+	if loc := New().Now().Location(); loc != time.UTC {
+		t.Errorf("Expected UTC, got: %v", loc)
+	}
+}
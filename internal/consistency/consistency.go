@@ -0,0 +1,183 @@
+// Package consistency verifies the ledger: periodically, and on demand, it
+// checks every stored balance against the replayed transaction ledger,
+// alerts ops about drift, e.g. in a Slack channel, and optionally suspends
+// the affected accounts until someone has looked at them.
+package consistency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"assignment/internal/core"
+	"assignment/internal/models"
+)
+
+// Ledger is the part of core.TransactionService the checker uses.
+type Ledger interface {
+	ReplayLedger() (*models.ReplayReport, error)
+	GetUser(userID int64) (*models.User, error)
+	SetUserStatus(userID int64, req models.UserStatusRequest) (*models.User, error)
+}
+
+// Sender delivers an alert payload, e.g. a webhook.Client posting to a Slack
+// incoming webhook.
+type Sender interface {
+	Send(ctx context.Context, payload interface{}) error
+}
+
+// QuarantineReason is recorded with the status change of a quarantined user.
+const QuarantineReason = core.QuarantineReason
+
+type Checker struct {
+	ledger     Ledger
+	sender     Sender
+	quarantine bool
+
+	// mu serializes checks, which compare drift with the previous one
+	mu sync.Mutex
+	// drifting holds the users alerted about and not yet resolved
+	drifting map[int64]bool
+}
+
+// NewChecker creates a checker. sender may be nil when no alerts are
+// configured. With quarantine set, active users whose balance drifted are
+// suspended.
+func NewChecker(ledger Ledger, sender Sender, quarantine bool) *Checker {
+	return &Checker{ledger: ledger, sender: sender, quarantine: quarantine, drifting: map[int64]bool{}}
+}
+
+// Run blocks until ctx is cancelled, checking the ledger every interval.
+func (c *Checker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := c.Check(ctx); err != nil {
+				log.Printf("Ledger verification failed: %v", err)
+			}
+		}
+	}
+}
+
+// Check replays the ledger and reports the users whose stored balance
+// differs from it. An alert is sent when users start drifting and when all
+// drift is resolved; users whose alert fails to send are alerted about at
+// the next check. With quarantine set, the drifting users are suspended and
+// listed in the report.
+func (c *Checker) Check(ctx context.Context) (*models.ReplayReport, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	report, err := c.ledger.ReplayLedger()
+	if err != nil {
+		return nil, err
+	}
+
+	current := map[int64]bool{}
+	var started []models.BalanceDivergence
+	for _, d := range report.Divergences {
+		current[d.UserID] = true
+		if !c.drifting[d.UserID] {
+			started = append(started, d)
+		}
+		log.Printf("Ledger drift for user %d: balance %s, ledger %s (difference %s)", d.UserID, d.Balance, d.Replayed, d.Difference)
+	}
+
+	if c.quarantine {
+		for _, d := range report.Divergences {
+			quarantined, err := c.suspend(d)
+			if err != nil {
+				return nil, err
+			}
+			if quarantined {
+				report.Quarantined = append(report.Quarantined, d.UserID)
+			}
+		}
+	}
+
+	switch {
+	case len(started) > 0:
+		if c.alert(ctx, driftAlert(started, report.UsersChecked)) {
+			for _, d := range started {
+				c.drifting[d.UserID] = true
+			}
+		}
+	case len(current) == 0 && len(c.drifting) > 0:
+		if c.alert(ctx, fmt.Sprintf("RESOLVED: ledger drift, all %d balances match the ledger", report.UsersChecked)) {
+			c.drifting = map[int64]bool{}
+		}
+	}
+	// Users no longer drifting are alerted about again if they drift anew;
+	// once none drift, the resolution above clears them
+	if len(current) > 0 {
+		for id := range c.drifting {
+			if !current[id] {
+				delete(c.drifting, id)
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// suspend quarantines a drifting user and reports whether they were
+// active. Suspended and closed users are left as they are.
+func (c *Checker) suspend(d models.BalanceDivergence) (bool, error) {
+	user, err := c.ledger.GetUser(d.UserID)
+	if err != nil {
+		if errors.Is(err, core.ErrUserNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to look up user %d: %w", d.UserID, err)
+	}
+	if user.Status != core.UserActive {
+		return false, nil
+	}
+	_, err = c.ledger.SetUserStatus(d.UserID, models.UserStatusRequest{
+		Status: core.UserSuspended,
+		Reason: fmt.Sprintf("%s: balance %s, ledger %s", QuarantineReason, d.Balance, d.Replayed),
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to quarantine user %d: %w", d.UserID, err)
+	}
+	return true, nil
+}
+
+// alert sends text, if a sender is configured, and reports whether it was
+// delivered.
+func (c *Checker) alert(ctx context.Context, text string) bool {
+	if c.sender == nil {
+		return true
+	}
+	if err := c.sender.Send(ctx, map[string]string{"text": text}); err != nil {
+		log.Printf("Failed to send ledger drift alert: %v", err)
+		return false
+	}
+	log.Printf("Ledger drift alert sent: %s", text)
+	return true
+}
+
+// maxAlertedUsers bounds the users listed in one alert.
+const maxAlertedUsers = 10
+
+func driftAlert(started []models.BalanceDivergence, checked int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "ALERT: ledger drift, %d of %d balances newly differ from the ledger", len(started), checked)
+	for i, d := range started {
+		if i == maxAlertedUsers {
+			fmt.Fprintf(&b, "\n... and %d more", len(started)-maxAlertedUsers)
+			break
+		}
+		fmt.Fprintf(&b, "\nuser %d: balance %s, ledger %s (difference %s)", d.UserID, d.Balance, d.Replayed, d.Difference)
+	}
+	return b.String()
+}
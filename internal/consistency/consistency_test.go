@@ -0,0 +1,132 @@
+package consistency
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"assignment/internal/core"
+	"assignment/internal/models"
+)
+
+type fakeLedger struct {
+	divergences []models.BalanceDivergence
+	statuses    map[int64]string
+}
+
+func (f *fakeLedger) ReplayLedger() (*models.ReplayReport, error) {
+	return &models.ReplayReport{UsersChecked: 3, Divergences: append([]models.BalanceDivergence{}, f.divergences...)}, nil
+}
+
+func (f *fakeLedger) GetUser(userID int64) (*models.User, error) {
+	status, ok := f.statuses[userID]
+	if !ok {
+		return nil, core.ErrUserNotFound
+	}
+	return &models.User{ID: userID, Status: status}, nil
+}
+
+func (f *fakeLedger) SetUserStatus(userID int64, req models.UserStatusRequest) (*models.User, error) {
+	f.statuses[userID] = req.Status
+	return &models.User{ID: userID, Status: req.Status}, nil
+}
+
+type fakeSender struct {
+	texts []string
+	err   error
+}
+
+func (f *fakeSender) Send(ctx context.Context, payload interface{}) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.texts = append(f.texts, payload.(map[string]string)["text"])
+	return nil
+}
+
+func drift(userID int64) models.BalanceDivergence {
+	return models.BalanceDivergence{UserID: userID, Balance: "10.00", Replayed: "9.99", Difference: "-0.01"}
+}
+
+func TestChecker(t *testing.T) {
+	ledger := &fakeLedger{statuses: map[int64]string{1: core.UserActive, 2: core.UserActive}}
+	sender := &fakeSender{}
+	c := NewChecker(ledger, sender, false)
+	ctx := context.Background()
+
+	// No drift, nothing to resolve
+	if _, err := c.Check(ctx); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(sender.texts) != 0 {
+		t.Fatalf("Expected no alert, got: %v", sender.texts)
+	}
+
+	ledger.divergences = []models.BalanceDivergence{drift(1)}
+	report, err := c.Check(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(report.Divergences) != 1 || len(report.Quarantined) != 0 {
+		t.Errorf("Expected one divergence and no quarantine, got: %+v", report)
+	}
+	if len(sender.texts) != 1 || !strings.HasPrefix(sender.texts[0], "ALERT: ledger drift, 1 of 3 balances") {
+		t.Fatalf("Expected one alert, got: %v", sender.texts)
+	}
+	if ledger.statuses[1] != core.UserActive {
+		t.Errorf("Expected user 1 to stay active without quarantine, got: %s", ledger.statuses[1])
+	}
+
+	// Still drifting: no repeated alert, but a newly drifting user is alerted
+	c.Check(ctx)
+	ledger.divergences = append(ledger.divergences, drift(2))
+	c.Check(ctx)
+	if len(sender.texts) != 2 || !strings.Contains(sender.texts[1], "user 2:") || strings.Contains(sender.texts[1], "user 1:") {
+		t.Fatalf("Expected an alert for user 2 only, got: %v", sender.texts)
+	}
+
+	ledger.divergences = nil
+	c.Check(ctx)
+	if len(sender.texts) != 3 || !strings.HasPrefix(sender.texts[2], "RESOLVED: ledger drift") {
+		t.Fatalf("Expected resolution, got: %v", sender.texts)
+	}
+}
+
+func TestChecker_RetriesFailedAlerts(t *testing.T) {
+	ledger := &fakeLedger{divergences: []models.BalanceDivergence{drift(1)}, statuses: map[int64]string{1: core.UserActive}}
+	sender := &fakeSender{err: errors.New("unavailable")}
+	c := NewChecker(ledger, sender, false)
+
+	c.Check(context.Background())
+	sender.err = nil
+	c.Check(context.Background())
+	if len(sender.texts) != 1 || !strings.HasPrefix(sender.texts[0], "ALERT:") {
+		t.Errorf("Expected the alert to be retried, got: %v", sender.texts)
+	}
+}
+
+func TestChecker_Quarantine(t *testing.T) {
+	ledger := &fakeLedger{
+		divergences: []models.BalanceDivergence{drift(1), drift(2), drift(3)},
+		statuses:    map[int64]string{1: core.UserActive, 2: core.UserClosed},
+	}
+	c := NewChecker(ledger, nil, true)
+
+	report, err := c.Check(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(report.Quarantined) != 1 || report.Quarantined[0] != 1 {
+		t.Errorf("Expected only the active user to be quarantined, got: %v", report.Quarantined)
+	}
+	if ledger.statuses[1] != core.UserSuspended || ledger.statuses[2] != core.UserClosed {
+		t.Errorf("Expected user 1 suspended and user 2 closed, got: %v", ledger.statuses)
+	}
+
+	// Already suspended users are not reported again
+	report, _ = c.Check(context.Background())
+	if len(report.Quarantined) != 0 {
+		t.Errorf("Expected no new quarantine, got: %v", report.Quarantined)
+	}
+}
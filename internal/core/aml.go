@@ -0,0 +1,278 @@
+package core
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"assignment/internal/models"
+	"assignment/internal/money"
+	"assignment/internal/utils"
+	"assignment/internal/validation"
+)
+
+// Errors of resolving AML flags.
+var (
+	ErrAMLFlagNotFound = errors.New("AML flag not found")
+	ErrAMLFlagResolved = errors.New("AML flag already resolved")
+)
+
+// AML rules a transaction may be flagged under.
+const (
+	// AMLSingleTransaction: the transaction moves more than
+	// AMLRules.SingleTransaction
+	AMLSingleTransaction = "single_transaction"
+	// AMLDailyVolume: the transaction takes the user's volume over the last
+	// 24 hours over AMLRules.DailyVolume
+	AMLDailyVolume = "daily_volume"
+	// AMLRapidCycling: the user's wins and losses alternated at least
+	// AMLRules.CycleCount times within AMLRules.CycleWindow
+	AMLRapidCycling = "rapid_cycling"
+)
+
+// AML flag statuses.
+const (
+	AMLFlagOpen     = "open"
+	AMLFlagResolved = "resolved"
+)
+
+// AMLRules configure which applied transactions are flagged for AML review.
+// Zero disables a rule. Amounts are in the user's currency and cover all
+// accounts of the user, wallets included, except moves between them and
+// escrow accounts.
+type AMLRules struct {
+	SingleTransaction float64
+	DailyVolume       float64
+	CycleCount        int
+	CycleWindow       time.Duration
+}
+
+func (r AMLRules) enabled() bool {
+	return r.SingleTransaction > 0 || r.DailyVolume > 0 || (r.CycleCount > 0 && r.CycleWindow > 0)
+}
+
+// SetAMLRules flags applied transactions matching rules into the AML review
+// queue. Flagging never rejects a transaction. Call it before serving
+// requests.
+func (s *TransactionService) SetAMLRules(rules AMLRules) {
+	s.amlRules = rules
+}
+
+// flagAML records the AML flags raised by a transaction just applied in tx
+// to an account locked by lockBalance. A user is flagged for daily volume
+// when a transaction crosses the threshold, and for rapid cycling while it
+// has no open flag for it, rather than on every transaction after.
+func (s *TransactionService) flagAML(tx *sql.Tx, accountID int64, req models.TransactionRequest, transferID string, now time.Time) error {
+	var ownerID int64
+	var currency string
+	err := tx.QueryRow(
+		`SELECT COALESCE(u.owner_id, u.id), u.currency FROM users u
+		 WHERE u.id = $1 AND u.kind <> $2
+		   AND NOT EXISTS (SELECT 1 FROM transfers tr WHERE tr.transfer_id = $3 AND tr.internal)`,
+		accountID,
+		AccountEscrow,
+		transferID,
+	).Scan(&ownerID, &currency)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up account owner: %w", err)
+	}
+
+	amount, err := utils.ParseAmount(req.Amount)
+	if err != nil {
+		return fmt.Errorf("failed to parse amount: %w", err)
+	}
+
+	type flag struct{ rule, detail string }
+	var flags []flag
+	if limit := s.amlRules.SingleTransaction; limit > 0 && minorUnits(amount, currency) > minorUnits(limit, currency) {
+		flags = append(flags, flag{AMLSingleTransaction, fmt.Sprintf("amount %s over %s", req.Amount, money.Format(limit, currency))})
+	}
+	if limit := s.amlRules.DailyVolume; limit > 0 {
+		volume, err := amlVolume(tx, ownerID, now.Add(-24*time.Hour))
+		if err != nil {
+			return err
+		}
+		if minorUnits(volume, currency) > minorUnits(limit, currency) && minorUnits(volume, currency)-minorUnits(amount, currency) <= minorUnits(limit, currency) {
+			flags = append(flags, flag{AMLDailyVolume, fmt.Sprintf("24h volume %s over %s", money.Format(volume, currency), money.Format(limit, currency))})
+		}
+	}
+	if s.amlRules.CycleCount > 0 && s.amlRules.CycleWindow > 0 {
+		cycles, err := amlCycles(tx, ownerID, now.Add(-s.amlRules.CycleWindow))
+		if err != nil {
+			return err
+		}
+		if cycles >= s.amlRules.CycleCount {
+			open, err := hasOpenAMLFlag(tx, ownerID, AMLRapidCycling)
+			if err != nil {
+				return err
+			}
+			if !open {
+				flags = append(flags, flag{AMLRapidCycling, fmt.Sprintf("%d win/lose alternations within %s", cycles, s.amlRules.CycleWindow)})
+			}
+		}
+	}
+
+	for _, f := range flags {
+		_, err := tx.Exec(
+			`INSERT INTO aml_flags (user_id, account_id, transaction_id, rule, detail, status, created_at)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			ownerID,
+			accountID,
+			req.TransactionID,
+			f.rule,
+			f.detail,
+			AMLFlagOpen,
+			now,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to record AML flag: %w", err)
+		}
+		s.logger.Printf("AML flag raised: userID=%d, transactionID=%s, rule=%s, detail=%s", ownerID, req.TransactionID, f.rule, f.detail)
+	}
+	return nil
+}
+
+// amlTransactions restricts transactions t to the applied ones of a user's
+// accounts since $2, except moves between them.
+const amlTransactions = `t.user_id IN (SELECT id FROM users WHERE id = $1 OR owner_id = $1)
+	AND t.applied AND t.created_at >= $2
+	AND NOT EXISTS (SELECT 1 FROM transfers tr WHERE tr.transfer_id = t.transfer_id AND tr.internal)`
+
+// amlVolume returns the total amount of a user's transactions since from,
+// wins and losses alike.
+func amlVolume(tx *sql.Tx, ownerID int64, from time.Time) (float64, error) {
+	var total string
+	err := tx.QueryRow(`SELECT COALESCE(SUM(t.amount), 0) FROM transactions t WHERE `+amlTransactions, ownerID, from).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum AML volume: %w", err)
+	}
+	volume, err := utils.ParseAmount(total)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse AML volume: %w", err)
+	}
+	return volume, nil
+}
+
+// amlCycles returns how many times a user's transactions since from switched
+// between wins and losses.
+func amlCycles(tx *sql.Tx, ownerID int64, from time.Time) (int, error) {
+	var cycles int
+	err := tx.QueryRow(
+		`SELECT COUNT(*) FROM (
+			SELECT t.state, LAG(t.state) OVER (ORDER BY t.created_at, t.id) AS previous
+			FROM transactions t WHERE `+amlTransactions+`
+		 ) s WHERE s.state <> s.previous`,
+		ownerID,
+		from,
+	).Scan(&cycles)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count AML cycles: %w", err)
+	}
+	return cycles, nil
+}
+
+func hasOpenAMLFlag(tx *sql.Tx, ownerID int64, rule string) (bool, error) {
+	var open bool
+	err := tx.QueryRow(
+		`SELECT EXISTS (SELECT 1 FROM aml_flags WHERE user_id = $1 AND rule = $2 AND status = $3)`,
+		ownerID,
+		rule,
+		AMLFlagOpen,
+	).Scan(&open)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up AML flags: %w", err)
+	}
+	return open, nil
+}
+
+const amlFlagColumns = `id, user_id, account_id, transaction_id, rule, detail, status, created_at, COALESCE(resolution, ''), resolved_at`
+
+func scanAMLFlag(row interface{ Scan(...interface{}) error }) (models.AMLFlag, error) {
+	var f models.AMLFlag
+	var resolvedAt sql.NullTime
+	err := row.Scan(&f.ID, &f.UserID, &f.AccountID, &f.TransactionID, &f.Rule, &f.Detail, &f.Status, &f.CreatedAt, &f.Resolution, &resolvedAt)
+	if resolvedAt.Valid {
+		f.ResolvedAt = &resolvedAt.Time
+	}
+	return f, err
+}
+
+// GetAMLFlagsPage returns a page of the AML flags with the given status,
+// oldest first, with cursors to the pages around it.
+func (s *TransactionService) GetAMLFlagsPage(status string, page Page) (*models.AMLFlagList, error) {
+	if status != AMLFlagOpen && status != AMLFlagResolved {
+		return nil, validation.Errorf("status", "invalid status: must be 'open' or 'resolved'")
+	}
+
+	cond, order, args := page.keyset("created_at", "id", 2)
+	args = append([]interface{}{status}, args...)
+	rows, err := s.db.Query(
+		fmt.Sprintf(`SELECT %s FROM aml_flags WHERE status = $1 AND %s ORDER BY %s LIMIT $%d`,
+			amlFlagColumns, cond, order, len(args)+1),
+		append(args, page.Limit+1)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query AML flags: %w", err)
+	}
+	defer rows.Close()
+
+	flags := []models.AMLFlag{}
+	for rows.Next() {
+		f, err := scanAMLFlag(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan AML flag: %w", err)
+		}
+		flags = append(flags, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read AML flags: %w", err)
+	}
+
+	flags, more := trimPage(flags, page)
+	list := &models.AMLFlagList{Flags: flags}
+	list.NextCursor, list.PrevCursor = pageCursors(page, flags, func(f models.AMLFlag) pageKey {
+		return pageKey{f.CreatedAt, f.ID}
+	}, more)
+	return list, nil
+}
+
+// ResolveAMLFlag closes an open AML flag with the outcome of its review and
+// returns it.
+func (s *TransactionService) ResolveAMLFlag(id int64, req models.ResolveAMLFlagRequest) (*models.AMLFlag, error) {
+	resolution := strings.TrimSpace(req.Resolution)
+	if resolution == "" {
+		return nil, validation.Errorf("resolution", "invalid resolution: must not be empty")
+	}
+
+	f, err := scanAMLFlag(s.db.QueryRow(
+		`UPDATE aml_flags SET status = $1, resolution = $2, resolved_at = $3
+		 WHERE id = $4 AND status = $5 RETURNING `+amlFlagColumns,
+		AMLFlagResolved,
+		resolution,
+		s.clock.Now(),
+		id,
+		AMLFlagOpen,
+	))
+	if err == sql.ErrNoRows {
+		var exists bool
+		if err := s.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM aml_flags WHERE id = $1)`, id).Scan(&exists); err != nil {
+			return nil, fmt.Errorf("failed to look up AML flag: %w", err)
+		}
+		if exists {
+			return nil, ErrAMLFlagResolved
+		}
+		return nil, ErrAMLFlagNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve AML flag: %w", err)
+	}
+
+	s.logger.Printf("AML flag resolved: id=%d, userID=%d, rule=%s", id, f.UserID, f.Rule)
+
+	return &f, nil
+}
@@ -0,0 +1,70 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"assignment/internal/clock"
+	"assignment/internal/models"
+	"assignment/internal/testutil"
+)
+
+func TestAMLFlags(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	clk := clock.NewFake(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	service := NewTransactionService(db, clk)
+	service.SetAMLRules(AMLRules{SingleTransaction: 50, DailyVolume: 100, CycleCount: 3, CycleWindow: time.Hour})
+
+	mustProcess(t, service, 1, models.TransactionRequest{State: "win", Amount: "60.00", TransactionID: "aml-1"}, "game")
+	mustProcess(t, service, 1, models.TransactionRequest{State: "lose", Amount: "30.00", TransactionID: "aml-2"}, "game")
+	// Crosses the daily volume and completes three alternations
+	mustProcess(t, service, 1, models.TransactionRequest{State: "win", Amount: "20.00", TransactionID: "aml-3"}, "game")
+	mustProcess(t, service, 1, models.TransactionRequest{State: "lose", Amount: "5.00", TransactionID: "aml-4"}, "game")
+	// Outside the window of the earlier transactions
+	clk.Advance(25 * time.Hour)
+	mustProcess(t, service, 1, models.TransactionRequest{State: "win", Amount: "5.00", TransactionID: "aml-5"}, "game")
+
+	list, err := service.GetAMLFlagsPage(AMLFlagOpen, Page{Limit: 10})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	want := []struct{ transactionID, rule string }{
+		{"aml-1", AMLSingleTransaction},
+		{"aml-3", AMLDailyVolume},
+		{"aml-4", AMLRapidCycling},
+	}
+	if len(list.Flags) != len(want) {
+		t.Fatalf("Expected %d flags, got: %+v", len(want), list.Flags)
+	}
+	for i, w := range want {
+		if f := list.Flags[i]; f.TransactionID != w.transactionID || f.Rule != w.rule || f.UserID != 1 || f.Status != AMLFlagOpen {
+			t.Errorf("Flag %d: expected %s on %s, got: %+v", i, w.rule, w.transactionID, f)
+		}
+	}
+
+	resolved, err := service.ResolveAMLFlag(list.Flags[0].ID, models.ResolveAMLFlagRequest{Resolution: "tournament payout"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if resolved.Status != AMLFlagResolved || resolved.Resolution != "tournament payout" || resolved.ResolvedAt == nil {
+		t.Errorf("Unexpected resolved flag: %+v", resolved)
+	}
+	if _, err := service.ResolveAMLFlag(list.Flags[0].ID, models.ResolveAMLFlagRequest{Resolution: "again"}); err == nil || err.Error() != "AML flag already resolved" {
+		t.Errorf("Expected already resolved, got: %v", err)
+	}
+	if _, err := service.ResolveAMLFlag(999, models.ResolveAMLFlagRequest{Resolution: "x"}); err == nil || err.Error() != "AML flag not found" {
+		t.Errorf("Expected not found, got: %v", err)
+	}
+	if _, err := service.ResolveAMLFlag(list.Flags[1].ID, models.ResolveAMLFlagRequest{Resolution: " "}); err == nil {
+		t.Error("Expected error for empty resolution")
+	}
+
+	if list, err := service.GetAMLFlagsPage(AMLFlagResolved, Page{Limit: 10}); err != nil || len(list.Flags) != 1 {
+		t.Errorf("Expected 1 resolved flag, got: %+v, %v", list, err)
+	}
+	if _, err := service.GetAMLFlagsPage("pending", Page{Limit: 10}); err == nil {
+		t.Error("Expected error for invalid status")
+	}
+}
@@ -0,0 +1,28 @@
+package core
+
+import (
+	"assignment/internal/utils"
+	"assignment/internal/validation"
+)
+
+// SetMaxTransactionAmount sets the largest amount a single transaction or
+// transfer may move, 0 for no limit. Call it before serving requests.
+func (s *TransactionService) SetMaxTransactionAmount(amount float64) {
+	s.maxTransactionAmount = amount
+}
+
+// ValidateTransactionAmount checks the format of an amount and that it does
+// not exceed the maximum transaction amount, and returns it parsed.
+func (s *TransactionService) ValidateTransactionAmount(amountStr string) (float64, error) {
+	if err := utils.ValidateAmount(amountStr); err != nil {
+		return 0, err
+	}
+	amount, err := utils.ParseAmount(amountStr)
+	if err != nil {
+		return 0, err
+	}
+	if s.maxTransactionAmount > 0 && minorUnits(amount, "") > minorUnits(s.maxTransactionAmount, "") {
+		return 0, validation.Errorf("amount", "invalid amount: exceeds the maximum transaction amount of %s", utils.FormatBalance(s.maxTransactionAmount))
+	}
+	return amount, nil
+}
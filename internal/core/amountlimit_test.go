@@ -0,0 +1,36 @@
+package core
+
+import (
+	"testing"
+
+	"assignment/internal/clock"
+)
+
+func TestValidateTransactionAmount(t *testing.T) {
+	service := NewTransactionService(nil, clock.New())
+	if _, err := service.ValidateTransactionAmount("9999999999.99"); err != nil {
+		t.Errorf("Expected no limit by default, got: %v", err)
+	}
+
+	service.SetMaxTransactionAmount(10000)
+	tests := []struct {
+		amount  string
+		wantErr string
+	}{
+		{"10000.00", ""},
+		{"0.01", ""},
+		{"10000.01", "invalid amount: exceeds the maximum transaction amount of 10000.00"},
+		{"9999999999.99", "invalid amount: exceeds the maximum transaction amount of 10000.00"},
+		{"1.0001", "invalid amount format: must be a string with up to 3 decimal places"},
+	}
+	for _, tt := range tests {
+		_, err := service.ValidateTransactionAmount(tt.amount)
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tt.wantErr {
+			t.Errorf("ValidateTransactionAmount(%q) error = %q, want %q", tt.amount, got, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"assignment/internal/models"
+	"assignment/internal/validation"
+)
+
+// Actors of the changes in an audit trail. The service does not identify
+// admins individually, so changes are attributed to the channel they came
+// through; transactions are attributed to their Source-Type, as
+// "source_type:game".
+const (
+	ActorAdminAPI           = "admin_api"
+	ActorConsistencyChecker = "consistency_checker"
+	ActorLedgerRepair       = "ledger_repair"
+)
+
+// QuarantineReason starts the reason recorded with the status change of a
+// user suspended by the consistency checker.
+const QuarantineReason = "ledger drift"
+
+// GetAuditTrail returns everything recorded about a user's main account
+// between from and to: its profile, opening and closing balances, applied
+// and rejected transactions, balance adjustments and status changes. Deleted
+// users are included.
+func (s *TransactionService) GetAuditTrail(userID int64, from, to time.Time) (*models.AuditTrail, error) {
+	if !from.Before(to) {
+		return nil, validation.Errorf("to", "invalid period: must end after it starts")
+	}
+
+	user, err := scanUser("get", s.db.QueryRow(
+		`SELECT `+userColumns+` FROM users WHERE id = $1 AND kind = $2`,
+		userID,
+		AccountUser,
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	trail := &models.AuditTrail{
+		User:          user,
+		PeriodStart:   from,
+		PeriodEnd:     to,
+		Transactions:  []models.AuditTransaction{},
+		Rejections:    []models.AuditRejection{},
+		Adjustments:   []models.AuditAdjustment{},
+		StatusChanges: []models.AuditStatusChange{},
+	}
+	if trail.OpeningBalance, err = s.balanceBefore(userID, from); err != nil {
+		return nil, err
+	}
+	if trail.ClosingBalance, err = s.balanceBefore(userID, to); err != nil {
+		return nil, err
+	}
+
+	if err := s.auditTransactions(trail); err != nil {
+		return nil, err
+	}
+	if err := s.auditRejections(trail); err != nil {
+		return nil, err
+	}
+	if err := s.auditAdjustments(trail); err != nil {
+		return nil, err
+	}
+	if err := s.auditStatusChanges(trail); err != nil {
+		return nil, err
+	}
+	return trail, nil
+}
+
+func sourceActor(sourceType string) string {
+	return "source_type:" + sourceType
+}
+
+// statusChangeActor returns the actor of a status change recorded with
+// reason.
+func statusChangeActor(reason string) string {
+	if strings.HasPrefix(reason, QuarantineReason) {
+		return ActorConsistencyChecker
+	}
+	return ActorAdminAPI
+}
+
+func (s *TransactionService) auditTransactions(trail *models.AuditTrail) error {
+	rows, err := s.db.Query(
+		`SELECT t.id, t.transaction_id, t.state, t.amount, COALESCE(b.balance::TEXT, ''),
+		        COALESCE(t.transfer_id, ''), t.source_type, COALESCE(t.hash, ''), t.created_at
+		 FROM transactions t
+		 LEFT JOIN balance_snapshots b ON b.transaction_id = t.transaction_id AND b.user_id = t.user_id
+		 WHERE t.user_id = $1 AND t.applied AND t.created_at >= $2 AND t.created_at < $3
+		 ORDER BY t.id`,
+		trail.User.ID,
+		trail.PeriodStart,
+		trail.PeriodEnd,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to query audit transactions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t models.AuditTransaction
+		var sourceType string
+		if err := rows.Scan(&t.ID, &t.TransactionID, &t.State, &t.Amount, &t.BalanceAfter, &t.TransferID, &sourceType, &t.Hash, &t.CreatedAt); err != nil {
+			return fmt.Errorf("failed to scan audit transaction: %w", err)
+		}
+		t.Actor = sourceActor(sourceType)
+		trail.Transactions = append(trail.Transactions, t)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read audit transactions: %w", err)
+	}
+	return nil
+}
+
+func (s *TransactionService) auditRejections(trail *models.AuditTrail) error {
+	rows, err := s.db.Query(
+		`SELECT COALESCE(transaction_id, ''), COALESCE(state, ''), COALESCE(amount::TEXT, ''),
+		        reason, COALESCE(source_type, ''), created_at
+		 FROM transaction_rejections
+		 WHERE user_id = $1 AND created_at >= $2 AND created_at < $3
+		 ORDER BY id`,
+		trail.User.ID,
+		trail.PeriodStart,
+		trail.PeriodEnd,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to query audit rejections: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r models.AuditRejection
+		var sourceType string
+		if err := rows.Scan(&r.TransactionID, &r.State, &r.Amount, &r.Reason, &sourceType, &r.CreatedAt); err != nil {
+			return fmt.Errorf("failed to scan audit rejection: %w", err)
+		}
+		r.Actor = sourceActor(sourceType)
+		trail.Rejections = append(trail.Rejections, r)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read audit rejections: %w", err)
+	}
+	return nil
+}
+
+func (s *TransactionService) auditAdjustments(trail *models.AuditTrail) error {
+	rows, err := s.db.Query(
+		`SELECT amount, balance, recorded_at
+		 FROM balance_events
+		 WHERE user_id = $1 AND type = $2 AND recorded_at >= $3 AND recorded_at < $4
+		 ORDER BY version`,
+		trail.User.ID,
+		EventAdjusted,
+		trail.PeriodStart,
+		trail.PeriodEnd,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to query audit adjustments: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		// Adjustments are only made by ledger repairs
+		a := models.AuditAdjustment{Actor: ActorLedgerRepair}
+		if err := rows.Scan(&a.Amount, &a.BalanceAfter, &a.RecordedAt); err != nil {
+			return fmt.Errorf("failed to scan audit adjustment: %w", err)
+		}
+		trail.Adjustments = append(trail.Adjustments, a)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read audit adjustments: %w", err)
+	}
+	return nil
+}
+
+func (s *TransactionService) auditStatusChanges(trail *models.AuditTrail) error {
+	rows, err := s.db.Query(
+		`SELECT from_status, to_status, reason, changed_at
+		 FROM user_status_changes
+		 WHERE user_id = $1 AND changed_at >= $2 AND changed_at < $3
+		 ORDER BY id`,
+		trail.User.ID,
+		trail.PeriodStart,
+		trail.PeriodEnd,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to query audit status changes: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c models.AuditStatusChange
+		if err := rows.Scan(&c.FromStatus, &c.ToStatus, &c.Reason, &c.ChangedAt); err != nil {
+			return fmt.Errorf("failed to scan audit status change: %w", err)
+		}
+		c.Actor = statusChangeActor(c.Reason)
+		trail.StatusChanges = append(trail.StatusChanges, c)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read audit status changes: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"assignment/internal/clock"
+	"assignment/internal/models"
+	"assignment/internal/testutil"
+)
+
+func TestGetAuditTrail(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	clk := clock.NewFake(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	service := NewTransactionService(db, clk)
+
+	if _, err := service.ProcessTransaction(1, models.TransactionRequest{State: "win", Amount: "10.00", TransactionID: "audit-1"}, "game"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := service.ProcessTransaction(1, models.TransactionRequest{State: "lose", Amount: "500.00", TransactionID: "audit-2"}, "payment"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := service.SetUserStatus(1, models.UserStatusRequest{Status: UserSuspended, Reason: QuarantineReason + ": balance 110.00, ledger 100.00"}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	// Outside the period
+	clk.Advance(31 * 24 * time.Hour)
+	if _, err := service.SetUserStatus(1, models.UserStatusRequest{Status: UserActive, Reason: "checked"}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	trail, err := service.GetAuditTrail(1, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if trail.User.ID != 1 || trail.OpeningBalance != "100.00" || trail.ClosingBalance != "110.00" {
+		t.Errorf("Unexpected trail: %+v", trail)
+	}
+	if len(trail.Transactions) != 1 || trail.Transactions[0].TransactionID != "audit-1" || trail.Transactions[0].Actor != "source_type:game" || trail.Transactions[0].BalanceAfter != "110.00" || trail.Transactions[0].Hash == "" {
+		t.Errorf("Unexpected transactions: %+v", trail.Transactions)
+	}
+	if len(trail.Rejections) != 1 || trail.Rejections[0].TransactionID != "audit-2" || trail.Rejections[0].Actor != "source_type:payment" {
+		t.Errorf("Unexpected rejections: %+v", trail.Rejections)
+	}
+	if len(trail.StatusChanges) != 1 || trail.StatusChanges[0].ToStatus != UserSuspended || trail.StatusChanges[0].Actor != ActorConsistencyChecker {
+		t.Errorf("Unexpected status changes: %+v", trail.StatusChanges)
+	}
+
+	if _, err := service.GetAuditTrail(999, trail.PeriodStart, trail.PeriodEnd); err == nil || err.Error() != "user not found" {
+		t.Errorf("Expected user not found, got: %v", err)
+	}
+	if _, err := service.GetAuditTrail(1, trail.PeriodEnd, trail.PeriodStart); err == nil {
+		t.Error("Expected an empty period to be refused")
+	}
+}
@@ -0,0 +1,88 @@
+package core
+
+import (
+	"errors"
+
+	"assignment/internal/models"
+	"assignment/internal/money"
+	"assignment/internal/utils"
+)
+
+// Balance cap modes: a credit that would take a user's balance over its
+// maximum is either rejected or clipped to the maximum.
+const (
+	CapReject = "reject"
+	CapClip   = "clip"
+)
+
+// SetBalanceCap sets the maximum balance of users without one of their own
+// (0 for none) and what happens to credits over any maximum. Escrow accounts
+// are never capped. Call it before serving requests.
+func (s *TransactionService) SetBalanceCap(maxBalance float64, mode string) error {
+	if mode != CapReject && mode != CapClip {
+		return errors.New("invalid balance cap mode: must be 'reject' or 'clip'")
+	}
+	s.maxBalance = maxBalance
+	s.clipCredits = mode == CapClip
+	return nil
+}
+
+// capCredit checks a credit of amount to a user locked by lockBalance against
+// its maximum balance. It returns the amount to apply, which is lower than
+// amount when clip is set and the credit is clipped, or a rejection reason
+// and message.
+func (s *TransactionService) capCredit(userID int64, account lockedAccount, amount float64, clip bool) (applied float64, reason, message string) {
+	cur := account.currency
+	if account.maxBalance == 0 || minorUnits(account.balance, cur)+minorUnits(amount, cur) <= minorUnits(account.maxBalance, cur) {
+		return amount, "", ""
+	}
+
+	if clip && minorUnits(account.balance, cur) < minorUnits(account.maxBalance, cur) {
+		applied = account.maxBalance - account.balance
+		s.logger.Printf("Balance cap hit: userID=%d, balance=%s, max=%s, credit=%s clipped to %s",
+			userID, money.Format(account.balance, cur), money.Format(account.maxBalance, cur),
+			money.Format(amount, cur), money.Format(applied, cur))
+		return applied, "", ""
+	}
+
+	s.logger.Printf("Balance cap hit: userID=%d, balance=%s, max=%s, credit=%s rejected",
+		userID, money.Format(account.balance, cur), money.Format(account.maxBalance, cur), money.Format(amount, cur))
+	return 0, ReasonMaxBalance, "Maximum balance exceeded"
+}
+
+// SetMaxBalance sets the maximum balance of a user, overriding the global
+// one, and returns the user. An empty maximum removes the user's own.
+func (s *TransactionService) SetMaxBalance(userID int64, req models.MaxBalanceRequest) (*models.User, error) {
+	exponent := 0
+	if req.MaxBalance != "" {
+		if err := utils.ValidateAmount(req.MaxBalance); err != nil {
+			return nil, err
+		}
+		// The maximum has the decimals of the user's currency
+		user, err := s.GetUser(userID)
+		if err != nil {
+			return nil, err
+		}
+		if err := money.ValidateAmount(req.MaxBalance, user.Currency); err != nil {
+			return nil, err
+		}
+		exponent = money.Exponent(user.Currency)
+	}
+
+	user, err := scanUser("update", s.db.QueryRow(
+		`UPDATE users SET max_balance = ROUND(NULLIF($1, '')::NUMERIC, $5), updated_at = $2
+		 WHERE id = $3 AND kind = $4 AND deleted_at IS NULL RETURNING `+userColumns,
+		req.MaxBalance,
+		s.clock.Now(),
+		userID,
+		AccountUser,
+		exponent,
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Printf("User max balance changed: userID=%d, max=%q", userID, req.MaxBalance)
+
+	return user, nil
+}
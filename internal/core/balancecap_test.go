@@ -0,0 +1,77 @@
+package core
+
+import (
+	"testing"
+
+	"assignment/internal/clock"
+	"assignment/internal/models"
+	"assignment/internal/testutil"
+)
+
+func TestBalanceCap_Reject(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.New())
+	if err := service.SetBalanceCap(120, CapReject); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	resp := mustProcess(t, service, 1, testutil.NewTransactionRequest("win", "20.01"), "game")
+	if resp.Message != "Maximum balance exceeded" || resp.Balance != "100.00" {
+		t.Errorf("Expected rejection at 100.00, got: %+v", resp)
+	}
+	resp = mustProcess(t, service, 1, testutil.NewTransactionRequest("win", "20.00"), "game")
+	if resp.Message != "Transaction applied successfully" || resp.Balance != "120.00" {
+		t.Errorf("Expected credit up to the cap, got: %+v", resp)
+	}
+
+	// A user's own maximum overrides the global one
+	if _, err := service.SetMaxBalance(2, models.MaxBalanceRequest{MaxBalance: "500.00"}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	resp = mustProcess(t, service, 2, testutil.NewTransactionRequest("win", "300.00"), "game")
+	if resp.Message != "Transaction applied successfully" {
+		t.Errorf("Expected user maximum to apply, got: %s", resp.Message)
+	}
+
+	// Transfers over the recipient's maximum are rejected
+	transfer, err := service.Transfer(models.TransferRequest{FromUserID: 2, ToUserID: 1, Amount: "1.00"}, "game")
+	if err != nil || transfer.Message != "Maximum balance exceeded" {
+		t.Errorf("Expected transfer rejection, got: %v, %v", transfer, err)
+	}
+}
+
+func TestBalanceCap_Clip(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.New())
+	if err := service.SetBalanceCap(120, CapClip); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	req := testutil.NewTransactionRequest("win", "50.00")
+	resp := mustProcess(t, service, 1, req, "game")
+	if resp.Message != "Transaction clipped to maximum balance" || resp.Balance != "120.00" {
+		t.Errorf("Expected clipped credit at 120.00, got: %+v", resp)
+	}
+
+	var amount string
+	if err := db.QueryRow(`SELECT amount FROM transactions WHERE transaction_id = $1`, req.TransactionID).Scan(&amount); err != nil {
+		t.Fatalf("Failed to read transaction: %v", err)
+	}
+	if amount != "20.00" {
+		t.Errorf("Expected the clipped amount 20.00 to be recorded, got: %s", amount)
+	}
+
+	// Nothing is left to credit at the cap
+	resp = mustProcess(t, service, 1, testutil.NewTransactionRequest("win", "1.00"), "game")
+	if resp.Message != "Maximum balance exceeded" {
+		t.Errorf("Expected rejection at the cap, got: %s", resp.Message)
+	}
+
+	if err := service.SetBalanceCap(0, "truncate"); err == nil {
+		t.Error("Expected error for invalid mode")
+	}
+}
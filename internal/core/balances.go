@@ -0,0 +1,130 @@
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"assignment/internal/models"
+	"assignment/internal/validation"
+	"github.com/lib/pq"
+)
+
+// MaxBulkBalances bounds the number of users of a bulk balance lookup.
+const MaxBulkBalances = 1000
+
+// GetBalances returns the balances of several users with a single query.
+// Users that don't exist are listed as not found rather than failing the
+// lookup; repeated IDs are answered once.
+func (s *TransactionService) GetBalances(userIDs []int64) (*models.BulkBalanceResponse, error) {
+	if len(userIDs) == 0 {
+		return nil, validation.Errorf("userIds", "invalid user IDs: at least one is required")
+	}
+	if len(userIDs) > MaxBulkBalances {
+		return nil, validation.Errorf("userIds", "invalid user IDs: at most %d per request", MaxBulkBalances)
+	}
+	for _, id := range userIDs {
+		if id <= 0 {
+			return nil, validation.Errorf("userId", "invalid user ID: must be a positive integer")
+		}
+	}
+	if s.store != nil {
+		return s.getStoredBalances(userIDs)
+	}
+
+	query := `SELECT id, balance FROM users WHERE id = ANY($1) AND kind = 'user' AND deleted_at IS NULL`
+	if s.readModel {
+		query = `SELECT r.user_id, r.balance FROM read_balances r JOIN users u ON u.id = r.user_id
+		 WHERE r.user_id = ANY($1) AND u.deleted_at IS NULL`
+	}
+	rows, err := s.db.Query(query, pq.Array(userIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query balances: %w", err)
+	}
+	defer rows.Close()
+
+	balances := make(map[int64]string, len(userIDs))
+	for rows.Next() {
+		var id int64
+		var balance string
+		if err := rows.Scan(&id, &balance); err != nil {
+			return nil, fmt.Errorf("failed to scan balance: %w", err)
+		}
+		balances[id] = balance
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read balances: %w", err)
+	}
+
+	resp := &models.BulkBalanceResponse{Balances: []models.BalanceResponse{}, NotFound: []int64{}}
+	seen := make(map[int64]bool, len(userIDs))
+	for _, id := range userIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if balance, ok := balances[id]; ok {
+			resp.Balances = append(resp.Balances, models.BalanceResponse{UserID: id, Balance: balance})
+		} else {
+			resp.NotFound = append(resp.NotFound, id)
+		}
+	}
+	return resp, nil
+}
+
+// balanceAtQuery replays a user's ledger up to a moment, like replayQuery:
+// from their latest checkpoint recorded by then, or else their baseline
+// snapshot, adding the applied transactions recorded after it until then.
+// It yields no base balance if the user had none recorded yet. $1 is the
+// user ID and $2 the moment.
+const balanceAtQuery = `WITH base AS (
+		SELECT COALESCE(c.balance, b.balance) AS balance, COALESCE(c.snapshot_id, b.id) AS after_id
+		FROM (SELECT 1) AS one
+		LEFT JOIN LATERAL (
+			SELECT c.balance, c.snapshot_id FROM ledger_checkpoints c
+			JOIN balance_snapshots s ON s.id = c.snapshot_id
+			WHERE c.user_id = $1 AND s.recorded_at <= $2 ORDER BY c.snapshot_id DESC LIMIT 1
+		) c ON true
+		LEFT JOIN LATERAL (
+			SELECT id, balance FROM balance_snapshots
+			WHERE user_id = $1 AND transaction_id IS NULL AND recorded_at <= $2 ORDER BY id LIMIT 1
+		) b ON true
+	)
+	SELECT base.balance IS NOT NULL,
+	       (COALESCE(base.balance, 0) + COALESCE(SUM(CASE WHEN t.state = 'win' THEN t.amount ELSE -t.amount END), 0))::NUMERIC
+	FROM base
+	LEFT JOIN balance_snapshots s ON s.user_id = $1 AND s.id > base.after_id AND s.recorded_at <= $2
+	LEFT JOIN transactions t ON t.transaction_id = s.transaction_id AND t.applied
+	GROUP BY base.balance`
+
+// GetBalanceAt reconstructs a user's balance as of at from the transaction
+// ledger rather than from stored balances, so it reflects what the ledger
+// says even where a stored balance was later repaired.
+func (s *TransactionService) GetBalanceAt(userID int64, at time.Time) (*models.BalanceResponse, error) {
+	if at.After(s.clock.Now()) {
+		return nil, validation.Errorf("at", "invalid at: must not be in the future")
+	}
+
+	var exists bool
+	err := s.db.QueryRow(
+		`SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND kind = 'user' AND deleted_at IS NULL)`,
+		userID,
+	).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up user: %w", err)
+	}
+	if !exists {
+		return nil, ErrUserNotFound
+	}
+
+	at = at.UTC()
+	var recorded bool
+	var balance string
+	if err := s.db.QueryRow(balanceAtQuery, userID, at).Scan(&recorded, &balance); err != nil {
+		return nil, fmt.Errorf("failed to replay balance: %w", err)
+	}
+	if !recorded {
+		return nil, validation.Errorf("at", "invalid at: precedes the user's first recorded balance")
+	}
+
+	return &models.BalanceResponse{UserID: userID, Balance: balance, At: &at}, nil
+}
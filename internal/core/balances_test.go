@@ -0,0 +1,91 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"assignment/internal/clock"
+	"assignment/internal/testutil"
+)
+
+func TestGetBalances(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.New())
+	resp, err := service.GetBalances([]int64{2, 999, 1, 2})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(resp.Balances) != 2 || resp.Balances[0].UserID != 2 || resp.Balances[0].Balance != "50.00" || resp.Balances[1].UserID != 1 {
+		t.Errorf("Expected balances of users 2 and 1 in order, got: %+v", resp.Balances)
+	}
+	if len(resp.NotFound) != 1 || resp.NotFound[0] != 999 {
+		t.Errorf("Expected user 999 not found, got: %v", resp.NotFound)
+	}
+}
+
+func TestGetBalances_Cap(t *testing.T) {
+	service := NewTransactionService(nil, clock.New())
+	if _, err := service.GetBalances(make([]int64, MaxBulkBalances+1)); err == nil || err.Error() != "invalid user IDs: at most 1000 per request" {
+		t.Errorf("Expected the cap to be enforced, got: %v", err)
+	}
+	if _, err := service.GetBalances(nil); err == nil {
+		t.Error("Expected an error for an empty lookup")
+	}
+}
+
+func TestGetBalanceAt(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	clk := clock.NewFake(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	service := NewTransactionService(db, clk)
+	if _, err := db.Exec(`UPDATE balance_snapshots SET recorded_at = $1 WHERE user_id = 1`, clk.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("Failed to backdate baseline: %v", err)
+	}
+
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("win", "10.00"), "game")
+	before := clk.Now()
+	clk.Advance(time.Hour)
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("lose", "25.50"), "game")
+	if _, err := service.CreateLedgerCheckpoints(); err != nil {
+		t.Fatalf("Failed to create checkpoints: %v", err)
+	}
+	clk.Advance(time.Hour)
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("win", "1.25"), "game")
+
+	tests := []struct {
+		at   time.Time
+		want string
+	}{
+		{before.Add(-30 * time.Minute), "100.00"},
+		{before, "110.00"},
+		{before.Add(90 * time.Minute), "84.50"},
+		{clk.Now(), "85.75"},
+	}
+	for _, tt := range tests {
+		resp, err := service.GetBalanceAt(1, tt.at)
+		if err != nil {
+			t.Fatalf("Expected no error at %v, got: %v", tt.at, err)
+		}
+		if resp.Balance != tt.want {
+			t.Errorf("Expected balance %s at %v, got: %s", tt.want, tt.at, resp.Balance)
+		}
+	}
+
+	if _, err := service.GetBalanceAt(1, before.Add(-2*time.Hour)); err == nil {
+		t.Error("Expected an error before the first recorded balance")
+	}
+	if _, err := service.GetBalanceAt(999, before); err == nil || err.Error() != "user not found" {
+		t.Errorf("Expected user not found, got: %v", err)
+	}
+}
+
+func TestGetBalanceAt_Future(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	service := NewTransactionService(nil, clk)
+	if _, err := service.GetBalanceAt(1, clk.Now().Add(time.Second)); err == nil || err.Error() != "invalid at: must not be in the future" {
+		t.Errorf("Expected a future moment to be refused, got: %v", err)
+	}
+}
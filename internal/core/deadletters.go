@@ -0,0 +1,142 @@
+package core
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"assignment/internal/models"
+)
+
+// Errors of replaying webhook dead letters.
+var (
+	ErrDeadLetterNotFound = errors.New("dead letter not found")
+	ErrDeadLetterReplayed = errors.New("dead letter already replayed")
+	// ErrReplayFailed is matched by failed deliveries of a replay
+	ErrReplayFailed = errors.New("replay failed")
+)
+
+// RecordWebhookDeadLetter stores a webhook delivery that failed every
+// attempt, so it can be replayed once the endpoint recovers.
+func (s *TransactionService) RecordWebhookDeadLetter(url string, payload []byte, attempts int, lastError string) error {
+	var id int64
+	err := s.db.QueryRow(
+		`INSERT INTO webhook_dead_letters (url, payload, attempts, last_error, created_at)
+		 VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		url,
+		string(payload),
+		attempts,
+		lastError,
+		s.clock.Now(),
+	).Scan(&id)
+	if err != nil {
+		return fmt.Errorf("failed to record dead letter: %w", err)
+	}
+
+	s.logger.Printf("Webhook dead letter recorded: id=%d, attempts=%d", id, attempts)
+
+	return nil
+}
+
+// GetWebhookDeadLetters returns up to limit dead letters not replayed yet,
+// oldest first.
+func (s *TransactionService) GetWebhookDeadLetters(limit int) (*models.WebhookDeadLetterList, error) {
+	return s.GetWebhookDeadLettersPage(Page{Limit: limit})
+}
+
+// GetWebhookDeadLettersPage returns a page of the dead letters not replayed
+// yet, oldest first, with cursors to the pages around it.
+func (s *TransactionService) GetWebhookDeadLettersPage(page Page) (*models.WebhookDeadLetterList, error) {
+	cond, order, args := page.keyset("created_at", "id", 1)
+	rows, err := s.db.Query(
+		fmt.Sprintf(`SELECT id, url, payload, attempts, last_error, created_at FROM webhook_dead_letters
+		 WHERE replayed_at IS NULL AND %s ORDER BY %s LIMIT $%d`, cond, order, len(args)+1),
+		append(args, page.Limit+1)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dead letters: %w", err)
+	}
+	defer rows.Close()
+
+	deadLetters := []models.WebhookDeadLetter{}
+	for rows.Next() {
+		var d models.WebhookDeadLetter
+		var payload []byte
+		if err := rows.Scan(&d.ID, &d.URL, &payload, &d.Attempts, &d.LastError, &d.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan dead letter: %w", err)
+		}
+		d.Payload = payload
+		deadLetters = append(deadLetters, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dead letters: %w", err)
+	}
+
+	deadLetters, more := trimPage(deadLetters, page)
+	list := &models.WebhookDeadLetterList{DeadLetters: deadLetters}
+	list.NextCursor, list.PrevCursor = pageCursors(page, deadLetters, func(d models.WebhookDeadLetter) pageKey {
+		return pageKey{d.CreatedAt, d.ID}
+	}, more)
+	return list, nil
+}
+
+// ReplayWebhookDeadLetter passes a dead letter to send and marks it replayed
+// if it succeeds. A failed replay counts as another attempt and keeps the
+// dead letter queued. Concurrent replays of the same dead letter are
+// serialized.
+func (s *TransactionService) ReplayWebhookDeadLetter(id int64, send func(url string, payload []byte) error) (*models.WebhookDeadLetter, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var d models.WebhookDeadLetter
+	var payload []byte
+	var replayedAt sql.NullTime
+	err = tx.QueryRow(
+		`SELECT id, url, payload, attempts, last_error, created_at, replayed_at FROM webhook_dead_letters
+		 WHERE id = $1 FOR UPDATE`,
+		id,
+	).Scan(&d.ID, &d.URL, &payload, &d.Attempts, &d.LastError, &d.CreatedAt, &replayedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrDeadLetterNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead letter: %w", err)
+	}
+	if replayedAt.Valid {
+		return nil, ErrDeadLetterReplayed
+	}
+	d.Payload = payload
+	d.Attempts++
+
+	sendErr := send(d.URL, payload)
+	if sendErr != nil {
+		d.LastError = sendErr.Error()
+		_, err = tx.Exec(
+			`UPDATE webhook_dead_letters SET attempts = $1, last_error = $2 WHERE id = $3`,
+			d.Attempts, d.LastError, id,
+		)
+	} else {
+		now := s.clock.Now()
+		d.ReplayedAt = &now
+		_, err = tx.Exec(
+			`UPDATE webhook_dead_letters SET attempts = $1, replayed_at = $2 WHERE id = $3`,
+			d.Attempts, now, id,
+		)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update dead letter: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	if sendErr != nil {
+		return nil, fmt.Errorf("%w: %w", ErrReplayFailed, sendErr)
+	}
+
+	s.logger.Printf("Webhook dead letter replayed: id=%d", id)
+
+	return &d, nil
+}
@@ -0,0 +1,51 @@
+package core
+
+import (
+	"errors"
+	"testing"
+
+	"assignment/internal/clock"
+	"assignment/internal/testutil"
+)
+
+func TestWebhookDeadLetters(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.New())
+
+	if err := service.RecordWebhookDeadLetter("http://partner.example/hook", []byte(`{"text":"hi"}`), 5, "webhook endpoint returned status 503"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	list, err := service.GetWebhookDeadLetters(10)
+	if err != nil || len(list.DeadLetters) != 1 {
+		t.Fatalf("Expected 1 dead letter, got: %+v, %v", list, err)
+	}
+	id := list.DeadLetters[0].ID
+
+	// A failed replay counts as an attempt and keeps the dead letter queued
+	if _, err := service.ReplayWebhookDeadLetter(id, func(string, []byte) error { return errors.New("still down") }); err == nil {
+		t.Fatal("Expected replay error")
+	}
+	var sent string
+	replayed, err := service.ReplayWebhookDeadLetter(id, func(url string, payload []byte) error {
+		sent = url + " " + string(payload)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if sent != `http://partner.example/hook {"text":"hi"}` || replayed.Attempts != 7 || replayed.ReplayedAt == nil {
+		t.Errorf("Unexpected replay: %q, %+v", sent, replayed)
+	}
+
+	if list, err := service.GetWebhookDeadLetters(10); err != nil || len(list.DeadLetters) != 0 {
+		t.Errorf("Expected no dead letters left, got: %+v, %v", list, err)
+	}
+	if _, err := service.ReplayWebhookDeadLetter(id, func(string, []byte) error { return nil }); err == nil || err.Error() != "dead letter already replayed" {
+		t.Errorf("Expected already replayed, got: %v", err)
+	}
+	if _, err := service.ReplayWebhookDeadLetter(999, func(string, []byte) error { return nil }); err == nil || err.Error() != "dead letter not found" {
+		t.Errorf("Expected not found, got: %v", err)
+	}
+}
@@ -0,0 +1,269 @@
+package core
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"assignment/internal/models"
+	"assignment/internal/money"
+	"assignment/internal/utils"
+	"assignment/internal/validation"
+)
+
+// ErrDepositNotFound is returned for unknown deposits.
+var ErrDepositNotFound = errors.New("deposit not found")
+
+// ErrDepositChargedBack is returned when charging back a deposit that was
+// already charged back with another reference.
+var ErrDepositChargedBack = errors.New("deposit already charged back")
+
+// Deposit statuses.
+const (
+	DepositPending   = "pending"
+	DepositSucceeded = "succeeded"
+	DepositFailed    = "failed"
+	// DepositChargedBack: the provider reversed a succeeded deposit
+	DepositChargedBack = "charged_back"
+)
+
+const depositColumns = `id, user_id, amount, currency, status, COALESCE(provider_payment_id, ''),
+	COALESCE(chargeback_reference, ''), COALESCE(chargeback_amount::TEXT, ''), charged_back_at, created_at, updated_at`
+
+func scanDeposit(row interface{ Scan(...interface{}) error }) (*models.Deposit, error) {
+	var d models.Deposit
+	var chargedBackAt sql.NullTime
+	err := row.Scan(&d.ID, &d.UserID, &d.Amount, &d.Currency, &d.Status, &d.ProviderPaymentID,
+		&d.ChargebackReference, &d.ChargebackAmount, &chargedBackAt, &d.CreatedAt, &d.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if chargedBackAt.Valid {
+		d.ChargedBackAt = &chargedBackAt.Time
+	}
+	return &d, nil
+}
+
+// CreateDeposit records a pending deposit of a user in the user's currency
+// and returns it. Creating a deposit with an existing ID returns the existing
+// deposit, provided the amount is the same.
+func (s *TransactionService) CreateDeposit(id string, userID int64, amount string) (*models.Deposit, error) {
+	var currency string
+	err := s.db.QueryRow(`SELECT currency FROM users WHERE id = $1 AND kind = $2`, userID, AccountUser).Scan(&currency)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to look up user: %w", err)
+	}
+	if err == nil {
+		if err := money.ValidateAmount(amount, currency); err != nil {
+			return nil, err
+		}
+		value, err := utils.ParseAmount(amount)
+		if err != nil {
+			return nil, err
+		}
+		amount = money.Format(value, currency)
+	}
+
+	now := s.clock.Now()
+	deposit, err := scanDeposit(s.db.QueryRow(
+		`INSERT INTO deposits (id, user_id, amount, currency, status, created_at, updated_at)
+		 SELECT $1, id, $2, currency, $3, $4, $4 FROM users WHERE id = $5 AND kind = $6 AND deleted_at IS NULL
+		 ON CONFLICT (id) DO NOTHING
+		 RETURNING `+depositColumns,
+		id,
+		amount,
+		DepositPending,
+		now,
+		userID,
+		AccountUser,
+	))
+	if err == nil {
+		s.logger.Printf("Deposit created: id=%s, userID=%d, amount=%s", id, userID, deposit.Amount)
+		return deposit, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to create deposit: %w", err)
+	}
+
+	// Either the user does not exist or the deposit does
+	deposit, err = scanDeposit(s.db.QueryRow(
+		`SELECT `+depositColumns+` FROM deposits WHERE id = $1 AND amount = $2`,
+		id,
+		amount,
+	))
+	if err == sql.ErrNoRows {
+		var exists bool
+		if err := s.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM deposits WHERE id = $1)`, id).Scan(&exists); err != nil {
+			return nil, fmt.Errorf("failed to get deposit: %w", err)
+		}
+		if exists {
+			return nil, validation.Errorf("depositId", "invalid deposit: depositId already used with a different amount")
+		}
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deposit: %w", err)
+	}
+	return deposit, nil
+}
+
+// SetDepositPayment links a deposit to the payment the provider created for
+// it.
+func (s *TransactionService) SetDepositPayment(id, providerPaymentID string) error {
+	_, err := s.db.Exec(
+		`UPDATE deposits SET provider_payment_id = $1, updated_at = $2 WHERE id = $3`,
+		providerPaymentID,
+		s.clock.Now(),
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to link deposit payment: %w", err)
+	}
+	return nil
+}
+
+// GetDepositByPayment returns the deposit of a provider payment.
+func (s *TransactionService) GetDepositByPayment(providerPaymentID string) (*models.Deposit, error) {
+	deposit, err := scanDeposit(s.db.QueryRow(
+		`SELECT `+depositColumns+` FROM deposits WHERE provider_payment_id = $1`,
+		providerPaymentID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, ErrDepositNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deposit: %w", err)
+	}
+	return deposit, nil
+}
+
+// SetDepositStatus moves a deposit to status and returns it. Succeeded and
+// charged back deposits are final; a failed deposit may still succeed, e.g.
+// when the user retries with another payment method.
+func (s *TransactionService) SetDepositStatus(id, status string) (*models.Deposit, error) {
+	deposit, err := scanDeposit(s.db.QueryRow(
+		`UPDATE deposits SET status = $1, updated_at = $2 WHERE id = $3 AND status NOT IN ($4, $5)
+		 RETURNING `+depositColumns,
+		status,
+		s.clock.Now(),
+		id,
+		DepositSucceeded,
+		DepositChargedBack,
+	))
+	if err == sql.ErrNoRows {
+		deposit, err = scanDeposit(s.db.QueryRow(`SELECT `+depositColumns+` FROM deposits WHERE id = $1`, id))
+		if err == sql.ErrNoRows {
+			return nil, ErrDepositNotFound
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update deposit: %w", err)
+	}
+
+	s.logger.Printf("Deposit %s: id=%s", deposit.Status, id)
+
+	return deposit, nil
+}
+
+// ChargeBackDeposit reverses a succeeded deposit the payment provider charged
+// back, for amount (the whole deposit if empty), and returns the deposit. The
+// reversal is debited as the transaction "{depositId}:chargeback" even if
+// the balance cannot cover it: the money has already left, so the balance
+// goes negative and the user is flagged with chargeback_at. Chargebacks are
+// idempotent on the provider's reference.
+func (s *TransactionService) ChargeBackDeposit(id string, req models.ChargebackRequest) (*models.Deposit, error) {
+	if req.Reference == "" {
+		return nil, validation.Errorf("reference", "invalid chargeback: reference is required")
+	}
+	if req.Amount != "" {
+		if err := utils.ValidateAmount(req.Amount); err != nil {
+			return nil, err
+		}
+	}
+
+	deposit, err := scanDeposit(s.db.QueryRow(`SELECT `+depositColumns+` FROM deposits WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrDepositNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deposit: %w", err)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// The user is locked before the deposit, as when it was credited
+	now := s.clock.Now()
+	account, err := s.lockBalance(tx, deposit.UserID, AccountUser, now)
+	if err != nil {
+		return nil, err
+	}
+	deposit, err = scanDeposit(tx.QueryRow(`SELECT `+depositColumns+` FROM deposits WHERE id = $1 FOR UPDATE`, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deposit: %w", err)
+	}
+	if req.Amount != "" {
+		if err := money.ValidateAmount(req.Amount, deposit.Currency); err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case deposit.Status == DepositChargedBack && deposit.ChargebackReference == req.Reference:
+		return deposit, nil
+	case deposit.Status == DepositChargedBack:
+		return nil, ErrDepositChargedBack
+	case deposit.Status != DepositSucceeded:
+		return nil, validation.Errorf("depositId", "invalid chargeback: only succeeded deposits can be charged back")
+	}
+
+	deposited, err := utils.ParseAmount(deposit.Amount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse amount: %w", err)
+	}
+	amount := deposited
+	if req.Amount != "" {
+		amount, _ = utils.ParseAmount(req.Amount)
+		if minorUnits(amount, deposit.Currency) > minorUnits(deposited, deposit.Currency) {
+			return nil, validation.Errorf("amount", "invalid chargeback: amount exceeds the deposit")
+		}
+	}
+
+	// Flag the user first: the balance check only lets flagged users go
+	// negative
+	if _, err := tx.Exec(`UPDATE users SET chargeback_at = $1 WHERE id = $2`, now, deposit.UserID); err != nil {
+		return nil, fmt.Errorf("failed to flag user: %w", err)
+	}
+	newBalance := money.Format(account.balance-amount, account.currency)
+	reversal := models.TransactionRequest{State: "lose", Amount: money.Format(amount, account.currency), TransactionID: deposit.ID + ":chargeback"}
+	if _, err := s.applyTransaction(tx, deposit.UserID, account.head, reversal, "payment", newBalance, "", now); err != nil {
+		return nil, err
+	}
+
+	deposit, err = scanDeposit(tx.QueryRow(
+		`UPDATE deposits SET status = $1, chargeback_reference = $2, chargeback_amount = $3, charged_back_at = $4, updated_at = $4
+		 WHERE id = $5 RETURNING `+depositColumns,
+		DepositChargedBack,
+		req.Reference,
+		reversal.Amount,
+		now,
+		id,
+	))
+	if isUniqueViolation(err) {
+		return nil, validation.Errorf("reference", "invalid chargeback: reference already used")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update deposit: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.logger.Printf("Deposit charged back: id=%s, userID=%d, reference=%s, amount=%s, newBalance=%s",
+		id, deposit.UserID, req.Reference, reversal.Amount, newBalance)
+
+	return deposit, nil
+}
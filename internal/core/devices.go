@@ -0,0 +1,103 @@
+package core
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"assignment/internal/models"
+	"assignment/internal/utils"
+)
+
+// ErrDeviceNotFound is returned for device tokens a user hasn't registered.
+var ErrDeviceNotFound = errors.New("device not found")
+
+// RegisterPushDevice registers a device token of a user for push
+// notifications and returns it. A token registered by another user, e.g.
+// after a new login on a shared device, moves to this one.
+func (s *TransactionService) RegisterPushDevice(userID int64, req models.PushDeviceRequest) (*models.PushDevice, error) {
+	if err := utils.ValidatePushPlatform(req.Platform); err != nil {
+		return nil, err
+	}
+	if err := utils.ValidateDeviceToken(req.Token); err != nil {
+		return nil, err
+	}
+
+	device := &models.PushDevice{UserID: userID, Platform: req.Platform, Token: req.Token, CreatedAt: s.clock.Now()}
+	var registered int64
+	err := s.db.QueryRow(
+		`INSERT INTO push_devices (platform, token, user_id, created_at)
+		 SELECT $1, $2, id, $3 FROM users WHERE id = $4 AND kind = $5 AND deleted_at IS NULL
+		 ON CONFLICT (platform, token) DO UPDATE SET user_id = EXCLUDED.user_id, created_at = EXCLUDED.created_at
+		 RETURNING user_id`,
+		req.Platform,
+		req.Token,
+		device.CreatedAt,
+		userID,
+		AccountUser,
+	).Scan(&registered)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to register device: %w", err)
+	}
+
+	s.logger.Printf("Push device registered: userID=%d, platform=%s", userID, req.Platform)
+
+	return device, nil
+}
+
+// UnregisterPushDevice removes a device token of a user.
+func (s *TransactionService) UnregisterPushDevice(userID int64, token string) error {
+	result, err := s.db.Exec(`DELETE FROM push_devices WHERE user_id = $1 AND token = $2`, userID, token)
+	if err != nil {
+		return fmt.Errorf("failed to unregister device: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrDeviceNotFound
+	}
+
+	s.logger.Printf("Push device unregistered: userID=%d", userID)
+
+	return nil
+}
+
+// GetPushDevices returns the devices registered by a user, oldest first.
+func (s *TransactionService) GetPushDevices(userID int64) (*models.PushDeviceList, error) {
+	var exists bool
+	err := s.db.QueryRow(
+		`SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND kind = $2 AND deleted_at IS NULL)`,
+		userID,
+		AccountUser,
+	).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if !exists {
+		return nil, ErrUserNotFound
+	}
+
+	rows, err := s.db.Query(
+		`SELECT user_id, platform, token, created_at FROM push_devices
+		 WHERE user_id = $1 ORDER BY created_at, token`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query devices: %w", err)
+	}
+	defer rows.Close()
+
+	list := &models.PushDeviceList{UserID: userID, Devices: []models.PushDevice{}}
+	for rows.Next() {
+		var d models.PushDevice
+		if err := rows.Scan(&d.UserID, &d.Platform, &d.Token, &d.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan device: %w", err)
+		}
+		list.Devices = append(list.Devices, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read devices: %w", err)
+	}
+	return list, nil
+}
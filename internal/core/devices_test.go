@@ -0,0 +1,62 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"assignment/internal/clock"
+	"assignment/internal/models"
+	"assignment/internal/testutil"
+)
+
+func TestPushDevices(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.NewFake(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)))
+	service.EnableNotifications()
+
+	device := models.PushDeviceRequest{Platform: "fcm", Token: "fcm-token-1"}
+	if _, err := service.RegisterPushDevice(2, device); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	// Registering the token again moves it to the new user
+	if _, err := service.RegisterPushDevice(1, device); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if list, err := service.GetPushDevices(2); err != nil || len(list.Devices) != 0 {
+		t.Errorf("Expected no devices for user 2, got: %+v, %v", list, err)
+	}
+	if list, err := service.GetPushDevices(1); err != nil || len(list.Devices) != 1 {
+		t.Errorf("Expected 1 device for user 1, got: %+v, %v", list, err)
+	}
+
+	// Users opted into push are pushed every balance change
+	if _, err := service.SetNotificationPreferences(1, models.NotificationPreferencesRequest{Channels: []string{"push"}}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("win", "5.00"), "game")
+	var got []models.Notification
+	if _, err := service.DeliverNotifications(10, func(n models.Notification) error {
+		got = append(got, n)
+		return nil
+	}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(got) != 1 || got[0].Type != NotifyBalanceChanged || got[0].Balance != "105.00" || got[0].Threshold != "" {
+		t.Errorf("Expected a balance change notification, got: %+v", got)
+	}
+
+	if err := service.UnregisterPushDevice(2, "fcm-token-1"); err == nil || err.Error() != "device not found" {
+		t.Errorf("Expected device not found for another user, got: %v", err)
+	}
+	if err := service.UnregisterPushDevice(1, "fcm-token-1"); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+	if _, err := service.RegisterPushDevice(999, device); err == nil || err.Error() != "user not found" {
+		t.Errorf("Expected user not found, got: %v", err)
+	}
+	if _, err := service.RegisterPushDevice(1, models.PushDeviceRequest{Platform: "fcm"}); err == nil {
+		t.Error("Expected error for empty token")
+	}
+}
@@ -0,0 +1,330 @@
+package core
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"assignment/internal/models"
+	"assignment/internal/money"
+	"assignment/internal/utils"
+	"assignment/internal/validation"
+)
+
+// Errors of opening and resolving disputes.
+var (
+	ErrTransactionNotFound = errors.New("transaction not found")
+	ErrDisputeNotFound     = errors.New("dispute not found")
+	ErrAlreadyDisputed     = errors.New("transaction already disputed")
+	ErrDisputeResolved     = errors.New("dispute already resolved")
+	// ErrDisputeInsufficientFunds is returned when the account cannot cover
+	// the debit of a hold or resolution
+	ErrDisputeInsufficientFunds = errors.New("insufficient funds for dispute entry")
+)
+
+// Dispute statuses.
+const (
+	DisputeOpen              = "open"
+	DisputeResolvedForPlayer = "resolved_for_player"
+	DisputeResolvedAgainst   = "resolved_against"
+)
+
+// OpenDispute opens a dispute against an applied transaction of a user or
+// wallet, for the whole amount or part of it. Transfers, escrow accounts and
+// the entries of disputes cannot be disputed, and a transaction has at most
+// one open dispute.
+//
+// A disputed win may be held: the amount is debited from the account at
+// once, as an entry with the transaction ID "dispute-{id}:hold", so the
+// player cannot spend it during the review.
+func (s *TransactionService) OpenDispute(req models.OpenDisputeRequest) (*models.Dispute, error) {
+	if req.TransactionID == "" {
+		return nil, validation.Errorf("transactionId", "invalid transaction ID: must not be empty")
+	}
+	if req.Amount != "" {
+		if err := utils.ValidateAmount(req.Amount); err != nil {
+			return nil, err
+		}
+	}
+
+	var accountID int64
+	var kind, state, amount, sourceType, currency string
+	var transfer, entry bool
+	err := s.db.QueryRow(
+		`SELECT t.user_id, u.kind, u.currency, t.state, t.amount, t.source_type, t.transfer_id IS NOT NULL,
+		        EXISTS (SELECT 1 FROM disputes d
+		                WHERE d.hold_transaction_id = t.transaction_id OR d.resolution_transaction_id = t.transaction_id)
+		 FROM transactions t JOIN users u ON u.id = t.user_id
+		 WHERE t.transaction_id = $1 AND t.applied`,
+		req.TransactionID,
+	).Scan(&accountID, &kind, &currency, &state, &amount, &sourceType, &transfer, &entry)
+	if err == sql.ErrNoRows {
+		return nil, ErrTransactionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
+	}
+	if kind == AccountEscrow || transfer || entry {
+		return nil, validation.Errorf("transactionId", "invalid transaction: transfers, escrow transactions and dispute entries cannot be disputed")
+	}
+	if req.Hold && state != "win" {
+		return nil, validation.Errorf("hold", "invalid hold: only disputed wins can be held")
+	}
+	disputed, err := utils.ParseAmount(amount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse amount: %w", err)
+	}
+	if req.Amount != "" {
+		if err := money.ValidateAmount(req.Amount, currency); err != nil {
+			return nil, err
+		}
+		partial, _ := utils.ParseAmount(req.Amount)
+		if minorUnits(partial, currency) > minorUnits(disputed, currency) {
+			return nil, validation.Errorf("amount", "invalid amount: exceeds the disputed transaction")
+		}
+		disputed = partial
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := s.clock.Now()
+	account, err := s.lockBalance(tx, accountID, kind, now)
+	if err != nil {
+		return nil, err
+	}
+	if req.Amount != "" {
+		if err := money.ValidateAmount(req.Amount, account.currency); err != nil {
+			return nil, err
+		}
+	}
+
+	d := models.Dispute{
+		UserID:        accountID,
+		TransactionID: req.TransactionID,
+		Amount:        money.Format(disputed, account.currency),
+		Reason:        req.Reason,
+		Held:          req.Hold,
+		Status:        DisputeOpen,
+		CreatedAt:     now,
+	}
+	err = tx.QueryRow(
+		`INSERT INTO disputes (user_id, transaction_id, amount, reason, held, status, created_at)
+		 VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7) RETURNING id`,
+		d.UserID,
+		d.TransactionID,
+		d.Amount,
+		d.Reason,
+		d.Held,
+		d.Status,
+		now,
+	).Scan(&d.ID)
+	if isUniqueViolation(err) {
+		return nil, ErrAlreadyDisputed
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dispute: %w", err)
+	}
+
+	if d.Held {
+		d.HoldTransactionID = fmt.Sprintf("dispute-%d:hold", d.ID)
+		if err := s.applyDisputeEntry(tx, accountID, account, d.HoldTransactionID, "lose", disputed, sourceType, now); err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(`UPDATE disputes SET hold_transaction_id = $1 WHERE id = $2`, d.HoldTransactionID, d.ID); err != nil {
+			return nil, fmt.Errorf("failed to record dispute hold: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.logger.Printf("Dispute opened: id=%d, userID=%d, transactionID=%s, amount=%s, held=%t", d.ID, accountID, d.TransactionID, d.Amount, d.Held)
+
+	return &d, nil
+}
+
+// ResolveDispute resolves an open dispute and applies the resolution to the
+// account as an entry linked to the dispute:
+//
+//   - for the player, a held amount is released ("dispute-{id}:release") and
+//     a disputed loss is refunded ("dispute-{id}:refund")
+//   - against the player, a disputed win that was not held is reversed
+//     ("dispute-{id}:reversal"); a held amount is kept
+//
+// Other resolutions leave the balance as it is. A reversal the balance
+// cannot cover fails and leaves the dispute open.
+func (s *TransactionService) ResolveDispute(id int64, req models.ResolveDisputeRequest) (*models.Dispute, error) {
+	if req.Status != DisputeResolvedForPlayer && req.Status != DisputeResolvedAgainst {
+		return nil, validation.Errorf("status", "invalid status: must be 'resolved_for_player' or 'resolved_against'")
+	}
+
+	var accountID int64
+	var kind string
+	err := s.db.QueryRow(
+		`SELECT d.user_id, u.kind FROM disputes d JOIN users u ON u.id = d.user_id WHERE d.id = $1`,
+		id,
+	).Scan(&accountID, &kind)
+	if err == sql.ErrNoRows {
+		return nil, ErrDisputeNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dispute: %w", err)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// The account is locked before the dispute, as when it was opened
+	now := s.clock.Now()
+	account, err := s.lockBalance(tx, accountID, kind, now)
+	if err != nil {
+		return nil, err
+	}
+	d, err := scanDispute(tx.QueryRow(`SELECT `+disputeColumns+` FROM disputes WHERE id = $1 FOR UPDATE`, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dispute: %w", err)
+	}
+	if d.Status != DisputeOpen {
+		return nil, ErrDisputeResolved
+	}
+
+	var state, sourceType string
+	err = tx.QueryRow(
+		`SELECT state, source_type FROM transactions WHERE transaction_id = $1`,
+		d.TransactionID,
+	).Scan(&state, &sourceType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get disputed transaction: %w", err)
+	}
+
+	entry, entryState := "", ""
+	switch {
+	case req.Status == DisputeResolvedForPlayer && d.Held:
+		entry, entryState = "release", "win"
+	case req.Status == DisputeResolvedForPlayer && state == "lose":
+		entry, entryState = "refund", "win"
+	case req.Status == DisputeResolvedAgainst && !d.Held && state == "win":
+		entry, entryState = "reversal", "lose"
+	}
+	if entry != "" {
+		amount, err := utils.ParseAmount(d.Amount)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse amount: %w", err)
+		}
+		d.ResolutionTransactionID = fmt.Sprintf("dispute-%d:%s", d.ID, entry)
+		if err := s.applyDisputeEntry(tx, accountID, account, d.ResolutionTransactionID, entryState, amount, sourceType, now); err != nil {
+			return nil, err
+		}
+	}
+
+	d.Status = req.Status
+	d.ResolvedAt = &now
+	_, err = tx.Exec(
+		`UPDATE disputes SET status = $1, resolution_transaction_id = NULLIF($2, ''), resolved_at = $3 WHERE id = $4`,
+		d.Status,
+		d.ResolutionTransactionID,
+		now,
+		d.ID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve dispute: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.logger.Printf("Dispute resolved: id=%d, userID=%d, status=%s, entry=%q", d.ID, accountID, d.Status, d.ResolutionTransactionID)
+
+	return &d, nil
+}
+
+// applyDisputeEntry applies an entry of a dispute to an account locked by
+// lockBalance. Entries are corrections ordered by an admin, so limits, caps
+// and freezes do not apply; only the balance must cover debits.
+func (s *TransactionService) applyDisputeEntry(tx *sql.Tx, accountID int64, account lockedAccount, transactionID, state string, amount float64, sourceType string, now time.Time) error {
+	newBalance := account.balance + amount
+	if state == "lose" {
+		newBalance = account.balance - amount
+		if newBalance < 0 {
+			return ErrDisputeInsufficientFunds
+		}
+	}
+	req := models.TransactionRequest{State: state, Amount: money.Format(amount, account.currency), TransactionID: transactionID}
+	_, err := s.applyTransaction(tx, accountID, account.head, req, sourceType, money.Format(newBalance, account.currency), "", now)
+	return err
+}
+
+// GetDispute returns a dispute.
+func (s *TransactionService) GetDispute(id int64) (*models.Dispute, error) {
+	d, err := scanDispute(s.db.QueryRow(`SELECT `+disputeColumns+` FROM disputes WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrDisputeNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dispute: %w", err)
+	}
+	return &d, nil
+}
+
+// GetDisputesPage returns a page of the disputes with the given status,
+// oldest first, with cursors to the pages around it.
+func (s *TransactionService) GetDisputesPage(status string, page Page) (*models.DisputeList, error) {
+	if status != DisputeOpen && status != DisputeResolvedForPlayer && status != DisputeResolvedAgainst {
+		return nil, validation.Errorf("status", "invalid status: must be 'open', 'resolved_for_player' or 'resolved_against'")
+	}
+
+	cond, order, args := page.keyset("created_at", "id", 2)
+	args = append([]interface{}{status}, args...)
+	rows, err := s.db.Query(
+		fmt.Sprintf(`SELECT %s FROM disputes WHERE status = $1 AND %s ORDER BY %s LIMIT $%d`,
+			disputeColumns, cond, order, len(args)+1),
+		append(args, page.Limit+1)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query disputes: %w", err)
+	}
+	defer rows.Close()
+
+	disputes := []models.Dispute{}
+	for rows.Next() {
+		d, err := scanDispute(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan dispute: %w", err)
+		}
+		disputes = append(disputes, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read disputes: %w", err)
+	}
+
+	disputes, more := trimPage(disputes, page)
+	list := &models.DisputeList{Disputes: disputes}
+	list.NextCursor, list.PrevCursor = pageCursors(page, disputes, func(d models.Dispute) pageKey {
+		return pageKey{d.CreatedAt, d.ID}
+	}, more)
+	return list, nil
+}
+
+const disputeColumns = `id, user_id, transaction_id, amount, COALESCE(reason, ''), held, status,
+	COALESCE(hold_transaction_id, ''), COALESCE(resolution_transaction_id, ''), created_at, resolved_at`
+
+func scanDispute(row interface{ Scan(...interface{}) error }) (models.Dispute, error) {
+	var d models.Dispute
+	var resolvedAt sql.NullTime
+	err := row.Scan(&d.ID, &d.UserID, &d.TransactionID, &d.Amount, &d.Reason, &d.Held, &d.Status,
+		&d.HoldTransactionID, &d.ResolutionTransactionID, &d.CreatedAt, &resolvedAt)
+	if resolvedAt.Valid {
+		d.ResolvedAt = &resolvedAt.Time
+	}
+	return d, err
+}
@@ -0,0 +1,87 @@
+package core
+
+import (
+	"testing"
+
+	"assignment/internal/clock"
+	"assignment/internal/models"
+	"assignment/internal/testutil"
+)
+
+func TestDisputes(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.New())
+
+	mustProcess(t, service, 1, models.TransactionRequest{State: "win", Amount: "40.00", TransactionID: "dispute-win"}, "game")
+	mustProcess(t, service, 1, models.TransactionRequest{State: "lose", Amount: "20.00", TransactionID: "dispute-lose"}, "game")
+
+	// A held win is taken from the balance until resolved
+	held, err := service.OpenDispute(models.OpenDisputeRequest{TransactionID: "dispute-win", Amount: "30.00", Reason: "bonus abuse", Hold: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if held.Status != DisputeOpen || held.Amount != "30.00" || held.HoldTransactionID == "" {
+		t.Errorf("Unexpected dispute: %+v", held)
+	}
+	expectBalance(t, service, 1, "90.00")
+	if _, err := service.OpenDispute(models.OpenDisputeRequest{TransactionID: "dispute-win"}); err == nil || err.Error() != "transaction already disputed" {
+		t.Errorf("Expected already disputed, got: %v", err)
+	}
+	if _, err := service.OpenDispute(models.OpenDisputeRequest{TransactionID: held.HoldTransactionID}); err == nil {
+		t.Error("Expected error disputing a dispute entry")
+	}
+
+	// Resolved against the player, the hold is kept
+	resolved, err := service.ResolveDispute(held.ID, models.ResolveDisputeRequest{Status: DisputeResolvedAgainst})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if resolved.ResolutionTransactionID != "" || resolved.ResolvedAt == nil {
+		t.Errorf("Unexpected resolution: %+v", resolved)
+	}
+	expectBalance(t, service, 1, "90.00")
+	if _, err := service.ResolveDispute(held.ID, models.ResolveDisputeRequest{Status: DisputeResolvedForPlayer}); err == nil || err.Error() != "dispute already resolved" {
+		t.Errorf("Expected already resolved, got: %v", err)
+	}
+
+	// A disputed loss resolved for the player is refunded
+	refund, err := service.OpenDispute(models.OpenDisputeRequest{TransactionID: "dispute-lose"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := service.OpenDispute(models.OpenDisputeRequest{TransactionID: "dispute-lose", Hold: true}); err == nil {
+		t.Error("Expected error holding a disputed loss")
+	}
+	resolved, err = service.ResolveDispute(refund.ID, models.ResolveDisputeRequest{Status: DisputeResolvedForPlayer})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if resolved.ResolutionTransactionID == "" {
+		t.Errorf("Expected a refund entry, got: %+v", resolved)
+	}
+	expectBalance(t, service, 1, "110.00")
+
+	list, err := service.GetDisputesPage(DisputeResolvedForPlayer, Page{Limit: 10})
+	if err != nil || len(list.Disputes) != 1 || list.Disputes[0].ID != refund.ID {
+		t.Errorf("Expected the refunded dispute, got: %+v, %v", list, err)
+	}
+	if _, err := service.OpenDispute(models.OpenDisputeRequest{TransactionID: "missing"}); err == nil || err.Error() != "transaction not found" {
+		t.Errorf("Expected transaction not found, got: %v", err)
+	}
+	if _, err := service.ResolveDispute(999, models.ResolveDisputeRequest{Status: DisputeResolvedAgainst}); err == nil || err.Error() != "dispute not found" {
+		t.Errorf("Expected dispute not found, got: %v", err)
+	}
+}
+
+func expectBalance(t *testing.T, service *TransactionService, userID int64, want string) {
+	t.Helper()
+	balance, err := service.GetBalance(userID)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if balance.Balance != want {
+		t.Errorf("Expected balance %s, got: %s", want, balance.Balance)
+	}
+}
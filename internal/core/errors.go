@@ -0,0 +1,207 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"assignment/internal/models"
+	"assignment/internal/validation"
+	"github.com/lib/pq"
+)
+
+// ErrUserNotFound is returned for users that don't exist or were deleted.
+var ErrUserNotFound = errors.New("user not found")
+
+// ErrWalletNotFound is returned for wallets a user doesn't have.
+var ErrWalletNotFound = errors.New("wallet not found")
+
+// ErrValidation is matched by the errors of requests failing validation, all
+// of them *ValidationError giving the offending field. Their messages start
+// with "invalid".
+var ErrValidation = validation.ErrInvalid
+
+// ValidationError is a request failing validation.
+type ValidationError = validation.Error
+
+// Outcomes of processed transactions that were not applied, which are
+// responses rather than errors to API clients; see TransactionError.
+var (
+	ErrDuplicateTransaction = errors.New("duplicate transaction")
+	ErrInsufficientFunds    = errors.New("insufficient funds")
+	// ErrTransactionRejected is matched by the other rejections, e.g. for a
+	// KYC limit or a suspended user
+	ErrTransactionRejected = errors.New("transaction rejected")
+)
+
+// Reasons why a transaction was not applied, recorded with rejections and
+// reported as TransactionResponse.Reason.
+const (
+	ReasonDuplicate         = "duplicate"
+	ReasonInsufficientFunds = "insufficient_funds"
+	ReasonUserSuspended     = "user_suspended"
+	ReasonUserClosed        = "user_closed"
+	ReasonFrozen            = "frozen"
+	ReasonKYCSingleLimit    = "kyc_single_limit"
+	ReasonKYCDailyLimit     = "kyc_daily_limit"
+	ReasonMaxBalance        = "max_balance"
+)
+
+// RejectionError is a rejection without a sentinel of its own, with its
+// reason and the lowercased response message, e.g. "kyc daily limit
+// exceeded".
+type RejectionError struct {
+	Reason  string
+	Message string
+}
+
+func (e *RejectionError) Error() string {
+	return e.Message
+}
+
+func (e *RejectionError) Is(target error) bool {
+	return target == ErrTransactionRejected
+}
+
+// TransactionError returns why the transaction answered with resp was not
+// applied: ErrDuplicateTransaction for a retry of a processed transaction
+// ID, ErrInsufficientFunds, or a *RejectionError matching
+// ErrTransactionRejected. It returns nil for applied transactions, including
+// clipped ones.
+func TransactionError(resp *models.TransactionResponse) error {
+	switch {
+	case resp.Replayed || resp.Reason == ReasonDuplicate:
+		return ErrDuplicateTransaction
+	case resp.Reason == "":
+		return nil
+	case resp.Reason == ReasonInsufficientFunds:
+		return ErrInsufficientFunds
+	}
+	return &RejectionError{Reason: resp.Reason, Message: strings.ToLower(resp.Message)}
+}
+
+// RetryableError is a failure that may not recur when the same request is
+// retried unchanged, e.g. a lost database connection. Any other failure is
+// terminal: retrying it yields the same result.
+type RetryableError struct {
+	Err error
+}
+
+func (e *RetryableError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryableError) Unwrap() error {
+	return e.Err
+}
+
+// retryablePgClasses are the Postgres error classes of transient failures:
+// connection exceptions, serialization failures and deadlocks, insufficient
+// resources and operator intervention (e.g. a server shutting down).
+var retryablePgClasses = map[pq.ErrorClass]bool{
+	"08": true,
+	"40": true,
+	"53": true,
+	"57": true,
+}
+
+// retryablePgCodes are further Postgres errors of transient failures:
+// lock_not_available, raised when lock_timeout expires waiting for a row
+// lock held by another transaction.
+var retryablePgCodes = map[pq.ErrorCode]bool{
+	"55P03": true,
+}
+
+// IsRetryable reports whether err is retryable: a RetryableError, or a
+// failure of the database or network anywhere in its chain.
+func IsRetryable(err error) bool {
+	var retryable *RetryableError
+	if errors.As(err, &retryable) {
+		return true
+	}
+	if _, ok := RetryAfter(err); ok {
+		return true
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return retryablePgClasses[pqErr.Code.Class()] || retryablePgCodes[pqErr.Code]
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// RetryAfter returns how long err asks callers to wait before retrying, e.g.
+// while the circuit breaker of the database is open. It reports false if err
+// doesn't say.
+func RetryAfter(err error) (time.Duration, bool) {
+	var delayed interface{ RetryAfter() time.Duration }
+	if errors.As(err, &delayed) {
+		return delayed.RetryAfter(), true
+	}
+	return 0, false
+}
+
+// contextError returns ctx's error in place of err once ctx is done: a
+// statement failing then, e.g. on a transaction rolled back by ctx, is only a
+// symptom of the caller giving up.
+func contextError(ctx context.Context, err error) error {
+	if err == nil || ctx.Err() == nil || errors.Is(err, ctx.Err()) {
+		return err
+	}
+	return fmt.Errorf("%w: %v", ctx.Err(), err)
+}
+
+// ConstraintError is a write the database refused because it would break a
+// schema constraint, e.g. a negative balance. Message describes the
+// violation for API clients.
+type ConstraintError struct {
+	Message string
+	Err     error
+}
+
+func (e *ConstraintError) Error() string {
+	return e.Message
+}
+
+func (e *ConstraintError) Unwrap() error {
+	return e.Err
+}
+
+// constraintMessages describe the CHECK constraints of the schema.
+var constraintMessages = map[string]string{
+	"transactions_state_check":  "invalid state: must be 'win' or 'lose'",
+	"transactions_amount_check": "invalid amount: must not be negative",
+	"users_balance_check":       "invalid balance: must not be negative",
+}
+
+// AsConstraintError returns the NOT NULL or CHECK constraint violation err
+// stems from, or nil if it doesn't.
+func AsConstraintError(err error) *ConstraintError {
+	var constraint *ConstraintError
+	if errors.As(err, &constraint) {
+		return constraint
+	}
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return nil
+	}
+	switch pqErr.Code.Name() {
+	case "not_null_violation":
+		return &ConstraintError{Message: fmt.Sprintf("invalid %s: must be set", pqErr.Column), Err: err}
+	case "check_violation":
+		message, ok := constraintMessages[pqErr.Constraint]
+		if !ok {
+			message = fmt.Sprintf("invalid request: violates constraint %s", pqErr.Constraint)
+		}
+		return &ConstraintError{Message: message, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package core
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"assignment/internal/models"
+	"github.com/lib/pq"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{errors.New("invalid amount format"), false},
+		{errors.New("user not found"), false},
+		{&RetryableError{errors.New("failed to generate a unique transaction ID")}, true},
+		{fmt.Errorf("failed to begin transaction: %w", driver.ErrBadConn), true},
+		{fmt.Errorf("failed to lock user: %w", &pq.Error{Code: "40001"}), true},
+		{fmt.Errorf("failed to lock user: %w", &pq.Error{Code: "40P01"}), true},
+		{fmt.Errorf("failed to lock user: %w", &pq.Error{Code: "55P03"}), true},
+		{fmt.Errorf("failed to lock user: %w", &pq.Error{Code: "57014"}), true},
+		{fmt.Errorf("failed to insert: %w", &pq.Error{Code: "23505"}), false},
+		{fmt.Errorf("failed to connect: %w", &net.OpError{Op: "dial", Err: errors.New("connection refused")}), true},
+		{fmt.Errorf("failed to get user: %w", waitError(time.Second)), true},
+	}
+	for _, tt := range tests {
+		if got := IsRetryable(tt.err); got != tt.want {
+			t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestAsConstraintError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{fmt.Errorf("failed to update user balance: %w", &pq.Error{Code: "23514", Constraint: "users_balance_check"}), "invalid balance: must not be negative"},
+		{fmt.Errorf("failed to insert transaction: %w", &pq.Error{Code: "23514", Constraint: "transactions_state_check"}), "invalid state: must be 'win' or 'lose'"},
+		{fmt.Errorf("failed to insert transaction: %w", &pq.Error{Code: "23514", Constraint: "deposits_amount_check"}), "invalid request: violates constraint deposits_amount_check"},
+		{fmt.Errorf("failed to insert transaction: %w", &pq.Error{Code: "23502", Column: "source_type"}), "invalid source_type: must be set"},
+	}
+	for _, tt := range tests {
+		got := AsConstraintError(tt.err)
+		if got == nil || got.Error() != tt.want {
+			t.Errorf("AsConstraintError(%v) = %v, want %q", tt.err, got, tt.want)
+		}
+	}
+
+	for _, err := range []error{
+		errors.New("user not found"),
+		fmt.Errorf("failed to insert: %w", &pq.Error{Code: "23505"}),
+		fmt.Errorf("failed to lock user: %w", &pq.Error{Code: "40001"}),
+	} {
+		if got := AsConstraintError(err); got != nil {
+			t.Errorf("AsConstraintError(%v) = %v, want nil", err, got)
+		}
+	}
+}
+
+type waitError time.Duration
+
+func (e waitError) Error() string             { return "unavailable" }
+func (e waitError) RetryAfter() time.Duration { return time.Duration(e) }
+
+func TestRetryAfter(t *testing.T) {
+	if wait, ok := RetryAfter(fmt.Errorf("failed to get user: %w", waitError(3*time.Second))); !ok || wait != 3*time.Second {
+		t.Errorf("Expected a 3s wait, got %v, %v", wait, ok)
+	}
+	if _, ok := RetryAfter(driver.ErrBadConn); ok {
+		t.Error("Expected no wait for an error without one")
+	}
+}
+
+func TestValidationErrors(t *testing.T) {
+	s := NewTransactionService(nil, nil)
+	_, err := s.ValidateTransactionAmount("-1")
+	var invalid *ValidationError
+	if !errors.Is(err, ErrValidation) || !errors.As(err, &invalid) || invalid.Field != "amount" {
+		t.Fatalf("Expected a validation error of the amount, got: %#v", err)
+	}
+	if err.Error() != "invalid amount format: must be a string with up to 3 decimal places" {
+		t.Errorf("Expected the message to be kept, got: %s", err)
+	}
+	if wrapped := fmt.Errorf("failed to import row: %w", err); !errors.Is(wrapped, ErrValidation) {
+		t.Errorf("Expected wrapped validation errors to match")
+	}
+	if errors.Is(ErrUserNotFound, ErrValidation) || errors.Is(errors.New("invalid amount format"), ErrValidation) {
+		t.Errorf("Expected only validation errors to match")
+	}
+}
+
+func TestTransactionError(t *testing.T) {
+	tests := []struct {
+		resp *models.TransactionResponse
+		want error
+	}{
+		{&models.TransactionResponse{Message: "Transaction applied successfully"}, nil},
+		{&models.TransactionResponse{Message: "Transaction clipped to maximum balance"}, nil},
+		{&models.TransactionResponse{Message: "Transaction applied successfully", Replayed: true}, ErrDuplicateTransaction},
+		{&models.TransactionResponse{Message: "Duplicate message ignored", Reason: ReasonDuplicate}, ErrDuplicateTransaction},
+		{&models.TransactionResponse{Message: "Insufficient funds", Reason: ReasonInsufficientFunds}, ErrInsufficientFunds},
+		{&models.TransactionResponse{Message: "KYC daily limit exceeded", Reason: ReasonKYCDailyLimit}, ErrTransactionRejected},
+	}
+	for _, tt := range tests {
+		if got := TransactionError(tt.resp); !errors.Is(got, tt.want) || (tt.want == nil) != (got == nil) {
+			t.Errorf("TransactionError(%+v) = %v, want %v", tt.resp, got, tt.want)
+		}
+	}
+	err := TransactionError(&models.TransactionResponse{Message: "KYC daily limit exceeded", Reason: ReasonKYCDailyLimit})
+	var rejection *RejectionError
+	if !errors.As(err, &rejection) || rejection.Reason != ReasonKYCDailyLimit || err.Error() != "kyc daily limit exceeded" {
+		t.Errorf("Expected the reason and response message, got: %#v", err)
+	}
+}
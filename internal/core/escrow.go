@@ -0,0 +1,114 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"assignment/internal/models"
+	"assignment/internal/utils"
+)
+
+// ErrEscrowNotFound is returned for unknown escrow accounts.
+var ErrEscrowNotFound = errors.New("escrow account not found")
+
+// CreateEscrowAccount creates a system-owned escrow account with a zero
+// balance, or returns the existing account with that name. Escrow accounts
+// are stored alongside users, with the same balance snapshots and event
+// stream, so funds held in escrow never leave the ledger. The account takes
+// the currency of the first user holding funds in it (see HoldInEscrow).
+func (s *TransactionService) CreateEscrowAccount(name string) (*models.EscrowAccount, error) {
+	if err := utils.ValidateEscrowName(name); err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRow(
+		`INSERT INTO users (balance, kind, escrow_name, created_at, updated_at) VALUES ('0.00', $1, $2, $3, $3)
+		 ON CONFLICT (escrow_name) DO NOTHING
+		 RETURNING id`,
+		AccountEscrow,
+		name,
+		now,
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		// Already exists
+		return s.GetEscrowAccount(name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create escrow account: %w", err)
+	}
+
+	_, err = tx.Exec(`INSERT INTO balance_snapshots (user_id, balance, recorded_at) VALUES ($1, '0.00', $2)`, id, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record balance snapshot: %w", err)
+	}
+	if _, err := s.openStream(tx, id, "0.00", now); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &models.EscrowAccount{ID: id, Name: name, Balance: "0.00", Currency: DefaultCurrency, CreatedAt: now}, nil
+}
+
+// GetEscrowAccount returns the escrow account with the given name.
+func (s *TransactionService) GetEscrowAccount(name string) (*models.EscrowAccount, error) {
+	account := models.EscrowAccount{Name: name}
+	err := s.db.QueryRow(
+		`SELECT id, balance, currency, created_at FROM users WHERE escrow_name = $1 AND kind = $2`,
+		name,
+		AccountEscrow,
+	).Scan(&account.ID, &account.Balance, &account.Currency, &account.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrEscrowNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get escrow account: %w", err)
+	}
+	return &account, nil
+}
+
+// HoldInEscrow moves funds from a user into the named escrow account, e.g. a
+// tournament buy-in, as a transfer. An escrow account that has never held
+// funds first takes the user's currency; later holds in another currency are
+// rejected like any cross-currency transfer.
+func (s *TransactionService) HoldInEscrow(ctx context.Context, name string, req models.EscrowRequest, sourceType string) (*models.TransferResponse, error) {
+	account, err := s.GetEscrowAccount(name)
+	if err != nil {
+		return nil, err
+	}
+	return s.transfer(ctx, models.TransferRequest{
+		FromUserID: req.UserID,
+		ToUserID:   account.ID,
+		Amount:     req.Amount,
+		TransferID: req.TransferID,
+	}, sourceType, AccountUser, AccountEscrow, false)
+}
+
+// ReleaseFromEscrow moves funds from the named escrow account to a user,
+// e.g. a prize payout or a refund, as a transfer. An escrow account cannot
+// release more than it holds.
+func (s *TransactionService) ReleaseFromEscrow(ctx context.Context, name string, req models.EscrowRequest, sourceType string) (*models.TransferResponse, error) {
+	account, err := s.GetEscrowAccount(name)
+	if err != nil {
+		return nil, err
+	}
+	return s.transfer(ctx, models.TransferRequest{
+		FromUserID: account.ID,
+		ToUserID:   req.UserID,
+		Amount:     req.Amount,
+		TransferID: req.TransferID,
+	}, sourceType, AccountEscrow, AccountUser, false)
+}
@@ -0,0 +1,117 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"assignment/internal/clock"
+	"assignment/internal/models"
+	"assignment/internal/testutil"
+)
+
+func TestEscrow(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.New())
+
+	account, err := service.CreateEscrowAccount("tournament-1")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	again, err := service.CreateEscrowAccount("tournament-1")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if again.ID != account.ID {
+		t.Errorf("Expected creation to be idempotent, got IDs %d and %d", account.ID, again.ID)
+	}
+
+	// Buy-ins from two users, then the whole pot to the winner
+	for _, userID := range []int64{1, 2} {
+		resp, err := service.HoldInEscrow(context.Background(), "tournament-1", models.EscrowRequest{UserID: userID, Amount: "25.00"}, "game")
+		if err != nil || resp.Message != "Transfer applied successfully" {
+			t.Fatalf("Expected buy-in of user %d to be applied, got: %v, %v", userID, resp, err)
+		}
+	}
+	resp, err := service.ReleaseFromEscrow(context.Background(), "tournament-1", models.EscrowRequest{UserID: 2, Amount: "50.00"}, "game")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if resp.FromBalance != "0.00" || resp.ToBalance != "75.00" {
+		t.Errorf("Expected escrow 0.00 and winner 75.00, got: %s and %s", resp.FromBalance, resp.ToBalance)
+	}
+
+	// Escrow cannot release more than it holds
+	resp, err = service.ReleaseFromEscrow(context.Background(), "tournament-1", models.EscrowRequest{UserID: 1, Amount: "0.01"}, "game")
+	if err != nil || resp.Message != "Insufficient funds" {
+		t.Errorf("Expected insufficient funds, got: %v, %v", resp, err)
+	}
+
+	// Escrow accounts are not users: no direct transactions, balances or rankings
+	if _, err := service.ProcessTransaction(account.ID, testutil.NewTransactionRequest("win", "1.00"), "game"); err == nil || err.Error() != "user not found" {
+		t.Errorf("Expected user not found for a transaction on escrow, got: %v", err)
+	}
+	if _, err := service.GetBalance(account.ID); err == nil || err.Error() != "user not found" {
+		t.Errorf("Expected user not found for escrow balance, got: %v", err)
+	}
+	if _, err := service.HoldInEscrow(context.Background(), "tournament-1", models.EscrowRequest{UserID: account.ID, Amount: "1.00"}, "game"); err == nil {
+		t.Error("Expected error moving funds from escrow into itself")
+	}
+	report, err := service.GetTopUsers("net_win", DefaultCurrency, 10, models.TagFilter{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	for _, user := range report.Users {
+		if user.UserID == account.ID {
+			t.Errorf("Expected escrow account to be excluded from rankings")
+		}
+	}
+
+	if diverged, err := service.CheckProjections(); err != nil || len(diverged) != 0 {
+		t.Errorf("Expected balances to match event streams, got: %v, %v", diverged, err)
+	}
+}
+
+func TestEscrow_NotFound(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.New())
+
+	_, err := service.HoldInEscrow(context.Background(), "missing", models.EscrowRequest{UserID: 1, Amount: "1.00"}, "game")
+	if err == nil || err.Error() != "escrow account not found" {
+		t.Errorf("Expected escrow account not found, got: %v", err)
+	}
+}
+
+func TestEscrow_PayerCurrency(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.New())
+
+	user, err := service.CreateUser(models.CreateUserRequest{Balance: "1000", Currency: "JPY"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := service.CreateEscrowAccount("tournament-jpy"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	// The first buy-in sets the currency of the pot
+	resp, err := service.HoldInEscrow(context.Background(), "tournament-jpy", models.EscrowRequest{UserID: user.ID, Amount: "300"}, "game")
+	if err != nil || resp.ToBalance != "300" {
+		t.Fatalf("Expected buy-in of 300 JPY, got: %v, %v", resp, err)
+	}
+	account, err := service.GetEscrowAccount("tournament-jpy")
+	if err != nil || account.Currency != "JPY" {
+		t.Errorf("Expected escrow account in JPY, got: %v, %v", account, err)
+	}
+
+	// Later buy-ins in another currency are rejected
+	if _, err := service.HoldInEscrow(context.Background(), "tournament-jpy", models.EscrowRequest{UserID: 1, Amount: "10.00"}, "game"); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected a validation error for a EUR buy-in, got: %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package core
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Balance event types. Every event stores its signed effect on the balance
+// (amount) and the balance after it, so replaying a stream is a running sum.
+const (
+	EventOpened   = "opened"
+	EventCredited = "credited"
+	EventDebited  = "debited"
+	EventAdjusted = "adjusted"
+)
+
+// streamHead is the latest event of a user's balance stream.
+type streamHead struct {
+	version int64
+	balance string
+}
+
+// EnableEventSourcing makes each user's balance event stream the source of
+// truth: transactions are applied on top of the balance at the head of the
+// stream, and users.balance is maintained as a projection of it. Call it
+// before serving requests.
+func (s *TransactionService) EnableEventSourcing() {
+	s.eventSourced = true
+}
+
+// openStream returns the head of the user's event stream, opening the stream
+// with the given balance if the user has none yet. The caller must hold the
+// user's row lock.
+func (s *TransactionService) openStream(tx *sql.Tx, userID int64, balance string, now time.Time) (streamHead, error) {
+	var head streamHead
+	err := tx.QueryRow(
+		`SELECT version, balance FROM balance_events WHERE user_id = $1 ORDER BY version DESC LIMIT 1`,
+		userID,
+	).Scan(&head.version, &head.balance)
+	if err == nil {
+		return head, nil
+	}
+	if err != sql.ErrNoRows {
+		return head, fmt.Errorf("failed to read balance event stream: %w", err)
+	}
+
+	head = streamHead{version: 1, balance: balance}
+	if err := s.appendEvent(tx, userID, head.version, EventOpened, balance, balance, "", now); err != nil {
+		return head, err
+	}
+	return head, nil
+}
+
+// appendEvent appends an event to the user's stream. With the read model
+// enabled the event is also queued in the outbox for the projector.
+func (s *TransactionService) appendEvent(tx *sql.Tx, userID, version int64, eventType, amount, balance, transactionID string, now time.Time) error {
+	query := `INSERT INTO balance_events (user_id, version, type, amount, balance, transaction_id, recorded_at)
+		 VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7)`
+	if s.readModel {
+		query = `WITH event AS (` + query + ` RETURNING id)
+		 INSERT INTO read_model_outbox (event_id) SELECT id FROM event`
+	}
+
+	_, err := tx.Exec(
+		query,
+		userID,
+		version,
+		eventType,
+		amount,
+		balance,
+		transactionID,
+		now,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to append balance event: %w", err)
+	}
+	return nil
+}
+
+// CheckProjections returns the IDs of users whose balance column disagrees
+// with the replay of their event stream.
+func (s *TransactionService) CheckProjections() ([]int64, error) {
+	rows, err := s.db.Query(
+		`SELECT u.id FROM users u
+		 JOIN (SELECT user_id, SUM(amount) AS total FROM balance_events GROUP BY user_id) e ON e.user_id = u.id
+		 WHERE u.balance <> e.total
+		 ORDER BY u.id`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check balance projections: %w", err)
+	}
+	return scanUserIDs(rows)
+}
+
+// RebuildProjections resets the balance column of every user whose column
+// disagrees with the replay of their event stream, returning their IDs.
+func (s *TransactionService) RebuildProjections() ([]int64, error) {
+	rows, err := s.db.Query(
+		`UPDATE users u SET balance = e.total, updated_at = $1
+		 FROM (SELECT user_id, SUM(amount) AS total FROM balance_events GROUP BY user_id) e
+		 WHERE e.user_id = u.id AND u.balance <> e.total
+		 RETURNING u.id`,
+		s.clock.Now(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rebuild balance projections: %w", err)
+	}
+	return scanUserIDs(rows)
+}
+
+func scanUserIDs(rows *sql.Rows) ([]int64, error) {
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan user ID: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read user IDs: %w", err)
+	}
+	return ids, nil
+}
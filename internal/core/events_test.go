@@ -0,0 +1,106 @@
+package core
+
+import (
+	"testing"
+
+	"assignment/internal/clock"
+	"assignment/internal/testutil"
+)
+
+func TestProcessTransaction_AppendsBalanceEvents(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.New())
+
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("win", "10.00"), "game")
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("lose", "30.00"), "game")
+	// Rejected transactions are not events
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("lose", "500.00"), "game")
+
+	rows, err := db.Query(`SELECT version, type, amount, balance FROM balance_events WHERE user_id = 1 ORDER BY version`)
+	if err != nil {
+		t.Fatalf("Failed to query events: %v", err)
+	}
+	defer rows.Close()
+
+	want := []struct {
+		version int64
+		kind    string
+		amount  string
+		balance string
+	}{
+		{1, EventOpened, "100.00", "100.00"},
+		{2, EventCredited, "10.00", "110.00"},
+		{3, EventDebited, "-30.00", "80.00"},
+	}
+	i := 0
+	for ; rows.Next(); i++ {
+		var version int64
+		var kind, amount, balance string
+		if err := rows.Scan(&version, &kind, &amount, &balance); err != nil {
+			t.Fatalf("Failed to scan event: %v", err)
+		}
+		if i >= len(want) {
+			continue
+		}
+		if w := want[i]; version != w.version || kind != w.kind || amount != w.amount || balance != w.balance {
+			t.Errorf("Event %d = (%d, %s, %s, %s), want %+v", i, version, kind, amount, balance, w)
+		}
+	}
+	if i != len(want) {
+		t.Errorf("Expected %d events, got: %d", len(want), i)
+	}
+
+	if _, err := db.Exec(`UPDATE balance_events SET amount = 0 WHERE user_id = 1`); err == nil {
+		t.Error("Expected balance_events to reject updates")
+	}
+}
+
+func TestEventSourcedMode_StreamIsAuthoritative(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.New())
+	service.EnableEventSourcing()
+
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("win", "10.00"), "game")
+
+	// Corrupt the projection behind the service's back
+	if _, err := db.Exec(`UPDATE users SET balance = 999.00 WHERE id = 1`); err != nil {
+		t.Fatalf("Failed to corrupt balance: %v", err)
+	}
+
+	drifted, err := service.CheckProjections()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(drifted) != 1 || drifted[0] != 1 {
+		t.Errorf("Expected user 1 to have drifted, got: %v", drifted)
+	}
+
+	repaired, err := service.RebuildProjections()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(repaired) != 1 || repaired[0] != 1 {
+		t.Errorf("Expected user 1 to be repaired, got: %v", repaired)
+	}
+
+	balance, err := service.GetBalance(1)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if balance.Balance != "110.00" {
+		t.Errorf("Expected rebuilt balance 110.00, got: %s", balance.Balance)
+	}
+
+	// New transactions build on the stream even if the column drifts again
+	if _, err := db.Exec(`UPDATE users SET balance = 0.00 WHERE id = 1`); err != nil {
+		t.Fatalf("Failed to corrupt balance: %v", err)
+	}
+	resp := mustProcess(t, service, 1, testutil.NewTransactionRequest("lose", "60.00"), "game")
+	if resp.Message != "Transaction applied successfully" || resp.Balance != "50.00" {
+		t.Errorf("Expected applied transaction with balance 50.00, got: %s %s", resp.Message, resp.Balance)
+	}
+}
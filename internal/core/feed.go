@@ -0,0 +1,72 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"assignment/internal/models"
+)
+
+// TransactionFilter narrows the transaction feed. Zero fields match every
+// transaction.
+type TransactionFilter struct {
+	UserID     int64
+	SourceType string
+	State      string
+}
+
+// GetTransactionFeed returns a page of the transactions of all users
+// matching filter, newest first, with cursors to the pages around it. It is
+// meant for triage, e.g. finding every account a misfiring provider hit.
+func (s *TransactionService) GetTransactionFeed(filter TransactionFilter, page Page) (*models.TransactionList, error) {
+	page.Newest = true
+
+	var conds []string
+	var args []interface{}
+	add := func(column string, value interface{}) {
+		args = append(args, value)
+		conds = append(conds, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+	if filter.UserID != 0 {
+		add("user_id", filter.UserID)
+	}
+	if filter.SourceType != "" {
+		add("source_type", filter.SourceType)
+	}
+	if filter.State != "" {
+		add("state", filter.State)
+	}
+	cond, order, keyArgs := page.keyset("created_at", "id", len(args)+1)
+	conds = append(conds, cond)
+	args = append(args, keyArgs...)
+
+	rows, err := s.db.Query(
+		fmt.Sprintf(`SELECT id, user_id, transaction_id, state, amount, source_type, applied, created_at
+		 FROM transactions
+		 WHERE %s ORDER BY %s LIMIT $%d`, strings.Join(conds, " AND "), order, len(args)+1),
+		append(args, page.Limit+1)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query transactions: %w", err)
+	}
+	defer rows.Close()
+
+	transactions := []models.Transaction{}
+	for rows.Next() {
+		var t models.Transaction
+		if err := rows.Scan(&t.ID, &t.UserID, &t.TransactionID, &t.State, &t.Amount, &t.SourceType, &t.Applied, &t.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+		transactions = append(transactions, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read transactions: %w", err)
+	}
+
+	transactions, more := trimPage(transactions, page)
+	list := &models.TransactionList{Transactions: transactions}
+	list.NextCursor, list.PrevCursor = pageCursors(page, transactions, func(t models.Transaction) pageKey {
+		return pageKey{t.CreatedAt, t.ID}
+	}, more)
+	return list, nil
+}
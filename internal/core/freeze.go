@@ -0,0 +1,68 @@
+package core
+
+import (
+	"assignment/internal/models"
+)
+
+// Freeze modes. A frozen user, and its wallets, cannot be debited, and with
+// FreezeAll cannot be credited either; balances and history stay readable.
+const (
+	FreezeDebits = "debits"
+	FreezeAll    = "all"
+)
+
+// freezeRejection returns the rejection reason and response message for a
+// transaction of state on an account with the given freeze mode, or empty
+// strings if the freeze lets it through.
+func freezeRejection(frozen, state string) (reason, message string) {
+	if frozen == FreezeAll || (frozen == FreezeDebits && state == "lose") {
+		return ReasonFrozen, "Account frozen"
+	}
+	return "", ""
+}
+
+// FreezeUser freezes a user's debits, and its credits if req.Credits is set,
+// and returns the user. Freezing a frozen user replaces its freeze. The
+// freeze applies from the next transaction.
+func (s *TransactionService) FreezeUser(userID int64, req models.FreezeRequest) (*models.User, error) {
+	mode := FreezeDebits
+	if req.Credits {
+		mode = FreezeAll
+	}
+
+	now := s.clock.Now()
+	user, err := scanUser("update", s.db.QueryRow(
+		`UPDATE users SET frozen = $1, frozen_reason = NULLIF($2, ''), frozen_at = $3, updated_at = $3
+		 WHERE id = $4 AND kind = $5 AND deleted_at IS NULL RETURNING `+userColumns,
+		mode,
+		req.Reason,
+		now,
+		userID,
+		AccountUser,
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Printf("User frozen: userID=%d, mode=%s, reason=%q", userID, mode, req.Reason)
+
+	return user, nil
+}
+
+// UnfreezeUser lifts the freeze of a user, if any, and returns the user.
+func (s *TransactionService) UnfreezeUser(userID int64) (*models.User, error) {
+	user, err := scanUser("update", s.db.QueryRow(
+		`UPDATE users SET frozen = NULL, frozen_reason = NULL, frozen_at = NULL, updated_at = $1
+		 WHERE id = $2 AND kind = $3 AND deleted_at IS NULL RETURNING `+userColumns,
+		s.clock.Now(),
+		userID,
+		AccountUser,
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Printf("User unfrozen: userID=%d", userID)
+
+	return user, nil
+}
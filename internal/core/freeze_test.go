@@ -0,0 +1,77 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"assignment/internal/clock"
+	"assignment/internal/models"
+	"assignment/internal/testutil"
+)
+
+func TestFreezeUser(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.New())
+
+	if _, err := service.CreateWallet(1, "casino"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	user, err := service.FreezeUser(1, models.FreezeRequest{Reason: "fraud investigation"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if user.Frozen != FreezeDebits || user.FrozenReason != "fraud investigation" || user.FrozenAt == nil {
+		t.Errorf("Unexpected frozen user: %+v", user)
+	}
+
+	// Debits are rejected, credits still applied
+	resp := mustProcess(t, service, 1, testutil.NewTransactionRequest("lose", "1.00"), "game")
+	if resp.Message != "Account frozen" || resp.Balance != "100.00" {
+		t.Errorf("Expected frozen rejection at 100.00, got: %+v", resp)
+	}
+	resp = mustProcess(t, service, 1, testutil.NewTransactionRequest("win", "1.00"), "game")
+	if resp.Message != "Transaction applied successfully" {
+		t.Errorf("Expected credit to be applied, got: %+v", resp)
+	}
+	transfer, err := service.Transfer(models.TransferRequest{FromUserID: 1, ToUserID: 2, Amount: "1.00"}, "game")
+	if err != nil || transfer.Message != "Account frozen" {
+		t.Errorf("Expected transfer from a frozen user to be rejected, got: %v, %v", transfer, err)
+	}
+	// Wallets share the freeze of their owner
+	wallet, err := service.ProcessWalletTransaction(context.Background(), 1, "casino", testutil.NewTransactionRequest("win", "1.00"), "game")
+	if err != nil || wallet.Message != "Transaction applied successfully" {
+		t.Errorf("Expected wallet credit to be applied, got: %v, %v", wallet, err)
+	}
+	wallet, err = service.ProcessWalletTransaction(context.Background(), 1, "casino", testutil.NewTransactionRequest("lose", "1.00"), "game")
+	if err != nil || wallet.Message != "Account frozen" {
+		t.Errorf("Expected wallet debit to be rejected, got: %v, %v", wallet, err)
+	}
+
+	// Freezing credits too
+	if _, err := service.FreezeUser(1, models.FreezeRequest{Credits: true}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	transfer, err = service.Transfer(models.TransferRequest{FromUserID: 2, ToUserID: 1, Amount: "1.00"}, "game")
+	if err != nil || transfer.Message != "Account frozen" {
+		t.Errorf("Expected transfer to a fully frozen user to be rejected, got: %v, %v", transfer, err)
+	}
+
+	user, err = service.UnfreezeUser(1)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if user.Frozen != "" || user.FrozenAt != nil {
+		t.Errorf("Expected user unfrozen, got: %+v", user)
+	}
+	resp = mustProcess(t, service, 1, testutil.NewTransactionRequest("lose", "1.00"), "game")
+	if resp.Message != "Transaction applied successfully" || resp.Balance != "100.00" {
+		t.Errorf("Expected debit applied at 100.00, got: %+v", resp)
+	}
+
+	if _, err := service.FreezeUser(999, models.FreezeRequest{}); err == nil || err.Error() != "user not found" {
+		t.Errorf("Expected user not found, got: %v", err)
+	}
+}
@@ -0,0 +1,181 @@
+package core
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"assignment/internal/models"
+)
+
+// Reasons a transaction breaks its account's hash chain.
+const (
+	// ChainHashMismatch: the row no longer hashes to its stored hash, i.e. it
+	// was edited
+	ChainHashMismatch = "hash_mismatch"
+	// ChainBrokenLink: the row does not follow the previous row, i.e. rows
+	// were deleted, inserted or reordered
+	ChainBrokenLink = "broken_link"
+	// ChainMissingHash: the row has no hash although an earlier one does
+	ChainMissingHash = "missing_hash"
+)
+
+// keyedHashPrefix marks the hashes keyed with the hash chain key.
+const keyedHashPrefix = "hmac-sha256:"
+
+// MinHashChainKeyLength is the minimum length in bytes of a hash chain key.
+const MinHashChainKeyLength = 32
+
+// ErrHashChainKeyRequired is returned when verifying a chain of keyed hashes
+// without the hash chain key.
+var ErrHashChainKeyRequired = errors.New("hash chain key not configured")
+
+// SetHashChainKey keys the hashes of the transactions applied from now on
+// with key, which must be kept outside the database, e.g. in a secret
+// manager. Call it before serving requests.
+//
+// Without a key the chain is a plain SHA-256 chain: it reveals rows edited,
+// deleted or reordered by hand, but whoever can write to the database can
+// also recompute the hashes of the rows after an edit and pass verification.
+// With a key, rewriting the chain needs the key as well, so the chain holds
+// against anyone with only database access, e.g. a DBA, SQL injection or a
+// doctored backup. It does not hold against anyone with the key, and it
+// cannot reveal the latest transactions being removed: the head of the chain
+// has to be kept elsewhere for that (see VerifyHashChain).
+func (s *TransactionService) SetHashChainKey(key []byte) error {
+	if len(key) < MinHashChainKeyLength {
+		return fmt.Errorf("invalid hash chain key: must be at least %d bytes", MinHashChainKeyLength)
+	}
+	s.chainKey = key
+	return nil
+}
+
+// chainTimeLayout formats created_at as stored: a TIMESTAMP keeps the wall
+// clock, to the microsecond, without a zone.
+const chainTimeLayout = "2006-01-02T15:04:05.000000"
+
+// chainHash is the hash of an applied transaction chained to prevHash, the
+// hash of the account's previous transaction ("" for the first): an
+// HMAC-SHA256 with key, marked with keyedHashPrefix, or a SHA-256 if key is
+// nil. It covers what the transaction did to the balance, as stored, but not
+// its client transaction ID, which retention may anonymize.
+func chainHash(key []byte, prevHash string, userID int64, state, amount, sourceType string, createdAt time.Time, transferID string) string {
+	data := []byte(prevHash + "\n" +
+		strconv.FormatInt(userID, 10) + "|" +
+		state + "|" +
+		amount + "|" +
+		sourceType + "|" +
+		createdAt.Format(chainTimeLayout) + "|" +
+		transferID)
+	if key == nil {
+		sum := sha256.Sum256(data)
+		return hex.EncodeToString(sum[:])
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return keyedHashPrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// previousChainHash returns the hash of the latest transaction of an account
+// locked by lockBalance, which keeps its transactions in chain order; "" if
+// it has none or only unchained ones.
+func previousChainHash(tx *sql.Tx, userID int64) (string, error) {
+	var hash string
+	err := tx.QueryRow(
+		`SELECT COALESCE(hash, '') FROM transactions WHERE user_id = $1 ORDER BY id DESC LIMIT 1`,
+		userID,
+	).Scan(&hash)
+	if err != nil && err != sql.ErrNoRows {
+		return "", fmt.Errorf("failed to read hash chain: %w", err)
+	}
+	return hash, nil
+}
+
+// VerifyHashChain recomputes the hash chain of an account's transactions and
+// reports the first one that breaks it. Transactions recorded before the
+// chain was introduced carry no hash and are counted as unchained; the chain
+// starts at the first hashed transaction, which must link to the stored hash
+// of the transaction before it, if any, so rows cannot be made to look
+// unchained by removing their hashes. Its link to purged predecessors cannot
+// be checked. With a hash chain key, unkeyed hashes recorded before it was
+// set are counted as unchained too, and an unkeyed hash after a keyed one
+// breaks the chain; keyed hashes cannot be verified without the key.
+func (s *TransactionService) VerifyHashChain(userID int64) (*models.HashChainReport, error) {
+	var exists bool
+	if err := s.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`, userID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to look up user: %w", err)
+	}
+	if !exists {
+		return nil, ErrUserNotFound
+	}
+
+	rows, err := s.db.Query(
+		`SELECT id, transaction_id, state, amount, source_type, created_at, COALESCE(transfer_id, ''),
+		        COALESCE(prev_hash, ''), hash
+		 FROM transactions WHERE user_id = $1 ORDER BY id`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transactions: %w", err)
+	}
+	defer rows.Close()
+
+	report := &models.HashChainReport{UserID: userID, Valid: true}
+	started := false
+	var last string
+	for rows.Next() {
+		var id int64
+		var transactionID, state, amount, sourceType, transferID, prevHash string
+		var hash sql.NullString
+		var createdAt time.Time
+		if err := rows.Scan(&id, &transactionID, &state, &amount, &sourceType, &createdAt, &transferID, &prevHash, &hash); err != nil {
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+
+		keyed := strings.HasPrefix(hash.String, keyedHashPrefix)
+		if keyed && s.chainKey == nil {
+			return nil, ErrHashChainKeyRequired
+		}
+		var key []byte
+		if keyed {
+			key = s.chainKey
+		}
+
+		reason := ""
+		switch {
+		case !started && (!hash.Valid || (s.chainKey != nil && !keyed)):
+			report.Unchained++
+			last = hash.String
+			continue
+		case !hash.Valid:
+			reason = ChainMissingHash
+		case (started || report.Unchained > 0) && prevHash != last:
+			reason = ChainBrokenLink
+		case s.chainKey != nil && !keyed:
+			reason = ChainHashMismatch
+		case chainHash(key, prevHash, userID, state, amount, sourceType, createdAt, transferID) != hash.String:
+			reason = ChainHashMismatch
+		}
+		if reason != "" {
+			report.Valid = false
+			report.Break = &models.HashChainBreak{ID: id, TransactionID: transactionID, Reason: reason}
+			break
+		}
+		started = true
+		last = hash.String
+		report.Verified++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read transactions: %w", err)
+	}
+	if started {
+		report.Head = last
+	}
+	return report, nil
+}
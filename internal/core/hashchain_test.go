@@ -0,0 +1,198 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"assignment/internal/clock"
+	"assignment/internal/models"
+	"assignment/internal/testutil"
+)
+
+func TestChainHash(t *testing.T) {
+	at := time.Date(2024, 1, 15, 12, 0, 0, 123456000, time.UTC)
+	hash := chainHash(nil, "", 1, "win", "10.00", "game", at, "")
+	if len(hash) != 64 {
+		t.Fatalf("Expected a hex SHA-256 hash, got: %q", hash)
+	}
+	if chainHash(nil, "", 1, "win", "10.00", "game", at, "") != hash {
+		t.Error("Expected the hash to be deterministic")
+	}
+	for name, other := range map[string]string{
+		"previous hash": chainHash(nil, hash, 1, "win", "10.00", "game", at, ""),
+		"user":          chainHash(nil, "", 2, "win", "10.00", "game", at, ""),
+		"state":         chainHash(nil, "", 1, "lose", "10.00", "game", at, ""),
+		"amount":        chainHash(nil, "", 1, "win", "10.01", "game", at, ""),
+		"source type":   chainHash(nil, "", 1, "win", "10.00", "server", at, ""),
+		"time":          chainHash(nil, "", 1, "win", "10.00", "game", at.Add(time.Microsecond), ""),
+		"transfer":      chainHash(nil, "", 1, "win", "10.00", "game", at, "t-1"),
+	} {
+		if other == hash {
+			t.Errorf("Expected the %s to change the hash", name)
+		}
+	}
+
+	key := []byte(strings.Repeat("k", MinHashChainKeyLength))
+	keyed := chainHash(key, "", 1, "win", "10.00", "game", at, "")
+	if !strings.HasPrefix(keyed, keyedHashPrefix) || len(keyed) != len(keyedHashPrefix)+64 {
+		t.Fatalf("Expected a marked hex HMAC-SHA256, got: %q", keyed)
+	}
+	otherKey := []byte(strings.Repeat("x", MinHashChainKeyLength))
+	if chainHash(otherKey, "", 1, "win", "10.00", "game", at, "") == keyed {
+		t.Error("Expected the key to change the hash")
+	}
+}
+
+func TestSetHashChainKey(t *testing.T) {
+	service := NewTransactionService(nil, clock.New())
+	if err := service.SetHashChainKey([]byte("short")); err == nil {
+		t.Error("Expected a short key to be refused")
+	}
+	if err := service.SetHashChainKey([]byte(strings.Repeat("k", MinHashChainKeyLength))); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+}
+
+func TestVerifyHashChain(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.New())
+
+	for i, amount := range []string{"10.00", "5.5", "2.25"} {
+		req := models.TransactionRequest{State: "win", Amount: amount, TransactionID: fmt.Sprintf("chain-%d", i+1)}
+		if _, err := service.ProcessTransaction(1, req, "game"); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+	if _, err := service.Transfer(models.TransferRequest{FromUserID: 1, ToUserID: 2, Amount: "1.00", TransferID: "chain-transfer"}, "server"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	for userID, verified := range map[int64]int{1: 4, 2: 1} {
+		report, err := service.VerifyHashChain(userID)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if !report.Valid || report.Verified != verified || report.Break != nil || report.Head == "" {
+			t.Errorf("Expected a valid chain of %d transactions for user %d, got: %+v", verified, userID, report)
+		}
+	}
+
+	// Anonymizing transaction IDs keeps the chain intact
+	if _, err := db.Exec(`UPDATE transactions SET transaction_id = 'anon-' || id WHERE transaction_id = 'chain-1'`); err != nil {
+		t.Fatalf("Failed to anonymize: %v", err)
+	}
+	if report, err := service.VerifyHashChain(1); err != nil || !report.Valid {
+		t.Errorf("Expected anonymized rows to verify, got: %+v, %v", report, err)
+	}
+
+	// An edited amount
+	if _, err := db.Exec(`UPDATE transactions SET amount = 500 WHERE transaction_id = 'chain-2'`); err != nil {
+		t.Fatalf("Failed to tamper: %v", err)
+	}
+	report, err := service.VerifyHashChain(1)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if report.Valid || report.Break == nil || report.Break.TransactionID != "chain-2" || report.Break.Reason != ChainHashMismatch || report.Verified != 1 {
+		t.Errorf("Expected a hash mismatch at chain-2, got: %+v %+v", report, report.Break)
+	}
+
+	// A deleted row
+	if _, err := db.Exec(`DELETE FROM transactions WHERE transaction_id = 'chain-2'`); err != nil {
+		t.Fatalf("Failed to tamper: %v", err)
+	}
+	report, err = service.VerifyHashChain(1)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if report.Valid || report.Break == nil || report.Break.TransactionID != "chain-3" || report.Break.Reason != ChainBrokenLink {
+		t.Errorf("Expected a broken link at chain-3, got: %+v %+v", report, report.Break)
+	}
+
+	if _, err := service.VerifyHashChain(999); err == nil || err.Error() != "user not found" {
+		t.Errorf("Expected user not found, got: %v", err)
+	}
+}
+
+func TestVerifyHashChain_Unchained(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.New())
+	process := func(id string) {
+		if _, err := service.ProcessTransaction(1, models.TransactionRequest{State: "win", Amount: "1.00", TransactionID: id}, "game"); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+
+	// A row from before the chain was introduced
+	process("old-1")
+	if _, err := db.Exec(`UPDATE transactions SET hash = NULL, prev_hash = NULL WHERE transaction_id = 'old-1'`); err != nil {
+		t.Fatalf("Failed to unchain: %v", err)
+	}
+	process("new-1")
+	process("new-2")
+
+	report, err := service.VerifyHashChain(1)
+	if err != nil || !report.Valid || report.Verified != 2 || report.Unchained != 1 {
+		t.Errorf("Expected 2 transactions verified after 1 unchained, got: %+v, %v", report, err)
+	}
+
+	// Removing the hash of the oldest chained row does not move the start
+	// of the chain past it unnoticed
+	if _, err := db.Exec(`UPDATE transactions SET hash = NULL WHERE transaction_id = 'new-1'`); err != nil {
+		t.Fatalf("Failed to tamper: %v", err)
+	}
+	report, err = service.VerifyHashChain(1)
+	if err != nil || report.Valid || report.Break == nil || report.Break.TransactionID != "new-2" || report.Break.Reason != ChainBrokenLink {
+		t.Errorf("Expected a broken link at new-2, got: %+v, %v", report, err)
+	}
+}
+
+func TestVerifyHashChain_Keyed(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.New())
+	if _, err := service.ProcessTransaction(1, models.TransactionRequest{State: "win", Amount: "1.00", TransactionID: "keyed-1"}, "game"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	service.SetHashChainKey([]byte(strings.Repeat("k", MinHashChainKeyLength)))
+	for _, id := range []string{"keyed-2", "keyed-3"} {
+		if _, err := service.ProcessTransaction(1, models.TransactionRequest{State: "win", Amount: "1.00", TransactionID: id}, "game"); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+
+	// Hashes from before the key are not trusted
+	report, err := service.VerifyHashChain(1)
+	if err != nil || !report.Valid || report.Verified != 2 || report.Unchained != 1 {
+		t.Errorf("Expected 2 keyed transactions verified, got: %+v, %v", report, err)
+	}
+
+	// Rewriting a row and recomputing its hash without the key is revealed:
+	// the row counts as unchained, but the next one no longer links to it
+	var prevHash, amount string
+	var createdAt time.Time
+	if err := db.QueryRow(`SELECT prev_hash, amount, created_at FROM transactions WHERE transaction_id = 'keyed-2'`).Scan(&prevHash, &amount, &createdAt); err != nil {
+		t.Fatalf("Failed to read row: %v", err)
+	}
+	forged := chainHash(nil, prevHash, 1, "win", "500.00", "game", createdAt, "")
+	if _, err := db.Exec(`UPDATE transactions SET amount = 500, hash = $1 WHERE transaction_id = 'keyed-2'`, forged); err != nil {
+		t.Fatalf("Failed to tamper: %v", err)
+	}
+	report, err = service.VerifyHashChain(1)
+	if err != nil || report.Valid || report.Break == nil || report.Break.TransactionID != "keyed-3" || report.Break.Reason != ChainBrokenLink {
+		t.Errorf("Expected a broken link at keyed-3, got: %+v, %v", report, err)
+	}
+
+	// Keyed hashes cannot be verified without the key
+	if _, err := NewTransactionService(db, clock.New()).VerifyHashChain(1); !errors.Is(err, ErrHashChainKeyRequired) {
+		t.Errorf("Expected ErrHashChainKeyRequired, got: %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+package core
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"assignment/internal/models"
+	"assignment/internal/validation"
+)
+
+// MaxHistorySnapshots bounds the size of a single history page.
+const MaxHistorySnapshots = 1000
+
+// GetBalanceHistory returns the balance snapshots recorded for a user between
+// from and to (inclusive). The first entry is the snapshot in effect at from,
+// so the balance at any moment in the window can be read off the result.
+// A zero to means now and a zero from means 24 hours before to. Long
+// histories are cut after MaxHistorySnapshots; see GetBalanceHistoryPage.
+func (s *TransactionService) GetBalanceHistory(userID int64, from, to time.Time) (*models.BalanceHistoryResponse, error) {
+	return s.GetBalanceHistoryPage(userID, from, to, Page{Limit: MaxHistorySnapshots})
+}
+
+// GetBalanceHistoryPage returns a page of the balance history between from
+// and to, with cursors to the pages around it. The snapshot in effect at from
+// leads the first page only. Limits over MaxHistorySnapshots are lowered.
+func (s *TransactionService) GetBalanceHistoryPage(userID int64, from, to time.Time, page Page) (*models.BalanceHistoryResponse, error) {
+	if page.Limit <= 0 || page.Limit > MaxHistorySnapshots {
+		page.Limit = MaxHistorySnapshots
+	}
+	if to.IsZero() {
+		to = s.clock.Now()
+	}
+	if from.IsZero() {
+		from = to.Add(-24 * time.Hour)
+	}
+	if from.After(to) {
+		return nil, validation.Errorf("from", "invalid time range: from must not be after to")
+	}
+
+	// With the read model enabled, history is served from its tables only
+	existsQuery := `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`
+	table, tiebreak := "balance_snapshots", "id"
+	if s.readModel {
+		existsQuery = `SELECT EXISTS (SELECT 1 FROM read_balances WHERE user_id = $1)`
+		table, tiebreak = "read_balance_history", "version"
+	}
+
+	var exists bool
+	err := s.db.QueryRow(existsQuery, userID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up user: %w", err)
+	}
+	if !exists {
+		return nil, ErrUserNotFound
+	}
+
+	from, to = from.UTC(), to.UTC()
+	history := &models.BalanceHistoryResponse{
+		UserID:    userID,
+		From:      from,
+		To:        to,
+		Snapshots: []models.BalanceSnapshot{},
+	}
+
+	cond, order, keyArgs := page.keyset("recorded_at", tiebreak, 4)
+	args := append([]interface{}{userID, from, to}, keyArgs...)
+	rows, err := s.db.Query(
+		fmt.Sprintf(`SELECT balance, transaction_id, recorded_at, %s FROM %s
+		 WHERE user_id = $1 AND recorded_at > $2 AND recorded_at <= $3 AND %s
+		 ORDER BY %s LIMIT $%d`, tiebreak, table, cond, order, len(args)+1),
+		append(args, page.Limit+1)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query balance history: %w", err)
+	}
+	defer rows.Close()
+
+	type keyedSnapshot struct {
+		snapshot models.BalanceSnapshot
+		id       int64
+	}
+	var snapshots []keyedSnapshot
+	for rows.Next() {
+		var k keyedSnapshot
+		var txID sql.NullString
+		if err := rows.Scan(&k.snapshot.Balance, &txID, &k.snapshot.RecordedAt, &k.id); err != nil {
+			return nil, fmt.Errorf("failed to scan balance snapshot: %w", err)
+		}
+		k.snapshot.TransactionID = txID.String
+		snapshots = append(snapshots, k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read balance history: %w", err)
+	}
+
+	snapshots, more := trimPage(snapshots, page)
+	history.NextCursor, history.PrevCursor = pageCursors(page, snapshots, func(k keyedSnapshot) pageKey {
+		return pageKey{k.snapshot.RecordedAt, k.id}
+	}, more)
+
+	// Snapshot in effect at the start of the window, on the first page
+	if page.Cursor == nil || page.Cursor.Before && !more {
+		var opening models.BalanceSnapshot
+		var openingTxID sql.NullString
+		err = s.db.QueryRow(
+			fmt.Sprintf(`SELECT balance, transaction_id, recorded_at FROM %s
+			 WHERE user_id = $1 AND recorded_at <= $2
+			 ORDER BY recorded_at DESC, %s DESC LIMIT 1`, table, tiebreak),
+			userID,
+			from,
+		).Scan(&opening.Balance, &openingTxID, &opening.RecordedAt)
+		if err == nil {
+			opening.TransactionID = openingTxID.String
+			history.Snapshots = append(history.Snapshots, opening)
+		} else if err != sql.ErrNoRows {
+			return nil, fmt.Errorf("failed to get opening snapshot: %w", err)
+		}
+	}
+	for _, k := range snapshots {
+		history.Snapshots = append(history.Snapshots, k.snapshot)
+	}
+
+	return history, nil
+}
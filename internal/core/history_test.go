@@ -0,0 +1,129 @@
+package core
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"assignment/internal/clock"
+	"assignment/internal/cursor"
+	"assignment/internal/models"
+	"assignment/internal/testutil"
+)
+
+func TestGetBalanceHistory(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	start := time.Date(2024, 2, 1, 10, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(start)
+	service := NewTransactionService(db, clk)
+
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("win", "10.00"), "game")
+	clk.Advance(time.Hour)
+	second := testutil.NewTransactionRequest("lose", "30.00")
+	mustProcess(t, service, 1, second, "game")
+	clk.Advance(time.Hour)
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("win", "5.00"), "game")
+
+	// Window starting between the first and second change
+	history, err := service.GetBalanceHistory(1, start.Add(30*time.Minute), start.Add(90*time.Minute))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(history.Snapshots) != 2 {
+		t.Fatalf("Expected 2 snapshots, got: %d", len(history.Snapshots))
+	}
+	if history.Snapshots[0].Balance != "110.00" {
+		t.Errorf("Expected opening balance 110.00, got: %s", history.Snapshots[0].Balance)
+	}
+	if history.Snapshots[1].Balance != "80.00" || history.Snapshots[1].TransactionID != second.TransactionID {
+		t.Errorf("Unexpected second snapshot: %+v", history.Snapshots[1])
+	}
+}
+
+func TestGetBalanceHistory_BeforeAnyChange(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.NewFake(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)))
+
+	history, err := service.GetBalanceHistory(2, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(history.Snapshots) != 1 || history.Snapshots[0].Balance != "50.00" {
+		t.Errorf("Expected only the baseline snapshot of 50.00, got: %+v", history.Snapshots)
+	}
+}
+
+func TestGetBalanceHistory_UserNotFound(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.New())
+
+	if _, err := service.GetBalanceHistory(999, time.Time{}, time.Time{}); err == nil || err.Error() != "user not found" {
+		t.Errorf("Expected 'user not found', got: %v", err)
+	}
+}
+
+func TestGetBalanceHistoryPage(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	start := time.Date(2024, 2, 1, 10, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(start)
+	service := NewTransactionService(db, clk)
+	for i := 0; i < 5; i++ {
+		clk.Advance(time.Minute)
+		mustProcess(t, service, 1, testutil.NewTransactionRequest("win", "1.00"), "game")
+	}
+	to := clk.Now()
+
+	// The first page leads with the opening snapshot
+	first, err := service.GetBalanceHistoryPage(1, start, to, Page{Limit: 2})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if balances(first) != "100.00 101.00 102.00" || first.NextCursor == "" || first.PrevCursor != "" {
+		t.Fatalf("Unexpected first page: %s %+v", balances(first), first)
+	}
+
+	second := mustHistoryPage(t, service, start, to, first.NextCursor)
+	if balances(second) != "103.00 104.00" || second.NextCursor == "" || second.PrevCursor == "" {
+		t.Fatalf("Unexpected second page: %s %+v", balances(second), second)
+	}
+	last := mustHistoryPage(t, service, start, to, second.NextCursor)
+	if balances(last) != "105.00" || last.NextCursor != "" {
+		t.Fatalf("Unexpected last page: %s %+v", balances(last), last)
+	}
+
+	// Going back from the second page ends on the first
+	back := mustHistoryPage(t, service, start, to, second.PrevCursor)
+	if balances(back) != balances(first) || back.PrevCursor != "" {
+		t.Errorf("Expected to be back on the first page, got: %s %+v", balances(back), back)
+	}
+}
+
+func mustHistoryPage(t *testing.T, service *TransactionService, from, to time.Time, token string) *models.BalanceHistoryResponse {
+	t.Helper()
+	c, err := cursor.Parse(token)
+	if err != nil {
+		t.Fatalf("Expected a valid cursor, got: %v", err)
+	}
+	history, err := service.GetBalanceHistoryPage(1, from, to, Page{Cursor: c, Limit: 2})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	return history
+}
+
+func balances(history *models.BalanceHistoryResponse) string {
+	var b []string
+	for _, s := range history.Snapshots {
+		b = append(b, s.Balance)
+	}
+	return strings.Join(b, " ")
+}
@@ -0,0 +1,233 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"assignment/internal/fixture"
+	"assignment/internal/models"
+	"assignment/internal/utils"
+	"assignment/internal/validation"
+)
+
+// MaxImportRows bounds the number of data rows in a single import.
+const MaxImportRows = 10000
+
+var (
+	requiredImportColumns = []string{"user_id", "transaction_id", "state", "amount", "source_type"}
+	optionalImportColumns = []string{"created_at"}
+)
+
+// ImportCSV applies historical transactions from a CSV document. The first
+// line is a header naming the columns user_id, transaction_id, state,
+// amount, source_type and optionally created_at (RFC 3339). Every row goes
+// through the same validation and idempotency checks as the API, so an
+// import can safely be re-run; the report has one result per data row.
+// Rows are applied to the current balance, so a created_at before the
+// user's latest transaction is refused. An error is only returned when the
+// document itself is invalid or cannot be read, e.g. because r stopped at a
+// size limit; the latter wraps the error of r.
+//
+// Rows are processed with ctx, so rows of a source type requiring signed
+// requests are only applied when ctx records that the import was signed
+// with that source type's secret (see WithVerifiedSignature).
+func (s *TransactionService) ImportCSV(ctx context.Context, r io.Reader) (*models.ImportReport, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, validation.Errorf("file", "invalid CSV: missing header row")
+	}
+	if err != nil && readFailure(err) {
+		return nil, fmt.Errorf("failed to read CSV: %w", err)
+	}
+	if err != nil {
+		return nil, validation.Errorf("file", "invalid CSV: %v", err)
+	}
+
+	index := map[string]int{}
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range requiredImportColumns {
+		if _, ok := index[name]; !ok {
+			return nil, validation.Errorf("file", "invalid CSV: missing column %q", name)
+		}
+	}
+	for name := range index {
+		if !contains(requiredImportColumns, name) && !contains(optionalImportColumns, name) {
+			return nil, validation.Errorf("file", "invalid CSV: unknown column %q", name)
+		}
+	}
+
+	report := &models.ImportReport{
+		Counts:  map[string]int{},
+		Results: []models.ImportRowResult{},
+	}
+
+	// Line 1 is the header
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil && readFailure(err) {
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		if report.Total == MaxImportRows {
+			return nil, validation.Errorf("file", "invalid CSV: more than %d rows", MaxImportRows)
+		}
+		report.Total++
+
+		var result models.ImportRowResult
+		if err != nil {
+			result = models.ImportRowResult{Status: "error", Message: err.Error()}
+		} else {
+			result = s.importRow(ctx, record, index)
+		}
+		result.Row = line
+
+		report.Counts[result.Status]++
+		report.Results = append(report.Results, result)
+	}
+
+	return report, nil
+}
+
+func (s *TransactionService) importRow(ctx context.Context, record []string, index map[string]int) models.ImportRowResult {
+	field := func(name string) string {
+		i, ok := index[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	req := models.TransactionRequest{
+		State:         field("state"),
+		Amount:        field("amount"),
+		TransactionID: field("transaction_id"),
+	}
+	result := models.ImportRowResult{TransactionID: req.TransactionID}
+
+	userID, err := utils.ValidateUserID(field("user_id"))
+	if err != nil {
+		result.Status, result.Message = "error", err.Error()
+		return result
+	}
+	result.UserID = userID
+
+	if req.TransactionID == "" {
+		result.Status, result.Message = "error", "transaction_id is required"
+		return result
+	}
+	if err := ValidateTransactionID(req.TransactionID); err != nil {
+		result.Status, result.Message = "error", err.Error()
+		return result
+	}
+
+	at := s.clock.Now()
+	if v := field("created_at"); v != "" {
+		if at, err = time.Parse(time.RFC3339, v); err != nil {
+			result.Status, result.Message = "error", "invalid created_at: must be an RFC 3339 timestamp"
+			return result
+		}
+		at = at.UTC()
+		ctx = context.WithValue(ctx, backdatedKey{}, true)
+	}
+
+	response, err := s.processTransaction(ctx, userID, AccountUser, req, field("source_type"), at, nil, nil)
+	if err != nil {
+		result.Status, result.Message = "error", err.Error()
+		return result
+	}
+
+	// Rejected rows are reported with their rejection reason
+	switch err := TransactionError(response); {
+	case err == nil:
+		result.Status = "applied"
+	case errors.Is(err, ErrDuplicateTransaction):
+		result.Status = "duplicate"
+	default:
+		result.Status = response.Reason
+	}
+	result.Message = response.Message
+	result.Balance = response.Balance
+	return result
+}
+
+// readFailure reports whether an error of a csv.Reader comes from the
+// underlying reader rather than from malformed CSV.
+func readFailure(err error) bool {
+	var parseErr *csv.ParseError
+	return !errors.As(err, &parseErr)
+}
+
+// backdatedKey marks the context of a transaction given its created_at by
+// the caller, which checkBackdated refuses to place before the history.
+type backdatedKey struct{}
+
+// checkBackdated refuses a backdated transaction at a time before the
+// user's latest transaction; applied to the current balance, its recorded
+// balance would contradict the history after it. The caller must hold the
+// user's row lock.
+func checkBackdated(ctx context.Context, tx *sql.Tx, userID int64, at time.Time) error {
+	if backdated, _ := ctx.Value(backdatedKey{}).(bool); !backdated {
+		return nil
+	}
+	var latest sql.NullTime
+	if err := tx.QueryRow(`SELECT MAX(created_at) FROM transactions WHERE user_id = $1`, userID).Scan(&latest); err != nil {
+		return fmt.Errorf("failed to get latest transaction: %w", err)
+	}
+	if latest.Valid && at.Before(latest.Time) {
+		return validation.Errorf("created_at", "invalid created_at: before the user's latest transaction at %s", latest.Time.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyFixture applies the historical transactions of a seeding fixture's
+// users, in order, like an import. Transactions applied before are ignored
+// as duplicates, so it is safe to run on every start. Any other outcome
+// than applied is an error: the fixture contradicts itself, e.g. a loss
+// exceeding the balance.
+func (s *TransactionService) ApplyFixture(f *fixture.Fixture) (int, error) {
+	applied := 0
+	for _, u := range f.Users {
+		for _, t := range u.Transactions {
+			at := t.CreatedAt
+			if at.IsZero() {
+				at = s.clock.Now()
+			}
+			req := models.TransactionRequest{State: t.State, Amount: string(t.Amount), TransactionID: t.TransactionID}
+			response, err := s.processTransaction(trustedContext, u.ID, AccountUser, req, t.SourceType, at.UTC(), nil, nil)
+			if err != nil {
+				return applied, fmt.Errorf("failed to apply fixture transaction %s of user %d: %w", t.TransactionID, u.ID, err)
+			}
+			switch transactionOutcome(response, nil) {
+			case OutcomeDuplicate:
+			case OutcomeApplied:
+				applied++
+			default:
+				return applied, fmt.Errorf("fixture transaction %s of user %d not applied: %s", t.TransactionID, u.ID, response.Message)
+			}
+		}
+	}
+	return applied, nil
+}
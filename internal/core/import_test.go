@@ -0,0 +1,111 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"assignment/internal/clock"
+	"assignment/internal/testutil"
+)
+
+func TestImportCSV(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.NewFake(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)))
+
+	csv := "transaction_id,user_id,state,amount,source_type,created_at\n" +
+		"legacy-1,1,win,10.00,payment,2023-06-01T10:00:00Z\n" +
+		"legacy-2,2,lose,60.00,game,\n" +
+		"legacy-3,abc,win,1.00,game,\n" +
+		"legacy-1,1,win,10.00,payment,2023-06-01T10:00:00Z\n"
+
+	report, err := service.ImportCSV(context.Background(), strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	want := []string{"applied", "insufficient_funds", "error", "duplicate"}
+	if len(report.Results) != len(want) {
+		t.Fatalf("Expected %d results, got: %d", len(want), len(report.Results))
+	}
+	for i, status := range want {
+		if report.Results[i].Status != status {
+			t.Errorf("Row %d: expected status %s, got: %s (%s)", report.Results[i].Row, status, report.Results[i].Status, report.Results[i].Message)
+		}
+	}
+
+	var createdAt time.Time
+	if err := db.QueryRow(`SELECT created_at FROM transactions WHERE transaction_id = 'legacy-1'`).Scan(&createdAt); err != nil {
+		t.Fatalf("Failed to read imported transaction: %v", err)
+	}
+	if want := time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC); !createdAt.Equal(want) {
+		t.Errorf("Expected historical created_at %v, got: %v", want, createdAt)
+	}
+}
+
+func TestImportCSV_Backdated(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.NewFake(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)))
+
+	csv := "transaction_id,user_id,state,amount,source_type,created_at\n" +
+		"backdated-1,1,win,10.00,payment,2023-06-01T10:00:00Z\n" +
+		"backdated-2,1,win,10.00,payment,2023-05-01T10:00:00Z\n" +
+		"backdated-3,1,win,10.00,payment,2023-06-01T10:00:00Z\n" +
+		"backdated-4,1,win,10.00,payment,\n" +
+		"backdated-5,1,win,10.00,payment,2023-07-01T10:00:00Z\n"
+
+	report, err := service.ImportCSV(context.Background(), strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	// Rows may not be placed before the user's latest transaction
+	want := []string{"applied", "error", "applied", "applied", "error"}
+	for i, status := range want {
+		if report.Results[i].Status != status {
+			t.Errorf("Row %d: expected status %s, got: %s (%s)", report.Results[i].Row, status, report.Results[i].Status, report.Results[i].Message)
+		}
+	}
+	if got := report.Results[1].Message; got != "invalid created_at: before the user's latest transaction at 2023-06-01T10:00:00Z" {
+		t.Errorf("Unexpected message: %s", got)
+	}
+}
+
+func TestImportCSV_ReadFailure(t *testing.T) {
+	service := NewTransactionService(nil, clock.New())
+
+	readErr := errors.New("request body too large")
+	r := io.MultiReader(strings.NewReader("user_id,transaction_id,state,amount,source_type\n"), iotest.ErrReader(readErr))
+	if _, err := service.ImportCSV(context.Background(), r); !errors.Is(err, readErr) || errors.Is(err, ErrValidation) {
+		t.Errorf("Expected the read error, got: %v", err)
+	}
+}
+
+func TestImportCSV_InvalidHeader(t *testing.T) {
+	service := NewTransactionService(nil, clock.New())
+
+	tests := []struct {
+		name string
+		csv  string
+	}{
+		{"empty", ""},
+		{"missing column", "user_id,transaction_id,state,amount\n"},
+		{"unknown column", "user_id,transaction_id,state,amount,source_type,currency\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := service.ImportCSV(context.Background(), strings.NewReader(tt.csv)); err == nil {
+				t.Error("Expected error, got none")
+			}
+		})
+	}
+}
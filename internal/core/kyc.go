@@ -0,0 +1,128 @@
+package core
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"assignment/internal/models"
+	"assignment/internal/money"
+	"assignment/internal/utils"
+	"assignment/internal/validation"
+)
+
+// KYC levels. Users registered through the API start unverified; seeded and
+// pre-existing users are fully verified.
+const (
+	KYCUnverified = "unverified"
+	KYCBasic      = "basic"
+	KYCFull       = "full"
+)
+
+// KYCLimit bounds the amounts a user may transact, in the user's currency.
+// Zero means unlimited.
+type KYCLimit struct {
+	MaxSingle float64
+	MaxDaily  float64
+}
+
+// KYCLimits are the limits of each KYC level. The daily limit bounds the
+// total of the user's applied transactions, wins and losses alike, per UTC
+// calendar day.
+var KYCLimits = map[string]KYCLimit{
+	KYCUnverified: {MaxSingle: 100, MaxDaily: 100},
+	KYCBasic:      {MaxSingle: 1000, MaxDaily: 10000},
+	KYCFull:       {},
+}
+
+// kycRejection checks a transaction of amount on an account locked by
+// lockBalance against the limits of its KYC level. The daily limit covers
+// all accounts of the owner except moves between them. It returns the
+// rejection reason and message of the limit the transaction would exceed,
+// or empty strings if it is within them.
+func kycRejection(tx *sql.Tx, account lockedAccount, amount float64, now time.Time) (reason, message string, err error) {
+	return checkKYCLimits(account.kycLevel, account.currency, amount, func() (int64, error) {
+		return spentToday(tx, account.ownerID, account.currency, now)
+	})
+}
+
+// checkKYCLimits checks a transaction of amount in currency against the
+// limits of kycLevel, calling spent for the minor units the owner transacted
+// so far today only if the level has a daily limit.
+func checkKYCLimits(kycLevel, currency string, amount float64, spent func() (int64, error)) (reason, message string, err error) {
+	limit := KYCLimits[kycLevel]
+	if limit.MaxSingle > 0 && minorUnits(amount, currency) > minorUnits(limit.MaxSingle, currency) {
+		return ReasonKYCSingleLimit, "KYC single transaction limit exceeded", nil
+	}
+	if limit.MaxDaily == 0 {
+		return "", "", nil
+	}
+
+	total, err := spent()
+	if err != nil {
+		return "", "", err
+	}
+	if total+minorUnits(amount, currency) > minorUnits(limit.MaxDaily, currency) {
+		return ReasonKYCDailyLimit, "KYC daily limit exceeded", nil
+	}
+	return "", "", nil
+}
+
+// spentToday returns the minor units of currency the accounts of an owner
+// transacted on the UTC day of now, except moves between them.
+func spentToday(tx *sql.Tx, ownerID int64, currency string, now time.Time) (int64, error) {
+	day := now.UTC().Truncate(24 * time.Hour)
+	var total string
+	err := tx.QueryRow(
+		`SELECT COALESCE(SUM(t.amount), 0) FROM transactions t
+		 WHERE t.user_id IN (SELECT id FROM users WHERE id = $1 OR owner_id = $1)
+		   AND t.applied AND t.created_at >= $2 AND t.created_at < $3
+		   AND NOT EXISTS (SELECT 1 FROM transfers tr WHERE tr.transfer_id = t.transfer_id AND tr.internal)`,
+		ownerID,
+		day,
+		day.Add(24*time.Hour),
+	).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum daily transactions: %w", err)
+	}
+	spent, err := utils.ParseAmount(total)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse daily total: %w", err)
+	}
+	return minorUnits(spent, currency), nil
+}
+
+// minorUnits converts an amount of currency to whole minor units, e.g. cents
+// or yen, so amounts compare exactly. Amounts whose currency is not known
+// yet, "", are counted in the smallest minor unit of any currency.
+func minorUnits(amount float64, currency string) int64 {
+	if currency == "" {
+		return int64(math.Round(amount * math.Pow10(money.MaxExponent)))
+	}
+	return money.ToMinor(amount, currency)
+}
+
+// SetKYCLevel changes the KYC level of a user and returns the user. The new
+// limits apply from the next transaction.
+func (s *TransactionService) SetKYCLevel(userID int64, req models.KYCLevelRequest) (*models.User, error) {
+	if _, ok := KYCLimits[req.KYCLevel]; !ok {
+		return nil, validation.Errorf("kyc_level", "invalid KYC level: must be 'unverified', 'basic' or 'full'")
+	}
+
+	user, err := scanUser("update", s.db.QueryRow(
+		`UPDATE users SET kyc_level = $1, updated_at = $2
+		 WHERE id = $3 AND kind = $4 AND deleted_at IS NULL RETURNING `+userColumns,
+		req.KYCLevel,
+		s.clock.Now(),
+		userID,
+		AccountUser,
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Printf("User KYC level changed: userID=%d, level=%s", userID, req.KYCLevel)
+
+	return user, nil
+}
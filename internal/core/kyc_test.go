@@ -0,0 +1,66 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"assignment/internal/clock"
+	"assignment/internal/models"
+	"assignment/internal/testutil"
+)
+
+func TestKYCLimits(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	clk := clock.NewFake(time.Date(2024, 1, 15, 22, 0, 0, 0, time.UTC))
+	service := NewTransactionService(db, clk)
+
+	user, err := service.CreateUser(models.CreateUserRequest{Balance: "500.00"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if user.KYCLevel != KYCUnverified {
+		t.Fatalf("Expected new users to be unverified, got: %s", user.KYCLevel)
+	}
+
+	resp := mustProcess(t, service, user.ID, testutil.NewTransactionRequest("lose", "100.01"), "game")
+	if resp.Message != "KYC single transaction limit exceeded" {
+		t.Errorf("Expected single limit, got: %s", resp.Message)
+	}
+
+	// Wins and losses both count towards the daily limit
+	mustProcess(t, service, user.ID, testutil.NewTransactionRequest("lose", "70.00"), "game")
+	mustProcess(t, service, user.ID, testutil.NewTransactionRequest("win", "30.00"), "game")
+	resp = mustProcess(t, service, user.ID, testutil.NewTransactionRequest("lose", "0.01"), "game")
+	if resp.Message != "KYC daily limit exceeded" || resp.Balance != "460.00" {
+		t.Errorf("Expected daily limit at 460.00, got: %+v", resp)
+	}
+	transfer, err := service.Transfer(models.TransferRequest{FromUserID: 1, ToUserID: user.ID, Amount: "1.00"}, "game")
+	if err != nil || transfer.Message != "KYC daily limit exceeded" {
+		t.Errorf("Expected transfer to be limited, got: %v, %v", transfer, err)
+	}
+
+	var reason string
+	if err := db.QueryRow(`SELECT reason FROM transaction_rejections WHERE user_id = $1 ORDER BY id DESC LIMIT 1`, user.ID).Scan(&reason); err != nil {
+		t.Fatalf("Failed to read rejection: %v", err)
+	}
+	if reason != "kyc_daily_limit" {
+		t.Errorf("Expected reason kyc_daily_limit, got: %s", reason)
+	}
+
+	// The limit resets at midnight UTC, and upgrading lifts it
+	clk.Advance(2 * time.Hour)
+	mustProcess(t, service, user.ID, testutil.NewTransactionRequest("lose", "100.00"), "game")
+	if _, err := service.SetKYCLevel(user.ID, models.KYCLevelRequest{KYCLevel: KYCFull}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	resp = mustProcess(t, service, user.ID, testutil.NewTransactionRequest("lose", "250.00"), "game")
+	if resp.Message != "Transaction applied successfully" {
+		t.Errorf("Expected fully verified user to be unlimited, got: %s", resp.Message)
+	}
+
+	if _, err := service.SetKYCLevel(user.ID, models.KYCLevelRequest{KYCLevel: "gold"}); err == nil {
+		t.Error("Expected error for invalid KYC level")
+	}
+}
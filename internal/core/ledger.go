@@ -0,0 +1,62 @@
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"assignment/internal/models"
+)
+
+// ReadLedger returns up to limit applied transactions positioned after the
+// given (txid, id) cursor and created at or after since, in stream order.
+//
+// Transactions are ordered by the database transaction that inserted them
+// rather than by row ID, and only rows whose inserting transaction is older
+// than every transaction still in progress are returned. A row that commits
+// late therefore can never appear behind a cursor a reader has already passed.
+// A long-running database transaction holds the stream back until it ends.
+func (s *TransactionService) ReadLedger(afterTxID, afterID int64, since time.Time, limit int) ([]models.LedgerEntry, error) {
+	rows, err := s.db.Query(
+		`SELECT txid, id, user_id, transaction_id, state, amount, source_type, applied, created_at
+		 FROM transactions
+		 WHERE applied
+		   AND (txid, id) > ($1, $2)
+		   AND txid < txid_snapshot_xmin(txid_current_snapshot())
+		   AND created_at >= $3
+		 ORDER BY txid, id
+		 LIMIT $4`,
+		afterTxID,
+		afterID,
+		since,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ledger: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.LedgerEntry{}
+	for rows.Next() {
+		var e models.LedgerEntry
+		err := rows.Scan(
+			&e.TxID,
+			&e.ID,
+			&e.UserID,
+			&e.TransactionID,
+			&e.State,
+			&e.Amount,
+			&e.SourceType,
+			&e.Applied,
+			&e.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan ledger entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read ledger: %w", err)
+	}
+
+	return entries, nil
+}
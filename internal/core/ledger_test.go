@@ -0,0 +1,84 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"assignment/internal/clock"
+	"assignment/internal/testutil"
+)
+
+func TestReadLedger(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	clk := clock.NewFake(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	service := NewTransactionService(db, clk)
+
+	first := testutil.NewTransactionRequest("win", "10.00")
+	mustProcess(t, service, 1, first, "game")
+	clk.Advance(time.Hour)
+	second := testutil.NewTransactionRequest("lose", "5.00")
+	mustProcess(t, service, 2, second, "payment")
+	// Rejected transactions are not part of the ledger
+	mustProcess(t, service, 3, testutil.NewTransactionRequest("lose", "1.00"), "game")
+
+	entries, err := service.ReadLedger(0, 0, time.Time{}, 10)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got: %d", len(entries))
+	}
+	if entries[0].TransactionID != first.TransactionID || entries[1].TransactionID != second.TransactionID {
+		t.Errorf("Expected entries in commit order, got: %s, %s", entries[0].TransactionID, entries[1].TransactionID)
+	}
+
+	// Resuming from the first entry's cursor returns only the second
+	entries, err = service.ReadLedger(entries[0].TxID, entries[0].ID, time.Time{}, 10)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(entries) != 1 || entries[0].TransactionID != second.TransactionID {
+		t.Errorf("Expected only %s after the cursor, got: %v", second.TransactionID, entries)
+	}
+
+	// Starting from a timestamp skips earlier transactions
+	entries, err = service.ReadLedger(0, 0, clk.Now(), 10)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(entries) != 1 || entries[0].TransactionID != second.TransactionID {
+		t.Errorf("Expected only %s since %v, got: %v", second.TransactionID, clk.Now(), entries)
+	}
+
+	// A transaction still in progress holds back the stream
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`SELECT txid_current()`); err != nil {
+		t.Fatalf("Failed to assign transaction ID: %v", err)
+	}
+
+	third := testutil.NewTransactionRequest("win", "1.00")
+	mustProcess(t, service, 1, third, "game")
+
+	entries, err = service.ReadLedger(0, 0, time.Time{}, 10)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("Expected 2 entries while a transaction is open, got: %d", len(entries))
+	}
+
+	tx.Rollback()
+	entries, err = service.ReadLedger(0, 0, time.Time{}, 10)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Errorf("Expected 3 entries once the transaction ended, got: %d", len(entries))
+	}
+}
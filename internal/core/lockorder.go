@@ -0,0 +1,80 @@
+package core
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// lockAccounts locks accounts, given by ID with their kind, for the rest of
+// tx and returns them as lockBalance does.
+//
+// Transactions touching several accounts must take their row locks through
+// here. Locks are taken in one canonical order, ascending ID, so two
+// transactions sharing accounts queue up behind each other rather than each
+// holding a lock the other waits for. The owners of wallets, which
+// lockBalance locks too, take their place in that order.
+func (s *TransactionService) lockAccounts(tx *sql.Tx, kinds map[int64]string, now time.Time) (map[int64]lockedAccount, error) {
+	owners, err := walletOwners(tx, kinds)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := map[int64]lockedAccount{}
+	for _, id := range lockOrder(kinds, owners) {
+		kind, ok := kinds[id]
+		if !ok {
+			if _, err := tx.Exec(`SELECT 1 FROM users WHERE id = $1 FOR SHARE`, id); err != nil {
+				return nil, fmt.Errorf("failed to lock wallet owner: %w", err)
+			}
+			continue
+		}
+		if accounts[id], err = s.lockBalance(tx, id, kind, now); err != nil {
+			return nil, err
+		}
+	}
+	return accounts, nil
+}
+
+// walletOwners returns the owners of the wallets among kinds. Owners never
+// change, so they are read before anything is locked.
+func walletOwners(tx *sql.Tx, kinds map[int64]string) ([]int64, error) {
+	var owners []int64
+	for id, kind := range kinds {
+		if kind != AccountWallet {
+			continue
+		}
+		var ownerID int64
+		err := tx.QueryRow(`SELECT owner_id FROM users WHERE id = $1 AND kind = $2`, id, kind).Scan(&ownerID)
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get wallet owner: %w", err)
+		}
+		owners = append(owners, ownerID)
+	}
+	return owners, nil
+}
+
+// lockOrder returns the IDs of the accounts and owners to lock, without
+// duplicates, in ascending order.
+func lockOrder(kinds map[int64]string, owners []int64) []int64 {
+	seen := map[int64]bool{}
+	var ids []int64
+	add := func(id int64) {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	for id := range kinds {
+		add(id)
+	}
+	for _, id := range owners {
+		add(id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
@@ -0,0 +1,150 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"assignment/internal/clock"
+	"assignment/internal/models"
+	"assignment/internal/testutil"
+)
+
+func TestLockOrder(t *testing.T) {
+	kinds := map[int64]string{9: AccountUser, 4: AccountWallet, 7: AccountWallet}
+	if got, want := lockOrder(kinds, []int64{2, 2}), []int64{2, 4, 7, 9}; !reflect.DeepEqual(got, want) {
+		t.Errorf("lockOrder() = %v, want %v", got, want)
+	}
+	// An owner that is also an account is locked once
+	if got, want := lockOrder(map[int64]string{5: AccountUser, 6: AccountWallet}, []int64{5}), []int64{5, 6}; !reflect.DeepEqual(got, want) {
+		t.Errorf("lockOrder() = %v, want %v", got, want)
+	}
+}
+
+// hammer runs every operation rounds times, all concurrently, and fails the
+// test on any error; a deadlock surfaces as one from Postgres' deadlock
+// detector.
+func hammer(t *testing.T, rounds int, ops ...func(round int) error) {
+	t.Helper()
+	var wg sync.WaitGroup
+	errs := make(chan error, rounds*len(ops))
+	for round := 0; round < rounds; round++ {
+		for _, op := range ops {
+			wg.Add(1)
+			go func(op func(int) error, round int) {
+				defer wg.Done()
+				errs <- op(round)
+			}(op, round)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Expected no error, got: %v", err)
+		}
+	}
+}
+
+// newVerifiedUser creates a user exempt from KYC limits, so no transaction
+// of the tests below is rejected for them.
+func newVerifiedUser(t *testing.T, service *TransactionService) int64 {
+	t.Helper()
+	user, err := service.CreateUser(models.CreateUserRequest{Balance: "500.00"})
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if _, err := service.SetKYCLevel(user.ID, models.KYCLevelRequest{KYCLevel: KYCFull}); err != nil {
+		t.Fatalf("Failed to verify user: %v", err)
+	}
+	return user.ID
+}
+
+func TestLockAccounts_TransferRing(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.New())
+	ids := []int64{newVerifiedUser(t, service), newVerifiedUser(t, service), newVerifiedUser(t, service)}
+
+	// Each transfer locks one user the next one also needs: a cycle if locks
+	// were taken sender first. Single-user transactions interleave with them.
+	transfer := func(from, to int64) func(int) error {
+		return func(round int) error {
+			_, err := service.Transfer(models.TransferRequest{FromUserID: from, ToUserID: to, Amount: "1.00", TransferID: fmt.Sprintf("ring-%d-%d-%d", from, to, round)}, "game")
+			return err
+		}
+	}
+	hammer(t, 30,
+		transfer(ids[0], ids[1]),
+		transfer(ids[1], ids[2]),
+		transfer(ids[2], ids[0]),
+		transfer(ids[2], ids[1]),
+		func(round int) error {
+			_, err := service.ProcessTransaction(ids[1], models.TransactionRequest{State: "win", Amount: "1.00", TransactionID: fmt.Sprintf("ring-win-%d", round)}, "game")
+			return err
+		},
+	)
+
+	for i, want := range []string{"500.00", "560.00", "470.00"} {
+		balance, err := service.GetBalance(ids[i])
+		if err != nil || balance.Balance != want {
+			t.Errorf("Expected user %d balance %s, got: %+v, %v", ids[i], want, balance, err)
+		}
+	}
+	if diverged, err := service.CheckProjections(); err != nil || len(diverged) != 0 {
+		t.Errorf("Expected balances to match event streams, got: %v, %v", diverged, err)
+	}
+}
+
+func TestLockAccounts_WalletsAndEscrow(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.New())
+	owner, other := newVerifiedUser(t, service), newVerifiedUser(t, service)
+	for _, name := range []string{"casino", "sports"} {
+		if _, err := service.CreateWallet(owner, name); err != nil {
+			t.Fatalf("Failed to create wallet: %v", err)
+		}
+	}
+	if _, err := service.CreateEscrowAccount("lock-order"); err != nil {
+		t.Fatalf("Failed to create escrow account: %v", err)
+	}
+
+	// Moves lock the owner along with the wallets, while transfers and
+	// escrow holds lock the owner's main account and the other user
+	move := func(from, to string) func(int) error {
+		return func(round int) error {
+			_, err := service.MoveBetweenWallets(context.Background(), owner, models.WalletMoveRequest{FromWalletID: from, ToWalletID: to, Amount: "1.00", MoveID: fmt.Sprintf("move-%s-%s-%d", from, to, round)}, "game")
+			return err
+		}
+	}
+	hammer(t, 20,
+		move(MainWallet, "casino"),
+		move("casino", "sports"),
+		move("sports", MainWallet),
+		func(round int) error {
+			_, err := service.Transfer(models.TransferRequest{FromUserID: other, ToUserID: owner, Amount: "1.00", TransferID: fmt.Sprintf("in-%d", round)}, "game")
+			return err
+		},
+		func(round int) error {
+			_, err := service.Transfer(models.TransferRequest{FromUserID: owner, ToUserID: other, Amount: "1.00", TransferID: fmt.Sprintf("out-%d", round)}, "game")
+			return err
+		},
+		func(round int) error {
+			_, err := service.HoldInEscrow(context.Background(), "lock-order", models.EscrowRequest{UserID: owner, Amount: "1.00", TransferID: fmt.Sprintf("hold-%d", round)}, "game")
+			return err
+		},
+		func(round int) error {
+			_, err := service.ReleaseFromEscrow(context.Background(), "lock-order", models.EscrowRequest{UserID: other, Amount: "1.00", TransferID: fmt.Sprintf("release-%d", round)}, "game")
+			return err
+		},
+	)
+
+	if diverged, err := service.CheckProjections(); err != nil || len(diverged) != 0 {
+		t.Errorf("Expected balances to match event streams, got: %v, %v", diverged, err)
+	}
+}
@@ -1,24 +1,151 @@
 package core
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"time"
 
+	"assignment/internal/clock"
 	"assignment/internal/models"
+	"assignment/internal/money"
+	"assignment/internal/ulid"
 	"assignment/internal/utils"
 )
 
+// Account kinds. Escrow accounts are owned by the system and only move
+// funds through transfers; wallets are additional accounts of a user.
+const (
+	AccountUser   = "user"
+	AccountEscrow = "escrow"
+	AccountWallet = "wallet"
+)
+
+// User statuses. Suspended users cannot take part in new transactions;
+// closed users cannot take part in any, not even replays of old ones.
+const (
+	UserActive    = "active"
+	UserSuspended = "suspended"
+	UserClosed    = "closed"
+)
+
 type TransactionService struct {
-	db *sql.DB
+	db            *sql.DB
+	clock         clock.Clock
+	eventSourced  bool
+	readModel     bool
+	maxBalance    float64
+	clipCredits   bool
+	notifications bool
+	// opsLargeTransaction is the amount from which transactions are
+	// alerted to ops, 0 for none
+	opsLargeTransaction float64
+	// maxTransactionAmount is the largest amount a transaction may move,
+	// 0 for no limit
+	maxTransactionAmount float64
+	txIDPolicy           txIDPolicy
+	amlRules             AMLRules
+	observers            []func(outcome string)
+	beforeHooks          []func(userID int64, req models.TransactionRequest, sourceType string) error
+	afterHooks           []func(transaction models.Transaction, balance string)
+	validators           []Validator
+	sourceCapabilities   map[string]SourceCapabilities
+	logger               *log.Logger
+	// store keeps users and transactions instead of db if set
+	store Store
+	// chainKey keys the hash chain of transactions, see SetHashChainKey
+	chainKey []byte
 }
 
-func NewTransactionService(db *sql.DB) *TransactionService {
-	return &TransactionService{db: db}
+// NewTransactionService returns a service storing into db, reading the
+// current time from clk, or the system time if it is nil, customized by
+// opts.
+func NewTransactionService(db *sql.DB, clk clock.Clock, opts ...Option) *TransactionService {
+	s := &TransactionService{db: db, clock: clk, logger: log.Default()}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.clock == nil {
+		s.clock = clock.New()
+	}
+	return s
 }
 
+// maxGeneratedIDAttempts bounds retries after a generated transaction ID
+// collides with a stored one.
+const maxGeneratedIDAttempts = 3
+
+// ProcessTransaction applies a transaction. Callers that cannot generate
+// stable idempotency keys may leave TransactionID empty; the server then
+// assigns a ULID, which is returned in the response. Such transactions are
+// not idempotent across retries.
 func (s *TransactionService) ProcessTransaction(userID int64, req models.TransactionRequest, sourceType string) (*models.TransactionResponse, error) {
+	return s.ProcessTransactionContext(context.Background(), userID, req, sourceType)
+}
+
+// ProcessTransactionContext is ProcessTransaction bounded by ctx. If ctx is
+// done before the transaction commits, it is rolled back and ctx's error is
+// returned, so nothing is applied on behalf of a caller that gave up.
+func (s *TransactionService) ProcessTransactionContext(ctx context.Context, userID int64, req models.TransactionRequest, sourceType string) (*models.TransactionResponse, error) {
+	return s.processAccountTransaction(ctx, userID, AccountUser, req, sourceType, nil)
+}
+
+// processAccountTransaction applies a transaction to an account of the given
+// kind, assigning a transaction ID if req has none. A non-nil ifMatch is the
+// precondition of ProcessTransactionIf.
+func (s *TransactionService) processAccountTransaction(ctx context.Context, userID int64, kind string, req models.TransactionRequest, sourceType string, ifMatch func(tag string) bool) (resp *models.TransactionResponse, err error) {
+	if len(s.observers) > 0 {
+		defer func() {
+			outcome := transactionOutcome(resp, err)
+			for _, observe := range s.observers {
+				observe(outcome)
+			}
+		}()
+	}
+	defer func() {
+		if err = contextError(ctx, err); err != nil {
+			resp = nil
+		}
+	}()
+
+	if req.TransactionID != "" {
+		return s.processTransaction(ctx, userID, kind, req, sourceType, s.clock.Now(), nil, ifMatch)
+	}
+
+	for attempt := 0; attempt < maxGeneratedIDAttempts; attempt++ {
+		id, err := ulid.New(s.clock.Now())
+		if err != nil {
+			return nil, &RetryableError{fmt.Errorf("failed to generate transaction ID: %w", err)}
+		}
+		req.TransactionID = id
+
+		resp, err := s.processTransaction(ctx, userID, kind, req, sourceType, s.clock.Now(), nil, ifMatch)
+		if err != nil {
+			return nil, err
+		}
+		// A generated ID must never be mistaken for a retry of another transaction
+		if !resp.Replayed {
+			return resp, nil
+		}
+	}
+	return nil, &RetryableError{errors.New("failed to generate a unique transaction ID")}
+}
+
+// processTransaction applies a transaction to an account of the given kind
+// as if it happened at the given time, which is recorded as its created_at.
+// When msg is set, its offset is committed in the same database transaction
+// as the outcome. When ifMatch is set, a new transaction is only applied if
+// it accepts the tag of the locked balance.
+func (s *TransactionService) processTransaction(ctx context.Context, userID int64, kind string, req models.TransactionRequest, sourceType string, now time.Time, msg *models.QueueMessage, ifMatch func(tag string) bool) (*models.TransactionResponse, error) {
+	for _, before := range s.beforeHooks {
+		if err := before(userID, req, sourceType); err != nil {
+			return nil, err
+		}
+	}
+
 	// Validate inputs
 	if err := utils.ValidateSourceType(sourceType); err != nil {
 		return nil, err
@@ -26,27 +153,55 @@ func (s *TransactionService) ProcessTransaction(userID int64, req models.Transac
 	if err := utils.ValidateState(req.State); err != nil {
 		return nil, err
 	}
-	if err := utils.ValidateAmount(req.Amount); err != nil {
+	amount, err := s.ValidateTransactionAmount(req.Amount)
+	if err != nil {
 		return nil, err
 	}
-
-	amount, err := utils.ParseAmount(req.Amount)
-	if err != nil {
+	if err := s.checkSourceCapabilities(ctx, req, amount, sourceType); err != nil {
 		return nil, err
 	}
+	if s.store != nil {
+		return s.processStoredTransaction(ctx, userID, kind, req, sourceType, amount, now, msg, ifMatch)
+	}
 
-	// Start database transaction
-	tx, err := s.db.Begin()
+	// Start database transaction, rolled back if ctx is done before it commits
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
+	// Redelivered broker messages are ignored outright
+	if msg != nil {
+		delivered, err := lockConsumerOffset(tx, msg, now)
+		if err != nil {
+			return nil, err
+		}
+		if delivered {
+			var balance string
+			err = tx.QueryRow(`SELECT balance FROM users WHERE id = $1`, userID).Scan(&balance)
+			if err == sql.ErrNoRows {
+				return nil, ErrUserNotFound
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to get user balance: %w", err)
+			}
+			return &models.TransactionResponse{
+				UserID:        userID,
+				TransactionID: req.TransactionID,
+				Balance:       balance,
+				Message:       "Duplicate message ignored",
+				Reason:        ReasonDuplicate,
+			}, nil
+		}
+	}
+
 	// Check if transaction already exists
 	var existingTransaction models.Transaction
 	var existingBalance string
+	var storedResponse []byte
 	err = tx.QueryRow(
-		`SELECT id, user_id, transaction_id, state, amount, source_type, applied, created_at 
+		`SELECT id, user_id, transaction_id, state, amount, source_type, applied, created_at, response 
 		 FROM transactions WHERE transaction_id = $1`,
 		req.TransactionID,
 	).Scan(
@@ -58,46 +213,67 @@ func (s *TransactionService) ProcessTransaction(userID int64, req models.Transac
 		&existingTransaction.SourceType,
 		&existingTransaction.Applied,
 		&existingTransaction.CreatedAt,
+		&storedResponse,
 	)
 
 	if err == nil {
-		// Transaction already exists - return duplicate response
+		// Transaction already exists - replay the response given then
+		var status string
 		err = tx.QueryRow(
-			`SELECT balance FROM users WHERE id = $1 FOR UPDATE`,
+			`SELECT balance, status FROM users WHERE id = $1 FOR UPDATE`,
 			existingTransaction.UserID,
-		).Scan(&existingBalance)
+		).Scan(&existingBalance, &status)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get user balance: %w", err)
 		}
-
-		tx.Commit()
-		return &models.TransactionResponse{
+		response := &models.TransactionResponse{
 			UserID:        existingTransaction.UserID,
 			TransactionID: existingTransaction.TransactionID,
 			Balance:       existingBalance,
-			Message:       "Duplicate transaction ignored",
-		}, nil
+			Message:       "User closed",
+			Reason:        ReasonUserClosed,
+		}
+		if status != UserClosed {
+			response.Message, response.Reason, response.Replayed = "Duplicate transaction ignored", ReasonDuplicate, true
+			// Transactions applied before responses were stored get the
+			// generic answer
+			if storedResponse != nil {
+				response = &models.TransactionResponse{Replayed: true}
+				if err := json.Unmarshal(storedResponse, response); err != nil {
+					return nil, fmt.Errorf("failed to decode stored response: %w", err)
+				}
+			}
+		}
+
+		if err := commitConsumerOffset(tx, msg, now); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		return response, nil
 	} else if err != sql.ErrNoRows {
 		return nil, fmt.Errorf("failed to check existing transaction: %w", err)
 	}
 
-	// Get user with lock for update
-	var currentBalance string
-	err = tx.QueryRow(
-		`SELECT balance FROM users WHERE id = $1 FOR UPDATE`,
-		userID,
-	).Scan(&currentBalance)
-	if err == sql.ErrNoRows {
-		return nil, errors.New("user not found")
-	}
+	account, err := s.lockBalance(tx, userID, kind, now)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user balance: %w", err)
+		return nil, err
 	}
-
-	// Parse current balance
-	currentBalanceFloat, err := utils.ParseAmount(currentBalance)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse current balance: %w", err)
+	if err := checkBackdated(ctx, tx, userID, now); err != nil {
+		return nil, err
+	}
+	currentBalanceFloat := account.balance
+	if ifMatch != nil && !ifMatch(BalanceTag(userID, money.Format(currentBalanceFloat, account.currency))) {
+		return nil, ErrPreconditionFailed
+	}
+	// The amount is recorded with the decimals of the account's currency
+	if err := money.ValidateAmount(req.Amount, account.currency); err != nil {
+		return nil, err
+	}
+	req.Amount = money.Format(amount, account.currency)
+	if err := s.validate(userID, account, req, sourceType, now); err != nil {
+		return nil, err
 	}
 
 	// Calculate new balance
@@ -108,67 +284,342 @@ func (s *TransactionService) ProcessTransaction(userID int64, req models.Transac
 		newBalance = currentBalanceFloat - amount
 	}
 
-	// Check if balance would go negative
-	if newBalance < 0 {
-		tx.Commit()
+	// Suspended and closed users are rejected before the balance check, so
+	// the status is read under the same lock as the balance
+	reason, message := statusRejection(account.status)
+	if reason == "" {
+		reason, message = freezeRejection(account.frozen, req.State)
+	}
+	if reason == "" {
+		if reason, message, err = kycRejection(tx, account, amount, now); err != nil {
+			return nil, err
+		}
+	}
+	applied := "Transaction applied successfully"
+	if reason == "" && req.State == "win" {
+		var credit float64
+		if credit, reason, message = s.capCredit(userID, account, amount, s.clipCredits); reason == "" && credit != amount {
+			// Clipped: the transaction is recorded with the credited amount
+			req.Amount = money.Format(credit, account.currency)
+			newBalance = currentBalanceFloat + credit
+			applied = "Transaction clipped to maximum balance"
+		}
+	}
+	if reason == "" && newBalance < 0 {
+		reason, message = ReasonInsufficientFunds, "Insufficient funds"
+	}
+	if reason != "" {
+		if err := recordRejection(tx, userID, req, sourceType, reason, now); err != nil {
+			return nil, err
+		}
+
+		if err := commitConsumerOffset(tx, msg, now); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
 		return &models.TransactionResponse{
 			UserID:        userID,
 			TransactionID: req.TransactionID,
-			Balance:       utils.FormatBalance(currentBalanceFloat),
-			Message:       "Insufficient funds",
+			Balance:       money.Format(currentBalanceFloat, account.currency),
+			Message:       message,
+			Reason:        reason,
 		}, nil
 	}
 
+	newBalanceStr := money.Format(newBalance, account.currency)
+	transaction, err := s.applyTransaction(tx, userID, account.head, req, sourceType, newBalanceStr, "", now)
+	if err != nil {
+		return nil, err
+	}
+	response := &models.TransactionResponse{
+		UserID:        userID,
+		TransactionID: req.TransactionID,
+		Balance:       newBalanceStr,
+		Message:       applied,
+	}
+	if err := storeResponse(tx, response); err != nil {
+		return nil, err
+	}
+
+	if err := commitConsumerOffset(tx, msg, now); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.logger.Printf("Transaction processed: userID=%d, transactionID=%s, state=%s, amount=%s, newBalance=%s",
+		userID, req.TransactionID, req.State, req.Amount, newBalanceStr)
+
+	for _, after := range s.afterHooks {
+		after(transaction, newBalanceStr)
+	}
+
+	return response, nil
+}
+
+// storeResponse keeps the response to a transaction just applied in tx, to
+// be replayed verbatim to retries of its transaction ID. Rejections are not
+// stored: a retry of a rejected transaction is evaluated anew.
+func storeResponse(tx *sql.Tx, response *models.TransactionResponse) error {
+	body, err := json.Marshal(response)
+	if err != nil {
+		return fmt.Errorf("failed to encode response: %w", err)
+	}
+	if _, err := tx.Exec(`UPDATE transactions SET response = $1 WHERE transaction_id = $2`, body, response.TransactionID); err != nil {
+		return fmt.Errorf("failed to store response: %w", err)
+	}
+	return nil
+}
+
+// lockedAccount is an account locked by lockBalance.
+type lockedAccount struct {
+	balance  float64
+	head     streamHead
+	status   string
+	kycLevel string
+	// frozen is the freeze mode, FreezeDebits or FreezeAll, "" if the
+	// account is not frozen
+	frozen string
+	// maxBalance is the effective maximum balance, 0 for none
+	maxBalance float64
+	// ownerID is the user owning a wallet, or the account itself
+	ownerID int64
+	// currency is the currency of the account's amounts
+	currency string
+}
+
+// lockBalance locks an account of the given kind for the rest of tx and
+// returns its current balance, event stream head, status, KYC level, freeze
+// mode and maximum balance. In event-sourced mode the balance is read from
+// the stream rather than the users table. Wallets share the status, KYC level
+// and freeze of their owner, which is locked too.
+func (s *TransactionService) lockBalance(tx *sql.Tx, userID int64, kind string, now time.Time) (lockedAccount, error) {
+	var ownerStatus, ownerKYCLevel, ownerFrozen string
+	if kind == AccountWallet {
+		// Owners are created before their wallets, so locking the owner
+		// first keeps locks in ascending ID order; lockAccounts has already
+		// locked it when several accounts are locked
+		err := tx.QueryRow(
+			`SELECT o.status, o.kyc_level, COALESCE(o.frozen, '') FROM users o JOIN users w ON w.owner_id = o.id
+			 WHERE w.id = $1 AND o.deleted_at IS NULL FOR SHARE OF o`,
+			userID,
+		).Scan(&ownerStatus, &ownerKYCLevel, &ownerFrozen)
+		if err == sql.ErrNoRows {
+			return lockedAccount{}, ErrUserNotFound
+		}
+		if err != nil {
+			return lockedAccount{}, fmt.Errorf("failed to lock wallet owner: %w", err)
+		}
+	}
+
+	var currentBalance, status, kycLevel, frozen, currency string
+	var userMaxBalance sql.NullString
+	var ownerID int64
+	err := tx.QueryRow(
+		`SELECT balance, status, kyc_level, COALESCE(frozen, ''), max_balance, COALESCE(owner_id, id), currency FROM users
+		 WHERE id = $1 AND kind = $2 AND deleted_at IS NULL FOR UPDATE`,
+		userID,
+		kind,
+	).Scan(&currentBalance, &status, &kycLevel, &frozen, &userMaxBalance, &ownerID, &currency)
+	if err == sql.ErrNoRows {
+		return lockedAccount{}, ErrUserNotFound
+	}
+	if err != nil {
+		return lockedAccount{}, fmt.Errorf("failed to get user balance: %w", err)
+	}
+	if kind == AccountWallet {
+		status, kycLevel, frozen = ownerStatus, ownerKYCLevel, ownerFrozen
+	}
+
+	// Every applied transaction is also appended to the user's event stream;
+	// in event-sourced mode the stream, not the column, is authoritative
+	head, err := s.openStream(tx, userID, currentBalance, now)
+	if err != nil {
+		return lockedAccount{}, err
+	}
+	if s.eventSourced {
+		currentBalance = head.balance
+	}
+
+	balance, err := utils.ParseBalance(currentBalance)
+	if err != nil {
+		return lockedAccount{}, fmt.Errorf("failed to parse current balance: %w", err)
+	}
+	account := lockedAccount{balance: balance, head: head, status: status, kycLevel: kycLevel, frozen: frozen, ownerID: ownerID, currency: currency}
+	if kind != AccountEscrow {
+		account.maxBalance = s.maxBalance
+		if userMaxBalance.Valid {
+			if account.maxBalance, err = utils.ParseAmount(userMaxBalance.String); err != nil {
+				return lockedAccount{}, fmt.Errorf("failed to parse max balance: %w", err)
+			}
+		}
+	}
+	return account, nil
+}
+
+// statusRejection returns the rejection reason and response message for a
+// new transaction on an account with the given status, or empty strings if
+// the account may transact.
+func statusRejection(status string) (reason, message string) {
+	switch status {
+	case UserSuspended:
+		return ReasonUserSuspended, "User suspended"
+	case UserClosed:
+		return ReasonUserClosed, "User closed"
+	}
+	return "", ""
+}
+
+// recordRejection records a transaction that was not applied and why.
+func recordRejection(tx *sql.Tx, userID int64, req models.TransactionRequest, sourceType, reason string, now time.Time) error {
+	_, err := tx.Exec(
+		`INSERT INTO transaction_rejections (user_id, transaction_id, state, amount, source_type, reason, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		userID,
+		req.TransactionID,
+		req.State,
+		req.Amount,
+		sourceType,
+		reason,
+		now,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record rejection: %w", err)
+	}
+	return nil
+}
+
+// applyTransaction records a validated transaction against a user locked by
+// lockBalance, moving its balance to newBalance. transferID links the two
+// legs of a transfer and is empty otherwise.
+func (s *TransactionService) applyTransaction(tx *sql.Tx, userID int64, head streamHead, req models.TransactionRequest, sourceType, newBalance, transferID string, now time.Time) (models.Transaction, error) {
 	// Update user balance
-	newBalanceStr := utils.FormatBalance(newBalance)
-	_, err = tx.Exec(
-		`UPDATE users SET balance = $1, updated_at = NOW() WHERE id = $2`,
-		newBalanceStr,
+	_, err := tx.Exec(
+		`UPDATE users SET balance = $1, updated_at = $2 WHERE id = $3`,
+		newBalance,
+		now,
 		userID,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to update user balance: %w", err)
+		return models.Transaction{}, fmt.Errorf("failed to update user balance: %w", err)
 	}
 
-	// Insert transaction record
-	_, err = tx.Exec(
-		`INSERT INTO transactions (user_id, transaction_id, state, amount, source_type, applied) 
-		 VALUES ($1, $2, $3, $4, $5, $6)`,
+	// Insert transaction record, chained to the account's previous one
+	prevHash, err := previousChainHash(tx, userID)
+	if err != nil {
+		return models.Transaction{}, err
+	}
+	var id int64
+	var amount string
+	var createdAt time.Time
+	err = tx.QueryRow(
+		`INSERT INTO transactions (user_id, transaction_id, state, amount, source_type, applied, created_at, transfer_id, prev_hash)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), NULLIF($9, ''))
+		 RETURNING id, amount, created_at`,
 		userID,
 		req.TransactionID,
 		req.State,
 		req.Amount,
 		sourceType,
 		true,
+		now,
+		transferID,
+		prevHash,
+	).Scan(&id, &amount, &createdAt)
+	if err != nil {
+		return models.Transaction{}, fmt.Errorf("failed to insert transaction: %w", err)
+	}
+	// Hash the row as stored, e.g. with its amount rounded to cents
+	hash := chainHash(s.chainKey, prevHash, userID, req.State, amount, sourceType, createdAt, transferID)
+	if _, err := tx.Exec(`UPDATE transactions SET hash = $1 WHERE id = $2`, hash, id); err != nil {
+		return models.Transaction{}, fmt.Errorf("failed to seal transaction: %w", err)
+	}
+
+	// Record the resulting balance for history lookups
+	_, err = tx.Exec(
+		`INSERT INTO balance_snapshots (user_id, balance, transaction_id, recorded_at) VALUES ($1, $2, $3, $4)`,
+		userID,
+		newBalance,
+		req.TransactionID,
+		now,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to insert transaction: %w", err)
+		return models.Transaction{}, fmt.Errorf("failed to record balance snapshot: %w", err)
 	}
 
-	// Commit transaction
-	if err := tx.Commit(); err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	// Signed effect of the transaction on the balance
+	delta, eventType := req.Amount, EventCredited
+	if req.State == "lose" {
+		delta, eventType = "-"+req.Amount, EventDebited
 	}
 
-	log.Printf("Transaction processed: userID=%d, transactionID=%s, state=%s, amount=%s, newBalance=%s",
-		userID, req.TransactionID, req.State, req.Amount, newBalanceStr)
+	err = s.appendEvent(tx, userID, head.version+1, eventType, delta, newBalance, req.TransactionID, now)
+	if err != nil {
+		return models.Transaction{}, err
+	}
+
+	// Keep the per-user net winnings aggregate used by ranking reports.
+	// Like tax reports, it leaves out payments and transfers, which move
+	// funds rather than win or lose them
+	if sourceType != "payment" && transferID == "" {
+		_, err = tx.Exec(
+			`INSERT INTO user_totals (user_id, net_win) VALUES ($1, $2)
+			 ON CONFLICT (user_id) DO UPDATE SET net_win = user_totals.net_win + EXCLUDED.net_win`,
+			userID,
+			delta,
+		)
+		if err != nil {
+			return models.Transaction{}, fmt.Errorf("failed to update user totals: %w", err)
+		}
+	}
+
+	if s.amlRules.enabled() {
+		if err := s.flagAML(tx, userID, req, transferID, now); err != nil {
+			return models.Transaction{}, err
+		}
+	}
 
-	return &models.TransactionResponse{
+	if s.notifications || s.opsLargeTransaction > 0 {
+		if err := s.queueAlerts(tx, userID, req, newBalance, now); err != nil {
+			return models.Transaction{}, err
+		}
+	}
+	return models.Transaction{
+		ID:            id,
 		UserID:        userID,
 		TransactionID: req.TransactionID,
-		Balance:       newBalanceStr,
-		Message:       "Transaction applied successfully",
+		State:         req.State,
+		Amount:        amount,
+		SourceType:    sourceType,
+		Applied:       true,
+		CreatedAt:     createdAt,
 	}, nil
 }
 
 func (s *TransactionService) GetBalance(userID int64) (*models.BalanceResponse, error) {
+	if s.store != nil {
+		acct, err := s.store.Account(context.Background(), userID)
+		if err != nil {
+			return nil, err
+		}
+		return &models.BalanceResponse{UserID: userID, Balance: acct.User.Balance}, nil
+	}
+
+	query := `SELECT balance FROM users WHERE id = $1 AND kind = 'user' AND deleted_at IS NULL`
+	if s.readModel {
+		query = `SELECT r.balance FROM read_balances r JOIN users u ON u.id = r.user_id
+		 WHERE r.user_id = $1 AND u.deleted_at IS NULL`
+	}
+
 	var balance string
-	err := s.db.QueryRow(
-		`SELECT balance FROM users WHERE id = $1`,
-		userID,
-	).Scan(&balance)
+	err := s.db.QueryRow(query, userID).Scan(&balance)
 	if err == sql.ErrNoRows {
-		return nil, errors.New("user not found")
+		return nil, ErrUserNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user balance: %w", err)
@@ -1,52 +1,21 @@
 package core
 
 import (
-	"database/sql"
+	"context"
+	"errors"
 	"testing"
+	"time"
 
+	"assignment/internal/clock"
 	"assignment/internal/models"
-	_ "github.com/lib/pq"
+	"assignment/internal/testutil"
 )
 
-func setupTestDB(t *testing.T) *sql.DB {
-	connStr := "host=localhost user=postgres password=postgres dbname=assignment_test sslmode=disable"
-	db, err := sql.Open("postgres", connStr)
-	if err != nil {
-		t.Skipf("Skipping test: database not available: %v", err)
-	}
-	if err := db.Ping(); err != nil {
-		t.Skipf("Skipping test: database not available: %v", err)
-	}
-
-	// Clean up and setup test database
-	db.Exec("DROP TABLE IF EXISTS transactions CASCADE")
-	db.Exec("DROP TABLE IF EXISTS users CASCADE")
-	db.Exec(`CREATE TABLE users (
-		id BIGSERIAL PRIMARY KEY,
-		balance NUMERIC(10,2) NOT NULL DEFAULT 0,
-		created_at TIMESTAMP DEFAULT NOW(),
-		updated_at TIMESTAMP DEFAULT NOW()
-	)`)
-	db.Exec(`CREATE TABLE transactions (
-		id BIGSERIAL PRIMARY KEY,
-		user_id BIGINT REFERENCES users(id),
-		transaction_id TEXT UNIQUE,
-		state TEXT,
-		amount NUMERIC(10,2),
-		source_type TEXT,
-		applied BOOLEAN,
-		created_at TIMESTAMP DEFAULT NOW()
-	)`)
-	db.Exec("INSERT INTO users (id, balance) VALUES (1, 100.00), (2, 50.00), (3, 0.00)")
-
-	return db
-}
-
 func TestProcessTransaction_Win(t *testing.T) {
-	db := setupTestDB(t)
+	db := testutil.NewDB(t)
 	defer db.Close()
 
-	service := NewTransactionService(db)
+	service := NewTransactionService(db, clock.New())
 
 	req := models.TransactionRequest{
 		State:         "win",
@@ -69,10 +38,10 @@ func TestProcessTransaction_Win(t *testing.T) {
 }
 
 func TestProcessTransaction_Lose(t *testing.T) {
-	db := setupTestDB(t)
+	db := testutil.NewDB(t)
 	defer db.Close()
 
-	service := NewTransactionService(db)
+	service := NewTransactionService(db, clock.New())
 
 	req := models.TransactionRequest{
 		State:         "lose",
@@ -91,10 +60,10 @@ func TestProcessTransaction_Lose(t *testing.T) {
 }
 
 func TestProcessTransaction_InsufficientFunds(t *testing.T) {
-	db := setupTestDB(t)
+	db := testutil.NewDB(t)
 	defer db.Close()
 
-	service := NewTransactionService(db)
+	service := NewTransactionService(db, clock.New())
 
 	req := models.TransactionRequest{
 		State:         "lose",
@@ -117,10 +86,10 @@ func TestProcessTransaction_InsufficientFunds(t *testing.T) {
 }
 
 func TestProcessTransaction_Duplicate(t *testing.T) {
-	db := setupTestDB(t)
+	db := testutil.NewDB(t)
 	defer db.Close()
 
-	service := NewTransactionService(db)
+	service := NewTransactionService(db, clock.New())
 
 	req := models.TransactionRequest{
 		State:         "win",
@@ -140,20 +109,81 @@ func TestProcessTransaction_Duplicate(t *testing.T) {
 		t.Fatalf("Expected no error, got: %v", err)
 	}
 
-	if resp2.Message != "Duplicate transaction ignored" {
-		t.Errorf("Expected duplicate message, got: %s", resp2.Message)
+	if !resp2.Replayed {
+		t.Errorf("Expected the response to be replayed")
+	}
+	resp2.Replayed = false
+	if *resp2 != *resp1 {
+		t.Errorf("Expected the original response %+v, got: %+v", resp1, resp2)
 	}
 
-	if resp1.Balance != resp2.Balance {
-		t.Errorf("Duplicate transaction should return same balance")
+	// Retries see the response given first, not the current balance
+	if _, err := service.ProcessTransaction(1, testutil.NewTransactionRequest("lose", "5.00"), "game"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	resp3, err := service.ProcessTransaction(1, req, "game")
+	if err != nil || resp3.Balance != resp1.Balance || resp3.Message != "Transaction applied successfully" {
+		t.Errorf("Expected the original response, got: %+v, %v", resp3, err)
+	}
+}
+
+func TestProcessTransaction_GeneratedTransactionID(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.New())
+
+	req := models.TransactionRequest{State: "win", Amount: "10.00"}
+
+	resp1, err := service.ProcessTransaction(1, req, "game")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	resp2, err := service.ProcessTransaction(1, req, "game")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(resp1.TransactionID) != 26 {
+		t.Errorf("Expected a 26 character ULID, got: %q", resp1.TransactionID)
+	}
+	if resp1.TransactionID == resp2.TransactionID {
+		t.Errorf("Expected distinct generated IDs, got: %s twice", resp1.TransactionID)
+	}
+	// Both requests were applied rather than treated as duplicates
+	if resp2.Balance != "120.00" {
+		t.Errorf("Expected balance 120.00, got: %s", resp2.Balance)
+	}
+}
+
+func TestProcessTransactionContext_Canceled(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.New())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := models.TransactionRequest{State: "win", Amount: "10.00", TransactionID: "test-canceled-1"}
+	if _, err := service.ProcessTransactionContext(ctx, 1, req, "game"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got: %v", err)
+	}
+
+	// Nothing was applied, so a retry is not a duplicate
+	resp, err := service.ProcessTransaction(1, req, "game")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if resp.Replayed || resp.Balance != "110.00" {
+		t.Errorf("Expected the retry to apply with balance 110.00, got: %+v", resp)
 	}
 }
 
 func TestGetBalance(t *testing.T) {
-	db := setupTestDB(t)
+	db := testutil.NewDB(t)
 	defer db.Close()
 
-	service := NewTransactionService(db)
+	service := NewTransactionService(db, clock.New())
 
 	resp, err := service.GetBalance(1)
 	if err != nil {
@@ -165,3 +195,99 @@ func TestGetBalance(t *testing.T) {
 	}
 }
 
+func TestProcessTransaction_UsesClock(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	now := time.Date(2024, 3, 1, 14, 32, 0, 0, time.UTC)
+	service := NewTransactionService(db, clock.NewFake(now))
+
+	req := testutil.NewTransactionRequest("win", "1.00")
+	if _, err := service.ProcessTransaction(1, req, "game"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	var createdAt, updatedAt time.Time
+	if err := db.QueryRow(`SELECT created_at FROM transactions WHERE transaction_id = $1`, req.TransactionID).Scan(&createdAt); err != nil {
+		t.Fatalf("Failed to read transaction: %v", err)
+	}
+	if err := db.QueryRow(`SELECT updated_at FROM users WHERE id = 1`).Scan(&updatedAt); err != nil {
+		t.Fatalf("Failed to read user: %v", err)
+	}
+
+	if !createdAt.Equal(now) {
+		t.Errorf("Expected created_at %v, got: %v", now, createdAt)
+	}
+	if !updatedAt.Equal(now) {
+		t.Errorf("Expected updated_at %v, got: %v", now, updatedAt)
+	}
+}
+
+func mustProcess(t *testing.T, service *TransactionService, userID int64, req models.TransactionRequest, sourceType string) *models.TransactionResponse {
+	t.Helper()
+	resp, err := service.ProcessTransaction(userID, req, sourceType)
+	if err != nil {
+		t.Fatalf("Failed to process transaction %s: %v", req.TransactionID, err)
+	}
+	return resp
+}
+
+func TestProcessTransaction_Hooks(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	var seen []string
+	var applied []models.Transaction
+	var balances []string
+	service := NewTransactionService(db, clock.New(), WithHooks(Hooks{
+		BeforeTransaction: func(userID int64, req models.TransactionRequest, sourceType string) error {
+			seen = append(seen, req.TransactionID)
+			if req.TransactionID == "test-hook-blocked" {
+				return &ValidationError{Field: "transactionId", Message: "match voided"}
+			}
+			return nil
+		},
+		AfterTransaction: func(transaction models.Transaction, balance string) {
+			applied = append(applied, transaction)
+			balances = append(balances, balance)
+		},
+	}))
+
+	if _, err := service.ProcessTransaction(1, testutil.NewTransactionRequest("win", "not-a-number"), "game"); !errors.Is(err, ErrValidation) {
+		t.Fatalf("Expected a validation error, got: %v", err)
+	}
+	if len(seen) != 1 {
+		t.Errorf("Expected the before hook to run before validation, got: %v", seen)
+	}
+
+	_, err := service.ProcessTransaction(1, models.TransactionRequest{State: "win", Amount: "10.00", TransactionID: "test-hook-blocked"}, "game")
+	if err == nil || err.Error() != "match voided" {
+		t.Fatalf("Expected the hook's error, got: %v", err)
+	}
+
+	req := models.TransactionRequest{State: "win", Amount: "10.00", TransactionID: "test-hook-1"}
+	if _, err := service.ProcessTransaction(1, req, "game"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	// Replays and rejections are not reported after commit
+	service.ProcessTransaction(1, req, "game")
+	service.ProcessTransaction(1, models.TransactionRequest{State: "lose", Amount: "1000.00", TransactionID: "test-hook-2"}, "game")
+
+	if len(applied) != 1 {
+		t.Fatalf("Expected one applied transaction, got: %+v", applied)
+	}
+	got := applied[0]
+	if got.ID == 0 || got.UserID != 1 || got.TransactionID != "test-hook-1" || got.State != "win" || got.Amount != "10.00" || got.SourceType != "game" || !got.Applied {
+		t.Errorf("Expected the stored transaction, got: %+v", got)
+	}
+	if balances[0] != "110.00" {
+		t.Errorf("Expected balance 110.00, got: %s", balances[0])
+	}
+
+	// The blocked transaction was not recorded
+	var count int
+	db.QueryRow(`SELECT COUNT(*) FROM transactions WHERE transaction_id = 'test-hook-blocked'`).Scan(&count)
+	if count != 0 {
+		t.Errorf("Expected the blocked transaction not to be recorded, got: %d", count)
+	}
+}
@@ -0,0 +1,329 @@
+package core
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"assignment/internal/models"
+	"assignment/internal/money"
+	"assignment/internal/utils"
+	"assignment/internal/validation"
+	"github.com/lib/pq"
+)
+
+// Notification types.
+const (
+	NotifyLowBalance       = "low_balance"
+	NotifyLargeTransaction = "large_transaction"
+	NotifyBalanceChanged   = "balance_changed"
+)
+
+// PushChannel delivers notifications to the registered devices of a user.
+// Users opted into it are also pushed every change of their balance.
+const PushChannel = "push"
+
+// OpsEmailChannel delivers a notification to the ops mailing list rather
+// than to the user.
+const OpsEmailChannel = "ops_email"
+
+// EnableNotifications queues an alert for every applied transaction matching
+// the preferences of its user, for the notifier to deliver. Call it before
+// serving requests.
+func (s *TransactionService) EnableNotifications() {
+	s.notifications = true
+}
+
+// SetLargeTransactionAlert queues an alert to OpsEmailChannel for every
+// applied transaction of at least threshold (0 for none), whatever the
+// preferences of its user. Escrow legs of transfers are not alerted. Call it
+// before serving requests.
+func (s *TransactionService) SetLargeTransactionAlert(threshold float64) {
+	s.opsLargeTransaction = threshold
+}
+
+// GetNotificationPreferences returns the notification preferences of a user.
+// Users who never set any have no alerts.
+func (s *TransactionService) GetNotificationPreferences(userID int64) (*models.NotificationPreferences, error) {
+	prefs := &models.NotificationPreferences{UserID: userID}
+	var balanceBelow, largeTransaction, phone sql.NullString
+	var updatedAt sql.NullTime
+	var exists bool
+	err := s.db.QueryRow(
+		`SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND kind = $2 AND deleted_at IS NULL),
+		        p.balance_below, p.large_transaction, p.phone, COALESCE(p.channels, '{}'), p.updated_at
+		 FROM (SELECT 1) one LEFT JOIN notification_preferences p ON p.user_id = $1`,
+		userID,
+		AccountUser,
+	).Scan(&exists, &balanceBelow, &largeTransaction, &phone, pq.Array(&prefs.Channels), &updatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification preferences: %w", err)
+	}
+	if !exists {
+		return nil, ErrUserNotFound
+	}
+
+	prefs.BalanceBelow = balanceBelow.String
+	prefs.LargeTransaction = largeTransaction.String
+	prefs.Phone = phone.String
+	if prefs.Channels == nil {
+		prefs.Channels = []string{}
+	}
+	if updatedAt.Valid {
+		prefs.UpdatedAt = &updatedAt.Time
+	}
+	return prefs, nil
+}
+
+// SetNotificationPreferences replaces the notification preferences of a user
+// and returns them. They apply from the next transaction.
+func (s *TransactionService) SetNotificationPreferences(userID int64, req models.NotificationPreferencesRequest) (*models.NotificationPreferences, error) {
+	for _, threshold := range []string{req.BalanceBelow, req.LargeTransaction} {
+		if threshold == "" {
+			continue
+		}
+		if err := utils.ValidateAmount(threshold); err != nil {
+			return nil, err
+		}
+	}
+	channels := []string{}
+	seen := map[string]bool{}
+	for _, channel := range req.Channels {
+		if err := utils.ValidateNotificationChannel(channel); err != nil {
+			return nil, err
+		}
+		if !seen[channel] {
+			seen[channel] = true
+			channels = append(channels, channel)
+		}
+	}
+	if req.Phone != "" {
+		if err := utils.ValidatePhone(req.Phone); err != nil {
+			return nil, err
+		}
+	} else if seen["sms"] {
+		return nil, validation.Errorf("phone", "invalid phone: required for the sms channel")
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockUser(tx, userID); err != nil {
+		return nil, err
+	}
+	// Thresholds have the decimals of the user's currency
+	var currency string
+	if err := tx.QueryRow(`SELECT currency FROM users WHERE id = $1`, userID).Scan(&currency); err != nil {
+		return nil, fmt.Errorf("failed to get currency: %w", err)
+	}
+	for _, threshold := range []string{req.BalanceBelow, req.LargeTransaction} {
+		if threshold == "" {
+			continue
+		}
+		if err := money.ValidateAmount(threshold, currency); err != nil {
+			return nil, err
+		}
+	}
+
+	now := s.clock.Now()
+	_, err = tx.Exec(
+		`INSERT INTO notification_preferences (user_id, balance_below, large_transaction, phone, channels, updated_at)
+		 VALUES ($1, ROUND(NULLIF($2, '')::NUMERIC, $7), ROUND(NULLIF($3, '')::NUMERIC, $7), NULLIF($4, ''), $5, $6)
+		 ON CONFLICT (user_id) DO UPDATE SET balance_below = EXCLUDED.balance_below,
+		   large_transaction = EXCLUDED.large_transaction, phone = EXCLUDED.phone,
+		   channels = EXCLUDED.channels, updated_at = EXCLUDED.updated_at`,
+		userID,
+		req.BalanceBelow,
+		req.LargeTransaction,
+		req.Phone,
+		pq.Array(channels),
+		now,
+		money.Exponent(currency),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save notification preferences: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	prefs := &models.NotificationPreferences{
+		UserID:    userID,
+		Phone:     req.Phone,
+		Channels:  channels,
+		UpdatedAt: &now,
+	}
+	if req.BalanceBelow != "" {
+		prefs.BalanceBelow = formatThreshold(req.BalanceBelow, currency)
+	}
+	if req.LargeTransaction != "" {
+		prefs.LargeTransaction = formatThreshold(req.LargeTransaction, currency)
+	}
+	return prefs, nil
+}
+
+// formatThreshold formats a validated threshold the way the column stores it,
+// with the decimals of currency, e.g. 50 as 50.00 for EUR.
+func formatThreshold(amount, currency string) string {
+	value, _ := utils.ParseAmount(amount)
+	return money.Format(value, currency)
+}
+
+// queueAlerts queues the alerts a transaction applied to an account locked
+// by lockBalance triggers: the ops alert for large transactions, and those of
+// the preferences of the account's user, including the balance change pushed
+// to users opted into PushChannel. Wallet transactions are alerted to their
+// owner.
+func (s *TransactionService) queueAlerts(tx *sql.Tx, userID int64, req models.TransactionRequest, newBalance string, now time.Time) error {
+	var ownerID int64
+	var currency string
+	var walletID, balanceBelow, largeTransaction sql.NullString
+	var channels []string
+	err := tx.QueryRow(
+		`SELECT COALESCE(u.owner_id, u.id), u.currency, u.wallet_name, p.balance_below, p.large_transaction, COALESCE(p.channels, '{}')
+		 FROM users u LEFT JOIN notification_preferences p ON p.user_id = COALESCE(u.owner_id, u.id)
+		 WHERE u.id = $1 AND u.kind <> $2`,
+		userID,
+		AccountEscrow,
+	).Scan(&ownerID, &currency, &walletID, &balanceBelow, &largeTransaction, pq.Array(&channels))
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get notification preferences: %w", err)
+	}
+
+	amount, err := utils.ParseAmount(req.Amount)
+	if err != nil {
+		return fmt.Errorf("failed to parse amount: %w", err)
+	}
+	balance, err := utils.ParseBalance(newBalance)
+	if err != nil {
+		return fmt.Errorf("failed to parse balance: %w", err)
+	}
+	previous := balance + amount
+	if req.State == "win" {
+		previous = balance - amount
+	}
+
+	queue := func(notificationType string, threshold interface{}, channels []string) error {
+		_, err := tx.Exec(
+			`INSERT INTO notification_outbox (user_id, wallet_id, type, transaction_id, amount, balance, threshold, channels, created_at)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+			ownerID,
+			walletID,
+			notificationType,
+			req.TransactionID,
+			req.Amount,
+			newBalance,
+			threshold,
+			pq.Array(channels),
+			now,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to queue notification: %w", err)
+		}
+		return nil
+	}
+
+	if s.opsLargeTransaction > 0 && minorUnits(amount, currency) >= minorUnits(s.opsLargeTransaction, currency) {
+		threshold := money.Format(s.opsLargeTransaction, currency)
+		if err := queue(NotifyLargeTransaction, threshold, []string{OpsEmailChannel}); err != nil {
+			return err
+		}
+	}
+
+	// Users without channels opted out of their alerts
+	if !s.notifications || len(channels) == 0 {
+		return nil
+	}
+	if largeTransaction.Valid {
+		threshold, err := utils.ParseAmount(largeTransaction.String)
+		if err != nil {
+			return fmt.Errorf("failed to parse large transaction threshold: %w", err)
+		}
+		if minorUnits(amount, currency) >= minorUnits(threshold, currency) {
+			if err := queue(NotifyLargeTransaction, largeTransaction.String, channels); err != nil {
+				return err
+			}
+		}
+	}
+	// Only crossing the threshold alerts, not every transaction below it
+	if balanceBelow.Valid {
+		threshold, err := utils.ParseAmount(balanceBelow.String)
+		if err != nil {
+			return fmt.Errorf("failed to parse balance threshold: %w", err)
+		}
+		if minorUnits(balance, currency) < minorUnits(threshold, currency) && minorUnits(previous, currency) >= minorUnits(threshold, currency) {
+			if err := queue(NotifyLowBalance, balanceBelow.String, channels); err != nil {
+				return err
+			}
+		}
+	}
+	for _, channel := range channels {
+		if channel == PushChannel {
+			return queue(NotifyBalanceChanged, nil, []string{PushChannel})
+		}
+	}
+	return nil
+}
+
+// DeliverNotifications takes up to limit queued notifications, oldest first,
+// and passes each to send. Delivered notifications are removed from the
+// queue; delivery stops at the first error, which is returned with the
+// number delivered. Notifications carry the current phone number of their
+// user. Concurrent notifiers take disjoint batches.
+func (s *TransactionService) DeliverNotifications(limit int, send func(models.Notification) error) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(
+		`SELECT o.id, o.user_id, COALESCE(o.wallet_id, ''), o.type, o.transaction_id, o.amount, o.balance, COALESCE(o.threshold::TEXT, ''),
+		        COALESCE(p.phone, ''), o.channels, o.created_at
+		 FROM notification_outbox o LEFT JOIN notification_preferences p ON p.user_id = o.user_id
+		 ORDER BY o.id LIMIT $1 FOR UPDATE OF o SKIP LOCKED`,
+		limit,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query notifications: %w", err)
+	}
+	var batch []models.Notification
+	for rows.Next() {
+		var n models.Notification
+		err := rows.Scan(&n.ID, &n.UserID, &n.WalletID, &n.Type, &n.TransactionID, &n.Amount, &n.Balance, &n.Threshold, &n.Phone, pq.Array(&n.Channels), &n.CreatedAt)
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		batch = append(batch, n)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read notifications: %w", err)
+	}
+
+	var delivered []int64
+	var sendErr error
+	for _, n := range batch {
+		if sendErr = send(n); sendErr != nil {
+			break
+		}
+		delivered = append(delivered, n.ID)
+	}
+
+	if len(delivered) > 0 {
+		if _, err := tx.Exec(`DELETE FROM notification_outbox WHERE id = ANY($1)`, pq.Array(delivered)); err != nil {
+			return 0, fmt.Errorf("failed to remove delivered notifications: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return len(delivered), sendErr
+}
@@ -0,0 +1,97 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"assignment/internal/clock"
+	"assignment/internal/models"
+	"assignment/internal/testutil"
+)
+
+func TestNotificationAlerts(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.NewFake(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)))
+	service.EnableNotifications()
+
+	prefs, err := service.SetNotificationPreferences(1, models.NotificationPreferencesRequest{
+		BalanceBelow:     "50",
+		LargeTransaction: "30.00",
+		Phone:            "+4915112345678",
+		Channels:         []string{"email", "sms"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if prefs.BalanceBelow != "50.00" {
+		t.Errorf("Expected threshold 50.00, got: %s", prefs.BalanceBelow)
+	}
+
+	// 100.00 -> 60.00 is large; 60.00 -> 40.00 crosses the threshold; 40.00
+	// -> 30.00 stays below it
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("lose", "40.00"), "game")
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("lose", "20.00"), "game")
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("lose", "10.00"), "game")
+	// Users without preferences are never alerted
+	mustProcess(t, service, 2, testutil.NewTransactionRequest("lose", "45.00"), "game")
+
+	var got []models.Notification
+	n, err := service.DeliverNotifications(10, func(notification models.Notification) error {
+		got = append(got, notification)
+		return nil
+	})
+	if err != nil || n != 2 {
+		t.Fatalf("Expected 2 notifications, got: %d, %v", n, err)
+	}
+	if got[0].Type != NotifyLargeTransaction || got[1].Type != NotifyLowBalance || got[1].Balance != "40.00" || got[1].Phone != "+4915112345678" {
+		t.Errorf("Expected large transaction then low balance alerts, got: %+v", got)
+	}
+
+	// Opting out of every channel stops alerts
+	if _, err := service.SetNotificationPreferences(1, models.NotificationPreferencesRequest{LargeTransaction: "1.00"}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("win", "5.00"), "game")
+	if n, err := service.DeliverNotifications(10, func(models.Notification) error { return nil }); err != nil || n != 0 {
+		t.Errorf("Expected no notifications, got: %d, %v", n, err)
+	}
+
+	if _, err := service.SetNotificationPreferences(1, models.NotificationPreferencesRequest{Channels: []string{"fax"}}); err == nil {
+		t.Error("Expected error for invalid channel")
+	}
+	if _, err := service.SetNotificationPreferences(1, models.NotificationPreferencesRequest{Channels: []string{"sms"}}); err == nil {
+		t.Error("Expected error for sms channel without phone")
+	}
+}
+
+func TestLargeTransactionAlert(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.New())
+	service.SetLargeTransactionAlert(25)
+
+	// Only the user leg of an escrow hold is alerted
+	if _, err := service.CreateEscrowAccount("tournament"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := service.HoldInEscrow(context.Background(), "tournament", models.EscrowRequest{UserID: 1, Amount: "30.00", TransferID: "hold-1"}, "game"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	mustProcess(t, service, 2, testutil.NewTransactionRequest("win", "24.99"), "game")
+
+	var got []models.Notification
+	_, err := service.DeliverNotifications(10, func(notification models.Notification) error {
+		got = append(got, notification)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(got) != 1 || got[0].UserID != 1 || got[0].Threshold != "25.00" || got[0].Channels[0] != OpsEmailChannel {
+		t.Errorf("Expected one ops alert for user 1, got: %+v", got)
+	}
+}
@@ -0,0 +1,77 @@
+package core
+
+import (
+	"log"
+
+	"assignment/internal/clock"
+	"assignment/internal/models"
+)
+
+// Option customizes a TransactionService when it is created; see
+// NewTransactionService. The Set and Enable methods configure the rest.
+type Option func(*TransactionService)
+
+// WithClock reads the current time from clk instead of the clock passed to
+// NewTransactionService.
+func WithClock(clk clock.Clock) Option {
+	return func(s *TransactionService) {
+		s.clock = clk
+	}
+}
+
+// WithLogger writes the service's log lines to logger instead of the
+// standard logger, e.g. to capture them in tests.
+func WithLogger(logger *log.Logger) Option {
+	return func(s *TransactionService) {
+		s.logger = logger
+	}
+}
+
+// WithMaxAmount bounds the amount a single transaction or transfer may
+// move, like SetMaxTransactionAmount.
+func WithMaxAmount(amount float64) Option {
+	return func(s *TransactionService) {
+		s.maxTransactionAmount = amount
+	}
+}
+
+// Hooks are called by the service as it processes transactions. Nil hooks
+// are skipped. Hooks must be fast and safe for concurrent use.
+type Hooks struct {
+	// Outcome is called with the outcome of every transaction processed,
+	// like the observers added with ObserveOutcomes
+	Outcome func(outcome string)
+	// BeforeTransaction is called with every transaction before it is
+	// validated, userID being the account it applies to. An error rejects
+	// the transaction with that error, e.g. a ValidationError for a 400 Bad
+	// Request, and nothing is recorded
+	BeforeTransaction func(userID int64, req models.TransactionRequest, sourceType string) error
+	// AfterTransaction is called once a transaction is committed with the
+	// transaction as stored and the resulting balance of its account.
+	// Rejected and replayed transactions are not reported
+	AfterTransaction func(transaction models.Transaction, balance string)
+}
+
+// WithHooks adds hooks to the service, like AddHooks.
+func WithHooks(hooks Hooks) Option {
+	return func(s *TransactionService) {
+		s.AddHooks(hooks)
+	}
+}
+
+// AddHooks adds hooks to the service, in addition to those added before.
+// Hooks run in the order added. They cover transactions processed through
+// ProcessTransaction, ProcessWalletTransaction, the message queue and
+// imports, but not transfers, escrow moves or ledger corrections. Call it
+// before serving requests.
+func (s *TransactionService) AddHooks(hooks Hooks) {
+	if hooks.Outcome != nil {
+		s.observers = append(s.observers, hooks.Outcome)
+	}
+	if hooks.BeforeTransaction != nil {
+		s.beforeHooks = append(s.beforeHooks, hooks.BeforeTransaction)
+	}
+	if hooks.AfterTransaction != nil {
+		s.afterHooks = append(s.afterHooks, hooks.AfterTransaction)
+	}
+}
@@ -0,0 +1,51 @@
+package core
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"assignment/internal/clock"
+)
+
+func TestOptions(t *testing.T) {
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	var logs bytes.Buffer
+	var outcomes []string
+	s := NewTransactionService(nil, nil,
+		WithClock(clock.NewFake(now)),
+		WithLogger(log.New(&logs, "", 0)),
+		WithMaxAmount(500),
+		WithHooks(Hooks{Outcome: func(outcome string) { outcomes = append(outcomes, outcome) }}),
+	)
+
+	if got := s.clock.Now(); !got.Equal(now) {
+		t.Errorf("Expected the fake clock, got: %v", got)
+	}
+	if _, err := s.ValidateTransactionAmount("500.01"); err == nil || !strings.Contains(err.Error(), "exceeds the maximum transaction amount of 500.00") {
+		t.Errorf("Expected the maximum amount to apply, got: %v", err)
+	}
+
+	account := lockedAccount{balance: 90, maxBalance: 100}
+	if _, reason, _ := s.capCredit(7, account, 20, false); reason != "max_balance" {
+		t.Errorf("Expected the credit to be rejected, got: %q", reason)
+	}
+	if !strings.Contains(logs.String(), "Balance cap hit: userID=7") {
+		t.Errorf("Expected the log line in the logger, got: %q", logs.String())
+	}
+
+	if len(s.observers) != 1 {
+		t.Fatalf("Expected the outcome hook to be observing, got: %d observers", len(s.observers))
+	}
+	s.observers[0](OutcomeApplied)
+	if len(outcomes) != 1 || outcomes[0] != OutcomeApplied {
+		t.Errorf("Expected the outcome hook to be called, got: %v", outcomes)
+	}
+
+	// Without options the system time is used
+	if NewTransactionService(nil, nil).clock == nil {
+		t.Error("Expected a default clock")
+	}
+}
@@ -0,0 +1,50 @@
+package core
+
+import (
+	"errors"
+
+	"assignment/internal/models"
+)
+
+// Transaction outcomes reported to the outcome observers.
+const (
+	OutcomeApplied           = "applied"
+	OutcomeDuplicate         = "duplicate"
+	OutcomeInsufficientFunds = "insufficient_funds"
+	// OutcomeRejected covers the other business rejections, e.g. KYC limits
+	OutcomeRejected = "rejected"
+	// OutcomeInvalid covers requests failing validation, naming unknown
+	// users or lacking a required signature, which are client errors
+	OutcomeInvalid = "invalid"
+	OutcomeError   = "error"
+)
+
+// ObserveOutcomes reports the outcome of every transaction processed through
+// ProcessTransaction or ProcessWalletTransaction to observe, in addition to
+// the observers added before. Observers must be fast and safe for concurrent
+// use. Call it before serving requests.
+func (s *TransactionService) ObserveOutcomes(observe func(outcome string)) {
+	s.observers = append(s.observers, observe)
+}
+
+// transactionOutcome classifies the result of processing a transaction.
+func transactionOutcome(resp *models.TransactionResponse, err error) string {
+	if err != nil {
+		if errors.Is(err, ErrPreconditionFailed) {
+			return OutcomeRejected
+		}
+		if errors.Is(err, ErrValidation) || errors.Is(err, ErrUserNotFound) || errors.Is(err, ErrWalletNotFound) || errors.Is(err, ErrSignatureRequired) {
+			return OutcomeInvalid
+		}
+		return OutcomeError
+	}
+	switch err := TransactionError(resp); {
+	case err == nil:
+		return OutcomeApplied
+	case errors.Is(err, ErrDuplicateTransaction):
+		return OutcomeDuplicate
+	case errors.Is(err, ErrInsufficientFunds):
+		return OutcomeInsufficientFunds
+	}
+	return OutcomeRejected
+}
@@ -0,0 +1,32 @@
+package core
+
+import (
+	"errors"
+	"testing"
+
+	"assignment/internal/models"
+)
+
+func TestTransactionOutcome(t *testing.T) {
+	tests := []struct {
+		resp *models.TransactionResponse
+		err  error
+		want string
+	}{
+		{&models.TransactionResponse{Message: "Transaction applied successfully"}, nil, OutcomeApplied},
+		{&models.TransactionResponse{Message: "Transaction clipped to maximum balance"}, nil, OutcomeApplied},
+		{&models.TransactionResponse{Message: "Duplicate transaction ignored", Reason: ReasonDuplicate}, nil, OutcomeDuplicate},
+		{&models.TransactionResponse{Message: "Transaction applied successfully", Replayed: true}, nil, OutcomeDuplicate},
+		{&models.TransactionResponse{Message: "Insufficient funds", Reason: ReasonInsufficientFunds}, nil, OutcomeInsufficientFunds},
+		{&models.TransactionResponse{Message: "KYC daily limit exceeded", Reason: ReasonKYCDailyLimit}, nil, OutcomeRejected},
+		{nil, ErrPreconditionFailed, OutcomeRejected},
+		{nil, &ValidationError{Field: "amount", Message: "invalid amount format"}, OutcomeInvalid},
+		{nil, ErrUserNotFound, OutcomeInvalid},
+		{nil, errors.New("failed to begin transaction: connection refused"), OutcomeError},
+	}
+	for _, tt := range tests {
+		if got := transactionOutcome(tt.resp, tt.err); got != tt.want {
+			t.Errorf("transactionOutcome(%v, %v) = %s, want %s", tt.resp, tt.err, got, tt.want)
+		}
+	}
+}
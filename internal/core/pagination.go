@@ -0,0 +1,77 @@
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"assignment/internal/cursor"
+)
+
+// Page selects a page of a listing: its first Limit rows, or the Limit rows
+// after or before Cursor. Listings are in ascending order of their sort key,
+// or descending with Newest set; "after" and "before" follow that order.
+type Page struct {
+	Cursor *cursor.Cursor
+	Limit  int
+	Newest bool
+}
+
+// keyset returns the range condition and ORDER BY list of a page query on
+// the sort columns timeColumn and idColumn. The condition refers to the next
+// two placeholders from $n, bound to the returned arguments. The query should
+// fetch Limit+1 rows so trimPage can tell whether there are more.
+func (p Page) keyset(timeColumn, idColumn string, n int) (cond, order string, args []interface{}) {
+	// Pages before a cursor are read against the listing order; trimPage
+	// restores it
+	backward := p.Cursor != nil && p.Cursor.Before
+	op, direction := ">", ""
+	if backward != p.Newest {
+		op, direction = "<", " DESC"
+	}
+	order = fmt.Sprintf("%s%s, %s%s", timeColumn, direction, idColumn, direction)
+	if p.Cursor == nil {
+		return "TRUE", order, nil
+	}
+	return fmt.Sprintf("(%s, %s) %s ($%d, $%d)", timeColumn, idColumn, op, n, n+1), order,
+		[]interface{}{p.Cursor.Time, p.Cursor.ID}
+}
+
+// trimPage cuts the rows read with keyset down to the page, in the listing's
+// order, and reports whether the query found rows beyond it.
+func trimPage[T any](rows []T, p Page) ([]T, bool) {
+	more := len(rows) > p.Limit
+	if more {
+		rows = rows[:p.Limit]
+	}
+	if p.Cursor != nil && p.Cursor.Before {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+	return rows, more
+}
+
+// pageCursors returns the next and previous cursors of a page of rows as
+// returned by trimPage, with their sort keys given by key. A page read
+// forwards has a previous page if it was read from a cursor; one read
+// backwards always has a next page.
+func pageCursors[T any](p Page, rows []T, key func(T) pageKey, more bool) (next, prev string) {
+	if len(rows) == 0 {
+		return "", ""
+	}
+	first, last := key(rows[0]), key(rows[len(rows)-1])
+	backward := p.Cursor != nil && p.Cursor.Before
+	if more || backward {
+		next = cursor.After(last.time, last.id).String()
+	}
+	if (backward && more) || (p.Cursor != nil && !backward) {
+		prev = cursor.Before(first.time, first.id).String()
+	}
+	return next, prev
+}
+
+// pageKey is the sort key of a listed row.
+type pageKey struct {
+	time time.Time
+	id   int64
+}
@@ -0,0 +1,65 @@
+package core
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"assignment/internal/cursor"
+)
+
+func TestPageCursors(t *testing.T) {
+	at := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	key := func(id int64) pageKey { return pageKey{at, id} }
+	rows := []int64{1, 2, 3}
+
+	tests := []struct {
+		name               string
+		page               Page
+		rows               []int64
+		wantRows           string
+		wantNext, wantPrev string
+	}{
+		{"first page", Page{Limit: 2}, rows, "[1 2]", cursor.After(at, 2).String(), ""},
+		{"only page", Page{Limit: 3}, rows, "[1 2 3]", "", ""},
+		{"after cursor", Page{Cursor: cursor.After(at, 0), Limit: 3}, rows, "[1 2 3]", "", cursor.Before(at, 1).String()},
+		{"before cursor", Page{Cursor: cursor.Before(at, 4), Limit: 2}, []int64{3, 2, 1}, "[2 3]", cursor.After(at, 3).String(), cursor.Before(at, 2).String()},
+		{"before cursor to start", Page{Cursor: cursor.Before(at, 4), Limit: 3}, []int64{3, 2, 1}, "[1 2 3]", cursor.After(at, 3).String(), ""},
+		{"empty", Page{Cursor: cursor.After(at, 3), Limit: 2}, nil, "[]", "", ""},
+	}
+	for _, tt := range tests {
+		page, more := trimPage(append([]int64(nil), tt.rows...), tt.page)
+		next, prev := pageCursors(tt.page, page, key, more)
+		if got := fmt.Sprint(page); got != tt.wantRows {
+			t.Errorf("%s: rows %s, want %s", tt.name, got, tt.wantRows)
+		}
+		if next != tt.wantNext || prev != tt.wantPrev {
+			t.Errorf("%s: cursors %q, %q, want %q, %q", tt.name, next, prev, tt.wantNext, tt.wantPrev)
+		}
+	}
+}
+
+func TestPageKeyset(t *testing.T) {
+	at := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name                string
+		page                Page
+		wantCond, wantOrder string
+	}{
+		{"first page", Page{Limit: 2}, "TRUE", "t, id"},
+		{"after cursor", Page{Cursor: cursor.After(at, 1)}, "(t, id) > ($3, $4)", "t, id"},
+		{"before cursor", Page{Cursor: cursor.Before(at, 1)}, "(t, id) < ($3, $4)", "t DESC, id DESC"},
+		{"newest first page", Page{Newest: true}, "TRUE", "t DESC, id DESC"},
+		{"newest after cursor", Page{Cursor: cursor.After(at, 1), Newest: true}, "(t, id) < ($3, $4)", "t DESC, id DESC"},
+		{"newest before cursor", Page{Cursor: cursor.Before(at, 1), Newest: true}, "(t, id) > ($3, $4)", "t, id"},
+	}
+	for _, tt := range tests {
+		cond, order, args := tt.page.keyset("t", "id", 3)
+		if cond != tt.wantCond || order != tt.wantOrder {
+			t.Errorf("%s: got %q ORDER BY %q, want %q ORDER BY %q", tt.name, cond, order, tt.wantCond, tt.wantOrder)
+		}
+		if (tt.page.Cursor == nil) != (args == nil) {
+			t.Errorf("%s: unexpected args %v", tt.name, args)
+		}
+	}
+}
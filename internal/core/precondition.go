@@ -0,0 +1,33 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"assignment/internal/models"
+)
+
+// ErrPreconditionFailed is returned by conditional writes when the balance
+// is no longer the one the client read.
+var ErrPreconditionFailed = errors.New("precondition failed: balance has changed")
+
+// BalanceTag returns the entity tag, quotes included, of a user's balance as
+// served by GET /user/{userId}/balance. It is derived from the balance itself
+// rather than users.updated_at, which also changes with unrelated profile
+// edits, so the tag only changes when the balance does.
+func BalanceTag(userID int64, balance string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d:%s", userID, balance)))
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// ProcessTransactionIf is ProcessTransactionContext applying the transaction only if
+// ifMatch accepts the tag of the user's balance, which is checked under the
+// same row lock as the balance. Otherwise the transaction is not recorded and
+// ErrPreconditionFailed is returned. Retries of an already processed
+// transaction are answered as duplicates whatever the balance.
+func (s *TransactionService) ProcessTransactionIf(ctx context.Context, userID int64, req models.TransactionRequest, sourceType string, ifMatch func(tag string) bool) (*models.TransactionResponse, error) {
+	return s.processAccountTransaction(ctx, userID, AccountUser, req, sourceType, ifMatch)
+}
@@ -0,0 +1,41 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"assignment/internal/clock"
+	"assignment/internal/testutil"
+)
+
+func TestProcessTransactionIf(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.New())
+	seen := BalanceTag(1, "100.00")
+	ifMatch := func(tag string) bool { return tag == seen }
+
+	req := testutil.NewTransactionRequest("win", "10.00")
+	resp, err := service.ProcessTransactionIf(context.Background(), 1, req, "game", ifMatch)
+	if err != nil || resp.Balance != "110.00" {
+		t.Fatalf("Expected the transaction on the seen balance to apply, got: %+v, %v", resp, err)
+	}
+
+	// The balance the tag was taken from is gone
+	_, err = service.ProcessTransactionIf(context.Background(), 1, testutil.NewTransactionRequest("win", "10.00"), "game", ifMatch)
+	if !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("Expected ErrPreconditionFailed, got: %v", err)
+	}
+	balance, err := service.GetBalance(1)
+	if err != nil || balance.Balance != "110.00" {
+		t.Errorf("Expected the balance to stay at 110.00, got: %+v, %v", balance, err)
+	}
+
+	// Retries of the applied transaction are still duplicates
+	resp, err = service.ProcessTransactionIf(context.Background(), 1, req, "game", ifMatch)
+	if err != nil || !resp.Replayed {
+		t.Errorf("Expected a duplicate, got: %+v, %v", resp, err)
+	}
+}
@@ -0,0 +1,319 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"assignment/internal/models"
+	"github.com/lib/pq"
+)
+
+// ErasePersonalData anonymizes a user (GDPR Article 17) and returns it: its
+// profile fields and phone number are cleared, the sms channel is dropped
+// from its notification preferences and its push devices are removed. The
+// ledger, which must be kept for financial regulation, stays untouched and
+// is no longer linked to the person. Deleted users can be anonymized, and
+// anonymizing a user again keeps its first anonymized_at.
+func (s *TransactionService) ErasePersonalData(userID int64) (*models.User, error) {
+	now := s.clock.Now()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	user, err := scanUser("anonymize", tx.QueryRow(
+		`UPDATE users SET external_ref = NULL, display_name = NULL, country = NULL,
+		        anonymized_at = COALESCE(anonymized_at, $1), updated_at = $1
+		 WHERE id = $2 AND kind = $3 RETURNING `+userColumns,
+		now,
+		userID,
+		AccountUser,
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(
+		`UPDATE notification_preferences SET phone = NULL, channels = array_remove(channels, 'sms'), updated_at = $1
+		 WHERE user_id = $2 AND (phone IS NOT NULL OR 'sms' = ANY(channels))`,
+		now,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to erase phone number: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM push_devices WHERE user_id = $1`, userID); err != nil {
+		return nil, fmt.Errorf("failed to remove push devices: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.logger.Printf("Personal data erased: userID=%d", userID)
+
+	return user, nil
+}
+
+// ExportPersonalData returns everything stored about a user (GDPR Article
+// 20): its profile, wallets, tags, notification preferences and devices, the
+// transactions and rejections of all its accounts, its status changes,
+// deposits and disputes. Deleted users are included. The export is read from
+// a single snapshot of the database.
+func (s *TransactionService) ExportPersonalData(userID int64) (*models.DataExport, error) {
+	tx, err := s.db.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	user, err := scanUser("get", tx.QueryRow(
+		`SELECT `+userColumns+` FROM users WHERE id = $1 AND kind = $2`,
+		userID,
+		AccountUser,
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	export := &models.DataExport{
+		ExportedAt:    s.clock.Now(),
+		User:          user,
+		Wallets:       []models.Wallet{},
+		PushDevices:   []models.PushDevice{},
+		Transactions:  []models.ExportTransaction{},
+		Rejections:    []models.ExportRejection{},
+		StatusChanges: []models.AuditStatusChange{},
+		Deposits:      []models.Deposit{},
+		Disputes:      []models.Dispute{},
+	}
+
+	tags, err := userTags(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	export.Tags = tags.Tags
+
+	for _, read := range []func(*sql.Tx, *models.DataExport) error{
+		exportWallets,
+		exportNotificationPreferences,
+		exportPushDevices,
+		exportTransactions,
+		exportRejections,
+		exportStatusChanges,
+		exportDeposits,
+		exportDisputes,
+	} {
+		if err := read(tx, export); err != nil {
+			return nil, err
+		}
+	}
+	return export, nil
+}
+
+func exportWallets(tx *sql.Tx, export *models.DataExport) error {
+	rows, err := tx.Query(
+		`SELECT COALESCE(wallet_name, $2), balance, created_at FROM users
+		 WHERE id = $1 OR (owner_id = $1 AND kind = $3)
+		 ORDER BY id`,
+		export.User.ID,
+		MainWallet,
+		AccountWallet,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to query wallets: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var wallet models.Wallet
+		if err := rows.Scan(&wallet.WalletID, &wallet.Balance, &wallet.CreatedAt); err != nil {
+			return fmt.Errorf("failed to scan wallet: %w", err)
+		}
+		export.Wallets = append(export.Wallets, wallet)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read wallets: %w", err)
+	}
+	return nil
+}
+
+func exportNotificationPreferences(tx *sql.Tx, export *models.DataExport) error {
+	prefs := &models.NotificationPreferences{UserID: export.User.ID, Channels: []string{}}
+	var balanceBelow, largeTransaction, phone sql.NullString
+	var updatedAt sql.NullTime
+	err := tx.QueryRow(
+		`SELECT balance_below, large_transaction, phone, channels, updated_at
+		 FROM notification_preferences WHERE user_id = $1`,
+		export.User.ID,
+	).Scan(&balanceBelow, &largeTransaction, &phone, pq.Array(&prefs.Channels), &updatedAt)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to get notification preferences: %w", err)
+	}
+
+	prefs.BalanceBelow = balanceBelow.String
+	prefs.LargeTransaction = largeTransaction.String
+	prefs.Phone = phone.String
+	if updatedAt.Valid {
+		prefs.UpdatedAt = &updatedAt.Time
+	}
+	export.NotificationPreferences = prefs
+	return nil
+}
+
+func exportPushDevices(tx *sql.Tx, export *models.DataExport) error {
+	rows, err := tx.Query(
+		`SELECT user_id, platform, token, created_at FROM push_devices
+		 WHERE user_id = $1 ORDER BY created_at, token`,
+		export.User.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to query devices: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var d models.PushDevice
+		if err := rows.Scan(&d.UserID, &d.Platform, &d.Token, &d.CreatedAt); err != nil {
+			return fmt.Errorf("failed to scan device: %w", err)
+		}
+		export.PushDevices = append(export.PushDevices, d)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read devices: %w", err)
+	}
+	return nil
+}
+
+func exportTransactions(tx *sql.Tx, export *models.DataExport) error {
+	rows, err := tx.Query(
+		`SELECT COALESCE(u.wallet_name, ''), t.transaction_id, t.state, t.amount, t.source_type, t.applied,
+		        COALESCE(t.transfer_id, ''), t.created_at
+		 FROM transactions t JOIN users u ON u.id = t.user_id
+		 WHERE u.id = $1 OR (u.owner_id = $1 AND u.kind = $2)
+		 ORDER BY t.id`,
+		export.User.ID,
+		AccountWallet,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to query transactions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t models.ExportTransaction
+		if err := rows.Scan(&t.Wallet, &t.TransactionID, &t.State, &t.Amount, &t.SourceType, &t.Applied, &t.TransferID, &t.CreatedAt); err != nil {
+			return fmt.Errorf("failed to scan transaction: %w", err)
+		}
+		export.Transactions = append(export.Transactions, t)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read transactions: %w", err)
+	}
+	return nil
+}
+
+func exportRejections(tx *sql.Tx, export *models.DataExport) error {
+	rows, err := tx.Query(
+		`SELECT COALESCE(u.wallet_name, ''), COALESCE(r.transaction_id, ''), COALESCE(r.state, ''),
+		        COALESCE(r.amount::TEXT, ''), COALESCE(r.source_type, ''), r.reason, r.created_at
+		 FROM transaction_rejections r JOIN users u ON u.id = r.user_id
+		 WHERE u.id = $1 OR (u.owner_id = $1 AND u.kind = $2)
+		 ORDER BY r.id`,
+		export.User.ID,
+		AccountWallet,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to query rejections: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r models.ExportRejection
+		if err := rows.Scan(&r.Wallet, &r.TransactionID, &r.State, &r.Amount, &r.SourceType, &r.Reason, &r.CreatedAt); err != nil {
+			return fmt.Errorf("failed to scan rejection: %w", err)
+		}
+		export.Rejections = append(export.Rejections, r)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read rejections: %w", err)
+	}
+	return nil
+}
+
+func exportStatusChanges(tx *sql.Tx, export *models.DataExport) error {
+	rows, err := tx.Query(
+		`SELECT from_status, to_status, reason, changed_at
+		 FROM user_status_changes WHERE user_id = $1 ORDER BY id`,
+		export.User.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to query status changes: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c models.AuditStatusChange
+		if err := rows.Scan(&c.FromStatus, &c.ToStatus, &c.Reason, &c.ChangedAt); err != nil {
+			return fmt.Errorf("failed to scan status change: %w", err)
+		}
+		c.Actor = statusChangeActor(c.Reason)
+		export.StatusChanges = append(export.StatusChanges, c)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read status changes: %w", err)
+	}
+	return nil
+}
+
+func exportDeposits(tx *sql.Tx, export *models.DataExport) error {
+	rows, err := tx.Query(
+		`SELECT `+depositColumns+` FROM deposits WHERE user_id = $1 ORDER BY created_at, id`,
+		export.User.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to query deposits: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		d, err := scanDeposit(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan deposit: %w", err)
+		}
+		export.Deposits = append(export.Deposits, *d)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read deposits: %w", err)
+	}
+	return nil
+}
+
+func exportDisputes(tx *sql.Tx, export *models.DataExport) error {
+	rows, err := tx.Query(
+		`SELECT `+disputeColumns+` FROM disputes
+		 WHERE user_id IN (SELECT id FROM users WHERE id = $1 OR (owner_id = $1 AND kind = $2))
+		 ORDER BY id`,
+		export.User.ID,
+		AccountWallet,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to query disputes: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		d, err := scanDispute(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan dispute: %w", err)
+		}
+		export.Disputes = append(export.Disputes, d)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read disputes: %w", err)
+	}
+	return nil
+}
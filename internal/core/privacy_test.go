@@ -0,0 +1,92 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"assignment/internal/clock"
+	"assignment/internal/models"
+	"assignment/internal/testutil"
+)
+
+func TestErasePersonalData(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.NewFake(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)))
+
+	user, err := service.CreateUser(models.CreateUserRequest{Balance: "20.00", ExternalRef: "crm-42", DisplayName: "Jane Doe", Country: "DE"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := service.CreateWallet(user.ID, "casino"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	_, err = service.SetNotificationPreferences(user.ID, models.NotificationPreferencesRequest{Phone: "+4915112345678", Channels: []string{"sms", "push"}})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := service.RegisterPushDevice(user.ID, models.PushDeviceRequest{Platform: "fcm", Token: "fcm-token-1"}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	mustProcess(t, service, user.ID, testutil.NewTransactionRequest("lose", "5.00"), "game")
+
+	export, err := service.ExportPersonalData(user.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if export.User.DisplayName != "Jane Doe" || export.NotificationPreferences.Phone != "+4915112345678" {
+		t.Errorf("Expected personal data in the export, got: %+v, %+v", export.User, export.NotificationPreferences)
+	}
+	if len(export.Wallets) != 2 || len(export.PushDevices) != 1 || len(export.Transactions) != 1 {
+		t.Errorf("Expected 2 wallets, 1 device and 1 transaction, got: %+v", export)
+	}
+
+	erased, err := service.ErasePersonalData(user.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if erased.ExternalRef != "" || erased.DisplayName != "" || erased.Country != "" || erased.AnonymizedAt == nil {
+		t.Errorf("Expected anonymized user, got: %+v", erased)
+	}
+	if erased.Balance != "15.00" {
+		t.Errorf("Expected balance 15.00 to be kept, got: %s", erased.Balance)
+	}
+
+	export, err = service.ExportPersonalData(user.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	prefs := export.NotificationPreferences
+	if prefs.Phone != "" || len(prefs.Channels) != 1 || prefs.Channels[0] != "push" {
+		t.Errorf("Expected phone and sms channel erased, got: %+v", prefs)
+	}
+	if len(export.PushDevices) != 0 {
+		t.Errorf("Expected push devices removed, got: %+v", export.PushDevices)
+	}
+	// The ledger is kept
+	if len(export.Transactions) != 1 || export.Transactions[0].Amount != "5.00" {
+		t.Errorf("Expected the transaction to be kept, got: %+v", export.Transactions)
+	}
+	if report, err := service.VerifyHashChain(user.ID); err != nil || !report.Valid {
+		t.Errorf("Expected a valid hash chain, got: %+v, %v", report, err)
+	}
+
+	// Deleted users can be exported and anonymized
+	if _, err := service.DeleteUser(2); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := service.ExportPersonalData(2); err != nil {
+		t.Errorf("Expected deleted user to be exported, got: %v", err)
+	}
+	if _, err := service.ErasePersonalData(2); err != nil {
+		t.Errorf("Expected deleted user to be anonymized, got: %v", err)
+	}
+
+	if _, err := service.ErasePersonalData(999); err == nil || err.Error() != "user not found" {
+		t.Errorf("Expected user not found, got: %v", err)
+	}
+	if _, err := service.ExportPersonalData(999); err == nil || err.Error() != "user not found" {
+		t.Errorf("Expected user not found, got: %v", err)
+	}
+}
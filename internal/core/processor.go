@@ -0,0 +1,82 @@
+package core
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"assignment/internal/models"
+)
+
+// TransactionProcessor is the part of TransactionService the HTTP handlers
+// use. Decorators, e.g. for caching, metrics, tracing or dry runs, embed a
+// TransactionProcessor and override the methods they wrap.
+type TransactionProcessor interface {
+	// Transactions
+	ValidateTransactionID(id string) error
+	ProcessTransactionContext(ctx context.Context, userID int64, req models.TransactionRequest, sourceType string) (*models.TransactionResponse, error)
+	ProcessTransactionIf(ctx context.Context, userID int64, req models.TransactionRequest, sourceType string, ifMatch func(tag string) bool) (*models.TransactionResponse, error)
+	ProcessWalletTransaction(ctx context.Context, userID int64, walletID string, req models.TransactionRequest, sourceType string) (*models.TransactionResponse, error)
+	TransferContext(ctx context.Context, req models.TransferRequest, sourceType string) (*models.TransferResponse, error)
+	MoveBetweenWallets(ctx context.Context, userID int64, req models.WalletMoveRequest, sourceType string) (*models.WalletMoveResponse, error)
+	ImportCSV(ctx context.Context, r io.Reader) (*models.ImportReport, error)
+	GetTransactionFeed(filter TransactionFilter, page Page) (*models.TransactionList, error)
+
+	// Balances
+	GetBalance(userID int64) (*models.BalanceResponse, error)
+	GetBalanceAt(userID int64, at time.Time) (*models.BalanceResponse, error)
+	GetBalances(userIDs []int64) (*models.BulkBalanceResponse, error)
+	GetBalanceHistoryPage(userID int64, from, to time.Time, page Page) (*models.BalanceHistoryResponse, error)
+	GetStatement(userID int64, month time.Time) (*models.Statement, error)
+	GetStatementSummary(userID int64, month time.Time) (*models.StatementSummary, error)
+	VerifyHashChain(userID int64) (*models.HashChainReport, error)
+	RebuildProjections() ([]int64, error)
+
+	// Users
+	CreateUser(req models.CreateUserRequest) (*models.User, error)
+	GetUser(userID int64) (*models.User, error)
+	UpdateUser(userID int64, req models.UpdateUserRequest) (*models.User, error)
+	DeleteUser(userID int64) (*models.User, error)
+	SetUserStatus(userID int64, req models.UserStatusRequest) (*models.User, error)
+	SetKYCLevel(userID int64, req models.KYCLevelRequest) (*models.User, error)
+	SetMaxBalance(userID int64, req models.MaxBalanceRequest) (*models.User, error)
+	FreezeUser(userID int64, req models.FreezeRequest) (*models.User, error)
+	UnfreezeUser(userID int64) (*models.User, error)
+	ExportPersonalData(userID int64) (*models.DataExport, error)
+	ErasePersonalData(userID int64) (*models.User, error)
+	GetUserTags(userID int64) (*models.UserTags, error)
+	AddUserTags(userID int64, tags []string) (*models.UserTags, error)
+	RemoveUserTag(userID int64, tag string) (*models.UserTags, error)
+	IssueAPIToken(userID int64) (*models.APIToken, error)
+	RevokeAPIToken(userID, tokenID int64) (*models.APIToken, error)
+	GetNotificationPreferences(userID int64) (*models.NotificationPreferences, error)
+	SetNotificationPreferences(userID int64, req models.NotificationPreferencesRequest) (*models.NotificationPreferences, error)
+	GetPushDevices(userID int64) (*models.PushDeviceList, error)
+	RegisterPushDevice(userID int64, req models.PushDeviceRequest) (*models.PushDevice, error)
+	UnregisterPushDevice(userID int64, token string) error
+
+	// Wallets and escrow
+	CreateWallet(userID int64, walletID string) (*models.Wallet, error)
+	GetWallets(userID int64) (*models.WalletList, error)
+	GetWalletBalance(userID int64, walletID string) (*models.WalletBalanceResponse, error)
+	CreateEscrowAccount(name string) (*models.EscrowAccount, error)
+	GetEscrowAccount(name string) (*models.EscrowAccount, error)
+	HoldInEscrow(ctx context.Context, name string, req models.EscrowRequest, sourceType string) (*models.TransferResponse, error)
+	ReleaseFromEscrow(ctx context.Context, name string, req models.EscrowRequest, sourceType string) (*models.TransferResponse, error)
+
+	// Operations
+	ChargeBackDeposit(id string, req models.ChargebackRequest) (*models.Deposit, error)
+	OpenDispute(req models.OpenDisputeRequest) (*models.Dispute, error)
+	GetDispute(id int64) (*models.Dispute, error)
+	GetDisputesPage(status string, page Page) (*models.DisputeList, error)
+	ResolveDispute(id int64, req models.ResolveDisputeRequest) (*models.Dispute, error)
+	GetAMLFlagsPage(status string, page Page) (*models.AMLFlagList, error)
+	ResolveAMLFlag(id int64, req models.ResolveAMLFlagRequest) (*models.AMLFlag, error)
+	GetWebhookDeadLettersPage(page Page) (*models.WebhookDeadLetterList, error)
+	ReplayWebhookDeadLetter(id int64, send func(url string, payload []byte) error) (*models.WebhookDeadLetter, error)
+	GetReconciliation(day time.Time, filter models.TagFilter) (*models.ReconciliationReport, error)
+	GetStats(window string, length time.Duration, filter models.TagFilter) (*models.StatsResponse, error)
+	GetTopUsers(metric, currency string, limit int, filter models.TagFilter) (*models.TopReport, error)
+}
+
+var _ TransactionProcessor = (*TransactionService)(nil)
@@ -0,0 +1,135 @@
+package core
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"assignment/internal/models"
+	"assignment/internal/validation"
+)
+
+// ConsumeTransaction applies a transaction read from a message broker and
+// commits the message offset in the same database transaction as its
+// outcome (applied, rejected or duplicate). A message at or below the
+// committed offset of its partition is a redelivery and is ignored with the
+// message "Duplicate message ignored", so a broker redelivering messages
+// can never apply one twice.
+//
+// Consumers must resume each partition from CommittedOffset + 1 rather than
+// from the broker's own offsets. When ConsumeTransaction returns an error the
+// offset has not moved: the consumer must either retry the message or, for
+// messages that can never succeed (e.g. failed validation), dead-letter it
+// and call SkipMessage before reading further.
+func (s *TransactionService) ConsumeTransaction(msg models.QueueMessage) (*models.TransactionResponse, error) {
+	if msg.Consumer == "" || msg.Topic == "" {
+		return nil, validation.Errorf("consumer", "invalid message: consumer and topic are required")
+	}
+	if msg.Offset < 0 {
+		return nil, validation.Errorf("offset", "invalid message: offset must not be negative")
+	}
+	if msg.Transaction.TransactionID == "" {
+		return nil, validation.Errorf("transactionId", "invalid message: transaction ID is required")
+	}
+	if err := s.ValidateTransactionID(msg.Transaction.TransactionID); err != nil {
+		return nil, err
+	}
+	return s.processTransaction(trustedContext, msg.UserID, AccountUser, msg.Transaction, msg.SourceType, s.clock.Now(), &msg, nil)
+}
+
+// SkipMessage commits the offset of a message without applying it.
+func (s *TransactionService) SkipMessage(msg models.QueueMessage) error {
+	now := s.clock.Now()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	delivered, err := lockConsumerOffset(tx, &msg, now)
+	if err != nil {
+		return err
+	}
+	if delivered {
+		return nil
+	}
+	if err := commitConsumerOffset(tx, &msg, now); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// CommittedOffset returns the last committed offset of a consumer's
+// partition, or -1 when nothing has been consumed from it.
+func (s *TransactionService) CommittedOffset(consumer, topic string, partition int32) (int64, error) {
+	var offset int64
+	err := s.db.QueryRow(
+		`SELECT committed_offset FROM consumer_offsets WHERE consumer = $1 AND topic = $2 AND partition = $3`,
+		consumer,
+		topic,
+		partition,
+	).Scan(&offset)
+	if err == sql.ErrNoRows {
+		return -1, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get consumer offset: %w", err)
+	}
+	return offset, nil
+}
+
+// lockConsumerOffset locks the offset row of the message's partition for
+// the rest of tx, so concurrent deliveries of one partition serialize, and
+// reports whether the message was already consumed.
+func lockConsumerOffset(tx *sql.Tx, msg *models.QueueMessage, now time.Time) (bool, error) {
+	_, err := tx.Exec(
+		`INSERT INTO consumer_offsets (consumer, topic, partition, committed_offset, updated_at)
+		 VALUES ($1, $2, $3, -1, $4)
+		 ON CONFLICT (consumer, topic, partition) DO NOTHING`,
+		msg.Consumer,
+		msg.Topic,
+		msg.Partition,
+		now,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to create consumer offset: %w", err)
+	}
+
+	var committed int64
+	err = tx.QueryRow(
+		`SELECT committed_offset FROM consumer_offsets
+		 WHERE consumer = $1 AND topic = $2 AND partition = $3 FOR UPDATE`,
+		msg.Consumer,
+		msg.Topic,
+		msg.Partition,
+	).Scan(&committed)
+	if err != nil {
+		return false, fmt.Errorf("failed to lock consumer offset: %w", err)
+	}
+	return msg.Offset <= committed, nil
+}
+
+// commitConsumerOffset records msg as consumed within tx. It does nothing
+// when msg is nil.
+func commitConsumerOffset(tx *sql.Tx, msg *models.QueueMessage, now time.Time) error {
+	if msg == nil {
+		return nil
+	}
+	_, err := tx.Exec(
+		`UPDATE consumer_offsets SET committed_offset = $4, updated_at = $5
+		 WHERE consumer = $1 AND topic = $2 AND partition = $3`,
+		msg.Consumer,
+		msg.Topic,
+		msg.Partition,
+		msg.Offset,
+		now,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to commit consumer offset: %w", err)
+	}
+	return nil
+}
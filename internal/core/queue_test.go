@@ -0,0 +1,97 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"assignment/internal/clock"
+	"assignment/internal/models"
+	"assignment/internal/testutil"
+)
+
+func queueMessage(offset int64, req models.TransactionRequest) models.QueueMessage {
+	return models.QueueMessage{
+		Consumer:    "wallet",
+		Topic:       "transactions",
+		Partition:   0,
+		Offset:      offset,
+		UserID:      1,
+		SourceType:  "game",
+		Transaction: req,
+	}
+}
+
+func TestConsumeTransaction_Redelivery(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.NewFake(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)))
+
+	if offset, err := service.CommittedOffset("wallet", "transactions", 0); err != nil || offset != -1 {
+		t.Fatalf("Expected no committed offset, got: %d, %v", offset, err)
+	}
+
+	win := queueMessage(0, testutil.NewTransactionRequest("win", "10.00"))
+	resp, err := service.ConsumeTransaction(win)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if resp.Balance != "110.00" {
+		t.Errorf("Expected balance 110.00, got: %s", resp.Balance)
+	}
+
+	// A rejection also consumes its message; funds arriving later must not
+	// let a redelivery of it succeed
+	lose := queueMessage(1, testutil.NewTransactionRequest("lose", "200.00"))
+	if resp, err := service.ConsumeTransaction(lose); err != nil || resp.Message != "Insufficient funds" {
+		t.Fatalf("Expected insufficient funds, got: %v, %v", resp, err)
+	}
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("win", "500.00"), "payment")
+
+	for _, msg := range []models.QueueMessage{win, lose} {
+		resp, err := service.ConsumeTransaction(msg)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if resp.Message != "Duplicate message ignored" {
+			t.Errorf("Expected redelivery of offset %d to be ignored, got: %s", msg.Offset, resp.Message)
+		}
+		if resp.Balance != "610.00" {
+			t.Errorf("Expected balance 610.00, got: %s", resp.Balance)
+		}
+	}
+
+	if offset, err := service.CommittedOffset("wallet", "transactions", 0); err != nil || offset != 1 {
+		t.Errorf("Expected committed offset 1, got: %d, %v", offset, err)
+	}
+}
+
+func TestSkipMessage(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.NewFake(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)))
+
+	invalid := queueMessage(5, testutil.NewTransactionRequest("draw", "1.00"))
+	if _, err := service.ConsumeTransaction(invalid); err == nil {
+		t.Fatal("Expected validation error")
+	}
+	if offset, _ := service.CommittedOffset("wallet", "transactions", 0); offset != -1 {
+		t.Errorf("Expected failed message to leave the offset alone, got: %d", offset)
+	}
+
+	if err := service.SkipMessage(invalid); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if offset, _ := service.CommittedOffset("wallet", "transactions", 0); offset != 5 {
+		t.Errorf("Expected committed offset 5, got: %d", offset)
+	}
+
+	// Skipping never moves the offset backwards
+	if err := service.SkipMessage(queueMessage(3, invalid.Transaction)); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if offset, _ := service.CommittedOffset("wallet", "transactions", 0); offset != 5 {
+		t.Errorf("Expected committed offset 5, got: %d", offset)
+	}
+}
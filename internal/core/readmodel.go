@@ -0,0 +1,69 @@
+package core
+
+import (
+	"fmt"
+)
+
+// projectEvents applies the events selected by the events CTE to the read
+// model and returns how many were selected. Replaying an event is harmless:
+// history rows are keyed by stream version and balances only move forward.
+const projectEvents = `WITH %s,
+	history AS (
+		INSERT INTO read_balance_history (user_id, version, balance, transaction_id, recorded_at)
+		SELECT user_id, version, balance, transaction_id, recorded_at FROM events
+		ON CONFLICT (user_id, version) DO NOTHING
+	),
+	balances AS (
+		INSERT INTO read_balances (user_id, balance, version, updated_at)
+		SELECT DISTINCT ON (user_id) user_id, balance, version, recorded_at FROM events
+		ORDER BY user_id, version DESC
+		ON CONFLICT (user_id) DO UPDATE
+		SET balance = EXCLUDED.balance, version = EXCLUDED.version, updated_at = EXCLUDED.updated_at
+		WHERE read_balances.version < EXCLUDED.version
+	)
+	SELECT COUNT(*) FROM events`
+
+// UseReadModel serves balance and history reads from the read model tables,
+// which the projector keeps up to date from the balance event stream, and
+// queues every new event for projection. Call it before serving requests and
+// before CatchUpReadModel.
+func (s *TransactionService) UseReadModel() {
+	s.readModel = true
+}
+
+// CatchUpReadModel projects every stored event, e.g. events appended while
+// the read model was disabled. It scans the whole event stream and is meant
+// to run once at startup.
+func (s *TransactionService) CatchUpReadModel() (int, error) {
+	var n int
+	err := s.db.QueryRow(fmt.Sprintf(projectEvents,
+		`events AS (SELECT user_id, version, balance, transaction_id, recorded_at FROM balance_events)`,
+	)).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to catch up read model: %w", err)
+	}
+	return n, nil
+}
+
+// ProjectReadModel takes up to limit events from the outbox, applies them to
+// the read model and returns how many were projected. Concurrent projectors
+// take disjoint batches.
+func (s *TransactionService) ProjectReadModel(limit int) (int, error) {
+	var n int
+	err := s.db.QueryRow(fmt.Sprintf(projectEvents,
+		`batch AS (
+			DELETE FROM read_model_outbox WHERE event_id IN (
+				SELECT event_id FROM read_model_outbox ORDER BY event_id LIMIT $1 FOR UPDATE SKIP LOCKED
+			)
+			RETURNING event_id
+		),
+		events AS (
+			SELECT e.user_id, e.version, e.balance, e.transaction_id, e.recorded_at
+			FROM balance_events e JOIN batch b ON b.event_id = e.id
+		)`,
+	), limit).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to project read model: %w", err)
+	}
+	return n, nil
+}
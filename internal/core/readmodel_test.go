@@ -0,0 +1,81 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"assignment/internal/clock"
+	"assignment/internal/testutil"
+)
+
+func TestReadModel(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	clk := clock.NewFake(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	service := NewTransactionService(db, clk)
+
+	// Events appended before the read model is enabled are caught up
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("win", "10.00"), "game")
+
+	service.UseReadModel()
+	n, err := service.CatchUpReadModel()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	// Three opened streams plus one credit
+	if n != 4 {
+		t.Errorf("Expected 4 events caught up, got: %d", n)
+	}
+
+	clk.Advance(time.Hour)
+	win := testutil.NewTransactionRequest("win", "5.00")
+	mustProcess(t, service, 1, win, "game")
+
+	// Reads are eventually consistent: nothing changes until projection
+	balance, err := service.GetBalance(1)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if balance.Balance != "110.00" {
+		t.Errorf("Expected stale balance 110.00 before projection, got: %s", balance.Balance)
+	}
+
+	if n, err := service.ProjectReadModel(100); err != nil || n != 1 {
+		t.Fatalf("Expected 1 event projected, got: %d, %v", n, err)
+	}
+	if n, err := service.ProjectReadModel(100); err != nil || n != 0 {
+		t.Fatalf("Expected empty outbox, got: %d, %v", n, err)
+	}
+
+	balance, err = service.GetBalance(1)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if balance.Balance != "115.00" {
+		t.Errorf("Expected balance 115.00, got: %s", balance.Balance)
+	}
+
+	history, err := service.GetBalanceHistory(1, testutil.Epoch, clk.Now())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(history.Snapshots) != 3 {
+		t.Fatalf("Expected 3 history entries, got: %d", len(history.Snapshots))
+	}
+	if last := history.Snapshots[2]; last.Balance != "115.00" || last.TransactionID != win.TransactionID {
+		t.Errorf("Unexpected last history entry: %+v", last)
+	}
+
+	// Catching up again must not duplicate history or move balances back
+	if _, err := service.CatchUpReadModel(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if history, _ = service.GetBalanceHistory(1, testutil.Epoch, clk.Now()); len(history.Snapshots) != 3 {
+		t.Errorf("Expected 3 history entries after replay, got: %d", len(history.Snapshots))
+	}
+
+	if _, err := service.GetBalance(999); err == nil || err.Error() != "user not found" {
+		t.Errorf("Expected 'user not found', got: %v", err)
+	}
+}
@@ -0,0 +1,177 @@
+package core
+
+import (
+	"database/sql"
+	"fmt"
+
+	"assignment/internal/models"
+)
+
+// replayQuery recomputes balances from the ledger. Each user's replay starts
+// at their latest checkpoint, or else at their baseline snapshot, and adds
+// the applied transactions recorded after it. Snapshot IDs order a user's
+// transactions as they were applied, regardless of their created_at. $1
+// restricts the replay to one user when not NULL.
+const replayQuery = `WITH base AS (
+		SELECT u.id AS user_id, u.balance AS current,
+		       COALESCE(c.balance, b.balance, 0) AS balance,
+		       COALESCE(c.snapshot_id, b.id, 0) AS after_id
+		FROM users u
+		LEFT JOIN LATERAL (
+			SELECT balance, snapshot_id FROM ledger_checkpoints
+			WHERE user_id = u.id ORDER BY snapshot_id DESC LIMIT 1
+		) c ON true
+		LEFT JOIN LATERAL (
+			SELECT id, balance FROM balance_snapshots
+			WHERE user_id = u.id AND transaction_id IS NULL ORDER BY id LIMIT 1
+		) b ON true
+		WHERE $1::BIGINT IS NULL OR u.id = $1
+	),
+	replayed AS (
+		SELECT base.user_id, base.current, base.after_id,
+		       (base.balance + COALESCE(SUM(CASE WHEN t.state = 'win' THEN t.amount ELSE -t.amount END), 0))::NUMERIC AS balance,
+		       GREATEST(base.after_id, COALESCE(MAX(s.id), 0)) AS last_id
+		FROM base
+		LEFT JOIN balance_snapshots s ON s.user_id = base.user_id AND s.id > base.after_id
+		LEFT JOIN transactions t ON t.transaction_id = s.transaction_id AND t.applied
+		GROUP BY base.user_id, base.current, base.balance, base.after_id
+	)`
+
+// ReplayLedger recomputes every user's balance from the ledger and reports
+// the users whose stored balance differs from the replay.
+func (s *TransactionService) ReplayLedger() (*models.ReplayReport, error) {
+	rows, err := s.db.Query(
+		replayQuery+`
+		SELECT user_id, current, balance, (balance - current)::NUMERIC, current <> balance
+		FROM replayed ORDER BY user_id`,
+		nil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay ledger: %w", err)
+	}
+	defer rows.Close()
+
+	report := &models.ReplayReport{Divergences: []models.BalanceDivergence{}}
+	for rows.Next() {
+		var d models.BalanceDivergence
+		var diverged bool
+		if err := rows.Scan(&d.UserID, &d.Balance, &d.Replayed, &d.Difference, &diverged); err != nil {
+			return nil, fmt.Errorf("failed to scan replayed balance: %w", err)
+		}
+		report.UsersChecked++
+		if diverged {
+			report.Divergences = append(report.Divergences, d)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read replayed balances: %w", err)
+	}
+
+	return report, nil
+}
+
+// RepairBalances replays the ledger and resets every diverging balance to
+// its replayed value. Each repair is recorded as a balance snapshot and as an
+// adjustment in the user's event stream. The returned report lists the
+// balances as they were before the repair.
+func (s *TransactionService) RepairBalances() (*models.ReplayReport, error) {
+	report, err := s.ReplayLedger()
+	if err != nil {
+		return nil, err
+	}
+
+	repaired := []models.BalanceDivergence{}
+	for _, d := range report.Divergences {
+		fixed, err := s.repairBalance(d.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if fixed != nil {
+			repaired = append(repaired, *fixed)
+		}
+	}
+	report.Divergences = repaired
+
+	return report, nil
+}
+
+// repairBalance replays one user under their row lock, so transactions
+// applied since the full replay are taken into account. It returns nil if
+// the balance no longer diverges.
+func (s *TransactionService) repairBalance(userID int64) (*models.BalanceDivergence, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT 1 FROM users WHERE id = $1 FOR UPDATE`, userID); err != nil {
+		return nil, fmt.Errorf("failed to lock user: %w", err)
+	}
+
+	var d models.BalanceDivergence
+	err = tx.QueryRow(
+		replayQuery+`
+		SELECT user_id, current, balance, (balance - current)::NUMERIC
+		FROM replayed WHERE current <> balance`,
+		userID,
+	).Scan(&d.UserID, &d.Balance, &d.Replayed, &d.Difference)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay user %d: %w", userID, err)
+	}
+
+	now := s.clock.Now()
+	if _, err := tx.Exec(`UPDATE users SET balance = $1, updated_at = $2 WHERE id = $3`, d.Replayed, now, userID); err != nil {
+		return nil, fmt.Errorf("failed to repair balance: %w", err)
+	}
+	_, err = tx.Exec(
+		`INSERT INTO balance_snapshots (user_id, balance, recorded_at) VALUES ($1, $2, $3)`,
+		userID,
+		d.Replayed,
+		now,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record balance snapshot: %w", err)
+	}
+
+	// Bring the event stream in line too, relative to its own head
+	head, err := s.openStream(tx, userID, d.Balance, now)
+	if err != nil {
+		return nil, err
+	}
+	if head.balance != d.Replayed {
+		var amount string
+		err := tx.QueryRow(`SELECT $1::NUMERIC - $2::NUMERIC`, d.Replayed, head.balance).Scan(&amount)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute adjustment: %w", err)
+		}
+		if err := s.appendEvent(tx, userID, head.version+1, EventAdjusted, amount, d.Replayed, "", now); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit balance repair: %w", err)
+	}
+	return &d, nil
+}
+
+// CreateLedgerCheckpoints stores the replayed balance of every user with
+// ledger activity since their last checkpoint, so later replays only need
+// to process newer transactions. It returns the number of checkpoints.
+func (s *TransactionService) CreateLedgerCheckpoints() (int64, error) {
+	result, err := s.db.Exec(
+		replayQuery+`
+		INSERT INTO ledger_checkpoints (user_id, balance, snapshot_id, created_at)
+		SELECT user_id, balance, last_id, $2::TIMESTAMP FROM replayed WHERE last_id > after_id`,
+		nil,
+		s.clock.Now(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create ledger checkpoints: %w", err)
+	}
+	return result.RowsAffected()
+}
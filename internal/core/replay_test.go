@@ -0,0 +1,101 @@
+package core
+
+import (
+	"testing"
+
+	"assignment/internal/clock"
+	"assignment/internal/testutil"
+)
+
+func TestReplayLedger(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.New())
+
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("win", "0.10"), "game")
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("win", "0.20"), "game")
+	mustProcess(t, service, 2, testutil.NewTransactionRequest("lose", "10.00"), "game")
+
+	report, err := service.ReplayLedger()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if report.UsersChecked != 3 || len(report.Divergences) != 0 {
+		t.Fatalf("Expected 3 consistent users, got: %+v", report)
+	}
+
+	// Simulate a rounding error in the stored balance
+	if _, err := db.Exec(`UPDATE users SET balance = 100.29 WHERE id = 1`); err != nil {
+		t.Fatalf("Failed to corrupt balance: %v", err)
+	}
+
+	report, err = service.ReplayLedger()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(report.Divergences) != 1 {
+		t.Fatalf("Expected 1 divergence, got: %+v", report.Divergences)
+	}
+	if d := report.Divergences[0]; d.UserID != 1 || d.Balance != "100.29" || d.Replayed != "100.30" || d.Difference != "0.01" {
+		t.Errorf("Unexpected divergence: %+v", d)
+	}
+
+	if report, err = service.RepairBalances(); err != nil || len(report.Divergences) != 1 {
+		t.Fatalf("Expected 1 repair, got: %+v, %v", report, err)
+	}
+
+	balance, err := service.GetBalance(1)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if balance.Balance != "100.30" {
+		t.Errorf("Expected repaired balance 100.30, got: %s", balance.Balance)
+	}
+	if report, _ = service.ReplayLedger(); len(report.Divergences) != 0 {
+		t.Errorf("Expected no divergence after repair, got: %+v", report.Divergences)
+	}
+	if drifted, _ := service.CheckProjections(); len(drifted) != 0 {
+		t.Errorf("Expected event streams to agree after repair, got: %v", drifted)
+	}
+}
+
+func TestCreateLedgerCheckpoints(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.New())
+
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("win", "5.00"), "game")
+
+	n, err := service.CreateLedgerCheckpoints()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("Expected 1 checkpoint, got: %d", n)
+	}
+
+	// Nothing changed since the last checkpoint
+	if n, _ := service.CreateLedgerCheckpoints(); n != 0 {
+		t.Errorf("Expected no new checkpoints, got: %d", n)
+	}
+
+	// Replay continues from the checkpoint
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("lose", "2.50"), "game")
+	report, err := service.ReplayLedger()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(report.Divergences) != 0 {
+		t.Errorf("Expected no divergence, got: %+v", report.Divergences)
+	}
+
+	var balance string
+	if err := db.QueryRow(`SELECT balance FROM ledger_checkpoints WHERE user_id = 1`).Scan(&balance); err != nil {
+		t.Fatalf("Failed to read checkpoint: %v", err)
+	}
+	if balance != "105.00" {
+		t.Errorf("Expected checkpoint balance 105.00, got: %s", balance)
+	}
+}
@@ -0,0 +1,313 @@
+package core
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"assignment/internal/models"
+	"assignment/internal/money"
+)
+
+// GetReconciliation returns the applied credit and debit totals per
+// currency and source type for the given UTC calendar day, together with the
+// resulting change in the aggregate balance of each currency, counting only
+// the users matching filter.
+func (s *TransactionService) GetReconciliation(day time.Time, filter models.TagFilter) (*models.ReconciliationReport, error) {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 1)
+	cond, tagArgs := tagCondition("t.user_id", filter, 3)
+	args := append([]interface{}{start, end}, tagArgs...)
+
+	rows, err := s.db.Query(
+		`SELECT u.currency, t.source_type,
+		        COALESCE(SUM(t.amount) FILTER (WHERE t.state = 'win'), 0),
+		        COUNT(*) FILTER (WHERE t.state = 'win'),
+		        COALESCE(SUM(t.amount) FILTER (WHERE t.state = 'lose'), 0),
+		        COUNT(*) FILTER (WHERE t.state = 'lose'),
+		        COALESCE(SUM(CASE WHEN t.state = 'win' THEN t.amount ELSE -t.amount END), 0)
+		 FROM transactions t JOIN users u ON u.id = t.user_id
+		 WHERE t.applied AND t.created_at >= $1 AND t.created_at < $2`+cond+`
+		 GROUP BY u.currency, t.source_type
+		 ORDER BY u.currency, t.source_type`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reconciliation totals: %w", err)
+	}
+	defer rows.Close()
+
+	report := &models.ReconciliationReport{
+		Date:          start.Format("2006-01-02"),
+		Sources:       []models.SourceTotals{},
+		BalanceDeltas: []models.CurrencyTotal{},
+	}
+	for rows.Next() {
+		var currency string
+		totals, err := scanSourceTotals(rows, &currency)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan reconciliation totals: %w", err)
+		}
+		totals.Currency = currency
+		report.Sources = append(report.Sources, totals)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read reconciliation totals: %w", err)
+	}
+
+	report.BalanceDeltas, err = s.currencyTotals(
+		`SELECT u.currency, SUM(CASE WHEN t.state = 'win' THEN t.amount ELSE -t.amount END)
+		 FROM transactions t JOIN users u ON u.id = t.user_id
+		 WHERE t.applied AND t.created_at >= $1 AND t.created_at < $2`+cond+`
+		 GROUP BY u.currency
+		 ORDER BY u.currency`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query balance deltas: %w", err)
+	}
+
+	return report, nil
+}
+
+// scanSourceTotals scans a row of source type totals led by the currency
+// they are in, into currency, and formats them with its decimals.
+func scanSourceTotals(rows *sql.Rows, currency *string) (models.SourceTotals, error) {
+	var totals models.SourceTotals
+	var credits, debits, net float64
+	if err := rows.Scan(
+		currency,
+		&totals.SourceType,
+		&credits,
+		&totals.CreditCount,
+		&debits,
+		&totals.DebitCount,
+		&net,
+	); err != nil {
+		return totals, err
+	}
+	totals.Credits = money.Format(credits, *currency)
+	totals.Debits = money.Format(debits, *currency)
+	totals.Net = money.Format(net, *currency)
+	return totals, nil
+}
+
+// currencyTotals runs query, which selects a currency and a sum of amounts
+// in it, and returns the sums formatted with the decimals of their
+// currency.
+func (s *TransactionService) currencyTotals(query string, args ...interface{}) ([]models.CurrencyTotal, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := []models.CurrencyTotal{}
+	for rows.Next() {
+		var total models.CurrencyTotal
+		var amount float64
+		if err := rows.Scan(&total.Currency, &amount); err != nil {
+			return nil, err
+		}
+		total.Amount = money.Format(amount, total.Currency)
+		totals = append(totals, total)
+	}
+	return totals, rows.Err()
+}
+
+// SaveSettlementReport stores the per-currency and source type totals of report,
+// replacing any totals previously stored for the same day.
+func (s *TransactionService) SaveSettlementReport(report *models.ReconciliationReport) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM settlement_reports WHERE report_date = $1`, report.Date); err != nil {
+		return fmt.Errorf("failed to clear settlement report: %w", err)
+	}
+
+	now := s.clock.Now()
+	for _, totals := range report.Sources {
+		_, err := tx.Exec(
+			`INSERT INTO settlement_reports (report_date, currency, source_type, credits, credit_count, debits, debit_count, net, created_at)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+			report.Date,
+			totals.Currency,
+			totals.SourceType,
+			totals.Credits,
+			totals.CreditCount,
+			totals.Debits,
+			totals.DebitCount,
+			totals.Net,
+			now,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert settlement report: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit settlement report: %w", err)
+	}
+	return nil
+}
+
+// GetStats aggregates the activity of the users matching filter over the
+// window ending now, with amounts totalled per currency. window is only
+// echoed back in the response.
+func (s *TransactionService) GetStats(window string, length time.Duration, filter models.TagFilter) (*models.StatsResponse, error) {
+	to := s.clock.Now()
+	from := to.Add(-length)
+	cond, tagArgs := tagCondition("user_id", filter, 3)
+	args := append([]interface{}{from, to}, tagArgs...)
+	txCond, _ := tagCondition("t.user_id", filter, 3)
+
+	stats := &models.StatsResponse{
+		Window:       window,
+		From:         from,
+		To:           to,
+		Transactions: []models.TransactionStats{},
+	}
+
+	rows, err := s.db.Query(
+		`SELECT u.currency, t.state, t.source_type, COUNT(*), SUM(t.amount)
+		 FROM transactions t JOIN users u ON u.id = t.user_id
+		 WHERE t.applied AND t.created_at > $1 AND t.created_at <= $2`+txCond+`
+		 GROUP BY u.currency, t.state, t.source_type
+		 ORDER BY u.currency, t.state, t.source_type`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query transaction stats: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row models.TransactionStats
+		var amount float64
+		if err := rows.Scan(&row.Currency, &row.State, &row.SourceType, &row.Count, &amount); err != nil {
+			return nil, fmt.Errorf("failed to scan transaction stats: %w", err)
+		}
+		row.Amount = money.Format(amount, row.Currency)
+		stats.Transactions = append(stats.Transactions, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read transaction stats: %w", err)
+	}
+
+	err = s.db.QueryRow(
+		`SELECT COUNT(*) FROM transaction_rejections
+		 WHERE reason = 'insufficient_funds' AND created_at > $1 AND created_at <= $2`+cond,
+		args...,
+	).Scan(&stats.InsufficientFundsRejections)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count rejections: %w", err)
+	}
+
+	cond, tagArgs = tagCondition("id", filter, 1)
+	err = s.db.QueryRow(`SELECT COUNT(*) FROM users WHERE TRUE`+cond, tagArgs...).Scan(&stats.UserCount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count users: %w", err)
+	}
+	stats.TotalBalances, err = s.currencyTotals(
+		`SELECT currency, SUM(balance) FROM users WHERE TRUE`+cond+`
+		 GROUP BY currency
+		 ORDER BY currency`,
+		tagArgs...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum balances: %w", err)
+	}
+
+	return stats, nil
+}
+
+// ListTransactionsBetween returns every stored transaction created in
+// [from, to) with the currency of its user, ordered by ID.
+func (s *TransactionService) ListTransactionsBetween(from, to time.Time) ([]models.Transaction, error) {
+	rows, err := s.db.Query(
+		`SELECT t.id, t.user_id, t.transaction_id, t.state, t.amount, u.currency, t.source_type, t.applied, t.created_at
+		 FROM transactions t JOIN users u ON u.id = t.user_id
+		 WHERE t.created_at >= $1 AND t.created_at < $2
+		 ORDER BY t.id`,
+		from,
+		to,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query transactions: %w", err)
+	}
+	defer rows.Close()
+
+	transactions := []models.Transaction{}
+	for rows.Next() {
+		var t models.Transaction
+		if err := rows.Scan(
+			&t.ID,
+			&t.UserID,
+			&t.TransactionID,
+			&t.State,
+			&t.Amount,
+			&t.Currency,
+			&t.SourceType,
+			&t.Applied,
+			&t.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+		transactions = append(transactions, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read transactions: %w", err)
+	}
+
+	return transactions, nil
+}
+
+// topQueries rank the users of a currency by each supported metric, leaving
+// out escrow accounts and deleted users. Both are served by an index on the
+// ranked column, so no full scan of the ledger is needed. The %s is replaced
+// by a tag condition on the user ID.
+var topQueries = map[string]string{
+	"balance": `SELECT u.id, u.balance FROM users u WHERE u.kind = 'user' AND u.deleted_at IS NULL AND u.currency = $2%s
+	 ORDER BY u.balance DESC, u.id LIMIT $1`,
+	"net_win": `SELECT t.user_id, t.net_win FROM user_totals t JOIN users u ON u.id = t.user_id
+	 WHERE u.kind = 'user' AND u.deleted_at IS NULL AND u.currency = $2%s ORDER BY t.net_win DESC, t.user_id LIMIT $1`,
+}
+
+// GetTopUsers returns up to limit users of currency matching filter ranked
+// by metric, highest first. Amounts in different currencies are not
+// comparable, so each currency is ranked on its own.
+func (s *TransactionService) GetTopUsers(metric, currency string, limit int, filter models.TagFilter) (*models.TopReport, error) {
+	query, ok := topQueries[metric]
+	if !ok {
+		return nil, fmt.Errorf("unknown metric %q", metric)
+	}
+	cond, tagArgs := tagCondition("u.id", filter, 3)
+
+	rows, err := s.db.Query(fmt.Sprintf(query, cond), append([]interface{}{limit, currency}, tagArgs...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top users: %w", err)
+	}
+	defer rows.Close()
+
+	report := &models.TopReport{
+		Metric:   metric,
+		Currency: currency,
+		Limit:    limit,
+		Users:    []models.RankedUser{},
+	}
+	for rows.Next() {
+		entry := models.RankedUser{Rank: len(report.Users) + 1}
+		if err := rows.Scan(&entry.UserID, &entry.Value); err != nil {
+			return nil, fmt.Errorf("failed to scan top users: %w", err)
+		}
+		report.Users = append(report.Users, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read top users: %w", err)
+	}
+
+	return report, nil
+}
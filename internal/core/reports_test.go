@@ -0,0 +1,179 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"assignment/internal/clock"
+	"assignment/internal/models"
+	"assignment/internal/testutil"
+)
+
+func TestGetReconciliation(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	day := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(day)
+	service := NewTransactionService(db, clk)
+
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("win", "10.50"), "game")
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("lose", "25.00"), "server")
+	mustProcess(t, service, 2, testutil.NewTransactionRequest("lose", "5.25"), "game")
+	// Rejected debits are not applied and must not be counted
+	mustProcess(t, service, 3, testutil.NewTransactionRequest("lose", "1.00"), "game")
+
+	// Next day's activity must not leak into the report
+	clk.Advance(24 * time.Hour)
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("win", "99.00"), "game")
+
+	report, err := service.GetReconciliation(day, models.TagFilter{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if report.Date != "2024-01-15" {
+		t.Errorf("Expected date 2024-01-15, got: %s", report.Date)
+	}
+	if len(report.BalanceDeltas) != 1 || report.BalanceDeltas[0] != (models.CurrencyTotal{Currency: "EUR", Amount: "-19.75"}) {
+		t.Errorf("Expected EUR balance delta -19.75, got: %+v", report.BalanceDeltas)
+	}
+	if len(report.Sources) != 2 {
+		t.Fatalf("Expected 2 source types, got: %d", len(report.Sources))
+	}
+
+	game := report.Sources[0]
+	if game.Currency != "EUR" || game.SourceType != "game" || game.Credits != "10.50" || game.Debits != "5.25" || game.CreditCount != 1 || game.DebitCount != 1 {
+		t.Errorf("Unexpected game totals: %+v", game)
+	}
+	server := report.Sources[1]
+	if server.SourceType != "server" || server.Debits != "25.00" || server.Net != "-25.00" {
+		t.Errorf("Unexpected server totals: %+v", server)
+	}
+}
+
+func TestGetReconciliation_PerCurrency(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	day := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	service := NewTransactionService(db, clock.NewFake(day))
+
+	yen, err := service.CreateUser(models.CreateUserRequest{Balance: "1000", Currency: "JPY"})
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("win", "10.50"), "game")
+	mustProcess(t, service, yen.ID, testutil.NewTransactionRequest("win", "500"), "game")
+
+	report, err := service.GetReconciliation(day, models.TagFilter{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	// Amounts in different currencies must never be added up
+	want := []models.CurrencyTotal{{Currency: "EUR", Amount: "10.50"}, {Currency: "JPY", Amount: "500"}}
+	if len(report.BalanceDeltas) != 2 || report.BalanceDeltas[0] != want[0] || report.BalanceDeltas[1] != want[1] {
+		t.Errorf("Expected balance deltas %+v, got: %+v", want, report.BalanceDeltas)
+	}
+	if len(report.Sources) != 2 || report.Sources[1].Currency != "JPY" || report.Sources[1].Credits != "500" {
+		t.Errorf("Expected game totals per currency, got: %+v", report.Sources)
+	}
+}
+
+func TestGetStats(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(now.Add(-48 * time.Hour))
+	service := NewTransactionService(db, clk)
+
+	// Outside the window
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("win", "50.00"), "game")
+
+	clk.Set(now.Add(-time.Hour))
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("win", "10.00"), "game")
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("win", "2.50"), "game")
+	mustProcess(t, service, 2, testutil.NewTransactionRequest("lose", "20.00"), "payment")
+	mustProcess(t, service, 3, testutil.NewTransactionRequest("lose", "1.00"), "game")
+
+	clk.Set(now)
+	stats, err := service.GetStats("24h", 24*time.Hour, models.TagFilter{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(stats.Transactions) != 2 {
+		t.Fatalf("Expected 2 state/source groups, got: %+v", stats.Transactions)
+	}
+	if win := stats.Transactions[1]; win.State != "win" || win.Count != 2 || win.Amount != "12.50" {
+		t.Errorf("Unexpected win stats: %+v", win)
+	}
+	if stats.InsufficientFundsRejections != 1 {
+		t.Errorf("Expected 1 rejection, got: %d", stats.InsufficientFundsRejections)
+	}
+	if stats.UserCount != 3 || len(stats.TotalBalances) != 1 || stats.TotalBalances[0] != (models.CurrencyTotal{Currency: "EUR", Amount: "192.50"}) {
+		t.Errorf("Expected 3 users holding EUR 192.50, got: %d holding %+v", stats.UserCount, stats.TotalBalances)
+	}
+}
+
+func TestGetTopUsers(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.New())
+
+	mustProcess(t, service, 2, testutil.NewTransactionRequest("win", "80.00"), "game")
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("win", "30.00"), "game")
+	// Payments and transfers move funds and are left out of net winnings
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("lose", "10.00"), "payment")
+	if _, err := service.Transfer(models.TransferRequest{FromUserID: 2, ToUserID: 1, Amount: "4.00"}, "game"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	// Rejected debits must not reduce net winnings
+	mustProcess(t, service, 3, testutil.NewTransactionRequest("lose", "1.00"), "game")
+	// Users of another currency are ranked separately
+	yen, err := service.CreateUser(models.CreateUserRequest{Balance: "100000", Currency: "JPY"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	mustProcess(t, service, yen.ID, testutil.NewTransactionRequest("win", "500"), "game")
+
+	byBalance, err := service.GetTopUsers("balance", DefaultCurrency, 2, models.TagFilter{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(byBalance.Users) != 2 {
+		t.Fatalf("Expected 2 users, got: %d", len(byBalance.Users))
+	}
+	if first := byBalance.Users[0]; first.Rank != 1 || first.UserID != 2 || first.Value != "126.00" {
+		t.Errorf("Unexpected top balance: %+v", first)
+	}
+	if second := byBalance.Users[1]; second.UserID != 1 || second.Value != "124.00" {
+		t.Errorf("Unexpected second balance: %+v", second)
+	}
+
+	byNetWin, err := service.GetTopUsers("net_win", DefaultCurrency, 10, models.TagFilter{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(byNetWin.Users) != 2 {
+		t.Fatalf("Expected 2 users with activity, got: %d", len(byNetWin.Users))
+	}
+	if byNetWin.Users[0].UserID != 2 || byNetWin.Users[1].Value != "30.00" {
+		t.Errorf("Unexpected net win ranking: %+v", byNetWin.Users)
+	}
+
+	byYen, err := service.GetTopUsers("net_win", "JPY", 10, models.TagFilter{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(byYen.Users) != 1 || byYen.Users[0].UserID != yen.ID || byYen.Currency != "JPY" {
+		t.Errorf("Unexpected JPY ranking: %+v", byYen)
+	}
+
+	if _, err := service.GetTopUsers("losses", DefaultCurrency, 10, models.TagFilter{}); err == nil {
+		t.Error("Expected error for unknown metric, got none")
+	}
+}
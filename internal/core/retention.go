@@ -0,0 +1,169 @@
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"assignment/internal/models"
+)
+
+// Retention modes: purging deletes old transactions, anonymizing replaces
+// their client-supplied transaction IDs, and the references to them in
+// balance snapshots, with "anon-" and the row ID, and drops their stored
+// responses, which repeat the IDs.
+const (
+	RetentionPurge     = "purge"
+	RetentionAnonymize = "anonymize"
+)
+
+// retentionBatchSize bounds the rows changed per database transaction, so a
+// first run over years of history doesn't hold locks for long.
+const retentionBatchSize = 10000
+
+// retentionCondition selects the transactions a retention run may touch:
+// those created before $1 that are either not applied, or applied and
+// covered by a ledger checkpoint, so replays never need them again.
+// Transactions a dispute refers to, as the disputed transaction or as one of
+// its entries, are kept with the dispute: disputes reference them by
+// transaction ID, so they could be neither deleted nor anonymized.
+const retentionCondition = `t.created_at < $1
+	AND (NOT t.applied OR EXISTS (
+		SELECT 1 FROM balance_snapshots s
+		JOIN ledger_checkpoints c ON c.user_id = s.user_id AND c.snapshot_id >= s.id
+		WHERE s.transaction_id = t.transaction_id
+	))
+	AND NOT EXISTS (
+		SELECT 1 FROM disputes d
+		WHERE t.transaction_id IN (d.transaction_id, d.hold_transaction_id, d.resolution_transaction_id)
+	)`
+
+// ValidRetentionMode reports whether mode is a retention mode.
+func ValidRetentionMode(mode string) bool {
+	return mode == RetentionPurge || mode == RetentionAnonymize
+}
+
+// RetentionCutoff returns the creation time before which transactions are
+// past a retention period of the given number of years.
+func RetentionCutoff(now time.Time, years int) time.Time {
+	return now.AddDate(-years, 0, 0)
+}
+
+// ApplyRetention purges or anonymizes the transactions created before
+// cutoff. Settlement totals are stored first for every day they fall on that
+// has none, and balances are checkpointed, so aggregates and ledger replays
+// survive the removal. Applied transactions a checkpoint doesn't cover, e.g.
+// because it failed, are left for a later run. A dry run only reports what
+// would be changed.
+func (s *TransactionService) ApplyRetention(cutoff time.Time, mode string, dryRun bool) (*models.RetentionReport, error) {
+	if !ValidRetentionMode(mode) {
+		return nil, fmt.Errorf("invalid retention mode %q: must be %q or %q", mode, RetentionPurge, RetentionAnonymize)
+	}
+	cond := retentionCondition
+	if mode == RetentionAnonymize {
+		cond += ` AND t.transaction_id NOT LIKE 'anon-%'`
+	}
+
+	if !dryRun {
+		if _, err := s.CreateLedgerCheckpoints(); err != nil {
+			return nil, err
+		}
+	}
+
+	report := &models.RetentionReport{Mode: mode, Cutoff: cutoff, DryRun: dryRun}
+	err := s.db.QueryRow(
+		`SELECT COUNT(*), COUNT(DISTINCT t.user_id), MIN(t.created_at)
+		 FROM transactions t WHERE `+cond,
+		cutoff,
+	).Scan(&report.Transactions, &report.Users, &report.Oldest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count expired transactions: %w", err)
+	}
+
+	days, err := s.unsettledDays(cond, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	report.SettlementDays = len(days)
+	if dryRun || report.Transactions == 0 {
+		return report, nil
+	}
+
+	for _, day := range days {
+		totals, err := s.GetReconciliation(day, models.TagFilter{})
+		if err != nil {
+			return nil, err
+		}
+		if err := s.SaveSettlementReport(totals); err != nil {
+			return nil, err
+		}
+	}
+
+	var total int64
+	for {
+		n, err := s.expireBatch(cond, cutoff, mode)
+		if err != nil {
+			return nil, err
+		}
+		total += n
+		if n < retentionBatchSize {
+			break
+		}
+	}
+	report.Transactions = total
+
+	return report, nil
+}
+
+// unsettledDays returns the UTC days of the transactions matching cond that
+// have no stored settlement totals.
+func (s *TransactionService) unsettledDays(cond string, cutoff time.Time) ([]time.Time, error) {
+	rows, err := s.db.Query(
+		`SELECT DISTINCT t.created_at::DATE AS day FROM transactions t
+		 WHERE `+cond+`
+		   AND NOT EXISTS (SELECT 1 FROM settlement_reports r WHERE r.report_date = t.created_at::DATE)
+		 ORDER BY day`,
+		cutoff,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query unsettled days: %w", err)
+	}
+	defer rows.Close()
+
+	var days []time.Time
+	for rows.Next() {
+		var day time.Time
+		if err := rows.Scan(&day); err != nil {
+			return nil, fmt.Errorf("failed to scan unsettled day: %w", err)
+		}
+		days = append(days, day)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read unsettled days: %w", err)
+	}
+	return days, nil
+}
+
+// expireBatch purges or anonymizes up to retentionBatchSize transactions
+// matching cond and returns how many it changed.
+func (s *TransactionService) expireBatch(cond string, cutoff time.Time, mode string) (int64, error) {
+	batch := `SELECT t.id, t.transaction_id FROM transactions t WHERE ` + cond +
+		fmt.Sprintf(` ORDER BY t.id LIMIT %d FOR UPDATE SKIP LOCKED`, retentionBatchSize)
+
+	query := `WITH batch AS (` + batch + `)
+		DELETE FROM transactions t USING batch WHERE t.id = batch.id`
+	if mode == RetentionAnonymize {
+		query = `WITH batch AS (` + batch + `),
+		snapshots AS (
+			UPDATE balance_snapshots s SET transaction_id = 'anon-' || batch.id
+			FROM batch WHERE s.transaction_id = batch.transaction_id
+		)
+		UPDATE transactions t SET transaction_id = 'anon-' || batch.id, response = NULL
+		FROM batch WHERE t.id = batch.id`
+	}
+
+	result, err := s.db.Exec(query, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to %s expired transactions: %w", mode, err)
+	}
+	return result.RowsAffected()
+}
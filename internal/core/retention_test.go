@@ -0,0 +1,171 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"assignment/internal/clock"
+	"assignment/internal/models"
+	"assignment/internal/testutil"
+)
+
+func TestRetentionCutoff(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	want := time.Date(2017, 3, 1, 12, 0, 0, 0, time.UTC)
+	if got := RetentionCutoff(now, 7); !got.Equal(want) {
+		t.Errorf("Expected cutoff %v, got: %v", want, got)
+	}
+}
+
+func TestApplyRetentionRejectsUnknownMode(t *testing.T) {
+	service := NewTransactionService(nil, clock.New())
+	if _, err := service.ApplyRetention(time.Now(), "archive", true); err == nil {
+		t.Error("Expected an error for an unknown mode")
+	}
+}
+
+func TestApplyRetention(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	clk := clock.NewFake(time.Date(2016, 5, 10, 12, 0, 0, 0, time.UTC))
+	service := NewTransactionService(db, clk)
+
+	old := testutil.NewTransactionRequest("win", "10.00")
+	mustProcess(t, service, 1, old, "game")
+	clk.Set(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	recent := testutil.NewTransactionRequest("lose", "5.00")
+	mustProcess(t, service, 1, recent, "game")
+	cutoff := RetentionCutoff(clk.Now(), 7)
+
+	// A dry run reports the old transaction without touching it
+	report, err := service.ApplyRetention(cutoff, RetentionPurge, true)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if report.Transactions != 0 {
+		t.Errorf("Expected no transactions before a checkpoint covers them, got: %d", report.Transactions)
+	}
+	if _, err := service.CreateLedgerCheckpoints(); err != nil {
+		t.Fatalf("Failed to create checkpoints: %v", err)
+	}
+	report, err = service.ApplyRetention(cutoff, RetentionPurge, true)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if report.Transactions != 1 || report.Users != 1 || report.SettlementDays != 1 {
+		t.Errorf("Expected 1 transaction of 1 user on 1 unsettled day, got: %+v", report)
+	}
+
+	report, err = service.ApplyRetention(cutoff, RetentionPurge, false)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if report.Transactions != 1 {
+		t.Errorf("Expected 1 purged transaction, got: %d", report.Transactions)
+	}
+
+	var remaining int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM transactions`).Scan(&remaining); err != nil {
+		t.Fatalf("Failed to count transactions: %v", err)
+	}
+	if remaining != 1 {
+		t.Errorf("Expected only the recent transaction to remain, got: %d", remaining)
+	}
+	var credits string
+	if err := db.QueryRow(`SELECT credits FROM settlement_reports WHERE report_date = '2016-05-10'`).Scan(&credits); err != nil {
+		t.Fatalf("Expected the purged day to be settled: %v", err)
+	}
+	if credits != "10.00" {
+		t.Errorf("Expected settled credits 10.00, got: %s", credits)
+	}
+
+	// Replays are unaffected by the purge
+	replay, err := service.ReplayLedger()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(replay.Divergences) != 0 {
+		t.Errorf("Expected no divergence, got: %+v", replay.Divergences)
+	}
+}
+
+func TestApplyRetentionAnonymize(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	clk := clock.NewFake(time.Date(2016, 5, 10, 12, 0, 0, 0, time.UTC))
+	service := NewTransactionService(db, clk)
+
+	old := testutil.NewTransactionRequest("win", "10.00")
+	mustProcess(t, service, 1, old, "game")
+	clk.Set(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	cutoff := RetentionCutoff(clk.Now(), 7)
+
+	report, err := service.ApplyRetention(cutoff, RetentionAnonymize, false)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if report.Transactions != 1 {
+		t.Errorf("Expected 1 anonymized transaction, got: %d", report.Transactions)
+	}
+
+	var found int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM transactions WHERE transaction_id = $1`, old.TransactionID).Scan(&found); err != nil {
+		t.Fatalf("Failed to count transactions: %v", err)
+	}
+	if found != 0 {
+		t.Errorf("Expected %s to be anonymized", old.TransactionID)
+	}
+
+	// Anonymized transactions are not anonymized again
+	report, err = service.ApplyRetention(cutoff, RetentionAnonymize, false)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if report.Transactions != 0 {
+		t.Errorf("Expected nothing left to anonymize, got: %d", report.Transactions)
+	}
+}
+
+func TestApplyRetentionKeepsDisputedTransactions(t *testing.T) {
+	for _, mode := range []string{RetentionPurge, RetentionAnonymize} {
+		t.Run(mode, func(t *testing.T) {
+			db := testutil.NewDB(t)
+			defer db.Close()
+
+			clk := clock.NewFake(time.Date(2016, 5, 10, 12, 0, 0, 0, time.UTC))
+			service := NewTransactionService(db, clk)
+
+			// The disputed transaction has the lower ID, so a batch ordered
+			// by ID would reach it first
+			disputed := testutil.NewTransactionRequest("win", "10.00")
+			mustProcess(t, service, 1, disputed, "game")
+			other := testutil.NewTransactionRequest("win", "5.00")
+			mustProcess(t, service, 1, other, "game")
+			dispute, err := service.OpenDispute(models.OpenDisputeRequest{TransactionID: disputed.TransactionID, Hold: true})
+			if err != nil {
+				t.Fatalf("Failed to open dispute: %v", err)
+			}
+			clk.Set(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+
+			report, err := service.ApplyRetention(RetentionCutoff(clk.Now(), 7), mode, false)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if report.Transactions != 1 {
+				t.Errorf("Expected only the undisputed transaction to expire, got: %d", report.Transactions)
+			}
+
+			for _, id := range []string{disputed.TransactionID, dispute.HoldTransactionID} {
+				var found int
+				if err := db.QueryRow(`SELECT COUNT(*) FROM transactions WHERE transaction_id = $1`, id).Scan(&found); err != nil {
+					t.Fatalf("Failed to count transactions: %v", err)
+				}
+				if found != 1 {
+					t.Errorf("Expected %s to be kept with its dispute", id)
+				}
+			}
+		})
+	}
+}
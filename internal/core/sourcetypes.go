@@ -0,0 +1,115 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"assignment/internal/models"
+	"assignment/internal/utils"
+	"assignment/internal/validation"
+)
+
+// ErrSignatureRequired is returned for transactions from a source type
+// requiring signed requests when the request's signature was not verified.
+var ErrSignatureRequired = errors.New("signature required")
+
+// SourceCapabilities declare which transactions a source type, e.g. "game",
+// may initiate.
+type SourceCapabilities struct {
+	// CanCredit allows wins, CanDebit losses
+	CanCredit bool
+	CanDebit  bool
+	// MaxAmount bounds the amount of a transaction from the source type
+	// below the service's maximum, 0 for none
+	MaxAmount float64
+	// RequiresSignature rejects transactions whose request signature the
+	// caller did not verify; see WithVerifiedSignature
+	RequiresSignature bool
+}
+
+// ParseSourceCapabilities parses a comma-separated list of the capabilities
+// "credit", "debit" and "signed", e.g. "debit,signed". The empty list allows
+// nothing.
+func ParseSourceCapabilities(list string) (SourceCapabilities, error) {
+	var caps SourceCapabilities
+	for _, capability := range strings.Split(list, ",") {
+		switch strings.TrimSpace(capability) {
+		case "":
+		case "credit":
+			caps.CanCredit = true
+		case "debit":
+			caps.CanDebit = true
+		case "signed":
+			caps.RequiresSignature = true
+		default:
+			return SourceCapabilities{}, fmt.Errorf("unknown capability %q: must be credit, debit or signed", capability)
+		}
+	}
+	return caps, nil
+}
+
+// SetSourceCapabilities restricts the transactions of sourceType to caps.
+// Source types without capabilities may initiate any transaction. They are
+// checked for transactions processed through ProcessTransaction,
+// ProcessWalletTransaction, the message queue and imports, and for both legs
+// of transfers, escrow moves and wallet moves. Queued transactions count as
+// signed; imported ones only when the import itself was signed (see
+// ImportCSV). Call it before serving requests.
+func (s *TransactionService) SetSourceCapabilities(sourceType string, caps SourceCapabilities) error {
+	if err := utils.ValidateSourceType(sourceType); err != nil {
+		return err
+	}
+	if s.sourceCapabilities == nil {
+		s.sourceCapabilities = map[string]SourceCapabilities{}
+	}
+	s.sourceCapabilities[sourceType] = caps
+	return nil
+}
+
+type signatureKey struct{}
+
+// anySourceType marks a context as verified for every source type.
+const anySourceType = "*"
+
+// WithVerifiedSignature returns a copy of ctx recording that the request it
+// serves was signed with the secret of sourceType, for source types
+// requiring signatures.
+func WithVerifiedSignature(ctx context.Context, sourceType string) context.Context {
+	return context.WithValue(ctx, signatureKey{}, sourceType)
+}
+
+// SignatureVerified reports whether ctx records a verified signature of
+// sourceType (see WithVerifiedSignature).
+func SignatureVerified(ctx context.Context, sourceType string) bool {
+	verified, _ := ctx.Value(signatureKey{}).(string)
+	return verified == sourceType || verified == anySourceType
+}
+
+// checkSourceCapabilities rejects a valid transaction its source type may not
+// initiate.
+func (s *TransactionService) checkSourceCapabilities(ctx context.Context, req models.TransactionRequest, amount float64, sourceType string) error {
+	caps, ok := s.sourceCapabilities[sourceType]
+	if !ok {
+		return nil
+	}
+	if caps.RequiresSignature && !SignatureVerified(ctx, sourceType) {
+		return fmt.Errorf("%w: Source-Type %s must sign its requests", ErrSignatureRequired, sourceType)
+	}
+	if req.State == "win" && !caps.CanCredit {
+		return validation.Errorf("Source-Type", "invalid state: Source-Type %s cannot credit users", sourceType)
+	}
+	if req.State == "lose" && !caps.CanDebit {
+		return validation.Errorf("Source-Type", "invalid state: Source-Type %s cannot debit users", sourceType)
+	}
+	if caps.MaxAmount > 0 && minorUnits(amount, "") > minorUnits(caps.MaxAmount, "") {
+		return validation.Errorf("amount", "invalid amount: exceeds the maximum amount of %s for Source-Type %s", utils.FormatBalance(caps.MaxAmount), sourceType)
+	}
+	return nil
+}
+
+// trustedContext is the context of transactions arriving through channels
+// authenticated otherwise, the message queue and fixtures, which never carry
+// request signatures.
+var trustedContext = context.WithValue(context.Background(), signatureKey{}, anySourceType)
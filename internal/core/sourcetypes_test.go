@@ -0,0 +1,99 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"assignment/internal/clock"
+	"assignment/internal/models"
+	"assignment/internal/testutil"
+)
+
+func TestParseSourceCapabilities(t *testing.T) {
+	caps, err := ParseSourceCapabilities("debit, signed")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if caps != (SourceCapabilities{CanDebit: true, RequiresSignature: true}) {
+		t.Errorf("Unexpected capabilities: %+v", caps)
+	}
+	if _, err := ParseSourceCapabilities("credit,deposit"); err == nil {
+		t.Error("Expected an error for an unknown capability")
+	}
+}
+
+func TestSourceCapabilities(t *testing.T) {
+	s := NewTransactionService(nil, clock.New())
+	if err := s.SetSourceCapabilities("casino", SourceCapabilities{}); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected an unknown source type to be refused, got: %v", err)
+	}
+	s.SetSourceCapabilities("game", SourceCapabilities{CanDebit: true, MaxAmount: 100})
+	s.SetSourceCapabilities("payment", SourceCapabilities{CanCredit: true, CanDebit: true, RequiresSignature: true})
+
+	tests := []struct {
+		ctx        context.Context
+		sourceType string
+		state      string
+		amount     string
+		want       error
+	}{
+		{context.Background(), "game", "lose", "100.00", nil},
+		{context.Background(), "game", "win", "1.00", ErrValidation},
+		{context.Background(), "game", "lose", "100.01", ErrValidation},
+		{context.Background(), "payment", "win", "5000.00", ErrSignatureRequired},
+		{WithVerifiedSignature(context.Background(), "payment"), "payment", "win", "5000.00", nil},
+		{WithVerifiedSignature(context.Background(), "game"), "payment", "win", "5000.00", ErrSignatureRequired},
+		{trustedContext, "payment", "lose", "1.00", nil},
+		// Source types without capabilities are not restricted
+		{context.Background(), "server", "win", "5000.00", nil},
+	}
+	for _, tt := range tests {
+		req := models.TransactionRequest{State: tt.state, Amount: tt.amount, TransactionID: "t1"}
+		amount, _ := s.ValidateTransactionAmount(tt.amount)
+		err := s.checkSourceCapabilities(tt.ctx, req, amount, tt.sourceType)
+		if (tt.want == nil && err != nil) || (tt.want != nil && !errors.Is(err, tt.want)) {
+			t.Errorf("%s %s %s: got %v, want %v", tt.sourceType, tt.state, tt.amount, err, tt.want)
+		}
+	}
+
+	// Refused before anything is read or recorded
+	if _, err := s.ProcessTransaction(1, models.TransactionRequest{State: "win", Amount: "1.00", TransactionID: "t2"}, "game"); err == nil || err.Error() != "invalid state: Source-Type game cannot credit users" {
+		t.Errorf("Expected the credit to be refused, got: %v", err)
+	}
+	// Transfers credit one side, so game may not transfer at all
+	if _, err := s.Transfer(models.TransferRequest{FromUserID: 1, ToUserID: 2, Amount: "1.00"}, "game"); err == nil || err.Error() != "invalid state: Source-Type game cannot credit users" {
+		t.Errorf("Expected the transfer to be refused, got: %v", err)
+	}
+}
+
+func TestImportCSV_SignedSourceTypes(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.New())
+	service.SetSourceCapabilities("payment", SourceCapabilities{CanCredit: true, CanDebit: true, RequiresSignature: true})
+
+	csv := "user_id,transaction_id,state,amount,source_type\n" +
+		"1,signed-import-1,win,5.00,payment\n" +
+		"1,signed-import-2,win,5.00,game\n"
+
+	// An unsigned import cannot apply rows of a signed source type
+	report, err := service.ImportCSV(context.Background(), strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if report.Results[0].Status != "error" || report.Results[1].Status != "applied" {
+		t.Errorf("Expected the payment row to be refused, got: %+v", report.Results)
+	}
+
+	// An import signed with the payment secret can
+	report, err = service.ImportCSV(WithVerifiedSignature(context.Background(), "payment"), strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if report.Results[0].Status != "applied" || report.Results[1].Status != "duplicate" {
+		t.Errorf("Expected the payment row to be applied, got: %+v", report.Results)
+	}
+}
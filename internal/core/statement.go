@@ -0,0 +1,163 @@
+package core
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"assignment/internal/models"
+)
+
+// GetStatement builds the account statement of a user for the UTC calendar
+// month containing month. Opening and closing balances come from the balance
+// snapshots in effect at the start and end of the month.
+func (s *TransactionService) GetStatement(userID int64, month time.Time) (*models.Statement, error) {
+	var exists bool
+	err := s.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`, userID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up user: %w", err)
+	}
+	if !exists {
+		return nil, ErrUserNotFound
+	}
+
+	start := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+
+	statement := &models.Statement{
+		UserID:       userID,
+		Month:        start.Format("2006-01"),
+		PeriodStart:  start,
+		PeriodEnd:    end,
+		Transactions: []models.StatementLine{},
+	}
+
+	if statement.OpeningBalance, err = s.balanceBefore(userID, start); err != nil {
+		return nil, err
+	}
+	if statement.ClosingBalance, err = s.balanceBefore(userID, end); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(
+		`SELECT t.transaction_id, t.state, t.source_type, t.amount, COALESCE(b.balance::TEXT, ''), t.created_at
+		 FROM transactions t
+		 LEFT JOIN balance_snapshots b ON b.transaction_id = t.transaction_id
+		 WHERE t.user_id = $1 AND t.applied AND t.created_at >= $2 AND t.created_at < $3
+		 ORDER BY t.created_at, t.id`,
+		userID,
+		start,
+		end,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query statement transactions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var line models.StatementLine
+		if err := rows.Scan(
+			&line.TransactionID,
+			&line.State,
+			&line.SourceType,
+			&line.Amount,
+			&line.BalanceAfter,
+			&line.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan statement transaction: %w", err)
+		}
+		statement.Transactions = append(statement.Transactions, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read statement transactions: %w", err)
+	}
+
+	return statement, nil
+}
+
+// balanceBefore returns the balance in effect just before t, or 0.00 if the
+// user had no balance yet.
+func (s *TransactionService) balanceBefore(userID int64, t time.Time) (string, error) {
+	var balance string
+	err := s.db.QueryRow(
+		`SELECT balance FROM balance_snapshots
+		 WHERE user_id = $1 AND recorded_at < $2
+		 ORDER BY recorded_at DESC, id DESC LIMIT 1`,
+		userID,
+		t,
+	).Scan(&balance)
+	if err == sql.ErrNoRows {
+		return "0.00", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get balance snapshot: %w", err)
+	}
+	return balance, nil
+}
+
+// GetStatementSummary returns the opening and closing balances of a user for
+// the UTC calendar month containing month, with applied credit and debit
+// totals per source type.
+func (s *TransactionService) GetStatementSummary(userID int64, month time.Time) (*models.StatementSummary, error) {
+	var exists bool
+	err := s.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`, userID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up user: %w", err)
+	}
+	if !exists {
+		return nil, ErrUserNotFound
+	}
+
+	start := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+
+	summary := &models.StatementSummary{
+		UserID:      userID,
+		Month:       start.Format("2006-01"),
+		PeriodStart: start,
+		PeriodEnd:   end,
+		Categories:  []models.SourceTotals{},
+	}
+
+	if summary.OpeningBalance, err = s.balanceBefore(userID, start); err != nil {
+		return nil, err
+	}
+	if summary.ClosingBalance, err = s.balanceBefore(userID, end); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(
+		`SELECT u.currency, t.source_type,
+		        COALESCE(SUM(t.amount) FILTER (WHERE t.state = 'win'), 0),
+		        COUNT(*) FILTER (WHERE t.state = 'win'),
+		        COALESCE(SUM(t.amount) FILTER (WHERE t.state = 'lose'), 0),
+		        COUNT(*) FILTER (WHERE t.state = 'lose'),
+		        COALESCE(SUM(CASE WHEN t.state = 'win' THEN t.amount ELSE -t.amount END), 0)
+		 FROM transactions t JOIN users u ON u.id = t.user_id
+		 WHERE t.user_id = $1 AND t.applied AND t.created_at >= $2 AND t.created_at < $3
+		 GROUP BY u.currency, t.source_type
+		 ORDER BY t.source_type`,
+		userID,
+		start,
+		end,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query statement totals: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		// Totals are in the user's currency, so it is left out
+		var currency string
+		totals, err := scanSourceTotals(rows, &currency)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan statement totals: %w", err)
+		}
+		summary.Categories = append(summary.Categories, totals)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read statement totals: %w", err)
+	}
+
+	return summary, nil
+}
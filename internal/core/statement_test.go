@@ -0,0 +1,107 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"assignment/internal/clock"
+	"assignment/internal/testutil"
+)
+
+func TestGetStatement(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	clk := clock.NewFake(time.Date(2024, 1, 31, 23, 0, 0, 0, time.UTC))
+	service := NewTransactionService(db, clk)
+
+	// January activity sets the opening balance for February
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("win", "20.00"), "game")
+
+	clk.Set(time.Date(2024, 2, 10, 9, 0, 0, 0, time.UTC))
+	win := testutil.NewTransactionRequest("win", "5.50")
+	mustProcess(t, service, 1, win, "game")
+	clk.Set(time.Date(2024, 2, 20, 9, 0, 0, 0, time.UTC))
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("lose", "40.00"), "payment")
+
+	// March activity must not affect February's closing balance
+	clk.Set(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("win", "1.00"), "game")
+
+	st, err := service.GetStatement(1, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if st.Month != "2024-02" {
+		t.Errorf("Expected month 2024-02, got: %s", st.Month)
+	}
+	if st.OpeningBalance != "120.00" {
+		t.Errorf("Expected opening balance 120.00, got: %s", st.OpeningBalance)
+	}
+	if st.ClosingBalance != "85.50" {
+		t.Errorf("Expected closing balance 85.50, got: %s", st.ClosingBalance)
+	}
+	if len(st.Transactions) != 2 {
+		t.Fatalf("Expected 2 transactions, got: %d", len(st.Transactions))
+	}
+	if line := st.Transactions[0]; line.TransactionID != win.TransactionID || line.BalanceAfter != "125.50" {
+		t.Errorf("Unexpected first line: %+v", line)
+	}
+}
+
+func TestGetStatement_BeforeUserExisted(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.New())
+
+	st, err := service.GetStatement(2, time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if st.OpeningBalance != "0.00" || st.ClosingBalance != "0.00" {
+		t.Errorf("Expected zero balances, got: %s / %s", st.OpeningBalance, st.ClosingBalance)
+	}
+}
+
+func TestGetStatementSummary(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	clk := clock.NewFake(time.Date(2024, 1, 31, 23, 0, 0, 0, time.UTC))
+	service := NewTransactionService(db, clk)
+
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("win", "20.00"), "game")
+
+	clk.Set(time.Date(2024, 2, 10, 9, 0, 0, 0, time.UTC))
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("win", "5.50"), "game")
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("lose", "2.00"), "game")
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("lose", "40.00"), "payment")
+	// Rejected transactions do not count towards the totals
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("lose", "500.00"), "payment")
+
+	summary, err := service.GetStatementSummary(1, time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if summary.OpeningBalance != "120.00" || summary.ClosingBalance != "83.50" {
+		t.Errorf("Expected balances 120.00 / 83.50, got: %s / %s", summary.OpeningBalance, summary.ClosingBalance)
+	}
+	if len(summary.Categories) != 2 {
+		t.Fatalf("Expected 2 categories, got: %d", len(summary.Categories))
+	}
+
+	game, payment := summary.Categories[0], summary.Categories[1]
+	if game.SourceType != "game" || game.Credits != "5.50" || game.Debits != "2.00" || game.Net != "3.50" {
+		t.Errorf("Unexpected game totals: %+v", game)
+	}
+	if payment.SourceType != "payment" || payment.DebitCount != 1 || payment.Net != "-40.00" {
+		t.Errorf("Unexpected payment totals: %+v", payment)
+	}
+
+	if _, err := service.GetStatementSummary(999, summary.PeriodStart); err == nil || err.Error() != "user not found" {
+		t.Errorf("Expected 'user not found', got: %v", err)
+	}
+}
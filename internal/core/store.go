@@ -0,0 +1,264 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"assignment/internal/models"
+	"assignment/internal/money"
+	"assignment/internal/utils"
+)
+
+// ErrStoreConflict is returned by a Store when a write loses a race to a
+// concurrent change; nothing of the write is stored.
+var ErrStoreConflict = errors.New("store: concurrent change")
+
+// maxStoreConflicts bounds the retries of a change losing races to
+// concurrent changes of the same account.
+const maxStoreConflicts = 10
+
+// errTooManyConflicts is returned by changes that kept losing races.
+var errTooManyConflicts = &RetryableError{Err: errors.New("too many concurrent changes")}
+
+// Store keeps users and transactions outside Postgres, e.g. in a key-value
+// store (see package kvledger), for the user, transaction and balance API.
+// It only persists: the service keeps applying its rules, hooks and
+// validators. Implementations must be safe for concurrent use.
+type Store interface {
+	// Account returns the account of a user that is not deleted, or
+	// ErrUserNotFound.
+	Account(ctx context.Context, userID int64) (*StoredAccount, error)
+	// CreateAccount stores a new user under the next free ID, or under
+	// user.ID if it is set and free, and returns it with its ID. It returns
+	// ErrExternalRefInUse for a taken external reference and
+	// ErrStoreConflict for a taken ID.
+	CreateAccount(ctx context.Context, user models.User) (*models.User, error)
+	// Transaction returns the transaction applied under a transaction ID,
+	// or ErrTransactionNotFound.
+	Transaction(ctx context.Context, transactionID string) (*StoredTransaction, error)
+	// Apply stores a new transaction together with its account as changed
+	// by it, all or nothing. If the transaction ID is taken or the account
+	// changed since it was read, it returns ErrStoreConflict.
+	Apply(ctx context.Context, account *StoredAccount, transaction *StoredTransaction) error
+}
+
+// StoredAccount is a user as kept by a Store.
+type StoredAccount struct {
+	User models.User
+	// Day is the UTC day, as 2006-01-02, of the transactions summed into
+	// DaySpent, in minor units of the user's currency, for the KYC daily
+	// limit
+	Day      string
+	DaySpent int64
+	// Version is the version the account was read at, checked by Apply
+	Version int64
+}
+
+// StoredTransaction is a transaction as kept by a Store, with the response
+// replayed to retries of its transaction ID.
+type StoredTransaction struct {
+	Transaction models.Transaction
+	Response    models.TransactionResponse
+}
+
+// WithStore keeps users and transactions in store instead of Postgres; the
+// database passed to NewTransactionService may then be nil. Only the user,
+// transaction and balance methods are served from a store: CreateUser,
+// GetUser, GetBalance, GetBalances, ProcessTransaction and its variants,
+// and ApplyFixture. Wallets, transfers and the other features need
+// Postgres.
+func WithStore(store Store) Option {
+	return func(s *TransactionService) {
+		s.store = store
+	}
+}
+
+// processStoredTransaction applies a valid transaction to a user in the
+// store, like processTransaction does in Postgres. Rejections are not
+// stored.
+func (s *TransactionService) processStoredTransaction(ctx context.Context, userID int64, kind string, req models.TransactionRequest, sourceType string, amount float64, now time.Time, msg *models.QueueMessage, ifMatch func(tag string) bool) (*models.TransactionResponse, error) {
+	if kind != AccountUser {
+		return nil, ErrUserNotFound
+	}
+	if msg != nil {
+		return nil, errors.New("queued transactions need Postgres")
+	}
+
+	for attempt := 0; attempt < maxStoreConflicts; attempt++ {
+		stored, err := s.store.Transaction(ctx, req.TransactionID)
+		if err != nil && !errors.Is(err, ErrTransactionNotFound) {
+			return nil, fmt.Errorf("failed to check existing transaction: %w", err)
+		}
+		if stored != nil {
+			return s.replayStoredTransaction(ctx, stored)
+		}
+
+		read, err := s.store.Account(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		acct := *read
+		user := &acct.User
+		account, err := s.storedAccount(acct)
+		if err != nil {
+			return nil, err
+		}
+		if ifMatch != nil && !ifMatch(BalanceTag(userID, user.Balance)) {
+			return nil, ErrPreconditionFailed
+		}
+		if err := money.ValidateAmount(req.Amount, account.currency); err != nil {
+			return nil, err
+		}
+		req.Amount = money.Format(amount, account.currency)
+		if err := s.validate(userID, account, req, sourceType, now); err != nil {
+			return nil, err
+		}
+
+		day := now.UTC().Format("2006-01-02")
+		if acct.Day != day {
+			acct.Day, acct.DaySpent = day, 0
+		}
+
+		newBalance, spent := account.balance+amount, amount
+		if req.State == "lose" {
+			newBalance = account.balance - amount
+		}
+		reason, message := statusRejection(account.status)
+		if reason == "" {
+			reason, message = freezeRejection(account.frozen, req.State)
+		}
+		if reason == "" {
+			reason, message, _ = checkKYCLimits(account.kycLevel, account.currency, amount, func() (int64, error) {
+				return acct.DaySpent, nil
+			})
+		}
+		applied := "Transaction applied successfully"
+		if reason == "" && req.State == "win" {
+			var credit float64
+			if credit, reason, message = s.capCredit(userID, account, amount, s.clipCredits); reason == "" && credit != amount {
+				req.Amount = money.Format(credit, account.currency)
+				newBalance, spent = account.balance+credit, credit
+				applied = "Transaction clipped to maximum balance"
+			}
+		}
+		if reason == "" && newBalance < 0 {
+			reason, message = ReasonInsufficientFunds, "Insufficient funds"
+		}
+		if reason != "" {
+			return &models.TransactionResponse{
+				UserID:        userID,
+				TransactionID: req.TransactionID,
+				Balance:       user.Balance,
+				Message:       message,
+				Reason:        reason,
+			}, nil
+		}
+
+		acct.DaySpent += minorUnits(spent, account.currency)
+		user.Balance = money.Format(newBalance, account.currency)
+		user.UpdatedAt = now
+		transaction := &StoredTransaction{
+			Transaction: models.Transaction{
+				UserID:        userID,
+				TransactionID: req.TransactionID,
+				State:         req.State,
+				Amount:        req.Amount,
+				SourceType:    sourceType,
+				Applied:       true,
+				CreatedAt:     now,
+			},
+			Response: models.TransactionResponse{
+				UserID:        userID,
+				TransactionID: req.TransactionID,
+				Balance:       user.Balance,
+				Message:       applied,
+			},
+		}
+		err = s.store.Apply(ctx, &acct, transaction)
+		if errors.Is(err, ErrStoreConflict) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply transaction: %w", err)
+		}
+
+		s.logger.Printf("Transaction processed: userID=%d, transactionID=%s, state=%s, amount=%s, newBalance=%s",
+			userID, req.TransactionID, req.State, req.Amount, user.Balance)
+
+		for _, after := range s.afterHooks {
+			after(transaction.Transaction, user.Balance)
+		}
+		response := transaction.Response
+		return &response, nil
+	}
+	return nil, errTooManyConflicts
+}
+
+// replayStoredTransaction answers a retry of a stored transaction with the
+// response given then, unless its user has been closed since.
+func (s *TransactionService) replayStoredTransaction(ctx context.Context, stored *StoredTransaction) (*models.TransactionResponse, error) {
+	acct, err := s.store.Account(ctx, stored.Transaction.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if acct.User.Status == UserClosed {
+		return &models.TransactionResponse{
+			UserID:        acct.User.ID,
+			TransactionID: stored.Transaction.TransactionID,
+			Balance:       acct.User.Balance,
+			Message:       "User closed",
+			Reason:        ReasonUserClosed,
+		}, nil
+	}
+	response := stored.Response
+	response.Replayed = true
+	return &response, nil
+}
+
+// storedAccount describes a stored account like lockBalance does.
+func (s *TransactionService) storedAccount(acct StoredAccount) (lockedAccount, error) {
+	balance, err := utils.ParseBalance(acct.User.Balance)
+	if err != nil {
+		return lockedAccount{}, fmt.Errorf("failed to parse current balance: %w", err)
+	}
+	account := lockedAccount{
+		balance:    balance,
+		status:     acct.User.Status,
+		kycLevel:   acct.User.KYCLevel,
+		frozen:     acct.User.Frozen,
+		ownerID:    acct.User.ID,
+		currency:   acct.User.Currency,
+		maxBalance: s.maxBalance,
+	}
+	if acct.User.MaxBalance != "" {
+		if account.maxBalance, err = utils.ParseAmount(acct.User.MaxBalance); err != nil {
+			return lockedAccount{}, fmt.Errorf("failed to parse max balance: %w", err)
+		}
+	}
+	return account, nil
+}
+
+// getStoredBalances returns the balances of users in the store, like
+// GetBalances.
+func (s *TransactionService) getStoredBalances(userIDs []int64) (*models.BulkBalanceResponse, error) {
+	resp := &models.BulkBalanceResponse{Balances: []models.BalanceResponse{}, NotFound: []int64{}}
+	seen := make(map[int64]bool, len(userIDs))
+	for _, id := range userIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		acct, err := s.store.Account(context.Background(), id)
+		switch {
+		case err == nil:
+			resp.Balances = append(resp.Balances, models.BalanceResponse{UserID: id, Balance: acct.User.Balance})
+		case errors.Is(err, ErrUserNotFound):
+			resp.NotFound = append(resp.NotFound, id)
+		default:
+			return nil, err
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,164 @@
+package core
+
+import (
+	"database/sql"
+	"fmt"
+
+	"assignment/internal/models"
+	"assignment/internal/utils"
+	"assignment/internal/validation"
+	"github.com/lib/pq"
+)
+
+// AddUserTags attaches tags to a user and returns all of its tags. Tags the
+// user already has are left alone.
+func (s *TransactionService) AddUserTags(userID int64, tags []string) (*models.UserTags, error) {
+	if len(tags) == 0 {
+		return nil, validation.Errorf("tags", "invalid tags: at least one tag is required")
+	}
+	for _, tag := range tags {
+		if err := utils.ValidateTag(tag); err != nil {
+			return nil, err
+		}
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockUser(tx, userID); err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(
+		`INSERT INTO user_tags (user_id, tag, created_at)
+		 SELECT $1, tag, $3 FROM UNNEST($2::TEXT[]) AS tag
+		 ON CONFLICT (user_id, tag) DO NOTHING`,
+		userID,
+		pq.Array(tags),
+		s.clock.Now(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add user tags: %w", err)
+	}
+
+	result, err := userTags(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return result, nil
+}
+
+// RemoveUserTag detaches a tag from a user and returns its remaining tags.
+// Removing a tag the user does not have is not an error.
+func (s *TransactionService) RemoveUserTag(userID int64, tag string) (*models.UserTags, error) {
+	if err := utils.ValidateTag(tag); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockUser(tx, userID); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`DELETE FROM user_tags WHERE user_id = $1 AND tag = $2`, userID, tag); err != nil {
+		return nil, fmt.Errorf("failed to remove user tag: %w", err)
+	}
+
+	result, err := userTags(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return result, nil
+}
+
+// GetUserTags returns the tags of a user.
+func (s *TransactionService) GetUserTags(userID int64) (*models.UserTags, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRow(
+		`SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND kind = $2 AND deleted_at IS NULL)`,
+		userID,
+		AccountUser,
+	).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check user existence: %w", err)
+	}
+	if !exists {
+		return nil, ErrUserNotFound
+	}
+	return userTags(tx, userID)
+}
+
+// lockUser locks a user whose tags or settings are about to change, so
+// concurrent changes to the same user are serialized.
+func lockUser(tx *sql.Tx, userID int64) error {
+	var id int64
+	err := tx.QueryRow(
+		`SELECT id FROM users WHERE id = $1 AND kind = $2 AND deleted_at IS NULL FOR UPDATE`,
+		userID,
+		AccountUser,
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock user: %w", err)
+	}
+	return nil
+}
+
+func userTags(tx *sql.Tx, userID int64) (*models.UserTags, error) {
+	rows, err := tx.Query(`SELECT tag FROM user_tags WHERE user_id = $1 ORDER BY tag`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user tags: %w", err)
+	}
+	defer rows.Close()
+
+	result := &models.UserTags{UserID: userID, Tags: []string{}}
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, fmt.Errorf("failed to scan user tag: %w", err)
+		}
+		result.Tags = append(result.Tags, tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read user tags: %w", err)
+	}
+	return result, nil
+}
+
+// tagCondition returns a SQL condition, starting with AND, restricting the
+// user ID column to users matching filter. Its parameters are numbered from
+// $next and returned in order.
+func tagCondition(column string, filter models.TagFilter, next int) (string, []interface{}) {
+	var cond string
+	var args []interface{}
+	if filter.Tag != "" {
+		args = append(args, filter.Tag)
+		cond += fmt.Sprintf(` AND EXISTS (SELECT 1 FROM user_tags ut WHERE ut.user_id = %s AND ut.tag = $%d)`, column, next)
+		next++
+	}
+	if len(filter.ExcludeTags) > 0 {
+		args = append(args, pq.Array(filter.ExcludeTags))
+		cond += fmt.Sprintf(` AND NOT EXISTS (SELECT 1 FROM user_tags ut WHERE ut.user_id = %s AND ut.tag = ANY($%d))`, column, next)
+	}
+	return cond, args
+}
@@ -0,0 +1,89 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"assignment/internal/clock"
+	"assignment/internal/models"
+	"assignment/internal/testutil"
+)
+
+func TestUserTags(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.NewFake(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)))
+
+	tags, err := service.AddUserTags(1, []string{"vip", "test_account"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	// Adding is idempotent and tags are returned sorted
+	tags, err = service.AddUserTags(1, []string{"vip"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(tags.Tags) != 2 || tags.Tags[0] != "test_account" || tags.Tags[1] != "vip" {
+		t.Errorf("Expected [test_account vip], got: %v", tags.Tags)
+	}
+
+	tags, err = service.RemoveUserTag(1, "vip")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(tags.Tags) != 1 || tags.Tags[0] != "test_account" {
+		t.Errorf("Expected [test_account], got: %v", tags.Tags)
+	}
+
+	if _, err := service.AddUserTags(999, []string{"vip"}); err == nil || err.Error() != "user not found" {
+		t.Errorf("Expected user not found, got: %v", err)
+	}
+	if _, err := service.AddUserTags(1, []string{"Bad Tag"}); err == nil {
+		t.Error("Expected error for invalid tag")
+	}
+}
+
+func TestReports_TagFilter(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	day := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	service := NewTransactionService(db, clock.NewFake(day))
+
+	if _, err := service.AddUserTags(1, []string{"test_account"}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := service.AddUserTags(2, []string{"vip"}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("lose", "40.00"), "game")
+	mustProcess(t, service, 2, testutil.NewTransactionRequest("lose", "10.00"), "game")
+
+	excludeTest := models.TagFilter{ExcludeTags: []string{"test_account"}}
+	report, err := service.GetReconciliation(day, excludeTest)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(report.BalanceDeltas) != 1 || report.BalanceDeltas[0].Amount != "-10.00" {
+		t.Errorf("Expected test account to be excluded from the delta, got: %+v", report.BalanceDeltas)
+	}
+
+	stats, err := service.GetStats("24h", 24*time.Hour, models.TagFilter{Tag: "vip"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if stats.UserCount != 1 || len(stats.TotalBalances) != 1 || stats.TotalBalances[0].Amount != "40.00" {
+		t.Errorf("Expected only the vip user, got: %d users, %+v", stats.UserCount, stats.TotalBalances)
+	}
+
+	top, err := service.GetTopUsers("balance", DefaultCurrency, 10, excludeTest)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	for _, user := range top.Users {
+		if user.UserID == 1 {
+			t.Error("Expected test account to be excluded from rankings")
+		}
+	}
+}
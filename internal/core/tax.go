@@ -0,0 +1,52 @@
+package core
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"assignment/internal/models"
+	"assignment/internal/money"
+)
+
+// GetTaxSummary returns the turnover, winnings and net win/loss of a user
+// over [start, end), across the user and its wallets. Only applied gambling
+// transactions count: payments (deposits, withdrawals and chargebacks) and
+// transfers, wallet moves included, are left out.
+func (s *TransactionService) GetTaxSummary(userID int64, start, end time.Time) (*models.TaxSummary, error) {
+	summary := &models.TaxSummary{UserID: userID, PeriodStart: start, PeriodEnd: end}
+	err := s.db.QueryRow(
+		`SELECT currency FROM users WHERE id = $1 AND kind = $2`,
+		userID,
+		AccountUser,
+	).Scan(&summary.Currency)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up user: %w", err)
+	}
+
+	var turnover, winnings, net float64
+	err = s.db.QueryRow(
+		`SELECT COALESCE(SUM(amount) FILTER (WHERE state = 'lose'), 0),
+		        COALESCE(SUM(amount) FILTER (WHERE state = 'win'), 0),
+		        COALESCE(SUM(CASE WHEN state = 'win' THEN amount ELSE -amount END), 0),
+		        COUNT(*)
+		 FROM transactions
+		 WHERE user_id IN (SELECT id FROM users WHERE id = $1 OR owner_id = $1)
+		   AND applied AND source_type <> 'payment' AND transfer_id IS NULL
+		   AND created_at >= $2 AND created_at < $3`,
+		userID,
+		start,
+		end,
+	).Scan(&turnover, &winnings, &net, &summary.TransactionCount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tax totals: %w", err)
+	}
+	summary.Turnover = money.Format(turnover, summary.Currency)
+	summary.Winnings = money.Format(winnings, summary.Currency)
+	summary.NetWinLoss = money.Format(net, summary.Currency)
+
+	return summary, nil
+}
@@ -0,0 +1,52 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"assignment/internal/clock"
+	"assignment/internal/models"
+	"assignment/internal/testutil"
+)
+
+func TestGetTaxSummary(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	clk := clock.NewFake(time.Date(2023, 12, 31, 23, 0, 0, 0, time.UTC))
+	service := NewTransactionService(db, clk)
+
+	// Outside the year
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("win", "50.00"), "game")
+
+	clk.Set(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("lose", "30.00"), "game")
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("win", "12.50"), "server")
+	// Wallets count, payments and transfers do not
+	if _, err := service.CreateWallet(1, "casino"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := service.ProcessWalletTransaction(context.Background(), 1, "casino", testutil.NewTransactionRequest("win", "5.00"), "game"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	mustProcess(t, service, 1, testutil.NewTransactionRequest("lose", "20.00"), "payment")
+	if _, err := service.Transfer(models.TransferRequest{FromUserID: 1, ToUserID: 2, Amount: "10.00"}, "game"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	summary, err := service.GetTaxSummary(1, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if summary.Turnover != "30.00" || summary.Winnings != "17.50" || summary.NetWinLoss != "-12.50" || summary.TransactionCount != 3 {
+		t.Errorf("Unexpected tax summary: %+v", summary)
+	}
+	if summary.Currency != "EUR" {
+		t.Errorf("Expected EUR, got: %s", summary.Currency)
+	}
+
+	if _, err := service.GetTaxSummary(999, summary.PeriodStart, summary.PeriodEnd); err == nil || err.Error() != "user not found" {
+		t.Errorf("Expected user not found, got: %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"assignment/internal/clock"
+	"assignment/internal/testutil"
+)
+
+func TestAPITokens(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.New())
+
+	issued, err := service.IssueAPIToken(2)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !strings.HasPrefix(issued.Token, tokenPrefix) {
+		t.Errorf("Expected token prefix %s, got: %s", tokenPrefix, issued.Token)
+	}
+
+	// Only the hash is stored
+	var stored string
+	if err := db.QueryRow(`SELECT token_hash FROM api_tokens WHERE id = $1`, issued.ID).Scan(&stored); err != nil {
+		t.Fatalf("Failed to read token: %v", err)
+	}
+	if stored == issued.Token {
+		t.Error("Expected token to be stored hashed")
+	}
+
+	userID, err := service.AuthenticateAPIToken(issued.Token)
+	if err != nil || userID != 2 {
+		t.Errorf("Expected token of user 2, got: %d, %v", userID, err)
+	}
+
+	if _, err := service.RevokeAPIToken(1, issued.ID); err == nil || err.Error() != "token not found" {
+		t.Errorf("Expected token not found for another user, got: %v", err)
+	}
+	if _, err := service.RevokeAPIToken(2, issued.ID); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := service.AuthenticateAPIToken(issued.Token); err == nil || err.Error() != "invalid token" {
+		t.Errorf("Expected revoked token to be invalid, got: %v", err)
+	}
+
+	if _, err := service.IssueAPIToken(999); err == nil || err.Error() != "user not found" {
+		t.Errorf("Expected user not found, got: %v", err)
+	}
+}
@@ -0,0 +1,244 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"assignment/internal/models"
+	"assignment/internal/money"
+	"assignment/internal/ulid"
+	"assignment/internal/utils"
+	"assignment/internal/validation"
+)
+
+// Transfer moves funds from one user to another in a single database
+// transaction: a "lose" leg ({transferId}:debit) for the sender and a "win"
+// leg ({transferId}:credit) for the recipient, linked by transfer ID. Both
+// users are locked in canonical order (see lockAccounts), so concurrent
+// transfers between the same users in opposite directions cannot deadlock.
+//
+// The transfer ID is the idempotency key; when it is empty a ULID is
+// assigned. A transfer the sender cannot cover is recorded as a rejection
+// and reported with the message "Insufficient funds". Both users must hold
+// the same currency; amounts are never converted.
+func (s *TransactionService) Transfer(req models.TransferRequest, sourceType string) (*models.TransferResponse, error) {
+	return s.TransferContext(context.Background(), req, sourceType)
+}
+
+// TransferContext is Transfer bounded by ctx. If ctx is done before the
+// transfer commits, neither leg is applied and ctx's error is returned.
+func (s *TransactionService) TransferContext(ctx context.Context, req models.TransferRequest, sourceType string) (*models.TransferResponse, error) {
+	return s.transfer(ctx, req, sourceType, AccountUser, AccountUser, false)
+}
+
+// transfer moves funds between two accounts of the given kinds. Internal
+// transfers move funds between accounts of the same owner and are exempt
+// from KYC limits.
+func (s *TransactionService) transfer(ctx context.Context, req models.TransferRequest, sourceType, fromKind, toKind string, internal bool) (resp *models.TransferResponse, err error) {
+	defer func() {
+		if err = contextError(ctx, err); err != nil {
+			resp = nil
+		}
+	}()
+
+	if err := utils.ValidateSourceType(sourceType); err != nil {
+		return nil, err
+	}
+	if req.FromUserID <= 0 || req.ToUserID <= 0 {
+		return nil, validation.Errorf("userId", "invalid user ID: must be a positive integer")
+	}
+	if req.FromUserID == req.ToUserID {
+		return nil, validation.Errorf("toUserId", "invalid transfer: sender and recipient must differ")
+	}
+	if err := s.ValidateTransferID(req.TransferID); err != nil {
+		return nil, err
+	}
+	amount, err := s.ValidateTransactionAmount(req.Amount)
+	if err != nil {
+		return nil, err
+	}
+	if amount == 0 {
+		return nil, validation.Errorf("amount", "invalid amount: must be greater than zero")
+	}
+	// A transfer debits one account and credits another, so its source type
+	// needs both capabilities
+	for _, state := range []string{"lose", "win"} {
+		if err := s.checkSourceCapabilities(ctx, models.TransactionRequest{State: state}, amount, sourceType); err != nil {
+			return nil, err
+		}
+	}
+
+	now := s.clock.Now()
+	if req.TransferID == "" {
+		if req.TransferID, err = ulid.New(now); err != nil {
+			return nil, &RetryableError{fmt.Errorf("failed to generate transfer ID: %w", err)}
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock both users before anything else
+	accounts, err := s.lockAccounts(tx, map[int64]string{req.FromUserID: fromKind, req.ToUserID: toKind}, now)
+	if err != nil {
+		return nil, err
+	}
+	balances := map[int64]float64{}
+	for userID, account := range accounts {
+		balances[userID] = account.balance
+	}
+	// Amounts are never converted between currencies, so both accounts must
+	// hold the same one
+	from, to := accounts[req.FromUserID], accounts[req.ToUserID]
+	if toKind == AccountEscrow && to.currency != from.currency {
+		if to, err = adoptCurrency(tx, req.ToUserID, to, from.currency); err != nil {
+			return nil, err
+		}
+		accounts[req.ToUserID] = to
+	}
+	if from.currency != to.currency {
+		return nil, validation.Errorf("toUserId", "invalid transfer: sender currency %s does not match recipient currency %s", from.currency, to.currency)
+	}
+	if err := money.ValidateAmount(req.Amount, from.currency); err != nil {
+		return nil, err
+	}
+	req.Amount = money.Format(amount, from.currency)
+
+	response := &models.TransferResponse{
+		TransferID:  req.TransferID,
+		FromUserID:  req.FromUserID,
+		ToUserID:    req.ToUserID,
+		Amount:      req.Amount,
+		FromBalance: money.Format(balances[req.FromUserID], from.currency),
+		ToBalance:   money.Format(balances[req.ToUserID], to.currency),
+	}
+
+	var exists bool
+	err = tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM transfers WHERE transfer_id = $1)`, req.TransferID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check existing transfer: %w", err)
+	}
+	if exists {
+		response.Message = "Duplicate transfer ignored"
+		if accounts[req.FromUserID].status == UserClosed || accounts[req.ToUserID].status == UserClosed {
+			response.Message = "User closed"
+		}
+		return response, nil
+	}
+
+	debit := models.TransactionRequest{State: "lose", Amount: req.Amount, TransactionID: req.TransferID + ":debit"}
+	credit := models.TransactionRequest{State: "win", Amount: req.Amount, TransactionID: req.TransferID + ":credit"}
+
+	// The leg of the first party that cannot transact is recorded as the
+	// rejected one
+	rejectedUserID, rejectedLeg := req.FromUserID, debit
+	reason, message := statusRejection(accounts[req.FromUserID].status)
+	if reason == "" {
+		rejectedUserID, rejectedLeg = req.ToUserID, credit
+		reason, message = statusRejection(accounts[req.ToUserID].status)
+	}
+	if reason == "" {
+		rejectedUserID, rejectedLeg = req.FromUserID, debit
+		reason, message = freezeRejection(accounts[req.FromUserID].frozen, debit.State)
+	}
+	if reason == "" {
+		rejectedUserID, rejectedLeg = req.ToUserID, credit
+		reason, message = freezeRejection(accounts[req.ToUserID].frozen, credit.State)
+	}
+	if reason == "" && !internal {
+		rejectedUserID, rejectedLeg = req.FromUserID, debit
+		reason, message, err = kycRejection(tx, accounts[req.FromUserID], amount, now)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if reason == "" && !internal {
+		rejectedUserID, rejectedLeg = req.ToUserID, credit
+		reason, message, err = kycRejection(tx, accounts[req.ToUserID], amount, now)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// Transfers are never clipped, so both legs keep the same amount
+	if reason == "" {
+		rejectedUserID, rejectedLeg = req.ToUserID, credit
+		_, reason, message = s.capCredit(req.ToUserID, accounts[req.ToUserID], amount, false)
+	}
+	fromBalance := balances[req.FromUserID] - amount
+	if reason == "" && fromBalance < 0 {
+		rejectedUserID, rejectedLeg = req.FromUserID, debit
+		reason, message = ReasonInsufficientFunds, "Insufficient funds"
+	}
+	if reason != "" {
+		if err := recordRejection(tx, rejectedUserID, rejectedLeg, sourceType, reason, now); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		response.Message = message
+		return response, nil
+	}
+	toBalance := balances[req.ToUserID] + amount
+
+	_, err = tx.Exec(
+		`INSERT INTO transfers (transfer_id, from_user_id, to_user_id, amount, source_type, internal, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		req.TransferID,
+		req.FromUserID,
+		req.ToUserID,
+		req.Amount,
+		sourceType,
+		internal,
+		now,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert transfer: %w", err)
+	}
+
+	response.FromBalance = money.Format(fromBalance, from.currency)
+	response.ToBalance = money.Format(toBalance, to.currency)
+
+	_, err = s.applyTransaction(tx, req.FromUserID, accounts[req.FromUserID].head, debit, sourceType, response.FromBalance, req.TransferID, now)
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.applyTransaction(tx, req.ToUserID, accounts[req.ToUserID].head, credit, sourceType, response.ToBalance, req.TransferID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.logger.Printf("Transfer processed: transferID=%s, from=%d, to=%d, amount=%s",
+		req.TransferID, req.FromUserID, req.ToUserID, req.Amount)
+
+	response.Message = "Transfer applied successfully"
+	return response, nil
+}
+
+// adoptCurrency switches a locked escrow account that has never had a ledger
+// entry to currency, so an escrow account takes the currency of its first
+// payer. Accounts with entries are returned unchanged.
+func adoptCurrency(tx *sql.Tx, accountID int64, account lockedAccount, currency string) (lockedAccount, error) {
+	var used bool
+	err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM transactions WHERE user_id = $1)`, accountID).Scan(&used)
+	if err != nil {
+		return account, fmt.Errorf("failed to check escrow entries: %w", err)
+	}
+	if used {
+		return account, nil
+	}
+	_, err = tx.Exec(`UPDATE users SET currency = $1, balance = $2 WHERE id = $3`, currency, money.Format(0, currency), accountID)
+	if err != nil {
+		return account, fmt.Errorf("failed to set escrow currency: %w", err)
+	}
+	account.currency = currency
+	return account, nil
+}
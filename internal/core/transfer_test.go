@@ -0,0 +1,113 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"assignment/internal/clock"
+	"assignment/internal/models"
+	"assignment/internal/testutil"
+)
+
+func TestTransfer(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.New())
+
+	req := models.TransferRequest{FromUserID: 1, ToUserID: 2, Amount: "30.00", TransferID: "transfer-1"}
+	resp, err := service.Transfer(req, "game")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if resp.FromBalance != "70.00" || resp.ToBalance != "80.00" {
+		t.Errorf("Expected balances 70.00 and 80.00, got: %s and %s", resp.FromBalance, resp.ToBalance)
+	}
+
+	// Both legs are stored and linked to the transfer
+	var legs int
+	err = db.QueryRow(`SELECT COUNT(*) FROM transactions WHERE transfer_id = $1`, req.TransferID).Scan(&legs)
+	if err != nil || legs != 2 {
+		t.Errorf("Expected 2 linked transactions, got: %d, %v", legs, err)
+	}
+
+	resp, err = service.Transfer(req, "game")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if resp.Message != "Duplicate transfer ignored" || resp.FromBalance != "70.00" {
+		t.Errorf("Expected duplicate with unchanged balance, got: %+v", resp)
+	}
+
+	// A rejected transfer moves nothing
+	resp, err = service.Transfer(models.TransferRequest{FromUserID: 3, ToUserID: 1, Amount: "5.00"}, "game")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if resp.Message != "Insufficient funds" || resp.ToBalance != "70.00" {
+		t.Errorf("Expected insufficient funds with unchanged balance, got: %+v", resp)
+	}
+
+	if diverged, err := service.CheckProjections(); err != nil || len(diverged) != 0 {
+		t.Errorf("Expected balances to match event streams, got: %v, %v", diverged, err)
+	}
+}
+
+func TestTransfer_OppositeDirectionsConcurrently(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.New())
+
+	// Deadlocks would surface as errors from Postgres' deadlock detector
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			_, err := service.Transfer(models.TransferRequest{FromUserID: 1, ToUserID: 2, Amount: "1.00", TransferID: fmt.Sprintf("forward-%d", i)}, "game")
+			errs <- err
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			_, err := service.Transfer(models.TransferRequest{FromUserID: 2, ToUserID: 1, Amount: "1.00", TransferID: fmt.Sprintf("back-%d", i)}, "game")
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Expected no error, got: %v", err)
+		}
+	}
+
+	one, _ := service.GetBalance(1)
+	two, _ := service.GetBalance(2)
+	if one.Balance != "100.00" || two.Balance != "50.00" {
+		t.Errorf("Expected balances 100.00 and 50.00, got: %s and %s", one.Balance, two.Balance)
+	}
+}
+
+func TestTransfer_CurrencyMismatch(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.New())
+
+	user, err := service.CreateUser(models.CreateUserRequest{Balance: "10", Currency: "USD"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	_, err = service.Transfer(models.TransferRequest{FromUserID: 1, ToUserID: user.ID, Amount: "5.00"}, "game")
+	if !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected a validation error for a cross-currency transfer, got: %v", err)
+	}
+	if balance, err := service.GetBalance(1); err != nil || balance.Balance != "100.00" {
+		t.Errorf("Expected sender balance to be unchanged, got: %v, %v", balance, err)
+	}
+}
@@ -0,0 +1,137 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"assignment/internal/validation"
+)
+
+// Transaction ID formats. Any accepts every ID of printable ASCII characters
+// without spaces; the others additionally require a UUID, a ULID or a match
+// of a configured pattern, e.g. a provider prefix.
+const (
+	TxIDAny     = "any"
+	TxIDUUID    = "uuid"
+	TxIDULID    = "ulid"
+	TxIDPattern = "pattern"
+)
+
+// DefaultMaxTransactionIDLength bounds client-chosen transaction IDs unless
+// another limit is configured.
+const DefaultMaxTransactionIDLength = 128
+
+// transferLegSuffixes end the transaction IDs of transfer legs, which are
+// derived from the transfer ID. Client-chosen transaction IDs cannot end
+// with them, so they never collide with a leg.
+var transferLegSuffixes = []string{":debit", ":credit"}
+
+var (
+	uuidRegex = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	ulidRegex = regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Za-hjkmnp-tv-z]{25}$`)
+)
+
+// txIDPolicy is the format client-chosen transaction IDs must have.
+type txIDPolicy struct {
+	format    string
+	pattern   *regexp.Regexp
+	maxLength int
+}
+
+// SetTransactionIDPolicy sets the format of transaction IDs chosen by
+// clients: one of the TxID formats, the regular expression IDs must match
+// for TxIDPattern, and their maximum length (0 for the default). Call it
+// before serving requests.
+func (s *TransactionService) SetTransactionIDPolicy(format, pattern string, maxLength int) error {
+	policy := txIDPolicy{format: format, maxLength: maxLength}
+	switch format {
+	case TxIDAny, TxIDUUID, TxIDULID:
+	case TxIDPattern:
+		if pattern == "" {
+			return errors.New("invalid transaction ID policy: a pattern is required")
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid transaction ID pattern: %w", err)
+		}
+		policy.pattern = re
+	default:
+		return errors.New("invalid transaction ID format: must be 'any', 'uuid', 'ulid' or 'pattern'")
+	}
+	if maxLength < 0 {
+		return errors.New("invalid transaction ID policy: maximum length must not be negative")
+	}
+	s.txIDPolicy = policy
+	return nil
+}
+
+// ValidateTransactionID checks a transaction ID chosen by a client against
+// the default transaction ID policy: at most DefaultMaxTransactionIDLength
+// printable ASCII characters without spaces. An empty ID is valid.
+func ValidateTransactionID(id string) error {
+	return txIDPolicy{}.validate(id)
+}
+
+// ValidateTransactionID checks a transaction ID chosen by a client against
+// the transaction ID policy. An empty ID is valid: the server assigns one.
+// IDs derived by the service itself, e.g. for withdrawals, are not checked.
+func (s *TransactionService) ValidateTransactionID(id string) error {
+	return s.txIDPolicy.validate(id)
+}
+
+// ValidateTransferID checks a transfer ID chosen by a client against the
+// transaction ID policy, like ValidateTransactionID. An empty ID is valid:
+// the server assigns one.
+func (s *TransactionService) ValidateTransferID(id string) error {
+	return s.txIDPolicy.check("transferId", "transfer ID", id)
+}
+
+// validate checks a client-chosen transaction ID against the policy.
+func (p txIDPolicy) validate(id string) error {
+	if err := p.check("transactionId", "transaction ID", id); err != nil {
+		return err
+	}
+	for _, suffix := range transferLegSuffixes {
+		if strings.HasSuffix(id, suffix) {
+			return validation.Errorf("transactionId", "invalid transaction ID: must not end with %q, which is reserved for transfers", suffix)
+		}
+	}
+	return nil
+}
+
+// check checks an ID of field, named name in errors, against the policy.
+func (p txIDPolicy) check(field, name, id string) error {
+	if id == "" {
+		return nil
+	}
+	maxLength := p.maxLength
+	if maxLength == 0 {
+		maxLength = DefaultMaxTransactionIDLength
+	}
+	if len(id) > maxLength {
+		return validation.Errorf(field, "invalid %s: must be at most %d characters", name, maxLength)
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return validation.Errorf(field, "invalid %s: must contain only printable ASCII characters without spaces", name)
+		}
+	}
+
+	switch p.format {
+	case TxIDUUID:
+		if !uuidRegex.MatchString(id) {
+			return validation.Errorf(field, "invalid %s: must be a UUID", name)
+		}
+	case TxIDULID:
+		if !ulidRegex.MatchString(id) {
+			return validation.Errorf(field, "invalid %s: must be a ULID", name)
+		}
+	case TxIDPattern:
+		if !p.pattern.MatchString(id) {
+			return validation.Errorf(field, "invalid %s: must match %s", name, p.pattern)
+		}
+	}
+	return nil
+}
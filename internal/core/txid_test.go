@@ -0,0 +1,96 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"assignment/internal/clock"
+)
+
+func TestValidateTransactionID(t *testing.T) {
+	tests := []struct {
+		format, pattern string
+		maxLength       int
+		id              string
+		wantErr         string
+	}{
+		{TxIDAny, "", 0, "", ""},
+		{TxIDAny, "", 0, "game-42:round_7", ""},
+		{TxIDAny, "", 0, "has space", "invalid transaction ID: must contain only printable ASCII characters without spaces"},
+		{TxIDAny, "", 0, "tx-\U0001F600", "invalid transaction ID: must contain only printable ASCII characters without spaces"},
+		{TxIDAny, "", 0, strings.Repeat("a", 129), "invalid transaction ID: must be at most 128 characters"},
+		{TxIDAny, "", 8, "123456789", "invalid transaction ID: must be at most 8 characters"},
+		{TxIDUUID, "", 0, "3f2504e0-4f89-11d3-9a0c-0305e82c3301", ""},
+		{TxIDUUID, "", 0, "3f2504e04f8911d39a0c0305e82c3301", "invalid transaction ID: must be a UUID"},
+		{TxIDULID, "", 0, "01HM6FWAG0R8QZ4M3V7KXB2D5N", ""},
+		{TxIDULID, "", 0, "01HM6FWAG0R8QZ4M3V7KXB2D5U", "invalid transaction ID: must be a ULID"},
+		{TxIDPattern, `^stripe_[a-z0-9]{8,}$`, 0, "stripe_abc12345", ""},
+		{TxIDPattern, `^stripe_[a-z0-9]{8,}$`, 0, "paypal_abc12345", "invalid transaction ID: must match ^stripe_[a-z0-9]{8,}$"},
+		// Transfer legs are "{transferId}:debit" and "{transferId}:credit"
+		{TxIDAny, "", 0, "t-1:debit", `invalid transaction ID: must not end with ":debit", which is reserved for transfers`},
+		{TxIDPattern, `^t-`, 0, "t-1:credit", `invalid transaction ID: must not end with ":credit", which is reserved for transfers`},
+		{TxIDAny, "", 0, "t-1:debited", ""},
+	}
+	for _, tt := range tests {
+		service := NewTransactionService(nil, clock.New())
+		if err := service.SetTransactionIDPolicy(tt.format, tt.pattern, tt.maxLength); err != nil {
+			t.Fatalf("SetTransactionIDPolicy(%s, %s, %d): %v", tt.format, tt.pattern, tt.maxLength, err)
+		}
+		err := service.ValidateTransactionID(tt.id)
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tt.wantErr {
+			t.Errorf("%s: ValidateTransactionID(%q) error = %q, want %q", tt.format, tt.id, got, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateTransactionID_DefaultPolicy(t *testing.T) {
+	for id, valid := range map[string]bool{
+		"":                       true,
+		"game-42:round_7":        true,
+		"has space":              false,
+		strings.Repeat("a", 129): false,
+		"t-1:credit":             false,
+	} {
+		if err := ValidateTransactionID(id); (err == nil) != valid {
+			t.Errorf("ValidateTransactionID(%q) = %v, want valid %v", id, err, valid)
+		}
+	}
+}
+
+func TestValidateTransferID(t *testing.T) {
+	service := NewTransactionService(nil, clock.New())
+	if err := service.SetTransactionIDPolicy(TxIDUUID, "", 0); err != nil {
+		t.Fatalf("SetTransactionIDPolicy: %v", err)
+	}
+	for id, wantErr := range map[string]string{
+		"":                                     "",
+		"3f2504e0-4f89-11d3-9a0c-0305e82c3301": "",
+		"has space":                            "invalid transfer ID: must contain only printable ASCII characters without spaces",
+		"transfer-1":                           "invalid transfer ID: must be a UUID",
+	} {
+		got := ""
+		if err := service.ValidateTransferID(id); err != nil {
+			got = err.Error()
+		}
+		if got != wantErr {
+			t.Errorf("ValidateTransferID(%q) error = %q, want %q", id, got, wantErr)
+		}
+	}
+}
+
+func TestSetTransactionIDPolicyRejectsInvalidConfig(t *testing.T) {
+	service := NewTransactionService(nil, clock.New())
+	for _, tt := range []struct{ format, pattern string }{
+		{"guid", ""},
+		{TxIDPattern, ""},
+		{TxIDPattern, "["},
+	} {
+		if err := service.SetTransactionIDPolicy(tt.format, tt.pattern, 0); err == nil {
+			t.Errorf("Expected error for format %q and pattern %q", tt.format, tt.pattern)
+		}
+	}
+}
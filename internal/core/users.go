@@ -0,0 +1,390 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"assignment/internal/models"
+	"assignment/internal/money"
+	"assignment/internal/utils"
+	"assignment/internal/validation"
+	"github.com/lib/pq"
+)
+
+// DefaultCurrency is the currency of users registered without one.
+const DefaultCurrency = "EUR"
+
+// userColumns are the columns read by scanUser.
+const userColumns = `id, balance, currency, status, kyc_level, external_ref, display_name, country, max_balance, frozen, frozen_reason, frozen_at, chargeback_at, anonymized_at, created_at, updated_at, deleted_at`
+
+// ErrExternalRefInUse is returned when another user already has the
+// requested external reference.
+var ErrExternalRefInUse = errors.New("external reference already in use")
+
+// ErrCurrencyInUse is returned when changing the currency of a user whose
+// amounts are already in its current currency.
+var ErrCurrencyInUse = errors.New("currency cannot be changed: user has a balance or transactions")
+
+// CreateUser registers an unverified user (see KYCLimits) with an optional
+// initial balance (default 0), currency and profile fields. The initial balance is recorded as the
+// user's baseline balance snapshot and opens its event stream, like the
+// seeded users'.
+func (s *TransactionService) CreateUser(req models.CreateUserRequest) (*models.User, error) {
+	if req.Balance == "" {
+		req.Balance = "0"
+	}
+	if req.Currency == "" {
+		req.Currency = DefaultCurrency
+	}
+	if err := validateProfile(req.Currency, req.ExternalRef, req.DisplayName, req.Country); err != nil {
+		return nil, err
+	}
+	if err := money.ValidateAmount(req.Balance, req.Currency); err != nil {
+		return nil, err
+	}
+
+	balance, err := utils.ParseAmount(req.Balance)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	user := &models.User{
+		Balance:     money.Format(balance, req.Currency),
+		Currency:    req.Currency,
+		Status:      UserActive,
+		KYCLevel:    KYCUnverified,
+		ExternalRef: req.ExternalRef,
+		DisplayName: req.DisplayName,
+		Country:     req.Country,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if s.store != nil {
+		return s.store.CreateAccount(context.Background(), *user)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(
+		`INSERT INTO users (balance, currency, kyc_level, external_ref, display_name, country, created_at, updated_at)
+		 VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), $7, $7)
+		 RETURNING id`,
+		user.Balance,
+		user.Currency,
+		user.KYCLevel,
+		user.ExternalRef,
+		user.DisplayName,
+		user.Country,
+		now,
+	).Scan(&user.ID)
+	if isUniqueViolation(err) {
+		return nil, ErrExternalRefInUse
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	_, err = tx.Exec(
+		`INSERT INTO balance_snapshots (user_id, balance, recorded_at) VALUES ($1, $2, $3)`,
+		user.ID,
+		user.Balance,
+		now,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record balance snapshot: %w", err)
+	}
+	if _, err := s.openStream(tx, user.ID, user.Balance, now); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return user, nil
+}
+
+// GetUser returns a user with its profile fields.
+func (s *TransactionService) GetUser(userID int64) (*models.User, error) {
+	if s.store != nil {
+		acct, err := s.store.Account(context.Background(), userID)
+		if err != nil {
+			return nil, err
+		}
+		return &acct.User, nil
+	}
+	return scanUser("get", s.db.QueryRow(
+		`SELECT `+userColumns+` FROM users WHERE id = $1 AND kind = $2 AND deleted_at IS NULL`,
+		userID,
+		AccountUser,
+	))
+}
+
+// UpdateUser changes the profile fields set in req and returns the user.
+// Amounts are never converted, so the currency can only change while the
+// user and its wallets have a zero balance and no transactions; the wallets
+// change currency with the user.
+func (s *TransactionService) UpdateUser(userID int64, req models.UpdateUserRequest) (*models.User, error) {
+	var sets []string
+	var args []interface{}
+	set := func(column string, value *string) {
+		if value != nil {
+			args = append(args, *value)
+			sets = append(sets, fmt.Sprintf("%s = NULLIF($%d, '')", column, len(args)))
+		}
+	}
+
+	if req.Currency != nil {
+		if err := utils.ValidateCurrency(*req.Currency); err != nil {
+			return nil, err
+		}
+	}
+	if err := validateProfile("", stringOr(req.ExternalRef), stringOr(req.DisplayName), stringOr(req.Country)); err != nil {
+		return nil, err
+	}
+	set("currency", req.Currency)
+	set("external_ref", req.ExternalRef)
+	set("display_name", req.DisplayName)
+	set("country", req.Country)
+
+	args = append(args, s.clock.Now(), userID, AccountUser)
+	sets = append(sets, fmt.Sprintf("updated_at = $%d", len(args)-2))
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if req.Currency != nil {
+		if err := checkCurrencyChange(tx, userID, *req.Currency); err != nil {
+			return nil, err
+		}
+	}
+
+	user, err := scanUser("update", tx.QueryRow(
+		fmt.Sprintf(`UPDATE users SET %s WHERE id = $%d AND kind = $%d AND deleted_at IS NULL RETURNING `+userColumns,
+			strings.Join(sets, ", "), len(args)-1, len(args)),
+		args...,
+	))
+	if isUniqueViolation(err) {
+		return nil, ErrExternalRefInUse
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Currency != nil {
+		_, err = tx.Exec(`UPDATE users SET currency = $1 WHERE owner_id = $2 AND kind = $3`, user.Currency, userID, AccountWallet)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update wallet currency: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return user, nil
+}
+
+// checkCurrencyChange locks the user and returns ErrCurrencyInUse if
+// currency differs from its current one while the user or one of its wallets
+// has a non-zero balance or any transaction.
+func checkCurrencyChange(tx *sql.Tx, userID int64, currency string) error {
+	var current string
+	err := tx.QueryRow(
+		`SELECT currency FROM users WHERE id = $1 AND kind = $2 AND deleted_at IS NULL FOR UPDATE`,
+		userID,
+		AccountUser,
+	).Scan(&current)
+	if err == sql.ErrNoRows {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get user currency: %w", err)
+	}
+	if current == currency {
+		return nil
+	}
+
+	var used bool
+	err = tx.QueryRow(
+		`SELECT EXISTS (
+			SELECT 1 FROM users a
+			WHERE (a.id = $1 OR a.owner_id = $1)
+			  AND (a.balance <> 0 OR EXISTS (SELECT 1 FROM transactions t WHERE t.user_id = a.id))
+		)`,
+		userID,
+	).Scan(&used)
+	if err != nil {
+		return fmt.Errorf("failed to check user transactions: %w", err)
+	}
+	if used {
+		return ErrCurrencyInUse
+	}
+	return nil
+}
+
+// SetUserStatus moves a user to another lifecycle status and records the
+// change with its reason. The status is changed under the same row lock
+// transactions take, so no transaction is applied after a user is suspended
+// or closed. Closing is final: a closed user cannot be reactivated.
+func (s *TransactionService) SetUserStatus(userID int64, req models.UserStatusRequest) (*models.User, error) {
+	if err := utils.ValidateUserStatus(req.Status); err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var current string
+	err = tx.QueryRow(
+		`SELECT status FROM users WHERE id = $1 AND kind = $2 AND deleted_at IS NULL FOR UPDATE`,
+		userID,
+		AccountUser,
+	).Scan(&current)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user status: %w", err)
+	}
+	if current == UserClosed && req.Status != UserClosed {
+		return nil, validation.Errorf("status", "invalid status transition: closed users cannot be reactivated")
+	}
+
+	if current != req.Status {
+		_, err = tx.Exec(
+			`INSERT INTO user_status_changes (user_id, from_status, to_status, reason, changed_at)
+			 VALUES ($1, $2, $3, $4, $5)`,
+			userID,
+			current,
+			req.Status,
+			req.Reason,
+			now,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record status change: %w", err)
+		}
+	}
+
+	user, err := scanUser("update", tx.QueryRow(
+		`UPDATE users SET status = $1, updated_at = $2 WHERE id = $3 RETURNING `+userColumns,
+		req.Status,
+		now,
+		userID,
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.logger.Printf("User status changed: userID=%d, from=%s, to=%s, reason=%q", userID, current, req.Status, req.Reason)
+
+	return user, nil
+}
+
+// DeleteUser soft-deletes a user: the user is hidden from lookups and
+// rankings and can no longer transact, while its transactions, snapshots and
+// events are kept for audit. Deleting a user twice reports it as not found.
+func (s *TransactionService) DeleteUser(userID int64) (*models.User, error) {
+	// The update waits for the row lock held by in-flight transactions, which
+	// then see the user as deleted
+	user, err := scanUser("delete", s.db.QueryRow(
+		`UPDATE users SET deleted_at = $1, updated_at = $1
+		 WHERE id = $2 AND kind = $3 AND deleted_at IS NULL RETURNING `+userColumns,
+		s.clock.Now(),
+		userID,
+		AccountUser,
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Printf("User deleted: userID=%d, balance=%s", userID, user.Balance)
+
+	return user, nil
+}
+
+// validateProfile checks the profile fields of a user; empty values are
+// allowed except for the currency, which is only checked when set.
+func validateProfile(currency, externalRef, displayName, country string) error {
+	if currency != "" {
+		if err := utils.ValidateCurrency(currency); err != nil {
+			return err
+		}
+	}
+	if err := utils.ValidateExternalRef(externalRef); err != nil {
+		return err
+	}
+	if err := utils.ValidateDisplayName(displayName); err != nil {
+		return err
+	}
+	if country != "" {
+		if err := utils.ValidateCountry(country); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanUser reads a user row; action names the failed operation in errors.
+func scanUser(action string, row *sql.Row) (*models.User, error) {
+	var user models.User
+	var externalRef, displayName, country, maxBalance, frozen, frozenReason sql.NullString
+	var frozenAt, chargebackAt, anonymizedAt, deletedAt sql.NullTime
+	err := row.Scan(&user.ID, &user.Balance, &user.Currency, &user.Status, &user.KYCLevel, &externalRef, &displayName, &country, &maxBalance, &frozen, &frozenReason, &frozenAt, &chargebackAt, &anonymizedAt, &user.CreatedAt, &user.UpdatedAt, &deletedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s user: %w", action, err)
+	}
+	user.ExternalRef = externalRef.String
+	user.DisplayName = displayName.String
+	user.Country = country.String
+	user.MaxBalance = maxBalance.String
+	user.Frozen = frozen.String
+	user.FrozenReason = frozenReason.String
+	if frozenAt.Valid {
+		user.FrozenAt = &frozenAt.Time
+	}
+	if chargebackAt.Valid {
+		user.ChargebackAt = &chargebackAt.Time
+	}
+	if anonymizedAt.Valid {
+		user.AnonymizedAt = &anonymizedAt.Time
+	}
+	if deletedAt.Valid {
+		user.DeletedAt = &deletedAt.Time
+	}
+	return &user, nil
+}
+
+func stringOr(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
@@ -0,0 +1,228 @@
+package core
+
+import (
+	"errors"
+	"testing"
+
+	"assignment/internal/clock"
+	"assignment/internal/models"
+	"assignment/internal/testutil"
+)
+
+func TestCreateUser(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.New())
+
+	user, err := service.CreateUser(models.CreateUserRequest{Balance: "25.5", Currency: "USD"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if user.ID != 4 || user.Balance != "25.50" || user.Currency != "USD" {
+		t.Errorf("Expected user 4 with 25.50 USD, got: %+v", user)
+	}
+
+	// The new user takes part in the ledger like any other
+	resp := mustProcess(t, service, user.ID, testutil.NewTransactionRequest("lose", "5.50"), "game")
+	if resp.Balance != "20.00" {
+		t.Errorf("Expected balance 20.00, got: %s", resp.Balance)
+	}
+	report, err := service.ReplayLedger()
+	if err != nil || len(report.Divergences) != 0 {
+		t.Errorf("Expected replay to match, got: %v, %v", report, err)
+	}
+
+	defaults, err := service.CreateUser(models.CreateUserRequest{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if defaults.Balance != "0.00" || defaults.Currency != DefaultCurrency {
+		t.Errorf("Expected 0.00 %s, got: %+v", DefaultCurrency, defaults)
+	}
+
+	if _, err := service.CreateUser(models.CreateUserRequest{Currency: "euro"}); err == nil {
+		t.Error("Expected error for invalid currency")
+	}
+}
+
+func TestUpdateUser(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.New())
+
+	ref, name := "crm-1", "Ada"
+	user, err := service.UpdateUser(1, models.UpdateUserRequest{ExternalRef: &ref, DisplayName: &name})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if user.ExternalRef != ref || user.DisplayName != name || user.Balance != "100.00" {
+		t.Errorf("Expected profile to be set, got: %+v", user)
+	}
+
+	// Unset fields are left alone; empty strings clear them
+	country, empty := "DE", ""
+	user, err = service.UpdateUser(1, models.UpdateUserRequest{Country: &country, DisplayName: &empty})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if user.ExternalRef != ref || user.DisplayName != "" || user.Country != country {
+		t.Errorf("Expected partial update, got: %+v", user)
+	}
+
+	got, err := service.GetUser(1)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if *got != *user {
+		t.Errorf("Expected %+v, got: %+v", user, got)
+	}
+
+	// External references are unique
+	if _, err := service.UpdateUser(2, models.UpdateUserRequest{ExternalRef: &ref}); err == nil || err.Error() != "external reference already in use" {
+		t.Errorf("Expected conflict, got: %v", err)
+	}
+	if _, err := service.UpdateUser(999, models.UpdateUserRequest{Country: &country}); err == nil || err.Error() != "user not found" {
+		t.Errorf("Expected user not found, got: %v", err)
+	}
+	if _, err := service.UpdateUser(1, models.UpdateUserRequest{Currency: &empty}); err == nil {
+		t.Error("Expected error clearing the currency")
+	}
+}
+
+func TestUpdateUser_Currency(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.New())
+
+	// User 1 has a balance, user 3 has none and no transactions
+	usd := "USD"
+	if _, err := service.UpdateUser(1, models.UpdateUserRequest{Currency: &usd}); !errors.Is(err, ErrCurrencyInUse) {
+		t.Errorf("Expected ErrCurrencyInUse for a user with a balance, got: %v", err)
+	}
+	eur := "EUR"
+	if _, err := service.UpdateUser(1, models.UpdateUserRequest{Currency: &eur}); err != nil {
+		t.Errorf("Expected setting the current currency to succeed, got: %v", err)
+	}
+
+	if _, err := service.CreateWallet(3, "bonus"); err != nil {
+		t.Fatalf("Failed to create wallet: %v", err)
+	}
+	user, err := service.UpdateUser(3, models.UpdateUserRequest{Currency: &usd})
+	if err != nil || user.Currency != "USD" {
+		t.Fatalf("Expected user 3 in USD, got: %v, %v", user, err)
+	}
+	var walletCurrency string
+	if err := db.QueryRow(`SELECT currency FROM users WHERE owner_id = 3 AND wallet_name = 'bonus'`).Scan(&walletCurrency); err != nil || walletCurrency != "USD" {
+		t.Errorf("Expected the wallet to follow the user to USD, got: %s, %v", walletCurrency, err)
+	}
+
+	// Once the user has transacted the currency is fixed
+	mustProcess(t, service, 3, testutil.NewTransactionRequest("win", "1.00"), "game")
+	mustProcess(t, service, 3, testutil.NewTransactionRequest("lose", "1.00"), "game")
+	if _, err := service.UpdateUser(3, models.UpdateUserRequest{Currency: &eur}); !errors.Is(err, ErrCurrencyInUse) {
+		t.Errorf("Expected ErrCurrencyInUse for a user with transactions, got: %v", err)
+	}
+}
+
+func TestSetUserStatus(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.New())
+
+	mustProcess(t, service, 1, models.TransactionRequest{State: "win", Amount: "5.00", TransactionID: "status-1"}, "game")
+
+	user, err := service.SetUserStatus(1, models.UserStatusRequest{Status: UserSuspended, Reason: "review"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if user.Status != UserSuspended {
+		t.Errorf("Expected status suspended, got: %s", user.Status)
+	}
+
+	// Suspended users are rejected, but replays are still answered
+	resp := mustProcess(t, service, 1, testutil.NewTransactionRequest("win", "1.00"), "game")
+	if resp.Message != "User suspended" || resp.Balance != "105.00" {
+		t.Errorf("Expected suspended rejection at 105.00, got: %+v", resp)
+	}
+	resp = mustProcess(t, service, 1, models.TransactionRequest{State: "win", Amount: "5.00", TransactionID: "status-1"}, "game")
+	if !resp.Replayed || resp.Message != "Transaction applied successfully" {
+		t.Errorf("Expected the original response replayed, got: %+v", resp)
+	}
+	transfer, err := service.Transfer(models.TransferRequest{FromUserID: 2, ToUserID: 1, Amount: "1.00"}, "game")
+	if err != nil || transfer.Message != "User suspended" {
+		t.Errorf("Expected transfer to a suspended user to be rejected, got: %v, %v", transfer, err)
+	}
+
+	// Closed users reject everything, and closing is final
+	if _, err := service.SetUserStatus(1, models.UserStatusRequest{Status: UserClosed}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	resp = mustProcess(t, service, 1, models.TransactionRequest{State: "win", Amount: "5.00", TransactionID: "status-1"}, "game")
+	if resp.Message != "User closed" {
+		t.Errorf("Expected closed rejection of the replay, got: %s", resp.Message)
+	}
+	if _, err := service.SetUserStatus(1, models.UserStatusRequest{Status: UserActive}); err == nil {
+		t.Error("Expected error reactivating a closed user")
+	}
+
+	var changes int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM user_status_changes WHERE user_id = 1`).Scan(&changes); err != nil {
+		t.Fatalf("Failed to count status changes: %v", err)
+	}
+	if changes != 2 {
+		t.Errorf("Expected 2 status changes, got: %d", changes)
+	}
+}
+
+func TestDeleteUser(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.New())
+
+	mustProcess(t, service, 2, models.TransactionRequest{State: "win", Amount: "5.00", TransactionID: "delete-1"}, "game")
+
+	user, err := service.DeleteUser(2)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if user.DeletedAt == nil {
+		t.Error("Expected deleted_at to be set")
+	}
+
+	// Hidden from lookups and rankings, and blocked from transacting
+	if _, err := service.GetUser(2); err == nil || err.Error() != "user not found" {
+		t.Errorf("Expected user not found, got: %v", err)
+	}
+	if _, err := service.GetBalance(2); err == nil || err.Error() != "user not found" {
+		t.Errorf("Expected user not found for balance, got: %v", err)
+	}
+	if _, err := service.ProcessTransaction(2, testutil.NewTransactionRequest("win", "1.00"), "game"); err == nil || err.Error() != "user not found" {
+		t.Errorf("Expected user not found for a transaction, got: %v", err)
+	}
+	if _, err := service.DeleteUser(2); err == nil || err.Error() != "user not found" {
+		t.Errorf("Expected user not found deleting twice, got: %v", err)
+	}
+	report, err := service.GetTopUsers("balance", DefaultCurrency, 10, models.TagFilter{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	for _, entry := range report.Users {
+		if entry.UserID == 2 {
+			t.Error("Expected deleted user to be excluded from rankings")
+		}
+	}
+
+	// The ledger is kept
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM transactions WHERE user_id = 2`).Scan(&count); err != nil {
+		t.Fatalf("Failed to count transactions: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected the transaction to be kept, got %d rows", count)
+	}
+}
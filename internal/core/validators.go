@@ -0,0 +1,72 @@
+package core
+
+import (
+	"time"
+
+	"assignment/internal/models"
+	"assignment/internal/money"
+)
+
+// UserContext describes the account a transaction applies to as locked for
+// it, for validators to decide on.
+type UserContext struct {
+	// UserID is the account, OwnerID the user owning it, the same for
+	// user accounts and the owner of a wallet
+	UserID     int64
+	OwnerID    int64
+	SourceType string
+	Status     string
+	KYCLevel   string
+	// Frozen is the freeze mode, FreezeDebits or FreezeAll, "" if the
+	// account is not frozen
+	Frozen   string
+	Balance  string
+	Currency string
+	Now      time.Time
+}
+
+// Validator enforces a deployment's own rule on transactions, e.g. blocking
+// bets on a voided match. An error rejects the transaction with that error
+// and nothing is recorded; return a ValidationError for a 400 Bad Request.
+type Validator func(req models.TransactionRequest, user UserContext) error
+
+// AddValidators runs validators, in order, on every new transaction once its
+// request is valid and its account is locked, before the status, freeze,
+// KYC and balance checks. Replayed transactions are not validated again.
+// Like hooks they cover ProcessTransaction, ProcessWalletTransaction, the
+// message queue and imports. Call it before serving requests.
+func (s *TransactionService) AddValidators(validators ...Validator) {
+	s.validators = append(s.validators, validators...)
+}
+
+// WithValidators adds validators to the service, like AddValidators.
+func WithValidators(validators ...Validator) Option {
+	return func(s *TransactionService) {
+		s.AddValidators(validators...)
+	}
+}
+
+// validate runs the validators on a transaction about to be evaluated
+// against an account locked by lockBalance.
+func (s *TransactionService) validate(userID int64, account lockedAccount, req models.TransactionRequest, sourceType string, now time.Time) error {
+	if len(s.validators) == 0 {
+		return nil
+	}
+	user := UserContext{
+		UserID:     userID,
+		OwnerID:    account.ownerID,
+		SourceType: sourceType,
+		Status:     account.status,
+		KYCLevel:   account.kycLevel,
+		Frozen:     account.frozen,
+		Balance:    money.Format(account.balance, account.currency),
+		Currency:   account.currency,
+		Now:        now,
+	}
+	for _, validate := range s.validators {
+		if err := validate(req, user); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package core
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"assignment/internal/clock"
+	"assignment/internal/models"
+	"assignment/internal/testutil"
+)
+
+func TestValidators(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	var calls []string
+	service := NewTransactionService(db, clock.New(), WithValidators(
+		func(req models.TransactionRequest, user UserContext) error {
+			calls = append(calls, "first")
+			if user.UserID != 1 || user.OwnerID != 1 || user.SourceType != "game" || user.Status != UserActive || user.Balance == "" {
+				t.Errorf("Unexpected user context: %+v", user)
+			}
+			if strings.HasPrefix(req.TransactionID, "match-42-") {
+				return &ValidationError{Field: "transactionId", Message: "match voided"}
+			}
+			return nil
+		},
+		func(req models.TransactionRequest, user UserContext) error {
+			calls = append(calls, "second")
+			return nil
+		},
+	))
+
+	req := models.TransactionRequest{State: "win", Amount: "10.00", TransactionID: "match-42-bet-1"}
+	if _, err := service.ProcessTransaction(1, req, "game"); !errors.Is(err, ErrValidation) || err.Error() != "match voided" {
+		t.Fatalf("Expected the validator's error, got: %v", err)
+	}
+	if len(calls) != 1 {
+		t.Errorf("Expected validation to stop at the first error, got: %v", calls)
+	}
+	balance, err := service.GetBalance(1)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if balance.Balance != "100.00" {
+		t.Errorf("Expected the balance untouched, got: %s", balance.Balance)
+	}
+
+	calls = nil
+	resp, err := service.ProcessTransaction(1, models.TransactionRequest{State: "win", Amount: "10.00", TransactionID: "match-43-bet-1"}, "game")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if resp.Balance != "110.00" || len(calls) != 2 || calls[0] != "first" || calls[1] != "second" {
+		t.Errorf("Expected both validators in order and the transaction applied, got: %v, %+v", calls, resp)
+	}
+}
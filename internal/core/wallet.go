@@ -0,0 +1,226 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"assignment/internal/models"
+	"assignment/internal/money"
+	"assignment/internal/utils"
+	"assignment/internal/validation"
+)
+
+// MainWallet addresses a user's primary balance, the one served by the
+// /user/{userId}/... endpoints.
+const MainWallet = "main"
+
+// CreateWallet adds a named wallet with a zero balance to a user, or returns
+// the existing wallet with that ID. Wallets are stored alongside users, with
+// their own balance snapshots and event stream, and take the currency of
+// their owner.
+func (s *TransactionService) CreateWallet(userID int64, walletID string) (*models.Wallet, error) {
+	if err := utils.ValidateWalletID(walletID); err != nil {
+		return nil, err
+	}
+	if walletID == MainWallet {
+		return nil, validation.Errorf("walletId", "invalid wallet ID: main is the user's primary wallet")
+	}
+
+	now := s.clock.Now()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// The zero balance is written with the decimals of the owner's currency
+	var currency string
+	err = tx.QueryRow(
+		`SELECT currency FROM users WHERE id = $1 AND kind = $2 AND deleted_at IS NULL`,
+		userID,
+		AccountUser,
+	).Scan(&currency)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up user: %w", err)
+	}
+	zero := money.Format(0, currency)
+
+	var id int64
+	err = tx.QueryRow(
+		`INSERT INTO users (balance, kind, owner_id, wallet_name, currency, created_at, updated_at)
+		 SELECT $1, $2, id, $3, currency, $4, $4 FROM users WHERE id = $5 AND kind = $6 AND deleted_at IS NULL
+		 ON CONFLICT (owner_id, wallet_name) DO NOTHING
+		 RETURNING id`,
+		zero,
+		AccountWallet,
+		walletID,
+		now,
+		userID,
+		AccountUser,
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		// Already exists, or the user does not
+		return s.getWallet(userID, walletID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create wallet: %w", err)
+	}
+
+	_, err = tx.Exec(`INSERT INTO balance_snapshots (user_id, balance, recorded_at) VALUES ($1, $2, $3)`, id, zero, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record balance snapshot: %w", err)
+	}
+	if _, err := s.openStream(tx, id, zero, now); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &models.Wallet{WalletID: walletID, Balance: zero, CreatedAt: now}, nil
+}
+
+// GetWallets returns the wallets of a user, starting with the main wallet.
+func (s *TransactionService) GetWallets(userID int64) (*models.WalletList, error) {
+	rows, err := s.db.Query(
+		`SELECT COALESCE(w.wallet_name, $3), w.balance, w.created_at
+		 FROM users o JOIN users w ON w.id = o.id OR (w.owner_id = o.id AND w.kind = $2 AND w.deleted_at IS NULL)
+		 WHERE o.id = $1 AND o.kind = $4 AND o.deleted_at IS NULL
+		 ORDER BY w.id`,
+		userID,
+		AccountWallet,
+		MainWallet,
+		AccountUser,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query wallets: %w", err)
+	}
+	defer rows.Close()
+
+	list := &models.WalletList{UserID: userID, Wallets: []models.Wallet{}}
+	for rows.Next() {
+		var wallet models.Wallet
+		if err := rows.Scan(&wallet.WalletID, &wallet.Balance, &wallet.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan wallet: %w", err)
+		}
+		list.Wallets = append(list.Wallets, wallet)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read wallets: %w", err)
+	}
+	if len(list.Wallets) == 0 {
+		return nil, ErrUserNotFound
+	}
+	return list, nil
+}
+
+// GetWalletBalance returns the balance of one wallet of a user.
+func (s *TransactionService) GetWalletBalance(userID int64, walletID string) (*models.WalletBalanceResponse, error) {
+	wallet, err := s.getWallet(userID, walletID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.WalletBalanceResponse{UserID: userID, WalletID: walletID, Balance: wallet.Balance}, nil
+}
+
+func (s *TransactionService) getWallet(userID int64, walletID string) (*models.Wallet, error) {
+	list, err := s.GetWallets(userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list.Wallets {
+		if list.Wallets[i].WalletID == walletID {
+			return &list.Wallets[i], nil
+		}
+	}
+	return nil, ErrWalletNotFound
+}
+
+// ProcessWalletTransaction applies a transaction to one wallet of a user,
+// exactly like ProcessTransactionContext does to the main wallet. The wallet
+// is subject to its owner's status and KYC limits.
+func (s *TransactionService) ProcessWalletTransaction(ctx context.Context, userID int64, walletID string, req models.TransactionRequest, sourceType string) (*models.TransactionResponse, error) {
+	accountID, kind, err := s.walletAccount(userID, walletID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.processAccountTransaction(ctx, accountID, kind, req, sourceType, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp.UserID = userID
+	resp.WalletID = walletID
+	return resp, nil
+}
+
+// MoveBetweenWallets moves funds between two wallets of a user as an
+// internal transfer: atomic, idempotent on the move ID and exempt from KYC
+// limits, since the funds never leave the user. If ctx is done before the
+// move commits, it is rolled back and ctx's error is returned.
+func (s *TransactionService) MoveBetweenWallets(ctx context.Context, userID int64, req models.WalletMoveRequest, sourceType string) (*models.WalletMoveResponse, error) {
+	if req.FromWalletID == req.ToWalletID {
+		return nil, validation.Errorf("toWalletId", "invalid move: wallets must differ")
+	}
+	fromID, fromKind, err := s.walletAccount(userID, req.FromWalletID)
+	if err != nil {
+		return nil, err
+	}
+	toID, toKind, err := s.walletAccount(userID, req.ToWalletID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.transfer(ctx, models.TransferRequest{
+		FromUserID: fromID,
+		ToUserID:   toID,
+		Amount:     req.Amount,
+		TransferID: req.MoveID,
+	}, sourceType, fromKind, toKind, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.WalletMoveResponse{
+		MoveID:       resp.TransferID,
+		UserID:       userID,
+		FromWalletID: req.FromWalletID,
+		ToWalletID:   req.ToWalletID,
+		Amount:       resp.Amount,
+		FromBalance:  resp.FromBalance,
+		ToBalance:    resp.ToBalance,
+		Message:      resp.Message,
+	}, nil
+}
+
+// walletAccount resolves a wallet of a user to the ID and kind of the
+// account holding it.
+func (s *TransactionService) walletAccount(userID int64, walletID string) (int64, string, error) {
+	if walletID == MainWallet {
+		return userID, AccountUser, nil
+	}
+	if err := utils.ValidateWalletID(walletID); err != nil {
+		return 0, "", err
+	}
+
+	var id int64
+	err := s.db.QueryRow(
+		`SELECT id FROM users WHERE owner_id = $1 AND wallet_name = $2 AND kind = $3 AND deleted_at IS NULL`,
+		userID,
+		walletID,
+		AccountWallet,
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, "", ErrWalletNotFound
+	}
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to get wallet: %w", err)
+	}
+	return id, AccountWallet, nil
+}
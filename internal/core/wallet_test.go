@@ -0,0 +1,68 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"assignment/internal/clock"
+	"assignment/internal/models"
+	"assignment/internal/testutil"
+)
+
+func TestWallets(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := NewTransactionService(db, clock.NewFake(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)))
+
+	user, err := service.CreateUser(models.CreateUserRequest{Balance: "50.00"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := service.CreateWallet(user.ID, "casino"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	// Creating a wallet again returns the existing one
+	if _, err := service.CreateWallet(user.ID, "casino"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := service.CreateWallet(user.ID, MainWallet); err == nil {
+		t.Error("Expected error for reserved wallet ID")
+	}
+
+	resp, err := service.ProcessWalletTransaction(context.Background(), user.ID, "casino", testutil.NewTransactionRequest("win", "20.00"), "game")
+	if err != nil || resp.Balance != "20.00" || resp.WalletID != "casino" {
+		t.Fatalf("Expected casino balance 20.00, got: %+v, %v", resp, err)
+	}
+
+	// Moves are internal, so the owner's KYC daily limit ignores them
+	move, err := service.MoveBetweenWallets(context.Background(), user.ID, models.WalletMoveRequest{
+		FromWalletID: MainWallet,
+		ToWalletID:   "casino",
+		Amount:       "50.00",
+		MoveID:       "move-1",
+	}, "game")
+	if err != nil || move.FromBalance != "0.00" || move.ToBalance != "70.00" {
+		t.Fatalf("Expected move to apply, got: %+v, %v", move, err)
+	}
+	resp, err = service.ProcessWalletTransaction(context.Background(), user.ID, "casino", testutil.NewTransactionRequest("lose", "80.01"), "game")
+	if err != nil || resp.Message != "KYC daily limit exceeded" {
+		t.Errorf("Expected owner's daily limit to cover wallets, got: %+v, %v", resp, err)
+	}
+
+	wallets, err := service.GetWallets(user.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(wallets.Wallets) != 2 || wallets.Wallets[0].WalletID != MainWallet || wallets.Wallets[1].Balance != "70.00" {
+		t.Errorf("Expected main and casino wallets, got: %+v", wallets.Wallets)
+	}
+
+	if _, err := service.GetWalletBalance(user.ID, "poker"); err == nil || err.Error() != "wallet not found" {
+		t.Errorf("Expected wallet not found, got: %v", err)
+	}
+	if _, err := service.MoveBetweenWallets(context.Background(), user.ID, models.WalletMoveRequest{FromWalletID: "casino", ToWalletID: "casino", Amount: "1.00"}, "game"); err == nil {
+		t.Error("Expected error for move within one wallet")
+	}
+}
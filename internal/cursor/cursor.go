@@ -0,0 +1,67 @@
+// Package cursor encodes positions in keyset-paginated listings as opaque
+// tokens. A position is the sort key of a row, a time and a unique ID, so a
+// page is read with a range condition on an index instead of an OFFSET that
+// scans every row before it.
+package cursor
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalid is returned for tokens that were not made by String.
+var ErrInvalid = errors.New("invalid cursor")
+
+// Cursor is a position in a listing ordered by (Time, ID). The next page
+// holds the rows after it; with Before set, the previous page holds the rows
+// before it.
+type Cursor struct {
+	Time   time.Time
+	ID     int64
+	Before bool
+}
+
+// After returns a cursor to the rows after the given sort key.
+func After(t time.Time, id int64) *Cursor {
+	return &Cursor{Time: t, ID: id}
+}
+
+// Before returns a cursor to the rows before the given sort key.
+func Before(t time.Time, id int64) *Cursor {
+	return &Cursor{Time: t, ID: id, Before: true}
+}
+
+// String returns the token of c.
+func (c Cursor) String() string {
+	direction := 'a'
+	if c.Before {
+		direction = 'b'
+	}
+	raw := fmt.Sprintf("%c.%d.%d", direction, c.Time.UnixNano(), c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// Parse decodes a token made by String.
+func Parse(token string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	parts := strings.Split(string(raw), ".")
+	if len(parts) != 3 || (parts[0] != "a" && parts[0] != "b") {
+		return nil, ErrInvalid
+	}
+	nanos, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	id, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	return &Cursor{Time: time.Unix(0, nanos).UTC(), ID: id, Before: parts[0] == "b"}, nil
+}
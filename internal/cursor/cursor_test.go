@@ -0,0 +1,27 @@
+package cursor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRoundTrip(t *testing.T) {
+	at := time.Date(2024, 1, 15, 12, 0, 0, 123456000, time.UTC)
+	for _, c := range []*Cursor{After(at, 42), Before(at, 7)} {
+		parsed, err := Parse(c.String())
+		if err != nil {
+			t.Fatalf("Parse(%s): %v", c, err)
+		}
+		if !parsed.Time.Equal(c.Time) || parsed.ID != c.ID || parsed.Before != c.Before {
+			t.Errorf("Expected %+v, got %+v", c, parsed)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, token := range []string{"", "!!", "eC4xLjI", "YS54LjE", "YS4xLjJ4"} {
+		if _, err := Parse(token); err != ErrInvalid {
+			t.Errorf("Parse(%q): expected ErrInvalid, got %v", token, err)
+		}
+	}
+}
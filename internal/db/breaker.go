@@ -0,0 +1,146 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"assignment/internal/clock"
+	"github.com/lib/pq"
+)
+
+// CircuitBreakerConfig sets when the circuit breaker of a DB opens.
+type CircuitBreakerConfig struct {
+	// Failures is the number of consecutive connection failures or timeouts
+	// that opens the breaker; zero disables it.
+	Failures int
+	// Cooldown is how long the breaker stays open before it lets a single
+	// statement through to probe the database.
+	Cooldown time.Duration
+}
+
+// CircuitOpenError is returned instead of running a statement while the
+// circuit breaker is open.
+type CircuitOpenError struct {
+	Wait time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	return "database unavailable: circuit breaker open"
+}
+
+// RetryAfter is how long until the breaker probes the database again.
+func (e *CircuitOpenError) RetryAfter() time.Duration {
+	return e.Wait
+}
+
+// SetCircuitBreaker makes the database fail statements fast while it looks
+// unreachable, e.g. during a failover, instead of letting every caller wait
+// on dead connections. After cfg.Failures consecutive connection failures or
+// timeouts the breaker opens, and statements and new connections fail with a
+// CircuitOpenError for cfg.Cooldown. Then one statement is let through: if
+// it reaches the database the breaker closes, otherwise it stays open for
+// another cooldown.
+func (db *DB) SetCircuitBreaker(cfg CircuitBreakerConfig) {
+	db.breaker.configure(cfg)
+}
+
+type circuitBreaker struct {
+	clock clock.Clock
+
+	mu       sync.Mutex
+	cfg      CircuitBreakerConfig
+	failures int
+	// openUntil is when an open breaker lets a probe through; zero while
+	// the breaker is closed
+	openUntil time.Time
+	probing   bool
+}
+
+func newCircuitBreaker(clk clock.Clock) *circuitBreaker {
+	return &circuitBreaker{clock: clk}
+}
+
+func (b *circuitBreaker) configure(cfg CircuitBreakerConfig) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.cfg = cfg
+	b.failures, b.openUntil, b.probing = 0, time.Time{}, false
+}
+
+// allow returns a CircuitOpenError if a statement must not run now.
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.cfg.Failures <= 0 || b.openUntil.IsZero() {
+		return nil
+	}
+	if wait := b.openUntil.Sub(b.clock.Now()); wait > 0 {
+		return &CircuitOpenError{Wait: wait}
+	}
+	if b.probing {
+		// Everyone else waits for the probe to settle
+		return &CircuitOpenError{Wait: time.Second}
+	}
+	b.probing = true
+	return nil
+}
+
+// record counts the outcome of a statement or connection attempt.
+func (b *circuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.cfg.Failures <= 0 {
+		return
+	}
+
+	if !isConnectionFailure(err) {
+		if !b.openUntil.IsZero() {
+			log.Printf("Database circuit breaker closed")
+		}
+		b.failures, b.openUntil, b.probing = 0, time.Time{}, false
+		return
+	}
+
+	b.failures++
+	if b.probing || b.failures >= b.cfg.Failures {
+		if b.openUntil.IsZero() {
+			log.Printf("Database circuit breaker opened after %d consecutive failures: %v", b.failures, err)
+		}
+		b.openUntil = b.clock.Now().Add(b.cfg.Cooldown)
+		b.probing = false
+	}
+}
+
+// abandon gives up a call without an outcome. If it was the probe of an
+// open breaker, the next call probes instead.
+func (b *circuitBreaker) abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// isConnectionFailure reports whether err says the database can't be
+// reached or didn't answer in time, as opposed to refusing a statement.
+func isConnectionFailure(err error) bool {
+	if err == nil || errors.Is(err, driver.ErrSkip) {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Name() {
+		case "admin_shutdown", "crash_shutdown", "cannot_connect_now", "query_canceled":
+			return true
+		}
+		return pqErr.Code.Class() == "08"
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
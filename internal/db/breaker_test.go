@@ -0,0 +1,95 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"assignment/internal/clock"
+	"github.com/lib/pq"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	b := newCircuitBreaker(clk)
+	b.configure(CircuitBreakerConfig{Failures: 3, Cooldown: 10 * time.Second})
+
+	// Failures must be consecutive, and refused statements don't count
+	b.record(driver.ErrBadConn)
+	b.record(driver.ErrBadConn)
+	b.record(&pq.Error{Code: "23505"})
+	b.record(driver.ErrBadConn)
+	if err := b.allow(); err != nil {
+		t.Fatalf("Expected the breaker to stay closed, got: %v", err)
+	}
+
+	b.record(driver.ErrBadConn)
+	b.record(&pq.Error{Code: "57P01"})
+	var open *CircuitOpenError
+	if err := b.allow(); !errors.As(err, &open) || open.RetryAfter() != 10*time.Second {
+		t.Fatalf("Expected the breaker to open for 10s, got: %v", err)
+	}
+
+	// After the cooldown a single probe goes through; it fails and the
+	// breaker stays open for another cooldown
+	clk.Advance(10 * time.Second)
+	if err := b.allow(); err != nil {
+		t.Fatalf("Expected a probe to be let through, got: %v", err)
+	}
+	if err := b.allow(); err == nil {
+		t.Fatal("Expected only one probe at a time")
+	}
+	b.record(context.DeadlineExceeded)
+	if err := b.allow(); !errors.As(err, &open) || open.RetryAfter() != 10*time.Second {
+		t.Fatalf("Expected the breaker to reopen for 10s, got: %v", err)
+	}
+
+	// A probe reaching the database closes the breaker
+	clk.Advance(10 * time.Second)
+	if err := b.allow(); err != nil {
+		t.Fatalf("Expected a probe to be let through, got: %v", err)
+	}
+	b.record(nil)
+	for i := 0; i < 3; i++ {
+		if err := b.allow(); err != nil {
+			t.Fatalf("Expected the breaker to be closed, got: %v", err)
+		}
+	}
+}
+
+func TestCircuitBreaker_Disabled(t *testing.T) {
+	b := newCircuitBreaker(clock.New())
+	for i := 0; i < 10; i++ {
+		b.record(driver.ErrBadConn)
+	}
+	if err := b.allow(); err != nil {
+		t.Errorf("Expected a disabled breaker to allow everything, got: %v", err)
+	}
+}
+
+func TestSettle_IgnoresCallersThatGaveUp(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	b := newCircuitBreaker(clk)
+	b.configure(CircuitBreakerConfig{Failures: 1, Cooldown: 10 * time.Second})
+
+	// A caller's deadline passing says nothing about the database
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now())
+	defer cancel()
+	settle(ctx, b, context.DeadlineExceeded)
+	if err := b.allow(); err != nil {
+		t.Fatalf("Expected the breaker to stay closed, got: %v", err)
+	}
+
+	// An abandoned probe lets the next call probe instead
+	b.record(driver.ErrBadConn)
+	clk.Advance(10 * time.Second)
+	if err := b.allow(); err != nil {
+		t.Fatalf("Expected a probe to be let through, got: %v", err)
+	}
+	settle(ctx, b, context.DeadlineExceeded)
+	if err := b.allow(); err != nil {
+		t.Fatalf("Expected another probe to be let through, got: %v", err)
+	}
+}
@@ -5,82 +5,104 @@ import (
 	"fmt"
 	"log"
 
-	_ "github.com/lib/pq"
+	"assignment/internal/clock"
+	"assignment/internal/fixture"
+	"github.com/lib/pq"
 )
 
 type DB struct {
 	*sql.DB
+	clock    clock.Clock
+	observer *queryObserver
+	breaker  *circuitBreaker
 }
 
-func NewDB(connectionString string) (*DB, error) {
-	db, err := sql.Open("postgres", connectionString)
+// Open opens the database without touching its schema. Its statements are
+// recorded once SetMetrics is called, and guarded by a circuit breaker once
+// SetCircuitBreaker is.
+func Open(connectionString string, clk clock.Clock) (*DB, error) {
+	connector, err := pq.NewConnector(connectionString)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	observer, breaker := &queryObserver{}, newCircuitBreaker(clk)
+	db := sql.OpenDB(&instrumentedConnector{Connector: connector, observer: observer, breaker: breaker})
 
 	if err := db.Ping(); err != nil {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	database := &DB{DB: db}
+	return &DB{DB: db, clock: clk, observer: observer, breaker: breaker}, nil
+}
+
+// NewDB opens the database, applies pending migrations and seeds it with
+// the users of seed, or of fixture.Default if seed is nil.
+func NewDB(connectionString string, clk clock.Clock, seed *fixture.Fixture) (*DB, error) {
+	database, err := Open(connectionString, clk)
+	if err != nil {
+		return nil, err
+	}
 
 	if err := database.Migrate(); err != nil {
 		return nil, fmt.Errorf("failed to migrate database: %w", err)
 	}
 
-	if err := database.Seed(); err != nil {
+	if seed == nil {
+		seed = fixture.Default()
+	}
+	if err := database.Seed(seed); err != nil {
 		return nil, fmt.Errorf("failed to seed database: %w", err)
 	}
 
 	return database, nil
 }
 
-func (db *DB) Migrate() error {
-	queries := []string{
-		`CREATE TABLE IF NOT EXISTS users (
-			id BIGSERIAL PRIMARY KEY,
-			balance NUMERIC(10,2) NOT NULL DEFAULT 0,
-			created_at TIMESTAMP DEFAULT NOW(),
-			updated_at TIMESTAMP DEFAULT NOW()
-		)`,
-		`CREATE TABLE IF NOT EXISTS transactions (
-			id BIGSERIAL PRIMARY KEY,
-			user_id BIGINT REFERENCES users(id),
-			transaction_id TEXT UNIQUE,
-			state TEXT,
-			amount NUMERIC(10,2),
-			source_type TEXT,
-			applied BOOLEAN,
-			created_at TIMESTAMP DEFAULT NOW()
-		)`,
-		`CREATE INDEX IF NOT EXISTS idx_transactions_user_id ON transactions(user_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_transactions_transaction_id ON transactions(transaction_id)`,
-	}
-
-	for _, query := range queries {
-		if _, err := db.Exec(query); err != nil {
-			return fmt.Errorf("failed to execute migration: %w", err)
+// Seed inserts the users of a fixture with their opening balance, leaving
+// existing users alone. Their historical transactions are applied with
+// core.TransactionService.ApplyFixture.
+func (db *DB) Seed(f *fixture.Fixture) error {
+	now := db.clock.Now()
+	for _, u := range f.Users {
+		createdAt := u.CreatedAt
+		if createdAt.IsZero() {
+			createdAt = now
+		}
+		_, err := db.Exec(
+			`INSERT INTO users (id, balance, created_at, updated_at) VALUES ($1, $2, $3, $3) ON CONFLICT (id) DO NOTHING`,
+			u.ID, string(u.Balance), createdAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to seed users: %w", err)
 		}
 	}
 
-	log.Println("Database migrations completed successfully")
-	return nil
-}
+	// The seeded IDs bypass the sequence, so move it past them
+	if _, err := db.Exec(`SELECT setval('users_id_seq', (SELECT MAX(id) FROM users))`); err != nil {
+		return fmt.Errorf("failed to advance user ID sequence: %w", err)
+	}
 
-func (db *DB) Seed() error {
-	// Insert users with ON CONFLICT to handle existing users gracefully
-	queries := []string{
-		`INSERT INTO users (id, balance) VALUES (1, 100.00) ON CONFLICT (id) DO NOTHING`,
-		`INSERT INTO users (id, balance) VALUES (2, 50.00) ON CONFLICT (id) DO NOTHING`,
-		`INSERT INTO users (id, balance) VALUES (3, 0.00) ON CONFLICT (id) DO NOTHING`,
+	// Give every user without history a baseline snapshot of its current balance
+	_, err := db.Exec(
+		`INSERT INTO balance_snapshots (user_id, balance, recorded_at)
+		 SELECT u.id, u.balance, COALESCE(u.updated_at, $1) FROM users u
+		 WHERE NOT EXISTS (SELECT 1 FROM balance_snapshots s WHERE s.user_id = u.id)`,
+		now,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to seed balance snapshots: %w", err)
 	}
 
-	for _, query := range queries {
-		if _, err := db.Exec(query); err != nil {
-			return fmt.Errorf("failed to seed users: %w", err)
-		}
+	// Open the balance event stream of every user that has none
+	_, err = db.Exec(
+		`INSERT INTO balance_events (user_id, version, type, amount, balance, recorded_at)
+		 SELECT u.id, 1, 'opened', u.balance, u.balance, COALESCE(u.updated_at, $1) FROM users u
+		 WHERE NOT EXISTS (SELECT 1 FROM balance_events e WHERE e.user_id = u.id)`,
+		now,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to open balance event streams: %w", err)
 	}
-	log.Println("Database seeded with initial users")
+	log.Printf("Database seeded with %d users", len(f.Users))
 
 	return nil
 }
@@ -0,0 +1,308 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	"assignment/internal/metrics"
+)
+
+// queryObserver records the metrics of the statements run on a DB's
+// connections, once metrics are set.
+type queryObserver struct {
+	metrics atomic.Value // observedMetrics
+}
+
+// observedMetrics boxes the metrics, as atomic.Value only stores values of
+// one concrete type.
+type observedMetrics struct {
+	metrics.Metrics
+}
+
+// SetMetrics makes the database count every statement (db_queries_total, by
+// operation and status) and time it (db_query_duration_seconds, by
+// operation). Statements run before are not recorded.
+func (db *DB) SetMetrics(m metrics.Metrics) {
+	db.observer.metrics.Store(observedMetrics{m})
+}
+
+func (o *queryObserver) observe(operation string, start time.Time, err error) {
+	m, ok := o.metrics.Load().(observedMetrics)
+	if !ok || errors.Is(err, driver.ErrSkip) {
+		return
+	}
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	m.Inc("db_queries_total", metrics.Labels{"operation": operation, "status": status})
+	m.Timing("db_query_duration_seconds", time.Since(start), metrics.Labels{"operation": operation})
+}
+
+// instrumentedConnector opens connections whose statements are observed and
+// guarded by the circuit breaker.
+type instrumentedConnector struct {
+	driver.Connector
+	observer *queryObserver
+	breaker  *circuitBreaker
+}
+
+func (c *instrumentedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	if err := c.breaker.allow(); err != nil {
+		return nil, err
+	}
+	conn, err := c.Connector.Connect(ctx)
+	settle(ctx, c.breaker, err)
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedConn{Conn: conn, observer: c.observer, breaker: c.breaker}, nil
+}
+
+// settle records the outcome of a call with the breaker, unless the caller
+// gave up on it or its deadline passed: that says nothing about the database.
+func settle(ctx context.Context, b *circuitBreaker, err error) {
+	if ctx.Err() != nil {
+		b.abandon()
+		return
+	}
+	b.record(err)
+}
+
+// instrumentedConn times queries and statements as they are sent and their
+// first results arrive; reading the rows of a query is not included. Other
+// calls are passed through.
+type instrumentedConn struct {
+	driver.Conn
+	observer *queryObserver
+	breaker  *circuitBreaker
+}
+
+func (c *instrumentedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	if err := c.breaker.allow(); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	rows, err := queryer.QueryContext(ctx, query, args)
+	c.observer.observe(operationName(query), start, err)
+	settle(ctx, c.breaker, err)
+	return rows, err
+}
+
+func (c *instrumentedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	if err := c.breaker.allow(); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	result, err := execer.ExecContext(ctx, query, args)
+	c.observer.observe(operationName(query), start, err)
+	settle(ctx, c.breaker, err)
+	return result, err
+}
+
+func (c *instrumentedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c *instrumentedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if err := c.breaker.allow(); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	var tx driver.Tx
+	var err error
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		tx, err = beginner.BeginTx(ctx, opts)
+	} else {
+		tx, err = c.Conn.Begin()
+	}
+	c.observer.observe("begin", start, err)
+	settle(ctx, c.breaker, err)
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedTx{Tx: tx, observer: c.observer}, nil
+}
+
+func (c *instrumentedConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *instrumentedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *instrumentedConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+type instrumentedTx struct {
+	driver.Tx
+	observer *queryObserver
+}
+
+func (t *instrumentedTx) Commit() error {
+	start := time.Now()
+	err := t.Tx.Commit()
+	t.observer.observe("commit", start, err)
+	return err
+}
+
+func (t *instrumentedTx) Rollback() error {
+	start := time.Now()
+	err := t.Tx.Rollback()
+	t.observer.observe("rollback", start, err)
+	return err
+}
+
+// operationName names the operation of a statement for metrics: its command
+// and the table it works on, e.g. select_users for
+// "SELECT balance FROM users WHERE id = $1" or insert_transactions. The
+// command of a statement with a WITH clause is the one after it; the table
+// of a query is the first one it reads from, preferring the outer query to
+// subqueries. Statements without a table, e.g. "SELECT pg_advisory_lock($1)",
+// are named by their command alone. Names are bounded by the schema, so they
+// are safe as metric labels.
+func operationName(query string) string {
+	tokens := sqlTokens(query)
+	if len(tokens) == 0 {
+		return "unknown"
+	}
+
+	command, at := tokens[0].word, 0
+	if command == "with" {
+		// The main statement follows the common table expressions
+		for i := 1; i < len(tokens); i++ {
+			if tokens[i].depth == 0 && isCommand(tokens[i].word) && tokens[i-1].word == ")" {
+				command, at = tokens[i].word, i
+				break
+			}
+		}
+	}
+
+	var table string
+	switch command {
+	case "insert":
+		table = wordAfter(tokens[at:], "into", false)
+	case "update":
+		table = wordAfter(tokens[at:], "update", false)
+	case "delete", "select":
+		if table = wordAfter(tokens[at:], "from", true); table == "" {
+			table = wordAfter(tokens[at:], "from", false)
+		}
+	}
+	if table == "" {
+		return command
+	}
+	return command + "_" + table
+}
+
+// sqlToken is a lower-cased word or punctuation character of a statement,
+// with the parenthesis depth it appears at.
+type sqlToken struct {
+	word  string
+	depth int
+}
+
+// sqlTokens splits a statement into words, skipping comments and string
+// literals, and keeps parentheses as tokens of their own.
+func sqlTokens(query string) []sqlToken {
+	var tokens []sqlToken
+	depth := 0
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				return tokens
+			}
+			i += end
+		case strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return tokens
+			}
+			i += end + 4
+		case c == '\'':
+			end := strings.IndexByte(query[i+1:], '\'')
+			if end < 0 {
+				return tokens
+			}
+			i += end + 2
+		case c == '(':
+			tokens = append(tokens, sqlToken{"(", depth})
+			depth++
+			i++
+		case c == ')':
+			depth--
+			tokens = append(tokens, sqlToken{")", depth})
+			i++
+		case isWordByte(c):
+			start := i
+			for i < len(query) && (isWordByte(query[i]) || query[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, sqlToken{strings.ToLower(query[start:i]), depth})
+		default:
+			i++
+		}
+	}
+	return tokens
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c == '"' || c < 0x80 && (unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c)))
+}
+
+func isCommand(word string) bool {
+	switch word {
+	case "select", "insert", "update", "delete":
+		return true
+	}
+	return false
+}
+
+// wordAfter returns the table name following the first keyword in tokens,
+// only looking at the outermost level if topLevel is set. Schema qualifiers
+// and quotes are dropped; subqueries are not names.
+func wordAfter(tokens []sqlToken, keyword string, topLevel bool) string {
+	base := tokens[0].depth
+	for i, t := range tokens[:len(tokens)-1] {
+		if t.word != keyword || topLevel && t.depth != base {
+			continue
+		}
+		name := tokens[i+1].word
+		name = strings.Trim(name[strings.LastIndexByte(name, '.')+1:], `"`)
+		if name == "" || name == "(" || unicode.IsDigit(rune(name[0])) {
+			continue
+		}
+		return name
+	}
+	return ""
+}
@@ -0,0 +1,99 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"assignment/internal/clock"
+	"assignment/internal/metrics"
+)
+
+func TestOperationName(t *testing.T) {
+	tests := map[string]string{
+		`SELECT balance FROM users WHERE id = $1`:                                                      "select_users",
+		`select balance, status from users where id = $1 for update`:                                   "select_users",
+		`SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`:                                            "select_users",
+		`SELECT COUNT(*) FROM transactions t WHERE t.user_id IN (SELECT id FROM x)`:                    "select_transactions",
+		`INSERT INTO balance_snapshots (user_id, balance) VALUES ($1, $2)`:                             "insert_balance_snapshots",
+		`UPDATE users SET balance = $1 WHERE id = $2`:                                                  "update_users",
+		`DELETE FROM settlement_reports WHERE report_date = $1`:                                        "delete_settlement_reports",
+		`INSERT INTO public."users" (id) VALUES ($1)`:                                                  "insert_users",
+		`SELECT pg_advisory_xact_lock($1)`:                                                             "select",
+		`SET LOCAL statement_timeout = 0`:                                                              "set",
+		"-- from the cache\nSELECT id FROM api_tokens WHERE note = 'from users'":                       "select_api_tokens",
+		`/* FROM users */ SELECT 1`:                                                                    "select",
+		"WITH batch AS (SELECT id FROM transactions LIMIT 10)\nDELETE FROM transactions t USING batch": "delete_transactions",
+		`WITH base AS (SELECT 1 FROM users), replayed AS (SELECT 2) SELECT * FROM replayed`:            "select_replayed",
+		``: "unknown",
+	}
+	for query, want := range tests {
+		if got := operationName(query); got != want {
+			t.Errorf("operationName(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
+
+// fakeConn answers every statement, failing those mentioning "missing".
+type fakeConn struct{}
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Begin() (driver.Tx, error)           { return fakeTx{}, nil }
+
+func (fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if strings.Contains(query, "missing") {
+		return nil, errors.New(`relation "missing" does not exist`)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeConnector struct{}
+
+func (fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn{}, nil }
+func (fakeConnector) Driver() driver.Driver                        { return nil }
+
+func TestSetMetrics(t *testing.T) {
+	observer, breaker := &queryObserver{}, newCircuitBreaker(clock.New())
+	database := &DB{DB: sql.OpenDB(&instrumentedConnector{Connector: fakeConnector{}, observer: observer, breaker: breaker}), observer: observer, breaker: breaker}
+	defer database.Close()
+
+	// Not recorded: no metrics yet
+	database.Exec(`UPDATE users SET balance = 0`)
+
+	p := metrics.NewPrometheus()
+	database.SetMetrics(p)
+	database.Exec(`UPDATE users SET balance = 0`)
+	database.Exec(`DELETE FROM missing`)
+	tx, err := database.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	tx.Exec(`INSERT INTO users (balance) VALUES (0)`)
+	tx.Commit()
+
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		`db_queries_total{operation="update_users",status="ok"} 1`,
+		`db_queries_total{operation="delete_missing",status="error"} 1`,
+		`db_queries_total{operation="insert_users",status="ok"} 1`,
+		`db_queries_total{operation="begin",status="ok"} 1`,
+		`db_queries_total{operation="commit",status="ok"} 1`,
+		`db_query_duration_seconds_count{operation="update_users"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %q in metrics, got:\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,732 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+)
+
+// Migration is a versioned schema change. Up applies it and Down reverts it;
+// each runs in one database transaction, together with the bookkeeping row
+// in schema_migrations, so a failed migration leaves no trace.
+type Migration struct {
+	Version int
+	Name    string
+	Up      []string
+	Down    []string
+}
+
+// migrations lists every schema change in version order. Add a change as a
+// new migration with the next version; released migrations are never edited.
+var migrations = []Migration{
+	{Version: 1, Name: "baseline", Up: baselineUp, Down: baselineDown},
+	{
+		Version: 2,
+		Name:    "transaction_feed_index",
+		Up: []string{
+			// Keyset pagination of the admin transaction feed
+			`CREATE INDEX IF NOT EXISTS idx_transactions_created_at_id ON transactions(created_at, id)`,
+		},
+		Down: []string{
+			`DROP INDEX IF EXISTS idx_transactions_created_at_id`,
+		},
+	},
+	{
+		Version: 3,
+		Name:    "ledger_constraints",
+		// Refuse nulls and negatives from any writer, not only the service;
+		// core.AsConstraintError describes violations to API clients. Fails
+		// if existing rows violate them, which must be fixed by hand first
+		Up: []string{
+			`ALTER TABLE transactions
+				ALTER COLUMN user_id SET NOT NULL,
+				ALTER COLUMN state SET NOT NULL,
+				ALTER COLUMN amount SET NOT NULL,
+				ALTER COLUMN source_type SET NOT NULL`,
+			`ALTER TABLE transactions ADD CONSTRAINT transactions_state_check CHECK (state IN ('win', 'lose'))`,
+			`ALTER TABLE transactions ADD CONSTRAINT transactions_amount_check CHECK (amount >= 0)`,
+			`ALTER TABLE users ADD CONSTRAINT users_balance_check CHECK (balance >= 0)`,
+		},
+		Down: []string{
+			`ALTER TABLE users DROP CONSTRAINT IF EXISTS users_balance_check`,
+			`ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_amount_check`,
+			`ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_state_check`,
+			`ALTER TABLE transactions
+				ALTER COLUMN user_id DROP NOT NULL,
+				ALTER COLUMN state DROP NOT NULL,
+				ALTER COLUMN amount DROP NOT NULL,
+				ALTER COLUMN source_type DROP NOT NULL`,
+		},
+	},
+	{
+		Version: 4,
+		Name:    "transaction_responses",
+		// The response given when a transaction was processed, replayed to
+		// retries of its transaction ID; null for older transactions
+		Up: []string{
+			`ALTER TABLE transactions ADD COLUMN IF NOT EXISTS response JSONB`,
+		},
+		Down: []string{
+			`ALTER TABLE transactions DROP COLUMN IF EXISTS response`,
+		},
+	},
+	{
+		Version: 5,
+		Name:    "transaction_hash_chain",
+		// Each applied transaction stores the hash of the account's previous
+		// one and its own, chaining them per account (see
+		// core.VerifyHashChain); null for older transactions
+		Up: []string{
+			`ALTER TABLE transactions ADD COLUMN IF NOT EXISTS prev_hash TEXT`,
+			`ALTER TABLE transactions ADD COLUMN IF NOT EXISTS hash TEXT`,
+			// Chain order: the latest transaction of an account
+			`CREATE INDEX IF NOT EXISTS idx_transactions_user_id_id ON transactions(user_id, id)`,
+		},
+		Down: []string{
+			`DROP INDEX IF EXISTS idx_transactions_user_id_id`,
+			`ALTER TABLE transactions DROP COLUMN IF EXISTS hash`,
+			`ALTER TABLE transactions DROP COLUMN IF EXISTS prev_hash`,
+		},
+	},
+	{
+		Version: 6,
+		Name:    "aml_flags",
+		// Transactions matching an AML rule, queued for review (see
+		// core.SetAMLRules)
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS aml_flags (
+				id BIGSERIAL PRIMARY KEY,
+				user_id BIGINT NOT NULL REFERENCES users(id),
+				account_id BIGINT NOT NULL REFERENCES users(id),
+				transaction_id TEXT NOT NULL,
+				rule TEXT NOT NULL,
+				detail TEXT NOT NULL,
+				status TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'resolved')),
+				created_at TIMESTAMP NOT NULL,
+				resolution TEXT,
+				resolved_at TIMESTAMP
+			)`,
+			// Keyset pagination of the review queue
+			`CREATE INDEX IF NOT EXISTS idx_aml_flags_status_created_at_id ON aml_flags(status, created_at, id)`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS aml_flags`,
+		},
+	},
+	{
+		Version: 7,
+		Name:    "user_freeze",
+		// Freeze mode of users under investigation (see core.FreezeUser);
+		// null when not frozen
+		Up: []string{
+			`ALTER TABLE users ADD COLUMN IF NOT EXISTS frozen TEXT CHECK (frozen IN ('debits', 'all'))`,
+			`ALTER TABLE users ADD COLUMN IF NOT EXISTS frozen_reason TEXT`,
+			`ALTER TABLE users ADD COLUMN IF NOT EXISTS frozen_at TIMESTAMP`,
+		},
+		Down: []string{
+			`ALTER TABLE users DROP COLUMN IF EXISTS frozen_at`,
+			`ALTER TABLE users DROP COLUMN IF EXISTS frozen_reason`,
+			`ALTER TABLE users DROP COLUMN IF EXISTS frozen`,
+		},
+	},
+	{
+		Version: 8,
+		Name:    "disputes",
+		// Disputes against applied transactions, with the ledger entries
+		// applied for them (see core.OpenDispute)
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS disputes (
+				id BIGSERIAL PRIMARY KEY,
+				user_id BIGINT NOT NULL REFERENCES users(id),
+				transaction_id TEXT NOT NULL REFERENCES transactions(transaction_id),
+				amount NUMERIC(10,2) NOT NULL CHECK (amount >= 0),
+				reason TEXT,
+				held BOOLEAN NOT NULL,
+				status TEXT NOT NULL CHECK (status IN ('open', 'resolved_for_player', 'resolved_against')),
+				hold_transaction_id TEXT,
+				resolution_transaction_id TEXT,
+				created_at TIMESTAMP NOT NULL,
+				resolved_at TIMESTAMP
+			)`,
+			// At most one open dispute per transaction
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_disputes_open_transaction_id ON disputes(transaction_id) WHERE status = 'open'`,
+			// Keyset pagination of disputes by status
+			`CREATE INDEX IF NOT EXISTS idx_disputes_status_created_at_id ON disputes(status, created_at, id)`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS disputes`,
+		},
+	},
+	{
+		Version: 9,
+		Name:    "deposit_chargebacks",
+		// Chargebacks reverse deposits even when the balance cannot cover
+		// them, so users flagged by one may hold a negative balance (see
+		// core.ChargeBackDeposit). Reverting fails while any balance is
+		// negative
+		Up: []string{
+			`ALTER TABLE deposits ADD COLUMN IF NOT EXISTS chargeback_reference TEXT UNIQUE`,
+			`ALTER TABLE deposits ADD COLUMN IF NOT EXISTS chargeback_amount NUMERIC(10,2)`,
+			`ALTER TABLE deposits ADD COLUMN IF NOT EXISTS charged_back_at TIMESTAMP`,
+			`ALTER TABLE users ADD COLUMN IF NOT EXISTS chargeback_at TIMESTAMP`,
+			`ALTER TABLE users DROP CONSTRAINT IF EXISTS users_balance_check`,
+			`ALTER TABLE users ADD CONSTRAINT users_balance_check CHECK (balance >= 0 OR chargeback_at IS NOT NULL)`,
+		},
+		Down: []string{
+			`ALTER TABLE users DROP CONSTRAINT IF EXISTS users_balance_check`,
+			`ALTER TABLE users ADD CONSTRAINT users_balance_check CHECK (balance >= 0)`,
+			`ALTER TABLE users DROP COLUMN IF EXISTS chargeback_at`,
+			`ALTER TABLE deposits DROP COLUMN IF EXISTS charged_back_at`,
+			`ALTER TABLE deposits DROP COLUMN IF EXISTS chargeback_amount`,
+			`ALTER TABLE deposits DROP COLUMN IF EXISTS chargeback_reference`,
+		},
+	},
+	{
+		Version: 10,
+		Name:    "currency_minor_units",
+		// Ledger amounts are written with the decimals of their currency (see
+		// package money), 0 for JPY or 3 for KWD, and an unconstrained
+		// NUMERIC keeps the scale written, so amounts read back as written
+		// and hash chains stay valid. Existing amounts keep their 2 decimals.
+		// Reverting rounds amounts to 2 decimals
+		Up: []string{
+			`ALTER TABLE users ALTER COLUMN balance TYPE NUMERIC`,
+			`ALTER TABLE transactions ALTER COLUMN amount TYPE NUMERIC`,
+			`ALTER TABLE transaction_rejections ALTER COLUMN amount TYPE NUMERIC`,
+			`ALTER TABLE balance_snapshots ALTER COLUMN balance TYPE NUMERIC`,
+			`ALTER TABLE balance_events ALTER COLUMN amount TYPE NUMERIC, ALTER COLUMN balance TYPE NUMERIC`,
+			`ALTER TABLE read_balances ALTER COLUMN balance TYPE NUMERIC`,
+			`ALTER TABLE read_balance_history ALTER COLUMN balance TYPE NUMERIC`,
+			`ALTER TABLE ledger_checkpoints ALTER COLUMN balance TYPE NUMERIC`,
+			`ALTER TABLE transfers ALTER COLUMN amount TYPE NUMERIC`,
+			`ALTER TABLE deposits ALTER COLUMN amount TYPE NUMERIC, ALTER COLUMN chargeback_amount TYPE NUMERIC`,
+			`ALTER TABLE disputes ALTER COLUMN amount TYPE NUMERIC`,
+		},
+		Down: []string{
+			`ALTER TABLE disputes ALTER COLUMN amount TYPE NUMERIC(10,2)`,
+			`ALTER TABLE deposits ALTER COLUMN amount TYPE NUMERIC(10,2), ALTER COLUMN chargeback_amount TYPE NUMERIC(10,2)`,
+			`ALTER TABLE transfers ALTER COLUMN amount TYPE NUMERIC(10,2)`,
+			`ALTER TABLE ledger_checkpoints ALTER COLUMN balance TYPE NUMERIC(12,2)`,
+			`ALTER TABLE read_balance_history ALTER COLUMN balance TYPE NUMERIC(10,2)`,
+			`ALTER TABLE read_balances ALTER COLUMN balance TYPE NUMERIC(10,2)`,
+			`ALTER TABLE balance_events ALTER COLUMN amount TYPE NUMERIC(12,2), ALTER COLUMN balance TYPE NUMERIC(10,2)`,
+			`ALTER TABLE balance_snapshots ALTER COLUMN balance TYPE NUMERIC(10,2)`,
+			`ALTER TABLE transaction_rejections ALTER COLUMN amount TYPE NUMERIC(10,2)`,
+			`ALTER TABLE transactions ALTER COLUMN amount TYPE NUMERIC(10,2)`,
+			`ALTER TABLE users ALTER COLUMN balance TYPE NUMERIC(10,2)`,
+		},
+	},
+	{
+		Version: 11,
+		Name:    "personal_data_erasure",
+		// Users whose personal data was erased (see
+		// core.ErasePersonalData); their ledger is kept
+		Up: []string{
+			`ALTER TABLE users ADD COLUMN IF NOT EXISTS anonymized_at TIMESTAMP`,
+		},
+		Down: []string{
+			`ALTER TABLE users DROP COLUMN IF EXISTS anonymized_at`,
+		},
+	},
+	{
+		Version: 12,
+		Name:    "user_totals_net_win",
+		// Net winnings leave out payments and transfers, like tax reports;
+		// totals counted with them are recomputed. Reverting counts them
+		// again
+		Up:   recomputeUserTotals(`source_type <> 'payment' AND transfer_id IS NULL`),
+		Down: recomputeUserTotals(`TRUE`),
+	},
+	{
+		Version: 13,
+		Name:    "currency_totals",
+		// Settlement totals are kept per currency, and the remaining amount
+		// columns keep the decimals of their currency like those of
+		// migration 10. Reverting drops the totals of currencies other than
+		// EUR and rounds amounts to 2 decimals
+		Up: []string{
+			`ALTER TABLE settlement_reports ADD COLUMN IF NOT EXISTS currency TEXT NOT NULL DEFAULT 'EUR'`,
+			`ALTER TABLE settlement_reports DROP CONSTRAINT settlement_reports_pkey`,
+			`ALTER TABLE settlement_reports ADD PRIMARY KEY (report_date, currency, source_type)`,
+			`ALTER TABLE settlement_reports ALTER COLUMN credits TYPE NUMERIC, ALTER COLUMN debits TYPE NUMERIC, ALTER COLUMN net TYPE NUMERIC`,
+			`ALTER TABLE user_totals ALTER COLUMN net_win TYPE NUMERIC`,
+			`ALTER TABLE users ALTER COLUMN max_balance TYPE NUMERIC`,
+			`ALTER TABLE notification_preferences ALTER COLUMN balance_below TYPE NUMERIC, ALTER COLUMN large_transaction TYPE NUMERIC`,
+			`ALTER TABLE notification_outbox ALTER COLUMN amount TYPE NUMERIC, ALTER COLUMN balance TYPE NUMERIC, ALTER COLUMN threshold TYPE NUMERIC`,
+		},
+		Down: []string{
+			`ALTER TABLE notification_outbox ALTER COLUMN amount TYPE NUMERIC(10,2), ALTER COLUMN balance TYPE NUMERIC(10,2), ALTER COLUMN threshold TYPE NUMERIC(10,2)`,
+			`ALTER TABLE notification_preferences ALTER COLUMN balance_below TYPE NUMERIC(10,2), ALTER COLUMN large_transaction TYPE NUMERIC(10,2)`,
+			`ALTER TABLE users ALTER COLUMN max_balance TYPE NUMERIC(10,2)`,
+			`ALTER TABLE user_totals ALTER COLUMN net_win TYPE NUMERIC(14,2)`,
+			`ALTER TABLE settlement_reports ALTER COLUMN credits TYPE NUMERIC(14,2), ALTER COLUMN debits TYPE NUMERIC(14,2), ALTER COLUMN net TYPE NUMERIC(14,2)`,
+			`DELETE FROM settlement_reports WHERE currency <> 'EUR'`,
+			`ALTER TABLE settlement_reports DROP CONSTRAINT settlement_reports_pkey`,
+			`ALTER TABLE settlement_reports ADD PRIMARY KEY (report_date, source_type)`,
+			`ALTER TABLE settlement_reports DROP COLUMN IF EXISTS currency`,
+		},
+	},
+}
+
+// recomputeUserTotals returns the statements setting the net winnings of
+// every user from the applied transactions matching cond.
+func recomputeUserTotals(cond string) []string {
+	return []string{
+		`UPDATE user_totals SET net_win = 0`,
+		`INSERT INTO user_totals (user_id, net_win)
+		 SELECT user_id, SUM(CASE WHEN state = 'win' THEN amount ELSE -amount END)
+		 FROM transactions
+		 WHERE applied AND ` + cond + `
+		 GROUP BY user_id
+		 ON CONFLICT (user_id) DO UPDATE SET net_win = EXCLUDED.net_win`,
+	}
+}
+
+// baselineUp is the schema as of the introduction of versioned migrations.
+// Its statements are idempotent, so databases created before then adopt it
+// as version 1 unchanged.
+var baselineUp = []string{
+	`CREATE TABLE IF NOT EXISTS users (
+		id BIGSERIAL PRIMARY KEY,
+		balance NUMERIC(10,2) NOT NULL DEFAULT 0,
+		created_at TIMESTAMP DEFAULT NOW(),
+		updated_at TIMESTAMP DEFAULT NOW()
+	)`,
+	`CREATE TABLE IF NOT EXISTS transactions (
+		id BIGSERIAL PRIMARY KEY,
+		user_id BIGINT REFERENCES users(id),
+		transaction_id TEXT UNIQUE,
+		state TEXT,
+		amount NUMERIC(10,2),
+		source_type TEXT,
+		applied BOOLEAN,
+		created_at TIMESTAMP DEFAULT NOW()
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_transactions_user_id ON transactions(user_id)`,
+	`CREATE INDEX IF NOT EXISTS idx_transactions_transaction_id ON transactions(transaction_id)`,
+	`CREATE TABLE IF NOT EXISTS settlement_reports (
+		report_date DATE NOT NULL,
+		source_type TEXT NOT NULL,
+		credits NUMERIC(14,2) NOT NULL,
+		credit_count BIGINT NOT NULL,
+		debits NUMERIC(14,2) NOT NULL,
+		debit_count BIGINT NOT NULL,
+		net NUMERIC(14,2) NOT NULL,
+		created_at TIMESTAMP DEFAULT NOW(),
+		PRIMARY KEY (report_date, source_type)
+	)`,
+	`CREATE TABLE IF NOT EXISTS balance_snapshots (
+		id BIGSERIAL PRIMARY KEY,
+		user_id BIGINT NOT NULL REFERENCES users(id),
+		balance NUMERIC(10,2) NOT NULL,
+		transaction_id TEXT,
+		recorded_at TIMESTAMP NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_balance_snapshots_user_recorded ON balance_snapshots(user_id, recorded_at)`,
+	// Keyset pagination of history pages
+	`CREATE INDEX IF NOT EXISTS idx_balance_snapshots_user_recorded_id ON balance_snapshots(user_id, recorded_at, id)`,
+	`CREATE TABLE IF NOT EXISTS transaction_rejections (
+		id BIGSERIAL PRIMARY KEY,
+		user_id BIGINT NOT NULL REFERENCES users(id),
+		transaction_id TEXT,
+		state TEXT,
+		amount NUMERIC(10,2),
+		source_type TEXT,
+		reason TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_transaction_rejections_created_at ON transaction_rejections(created_at)`,
+	`CREATE INDEX IF NOT EXISTS idx_transactions_created_at ON transactions(created_at)`,
+	`CREATE INDEX IF NOT EXISTS idx_users_balance ON users(balance DESC, id)`,
+	`CREATE TABLE IF NOT EXISTS user_totals (
+		user_id BIGINT PRIMARY KEY REFERENCES users(id),
+		net_win NUMERIC(14,2) NOT NULL DEFAULT 0
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_user_totals_net_win ON user_totals(net_win DESC, user_id)`,
+	// Backfill totals once for ledgers that predate the user_totals table
+	`INSERT INTO user_totals (user_id, net_win)
+	 SELECT user_id, SUM(CASE WHEN state = 'win' THEN amount ELSE -amount END)
+	 FROM transactions
+	 WHERE applied AND NOT EXISTS (SELECT 1 FROM user_totals)
+	 GROUP BY user_id`,
+	`CREATE TABLE IF NOT EXISTS balance_events (
+		id BIGSERIAL PRIMARY KEY,
+		user_id BIGINT NOT NULL REFERENCES users(id),
+		version BIGINT NOT NULL,
+		type TEXT NOT NULL,
+		amount NUMERIC(12,2) NOT NULL,
+		balance NUMERIC(10,2) NOT NULL,
+		transaction_id TEXT,
+		recorded_at TIMESTAMP NOT NULL,
+		UNIQUE (user_id, version)
+	)`,
+	// The event stream is append-only
+	`CREATE OR REPLACE FUNCTION reject_balance_event_change() RETURNS trigger AS $$
+	BEGIN
+		RAISE EXCEPTION 'balance_events is append-only';
+	END;
+	$$ LANGUAGE plpgsql`,
+	`DROP TRIGGER IF EXISTS balance_events_append_only ON balance_events`,
+	`CREATE TRIGGER balance_events_append_only BEFORE UPDATE OR DELETE ON balance_events
+		FOR EACH ROW EXECUTE FUNCTION reject_balance_event_change()`,
+	`CREATE TABLE IF NOT EXISTS read_model_outbox (
+		event_id BIGINT PRIMARY KEY REFERENCES balance_events(id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS read_balances (
+		user_id BIGINT PRIMARY KEY,
+		balance NUMERIC(10,2) NOT NULL,
+		version BIGINT NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS read_balance_history (
+		user_id BIGINT NOT NULL,
+		version BIGINT NOT NULL,
+		balance NUMERIC(10,2) NOT NULL,
+		transaction_id TEXT,
+		recorded_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (user_id, version)
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_read_balance_history_user_recorded ON read_balance_history(user_id, recorded_at)`,
+	`CREATE INDEX IF NOT EXISTS idx_read_balance_history_user_recorded_version ON read_balance_history(user_id, recorded_at, version)`,
+	`CREATE TABLE IF NOT EXISTS ledger_checkpoints (
+		id BIGSERIAL PRIMARY KEY,
+		user_id BIGINT NOT NULL REFERENCES users(id),
+		balance NUMERIC(12,2) NOT NULL,
+		snapshot_id BIGINT NOT NULL,
+		created_at TIMESTAMP NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_ledger_checkpoints_user_snapshot ON ledger_checkpoints(user_id, snapshot_id)`,
+	`CREATE TABLE IF NOT EXISTS sagas (
+		id TEXT PRIMARY KEY,
+		definition TEXT NOT NULL,
+		payload JSONB NOT NULL,
+		status TEXT NOT NULL,
+		step INT NOT NULL,
+		error TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_sagas_status ON sagas(status)`,
+	`CREATE TABLE IF NOT EXISTS saga_steps (
+		id BIGSERIAL PRIMARY KEY,
+		saga_id TEXT NOT NULL REFERENCES sagas(id),
+		step TEXT NOT NULL,
+		phase TEXT NOT NULL,
+		error TEXT NOT NULL DEFAULT '',
+		recorded_at TIMESTAMP NOT NULL
+	)`,
+	// ID of the database transaction that inserted each row, which orders
+	// the ledger stream by commit visibility; rows predating it sort first
+	`ALTER TABLE transactions ADD COLUMN IF NOT EXISTS txid BIGINT NOT NULL DEFAULT 0`,
+	`ALTER TABLE transactions ALTER COLUMN txid SET DEFAULT txid_current()`,
+	`CREATE INDEX IF NOT EXISTS idx_transactions_txid_id ON transactions(txid, id)`,
+	`CREATE TABLE IF NOT EXISTS consumer_offsets (
+		consumer TEXT NOT NULL,
+		topic TEXT NOT NULL,
+		partition INT NOT NULL,
+		committed_offset BIGINT NOT NULL,
+		updated_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (consumer, topic, partition)
+	)`,
+	`CREATE TABLE IF NOT EXISTS transfers (
+		transfer_id TEXT PRIMARY KEY,
+		from_user_id BIGINT NOT NULL REFERENCES users(id),
+		to_user_id BIGINT NOT NULL REFERENCES users(id),
+		amount NUMERIC(10,2) NOT NULL,
+		source_type TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL
+	)`,
+	// Links the debit and credit legs of a transfer
+	`ALTER TABLE transactions ADD COLUMN IF NOT EXISTS transfer_id TEXT REFERENCES transfers(transfer_id)`,
+	`CREATE INDEX IF NOT EXISTS idx_transactions_transfer_id ON transactions(transfer_id)`,
+	// System-owned escrow accounts are users rows of kind 'escrow'
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS kind TEXT NOT NULL DEFAULT 'user'`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS escrow_name TEXT`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_escrow_name ON users(escrow_name)`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS currency TEXT NOT NULL DEFAULT 'EUR'`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS external_ref TEXT`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS display_name TEXT`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS country TEXT`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_external_ref ON users(external_ref)`,
+	// Lifecycle status: active, suspended or closed
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'active'`,
+	`CREATE TABLE IF NOT EXISTS user_status_changes (
+		id BIGSERIAL PRIMARY KEY,
+		user_id BIGINT NOT NULL REFERENCES users(id),
+		from_status TEXT NOT NULL,
+		to_status TEXT NOT NULL,
+		reason TEXT NOT NULL DEFAULT '',
+		changed_at TIMESTAMP NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_user_status_changes_user_id ON user_status_changes(user_id, changed_at)`,
+	// Deleted users are hidden but keep their ledger
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP`,
+	// Seeded and pre-existing users are fully verified; registration
+	// sets the level explicitly
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS kyc_level TEXT NOT NULL DEFAULT 'full'`,
+	`CREATE TABLE IF NOT EXISTS user_tags (
+		user_id BIGINT NOT NULL REFERENCES users(id),
+		tag TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (user_id, tag)
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_user_tags_tag ON user_tags(tag, user_id)`,
+	// Per-user maximum balance overriding the global one
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS max_balance NUMERIC(10,2)`,
+	// Wallets are users rows of kind 'wallet' owned by a user
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS owner_id BIGINT REFERENCES users(id)`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS wallet_name TEXT`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_owner_wallet ON users(owner_id, wallet_name)`,
+	`ALTER TABLE transfers ADD COLUMN IF NOT EXISTS internal BOOLEAN NOT NULL DEFAULT FALSE`,
+	// Alerts a user opted into, and alerts waiting to be delivered
+	`CREATE TABLE IF NOT EXISTS notification_preferences (
+		user_id BIGINT PRIMARY KEY REFERENCES users(id),
+		balance_below NUMERIC(10,2),
+		large_transaction NUMERIC(10,2),
+		channels TEXT[] NOT NULL DEFAULT '{}',
+		updated_at TIMESTAMP NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS notification_outbox (
+		id BIGSERIAL PRIMARY KEY,
+		user_id BIGINT NOT NULL REFERENCES users(id),
+		wallet_id TEXT,
+		type TEXT NOT NULL,
+		transaction_id TEXT NOT NULL,
+		amount NUMERIC(10,2) NOT NULL,
+		balance NUMERIC(10,2) NOT NULL,
+		threshold NUMERIC(10,2) NOT NULL,
+		channels TEXT[] NOT NULL,
+		created_at TIMESTAMP NOT NULL
+	)`,
+	// Phone number SMS alerts are sent to
+	`ALTER TABLE notification_preferences ADD COLUMN IF NOT EXISTS phone TEXT`,
+	// Mobile devices push alerts are sent to; a device token belongs to
+	// the user who registered it last
+	`CREATE TABLE IF NOT EXISTS push_devices (
+		platform TEXT NOT NULL,
+		token TEXT NOT NULL,
+		user_id BIGINT NOT NULL REFERENCES users(id),
+		created_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (platform, token)
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_push_devices_user_id ON push_devices(user_id)`,
+	// Balance change notifications have no threshold
+	`ALTER TABLE notification_outbox ALTER COLUMN threshold DROP NOT NULL`,
+	// Read-only API tokens scoped to one user, stored as SHA-256 hashes
+	`CREATE TABLE IF NOT EXISTS api_tokens (
+		id BIGSERIAL PRIMARY KEY,
+		user_id BIGINT NOT NULL REFERENCES users(id),
+		token_hash TEXT NOT NULL UNIQUE,
+		created_at TIMESTAMP NOT NULL,
+		revoked_at TIMESTAMP
+	)`,
+	// Deposits collected through the payment provider
+	`CREATE TABLE IF NOT EXISTS deposits (
+		id TEXT PRIMARY KEY,
+		user_id BIGINT NOT NULL REFERENCES users(id),
+		amount NUMERIC(10,2) NOT NULL,
+		currency TEXT NOT NULL,
+		status TEXT NOT NULL,
+		provider_payment_id TEXT UNIQUE,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	)`,
+	// Webhook deliveries that failed every attempt, kept for replay
+	`CREATE TABLE IF NOT EXISTS webhook_dead_letters (
+		id BIGSERIAL PRIMARY KEY,
+		url TEXT NOT NULL,
+		payload JSON NOT NULL,
+		attempts INTEGER NOT NULL,
+		last_error TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		replayed_at TIMESTAMP
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_webhook_dead_letters_pending ON webhook_dead_letters(created_at, id) WHERE replayed_at IS NULL`,
+}
+
+// baselineDown drops everything baselineUp creates, data included.
+var baselineDown = []string{
+	`DROP TABLE IF EXISTS
+		webhook_dead_letters, deposits, api_tokens, push_devices, notification_outbox,
+		notification_preferences, user_tags, user_status_changes, consumer_offsets,
+		saga_steps, sagas, ledger_checkpoints, read_balance_history, read_balances,
+		read_model_outbox, balance_events, user_totals, transaction_rejections,
+		balance_snapshots, settlement_reports, transactions, transfers, users CASCADE`,
+	`DROP FUNCTION IF EXISTS reject_balance_event_change()`,
+}
+
+// migrationLock serializes migrations of instances starting at once.
+const migrationLock = 7263150
+
+// Migrations returns every known migration in version order.
+func Migrations() []Migration {
+	return migrations
+}
+
+// Migrate applies every pending migration in version order.
+func (db *DB) Migrate() error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	pending, err := db.PendingMigrations()
+	if err != nil {
+		return err
+	}
+	for _, m := range pending {
+		if err := db.runMigration(m, true); err != nil {
+			return err
+		}
+		log.Printf("Applied migration %d %s", m.Version, m.Name)
+	}
+
+	log.Println("Database migrations completed successfully")
+	return nil
+}
+
+// MigrateDown reverts the last n applied migrations, newest first.
+func (db *DB) MigrateDown(n int) error {
+	revert, err := db.LastMigrations(n)
+	if err != nil {
+		return err
+	}
+	for _, m := range revert {
+		if err := db.runMigration(m, false); err != nil {
+			return err
+		}
+		log.Printf("Reverted migration %d %s", m.Version, m.Name)
+	}
+	return nil
+}
+
+// PendingMigrations returns the migrations not applied yet, in version
+// order, without changing the database.
+func (db *DB) PendingMigrations() ([]Migration, error) {
+	applied, err := db.appliedVersions()
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, m := range migrations {
+		if !applied[m.Version] {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// LastMigrations returns the last n applied migrations, newest first, as
+// MigrateDown reverts them, without changing the database.
+func (db *DB) LastMigrations(n int) ([]Migration, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("invalid number of migrations: must be positive")
+	}
+	applied, err := db.appliedVersions()
+	if err != nil {
+		return nil, err
+	}
+	known := map[int]bool{}
+	for _, m := range migrations {
+		known[m.Version] = true
+	}
+	for version := range applied {
+		if !known[version] {
+			return nil, fmt.Errorf("migration %d is applied but unknown to this build", version)
+		}
+	}
+
+	var last []Migration
+	for i := len(migrations) - 1; i >= 0 && len(last) < n; i-- {
+		if applied[migrations[i].Version] {
+			last = append(last, migrations[i])
+		}
+	}
+	if len(last) < n {
+		return nil, fmt.Errorf("cannot revert %d migrations: only %d applied", n, len(last))
+	}
+	return last, nil
+}
+
+// appliedVersions returns the versions recorded in schema_migrations; none
+// if the table doesn't exist yet.
+func (db *DB) appliedVersions() (map[int]bool, error) {
+	applied := map[int]bool{}
+	var exists bool
+	if err := db.QueryRow(`SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to look up schema_migrations: %w", err)
+	}
+	if !exists {
+		return applied, nil
+	}
+
+	rows, err := db.Query(`SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query applied migrations: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("failed to scan applied migration: %w", err)
+		}
+		applied[version] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	return applied, nil
+}
+
+// runMigration applies (up) or reverts m in one transaction. Under the lock
+// it checks the migration wasn't applied or reverted by another instance
+// in the meantime.
+func (db *DB) runMigration(m Migration, up bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Migrations may take long and wait for other instances
+	if _, err := tx.Exec(`SET LOCAL statement_timeout = 0`); err != nil {
+		return fmt.Errorf("failed to disable statement timeout: %w", err)
+	}
+	if _, err := tx.Exec(`SET LOCAL lock_timeout = 0`); err != nil {
+		return fmt.Errorf("failed to disable lock timeout: %w", err)
+	}
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, migrationLock); err != nil {
+		return fmt.Errorf("failed to lock migrations: %w", err)
+	}
+	var applied bool
+	err = tx.QueryRow(`SELECT TRUE FROM schema_migrations WHERE version = $1`, m.Version).Scan(&applied)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to look up migration %d: %w", m.Version, err)
+	}
+	if applied == up {
+		return nil
+	}
+
+	statements := m.Down
+	if up {
+		statements = m.Up
+	}
+	for _, query := range statements {
+		if _, err := tx.Exec(query); err != nil {
+			return fmt.Errorf("failed to execute migration %d %s: %w", m.Version, m.Name, err)
+		}
+	}
+
+	if up {
+		_, err = tx.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, NOW())`, m.Version, m.Name)
+	} else {
+		_, err = tx.Exec(`DELETE FROM schema_migrations WHERE version = $1`, m.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", m.Version, err)
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,19 @@
+package db
+
+import "testing"
+
+func TestMigrationsAreOrdered(t *testing.T) {
+	names := map[string]bool{}
+	for i, m := range Migrations() {
+		if m.Version != i+1 {
+			t.Errorf("Migration %s has version %d, want %d", m.Name, m.Version, i+1)
+		}
+		if m.Name == "" || names[m.Name] {
+			t.Errorf("Migration %d needs a unique name, got %q", m.Version, m.Name)
+		}
+		names[m.Name] = true
+		if len(m.Up) == 0 || len(m.Down) == 0 {
+			t.Errorf("Migration %d %s needs both up and down statements", m.Version, m.Name)
+		}
+	}
+}
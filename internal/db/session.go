@@ -0,0 +1,87 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// SessionConfig holds settings of every database session.
+type SessionConfig struct {
+	// StatementTimeout aborts statements running longer; zero disables it.
+	StatementTimeout time.Duration
+	// LockTimeout aborts statements waiting longer for a lock, e.g. a
+	// FOR UPDATE on a hot user's row, so the request fails fast and can be
+	// retried; zero disables it.
+	LockTimeout time.Duration
+	// Schema, if set, is the only schema on the search path, so a tenant's
+	// tables, migrations included, live in a schema of their own.
+	Schema string
+}
+
+// schemaName matches the schema names SessionConfig accepts: unquoted
+// lower-case Postgres identifiers.
+var schemaName = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,62}$`)
+
+// ValidateSchema checks that name can be used as SessionConfig.Schema.
+func ValidateSchema(name string) error {
+	if !schemaName.MatchString(name) || strings.HasPrefix(name, "pg_") {
+		return fmt.Errorf("invalid schema %q: must be a lower-case identifier of letters, digits and underscores not starting with pg_", name)
+	}
+	return nil
+}
+
+// CreateSchema creates the schema name if it doesn't exist yet. Migrations
+// can only create tables in a schema on the search path that exists.
+func CreateSchema(connectionString, name string) error {
+	if err := ValidateSchema(name); err != nil {
+		return err
+	}
+	conn, err := sql.Open("postgres", connectionString)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer conn.Close()
+
+	// The name is validated; identifiers can't be bound as parameters
+	if _, err := conn.Exec(`CREATE SCHEMA IF NOT EXISTS ` + name); err != nil {
+		return fmt.Errorf("failed to create schema %s: %w", name, err)
+	}
+	return nil
+}
+
+// DSN returns the connection string with the settings added as run-time
+// parameters, which the driver sends with every new connection. URL
+// connection strings are converted to the key=value form.
+func (c SessionConfig) DSN(connectionString string) (string, error) {
+	if strings.HasPrefix(connectionString, "postgres://") || strings.HasPrefix(connectionString, "postgresql://") {
+		converted, err := pq.ParseURL(connectionString)
+		if err != nil {
+			return "", fmt.Errorf("invalid connection URL: %w", err)
+		}
+		connectionString = converted
+	}
+	for _, setting := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"statement_timeout", c.StatementTimeout},
+		{"lock_timeout", c.LockTimeout},
+	} {
+		if setting.value < 0 {
+			return "", fmt.Errorf("invalid %s: must not be negative", setting.name)
+		}
+		connectionString += fmt.Sprintf(" %s=%d", setting.name, setting.value.Milliseconds())
+	}
+	if c.Schema != "" {
+		if err := ValidateSchema(c.Schema); err != nil {
+			return "", err
+		}
+		connectionString += " search_path=" + c.Schema
+	}
+	return strings.TrimSpace(connectionString), nil
+}
@@ -0,0 +1,40 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionConfigDSN(t *testing.T) {
+	cfg := SessionConfig{StatementTimeout: 30 * time.Second, LockTimeout: 1500 * time.Millisecond}
+	tests := []struct {
+		connStr, want string
+	}{
+		{"host=postgres dbname=assignment", "host=postgres dbname=assignment statement_timeout=30000 lock_timeout=1500"},
+		{"postgres://u:p@db:5432/assignment?sslmode=disable", "dbname='assignment' host='db' password='p' port='5432' sslmode='disable' user='u' statement_timeout=30000 lock_timeout=1500"},
+		{"", "statement_timeout=30000 lock_timeout=1500"},
+	}
+	for _, tt := range tests {
+		got, err := cfg.DSN(tt.connStr)
+		if err != nil || got != tt.want {
+			t.Errorf("DSN(%q) = %q, %v, want %q", tt.connStr, got, err, tt.want)
+		}
+	}
+
+	if _, err := (SessionConfig{LockTimeout: -time.Second}).DSN("host=postgres"); err == nil {
+		t.Error("Expected an error for a negative timeout")
+	}
+}
+
+func TestSessionConfigDSN_Schema(t *testing.T) {
+	got, err := SessionConfig{Schema: "tenant_acme"}.DSN("host=postgres")
+	if want := "host=postgres statement_timeout=0 lock_timeout=0 search_path=tenant_acme"; err != nil || got != want {
+		t.Errorf("Expected %q, got: %q, %v", want, got, err)
+	}
+
+	for _, name := range []string{"Tenant", "1tenant", "tenant-acme", "tenant acme", "pg_catalog", "a\"; DROP"} {
+		if _, err := (SessionConfig{Schema: name}).DSN("host=postgres"); err == nil {
+			t.Errorf("Expected schema %q to be refused", name)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+// Package export writes each day's transactions as a Parquet file to object
+// storage, so the data warehouse can ingest them without database access.
+package export
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"assignment/internal/clock"
+	"assignment/internal/core"
+	"assignment/internal/lock"
+	"assignment/internal/money"
+	"assignment/internal/parquet"
+	"assignment/internal/schedule"
+)
+
+// Uploader stores an object under a key, replacing any previous version.
+type Uploader interface {
+	Put(ctx context.Context, key string, body []byte, contentType string) error
+}
+
+var columns = []parquet.Column{
+	{Name: "id", Type: parquet.Int64},
+	{Name: "user_id", Type: parquet.Int64},
+	{Name: "transaction_id", Type: parquet.String},
+	{Name: "state", Type: parquet.String},
+	// Amounts of every currency fit the decimals of the one with the most
+	{Name: "amount", Type: parquet.Decimal, Scale: money.MaxExponent},
+	{Name: "currency", Type: parquet.String},
+	{Name: "source_type", Type: parquet.String},
+	{Name: "applied", Type: parquet.Boolean},
+	{Name: "created_at", Type: parquet.Timestamp},
+}
+
+type Exporter struct {
+	service *core.TransactionService
+	store   Uploader
+	clock   clock.Clock
+	prefix  string
+	at      time.Duration
+	locker  lock.Locker
+}
+
+// NewExporter creates an exporter that runs every day at the given offset
+// from UTC midnight. prefix is prepended to every object key. locker may be
+// nil when a single instance runs the exporter.
+func NewExporter(service *core.TransactionService, store Uploader, clk clock.Clock, prefix string, at time.Duration, locker lock.Locker) *Exporter {
+	return &Exporter{
+		service: service,
+		store:   store,
+		clock:   clk,
+		prefix:  prefix,
+		at:      at,
+		locker:  locker,
+	}
+}
+
+// Run blocks until ctx is cancelled, exporting the previous day at each
+// scheduled time.
+func (e *Exporter) Run(ctx context.Context) {
+	schedule.Daily(ctx, e.clock, e.locker, "export", e.at, func(ctx context.Context, day time.Time) {
+		if _, err := e.ExportDay(ctx, day); err != nil {
+			log.Printf("Export for %s failed: %v", day.Format("2006-01-02"), err)
+		}
+	})
+}
+
+// Key returns the object key for a day, using Hive-style partitioning.
+func (e *Exporter) Key(day time.Time) string {
+	return e.prefix + "transactions/date=" + day.Format("2006-01-02") + "/transactions.parquet"
+}
+
+// ExportDay writes all transactions of the given UTC day and returns the
+// object key. Re-exporting a day overwrites the previous file.
+func (e *Exporter) ExportDay(ctx context.Context, day time.Time) (string, error) {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+
+	transactions, err := e.service.ListTransactionsBetween(start, start.AddDate(0, 0, 1))
+	if err != nil {
+		return "", err
+	}
+
+	rows := make([][]interface{}, len(transactions))
+	for i, t := range transactions {
+		amount, err := parseUnscaled(t.Amount, t.Currency)
+		if err != nil {
+			return "", fmt.Errorf("transaction %s: %w", t.TransactionID, err)
+		}
+		rows[i] = []interface{}{
+			t.ID,
+			t.UserID,
+			t.TransactionID,
+			t.State,
+			amount,
+			t.Currency,
+			t.SourceType,
+			t.Applied,
+			t.CreatedAt,
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := parquet.Write(&buf, columns, rows); err != nil {
+		return "", fmt.Errorf("failed to encode parquet: %w", err)
+	}
+
+	key := e.Key(start)
+	if err := e.store.Put(ctx, key, buf.Bytes(), "application/vnd.apache.parquet"); err != nil {
+		return "", err
+	}
+
+	log.Printf("Exported %d transactions for %s to %s", len(rows), start.Format("2006-01-02"), key)
+	return key, nil
+}
+
+// parseUnscaled converts an amount of currency such as "10.5" into its
+// unscaled value at the scale of the amount column, 10500, without going
+// through floating point. Amounts with more decimals than their currency has
+// are rejected.
+func parseUnscaled(amount, currency string) (int64, error) {
+	whole, frac, _ := strings.Cut(amount, ".")
+	if exp := money.Exponent(currency); len(frac) > exp {
+		return 0, fmt.Errorf("invalid amount: more than %d decimal places", exp)
+	}
+	frac += strings.Repeat("0", money.MaxExponent-len(frac))
+
+	negative := strings.HasPrefix(whole, "-")
+	units, err := strconv.ParseInt(strings.TrimPrefix(whole, "-")+frac, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", amount)
+	}
+	if negative {
+		units = -units
+	}
+	return units, nil
+}
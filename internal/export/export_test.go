@@ -0,0 +1,82 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"assignment/internal/clock"
+	"assignment/internal/core"
+	"assignment/internal/testutil"
+)
+
+type memoryStore map[string][]byte
+
+func (m memoryStore) Put(ctx context.Context, key string, body []byte, contentType string) error {
+	m[key] = body
+	return nil
+}
+
+func TestParseUnscaled(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+		want     int64
+		wantErr  bool
+	}{
+		{"10.50", "EUR", 10500, false},
+		{"10.5", "EUR", 10500, false},
+		{"10", "EUR", 10000, false},
+		{"0.01", "EUR", 10, false},
+		{"-2.25", "EUR", -2250, false},
+		{"1.005", "EUR", 0, true},
+		{"1500", "JPY", 1500000, false},
+		{"1.5", "JPY", 0, true},
+		{"1.005", "KWD", 1005, false},
+		{"abc", "EUR", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.amount+" "+tt.currency, func(t *testing.T) {
+			got, err := parseUnscaled(tt.amount, tt.currency)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseUnscaled() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseUnscaled() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExportDay(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	day := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	service := core.NewTransactionService(db, clock.NewFake(day))
+	req := testutil.NewTransactionRequest("win", "10.50")
+	if _, err := service.ProcessTransaction(1, req, "game"); err != nil {
+		t.Fatalf("Failed to process transaction: %v", err)
+	}
+
+	store := memoryStore{}
+	exporter := NewExporter(service, store, clock.New(), "warehouse/", 0, nil)
+
+	key, err := exporter.ExportDay(context.Background(), day)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if key != "warehouse/transactions/date=2024-01-15/transactions.parquet" {
+		t.Errorf("Unexpected key: %s", key)
+	}
+	file := store[key]
+	if !bytes.HasPrefix(file, []byte("PAR1")) || !bytes.HasSuffix(file, []byte("PAR1")) {
+		t.Fatalf("Exported object is not a parquet file")
+	}
+	if !bytes.Contains(file, []byte("transaction_id")) {
+		t.Errorf("Expected schema to contain transaction_id column")
+	}
+}
@@ -0,0 +1,142 @@
+// Package fixture loads the users, and their historical transactions, the
+// database is seeded with from declarative JSON or YAML files, so demo,
+// staging and load-test environments can each get their own dataset.
+package fixture
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"assignment/internal/utils"
+)
+
+// Fixture is a dataset to seed.
+type Fixture struct {
+	Users []User `json:"users"`
+}
+
+// User is a seeded user. Balance is the opening balance at CreatedAt; the
+// user's transactions are applied on top of it, in order.
+type User struct {
+	ID           int64         `json:"id"`
+	Balance      Amount        `json:"balance"`
+	CreatedAt    time.Time     `json:"createdAt"`
+	Transactions []Transaction `json:"transactions"`
+}
+
+// Transaction is a historical transaction of a seeded user. Its ID makes
+// seeding idempotent: a transaction already applied is ignored.
+type Transaction struct {
+	TransactionID string    `json:"transactionId"`
+	State         string    `json:"state"`
+	Amount        Amount    `json:"amount"`
+	SourceType    string    `json:"sourceType"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// Amount is a decimal amount. Fixtures may give it as a string or, for
+// convenience, as a number; a number keeps its literal, so 100.00 is
+// "100.00".
+type Amount string
+
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*a = Amount(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return errors.New("amount must be a string or a number")
+	}
+	*a = Amount(n.String())
+	return nil
+}
+
+// Default is the dataset seeded without a fixture file.
+func Default() *Fixture {
+	return &Fixture{Users: []User{
+		{ID: 1, Balance: "100.00"},
+		{ID: 2, Balance: "50.00"},
+		{ID: 3, Balance: "0.00"},
+	}}
+}
+
+// Load reads and validates a fixture file: YAML for the .yaml and .yml
+// extensions, JSON otherwise.
+func Load(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		tree, err := parseYAML(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fixture %s: %w", path, err)
+		}
+		if data, err = json.Marshal(tree); err != nil {
+			return nil, fmt.Errorf("invalid fixture %s: %w", path, err)
+		}
+	}
+
+	var f Fixture
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("invalid fixture %s: %w", path, err)
+	}
+	if err := f.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid fixture %s: %w", path, err)
+	}
+	return &f, nil
+}
+
+// Validate checks user IDs and amounts, and that every transaction has a
+// unique ID and a valid state and source type.
+func (f *Fixture) Validate() error {
+	users := map[int64]bool{}
+	transactions := map[string]bool{}
+	for i, u := range f.Users {
+		if u.ID <= 0 {
+			return fmt.Errorf("users[%d]: invalid user ID: must be a positive integer", i)
+		}
+		if users[u.ID] {
+			return fmt.Errorf("users[%d]: duplicate user ID %d", i, u.ID)
+		}
+		users[u.ID] = true
+		if err := utils.ValidateAmount(string(u.Balance)); err != nil {
+			return fmt.Errorf("users[%d]: balance: %w", i, err)
+		}
+
+		for j, t := range u.Transactions {
+			where := fmt.Sprintf("users[%d].transactions[%d]", i, j)
+			if t.TransactionID == "" {
+				return fmt.Errorf("%s: transactionId is required", where)
+			}
+			if transactions[t.TransactionID] {
+				return fmt.Errorf("%s: duplicate transactionId %q", where, t.TransactionID)
+			}
+			transactions[t.TransactionID] = true
+			if err := utils.ValidateState(t.State); err != nil {
+				return fmt.Errorf("%s: %w", where, err)
+			}
+			if err := utils.ValidateAmount(string(t.Amount)); err != nil {
+				return fmt.Errorf("%s: %w", where, err)
+			}
+			if err := utils.ValidateSourceType(t.SourceType); err != nil {
+				return fmt.Errorf("%s: invalid sourceType: must be 'game', 'server', or 'payment'", where)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package fixture
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFixture(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write fixture: %v", err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	want := &Fixture{Users: []User{
+		{ID: 1, Balance: "100.00", CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Transactions: []Transaction{
+			{TransactionID: "seed-1", State: "win", Amount: "10.50", SourceType: "game", CreatedAt: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+			{TransactionID: "seed-2", State: "lose", Amount: "5", SourceType: "payment"},
+		}},
+		{ID: 2, Balance: "0.00"},
+	}}
+
+	yaml := `---
+# Demo dataset
+users:
+  - id: 1
+    balance: 100.00   # opening balance
+    createdAt: 2024-01-01T00:00:00Z
+    transactions:
+    - transactionId: seed-1
+      state: win
+      amount: "10.50"
+      sourceType: 'game'
+      createdAt: "2024-01-02T00:00:00Z"
+    - transactionId: "seed-2"
+      state: lose
+      amount: 5
+      sourceType: payment
+  - id: 2
+    balance: "0.00"
+    transactions: []
+`
+	json := `{"users": [
+  {"id": 1, "balance": 100.00, "createdAt": "2024-01-01T00:00:00Z", "transactions": [
+    {"transactionId": "seed-1", "state": "win", "amount": "10.50", "sourceType": "game", "createdAt": "2024-01-02T00:00:00Z"},
+    {"transactionId": "seed-2", "state": "lose", "amount": 5, "sourceType": "payment"}
+  ]},
+  {"id": 2, "balance": "0.00", "transactions": []}
+]}`
+
+	for name, content := range map[string]string{"demo.yaml": yaml, "demo.json": json} {
+		f, err := Load(writeFixture(t, name, content))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		f.Users[1].Transactions = nil
+		if !reflect.DeepEqual(f, want) {
+			t.Errorf("%s: got %+v, want %+v", name, f, want)
+		}
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name, content, wantErr string
+	}{
+		{"unknown.json", `{"users": [{"id": 1, "balance": "1.00", "kind": "escrow"}]}`, `unknown field "kind"`},
+		{"id.yaml", "users:\n  - id: 0\n    balance: 1.00\n", "users[0]: invalid user ID"},
+		{"dup.yaml", "users:\n  - id: 1\n    balance: 1.00\n  - id: 1\n    balance: 2.00\n", "duplicate user ID 1"},
+		{"balance.yaml", "users:\n  - id: 1\n    balance: -1\n", "users[0]: balance: invalid amount"},
+		{"txid.yaml", "users:\n  - id: 1\n    balance: 1.00\n    transactions:\n      - state: win\n        amount: 1.00\n        sourceType: game\n", "transactionId is required"},
+		{"state.yaml", "users:\n  - id: 1\n    balance: 1.00\n    transactions:\n      - transactionId: t\n        state: draw\n        amount: 1.00\n        sourceType: game\n", "users[0].transactions[0]: invalid state"},
+		{"indent.yaml", "users:\n  - id: 1\n      balance: 1.00\n", "line 3: unexpected indentation"},
+		{"tabs.yaml", "users:\n\t- id: 1\n", "line 2: tabs are not allowed"},
+		{"flow.yaml", "users: [{id: 1}]\n", "flow collections are not supported"},
+		{"quote.yaml", "users:\n  - id: \"1\n", "unterminated string"},
+	}
+	for _, tt := range tests {
+		_, err := Load(writeFixture(t, tt.name, tt.content))
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+func TestLoadDemo(t *testing.T) {
+	f, err := Load("../../fixtures/demo.yaml")
+	if err != nil {
+		t.Fatalf("Failed to load demo fixture: %v", err)
+	}
+	if len(f.Users) != 3 || len(f.Users[0].Transactions) != 3 {
+		t.Errorf("Unexpected demo fixture: %+v", f)
+	}
+}
@@ -0,0 +1,109 @@
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ECBDailyURL publishes the euro reference rates of the last working day.
+const ECBDailyURL = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-daily.xml"
+
+// ECB fetches the euro foreign exchange reference rates of the European
+// Central Bank.
+type ECB struct {
+	url        string
+	httpClient *http.Client
+}
+
+func NewECB() *ECB {
+	return &ECB{url: ECBDailyURL, httpClient: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (e *ECB) Fetch(ctx context.Context) (*Table, error) {
+	var doc struct {
+		Cube struct {
+			Cube struct {
+				Time  string `xml:"time,attr"`
+				Rates []struct {
+					Currency string  `xml:"currency,attr"`
+					Rate     float64 `xml:"rate,attr"`
+				} `xml:"Cube"`
+			} `xml:"Cube"`
+		} `xml:"Cube"`
+	}
+	if err := get(ctx, e.httpClient, e.url, func(resp *http.Response) error {
+		return xml.NewDecoder(resp.Body).Decode(&doc)
+	}); err != nil {
+		return nil, err
+	}
+
+	day := doc.Cube.Cube
+	asOf, err := time.Parse("2006-01-02", day.Time)
+	if err != nil || len(day.Rates) == 0 {
+		return nil, fmt.Errorf("unexpected ECB response")
+	}
+	table := &Table{Base: "EUR", Rates: map[string]float64{}, AsOf: asOf}
+	for _, rate := range day.Rates {
+		table.Rates[rate.Currency] = rate.Rate
+	}
+	return table, nil
+}
+
+// HTTPProvider fetches rates from a JSON API answering
+// {"base": "EUR", "date": "2024-01-15", "rates": {"USD": 1.09, ...}}, the
+// format of Frankfurter and similar services. date is optional.
+type HTTPProvider struct {
+	url        string
+	httpClient *http.Client
+}
+
+func NewHTTPProvider(url string) *HTTPProvider {
+	return &HTTPProvider{url: url, httpClient: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (p *HTTPProvider) Fetch(ctx context.Context) (*Table, error) {
+	var reply struct {
+		Base  string             `json:"base"`
+		Date  string             `json:"date"`
+		Rates map[string]float64 `json:"rates"`
+	}
+	if err := get(ctx, p.httpClient, p.url, func(resp *http.Response) error {
+		return json.NewDecoder(resp.Body).Decode(&reply)
+	}); err != nil {
+		return nil, err
+	}
+	if reply.Base == "" || len(reply.Rates) == 0 {
+		return nil, fmt.Errorf("unexpected exchange rate response")
+	}
+
+	table := &Table{Base: reply.Base, Rates: reply.Rates, AsOf: time.Now().UTC()}
+	if asOf, err := time.Parse("2006-01-02", reply.Date); err == nil {
+		table.AsOf = asOf
+	}
+	return table, nil
+}
+
+// get fetches url and passes a 2xx response to decode.
+func get(ctx context.Context, httpClient *http.Client, url string, decode func(*http.Response) error) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build exchange rate request: %w", err)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch exchange rates: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("exchange rate provider returned status %d", resp.StatusCode)
+	}
+	if err := decode(resp); err != nil {
+		return fmt.Errorf("failed to decode exchange rates: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+// Package fx provides exchange rates between the currencies of users. Rates
+// are fetched from a provider (the ECB by default), cached in process, and
+// replaced by a static fallback table when the provider has been unreachable
+// for too long.
+package fx
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"assignment/internal/clock"
+)
+
+// Sources of a Table.
+const (
+	SourceProvider = "provider"
+	SourceFallback = "fallback"
+)
+
+// retryInterval is how long a failed fetch is not retried, so requests do
+// not pile up on an unavailable provider.
+const retryInterval = time.Minute
+
+// Table holds the rates of currencies against Base: one unit of Base buys
+// Rates[currency] units of currency.
+type Table struct {
+	Base   string
+	Rates  map[string]float64
+	AsOf   time.Time
+	Source string
+}
+
+// Provider fetches the current rates, e.g. from the ECB.
+type Provider interface {
+	Fetch(ctx context.Context) (*Table, error)
+}
+
+// DefaultFallback is the fallback table against EUR, used when the provider
+// has been unreachable for longer than MaxAge. It is only meant to keep
+// conversions approximately right during an outage.
+var DefaultFallback = map[string]float64{
+	"EUR": 1,
+	"USD": 1.08,
+	"GBP": 0.85,
+	"CHF": 0.95,
+	"JPY": 160,
+	"CAD": 1.47,
+	"AUD": 1.63,
+	"SEK": 11.3,
+	"NOK": 11.6,
+	"DKK": 7.46,
+	"PLN": 4.3,
+}
+
+// Config sets how long fetched rates are cached (TTL, default 1h) and how
+// old they may get while the provider is failing before the fallback table,
+// which is against EUR, is used instead (MaxAge, default 72h, as the ECB does
+// not publish on weekends and holidays).
+type Config struct {
+	TTL      time.Duration
+	MaxAge   time.Duration
+	Fallback map[string]float64
+}
+
+type Rates struct {
+	provider Provider
+	clock    clock.Clock
+	cfg      Config
+
+	mu          sync.Mutex
+	current     *Table
+	fetchedAt   time.Time
+	lastAttempt time.Time
+}
+
+func NewRates(provider Provider, clk clock.Clock, cfg Config) *Rates {
+	if cfg.TTL <= 0 {
+		cfg.TTL = time.Hour
+	}
+	if cfg.MaxAge <= 0 {
+		cfg.MaxAge = 72 * time.Hour
+	}
+	if cfg.Fallback == nil {
+		cfg.Fallback = DefaultFallback
+	}
+	return &Rates{provider: provider, clock: clk, cfg: cfg}
+}
+
+// Table returns the current rates: the cached ones, fetching them again once
+// they are older than the TTL. If the provider fails, the cached rates are
+// used until they are older than MaxAge, then the fallback table.
+func (r *Rates) Table(ctx context.Context) *Table {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.clock.Now()
+	if r.current != nil && now.Sub(r.fetchedAt) < r.cfg.TTL {
+		return r.current
+	}
+	if r.lastAttempt.IsZero() || now.Sub(r.lastAttempt) >= retryInterval {
+		r.lastAttempt = now
+		table, err := r.provider.Fetch(ctx)
+		if err == nil {
+			table.Source = SourceProvider
+			table.Rates[table.Base] = 1
+			r.current, r.fetchedAt = table, now
+			return table
+		}
+		log.Printf("Failed to fetch exchange rates: %v", err)
+	}
+
+	if r.current != nil && now.Sub(r.fetchedAt) < r.cfg.MaxAge {
+		return r.current
+	}
+	return &Table{Base: "EUR", Rates: r.cfg.Fallback, AsOf: now, Source: SourceFallback}
+}
+
+// Rate returns the rate converting from one currency to another, and the
+// table it was derived from.
+func (r *Rates) Rate(ctx context.Context, from, to string) (float64, *Table, error) {
+	table := r.Table(ctx)
+	fromRate, ok := table.Rates[from]
+	if !ok || fromRate <= 0 {
+		return 0, nil, fmt.Errorf("invalid currency: no exchange rate for %s", from)
+	}
+	toRate, ok := table.Rates[to]
+	if !ok || toRate <= 0 {
+		return 0, nil, fmt.Errorf("invalid currency: no exchange rate for %s", to)
+	}
+	return toRate / fromRate, table, nil
+}
+
+// ParseFallback parses a fallback table against EUR such as
+// "USD=1.08,GBP=0.85".
+func ParseFallback(s string) (map[string]float64, error) {
+	rates := map[string]float64{"EUR": 1}
+	for _, pair := range strings.Split(s, ",") {
+		currency, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid fallback rate %q: must be CUR=rate", pair)
+		}
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("invalid fallback rate %q: must be a positive number", pair)
+		}
+		rates[currency] = rate
+	}
+	return rates, nil
+}
@@ -0,0 +1,122 @@
+package fx
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"assignment/internal/clock"
+)
+
+type fakeProvider struct {
+	calls int
+	err   error
+}
+
+func (p *fakeProvider) Fetch(ctx context.Context) (*Table, error) {
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &Table{Base: "EUR", Rates: map[string]float64{"USD": 1.1, "GBP": 0.88}}, nil
+}
+
+func TestRatesCaching(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	provider := &fakeProvider{}
+	rates := NewRates(provider, clk, Config{TTL: time.Hour, MaxAge: 24 * time.Hour})
+
+	rate, table, err := rates.Rate(context.Background(), "USD", "GBP")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if math.Abs(rate-0.8) > 1e-9 || table.Source != SourceProvider {
+		t.Errorf("Unexpected rate: %v from %s", rate, table.Source)
+	}
+	rates.Table(context.Background())
+	if provider.calls != 1 {
+		t.Errorf("Expected cached rates, got %d fetches", provider.calls)
+	}
+
+	// Stale rates are served while the provider fails, within MaxAge
+	provider.err = errors.New("unavailable")
+	clk.Advance(2 * time.Hour)
+	if table := rates.Table(context.Background()); table.Source != SourceProvider || provider.calls != 2 {
+		t.Errorf("Expected stale provider rates after a fetch, got: %s, %d fetches", table.Source, provider.calls)
+	}
+	// Failed fetches are not retried immediately
+	rates.Table(context.Background())
+	if provider.calls != 2 {
+		t.Errorf("Expected no retry within the retry interval, got %d fetches", provider.calls)
+	}
+
+	clk.Advance(24 * time.Hour)
+	table = rates.Table(context.Background())
+	if table.Source != SourceFallback || table.Rates["USD"] != DefaultFallback["USD"] {
+		t.Errorf("Expected fallback rates, got: %+v", table)
+	}
+
+	if _, _, err := rates.Rate(context.Background(), "EUR", "XXX"); err == nil {
+		t.Error("Expected error for unknown currency")
+	}
+}
+
+func TestECB(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<gesmes:subject>Reference rates</gesmes:subject>
+	<Cube>
+		<Cube time='2024-01-12'>
+			<Cube currency='USD' rate='1.0942'/>
+			<Cube currency='JPY' rate='159.17'/>
+		</Cube>
+	</Cube>
+</gesmes:Envelope>`))
+	}))
+	defer server.Close()
+
+	ecb := NewECB()
+	ecb.url = server.URL
+	table, err := ecb.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if table.Base != "EUR" || table.Rates["USD"] != 1.0942 || table.Rates["JPY"] != 159.17 || table.AsOf.Format("2006-01-02") != "2024-01-12" {
+		t.Errorf("Unexpected table: %+v", table)
+	}
+}
+
+func TestHTTPProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"base":"USD","date":"2024-01-12","rates":{"EUR":0.914}}`))
+	}))
+	defer server.Close()
+
+	table, err := NewHTTPProvider(server.URL).Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if table.Base != "USD" || table.Rates["EUR"] != 0.914 {
+		t.Errorf("Unexpected table: %+v", table)
+	}
+}
+
+func TestParseFallback(t *testing.T) {
+	rates, err := ParseFallback("USD=1.1, GBP=0.9")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if rates["EUR"] != 1 || rates["USD"] != 1.1 || rates["GBP"] != 0.9 {
+		t.Errorf("Unexpected rates: %v", rates)
+	}
+	for _, s := range []string{"USD", "USD=abc", "USD=-1"} {
+		if _, err := ParseFallback(s); err == nil {
+			t.Errorf("Expected %q to be invalid", s)
+		}
+	}
+}
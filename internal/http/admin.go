@@ -0,0 +1,523 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"assignment/internal/core"
+	"assignment/internal/models"
+	"assignment/internal/utils"
+)
+
+// parseTagFilter reads the optional tag and (repeatable) exclude_tag query
+// parameters of a report.
+func parseTagFilter(r *http.Request) (models.TagFilter, error) {
+	query := r.URL.Query()
+	filter := models.TagFilter{Tag: query.Get("tag"), ExcludeTags: query["exclude_tag"]}
+	if filter.Tag != "" {
+		if err := utils.ValidateTag(filter.Tag); err != nil {
+			return filter, err
+		}
+	}
+	for _, tag := range filter.ExcludeTags {
+		if err := utils.ValidateTag(tag); err != nil {
+			return filter, err
+		}
+	}
+	return filter, nil
+}
+
+func (h *Handlers) HandleReconciliation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// Date is required and interpreted as a UTC calendar day
+	day, err := time.Parse("2006-01-02", r.URL.Query().Get("date"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid date: must be in YYYY-MM-DD format")
+		return
+	}
+
+	filter, err := parseTagFilter(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	report, err := h.transactionService.GetReconciliation(day, filter)
+	if err != nil {
+		log.Printf("Error building reconciliation report: %v", err)
+		respondInternalError(w, err)
+		return
+	}
+
+	respondJSON(w, report)
+}
+
+func (h *Handlers) HandleStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	window := r.URL.Query().Get("window")
+	if window == "" {
+		window = "24h"
+	}
+	length, err := utils.ParseWindow(window)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter, err := parseTagFilter(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	stats, err := h.transactionService.GetStats(window, length, filter)
+	if err != nil {
+		log.Printf("Error computing stats: %v", err)
+		respondInternalError(w, err)
+		return
+	}
+
+	respondJSON(w, stats)
+}
+
+func (h *Handlers) HandleTopReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	metric := r.URL.Query().Get("metric")
+	if err := utils.ValidateTopMetric(metric); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	currency := r.URL.Query().Get("currency")
+	if currency == "" {
+		currency = core.DefaultCurrency
+	}
+	if err := utils.ValidateCurrency(currency); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit, err := utils.ParseLimit(r.URL.Query().Get("limit"), 10)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter, err := parseTagFilter(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	report, err := h.transactionService.GetTopUsers(metric, currency, limit, filter)
+	if err != nil {
+		log.Printf("Error building top report: %v", err)
+		respondInternalError(w, err)
+		return
+	}
+
+	respondJSON(w, report)
+}
+
+func (h *Handlers) HandleRebuildProjections(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	repaired, err := h.transactionService.RebuildProjections()
+	if err != nil {
+		log.Printf("Error rebuilding balance projections: %v", err)
+		respondInternalError(w, err)
+		return
+	}
+
+	if len(repaired) > 0 {
+		log.Printf("Rebuilt balance projections for users %v", repaired)
+	}
+	respondJSON(w, models.ProjectionRebuild{RepairedUsers: repaired})
+}
+
+// maxImportBytes bounds the size of an uploaded import file.
+const maxImportBytes = 10 << 20
+
+func (h *Handlers) HandleImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	report, err := h.transactionService.ImportCSV(r.Context(), http.MaxBytesReader(w, r.Body, maxImportBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			respondError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("import file too large: the limit is %d bytes", tooLarge.Limit))
+		case errors.Is(err, core.ErrValidation):
+			respondError(w, http.StatusBadRequest, err.Error())
+		default:
+			respondError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		}
+		return
+	}
+
+	log.Printf("Import processed: %d rows, %v", report.Total, report.Counts)
+	respondJSON(w, report)
+}
+
+func extractAdminUserID(path string) string {
+	// Path format: /admin/users/{userId}/status, /admin/users/{userId}/kyc,
+	// /admin/users/{userId}/max-balance, /admin/users/{userId}/freeze,
+	// /admin/users/{userId}/unfreeze, /admin/users/{userId}/hash-chain,
+	// /admin/users/{userId}/audit-trail, /admin/users/{userId}/personal-data,
+	// /admin/users/{userId}/data-export or
+	// /admin/users/{userId}/tags/{tag}
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) >= 3 && parts[0] == "admin" && parts[1] == "users" {
+		return parts[2]
+	}
+	return ""
+}
+
+func (h *Handlers) HandleSetUserStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, err := utils.ValidateUserID(extractAdminUserID(r.URL.Path))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req models.UserStatusRequest
+	if err := decodeBody(r, "user_status", &req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	user, err := h.transactionService.SetUserStatus(userID, req)
+	if err != nil {
+		respondUserError(w, err)
+		return
+	}
+
+	respondJSON(w, user)
+}
+
+func (h *Handlers) HandleSetKYCLevel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, err := utils.ValidateUserID(extractAdminUserID(r.URL.Path))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req models.KYCLevelRequest
+	if err := decodeBody(r, "kyc_level", &req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	user, err := h.transactionService.SetKYCLevel(userID, req)
+	if err != nil {
+		respondUserError(w, err)
+		return
+	}
+
+	respondJSON(w, user)
+}
+
+func (h *Handlers) HandleSetMaxBalance(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, err := utils.ValidateUserID(extractAdminUserID(r.URL.Path))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req models.MaxBalanceRequest
+	if err := decodeBody(r, "max_balance", &req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	user, err := h.transactionService.SetMaxBalance(userID, req)
+	if err != nil {
+		respondUserError(w, err)
+		return
+	}
+
+	respondJSON(w, user)
+}
+
+// HandleFreezeUser freezes a user under investigation: its debits, and its
+// credits too if the body asks for it, are rejected until it is unfrozen.
+func (h *Handlers) HandleFreezeUser(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, err := utils.ValidateUserID(extractAdminUserID(r.URL.Path))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req models.FreezeRequest
+	if err := decodeBody(r, "freeze", &req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	user, err := h.transactionService.FreezeUser(userID, req)
+	if err != nil {
+		respondUserError(w, err)
+		return
+	}
+
+	respondJSON(w, user)
+}
+
+func (h *Handlers) HandleUnfreezeUser(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, err := utils.ValidateUserID(extractAdminUserID(r.URL.Path))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, err := h.transactionService.UnfreezeUser(userID)
+	if err != nil {
+		respondUserError(w, err)
+		return
+	}
+
+	respondJSON(w, user)
+}
+
+// HandleVerifyHashChain verifies the hash chain of a user's transactions,
+// for auditors to detect rows edited or removed outside the service.
+func (h *Handlers) HandleVerifyHashChain(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, err := utils.ValidateUserID(extractAdminUserID(r.URL.Path))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	report, err := h.transactionService.VerifyHashChain(userID)
+	if err != nil {
+		respondUserError(w, err)
+		return
+	}
+
+	if !report.Valid {
+		log.Printf("Hash chain of user %d broken at transaction %d: %s", userID, report.Break.ID, report.Break.Reason)
+	}
+	respondJSON(w, report)
+}
+
+// HandleErasePersonalData anonymizes a user on a GDPR erasure request,
+// keeping its ledger.
+func (h *Handlers) HandleErasePersonalData(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, err := utils.ValidateUserID(extractAdminUserID(r.URL.Path))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, err := h.transactionService.ErasePersonalData(userID)
+	if err != nil {
+		respondUserError(w, err)
+		return
+	}
+
+	respondJSON(w, user)
+}
+
+// HandleExportPersonalData serves everything stored about a user as a JSON
+// download, on a GDPR data portability request.
+func (h *Handlers) HandleExportPersonalData(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, err := utils.ValidateUserID(extractAdminUserID(r.URL.Path))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	export, err := h.transactionService.ExportPersonalData(userID)
+	if err != nil {
+		respondUserError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="user-%d-data.json"`, userID))
+	respondJSON(w, export)
+}
+
+func (h *Handlers) HandleGetUserTags(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, err := utils.ValidateUserID(extractAdminUserID(r.URL.Path))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tags, err := h.transactionService.GetUserTags(userID)
+	if err != nil {
+		respondUserError(w, err)
+		return
+	}
+
+	respondJSON(w, tags)
+}
+
+func (h *Handlers) HandleAddUserTags(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, err := utils.ValidateUserID(extractAdminUserID(r.URL.Path))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req models.AddUserTagsRequest
+	if err := decodeBody(r, "add_user_tags", &req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	tags, err := h.transactionService.AddUserTags(userID, req.Tags)
+	if err != nil {
+		respondUserError(w, err)
+		return
+	}
+
+	respondJSON(w, tags)
+}
+
+func (h *Handlers) HandleRemoveUserTag(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, err := utils.ValidateUserID(extractAdminUserID(r.URL.Path))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Path format: /admin/users/{userId}/tags/{tag}
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	tag := parts[len(parts)-1]
+
+	tags, err := h.transactionService.RemoveUserTag(userID, tag)
+	if err != nil {
+		respondUserError(w, err)
+		return
+	}
+
+	respondJSON(w, tags)
+}
+
+// parseTransactionFilter reads the optional source, state and user query
+// parameters of the transaction feed.
+func parseTransactionFilter(r *http.Request) (core.TransactionFilter, error) {
+	query := r.URL.Query()
+	filter := core.TransactionFilter{SourceType: query.Get("source"), State: query.Get("state")}
+	if filter.SourceType != "" {
+		if err := utils.ValidateSourceType(filter.SourceType); err != nil {
+			return filter, errors.New("invalid source: must be 'game', 'server', or 'payment'")
+		}
+	}
+	if filter.State != "" {
+		if err := utils.ValidateState(filter.State); err != nil {
+			return filter, err
+		}
+	}
+	if user := query.Get("user"); user != "" {
+		userID, err := utils.ValidateUserID(user)
+		if err != nil {
+			return filter, err
+		}
+		filter.UserID = userID
+	}
+	return filter, nil
+}
+
+func (h *Handlers) HandleGetTransactionFeed(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	filter, err := parseTransactionFilter(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	page, err := parsePage(r, 50, utils.MaxReportLimit)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	transactions, err := h.transactionService.GetTransactionFeed(filter, page)
+	if err != nil {
+		log.Printf("Error listing transactions: %v", err)
+		respondInternalError(w, err)
+		return
+	}
+
+	respondJSON(w, transactions)
+}
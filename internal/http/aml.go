@@ -0,0 +1,83 @@
+package http
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"assignment/internal/core"
+	"assignment/internal/models"
+	"assignment/internal/utils"
+)
+
+func (h *Handlers) HandleGetAMLFlags(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	page, err := parsePage(r, 50, utils.MaxReportLimit)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = core.AMLFlagOpen
+	}
+
+	flags, err := h.transactionService.GetAMLFlagsPage(status, page)
+	if err != nil {
+		if errors.Is(err, core.ErrValidation) {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Printf("Error listing AML flags: %v", err)
+		respondInternalError(w, err)
+		return
+	}
+
+	respondJSON(w, flags)
+}
+
+func (h *Handlers) HandleResolveAMLFlag(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// Path format: /admin/aml/flags/{id}/resolve
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	id, err := strconv.ParseInt(parts[len(parts)-2], 10, 64)
+	if err != nil || id <= 0 {
+		respondError(w, http.StatusBadRequest, "invalid AML flag ID: must be a positive integer")
+		return
+	}
+
+	var req models.ResolveAMLFlagRequest
+	if err := decodeBody(r, "resolve_aml_flag", &req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	flag, err := h.transactionService.ResolveAMLFlag(id, req)
+	if err != nil {
+		errMsg := err.Error()
+		switch {
+		case errors.Is(err, core.ErrValidation):
+			respondError(w, http.StatusBadRequest, errMsg)
+		case errors.Is(err, core.ErrAMLFlagNotFound):
+			respondError(w, http.StatusNotFound, errMsg)
+		case errors.Is(err, core.ErrAMLFlagResolved):
+			respondError(w, http.StatusConflict, errMsg)
+		default:
+			log.Printf("Error resolving AML flag: %v", err)
+			respondInternalError(w, err)
+		}
+		return
+	}
+
+	respondJSON(w, flag)
+}
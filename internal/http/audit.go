@@ -0,0 +1,98 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"assignment/internal/audit"
+	"assignment/internal/requestid"
+	"assignment/internal/utils"
+)
+
+// maxAuditedBody bounds the admin request body kept with an audit event.
+const maxAuditedBody = 1024
+
+// SecurityAuditor records security-relevant requests, e.g. an audit.Logger.
+type SecurityAuditor interface {
+	Record(event audit.Event)
+}
+
+type securityAudit struct {
+	auditor SecurityAuditor
+	next    http.Handler
+}
+
+// NewAuditMiddleware wraps next with a middleware recording authentication
+// failures (401), denied access (403), rate-limited requests (429) and admin
+// changes (any /admin request but a GET) to auditor.
+func NewAuditMiddleware(auditor SecurityAuditor, next http.Handler) http.Handler {
+	return &securityAudit{auditor: auditor, next: next}
+}
+
+func (a *securityAudit) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	adminAction := r.Method != http.MethodGet && strings.HasPrefix(r.URL.Path, "/admin/")
+	var body []byte
+	if adminAction && r.Body != nil {
+		// Keep the start of the body and put it back for the handler
+		body, _ = io.ReadAll(io.LimitReader(r.Body, maxAuditedBody+1))
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+	}
+
+	rec := &statusRecorder{ResponseWriter: w}
+	a.next.ServeHTTP(rec, r)
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+
+	var eventType string
+	switch {
+	case rec.status == http.StatusUnauthorized:
+		eventType = audit.AuthFailure
+	case rec.status == http.StatusForbidden:
+		eventType = audit.AccessDenied
+	case rec.status == http.StatusTooManyRequests:
+		eventType = audit.RateLimited
+	case adminAction:
+		eventType = audit.AdminAction
+	default:
+		return
+	}
+
+	event := audit.Event{
+		Type:         eventType,
+		Outcome:      "success",
+		Method:       r.Method,
+		Path:         r.URL.Path,
+		Route:        normalizeRoute(r.URL.Path),
+		Status:       rec.status,
+		SourceIP:     r.RemoteAddr,
+		ForwardedFor: r.Header.Get("X-Forwarded-For"),
+		UserAgent:    r.UserAgent(),
+		RequestID:    r.Header.Get(requestid.Header),
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		event.SourceIP = host
+	}
+	if rec.status >= 400 {
+		event.Outcome = "failure"
+		event.Reason = rec.message()
+	}
+	userID := extractUserID(r.URL.Path)
+	if userID == "" {
+		userID = extractAdminUserID(r.URL.Path)
+	}
+	if id, err := utils.ValidateUserID(userID); err == nil {
+		event.UserID = id
+	}
+	if eventType == audit.AdminAction && len(body) <= maxAuditedBody && json.Valid(body) {
+		event.Request = body
+	}
+	a.auditor.Record(event)
+}
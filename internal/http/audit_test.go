@@ -0,0 +1,64 @@
+package http
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"assignment/internal/audit"
+)
+
+type fakeAuditor struct {
+	events []audit.Event
+}
+
+func (f *fakeAuditor) Record(event audit.Event) {
+	f.events = append(f.events, event)
+}
+
+func TestAuditMiddleware(t *testing.T) {
+	auditor := &fakeAuditor{}
+	var handlerBody string
+	m := NewAuditMiddleware(auditor, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		handlerBody = string(body)
+		switch r.URL.Path {
+		case "/user/1/balance":
+			respondError(w, http.StatusUnauthorized, "invalid token")
+		case "/user/2/transaction":
+			respondError(w, http.StatusForbidden, "token does not grant access to this resource")
+		default:
+			respondJSON(w, map[string]string{"status": "ok"})
+		}
+	}))
+
+	requests := []*http.Request{
+		httptest.NewRequest("GET", "/user/1/balance", nil),
+		httptest.NewRequest("POST", "/user/2/transaction", nil),
+		httptest.NewRequest("GET", "/user/3", nil),
+		httptest.NewRequest("GET", "/admin/stats", nil),
+		httptest.NewRequest("POST", "/admin/users/4/status", strings.NewReader(`{"status": "frozen"}`)),
+	}
+	for _, r := range requests {
+		m.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	// The body is still read in full by the handler
+	if handlerBody != `{"status": "frozen"}` {
+		t.Errorf("Expected handler to read the admin body, got: %q", handlerBody)
+	}
+	if len(auditor.events) != 3 {
+		t.Fatalf("Expected 3 audit events, got: %+v", auditor.events)
+	}
+	if got := auditor.events[0]; got.Type != audit.AuthFailure || got.Outcome != "failure" || got.Status != 401 || got.UserID != 1 || got.Reason != "invalid token" || got.SourceIP != "192.0.2.1" {
+		t.Errorf("Unexpected auth failure event: %+v", got)
+	}
+	if got := auditor.events[1]; got.Type != audit.AccessDenied || got.Route != "/user/{id}/transaction" {
+		t.Errorf("Unexpected access denied event: %+v", got)
+	}
+	if got := auditor.events[2]; got.Type != audit.AdminAction || got.Outcome != "success" || got.UserID != 4 || string(got.Request) != `{"status": "frozen"}` {
+		t.Errorf("Unexpected admin action event: %+v", got)
+	}
+}
@@ -0,0 +1,99 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"assignment/internal/schema"
+)
+
+// maxAmountDecimals bounds the decimal places of numeric amounts accepted in
+// lenient mode; amounts with more are rejected by the usual validation anyway.
+const maxAmountDecimals = 18
+
+// maxAmountExponent bounds the exponent of numeric amounts such as 1e3.
+const maxAmountExponent = 30
+
+// decodeBody validates a JSON request body against the named schema (see
+// internal/schema/schemas) and decodes it into v. Clients sending
+// "Content-Type: application/json; amounts=lenient" may give amounts as JSON
+// numbers, which are converted to their exact decimal strings first.
+func decodeBody(r *http.Request, schemaName string, v interface{}) error {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	if lenientAmounts(r) {
+		if body, err = stringifyAmounts(body); err != nil {
+			return err
+		}
+	}
+	if err := schema.Validate(schemaName, body); err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+// lenientAmounts reports whether the request opted into numeric amounts.
+func lenientAmounts(r *http.Request) bool {
+	_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && params["amounts"] == "lenient"
+}
+
+// stringifyAmounts replaces a numeric top-level "amount" of a JSON object with
+// its decimal string, e.g. 10.5 with "10.5" and 1e2 with "100". The number is
+// converted from its literal, never through a float, so no precision is lost.
+// Bodies without a numeric amount are returned unchanged.
+func stringifyAmounts(body []byte) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(body, &fields) != nil {
+		// Leave the error to the schema validation
+		return body, nil
+	}
+	raw, ok := fields["amount"]
+	if !ok {
+		return body, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	tok, _ := dec.Token()
+	number, ok := tok.(json.Number)
+	if !ok {
+		return body, nil
+	}
+
+	// Bound the exponent: 1e999999999 would take ages to expand
+	if _, exp, found := strings.Cut(strings.ToLower(number.String()), "e"); found {
+		if n, err := strconv.Atoi(exp); err != nil || n > maxAmountExponent || n < -maxAmountExponent {
+			return nil, errors.New("amount: out of range " + number.String())
+		}
+	}
+	amount, ok := new(big.Rat).SetString(number.String())
+	if !ok {
+		return nil, errors.New("amount: invalid number " + number.String())
+	}
+	text, ok := decimalString(amount)
+	if !ok {
+		return nil, errors.New("amount: too many decimal places in " + number.String())
+	}
+	fields["amount"], _ = json.Marshal(text)
+	return json.Marshal(fields)
+}
+
+// decimalString formats x with as few decimal places as represent it exactly.
+func decimalString(x *big.Rat) (string, bool) {
+	for places := 0; places <= maxAmountDecimals; places++ {
+		s := x.FloatString(places)
+		if y, _ := new(big.Rat).SetString(s); y.Cmp(x) == 0 {
+			return s, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,33 @@
+package http
+
+import (
+	"testing"
+)
+
+func TestStringifyAmounts(t *testing.T) {
+	tests := []struct {
+		body, want string
+		wantErr    bool
+	}{
+		{`{"amount":10.5,"state":"win"}`, `{"amount":"10.5","state":"win"}`, false},
+		{`{"amount":1e2}`, `{"amount":"100"}`, false},
+		{`{"amount":1.05E1}`, `{"amount":"10.5"}`, false},
+		{`{"amount":0.1}`, `{"amount":"0.1"}`, false},
+		{`{"amount":-3}`, `{"amount":"-3"}`, false},
+		{`{"amount":"10.50"}`, `{"amount":"10.50"}`, false},
+		{`{"state":"win"}`, `{"state":"win"}`, false},
+		{`{`, `{`, false},
+		{`{"amount":1e999999999}`, "", true},
+		{`{"amount":1e-25}`, "", true},
+	}
+	for _, tt := range tests {
+		got, err := stringifyAmounts([]byte(tt.body))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error %v, want error %v", tt.body, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && string(got) != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.body, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package http
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"assignment/internal/metrics"
+)
+
+// BulkheadConfig partitions the requests the server serves at once by
+// Source-Type.
+type BulkheadConfig struct {
+	// Sizes is the number of requests of each Source-Type, e.g. "game",
+	// served at once. Source types without a size, and requests without a
+	// Source-Type, are not limited.
+	Sizes map[string]int
+}
+
+// Enabled reports whether any source type is limited.
+func (c BulkheadConfig) Enabled() bool {
+	for _, size := range c.Sizes {
+		if size > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+type bulkheads struct {
+	m    metrics.Metrics
+	next http.Handler
+	// compartments is fixed at construction, so it is read without locking
+	compartments map[string]*compartment
+}
+
+type compartment struct {
+	slots    chan struct{}
+	inFlight int64
+}
+
+// NewBulkheadMiddleware wraps next with a middleware that serves at most
+// cfg.Sizes[t] requests with Source-Type t at once and answers further ones
+// with 503 Service Unavailable at once. A flood from one source, e.g. the
+// game servers, then cannot take the capacity another source, e.g. payment
+// provider callbacks, needs. It records the requests in flight
+// (bulkhead_in_flight) and those turned away (bulkhead_rejected_total) by
+// source type; m may be nil.
+func NewBulkheadMiddleware(cfg BulkheadConfig, m metrics.Metrics, next http.Handler) http.Handler {
+	if m == nil {
+		m = metrics.Nop{}
+	}
+	b := &bulkheads{m: m, next: next, compartments: map[string]*compartment{}}
+	for sourceType, size := range cfg.Sizes {
+		if size > 0 {
+			b.compartments[sourceType] = &compartment{slots: make(chan struct{}, size)}
+			m.Gauge("bulkhead_size", float64(size), metrics.Labels{"source_type": sourceType})
+		}
+	}
+	return b
+}
+
+func (b *bulkheads) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	sourceType := r.Header.Get("Source-Type")
+	c := b.compartments[sourceType]
+	if c == nil {
+		b.next.ServeHTTP(w, r)
+		return
+	}
+
+	labels := metrics.Labels{"source_type": sourceType}
+	select {
+	case c.slots <- struct{}{}:
+	default:
+		b.m.Inc("bulkhead_rejected_total", labels)
+		respondRetryLater(w, http.StatusServiceUnavailable, reasonOverloaded, transientRetryAfter, "Service unavailable: too many concurrent requests from Source-Type "+sourceType)
+		return
+	}
+	b.m.Gauge("bulkhead_in_flight", float64(atomic.AddInt64(&c.inFlight, 1)), labels)
+	defer func() {
+		b.m.Gauge("bulkhead_in_flight", float64(atomic.AddInt64(&c.inFlight, -1)), labels)
+		<-c.slots
+	}()
+
+	b.next.ServeHTTP(w, r)
+}
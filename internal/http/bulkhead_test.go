@@ -0,0 +1,84 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"assignment/internal/metrics"
+)
+
+func TestBulkhead_IsolatesSourceTypes(t *testing.T) {
+	p := metrics.NewPrometheus()
+	started, release := make(chan struct{}, 10), make(chan struct{})
+	b := NewBulkheadMiddleware(BulkheadConfig{Sizes: map[string]int{"game": 2, "payment": 1}}, p, blockingHandler(started, release))
+
+	serve := func(sourceType string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/user/1/transaction", nil)
+		if sourceType != "" {
+			r.Header.Set("Source-Type", sourceType)
+		}
+		w := httptest.NewRecorder()
+		b.ServeHTTP(w, r)
+		return w
+	}
+
+	// Fill the game bulkhead
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			serve("game")
+		}()
+		<-started
+	}
+
+	// Further game requests are shed at once
+	if w := serve("game"); w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), `"reason":"overloaded"`) {
+		t.Fatalf("Expected status 503 overloaded, got: %d %s", w.Code, w.Body)
+	}
+
+	// Payments and unlimited source types still get through
+	for _, sourceType := range []string{"payment", "server", ""} {
+		wg.Add(1)
+		go func(sourceType string) {
+			defer wg.Done()
+			if w := serve(sourceType); w.Code != http.StatusOK {
+				t.Errorf("Expected status 200 for Source-Type %q, got: %d", sourceType, w.Code)
+			}
+		}(sourceType)
+		<-started
+	}
+
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		`bulkhead_size{source_type="game"} 2`,
+		`bulkhead_in_flight{source_type="game"} 2`,
+		`bulkhead_in_flight{source_type="payment"} 1`,
+		`bulkhead_rejected_total{source_type="game"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", want, body)
+		}
+	}
+
+	close(release)
+	wg.Wait()
+	if w := serve("game"); w.Code != http.StatusOK {
+		t.Errorf("Expected the bulkhead to free up, got: %d", w.Code)
+	}
+}
+
+func TestBulkheadConfig_Enabled(t *testing.T) {
+	if (BulkheadConfig{Sizes: map[string]int{"game": 0}}).Enabled() {
+		t.Error("Expected zero sizes to disable the bulkheads")
+	}
+	if !(BulkheadConfig{Sizes: map[string]int{"game": 0, "payment": 4}}).Enabled() {
+		t.Error("Expected a positive size to enable the bulkheads")
+	}
+}
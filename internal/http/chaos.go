@@ -0,0 +1,80 @@
+package http
+
+import (
+	"log"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ChaosConfig controls the fault-injection middleware. Rates are
+// probabilities between 0 and 1 and are evaluated independently per request.
+type ChaosConfig struct {
+	LatencyRate float64
+	Latency     time.Duration
+	DropRate    float64
+	ErrorRate   float64
+}
+
+// Enabled reports whether any fault would ever be injected.
+func (c ChaosConfig) Enabled() bool {
+	return c.LatencyRate > 0 || c.DropRate > 0 || c.ErrorRate > 0
+}
+
+type chaos struct {
+	cfg  ChaosConfig
+	next http.Handler
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// NewChaosMiddleware wraps next with a middleware that randomly delays
+// requests, drops connections and returns 503 responses. It is meant for
+// resilience testing only and must never be enabled in production.
+func NewChaosMiddleware(cfg ChaosConfig, next http.Handler) http.Handler {
+	return newChaos(cfg, next, rand.New(rand.NewSource(time.Now().UnixNano())))
+}
+
+func newChaos(cfg ChaosConfig, next http.Handler, rng *rand.Rand) *chaos {
+	return &chaos{cfg: cfg, next: next, rng: rng}
+}
+
+func (c *chaos) roll(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rng.Float64() < rate
+}
+
+func (c *chaos) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if c.roll(c.cfg.LatencyRate) {
+		log.Printf("Chaos: delaying %s %s by %s", r.Method, r.URL.Path, c.cfg.Latency)
+		select {
+		case <-time.After(c.cfg.Latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if c.roll(c.cfg.DropRate) {
+		if hj, ok := w.(http.Hijacker); ok {
+			if conn, _, err := hj.Hijack(); err == nil {
+				log.Printf("Chaos: dropping connection for %s %s", r.Method, r.URL.Path)
+				conn.Close()
+				return
+			}
+		}
+	}
+
+	if c.roll(c.cfg.ErrorRate) {
+		log.Printf("Chaos: injecting error for %s %s", r.Method, r.URL.Path)
+		respondRetryLater(w, http.StatusServiceUnavailable, reasonInjectedFault, time.Second, "Injected fault")
+		return
+	}
+
+	c.next.ServeHTTP(w, r)
+}
@@ -0,0 +1,64 @@
+package http
+
+import (
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func okHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func TestChaos_Disabled(t *testing.T) {
+	c := newChaos(ChaosConfig{}, okHandler(), rand.New(rand.NewSource(1)))
+
+	for i := 0; i < 100; i++ {
+		w := httptest.NewRecorder()
+		c.ServeHTTP(w, httptest.NewRequest("GET", "/health", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got: %d", w.Code)
+		}
+	}
+}
+
+func TestChaos_ErrorRate(t *testing.T) {
+	c := newChaos(ChaosConfig{ErrorRate: 1}, okHandler(), rand.New(rand.NewSource(1)))
+
+	w := httptest.NewRecorder()
+	c.ServeHTTP(w, httptest.NewRequest("GET", "/health", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got: %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "1" || !strings.Contains(w.Body.String(), `"reason":"injected_fault"`) {
+		t.Errorf("Expected back-off guidance, got: %v %s", w.Header(), w.Body)
+	}
+}
+
+func TestChaos_Latency(t *testing.T) {
+	c := newChaos(ChaosConfig{LatencyRate: 1, Latency: 20 * time.Millisecond}, okHandler(), rand.New(rand.NewSource(1)))
+
+	start := time.Now()
+	w := httptest.NewRecorder()
+	c.ServeHTTP(w, httptest.NewRequest("GET", "/health", nil))
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("Expected at least 20ms latency, got: %s", elapsed)
+	}
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got: %d", w.Code)
+	}
+}
+
+func TestChaos_DropConnection(t *testing.T) {
+	server := httptest.NewServer(newChaos(ChaosConfig{DropRate: 1}, okHandler(), rand.New(rand.NewSource(1))))
+	defer server.Close()
+
+	if _, err := http.Get(server.URL + "/health"); err == nil {
+		t.Error("Expected dropped connection error, got none")
+	}
+}
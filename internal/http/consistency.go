@@ -0,0 +1,36 @@
+package http
+
+import (
+	"log"
+	"net/http"
+
+	"assignment/internal/consistency"
+)
+
+type ConsistencyHandlers struct {
+	checker *consistency.Checker
+}
+
+func NewConsistencyHandlers(checker *consistency.Checker) *ConsistencyHandlers {
+	return &ConsistencyHandlers{
+		checker: checker,
+	}
+}
+
+// HandleVerifyLedger runs a ledger consistency check on demand, alerting and
+// quarantining like the periodic check.
+func (h *ConsistencyHandlers) HandleVerifyLedger(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	report, err := h.checker.Check(r.Context())
+	if err != nil {
+		log.Printf("Error verifying ledger: %v", err)
+		respondInternalError(w, err)
+		return
+	}
+
+	respondJSON(w, report)
+}
@@ -0,0 +1,953 @@
+package http
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+type contractRequest struct {
+	method  string
+	path    string
+	headers map[string]string
+	body    string
+}
+
+type contractCase struct {
+	name    string
+	setup   []contractRequest
+	request contractRequest
+}
+
+func transactionRequest(userID, sourceType, body string) contractRequest {
+	headers := map[string]string{"Content-Type": "application/json"}
+	if sourceType != "" {
+		headers["Source-Type"] = sourceType
+	}
+	return contractRequest{
+		method:  "POST",
+		path:    "/user/" + userID + "/transaction",
+		headers: headers,
+		body:    body,
+	}
+}
+
+func transferRequest(sourceType, body string) contractRequest {
+	return contractRequest{
+		method:  "POST",
+		path:    "/transfer",
+		headers: map[string]string{"Content-Type": "application/json", "Source-Type": sourceType},
+		body:    body,
+	}
+}
+
+func bulkBalanceRequest(body string) contractRequest {
+	return contractRequest{
+		method:  "POST",
+		path:    "/balances",
+		headers: map[string]string{"Content-Type": "application/json"},
+		body:    body,
+	}
+}
+
+func createUserRequest(body string) contractRequest {
+	return contractRequest{
+		method:  "POST",
+		path:    "/user",
+		headers: map[string]string{"Content-Type": "application/json"},
+		body:    body,
+	}
+}
+
+func updateUserRequest(userID, body string) contractRequest {
+	return contractRequest{
+		method:  "PATCH",
+		path:    "/user/" + userID,
+		headers: map[string]string{"Content-Type": "application/json"},
+		body:    body,
+	}
+}
+
+func userStatusRequest(userID, body string) contractRequest {
+	return contractRequest{
+		method:  "POST",
+		path:    "/admin/users/" + userID + "/status",
+		headers: map[string]string{"Content-Type": "application/json"},
+		body:    body,
+	}
+}
+
+func kycLevelRequest(userID, level string) contractRequest {
+	return contractRequest{
+		method:  "POST",
+		path:    "/admin/users/" + userID + "/kyc",
+		headers: map[string]string{"Content-Type": "application/json"},
+		body:    `{"kyc_level":"` + level + `"}`,
+	}
+}
+
+func addUserTagsRequest(userID, body string) contractRequest {
+	return contractRequest{
+		method:  "POST",
+		path:    "/admin/users/" + userID + "/tags",
+		headers: map[string]string{"Content-Type": "application/json"},
+		body:    body,
+	}
+}
+
+func maxBalanceRequest(userID, maxBalance string) contractRequest {
+	return contractRequest{
+		method:  "POST",
+		path:    "/admin/users/" + userID + "/max-balance",
+		headers: map[string]string{"Content-Type": "application/json"},
+		body:    `{"max_balance":"` + maxBalance + `"}`,
+	}
+}
+
+func createEscrowRequest(name string) contractRequest {
+	return contractRequest{
+		method:  "POST",
+		path:    "/escrow",
+		headers: map[string]string{"Content-Type": "application/json"},
+		body:    fmt.Sprintf(`{"name":%q}`, name),
+	}
+}
+
+func escrowRequest(name, action, body string) contractRequest {
+	return contractRequest{
+		method:  "POST",
+		path:    "/escrow/" + name + "/" + action,
+		headers: map[string]string{"Content-Type": "application/json", "Source-Type": "game"},
+		body:    body,
+	}
+}
+
+func createWalletRequest(userID, walletID string) contractRequest {
+	return contractRequest{
+		method:  "POST",
+		path:    "/user/" + userID + "/wallet",
+		headers: map[string]string{"Content-Type": "application/json"},
+		body:    fmt.Sprintf(`{"walletId":%q}`, walletID),
+	}
+}
+
+func walletMoveRequest(userID, body string) contractRequest {
+	return contractRequest{
+		method:  "POST",
+		path:    "/user/" + userID + "/wallet/move",
+		headers: map[string]string{"Content-Type": "application/json", "Source-Type": "game"},
+		body:    body,
+	}
+}
+
+func pushDeviceRequest(userID, body string) contractRequest {
+	return contractRequest{
+		method:  "POST",
+		path:    "/user/" + userID + "/devices",
+		headers: map[string]string{"Content-Type": "application/json"},
+		body:    body,
+	}
+}
+
+// conditionalTransactionRequest only applies on the balance tagged etag.
+func conditionalTransactionRequest(userID, etag, body string) contractRequest {
+	req := transactionRequest(userID, "game", body)
+	req.headers["If-Match"] = etag
+	return req
+}
+
+// lenientTransactionRequest sends amounts as JSON numbers, like the legacy
+// provider does.
+func lenientTransactionRequest(userID, body string) contractRequest {
+	req := transactionRequest(userID, "payment", body)
+	req.headers["Content-Type"] = "application/json; amounts=lenient"
+	return req
+}
+
+func notificationPreferencesRequest(userID, body string) contractRequest {
+	return contractRequest{
+		method:  "PUT",
+		path:    "/user/" + userID + "/notifications",
+		headers: map[string]string{"Content-Type": "application/json"},
+		body:    body,
+	}
+}
+
+func balanceRequest(userID string) contractRequest {
+	return contractRequest{method: "GET", path: "/user/" + userID + "/balance"}
+}
+
+func conditionalBalanceRequest(userID, etag string) contractRequest {
+	req := balanceRequest(userID)
+	req.headers = map[string]string{"If-None-Match": etag}
+	return req
+}
+
+var contractCases = []contractCase{
+	{
+		name:    "transaction_win",
+		request: transactionRequest("1", "game", `{"state":"win","amount":"10.50","transactionId":"contract-1"}`),
+	},
+	{
+		name:    "transaction_lose",
+		request: transactionRequest("1", "server", `{"state":"lose","amount":"25.00","transactionId":"contract-2"}`),
+	},
+	{
+		name:    "transaction_duplicate",
+		setup:   []contractRequest{transactionRequest("1", "game", `{"state":"win","amount":"10.50","transactionId":"contract-3"}`)},
+		request: transactionRequest("1", "payment", `{"state":"win","amount":"10.50","transactionId":"contract-3"}`),
+	},
+	{
+		name:    "transaction_insufficient_funds",
+		request: transactionRequest("3", "game", `{"state":"lose","amount":"100.00","transactionId":"contract-4"}`),
+	},
+	{
+		name:    "transaction_user_not_found",
+		request: transactionRequest("999", "game", `{"state":"win","amount":"1.00","transactionId":"contract-5"}`),
+	},
+	{
+		name:    "transaction_invalid_user_id",
+		request: transactionRequest("abc", "game", `{"state":"win","amount":"1.00","transactionId":"contract-6"}`),
+	},
+	{
+		name:    "transaction_missing_source_type",
+		request: transactionRequest("1", "", `{"state":"win","amount":"1.00","transactionId":"contract-7"}`),
+	},
+	{
+		name:    "transaction_invalid_source_type",
+		request: transactionRequest("1", "invalid", `{"state":"win","amount":"1.00","transactionId":"contract-8"}`),
+	},
+	{
+		name:    "transaction_invalid_state",
+		request: transactionRequest("1", "game", `{"state":"draw","amount":"1.00","transactionId":"contract-9"}`),
+	},
+	{
+		name:    "transaction_invalid_amount",
+		request: transactionRequest("1", "game", `{"state":"win","amount":"1.005","transactionId":"contract-10"}`),
+	},
+	{
+		name:    "transaction_invalid_body",
+		request: transactionRequest("1", "game", `{`),
+	},
+	{
+		name:    "transaction_schema_violation",
+		request: transactionRequest("1", "game", `{"state":"win","amount":10.5,"transactionId":["contract-11"]}`),
+	},
+	{
+		name:    "transaction_invalid_transaction_id",
+		request: transactionRequest("1", "game", `{"state":"win","amount":"1.00","transactionId":"contract 12"}`),
+	},
+	{
+		name:    "transaction_lenient_amount",
+		request: lenientTransactionRequest("1", `{"state":"win","amount":1.05e1,"transactionId":"contract-13"}`),
+	},
+	{
+		name:    "transaction_lenient_invalid_amount",
+		request: lenientTransactionRequest("1", `{"state":"win","amount":1.005,"transactionId":"contract-14"}`),
+	},
+	{
+		name:    "transaction_if_match",
+		request: conditionalTransactionRequest("1", `"ea026b1aef20e1ab"`, `{"state":"win","amount":"10.50","transactionId":"contract-15"}`),
+	},
+	{
+		name:    "transaction_precondition_failed",
+		setup:   []contractRequest{transactionRequest("1", "game", `{"state":"win","amount":"10.50","transactionId":"contract-16"}`)},
+		request: conditionalTransactionRequest("1", `"ea026b1aef20e1ab"`, `{"state":"win","amount":"10.50","transactionId":"contract-17"}`),
+	},
+	{
+		name:    "transaction_method_not_allowed",
+		request: contractRequest{method: "GET", path: "/user/1/transaction"},
+	},
+	{
+		name:    "balance_success",
+		request: balanceRequest("1"),
+	},
+	{
+		name:    "balance_after_transaction",
+		setup:   []contractRequest{transactionRequest("2", "game", `{"state":"lose","amount":"12.25","transactionId":"contract-11"}`)},
+		request: balanceRequest("2"),
+	},
+	{
+		name:    "balance_not_modified",
+		request: conditionalBalanceRequest("1", `"ea026b1aef20e1ab"`),
+	},
+	{
+		name:    "balance_modified",
+		setup:   []contractRequest{transactionRequest("2", "game", `{"state":"lose","amount":"12.25","transactionId":"contract-11"}`)},
+		request: conditionalBalanceRequest("2", `"1c1d0e9723f1d81a"`),
+	},
+	{
+		name:    "balance_user_not_found",
+		request: balanceRequest("999"),
+	},
+	{
+		name:    "balance_invalid_user_id",
+		request: balanceRequest("0"),
+	},
+	{
+		name:    "balance_at_invalid",
+		request: contractRequest{method: "GET", path: "/user/1/balance?at=yesterday"},
+	},
+	{
+		name:    "balance_at_future",
+		request: contractRequest{method: "GET", path: "/user/1/balance?at=2999-01-01T00:00:00Z"},
+	},
+	{
+		name:    "balance_method_not_allowed",
+		request: contractRequest{method: "POST", path: "/user/1/balance"},
+	},
+	{
+		name:    "bulk_balances",
+		request: bulkBalanceRequest(`{"userIds":[3,999,1,3]}`),
+	},
+	{
+		name:    "bulk_balances_empty",
+		request: bulkBalanceRequest(`{"userIds":[]}`),
+	},
+	{
+		name:    "bulk_balances_invalid_user_id",
+		request: bulkBalanceRequest(`{"userIds":[1,0]}`),
+	},
+	{
+		name:    "balance_history",
+		setup:   []contractRequest{transactionRequest("1", "game", `{"state":"win","amount":"10.50","transactionId":"contract-15"}`)},
+		request: contractRequest{method: "GET", path: "/user/1/balance/history?from=2024-01-15T00:00:00Z&to=2024-01-16T00:00:00Z"},
+	},
+	{
+		name:    "balance_history_invalid_range",
+		request: contractRequest{method: "GET", path: "/user/1/balance/history?from=2024-01-16T00:00:00Z&to=2024-01-15T00:00:00Z"},
+	},
+	{
+		name:    "balance_history_invalid_cursor",
+		request: contractRequest{method: "GET", path: "/user/1/balance/history?cursor=not-a-cursor"},
+	},
+	{
+		name:    "balance_history_invalid_limit",
+		request: contractRequest{method: "GET", path: "/user/1/balance/history?limit=1001"},
+	},
+	{
+		name:    "balance_history_user_not_found",
+		request: contractRequest{method: "GET", path: "/user/999/balance/history"},
+	},
+	{
+		name: "reconciliation_success",
+		setup: []contractRequest{
+			transactionRequest("1", "game", `{"state":"win","amount":"10.50","transactionId":"contract-12"}`),
+			transactionRequest("1", "server", `{"state":"lose","amount":"25.00","transactionId":"contract-13"}`),
+			transactionRequest("2", "game", `{"state":"lose","amount":"5.25","transactionId":"contract-14"}`),
+		},
+		request: contractRequest{method: "GET", path: "/admin/reconciliation?date=2024-01-15"},
+	},
+	{
+		name:    "reconciliation_empty_day",
+		request: contractRequest{method: "GET", path: "/admin/reconciliation?date=2023-12-31"},
+	},
+	{
+		name:    "reconciliation_invalid_date",
+		request: contractRequest{method: "GET", path: "/admin/reconciliation?date=15-01-2024"},
+	},
+	{
+		name: "stats_success",
+		setup: []contractRequest{
+			transactionRequest("1", "game", `{"state":"win","amount":"10.50","transactionId":"contract-16"}`),
+			transactionRequest("3", "game", `{"state":"lose","amount":"100.00","transactionId":"contract-17"}`),
+		},
+		request: contractRequest{method: "GET", path: "/admin/stats?window=24h"},
+	},
+	{
+		name: "import_success",
+		setup: []contractRequest{
+			transactionRequest("1", "game", `{"state":"win","amount":"1.00","transactionId":"import-2"}`),
+		},
+		request: contractRequest{
+			method:  "POST",
+			path:    "/admin/import",
+			headers: map[string]string{"Content-Type": "text/csv"},
+			body: "user_id,transaction_id,state,amount,source_type,created_at\n" +
+				"1,import-1,win,10.00,payment,2023-06-01T10:00:00Z\n" +
+				"1,import-2,win,1.00,payment,\n" +
+				"3,import-3,lose,5.00,game,\n" +
+				"2,import-4,draw,5.00,game,\n",
+		},
+	},
+	{
+		name: "import_missing_column",
+		request: contractRequest{
+			method:  "POST",
+			path:    "/admin/import",
+			headers: map[string]string{"Content-Type": "text/csv"},
+			body:    "user_id,transaction_id,state,amount\n1,import-5,win,1.00\n",
+		},
+	},
+	{
+		name:    "stats_invalid_window",
+		request: contractRequest{method: "GET", path: "/admin/stats?window=forever"},
+	},
+	{
+		name:    "statement_invalid_month",
+		request: contractRequest{method: "GET", path: "/user/1/statement?month=2024-13"},
+	},
+	{
+		name:    "statement_user_not_found",
+		request: contractRequest{method: "GET", path: "/user/999/statement?month=2024-01"},
+	},
+	{
+		name: "statement_summary_success",
+		setup: []contractRequest{
+			transactionRequest("1", "game", `{"state":"win","amount":"10.50","transactionId":"contract-18"}`),
+			transactionRequest("1", "game", `{"state":"lose","amount":"0.50","transactionId":"contract-19"}`),
+			transactionRequest("1", "payment", `{"state":"lose","amount":"30.00","transactionId":"contract-20"}`),
+		},
+		request: contractRequest{method: "GET", path: "/user/1/statement/summary?month=2024-01"},
+	},
+	{
+		name: "top_net_win",
+		setup: []contractRequest{
+			transactionRequest("2", "game", `{"state":"win","amount":"25.00","transactionId":"contract-21"}`),
+			transactionRequest("1", "game", `{"state":"win","amount":"5.00","transactionId":"contract-22"}`),
+			transactionRequest("1", "game", `{"state":"lose","amount":"7.50","transactionId":"contract-23"}`),
+		},
+		request: contractRequest{method: "GET", path: "/admin/reports/top?metric=net_win&limit=5"},
+	},
+	{
+		name:    "top_balance",
+		request: contractRequest{method: "GET", path: "/admin/reports/top?metric=balance&limit=2"},
+	},
+	{
+		name:    "top_invalid_metric",
+		request: contractRequest{method: "GET", path: "/admin/reports/top?metric=losses"},
+	},
+	{
+		name: "projections_rebuild",
+		setup: []contractRequest{
+			transactionRequest("1", "game", `{"state":"win","amount":"1.00","transactionId":"contract-24"}`),
+		},
+		request: contractRequest{method: "POST", path: "/admin/projections/rebuild"},
+	},
+	{
+		name:    "transfer_success",
+		request: transferRequest("game", `{"fromUserId":1,"toUserId":3,"amount":"40.00","transferId":"contract-transfer-1"}`),
+	},
+	{
+		name:    "transfer_duplicate",
+		setup:   []contractRequest{transferRequest("game", `{"fromUserId":2,"toUserId":1,"amount":"10.00","transferId":"contract-transfer-2"}`)},
+		request: transferRequest("game", `{"fromUserId":2,"toUserId":1,"amount":"10.00","transferId":"contract-transfer-2"}`),
+	},
+	{
+		name:    "transfer_insufficient_funds",
+		request: transferRequest("game", `{"fromUserId":3,"toUserId":1,"amount":"0.01","transferId":"contract-transfer-3"}`),
+	},
+	{
+		name:    "transfer_same_user",
+		request: transferRequest("game", `{"fromUserId":1,"toUserId":1,"amount":"1.00","transferId":"contract-transfer-4"}`),
+	},
+	{
+		name:    "transfer_user_not_found",
+		request: transferRequest("game", `{"fromUserId":1,"toUserId":999,"amount":"1.00","transferId":"contract-transfer-5"}`),
+	},
+	{
+		name:    "escrow_create",
+		request: createEscrowRequest("tournament-1"),
+	},
+	{
+		name:    "escrow_create_invalid_name",
+		request: createEscrowRequest("Tournament 1"),
+	},
+	{
+		name: "escrow_hold",
+		setup: []contractRequest{
+			createEscrowRequest("tournament-1"),
+			escrowRequest("tournament-1", "hold", `{"userId":2,"amount":"20.00","transferId":"contract-escrow-1"}`),
+		},
+		request: escrowRequest("tournament-1", "hold", `{"userId":1,"amount":"20.00","transferId":"contract-escrow-2"}`),
+	},
+	{
+		name: "escrow_release_insufficient_funds",
+		setup: []contractRequest{
+			createEscrowRequest("tournament-1"),
+			escrowRequest("tournament-1", "hold", `{"userId":1,"amount":"20.00","transferId":"contract-escrow-3"}`),
+		},
+		request: escrowRequest("tournament-1", "release", `{"userId":2,"amount":"20.01","transferId":"contract-escrow-4"}`),
+	},
+	{
+		name: "escrow_get",
+		setup: []contractRequest{
+			createEscrowRequest("tournament-1"),
+			escrowRequest("tournament-1", "hold", `{"userId":1,"amount":"20.00","transferId":"contract-escrow-5"}`),
+			escrowRequest("tournament-1", "hold", `{"userId":2,"amount":"20.00","transferId":"contract-escrow-6"}`),
+			escrowRequest("tournament-1", "release", `{"userId":2,"amount":"40.00","transferId":"contract-escrow-7"}`),
+		},
+		request: contractRequest{method: "GET", path: "/escrow/tournament-1"},
+	},
+	{
+		name:    "escrow_not_found",
+		request: escrowRequest("tournament-9", "hold", `{"userId":1,"amount":"1.00","transferId":"contract-escrow-8"}`),
+	},
+	{
+		name:    "escrow_balance_hidden",
+		setup:   []contractRequest{createEscrowRequest("tournament-1")},
+		request: balanceRequest("4"),
+	},
+	{
+		name:    "user_create",
+		request: createUserRequest(`{"balance":"25.5","currency":"USD"}`),
+	},
+	{
+		name:    "user_create_defaults",
+		request: createUserRequest(`{}`),
+	},
+	{
+		name:    "user_create_invalid_currency",
+		request: createUserRequest(`{"currency":"euro"}`),
+	},
+	{
+		name:    "user_create_invalid_balance",
+		request: createUserRequest(`{"balance":"-5.00"}`),
+	},
+	{
+		name:    "user_balance_after_create",
+		setup:   []contractRequest{createUserRequest(`{"balance":"12.34"}`)},
+		request: balanceRequest("4"),
+	},
+	{
+		name:    "user_create_profile",
+		request: createUserRequest(`{"external_ref":"crm-1001","display_name":"Ada","country":"GB","currency":"GBP"}`),
+	},
+	{
+		name:    "user_create_external_ref_conflict",
+		setup:   []contractRequest{createUserRequest(`{"external_ref":"crm-1001"}`)},
+		request: createUserRequest(`{"external_ref":"crm-1001"}`),
+	},
+	{
+		name:    "user_get",
+		request: contractRequest{method: "GET", path: "/user/1"},
+	},
+	{
+		name:    "user_get_not_found",
+		request: contractRequest{method: "GET", path: "/user/999"},
+	},
+	{
+		name: "user_update",
+		setup: []contractRequest{
+			updateUserRequest("2", `{"display_name":"Grace","external_ref":"crm-2"}`),
+		},
+		request: updateUserRequest("2", `{"country":"US","external_ref":""}`),
+	},
+	{
+		name:    "user_update_invalid_country",
+		request: updateUserRequest("1", `{"country":"usa"}`),
+	},
+	{
+		name:    "user_delete",
+		request: contractRequest{method: "DELETE", path: "/user/3"},
+	},
+	{
+		name:    "user_get_deleted",
+		setup:   []contractRequest{{method: "DELETE", path: "/user/3"}},
+		request: contractRequest{method: "GET", path: "/user/3"},
+	},
+	{
+		name:    "user_delete_twice",
+		setup:   []contractRequest{{method: "DELETE", path: "/user/3"}},
+		request: contractRequest{method: "DELETE", path: "/user/3"},
+	},
+	{
+		name:    "transaction_deleted_user",
+		setup:   []contractRequest{{method: "DELETE", path: "/user/3"}},
+		request: transactionRequest("3", "game", `{"state":"win","amount":"10.00","transactionId":"tx-deleted"}`),
+	},
+	{
+		name:    "kyc_set_level",
+		setup:   []contractRequest{createUserRequest(`{}`)},
+		request: kycLevelRequest("4", "basic"),
+	},
+	{
+		name:    "kyc_invalid_level",
+		request: kycLevelRequest("1", "gold"),
+	},
+	{
+		name:    "transaction_kyc_single_limit",
+		setup:   []contractRequest{kycLevelRequest("1", "unverified")},
+		request: transactionRequest("1", "game", `{"state":"win","amount":"100.01","transactionId":"tx-kyc-single"}`),
+	},
+	{
+		name: "transaction_kyc_daily_limit",
+		setup: []contractRequest{
+			kycLevelRequest("1", "unverified"),
+			transactionRequest("1", "game", `{"state":"lose","amount":"60.00","transactionId":"tx-kyc-1"}`),
+		},
+		request: transactionRequest("1", "game", `{"state":"win","amount":"40.01","transactionId":"tx-kyc-2"}`),
+	},
+	{
+		name:    "user_tags_add",
+		setup:   []contractRequest{addUserTagsRequest("1", `{"tags":["vip"]}`)},
+		request: addUserTagsRequest("1", `{"tags":["test_account","vip"]}`),
+	},
+	{
+		name:    "user_tags_invalid",
+		request: addUserTagsRequest("1", `{"tags":["VIP!"]}`),
+	},
+	{
+		name:    "user_tags_remove",
+		setup:   []contractRequest{addUserTagsRequest("2", `{"tags":["vip","fraud_watch"]}`)},
+		request: contractRequest{method: "DELETE", path: "/admin/users/2/tags/fraud_watch"},
+	},
+	{
+		name:    "user_tags_get_not_found",
+		request: contractRequest{method: "GET", path: "/admin/users/999/tags"},
+	},
+	{
+		name: "top_exclude_tag",
+		setup: []contractRequest{
+			addUserTagsRequest("1", `{"tags":["test_account"]}`),
+		},
+		request: contractRequest{method: "GET", path: "/admin/reports/top?metric=balance&exclude_tag=test_account"},
+	},
+	{
+		name: "stats_tag",
+		setup: []contractRequest{
+			addUserTagsRequest("2", `{"tags":["vip"]}`),
+			transactionRequest("1", "game", `{"state":"win","amount":"10.00","transactionId":"tx-untagged"}`),
+			transactionRequest("2", "game", `{"state":"lose","amount":"5.00","transactionId":"tx-vip"}`),
+		},
+		request: contractRequest{method: "GET", path: "/admin/stats?window=24h&tag=vip"},
+	},
+	{
+		name:    "stats_invalid_tag",
+		request: contractRequest{method: "GET", path: "/admin/stats?tag=Not%20A%20Tag"},
+	},
+	{
+		name:    "max_balance_set",
+		request: maxBalanceRequest("2", "75.00"),
+	},
+	{
+		name:    "max_balance_invalid",
+		request: maxBalanceRequest("2", "lots"),
+	},
+	{
+		name:    "transaction_max_balance",
+		setup:   []contractRequest{maxBalanceRequest("2", "75.00")},
+		request: transactionRequest("2", "game", `{"state":"win","amount":"25.01","transactionId":"tx-over-cap"}`),
+	},
+	{
+		name: "transaction_max_balance_debit",
+		setup: []contractRequest{
+			maxBalanceRequest("1", "50.00"),
+		},
+		request: transactionRequest("1", "game", `{"state":"lose","amount":"10.00","transactionId":"tx-under-cap"}`),
+	},
+	{
+		name:    "user_status_suspend",
+		request: userStatusRequest("1", `{"status":"suspended","reason":"chargeback review"}`),
+	},
+	{
+		name:    "user_status_invalid",
+		request: userStatusRequest("1", `{"status":"frozen"}`),
+	},
+	{
+		name: "user_status_reopen_closed",
+		setup: []contractRequest{
+			userStatusRequest("3", `{"status":"closed"}`),
+		},
+		request: userStatusRequest("3", `{"status":"active"}`),
+	},
+	{
+		name: "transaction_user_suspended",
+		setup: []contractRequest{
+			userStatusRequest("1", `{"status":"suspended"}`),
+		},
+		request: transactionRequest("1", "game", `{"state":"win","amount":"10.00","transactionId":"tx-suspended"}`),
+	},
+	{
+		name: "transaction_user_closed_duplicate",
+		setup: []contractRequest{
+			transactionRequest("2", "game", `{"state":"win","amount":"5.00","transactionId":"tx-before-close"}`),
+			userStatusRequest("2", `{"status":"closed"}`),
+		},
+		request: transactionRequest("2", "game", `{"state":"win","amount":"5.00","transactionId":"tx-before-close"}`),
+	},
+	{
+		name: "transfer_recipient_suspended",
+		setup: []contractRequest{
+			userStatusRequest("2", `{"status":"suspended"}`),
+		},
+		request: transferRequest("game", `{"fromUserId":1,"toUserId":2,"amount":"10.00","transferId":"tr-suspended"}`),
+	},
+	{
+		name:    "wallet_create",
+		request: createWalletRequest("1", "casino"),
+	},
+	{
+		name:    "wallet_create_invalid",
+		request: createWalletRequest("1", "Casino!"),
+	},
+	{
+		name:    "wallet_list",
+		setup:   []contractRequest{createWalletRequest("1", "casino")},
+		request: contractRequest{method: "GET", path: "/user/1/wallet"},
+	},
+	{
+		name:  "wallet_transaction",
+		setup: []contractRequest{createWalletRequest("1", "casino")},
+		request: contractRequest{
+			method:  "POST",
+			path:    "/user/1/wallet/casino/transaction",
+			headers: map[string]string{"Content-Type": "application/json", "Source-Type": "game"},
+			body:    `{"state":"win","amount":"10.00","transactionId":"tx-casino"}`,
+		},
+	},
+	{
+		name:    "wallet_balance_not_found",
+		request: contractRequest{method: "GET", path: "/user/1/wallet/poker/balance"},
+	},
+	{
+		name:    "wallet_move",
+		setup:   []contractRequest{createWalletRequest("1", "casino")},
+		request: walletMoveRequest("1", `{"fromWalletId":"main","toWalletId":"casino","amount":"30.00","moveId":"mv-1"}`),
+	},
+	{
+		name:    "wallet_move_same_wallet",
+		request: walletMoveRequest("1", `{"fromWalletId":"main","toWalletId":"main","amount":"30.00","moveId":"mv-2"}`),
+	},
+	{
+		name:    "notifications_set",
+		request: notificationPreferencesRequest("1", `{"balance_below":"20","large_transaction":"500.00","channels":["email","push","email"]}`),
+	},
+	{
+		name:    "notifications_get_default",
+		request: contractRequest{method: "GET", path: "/user/2/notifications"},
+	},
+	{
+		name:    "notifications_invalid_channel",
+		request: notificationPreferencesRequest("1", `{"channels":["pigeon"]}`),
+	},
+	{
+		name:    "notifications_sms_without_phone",
+		request: notificationPreferencesRequest("1", `{"balance_below":"20.00","channels":["sms"]}`),
+	},
+	{
+		name:    "notifications_user_not_found",
+		request: contractRequest{method: "GET", path: "/user/999/notifications"},
+	},
+	{
+		name:    "device_register",
+		request: pushDeviceRequest("1", `{"platform":"fcm","token":"fcm-token-1"}`),
+	},
+	{
+		name:    "device_register_invalid_platform",
+		request: pushDeviceRequest("1", `{"platform":"pager","token":"fcm-token-1"}`),
+	},
+	{
+		name:    "device_list",
+		setup:   []contractRequest{pushDeviceRequest("1", `{"platform":"apns","token":"apns-token-1"}`)},
+		request: contractRequest{method: "GET", path: "/user/1/devices"},
+	},
+	{
+		name:    "device_unregister_not_found",
+		request: contractRequest{method: "DELETE", path: "/user/1/devices/unknown-token"},
+	},
+	{
+		name:    "dead_letters_empty",
+		request: contractRequest{method: "GET", path: "/admin/webhooks/dead-letters"},
+	},
+	{
+		name:    "transaction_feed_invalid_source",
+		request: contractRequest{method: "GET", path: "/admin/transactions?source=casino"},
+	},
+	{
+		name:    "transaction_feed_invalid_user",
+		request: contractRequest{method: "GET", path: "/admin/transactions?state=win&user=abc"},
+	},
+	{
+		name:    "dead_letter_replay_not_found",
+		request: contractRequest{method: "POST", path: "/admin/webhooks/dead-letters/42/replay"},
+	},
+	{
+		name:    "dead_letter_replay_invalid_id",
+		request: contractRequest{method: "POST", path: "/admin/webhooks/dead-letters/abc/replay"},
+	},
+	{
+		name:    "token_issue_user_not_found",
+		request: contractRequest{method: "POST", path: "/admin/users/999/tokens"},
+	},
+	{
+		name:    "token_revoke_not_found",
+		request: contractRequest{method: "DELETE", path: "/admin/users/1/tokens/42"},
+	},
+	{
+		name:    "token_revoke_invalid_id",
+		request: contractRequest{method: "DELETE", path: "/admin/users/1/tokens/abc"},
+	},
+}
+
+func (h *Handlers) serveContract(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/admin/reconciliation"):
+		h.HandleReconciliation(w, r)
+	case r.URL.Path == "/admin/transactions":
+		h.HandleGetTransactionFeed(w, r)
+	case strings.HasPrefix(r.URL.Path, "/admin/stats"):
+		h.HandleStats(w, r)
+	case strings.HasPrefix(r.URL.Path, "/admin/reports/top"):
+		h.HandleTopReport(w, r)
+	case strings.HasPrefix(r.URL.Path, "/admin/projections/rebuild"):
+		h.HandleRebuildProjections(w, r)
+	case r.URL.Path == "/admin/webhooks/dead-letters":
+		h.HandleGetWebhookDeadLetters(w, r)
+	case strings.HasPrefix(r.URL.Path, "/admin/webhooks/dead-letters/"):
+		h.HandleReplayWebhookDeadLetter(w, r)
+	case strings.HasPrefix(r.URL.Path, "/admin/import"):
+		h.HandleImport(w, r)
+	case strings.HasPrefix(r.URL.Path, "/admin/users/") && strings.HasSuffix(r.URL.Path, "/tags"):
+		if r.Method == http.MethodPost {
+			h.HandleAddUserTags(w, r)
+		} else {
+			h.HandleGetUserTags(w, r)
+		}
+	case strings.HasPrefix(r.URL.Path, "/admin/users/") && strings.Contains(r.URL.Path, "/tags/"):
+		h.HandleRemoveUserTag(w, r)
+	case strings.HasPrefix(r.URL.Path, "/admin/users/") && strings.HasSuffix(r.URL.Path, "/tokens"):
+		h.HandleIssueAPIToken(w, r)
+	case strings.HasPrefix(r.URL.Path, "/admin/users/") && strings.Contains(r.URL.Path, "/tokens/"):
+		h.HandleRevokeAPIToken(w, r)
+	case strings.HasPrefix(r.URL.Path, "/admin/users/") && strings.HasSuffix(r.URL.Path, "/max-balance"):
+		h.HandleSetMaxBalance(w, r)
+	case strings.HasPrefix(r.URL.Path, "/admin/users/") && strings.HasSuffix(r.URL.Path, "/kyc"):
+		h.HandleSetKYCLevel(w, r)
+	case strings.HasPrefix(r.URL.Path, "/admin/users/"):
+		h.HandleSetUserStatus(w, r)
+	case strings.HasPrefix(r.URL.Path, "/user/") && strings.Contains(r.URL.Path, "/wallet/") && strings.HasSuffix(r.URL.Path, "/transaction"):
+		h.HandleWalletTransaction(w, r)
+	case strings.HasPrefix(r.URL.Path, "/user/") && strings.Contains(r.URL.Path, "/wallet/") && strings.HasSuffix(r.URL.Path, "/balance"):
+		h.HandleGetWalletBalance(w, r)
+	case strings.HasPrefix(r.URL.Path, "/user/") && strings.HasSuffix(r.URL.Path, "/wallet/move"):
+		h.HandleWalletMove(w, r)
+	case strings.HasPrefix(r.URL.Path, "/user/") && strings.HasSuffix(r.URL.Path, "/notifications"):
+		if r.Method == http.MethodPut {
+			h.HandleSetNotificationPreferences(w, r)
+		} else {
+			h.HandleGetNotificationPreferences(w, r)
+		}
+	case strings.HasPrefix(r.URL.Path, "/user/") && strings.HasSuffix(r.URL.Path, "/devices"):
+		if r.Method == http.MethodPost {
+			h.HandleRegisterPushDevice(w, r)
+		} else {
+			h.HandleGetPushDevices(w, r)
+		}
+	case strings.HasPrefix(r.URL.Path, "/user/") && strings.Contains(r.URL.Path, "/devices/"):
+		h.HandleUnregisterPushDevice(w, r)
+	case strings.HasPrefix(r.URL.Path, "/user/") && strings.HasSuffix(r.URL.Path, "/wallet"):
+		if r.Method == http.MethodPost {
+			h.HandleCreateWallet(w, r)
+		} else {
+			h.HandleGetWallets(w, r)
+		}
+	case r.URL.Path == "/user":
+		h.HandleCreateUser(w, r)
+	case strings.HasPrefix(r.URL.Path, "/user/") && !strings.Contains(r.URL.Path[6:], "/"):
+		switch r.Method {
+		case http.MethodPatch:
+			h.HandleUpdateUser(w, r)
+		case http.MethodDelete:
+			h.HandleDeleteUser(w, r)
+		default:
+			h.HandleGetUser(w, r)
+		}
+	case r.URL.Path == "/balances":
+		h.HandleGetBalances(w, r)
+	case r.URL.Path == "/transfer":
+		h.HandleTransfer(w, r)
+	case r.URL.Path == "/escrow":
+		h.HandleCreateEscrow(w, r)
+	case strings.HasPrefix(r.URL.Path, "/escrow/") && strings.HasSuffix(r.URL.Path, "/hold"):
+		h.HandleEscrowHold(w, r)
+	case strings.HasPrefix(r.URL.Path, "/escrow/") && strings.HasSuffix(r.URL.Path, "/release"):
+		h.HandleEscrowRelease(w, r)
+	case strings.HasPrefix(r.URL.Path, "/escrow/"):
+		h.HandleGetEscrow(w, r)
+	case strings.HasSuffix(r.URL.Path, "/balance/history"):
+		h.HandleGetBalanceHistory(w, r)
+	case strings.HasSuffix(r.URL.Path, "/statement/summary"):
+		h.HandleGetStatementSummary(w, r)
+	case strings.HasSuffix(r.URL.Path, "/statement"):
+		h.HandleGetStatement(w, r)
+	case strings.HasSuffix(r.URL.Path, "/transaction"):
+		h.HandleTransaction(w, r)
+	default:
+		h.HandleGetBalance(w, r)
+	}
+}
+
+func doContractRequest(h *Handlers, cr contractRequest) *http.Response {
+	req := httptest.NewRequest(cr.method, cr.path, strings.NewReader(cr.body))
+	for k, v := range cr.headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	h.serveContract(w, req)
+	return w.Result()
+}
+
+// formatResponse renders status, headers and body in a stable, diffable form.
+func formatResponse(resp *http.Response) string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "HTTP %d\n", resp.StatusCode)
+
+	keys := make([]string, 0, len(resp.Header))
+	for k := range resp.Header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&buf, "%s: %s\n", k, strings.Join(resp.Header[k], ", "))
+	}
+
+	buf.WriteString("\n")
+	buf.ReadFrom(resp.Body)
+	return buf.String()
+}
+
+func TestAPIContract(t *testing.T) {
+	for _, tc := range contractCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handlers, db := setupTestHandlers(t)
+			defer db.Close()
+
+			for _, setup := range tc.setup {
+				if resp := doContractRequest(handlers, setup); resp.StatusCode != http.StatusOK {
+					t.Fatalf("Setup request %s %s failed with status: %d", setup.method, setup.path, resp.StatusCode)
+				}
+			}
+
+			got := formatResponse(doContractRequest(handlers, tc.request))
+			golden := filepath.Join("testdata", "contract", tc.name+".golden")
+
+			if *update {
+				if err := os.MkdirAll(filepath.Dir(golden), 0o755); err != nil {
+					t.Fatalf("Failed to create golden directory: %v", err)
+				}
+				if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+					t.Fatalf("Failed to write golden file: %v", err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("Failed to read golden file (run with -update to create it): %v", err)
+			}
+			if got != string(want) {
+				t.Errorf("Response does not match %s\n--- got ---\n%s\n--- want ---\n%s", golden, got, want)
+			}
+		})
+	}
+}
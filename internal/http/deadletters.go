@@ -0,0 +1,71 @@
+package http
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"assignment/internal/core"
+	"assignment/internal/utils"
+	"assignment/internal/webhook"
+)
+
+func (h *Handlers) HandleGetWebhookDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	page, err := parsePage(r, 50, utils.MaxReportLimit)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	deadLetters, err := h.transactionService.GetWebhookDeadLettersPage(page)
+	if err != nil {
+		log.Printf("Error listing webhook dead letters: %v", err)
+		respondInternalError(w, err)
+		return
+	}
+
+	respondJSON(w, deadLetters)
+}
+
+func (h *Handlers) HandleReplayWebhookDeadLetter(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// Path format: /admin/webhooks/dead-letters/{id}/replay
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	id, err := strconv.ParseInt(parts[len(parts)-2], 10, 64)
+	if err != nil || id <= 0 {
+		respondError(w, http.StatusBadRequest, "invalid dead letter ID: must be a positive integer")
+		return
+	}
+
+	deadLetter, err := h.transactionService.ReplayWebhookDeadLetter(id, func(url string, payload []byte) error {
+		return webhook.Post(r.Context(), url, payload)
+	})
+	if err != nil {
+		errMsg := err.Error()
+		switch {
+		case errors.Is(err, core.ErrDeadLetterNotFound):
+			respondError(w, http.StatusNotFound, errMsg)
+		case errors.Is(err, core.ErrDeadLetterReplayed):
+			respondError(w, http.StatusConflict, errMsg)
+		case errors.Is(err, core.ErrReplayFailed):
+			respondError(w, http.StatusBadGateway, errMsg)
+		default:
+			log.Printf("Error replaying webhook dead letter: %v", err)
+			respondInternalError(w, err)
+		}
+		return
+	}
+
+	respondJSON(w, deadLetter)
+}
@@ -0,0 +1,113 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Headers by which callers say when they stop waiting for a response.
+const (
+	// DeadlineHeader is an absolute deadline, as an RFC 3339 timestamp
+	DeadlineHeader = "X-Request-Deadline"
+	// TimeoutHeader is a relative timeout in the gRPC format: up to 8 digits
+	// followed by a unit, H, M, S, m (milliseconds), u or n, e.g. "500m"
+	TimeoutHeader = "Grpc-Timeout"
+)
+
+// grpcTimeoutUnits are the units of TimeoutHeader values.
+var grpcTimeoutUnits = map[byte]time.Duration{
+	'H': time.Hour,
+	'M': time.Minute,
+	'S': time.Second,
+	'm': time.Millisecond,
+	'u': time.Microsecond,
+	'n': time.Nanosecond,
+}
+
+type deadline struct {
+	next http.Handler
+}
+
+// NewDeadlineMiddleware wraps next with a middleware that bounds the request
+// context by the deadline the caller sends in X-Request-Deadline or
+// Grpc-Timeout, the earlier one if both are set. Work still running when the
+// deadline passes is abandoned and its database transaction rolled back
+// rather than committed for a caller that is no longer waiting; it is
+// answered with 504 Gateway Timeout. Requests whose deadline has already
+// passed are answered so at once, and malformed deadlines with 400 Bad
+// Request.
+func NewDeadlineMiddleware(next http.Handler) http.Handler {
+	return &deadline{next: next}
+}
+
+func (d *deadline) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	at, ok, err := requestDeadline(r, time.Now())
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !ok {
+		d.next.ServeHTTP(w, r)
+		return
+	}
+	if !time.Now().Before(at) {
+		respondError(w, http.StatusGatewayTimeout, "Deadline exceeded: request deadline already passed")
+		return
+	}
+
+	ctx, cancel := context.WithDeadline(r.Context(), at)
+	defer cancel()
+	d.next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// requestDeadline returns the deadline r's headers set, relative ones
+// counting from now, and reports whether they set any.
+func requestDeadline(r *http.Request, now time.Time) (time.Time, bool, error) {
+	var at time.Time
+	if value := r.Header.Get(DeadlineHeader); value != "" {
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return time.Time{}, false, errors.New("invalid " + DeadlineHeader + " header: must be an RFC 3339 timestamp")
+		}
+		at = t
+	}
+	if value := r.Header.Get(TimeoutHeader); value != "" {
+		timeout, err := parseGRPCTimeout(value)
+		if err != nil {
+			return time.Time{}, false, errors.New("invalid " + TimeoutHeader + " header: " + err.Error())
+		}
+		if t := now.Add(timeout); at.IsZero() || t.Before(at) {
+			at = t
+		}
+	}
+	return at, !at.IsZero(), nil
+}
+
+// parseGRPCTimeout parses a timeout in the gRPC wire format.
+func parseGRPCTimeout(value string) (time.Duration, error) {
+	if len(value) < 2 || len(value) > 9 {
+		return 0, errors.New("must be 1 to 8 digits followed by a unit")
+	}
+	unit, ok := grpcTimeoutUnits[value[len(value)-1]]
+	if !ok {
+		return 0, errors.New("unit must be one of H, M, S, m, u or n")
+	}
+	digits := value[:len(value)-1]
+	for i := 0; i < len(digits); i++ {
+		if digits[i] < '0' || digits[i] > '9' {
+			return 0, errors.New("must be 1 to 8 digits followed by a unit")
+		}
+	}
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, errors.New("must be 1 to 8 digits followed by a unit")
+	}
+	// 99999999 hours overflow a Duration; such a timeout never expires anyway
+	if n > int64(1<<63-1)/int64(unit) {
+		return 1<<63 - 1, nil
+	}
+	return time.Duration(n) * unit, nil
+}
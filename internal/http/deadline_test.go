@@ -0,0 +1,99 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDeadlineMiddleware(t *testing.T) {
+	var deadline time.Time
+	var hasDeadline bool
+	handler := NewDeadlineMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadline, hasDeadline = r.Context().Deadline()
+	}))
+
+	serve := func(header, value string) *httptest.ResponseRecorder {
+		deadline, hasDeadline = time.Time{}, false
+		r := httptest.NewRequest("POST", "/user/1/transaction", nil)
+		if header != "" {
+			r.Header.Set(header, value)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := serve("", ""); w.Code != http.StatusOK || hasDeadline {
+		t.Errorf("Expected no deadline without headers, got: %d %v", w.Code, deadline)
+	}
+
+	start := time.Now()
+	if w := serve(TimeoutHeader, "1500m"); w.Code != http.StatusOK || !hasDeadline || deadline.Sub(start) < 1500*time.Millisecond || deadline.Sub(start) > 2*time.Second {
+		t.Errorf("Expected a deadline 1.5s out, got: %d %v", w.Code, deadline.Sub(start))
+	}
+
+	at := time.Now().Add(time.Minute).UTC().Truncate(time.Second)
+	if w := serve(DeadlineHeader, at.Format(time.RFC3339)); w.Code != http.StatusOK || !deadline.Equal(at) {
+		t.Errorf("Expected deadline %v, got: %d %v", at, w.Code, deadline)
+	}
+
+	// A deadline already passed is answered without doing any work
+	if w := serve(DeadlineHeader, time.Now().Add(-time.Second).Format(time.RFC3339Nano)); w.Code != http.StatusGatewayTimeout || hasDeadline {
+		t.Errorf("Expected status 504 without calling the handler, got: %d", w.Code)
+	}
+
+	for _, tc := range []struct{ header, value string }{
+		{DeadlineHeader, "tomorrow"},
+		{TimeoutHeader, "5"},
+		{TimeoutHeader, "5s"},
+		{TimeoutHeader, "-5S"},
+		{TimeoutHeader, "123456789S"},
+	} {
+		if w := serve(tc.header, tc.value); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s: %s, got: %d", tc.header, tc.value, w.Code)
+		}
+	}
+}
+
+func TestDeadlineMiddleware_EarlierDeadlineWins(t *testing.T) {
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	r := httptest.NewRequest("POST", "/transfer", nil)
+	r.Header.Set(DeadlineHeader, "2024-01-15T12:00:10Z")
+	r.Header.Set(TimeoutHeader, "5S")
+	at, ok, err := requestDeadline(r, now)
+	if err != nil || !ok || !at.Equal(now.Add(5*time.Second)) {
+		t.Errorf("Expected the 5s timeout to win, got: %v %v %v", at, ok, err)
+	}
+
+	r.Header.Set(TimeoutHeader, "1M")
+	if at, _, _ := requestDeadline(r, now); !at.Equal(now.Add(10 * time.Second)) {
+		t.Errorf("Expected the absolute deadline to win, got: %v", at)
+	}
+}
+
+func TestParseGRPCTimeout(t *testing.T) {
+	for value, want := range map[string]time.Duration{
+		"1H":        time.Hour,
+		"2M":        2 * time.Minute,
+		"30S":       30 * time.Second,
+		"250m":      250 * time.Millisecond,
+		"10u":       10 * time.Microsecond,
+		"7n":        7,
+		"99999999H": 1<<63 - 1,
+	} {
+		if got, err := parseGRPCTimeout(value); err != nil || got != want {
+			t.Errorf("parseGRPCTimeout(%q) = %v, %v; expected %v", value, got, err, want)
+		}
+	}
+}
+
+func TestRespondInternalError_DeadlineExceeded(t *testing.T) {
+	w := httptest.NewRecorder()
+	respondInternalError(w, context.DeadlineExceeded)
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected status 504, got: %d", w.Code)
+	}
+}
@@ -0,0 +1,139 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"assignment/internal/core"
+	"assignment/internal/models"
+	"assignment/internal/payment"
+	"assignment/internal/utils"
+)
+
+// maxCallbackSize bounds the payment provider callback bodies read.
+const maxCallbackSize = 1 << 20
+
+type DepositHandlers struct {
+	deposits *payment.Deposits
+}
+
+func NewDepositHandlers(deposits *payment.Deposits) *DepositHandlers {
+	return &DepositHandlers{
+		deposits: deposits,
+	}
+}
+
+func (h *DepositHandlers) HandleDepositIntent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, err := utils.ValidateUserID(extractUserID(r.URL.Path))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req models.DepositIntentRequest
+	if err := decodeBody(r, "deposit_intent", &req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	deposit, err := h.deposits.CreateIntent(r.Context(), userID, req.DepositID, req.Amount)
+	if err != nil {
+		if errors.Is(err, payment.ErrDepositIDRequired) {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		respondUserError(w, err)
+		return
+	}
+
+	respondJSON(w, deposit)
+}
+
+// HandlePaymentCallback receives the payment provider's callbacks. Errors
+// other than invalid callbacks make the provider deliver the callback again.
+func (h *DepositHandlers) HandlePaymentCallback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// Signatures are computed over the exact body
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxCallbackSize))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	deposit, err := h.deposits.Confirm(payload, r.Header)
+	if err != nil {
+		errMsg := err.Error()
+		switch {
+		case errors.Is(err, payment.ErrInvalidCallback):
+			log.Printf("Rejected payment callback: %v", err)
+			respondError(w, http.StatusBadRequest, errMsg)
+		case errors.Is(err, payment.ErrDepositRejected), errors.Is(err, core.ErrValidation),
+			errors.Is(err, core.ErrDepositChargedBack):
+			log.Printf("Deposit not credited: %v", err)
+			respondError(w, http.StatusConflict, errMsg)
+		default:
+			log.Printf("Error confirming deposit: %v", err)
+			respondInternalError(w, err)
+		}
+		return
+	}
+	if deposit == nil {
+		respondJSON(w, map[string]string{"message": "Callback ignored"})
+		return
+	}
+
+	respondJSON(w, deposit)
+}
+
+// HandleChargeBackDeposit records a chargeback reported outside the
+// provider's callbacks, e.g. by a bank's notice.
+func (h *Handlers) HandleChargeBackDeposit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// Path format: /admin/deposits/{depositId}/chargeback
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 3 || parts[2] == "" {
+		respondError(w, http.StatusBadRequest, "invalid deposit ID: must not be empty")
+		return
+	}
+
+	var req models.ChargebackRequest
+	if err := decodeBody(r, "chargeback", &req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	deposit, err := h.transactionService.ChargeBackDeposit(parts[2], req)
+	if err != nil {
+		errMsg := err.Error()
+		switch {
+		case errors.Is(err, core.ErrValidation):
+			respondError(w, http.StatusBadRequest, errMsg)
+		case errors.Is(err, core.ErrDepositNotFound), errors.Is(err, core.ErrUserNotFound):
+			respondError(w, http.StatusNotFound, errMsg)
+		case errors.Is(err, core.ErrDepositChargedBack):
+			respondError(w, http.StatusConflict, errMsg)
+		default:
+			log.Printf("Error charging back deposit: %v", err)
+			respondInternalError(w, err)
+		}
+		return
+	}
+
+	respondJSON(w, deposit)
+}
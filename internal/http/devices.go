@@ -0,0 +1,99 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"assignment/internal/core"
+	"assignment/internal/models"
+	"assignment/internal/utils"
+)
+
+func (h *Handlers) HandleRegisterPushDevice(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, err := utils.ValidateUserID(extractUserID(r.URL.Path))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req models.PushDeviceRequest
+	if err := decodeBody(r, "push_device", &req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	device, err := h.transactionService.RegisterPushDevice(userID, req)
+	if err != nil {
+		respondUserError(w, err)
+		return
+	}
+
+	respondJSON(w, device)
+}
+
+func (h *Handlers) HandleGetPushDevices(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, err := utils.ValidateUserID(extractUserID(r.URL.Path))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	devices, err := h.transactionService.GetPushDevices(userID)
+	if err != nil {
+		respondUserError(w, err)
+		return
+	}
+
+	respondJSON(w, devices)
+}
+
+func (h *Handlers) HandleUnregisterPushDevice(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, err := utils.ValidateUserID(extractUserID(r.URL.Path))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Path format: /user/{userId}/devices/{token}
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	token, err := url.PathUnescape(parts[len(parts)-1])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid device token: "+err.Error())
+		return
+	}
+
+	if err := h.transactionService.UnregisterPushDevice(userID, token); err != nil {
+		if errors.Is(err, core.ErrDeviceNotFound) {
+			respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		respondUserError(w, err)
+		return
+	}
+
+	// Respond with the devices left, like removing a tag does
+	devices, err := h.transactionService.GetPushDevices(userID)
+	if err != nil {
+		respondUserError(w, err)
+		return
+	}
+
+	respondJSON(w, devices)
+}
@@ -0,0 +1,139 @@
+package http
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"assignment/internal/core"
+	"assignment/internal/models"
+	"assignment/internal/utils"
+)
+
+func (h *Handlers) HandleOpenDispute(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req models.OpenDisputeRequest
+	if err := decodeBody(r, "open_dispute", &req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if err := core.ValidateTransactionID(req.TransactionID); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	dispute, err := h.transactionService.OpenDispute(req)
+	if err != nil {
+		respondDisputeError(w, err)
+		return
+	}
+
+	respondJSON(w, dispute)
+}
+
+func (h *Handlers) HandleGetDisputes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	page, err := parsePage(r, 50, utils.MaxReportLimit)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = core.DisputeOpen
+	}
+
+	disputes, err := h.transactionService.GetDisputesPage(status, page)
+	if err != nil {
+		respondDisputeError(w, err)
+		return
+	}
+
+	respondJSON(w, disputes)
+}
+
+func (h *Handlers) HandleGetDispute(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id, ok := disputeID(w, r)
+	if !ok {
+		return
+	}
+
+	dispute, err := h.transactionService.GetDispute(id)
+	if err != nil {
+		respondDisputeError(w, err)
+		return
+	}
+
+	respondJSON(w, dispute)
+}
+
+func (h *Handlers) HandleResolveDispute(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id, ok := disputeID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.ResolveDisputeRequest
+	if err := decodeBody(r, "resolve_dispute", &req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	dispute, err := h.transactionService.ResolveDispute(id, req)
+	if err != nil {
+		respondDisputeError(w, err)
+		return
+	}
+
+	respondJSON(w, dispute)
+}
+
+// disputeID parses the dispute ID of a request, answering 400 Bad Request
+// if it is invalid.
+func disputeID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	// Path format: /admin/disputes/{id} or /admin/disputes/{id}/resolve
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) >= 3 {
+		if id, err := strconv.ParseInt(parts[2], 10, 64); err == nil && id > 0 {
+			return id, true
+		}
+	}
+	respondError(w, http.StatusBadRequest, "invalid dispute ID: must be a positive integer")
+	return 0, false
+}
+
+func respondDisputeError(w http.ResponseWriter, err error) {
+	errMsg := err.Error()
+	switch {
+	case errors.Is(err, core.ErrValidation):
+		respondError(w, http.StatusBadRequest, errMsg)
+	case errors.Is(err, core.ErrTransactionNotFound), errors.Is(err, core.ErrDisputeNotFound), errors.Is(err, core.ErrUserNotFound):
+		respondError(w, http.StatusNotFound, errMsg)
+	case errors.Is(err, core.ErrAlreadyDisputed), errors.Is(err, core.ErrDisputeResolved),
+		errors.Is(err, core.ErrDisputeInsufficientFunds):
+		respondError(w, http.StatusConflict, errMsg)
+	default:
+		log.Printf("Error handling dispute: %v", err)
+		respondInternalError(w, err)
+	}
+}
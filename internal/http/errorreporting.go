@@ -0,0 +1,105 @@
+package http
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"runtime/debug"
+)
+
+// maxReportedBody bounds the response body kept to describe an error.
+const maxReportedBody = 1024
+
+// ErrorReporter captures server errors with the request that caused them,
+// e.g. a sentry.Client. stack is the stack of the panic, if any.
+type ErrorReporter interface {
+	CaptureRequestError(r *http.Request, status int, message string, stack []byte)
+}
+
+type errorReporting struct {
+	reporter ErrorReporter
+	next     http.Handler
+}
+
+// NewErrorReportingMiddleware wraps next with a middleware reporting 5xx
+// responses and panics to reporter. Panics are answered with a 500.
+func NewErrorReportingMiddleware(reporter ErrorReporter, next http.Handler) http.Handler {
+	return &errorReporting{reporter: reporter, next: next}
+}
+
+// statusRecorder remembers the status of a response and the start of the
+// body of error responses.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	body   []byte
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(p []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	if s.status >= 400 && len(s.body) < maxReportedBody {
+		s.body = append(s.body, p[:min(len(p), maxReportedBody-len(s.body))]...)
+	}
+	return s.ResponseWriter.Write(p)
+}
+
+// Hijack lets handlers inside, such as the chaos middleware, take over the
+// connection.
+func (s *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := s.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("connection does not support hijacking")
+	}
+	return hj.Hijack()
+}
+
+// message is the error of a JSON error response, or its raw body.
+func (s *statusRecorder) message() string {
+	var resp struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(s.body, &resp) == nil && resp.Error != "" {
+		return resp.Error
+	}
+	if len(s.body) > 0 {
+		return string(s.body)
+	}
+	return http.StatusText(s.status)
+}
+
+func (e *errorReporting) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rec := &statusRecorder{ResponseWriter: w}
+	defer func() {
+		p := recover()
+		if p == nil {
+			return
+		}
+		if p == http.ErrAbortHandler {
+			panic(p)
+		}
+		stack := debug.Stack()
+		log.Printf("Panic serving %s %s: %v\n%s", r.Method, r.URL.Path, p, stack)
+		e.reporter.CaptureRequestError(r, http.StatusInternalServerError, fmt.Sprintf("panic: %v", p), stack)
+		if rec.status == 0 {
+			respondError(w, http.StatusInternalServerError, "Internal server error")
+		}
+	}()
+
+	e.next.ServeHTTP(rec, r)
+	if rec.status >= 500 {
+		e.reporter.CaptureRequestError(r, rec.status, rec.message(), nil)
+	}
+}
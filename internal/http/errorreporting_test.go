@@ -0,0 +1,56 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type capturedError struct {
+	status  int
+	message string
+	stack   []byte
+}
+
+type fakeReporter struct {
+	captured []capturedError
+}
+
+func (f *fakeReporter) CaptureRequestError(r *http.Request, status int, message string, stack []byte) {
+	f.captured = append(f.captured, capturedError{status, message, stack})
+}
+
+func TestErrorReportingMiddleware(t *testing.T) {
+	reporter := &fakeReporter{}
+	m := NewErrorReportingMiddleware(reporter, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/panic":
+			panic("boom")
+		case "/error":
+			respondError(w, http.StatusInternalServerError, "Internal server error: connection refused")
+		case "/bad":
+			respondError(w, http.StatusBadRequest, "invalid amount")
+		default:
+			respondJSON(w, map[string]string{"status": "ok"})
+		}
+	}))
+
+	for _, path := range []string{"/ok", "/bad", "/error", "/panic"} {
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if path == "/panic" && rec.Code != http.StatusInternalServerError {
+			t.Errorf("Expected panic to be answered with 500, got: %d", rec.Code)
+		}
+	}
+
+	// Only server errors are reported
+	if len(reporter.captured) != 2 {
+		t.Fatalf("Expected 2 reported errors, got: %+v", reporter.captured)
+	}
+	if got := reporter.captured[0]; got.status != 500 || got.message != "Internal server error: connection refused" || got.stack != nil {
+		t.Errorf("Unexpected error report: %+v", got)
+	}
+	if got := reporter.captured[1]; got.message != "panic: boom" || len(got.stack) == 0 {
+		t.Errorf("Unexpected panic report: %+v", got)
+	}
+}
@@ -0,0 +1,118 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"assignment/internal/core"
+	"assignment/internal/models"
+)
+
+func extractEscrowName(path string) string {
+	// Path format: /escrow/{name} or /escrow/{name}/hold
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) >= 2 && parts[0] == "escrow" {
+		return parts[1]
+	}
+	return ""
+}
+
+func (h *Handlers) HandleCreateEscrow(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req models.CreateEscrowRequest
+	if err := decodeBody(r, "create_escrow", &req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	account, err := h.transactionService.CreateEscrowAccount(req.Name)
+	if err != nil {
+		if errors.Is(err, core.ErrValidation) {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Printf("Error creating escrow account: %v", err)
+		respondInternalError(w, err)
+		return
+	}
+
+	respondJSON(w, account)
+}
+
+func (h *Handlers) HandleGetEscrow(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	account, err := h.transactionService.GetEscrowAccount(extractEscrowName(r.URL.Path))
+	if err != nil {
+		if errors.Is(err, core.ErrEscrowNotFound) {
+			respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		log.Printf("Error getting escrow account: %v", err)
+		respondInternalError(w, err)
+		return
+	}
+
+	respondJSON(w, account)
+}
+
+func (h *Handlers) HandleEscrowHold(w http.ResponseWriter, r *http.Request) {
+	h.handleEscrowMove(w, r, h.transactionService.HoldInEscrow)
+}
+
+func (h *Handlers) HandleEscrowRelease(w http.ResponseWriter, r *http.Request) {
+	h.handleEscrowMove(w, r, h.transactionService.ReleaseFromEscrow)
+}
+
+// handleEscrowMove serves the endpoints moving funds into and out of escrow.
+func (h *Handlers) handleEscrowMove(w http.ResponseWriter, r *http.Request, move func(context.Context, string, models.EscrowRequest, string) (*models.TransferResponse, error)) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	sourceType := r.Header.Get("Source-Type")
+	if sourceType == "" {
+		respondError(w, http.StatusBadRequest, "Source-Type header is required")
+		return
+	}
+
+	var req models.EscrowRequest
+	if err := decodeBody(r, "escrow", &req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	response, err := move(r.Context(), extractEscrowName(r.URL.Path), req, sourceType)
+	if err != nil {
+		errMsg := err.Error()
+		if errors.Is(err, core.ErrUserNotFound) || errors.Is(err, core.ErrEscrowNotFound) {
+			respondError(w, http.StatusNotFound, errMsg)
+			return
+		}
+		if errors.Is(err, core.ErrValidation) {
+			respondError(w, http.StatusBadRequest, errMsg)
+			return
+		}
+		if errors.Is(err, core.ErrSignatureRequired) {
+			respondError(w, http.StatusUnauthorized, errMsg)
+			return
+		}
+		log.Printf("Error moving escrow funds: %v", err)
+		respondInternalError(w, err)
+		return
+	}
+
+	// Duplicates and insufficient funds are reported with 200 as well
+	respondJSON(w, response)
+}
@@ -0,0 +1,19 @@
+package http
+
+import "strings"
+
+// etagMatches reports whether an If-None-Match or If-Match header value lists
+// etag, or is "*". With weak comparison, used for If-None-Match, weak tags
+// (W/"...") match their strong counterpart; If-Match compares strongly.
+func etagMatches(header, etag string, weak bool) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if weak {
+			tag = strings.TrimPrefix(tag, "W/")
+		}
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
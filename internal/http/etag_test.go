@@ -0,0 +1,33 @@
+package http
+
+import (
+	"testing"
+
+	"assignment/internal/core"
+)
+
+func TestETagMatches(t *testing.T) {
+	etag := core.BalanceTag(1, "100.00")
+	if other := core.BalanceTag(1, "100.01"); other == etag {
+		t.Fatalf("Expected different tags for different balances, got %s twice", etag)
+	}
+
+	tests := []struct {
+		header string
+		weak   bool
+		want   bool
+	}{
+		{etag, false, true},
+		{"W/" + etag, true, true},
+		{"W/" + etag, false, false},
+		{`"other", ` + etag, false, true},
+		{"*", false, true},
+		{`"other"`, true, false},
+		{"", true, false},
+	}
+	for _, tt := range tests {
+		if got := etagMatches(tt.header, etag, tt.weak); got != tt.want {
+			t.Errorf("etagMatches(%q, weak=%v): got %v, want %v", tt.header, tt.weak, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+type sparseFields struct {
+	next http.Handler
+}
+
+// NewSparseFieldsMiddleware wraps next with a middleware trimming successful
+// JSON responses to the fields named in the "fields" query parameter, e.g.
+// ?fields=balance or ?fields=transactions(amount,state,created_at). A field
+// followed by a parenthesized list keeps only those fields of the object, or
+// of each object in the array, it holds; lists nest. Fields a response
+// doesn't have are left out. Requests without the parameter are untouched.
+func NewSparseFieldsMiddleware(next http.Handler) http.Handler {
+	return &sparseFields{next: next}
+}
+
+// fieldSelection maps the names of kept fields to the selection applied to
+// their values; nil keeps a value whole.
+type fieldSelection map[string]fieldSelection
+
+// fieldsWriter holds back successful JSON responses to trim them, and passes
+// anything else through.
+type fieldsWriter struct {
+	http.ResponseWriter
+	status    int
+	buffering bool
+	body      bytes.Buffer
+}
+
+func (f *fieldsWriter) WriteHeader(status int) {
+	if f.status != 0 {
+		return
+	}
+	f.status = status
+	f.buffering = status >= 200 && status < 300 && strings.HasPrefix(f.Header().Get("Content-Type"), "application/json")
+	if !f.buffering {
+		f.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (f *fieldsWriter) Write(b []byte) (int, error) {
+	if f.status == 0 {
+		f.WriteHeader(http.StatusOK)
+	}
+	if f.buffering {
+		return f.body.Write(b)
+	}
+	return f.ResponseWriter.Write(b)
+}
+
+func (sf *sparseFields) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if !query.Has("fields") {
+		sf.next.ServeHTTP(w, r)
+		return
+	}
+	selection, err := parseFields(query.Get("fields"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	fw := &fieldsWriter{ResponseWriter: w}
+	sf.next.ServeHTTP(fw, r)
+	if !fw.buffering {
+		return
+	}
+
+	w.Header().Del("Content-Length")
+	w.WriteHeader(fw.status)
+	body := selection.apply(bytes.TrimSpace(fw.body.Bytes()))
+	w.Write(append(body, '\n'))
+}
+
+var errInvalidFields = errors.New("invalid fields: must be a comma-separated list of names, each optionally followed by a parenthesized list")
+
+// parseFields parses the value of the "fields" query parameter.
+func parseFields(expr string) (fieldSelection, error) {
+	selection, rest, err := parseFieldList(expr)
+	if err != nil || rest != "" {
+		return nil, errInvalidFields
+	}
+	return selection, nil
+}
+
+// parseFieldList parses a list of fields from the start of expr and returns
+// the remainder.
+func parseFieldList(expr string) (fieldSelection, string, error) {
+	selection := fieldSelection{}
+	for {
+		end := 0
+		for end < len(expr) && isFieldNameByte(expr[end]) {
+			end++
+		}
+		if end == 0 {
+			return nil, "", errInvalidFields
+		}
+		name := expr[:end]
+		expr = expr[end:]
+
+		var sub fieldSelection
+		if strings.HasPrefix(expr, "(") {
+			var err error
+			if sub, expr, err = parseFieldList(expr[1:]); err != nil || !strings.HasPrefix(expr, ")") {
+				return nil, "", errInvalidFields
+			}
+			expr = expr[1:]
+		}
+		selection[name] = sub
+
+		if !strings.HasPrefix(expr, ",") {
+			return selection, expr, nil
+		}
+		expr = expr[1:]
+	}
+}
+
+func isFieldNameByte(c byte) bool {
+	return c == '_' || c == '-' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+// apply trims a JSON value to the selection: the selected fields of an
+// object, in their order, or the selected fields of each object in an
+// array. Other values are returned unchanged.
+func (s fieldSelection) apply(value []byte) []byte {
+	if len(value) == 0 {
+		return value
+	}
+	switch value[0] {
+	case '{':
+		dec := json.NewDecoder(bytes.NewReader(value))
+		if _, err := dec.Token(); err != nil {
+			return value
+		}
+		var out bytes.Buffer
+		out.WriteByte('{')
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return value
+			}
+			var field json.RawMessage
+			if err := dec.Decode(&field); err != nil {
+				return value
+			}
+			key, _ := tok.(string)
+			sub, ok := s[key]
+			if !ok {
+				continue
+			}
+			if sub != nil {
+				field = sub.apply(field)
+			}
+			if out.Len() > 1 {
+				out.WriteByte(',')
+			}
+			name, _ := json.Marshal(key)
+			out.Write(name)
+			out.WriteByte(':')
+			out.Write(field)
+		}
+		out.WriteByte('}')
+		return out.Bytes()
+	case '[':
+		var items []json.RawMessage
+		if err := json.Unmarshal(value, &items); err != nil {
+			return value
+		}
+		for i, item := range items {
+			items[i] = s.apply(item)
+		}
+		out, err := json.Marshal(items)
+		if err != nil {
+			return value
+		}
+		return out
+	}
+	return value
+}
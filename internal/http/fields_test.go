@@ -0,0 +1,51 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSparseFields(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/balance":
+			respondJSON(w, map[string]interface{}{"userId": 1, "balance": "10.00"})
+		case "/history":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"userId":1,"transactions":[{"amount":"1.00","state":"win","created_at":"2024-01-15","source":"game"},{"amount":"2.00","state":"lose","created_at":"2024-01-16","source":"server"}],"next":{"a":{"b":1,"c":2}}}`))
+		case "/list":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`[{"id":1,"name":"main"},{"id":2,"name":"bonus"}]`))
+		default:
+			respondError(w, http.StatusNotFound, "user not found")
+		}
+	})
+	m := NewSparseFieldsMiddleware(next)
+
+	tests := []struct {
+		url      string
+		wantCode int
+		wantBody string
+	}{
+		{"/balance", http.StatusOK, `{"balance":"10.00","userId":1}` + "\n"},
+		{"/balance?fields=balance", http.StatusOK, `{"balance":"10.00"}` + "\n"},
+		{"/balance?fields=balance,missing", http.StatusOK, `{"balance":"10.00"}` + "\n"},
+		{"/history?fields=transactions(amount,state,created_at)", http.StatusOK,
+			`{"transactions":[{"amount":"1.00","state":"win","created_at":"2024-01-15"},{"amount":"2.00","state":"lose","created_at":"2024-01-16"}]}` + "\n"},
+		{"/history?fields=userId,next(a(c))", http.StatusOK, `{"userId":1,"next":{"a":{"c":2}}}` + "\n"},
+		{"/list?fields=name", http.StatusOK, `[{"name":"main"},{"name":"bonus"}]` + "\n"},
+		{"/missing?fields=balance", http.StatusNotFound, `{"error":"user not found","retryable":false}` + "\n"},
+		{"/balance?fields=", http.StatusBadRequest, `{"error":"` + errInvalidFields.Error() + `","retryable":false}` + "\n"},
+		{"/balance?fields=transactions(amount", http.StatusBadRequest, `{"error":"` + errInvalidFields.Error() + `","retryable":false}` + "\n"},
+		{"/balance?fields=a,,b", http.StatusBadRequest, `{"error":"` + errInvalidFields.Error() + `","retryable":false}` + "\n"},
+		{"/balance?fields=a)", http.StatusBadRequest, `{"error":"` + errInvalidFields.Error() + `","retryable":false}` + "\n"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest("GET", tt.url, nil))
+		if w.Code != tt.wantCode || w.Body.String() != tt.wantBody {
+			t.Errorf("%s: got %d %q, want %d %q", tt.url, w.Code, w.Body.String(), tt.wantCode, tt.wantBody)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package http
+
+import (
+	"math"
+	"net/http"
+
+	"assignment/internal/fx"
+	"assignment/internal/models"
+	"assignment/internal/money"
+	"assignment/internal/utils"
+)
+
+type FXHandlers struct {
+	rates *fx.Rates
+}
+
+func NewFXHandlers(rates *fx.Rates) *FXHandlers {
+	return &FXHandlers{
+		rates: rates,
+	}
+}
+
+// roundRate rounds a derived rate to 6 decimal places, the precision the ECB
+// publishes at most.
+func roundRate(rate float64) float64 {
+	return math.Round(rate*1e6) / 1e6
+}
+
+func (h *FXHandlers) HandleGetRates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	table := h.rates.Table(r.Context())
+	base := r.URL.Query().Get("base")
+	if base == "" {
+		base = table.Base
+	}
+	if err := utils.ValidateCurrency(base); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	baseRate, ok := table.Rates[base]
+	if !ok {
+		respondError(w, http.StatusBadRequest, "invalid currency: no exchange rate for "+base)
+		return
+	}
+
+	rates := make(map[string]float64, len(table.Rates))
+	for currency, rate := range table.Rates {
+		rates[currency] = roundRate(rate / baseRate)
+	}
+	respondJSON(w, models.ExchangeRates{Base: base, Rates: rates, AsOf: table.AsOf, Source: table.Source})
+}
+
+func (h *FXHandlers) HandleConvert(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	amount, from, to := query.Get("amount"), query.Get("from"), query.Get("to")
+	for _, currency := range []string{from, to} {
+		if err := utils.ValidateCurrency(currency); err != nil {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if err := money.ValidateAmount(amount, from); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rate, table, err := h.rates.Rate(r.Context(), from, to)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	value, _ := utils.ParseAmount(amount)
+	respondJSON(w, models.Conversion{
+		Amount:    money.Format(value, from),
+		From:      from,
+		To:        to,
+		Rate:      roundRate(rate),
+		Converted: money.Format(value*rate, to),
+		AsOf:      table.AsOf,
+		Source:    table.Source,
+	})
+}
@@ -0,0 +1,58 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"assignment/internal/clock"
+	"assignment/internal/fx"
+)
+
+type staticRates struct{}
+
+func (staticRates) Fetch(ctx context.Context) (*fx.Table, error) {
+	return &fx.Table{
+		Base:  "EUR",
+		Rates: map[string]float64{"USD": 1.1, "GBP": 0.88},
+		AsOf:  time.Date(2024, 1, 12, 0, 0, 0, 0, time.UTC),
+	}, nil
+}
+
+func TestFXHandlers(t *testing.T) {
+	rates := fx.NewRates(staticRates{}, clock.NewFake(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)), fx.Config{})
+	h := NewFXHandlers(rates)
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		target  string
+		want    int
+		body    string
+	}{
+		{"rates", h.HandleGetRates, "/fx/rates", http.StatusOK,
+			`{"base":"EUR","rates":{"EUR":1,"GBP":0.88,"USD":1.1},"asOf":"2024-01-12T00:00:00Z","source":"provider"}`},
+		{"rates rebased", h.HandleGetRates, "/fx/rates?base=USD", http.StatusOK,
+			`{"base":"USD","rates":{"EUR":0.909091,"GBP":0.8,"USD":1},"asOf":"2024-01-12T00:00:00Z","source":"provider"}`},
+		{"rates unknown base", h.HandleGetRates, "/fx/rates?base=CHF", http.StatusBadRequest,
+			`{"error":"invalid currency: no exchange rate for CHF","retryable":false}`},
+		{"convert", h.HandleConvert, "/fx/convert?amount=25&from=USD&to=GBP", http.StatusOK,
+			`{"amount":"25.00","from":"USD","to":"GBP","rate":0.8,"converted":"20.00","asOf":"2024-01-12T00:00:00Z","source":"provider"}`},
+		{"convert invalid currency", h.HandleConvert, "/fx/convert?amount=25&from=usd&to=GBP", http.StatusBadRequest,
+			`{"error":"invalid currency: must be a 3-letter ISO 4217 code","retryable":false}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tt.handler(rec, httptest.NewRequest("GET", tt.target, nil))
+			if rec.Code != tt.want {
+				t.Errorf("Expected status %d, got: %d", tt.want, rec.Code)
+			}
+			if body := rec.Body.String(); body != tt.body+"\n" {
+				t.Errorf("Unexpected body: %s", body)
+			}
+		})
+	}
+}
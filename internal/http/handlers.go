@@ -1,21 +1,30 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"assignment/internal/core"
 	"assignment/internal/models"
+	"assignment/internal/requestid"
+	"assignment/internal/statement"
 	"assignment/internal/utils"
 )
 
 type Handlers struct {
-	transactionService *core.TransactionService
+	transactionService core.TransactionProcessor
 }
 
-func NewHandlers(transactionService *core.TransactionService) *Handlers {
+// NewHandlers serves requests with transactionService, usually a
+// *core.TransactionService, possibly wrapped in decorators.
+func NewHandlers(transactionService core.TransactionProcessor) *Handlers {
 	return &Handlers{
 		transactionService: transactionService,
 	}
@@ -30,6 +39,122 @@ func extractUserID(path string) string {
 	return ""
 }
 
+func (h *Handlers) HandleCreateUser(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req models.CreateUserRequest
+	if err := decodeBody(r, "create_user", &req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	user, err := h.transactionService.CreateUser(req)
+	if err != nil {
+		respondUserError(w, err)
+		return
+	}
+
+	respondJSON(w, user)
+}
+
+func (h *Handlers) HandleGetUser(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// Get user ID from path
+	userIDStr := extractUserID(r.URL.Path)
+	userID, err := utils.ValidateUserID(userIDStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, err := h.transactionService.GetUser(userID)
+	if err != nil {
+		if errors.Is(err, core.ErrUserNotFound) {
+			respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		log.Printf("Error getting user: %v", err)
+		respondInternalError(w, err)
+		return
+	}
+
+	respondJSON(w, user)
+}
+
+func (h *Handlers) HandleUpdateUser(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// Get user ID from path
+	userIDStr := extractUserID(r.URL.Path)
+	userID, err := utils.ValidateUserID(userIDStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req models.UpdateUserRequest
+	if err := decodeBody(r, "update_user", &req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	user, err := h.transactionService.UpdateUser(userID, req)
+	if err != nil {
+		respondUserError(w, err)
+		return
+	}
+
+	respondJSON(w, user)
+}
+
+func (h *Handlers) HandleDeleteUser(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// Get user ID from path
+	userIDStr := extractUserID(r.URL.Path)
+	userID, err := utils.ValidateUserID(userIDStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, err := h.transactionService.DeleteUser(userID)
+	if err != nil {
+		respondUserError(w, err)
+		return
+	}
+
+	respondJSON(w, user)
+}
+
+// respondUserError maps errors from creating or updating a user to a status.
+func respondUserError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, core.ErrUserNotFound):
+		respondError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, core.ErrExternalRefInUse), errors.Is(err, core.ErrCurrencyInUse):
+		respondError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, core.ErrValidation):
+		respondError(w, http.StatusBadRequest, err.Error())
+	default:
+		log.Printf("Error saving user: %v", err)
+		respondInternalError(w, err)
+	}
+}
+
 func (h *Handlers) HandleTransaction(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
@@ -53,39 +178,98 @@ func (h *Handlers) HandleTransaction(w http.ResponseWriter, r *http.Request) {
 
 	// Parse request body
 	var req models.TransactionRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := decodeBody(r, "transaction", &req); err != nil {
 		respondError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
 		return
 	}
 
-	// Process transaction
-	response, err := h.transactionService.ProcessTransaction(userID, req, sourceType)
+	if err := h.transactionService.ValidateTransactionID(req.TransactionID); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Process transaction, only on the balance the client read if it says so
+	var response *models.TransactionResponse
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		response, err = h.transactionService.ProcessTransactionIf(r.Context(), userID, req, sourceType, func(tag string) bool {
+			return etagMatches(ifMatch, tag, false)
+		})
+	} else {
+		response, err = h.transactionService.ProcessTransactionContext(r.Context(), userID, req, sourceType)
+	}
 	if err != nil {
 		log.Printf("Error processing transaction: %v", err)
-		
-		// Check if it's a validation error (should return 400)
-		errMsg := err.Error()
-		if strings.Contains(errMsg, "invalid Source-Type header") ||
-		   strings.Contains(errMsg, "invalid state") ||
-		   strings.Contains(errMsg, "invalid amount format") ||
-		   strings.Contains(errMsg, "invalid amount: cannot parse") ||
-		   strings.Contains(errMsg, "invalid amount: cannot be negative") {
-			respondError(w, http.StatusBadRequest, errMsg)
+		if errors.Is(err, core.ErrPreconditionFailed) {
+			respondError(w, http.StatusPreconditionFailed, err.Error())
+			return
+		}
+		if errors.Is(err, core.ErrValidation) {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, core.ErrSignatureRequired) {
+			respondError(w, http.StatusUnauthorized, err.Error())
 			return
 		}
-		
+
 		// For other errors (like database errors), return 500
-		respondError(w, http.StatusInternalServerError, "Internal server error: "+err.Error())
+		respondInternalError(w, err)
 		return
 	}
 
-	// Check if it's a duplicate or insufficient funds response
-	if response.Message == "Duplicate transaction ignored" || response.Message == "Insufficient funds" {
-		w.WriteHeader(http.StatusOK)
-	} else {
-		w.WriteHeader(http.StatusOK)
+	// Duplicates and insufficient funds are reported with 200 as well
+	respondTransaction(w, response)
+}
+
+// respondTransaction answers with the outcome of a transaction. Retries of
+// an applied transaction get the response given then, marked with an
+// Idempotent-Replayed header.
+func respondTransaction(w http.ResponseWriter, response *models.TransactionResponse) {
+	if response.Replayed {
+		w.Header().Set("Idempotent-Replayed", "true")
 	}
+	respondJSON(w, response)
+}
 
+func (h *Handlers) HandleTransfer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	sourceType := r.Header.Get("Source-Type")
+	if sourceType == "" {
+		respondError(w, http.StatusBadRequest, "Source-Type header is required")
+		return
+	}
+
+	var req models.TransferRequest
+	if err := decodeBody(r, "transfer", &req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	response, err := h.transactionService.TransferContext(r.Context(), req, sourceType)
+	if err != nil {
+		errMsg := err.Error()
+		if errors.Is(err, core.ErrUserNotFound) {
+			respondError(w, http.StatusNotFound, errMsg)
+			return
+		}
+		if errors.Is(err, core.ErrValidation) {
+			respondError(w, http.StatusBadRequest, errMsg)
+			return
+		}
+		if errors.Is(err, core.ErrSignatureRequired) {
+			respondError(w, http.StatusUnauthorized, errMsg)
+			return
+		}
+		log.Printf("Error processing transfer: %v", err)
+		respondInternalError(w, err)
+		return
+	}
+
+	// Duplicates and insufficient funds are reported with 200 as well
 	respondJSON(w, response)
 }
 
@@ -103,22 +287,173 @@ func (h *Handlers) HandleGetBalance(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A past balance is reconstructed from the ledger
+	if v := r.URL.Query().Get("at"); v != "" {
+		at, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid at: must be an RFC 3339 timestamp")
+			return
+		}
+		response, err := h.transactionService.GetBalanceAt(userID, at)
+		if err != nil {
+			errMsg := err.Error()
+			if errors.Is(err, core.ErrUserNotFound) {
+				respondError(w, http.StatusNotFound, errMsg)
+				return
+			}
+			if errors.Is(err, core.ErrValidation) {
+				respondError(w, http.StatusBadRequest, errMsg)
+				return
+			}
+			log.Printf("Error getting balance at %s: %v", v, err)
+			respondInternalError(w, err)
+			return
+		}
+		respondJSON(w, response)
+		return
+	}
+
 	// Get balance
 	response, err := h.transactionService.GetBalance(userID)
 	if err != nil {
-		if err.Error() == "user not found" {
+		if errors.Is(err, core.ErrUserNotFound) {
 			respondError(w, http.StatusNotFound, err.Error())
 			return
 		}
 		log.Printf("Error getting balance: %v", err)
-		respondError(w, http.StatusInternalServerError, "Internal server error: "+err.Error())
+		respondInternalError(w, err)
+		return
+	}
+
+	// Let polling clients revalidate instead of downloading the balance again
+	etag := core.BalanceTag(response.UserID, response.Balance)
+	w.Header().Set("ETag", etag)
+	if etagMatches(r.Header.Get("If-None-Match"), etag, true) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	respondJSON(w, response)
+}
+
+// HandleGetBalances looks up the balances of several users at once. Users
+// not found are listed in the response instead of failing the request.
+func (h *Handlers) HandleGetBalances(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req models.BulkBalanceRequest
+	if err := decodeBody(r, "bulk_balances", &req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	response, err := h.transactionService.GetBalances(req.UserIDs)
+	if err != nil {
+		if errors.Is(err, core.ErrValidation) {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Printf("Error getting balances: %v", err)
+		respondInternalError(w, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
 	respondJSON(w, response)
 }
 
+func (h *Handlers) HandleGetBalanceHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// Get user ID from path
+	userIDStr := extractUserID(r.URL.Path)
+	userID, err := utils.ValidateUserID(userIDStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Optional RFC 3339 time range
+	var from, to time.Time
+	if v := r.URL.Query().Get("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid from: must be an RFC 3339 timestamp")
+			return
+		}
+	}
+	if v := r.URL.Query().Get("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid to: must be an RFC 3339 timestamp")
+			return
+		}
+	}
+
+	page, err := parsePage(r, core.MaxHistorySnapshots, core.MaxHistorySnapshots)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response, err := h.transactionService.GetBalanceHistoryPage(userID, from, to, page)
+	if err != nil {
+		errMsg := err.Error()
+		if errors.Is(err, core.ErrUserNotFound) {
+			respondError(w, http.StatusNotFound, errMsg)
+			return
+		}
+		if errors.Is(err, core.ErrValidation) {
+			respondError(w, http.StatusBadRequest, errMsg)
+			return
+		}
+		log.Printf("Error getting balance history: %v", err)
+		respondInternalError(w, err)
+		return
+	}
+
+	respondJSON(w, response)
+}
+
+func (h *Handlers) HandleGetStatement(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// Get user ID from path
+	userIDStr := extractUserID(r.URL.Path)
+	userID, err := utils.ValidateUserID(userIDStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	month, err := time.Parse("2006-01", r.URL.Query().Get("month"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid month: must be in YYYY-MM format")
+		return
+	}
+
+	st, err := h.transactionService.GetStatement(userID, month)
+	if err != nil {
+		if errors.Is(err, core.ErrUserNotFound) {
+			respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		log.Printf("Error building statement: %v", err)
+		respondInternalError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="statement-%d-%s.pdf"`, userID, st.Month))
+	w.Write(statement.RenderPDF(st))
+}
+
 func respondJSON(w http.ResponseWriter, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(data); err != nil {
@@ -126,9 +461,121 @@ func respondJSON(w http.ResponseWriter, data interface{}) {
 	}
 }
 
+// respondError writes an error response. "retryable" tells clients whether
+// the same request may succeed later; it follows from the status code.
+// "requestId" echoes the ID set by the request ID middleware, if any.
 func respondError(w http.ResponseWriter, statusCode int, message string) {
+	writeError(w, statusCode, errorBody(w, statusCode, message))
+}
+
+// Reasons given with 429 Too Many Requests and 503 Service Unavailable
+// responses, for clients to tell why they should back off.
+const (
+	reasonTransientFailure = "transient_failure"
+	reasonInjectedFault    = "injected_fault"
+	reasonCircuitOpen      = "circuit_open"
+)
+
+// transientRetryAfter is how long clients should wait before retrying a
+// request that failed transiently, e.g. on a lost database connection.
+const transientRetryAfter = time.Second
+
+// respondRetryLater answers a request turned away for now with statusCode,
+// 429 or 503. The Retry-After header tells clients how long to back off, in
+// whole seconds, and "reason" why the request was turned away, so they do
+// not retry in a tight loop.
+func respondRetryLater(w http.ResponseWriter, statusCode int, reason string, after time.Duration, message string) {
+	seconds := int64((after + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+	body := errorBody(w, statusCode, message)
+	body["reason"] = reason
+	writeError(w, statusCode, body)
+}
+
+func errorBody(w http.ResponseWriter, statusCode int, message string) map[string]interface{} {
+	body := map[string]interface{}{"error": message, "retryable": retryableStatus(statusCode)}
+	if id := w.Header().Get(requestid.Header); id != "" {
+		body["requestId"] = id
+	}
+	return body
+}
+
+func writeError(w http.ResponseWriter, statusCode int, body map[string]interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(map[string]string{"error": message})
+	json.NewEncoder(w).Encode(body)
+}
+
+// respondInternalError answers a failure the handler didn't anticipate:
+// 422 Unprocessable Entity when the database refused the write as breaking a
+// schema constraint, 504 Gateway Timeout when the request's deadline passed
+// (see NewDeadlineMiddleware), 503 Service Unavailable when it is retryable,
+// e.g. a lost database connection or an open circuit breaker, and 500
+// Internal Server Error otherwise.
+func respondInternalError(w http.ResponseWriter, err error) {
+	if constraint := core.AsConstraintError(err); constraint != nil {
+		respondError(w, http.StatusUnprocessableEntity, constraint.Error())
+		return
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		// The caller's deadline passed or it went away; the work was rolled back
+		respondError(w, http.StatusGatewayTimeout, "Deadline exceeded: "+err.Error())
+		return
+	}
+	if wait, ok := core.RetryAfter(err); ok {
+		respondRetryLater(w, http.StatusServiceUnavailable, reasonCircuitOpen, wait, "Service unavailable: "+err.Error())
+		return
+	}
+	if core.IsRetryable(err) {
+		respondRetryLater(w, http.StatusServiceUnavailable, reasonTransientFailure, transientRetryAfter, "Service unavailable: "+err.Error())
+		return
+	}
+	respondError(w, http.StatusInternalServerError, "Internal server error: "+err.Error())
 }
 
+// retryableStatus reports whether a request that failed with status may
+// succeed when retried unchanged.
+func retryableStatus(status int) bool {
+	switch status {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func (h *Handlers) HandleGetStatementSummary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// Get user ID from path
+	userIDStr := extractUserID(r.URL.Path)
+	userID, err := utils.ValidateUserID(userIDStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	month, err := time.Parse("2006-01", r.URL.Query().Get("month"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid month: must be in YYYY-MM format")
+		return
+	}
+
+	summary, err := h.transactionService.GetStatementSummary(userID, month)
+	if err != nil {
+		if errors.Is(err, core.ErrUserNotFound) {
+			respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		log.Printf("Error building statement summary: %v", err)
+		respondInternalError(w, err)
+		return
+	}
+
+	respondJSON(w, summary)
+}
@@ -4,47 +4,30 @@ import (
 	"bytes"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"assignment/internal/clock"
 	"assignment/internal/core"
+	"assignment/internal/db"
 	"assignment/internal/models"
-	_ "github.com/lib/pq"
+	"assignment/internal/testutil"
+	"github.com/lib/pq"
 )
 
+// testNow is the fixed time seen by handlers under test.
+var testNow = time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
 func setupTestHandlers(t *testing.T) (*Handlers, *sql.DB) {
-	connStr := "host=localhost user=postgres password=postgres dbname=assignment_test sslmode=disable"
-	db, err := sql.Open("postgres", connStr)
-	if err != nil {
-		t.Skipf("Skipping test: database not available: %v", err)
-	}
-	if err := db.Ping(); err != nil {
-		t.Skipf("Skipping test: database not available: %v", err)
-	}
-
-	// Clean up and setup test database
-	db.Exec("DROP TABLE IF EXISTS transactions CASCADE")
-	db.Exec("DROP TABLE IF EXISTS users CASCADE")
-	db.Exec(`CREATE TABLE users (
-		id BIGSERIAL PRIMARY KEY,
-		balance NUMERIC(10,2) NOT NULL DEFAULT 0,
-		created_at TIMESTAMP DEFAULT NOW(),
-		updated_at TIMESTAMP DEFAULT NOW()
-	)`)
-	db.Exec(`CREATE TABLE transactions (
-		id BIGSERIAL PRIMARY KEY,
-		user_id BIGINT REFERENCES users(id),
-		transaction_id TEXT UNIQUE,
-		state TEXT,
-		amount NUMERIC(10,2),
-		source_type TEXT,
-		applied BOOLEAN,
-		created_at TIMESTAMP DEFAULT NOW()
-	)`)
-	db.Exec("INSERT INTO users (id, balance) VALUES (1, 100.00), (2, 50.00), (3, 0.00)")
-
-	service := core.NewTransactionService(db)
+	db := testutil.NewDB(t)
+
+	service := core.NewTransactionService(db, clock.NewFake(testNow))
 	handlers := NewHandlers(service)
 
 	return handlers, db
@@ -142,3 +125,70 @@ func TestHandleGetBalance_UserNotFound(t *testing.T) {
 	}
 }
 
+
+func TestRespondInternalError(t *testing.T) {
+	tests := []struct {
+		err        error
+		wantStatus int
+		wantBody   string
+	}{
+		{&core.RetryableError{Err: errors.New("failed to generate a unique transaction ID")}, http.StatusServiceUnavailable,
+			`{"error":"Service unavailable: failed to generate a unique transaction ID","reason":"transient_failure","retryable":true}`},
+		{errors.New("failed to parse balance"), http.StatusInternalServerError,
+			`{"error":"Internal server error: failed to parse balance","retryable":false}`},
+		{fmt.Errorf("failed to update user balance: %w", &pq.Error{Code: "23514", Constraint: "users_balance_check"}), http.StatusUnprocessableEntity,
+			`{"error":"invalid balance: must not be negative","retryable":false}`},
+		{fmt.Errorf("failed to get user balance: %w", &db.CircuitOpenError{Wait: 4500 * time.Millisecond}), http.StatusServiceUnavailable,
+			`{"error":"Service unavailable: failed to get user balance: database unavailable: circuit breaker open","reason":"circuit_open","retryable":true}`},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		respondInternalError(w, tt.err)
+		if w.Code != tt.wantStatus || strings.TrimSpace(w.Body.String()) != tt.wantBody {
+			t.Errorf("respondInternalError(%v) = %d %s, want %d %s", tt.err, w.Code, w.Body, tt.wantStatus, tt.wantBody)
+		}
+		wantRetryAfter := ""
+		if tt.wantStatus == http.StatusServiceUnavailable {
+			wantRetryAfter = "1"
+			if wait, ok := core.RetryAfter(tt.err); ok {
+				wantRetryAfter = fmt.Sprint(int(math.Ceil(wait.Seconds())))
+			}
+		}
+		if retryAfter := w.Header().Get("Retry-After"); retryAfter != wantRetryAfter {
+			t.Errorf("respondInternalError(%v): unexpected Retry-After %q", tt.err, retryAfter)
+		}
+	}
+}
+
+// cachedBalances serves balances from a map, passing the rest through.
+type cachedBalances struct {
+	core.TransactionProcessor
+	balances map[int64]string
+}
+
+func (c cachedBalances) GetBalance(userID int64) (*models.BalanceResponse, error) {
+	if balance, ok := c.balances[userID]; ok {
+		return &models.BalanceResponse{UserID: userID, Balance: balance}, nil
+	}
+	return c.TransactionProcessor.GetBalance(userID)
+}
+
+func TestHandlers_DecoratedProcessor(t *testing.T) {
+	service := core.NewTransactionService(nil, clock.NewFake(testNow))
+	handlers := NewHandlers(cachedBalances{service, map[int64]string{1: "42.00"}})
+
+	req := httptest.NewRequest("GET", "/user/1/balance", nil)
+	w := httptest.NewRecorder()
+	handlers.HandleGetBalance(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got: %d %s", w.Code, w.Body)
+	}
+	var resp models.BalanceResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.UserID != 1 || resp.Balance != "42.00" {
+		t.Errorf("Expected the decorated balance, got: %+v", resp)
+	}
+}
@@ -0,0 +1,134 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ConcurrencyConfig bounds the requests each endpoint serves at once.
+type ConcurrencyConfig struct {
+	// Limit is the number of requests an endpoint serves concurrently; zero
+	// disables the limiter.
+	Limit int
+	// Queue is the number of requests that may wait for an endpoint to free
+	// up; requests beyond it are turned away at once.
+	Queue int
+	// QueueTimeout is how long a request waits in the queue before it is
+	// turned away.
+	QueueTimeout time.Duration
+}
+
+// Enabled reports whether requests are limited at all.
+func (c ConcurrencyConfig) Enabled() bool {
+	return c.Limit > 0
+}
+
+// reasonOverloaded is given with 503 responses to requests shed by the
+// concurrency limiter.
+const reasonOverloaded = "overloaded"
+
+type concurrencyLimit struct {
+	cfg  ConcurrencyConfig
+	next http.Handler
+
+	mu sync.Mutex
+	// endpoints holds the endpoints with requests in flight or waiting, by
+	// method and route; idle ones are dropped so unknown paths don't pile up
+	endpoints map[string]*endpointSlots
+}
+
+type endpointSlots struct {
+	slots   chan struct{}
+	waiting int
+	// users counts the requests holding or waiting for a slot
+	users int
+}
+
+// NewConcurrencyLimitMiddleware wraps next with a middleware that serves at
+// most cfg.Limit requests per endpoint (method and route, e.g. POST
+// /user/{id}/transaction) at once. Further requests wait in a queue of
+// cfg.Queue for up to cfg.QueueTimeout; when the queue is full or the wait
+// times out they are answered with 503 Service Unavailable, so a spike sheds
+// load instead of growing latency and database connections without bound.
+func NewConcurrencyLimitMiddleware(cfg ConcurrencyConfig, next http.Handler) http.Handler {
+	return &concurrencyLimit{cfg: cfg, next: next, endpoints: map[string]*endpointSlots{}}
+}
+
+func (c *concurrencyLimit) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := r.Method + " " + normalizeRoute(r.URL.Path)
+	e, ok := c.acquire(r, key)
+	if !ok {
+		switch r.Context().Err() {
+		case nil:
+			respondRetryLater(w, http.StatusServiceUnavailable, reasonOverloaded, transientRetryAfter, "Service unavailable: too many concurrent requests")
+		case context.DeadlineExceeded:
+			respondError(w, http.StatusGatewayTimeout, "Deadline exceeded: request deadline passed while queued")
+		}
+		return
+	}
+	defer c.release(key, e)
+
+	c.next.ServeHTTP(w, r)
+}
+
+// acquire takes a slot of the endpoint, waiting in its queue if they are all
+// taken, and reports whether it got one.
+func (c *concurrencyLimit) acquire(r *http.Request, key string) (*endpointSlots, bool) {
+	c.mu.Lock()
+	e := c.endpoints[key]
+	if e == nil {
+		e = &endpointSlots{slots: make(chan struct{}, c.cfg.Limit)}
+		c.endpoints[key] = e
+	}
+	e.users++
+	select {
+	case e.slots <- struct{}{}:
+		c.mu.Unlock()
+		return e, true
+	default:
+	}
+	if e.waiting >= c.cfg.Queue {
+		c.leave(key, e)
+		c.mu.Unlock()
+		return nil, false
+	}
+	e.waiting++
+	c.mu.Unlock()
+
+	timer := time.NewTimer(c.cfg.QueueTimeout)
+	defer timer.Stop()
+	acquired := false
+	select {
+	case e.slots <- struct{}{}:
+		acquired = true
+	case <-timer.C:
+	case <-r.Context().Done():
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e.waiting--
+	if !acquired {
+		c.leave(key, e)
+		return nil, false
+	}
+	return e, true
+}
+
+func (c *concurrencyLimit) release(key string, e *endpointSlots) {
+	<-e.slots
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.leave(key, e)
+}
+
+// leave drops a request from the endpoint, and the endpoint once it is
+// idle. c.mu must be held.
+func (c *concurrencyLimit) leave(key string, e *endpointSlots) {
+	e.users--
+	if e.users == 0 {
+		delete(c.endpoints, key)
+	}
+}
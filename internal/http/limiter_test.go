@@ -0,0 +1,102 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingHandler holds requests until release is closed.
+func blockingHandler(started chan<- struct{}, release <-chan struct{}) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func TestConcurrencyLimit_ShedsWhenQueueIsFull(t *testing.T) {
+	started, release := make(chan struct{}, 10), make(chan struct{})
+	limiter := NewConcurrencyLimitMiddleware(ConcurrencyConfig{Limit: 1, Queue: 1, QueueTimeout: time.Minute}, blockingHandler(started, release))
+
+	var wg sync.WaitGroup
+	codes := make([]int, 2)
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			limiter.ServeHTTP(w, httptest.NewRequest("POST", "/user/1/transaction", nil))
+			codes[i] = w.Code
+		}(i)
+		if i == 0 {
+			<-started
+		}
+	}
+	// Wait for the second request to queue up
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		l := limiter.(*concurrencyLimit)
+		l.mu.Lock()
+		waiting := l.endpoints["POST /user/{id}/transaction"].waiting
+		l.mu.Unlock()
+		if waiting == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected a request to wait in the queue")
+		}
+	}
+
+	// Another user's transactions share the endpoint and are shed
+	w := httptest.NewRecorder()
+	limiter.ServeHTTP(w, httptest.NewRequest("POST", "/user/2/transaction", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status 503, got: %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "1" || !strings.Contains(w.Body.String(), `"reason":"overloaded"`) {
+		t.Errorf("Expected back-off guidance, got: %v %s", w.Header(), w.Body)
+	}
+
+	// Other endpoints have slots of their own
+	go func() { <-started }()
+	done := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		limiter.ServeHTTP(w, httptest.NewRequest("GET", "/user/1/balance", nil))
+		done <- w.Code
+	}()
+
+	close(release)
+	if code := <-done; code != http.StatusOK {
+		t.Errorf("Expected status 200 for another endpoint, got: %d", code)
+	}
+	wg.Wait()
+	if codes[0] != http.StatusOK || codes[1] != http.StatusOK {
+		t.Errorf("Expected the running and queued requests to succeed, got: %v", codes)
+	}
+	if n := len(limiter.(*concurrencyLimit).endpoints); n != 0 {
+		t.Errorf("Expected idle endpoints to be dropped, got: %d", n)
+	}
+}
+
+func TestConcurrencyLimit_QueueTimeout(t *testing.T) {
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	defer close(release)
+	limiter := NewConcurrencyLimitMiddleware(ConcurrencyConfig{Limit: 1, Queue: 5, QueueTimeout: 20 * time.Millisecond}, blockingHandler(started, release))
+
+	go limiter.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/user/1/balance", nil))
+	<-started
+
+	start := time.Now()
+	w := httptest.NewRecorder()
+	limiter.ServeHTTP(w, httptest.NewRequest("GET", "/user/1/balance", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got: %d", w.Code)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("Expected the request to wait for the queue timeout, got: %s", elapsed)
+	}
+}
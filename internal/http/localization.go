@@ -0,0 +1,127 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"assignment/internal/i18n"
+)
+
+type localization struct {
+	next http.Handler
+}
+
+// NewLocalizationMiddleware wraps next with a middleware translating the
+// "error" and "message" of JSON responses into the language of the
+// Accept-Language header, and adding their stable "code". Handlers keep
+// writing English, which is what the inner middlewares see.
+func NewLocalizationMiddleware(next http.Handler) http.Handler {
+	return &localization{next: next}
+}
+
+// localizingWriter holds back JSON responses to rewrite them, and passes
+// anything else through.
+type localizingWriter struct {
+	http.ResponseWriter
+	status    int
+	buffering bool
+	body      bytes.Buffer
+}
+
+func (l *localizingWriter) WriteHeader(status int) {
+	if l.status != 0 {
+		return
+	}
+	l.status = status
+	l.buffering = strings.HasPrefix(l.Header().Get("Content-Type"), "application/json")
+	if !l.buffering {
+		l.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (l *localizingWriter) Write(p []byte) (int, error) {
+	if l.status == 0 {
+		l.WriteHeader(http.StatusOK)
+	}
+	if l.buffering {
+		return l.body.Write(p)
+	}
+	return l.ResponseWriter.Write(p)
+}
+
+func (lm *localization) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	lang := i18n.Negotiate(r.Header.Get("Accept-Language"))
+	w.Header().Add("Vary", "Accept-Language")
+
+	lw := &localizingWriter{ResponseWriter: w}
+	lm.next.ServeHTTP(lw, r)
+	if !lw.buffering {
+		return
+	}
+
+	w.Header().Set("Content-Language", lang)
+	w.Header().Del("Content-Length")
+	w.WriteHeader(lw.status)
+	w.Write(localizeBody(lw.body.Bytes(), lang))
+}
+
+// localizeBody translates the "error" and "message" of a JSON object, adding
+// a "code" after each translated one. Other bodies, and fields not in the
+// catalog, are returned unchanged; the order of fields is kept.
+func localizeBody(body []byte, lang string) []byte {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return body
+	}
+
+	type field struct {
+		key   string
+		value json.RawMessage
+	}
+	var fields []field
+	hasCode, changed := false, false
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return body
+		}
+		key, _ := tok.(string)
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return body
+		}
+		fields = append(fields, field{key, value})
+		hasCode = hasCode || key == "code"
+	}
+
+	var out bytes.Buffer
+	out.WriteByte('{')
+	for i, f := range fields {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		var text string
+		if (f.key == "error" || f.key == "message") && json.Unmarshal(f.value, &text) == nil {
+			if code, translated, ok := i18n.Translate(text, lang); ok {
+				f.value, _ = json.Marshal(translated)
+				if !hasCode {
+					code, _ := json.Marshal(code)
+					f.value = append(append(f.value, `,"code":`...), code...)
+					hasCode = true
+				}
+				changed = true
+			}
+		}
+		key, _ := json.Marshal(f.key)
+		out.Write(key)
+		out.WriteByte(':')
+		out.Write(f.value)
+	}
+	out.WriteString("}\n")
+	if !changed {
+		return body
+	}
+	return out.Bytes()
+}
@@ -0,0 +1,48 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"assignment/internal/models"
+)
+
+func TestLocalizationMiddleware(t *testing.T) {
+	m := NewLocalizationMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/transaction":
+			respondJSON(w, models.TransactionResponse{UserID: 1, TransactionID: "t1", Balance: "5.00", Message: "Insufficient funds"})
+		case "/error":
+			respondError(w, http.StatusBadRequest, "Invalid request body: unexpected EOF")
+		case "/unknown":
+			respondError(w, http.StatusNotFound, "escrow not found")
+		default:
+			w.Header().Set("Content-Type", "text/csv")
+			w.Write([]byte("id,amount\n"))
+		}
+	}))
+
+	tests := []struct {
+		path, lang string
+		wantStatus int
+		wantBody   string
+	}{
+		{"/transaction", "de-DE,de;q=0.9", http.StatusOK, `{"userId":1,"transactionId":"t1","balance":"5.00","message":"Unzureichendes Guthaben","code":"insufficient_funds"}` + "\n"},
+		{"/transaction", "", http.StatusOK, `{"userId":1,"transactionId":"t1","balance":"5.00","message":"Insufficient funds","code":"insufficient_funds"}` + "\n"},
+		{"/error", "fr", http.StatusBadRequest, `{"error":"Corps de requête invalide : unexpected EOF","code":"invalid_request_body","retryable":false}` + "\n"},
+		{"/unknown", "fr", http.StatusNotFound, `{"error":"escrow not found","retryable":false}` + "\n"},
+		{"/export", "de", http.StatusOK, "id,amount\n"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		if tt.lang != "" {
+			req.Header.Set("Accept-Language", tt.lang)
+		}
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, req)
+		if rec.Code != tt.wantStatus || rec.Body.String() != tt.wantBody {
+			t.Errorf("%s (%s): got %d %s, want %d %s", tt.path, tt.lang, rec.Code, rec.Body, tt.wantStatus, tt.wantBody)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"assignment/internal/metrics"
+)
+
+type requestMetrics struct {
+	m    metrics.Metrics
+	next http.Handler
+}
+
+// NewMetricsMiddleware wraps next with a middleware counting requests
+// (http_requests_total) and timing them (http_request_duration_seconds) by
+// method, route and status.
+func NewMetricsMiddleware(m metrics.Metrics, next http.Handler) http.Handler {
+	return &requestMetrics{m: m, next: next}
+}
+
+func (rm *requestMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	rec := &statusRecorder{ResponseWriter: w}
+	rm.next.ServeHTTP(rec, r)
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+
+	route := normalizeRoute(r.URL.Path)
+	rm.m.Inc("http_requests_total", metrics.Labels{"method": r.Method, "route": route, "status": strconv.Itoa(rec.status)})
+	rm.m.Timing("http_request_duration_seconds", time.Since(start), metrics.Labels{"method": r.Method, "route": route})
+}
+
+// normalizeRoute replaces the IDs and names in a path with placeholders, e.g.
+// /user/42/wallet/savings/transaction becomes
+// /user/{id}/wallet/{name}/transaction, so each route is a single series.
+func normalizeRoute(path string) string {
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if i == 0 || part == "" {
+			continue
+		}
+		if _, err := strconv.ParseInt(part, 10, 64); err == nil {
+			parts[i] = "{id}"
+			continue
+		}
+		switch parts[i-1] {
+		case "wallet":
+			if part != "move" {
+				parts[i] = "{name}"
+			}
+		case "devices", "tags":
+			parts[i] = "{name}"
+		}
+	}
+	return strings.Join(parts, "/")
+}
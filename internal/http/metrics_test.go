@@ -0,0 +1,56 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"assignment/internal/metrics"
+)
+
+func TestNormalizeRoute(t *testing.T) {
+	tests := map[string]string{
+		"/user/42":                              "/user/{id}",
+		"/user/42/wallet":                       "/user/{id}/wallet",
+		"/user/42/wallet/move":                  "/user/{id}/wallet/move",
+		"/user/42/wallet/savings/transaction":   "/user/{id}/wallet/{name}/transaction",
+		"/user/42/devices/abc123":               "/user/{id}/devices/{name}",
+		"/admin/users/7/tags/vip":               "/admin/users/{id}/tags/{name}",
+		"/admin/webhooks/dead-letters/3/replay": "/admin/webhooks/dead-letters/{id}/replay",
+		"/health":                               "/health",
+	}
+	for path, want := range tests {
+		if got := normalizeRoute(path); got != want {
+			t.Errorf("normalizeRoute(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	p := metrics.NewPrometheus()
+	m := NewMetricsMiddleware(p, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/user/2" {
+			respondError(w, http.StatusNotFound, "user not found")
+			return
+		}
+		w.Write([]byte("OK"))
+	}))
+
+	for _, path := range []string{"/user/1", "/user/1", "/user/2"} {
+		m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		`http_requests_total{method="GET",route="/user/{id}",status="200"} 2`,
+		`http_requests_total{method="GET",route="/user/{id}",status="404"} 1`,
+		`http_request_duration_seconds_count{method="GET",route="/user/{id}"} 3`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package http
+
+import (
+	"net/http"
+
+	"assignment/internal/models"
+	"assignment/internal/utils"
+)
+
+func (h *Handlers) HandleGetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, err := utils.ValidateUserID(extractUserID(r.URL.Path))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	prefs, err := h.transactionService.GetNotificationPreferences(userID)
+	if err != nil {
+		respondUserError(w, err)
+		return
+	}
+
+	respondJSON(w, prefs)
+}
+
+func (h *Handlers) HandleSetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, err := utils.ValidateUserID(extractUserID(r.URL.Path))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req models.NotificationPreferencesRequest
+	if err := decodeBody(r, "notification_preferences", &req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	prefs, err := h.transactionService.SetNotificationPreferences(userID, req)
+	if err != nil {
+		respondUserError(w, err)
+		return
+	}
+
+	respondJSON(w, prefs)
+}
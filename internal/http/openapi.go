@@ -0,0 +1,102 @@
+package http
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"assignment/internal/schema"
+)
+
+// bodyRoute is an endpoint whose JSON body is validated against a schema.
+type bodyRoute struct {
+	method  string
+	path    string
+	summary string
+	schema  string
+}
+
+// bodyRoutes are the endpoints the OpenAPI document describes. The schema of
+// each must be the one its handler passes to decodeBody.
+var bodyRoutes = []bodyRoute{
+	{"POST", "/user", "Create a user", "create_user"},
+	{"PATCH", "/user/{userId}", "Update a user's profile", "update_user"},
+	{"POST", "/user/{userId}/transaction", "Apply a transaction", "transaction"},
+	{"POST", "/user/{userId}/wallet", "Create a wallet", "create_wallet"},
+	{"POST", "/user/{userId}/wallet/{walletId}/transaction", "Apply a transaction to a wallet", "transaction"},
+	{"POST", "/user/{userId}/wallet/move", "Move funds between wallets", "wallet_move"},
+	{"POST", "/user/{userId}/deposit/intent", "Start a deposit", "deposit_intent"},
+	{"POST", "/user/{userId}/withdrawal", "Withdraw funds", "withdrawal"},
+	{"POST", "/user/{userId}/devices", "Register a push device", "push_device"},
+	{"PUT", "/user/{userId}/notifications", "Set notification preferences", "notification_preferences"},
+	{"POST", "/transfer", "Transfer funds between users", "transfer"},
+	{"POST", "/balances", "Look up the balances of several users", "bulk_balances"},
+	{"POST", "/escrow", "Create an escrow account", "create_escrow"},
+	{"POST", "/escrow/{name}/hold", "Move funds from a user into escrow", "escrow"},
+	{"POST", "/escrow/{name}/release", "Release funds from escrow to a user", "escrow"},
+	{"POST", "/admin/users/{userId}/status", "Change a user's status", "user_status"},
+	{"POST", "/admin/users/{userId}/kyc", "Change a user's KYC level", "kyc_level"},
+	{"POST", "/admin/users/{userId}/max-balance", "Set a user's maximum balance", "max_balance"},
+	{"POST", "/admin/users/{userId}/freeze", "Freeze a user", "freeze"},
+	{"POST", "/admin/users/{userId}/tags", "Tag a user", "add_user_tags"},
+	{"POST", "/admin/aml/flags/{id}/resolve", "Resolve an AML flag", "resolve_aml_flag"},
+	{"POST", "/admin/disputes", "Open a dispute against a transaction", "open_dispute"},
+	{"POST", "/admin/disputes/{id}/resolve", "Resolve a dispute", "resolve_dispute"},
+	{"POST", "/admin/deposits/{depositId}/chargeback", "Charge back a deposit", "chargeback"},
+}
+
+var pathParam = regexp.MustCompile(`\{(\w+)\}`)
+
+// openAPIDocument builds an OpenAPI 3.1 document of the request bodies, with
+// the schemas the bodies are validated against as its components.
+func openAPIDocument() ([]byte, error) {
+	components := map[string]json.RawMessage{}
+	for name, doc := range schema.Documents() {
+		components[name] = doc
+	}
+
+	paths := map[string]map[string]interface{}{}
+	for _, route := range bodyRoutes {
+		if paths[route.path] == nil {
+			paths[route.path] = map[string]interface{}{}
+		}
+		var params []map[string]interface{}
+		for _, m := range pathParam.FindAllStringSubmatch(route.path, -1) {
+			params = append(params, map[string]interface{}{
+				"name":     m[1],
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]string{"type": "string"},
+			})
+		}
+		operation := map[string]interface{}{
+			"summary": route.summary,
+			"requestBody": map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{
+						"schema": map[string]string{"$ref": "#/components/schemas/" + route.schema},
+					},
+				},
+			},
+			"responses": map[string]interface{}{
+				"200": map[string]string{"description": "Success"},
+				"400": map[string]string{"description": "Invalid request, e.g. a body not matching the schema"},
+			},
+		}
+		if len(params) > 0 {
+			operation["parameters"] = params
+		}
+		paths[route.path][strings.ToLower(route.method)] = operation
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"openapi": "3.1.0",
+		"info": map[string]string{
+			"title":   "Wallet API",
+			"version": "1.0.0",
+		},
+		"paths":      paths,
+		"components": map[string]interface{}{"schemas": components},
+	})
+}
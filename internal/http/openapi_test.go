@@ -0,0 +1,56 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"assignment/internal/schema"
+)
+
+func TestOpenAPIDocument(t *testing.T) {
+	static, err := NewStaticHandler()
+	if err != nil {
+		t.Fatalf("Failed to build static handler: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	static.ServeHTTP(rec, httptest.NewRequest("GET", "/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got: %d", rec.Code)
+	}
+
+	var doc struct {
+		OpenAPI    string                                       `json:"openapi"`
+		Paths      map[string]map[string]map[string]interface{} `json:"paths"`
+		Components struct {
+			Schemas map[string]map[string]interface{} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("Failed to decode document: %v", err)
+	}
+	if doc.OpenAPI != "3.1.0" {
+		t.Errorf("Expected OpenAPI 3.1.0, got: %s", doc.OpenAPI)
+	}
+	if _, ok := doc.Paths["/user/{userId}/transaction"]["post"]; !ok {
+		t.Errorf("Expected POST /user/{userId}/transaction in document")
+	}
+
+	// Every schema is published and used by a route
+	used := map[string]bool{}
+	for _, route := range bodyRoutes {
+		if _, ok := schema.Documents()[route.schema]; !ok {
+			t.Errorf("Route %s %s uses unknown schema %s", route.method, route.path, route.schema)
+		}
+		used[route.schema] = true
+	}
+	for name := range schema.Documents() {
+		if !used[name] {
+			t.Errorf("Schema %s is not used by any route", name)
+		}
+		if doc.Components.Schemas[name]["title"] == nil {
+			t.Errorf("Schema %s missing from components", name)
+		}
+	}
+}
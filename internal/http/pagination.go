@@ -0,0 +1,32 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"assignment/internal/core"
+	"assignment/internal/cursor"
+)
+
+// parsePage reads the "limit" and "cursor" query parameters of a listing
+// request. limit defaults to def and may be at most max; cursor is a token
+// from the nextCursor or prevCursor of a previous page.
+func parsePage(r *http.Request, def, max int) (core.Page, error) {
+	page := core.Page{Limit: def}
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > max {
+			return page, fmt.Errorf("invalid limit: must be an integer between 1 and %d", max)
+		}
+		page.Limit = n
+	}
+	if v := r.URL.Query().Get("cursor"); v != "" {
+		c, err := cursor.Parse(v)
+		if err != nil {
+			return page, err
+		}
+		page.Cursor = c
+	}
+	return page, nil
+}
@@ -0,0 +1,145 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ProblemContentType is the media type of RFC 7807 Problem Details.
+const ProblemContentType = "application/problem+json"
+
+// ProblemTypePrefix starts the "type" of problems with a stable code; the
+// code follows it. Problems without a code have the type "about:blank".
+const ProblemTypePrefix = "urn:wallet:problem:"
+
+type problemDetails struct {
+	next http.Handler
+}
+
+// NewProblemDetailsMiddleware wraps next with a middleware answering errors
+// as RFC 7807 Problem Details to clients preferring application/problem+json
+// in their Accept header. Other clients keep getting the plain error body.
+// It has to wrap the localization middleware to see the message codes.
+func NewProblemDetailsMiddleware(next http.Handler) http.Handler {
+	return &problemDetails{next: next}
+}
+
+// problemWriter holds back JSON error responses to rewrite them, and passes
+// anything else through.
+type problemWriter struct {
+	http.ResponseWriter
+	status    int
+	buffering bool
+	body      bytes.Buffer
+}
+
+func (p *problemWriter) WriteHeader(status int) {
+	if p.status != 0 {
+		return
+	}
+	p.status = status
+	p.buffering = status >= 400 && strings.HasPrefix(p.Header().Get("Content-Type"), "application/json")
+	if !p.buffering {
+		p.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (p *problemWriter) Write(b []byte) (int, error) {
+	if p.status == 0 {
+		p.WriteHeader(http.StatusOK)
+	}
+	if p.buffering {
+		return p.body.Write(b)
+	}
+	return p.ResponseWriter.Write(b)
+}
+
+func (pm *problemDetails) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Vary", "Accept")
+	if !prefersProblem(r.Header.Get("Accept")) {
+		pm.next.ServeHTTP(w, r)
+		return
+	}
+
+	pw := &problemWriter{ResponseWriter: w}
+	pm.next.ServeHTTP(pw, r)
+	if !pw.buffering {
+		return
+	}
+
+	body, ok := problemBody(pw.body.Bytes(), pw.status, r.URL.RequestURI())
+	if ok {
+		w.Header().Set("Content-Type", ProblemContentType)
+	} else {
+		body = pw.body.Bytes()
+	}
+	w.Header().Del("Content-Length")
+	w.WriteHeader(pw.status)
+	w.Write(body)
+}
+
+// prefersProblem reports whether accept ranks application/problem+json at
+// least as high as application/json. Wildcards don't count: clients have to
+// ask for Problem Details explicitly.
+func prefersProblem(accept string) bool {
+	var problemQ, jsonQ float64
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				parsed, err := strconv.ParseFloat(value, 64)
+				if err != nil {
+					parsed = 0
+				}
+				q = parsed
+			}
+		}
+		switch strings.ToLower(strings.TrimSpace(mediaType)) {
+		case ProblemContentType:
+			problemQ = q
+		case "application/json":
+			jsonQ = q
+		}
+	}
+	return problemQ > 0 && problemQ >= jsonQ
+}
+
+// problemBody turns an error body such as {"error":"...","code":"...",
+// "retryable":false} into Problem Details: the message becomes "detail", the
+// code makes up "type", and the remaining fields are kept as extension
+// members. ok is false if body isn't a JSON object with an "error".
+func problemBody(body []byte, status int, instance string) ([]byte, bool) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, false
+	}
+	detail, ok := fields["error"]
+	if !ok {
+		return nil, false
+	}
+	delete(fields, "error")
+
+	problemType := "about:blank"
+	var code string
+	if json.Unmarshal(fields["code"], &code) == nil && code != "" {
+		problemType = ProblemTypePrefix + code
+	}
+	set := func(key string, value interface{}) {
+		fields[key], _ = json.Marshal(value)
+	}
+	set("type", problemType)
+	set("title", http.StatusText(status))
+	set("status", status)
+	set("instance", instance)
+	fields["detail"] = detail
+
+	out, err := json.Marshal(fields)
+	if err != nil {
+		return nil, false
+	}
+	return append(out, '\n'), true
+}
@@ -0,0 +1,54 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProblemDetailsMiddleware(t *testing.T) {
+	m := NewProblemDetailsMiddleware(NewLocalizationMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/error":
+			w.Header().Set("X-Request-ID", "req-1")
+			respondError(w, http.StatusBadRequest, "Invalid request body: unexpected EOF")
+		case "/unknown":
+			respondError(w, http.StatusNotFound, "escrow not found")
+		default:
+			respondJSON(w, map[string]string{"status": "ok"})
+		}
+	})))
+
+	tests := []struct {
+		path, accept, lang string
+		wantType, wantBody string
+	}{
+		{"/error", "application/problem+json", "",
+			ProblemContentType, `{"code":"invalid_request_body","detail":"Invalid request body: unexpected EOF","instance":"/error","requestId":"req-1","retryable":false,"status":400,"title":"Bad Request","type":"urn:wallet:problem:invalid_request_body"}` + "\n"},
+		{"/error", "application/json;q=0.5, application/problem+json", "de",
+			ProblemContentType, `{"code":"invalid_request_body","detail":"Ungültiger Anfrageinhalt: unexpected EOF","instance":"/error","requestId":"req-1","retryable":false,"status":400,"title":"Bad Request","type":"urn:wallet:problem:invalid_request_body"}` + "\n"},
+		{"/unknown?x=1", "application/problem+json", "",
+			ProblemContentType, `{"detail":"escrow not found","instance":"/unknown?x=1","retryable":false,"status":404,"title":"Not Found","type":"about:blank"}` + "\n"},
+		{"/unknown", "application/json, application/problem+json;q=0.9", "",
+			"application/json", `{"error":"escrow not found","retryable":false}` + "\n"},
+		{"/unknown", "*/*", "",
+			"application/json", `{"error":"escrow not found","retryable":false}` + "\n"},
+		{"/ok", "application/problem+json", "",
+			"application/json", `{"status":"ok"}` + "\n"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.Header.Set("Accept", tt.accept)
+		if tt.lang != "" {
+			req.Header.Set("Accept-Language", tt.lang)
+		}
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, req)
+		if got := rec.Header().Get("Content-Type"); got != tt.wantType {
+			t.Errorf("%s (%s): Content-Type %q, want %q", tt.path, tt.accept, got, tt.wantType)
+		}
+		if rec.Body.String() != tt.wantBody {
+			t.Errorf("%s (%s): got %s, want %s", tt.path, tt.accept, rec.Body, tt.wantBody)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package http
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"assignment/internal/core"
+	"assignment/internal/reporting"
+	"assignment/internal/utils"
+)
+
+type ReportingHandlers struct {
+	transactionService *core.TransactionService
+	signer             *reporting.Signer
+}
+
+func NewReportingHandlers(transactionService *core.TransactionService, signer *reporting.Signer) *ReportingHandlers {
+	return &ReportingHandlers{
+		transactionService: transactionService,
+		signer:             signer,
+	}
+}
+
+// HandleGetAuditTrail returns the signed audit bundle of a user for the UTC
+// calendar days from through to, in the format asked for (csv by default).
+func (h *ReportingHandlers) HandleGetAuditTrail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, err := utils.ValidateUserID(extractAdminUserID(r.URL.Path))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	query := r.URL.Query()
+	from, err := time.Parse("2006-01-02", query.Get("from"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid from: must be in YYYY-MM-DD format")
+		return
+	}
+	to, err := time.Parse("2006-01-02", query.Get("to"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid to: must be in YYYY-MM-DD format")
+		return
+	}
+	if to.Before(from) {
+		respondError(w, http.StatusBadRequest, "invalid period: to must not be before from")
+		return
+	}
+	format := query.Get("format")
+	if format == "" {
+		format = reporting.FormatCSV
+	}
+	if format != reporting.FormatCSV && format != reporting.FormatXML {
+		respondError(w, http.StatusBadRequest, "invalid format: must be 'csv' or 'xml'")
+		return
+	}
+
+	trail, err := h.transactionService.GetAuditTrail(userID, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		respondUserError(w, err)
+		return
+	}
+	bundle, err := h.signer.Bundle(trail, format, time.Now())
+	if err != nil {
+		log.Printf("Error building audit bundle: %v", err)
+		respondInternalError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="audit-trail-%d-%s-%s.zip"`, userID, from.Format("2006-01-02"), to.Format("2006-01-02")))
+	w.Write(bundle)
+}
@@ -0,0 +1,46 @@
+package http
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"assignment/internal/requestid"
+)
+
+type requestIDs struct {
+	next http.Handler
+}
+
+// NewRequestIDMiddleware wraps next with a middleware giving every request
+// an ID: the client's X-Request-ID if it is valid, or a generated one. The ID
+// is set on the request for the inner middlewares, carried by its context
+// to outgoing calls, echoed in the X-Request-ID response header and in error
+// bodies, and logged with the outcome of the request.
+func NewRequestIDMiddleware(next http.Handler) http.Handler {
+	return &requestIDs{next: next}
+}
+
+func (m *requestIDs) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := r.Header.Get(requestid.Header)
+	if !requestid.Valid(id) {
+		id = requestid.New()
+		r.Header.Set(requestid.Header, id)
+	}
+	r = r.WithContext(requestid.WithID(r.Context(), id))
+	w.Header().Set(requestid.Header, id)
+
+	start := time.Now()
+	rec := &statusRecorder{ResponseWriter: w}
+	defer func() {
+		if p := recover(); p != nil {
+			log.Printf("%s %s panicked request_id=%s", r.Method, r.URL.Path, id)
+			panic(p)
+		}
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		log.Printf("%s %s %d %s request_id=%s", r.Method, r.URL.Path, rec.status, time.Since(start).Round(time.Millisecond), id)
+	}()
+	m.next.ServeHTTP(rec, r)
+}
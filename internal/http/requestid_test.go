@@ -0,0 +1,44 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"assignment/internal/requestid"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var seen string
+	m := NewRequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = requestid.FromContext(r.Context())
+		respondError(w, http.StatusNotFound, "user not found")
+	}))
+
+	// A valid client ID is kept and echoed
+	req := httptest.NewRequest("GET", "/user/9", nil)
+	req.Header.Set("X-Request-ID", "gateway-123")
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, req)
+	if seen != "gateway-123" || rec.Header().Get("X-Request-ID") != "gateway-123" {
+		t.Errorf("Expected client ID to be kept, got context %q and header %q", seen, rec.Header().Get("X-Request-ID"))
+	}
+	if want := `{"error":"user not found","requestId":"gateway-123","retryable":false}`; strings.TrimSpace(rec.Body.String()) != want {
+		t.Errorf("Expected error body %s, got: %s", want, rec.Body)
+	}
+
+	// Missing and unsafe IDs are replaced
+	for _, id := range []string{"", "evil\nlog line", strings.Repeat("x", 129)} {
+		req := httptest.NewRequest("GET", "/user/9", nil)
+		if id != "" {
+			req.Header.Set("X-Request-ID", id)
+		}
+		rec := httptest.NewRecorder()
+		m.ServeHTTP(rec, req)
+		got := rec.Header().Get("X-Request-ID")
+		if got == id || len(got) != 26 || seen != got {
+			t.Errorf("Expected a generated ULID for %q, got header %q and context %q", id, got, seen)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package http
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Router dispatches requests by method and path pattern. A pattern is made
+// of literal segments and {name} segments, which match any one non-empty
+// segment; handlers read the values from the path themselves. When several
+// patterns match, the one with the most literal segments wins.
+//
+// For every path a pattern matches, OPTIONS is answered with the methods
+// registered for it in Allow, and other methods with 405 Method Not Allowed
+// and Allow. Paths no pattern matches are 404 Not Found.
+type Router struct {
+	routes []route
+}
+
+type route struct {
+	method   string
+	segments []string
+	literals int
+	handler  http.Handler
+}
+
+// NewRouter returns a router without routes.
+func NewRouter() *Router {
+	return &Router{}
+}
+
+// Handle registers handler for requests with method to paths matching pattern.
+func (rt *Router) Handle(method, pattern string, handler http.Handler) {
+	segments := strings.Split(strings.Trim(pattern, "/"), "/")
+	literals := 0
+	for _, s := range segments {
+		if !isParam(s) {
+			literals++
+		}
+	}
+	rt.routes = append(rt.routes, route{method: method, segments: segments, literals: literals, handler: handler})
+}
+
+// HandleFunc registers a handler function like Handle.
+func (rt *Router) HandleFunc(method, pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	rt.Handle(method, pattern, http.HandlerFunc(handler))
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	var best *route
+	allowed := map[string]bool{}
+	for i := range rt.routes {
+		candidate := &rt.routes[i]
+		if !candidate.matches(segments) {
+			continue
+		}
+		allowed[candidate.method] = true
+		if candidate.method == r.Method && (best == nil || candidate.literals > best.literals) {
+			best = candidate
+		}
+	}
+
+	switch {
+	case best != nil:
+		best.handler.ServeHTTP(w, r)
+	case len(allowed) == 0:
+		http.NotFound(w, r)
+	case r.Method == http.MethodOptions:
+		w.Header().Set("Allow", allowHeader(allowed))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", allowHeader(allowed))
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+func (rt *route) matches(segments []string) bool {
+	if len(segments) != len(rt.segments) {
+		return false
+	}
+	for i, s := range rt.segments {
+		if isParam(s) {
+			if segments[i] == "" {
+				return false
+			}
+		} else if s != segments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func isParam(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+// allowHeader lists the allowed methods, and OPTIONS, in a stable order.
+func allowHeader(allowed map[string]bool) string {
+	methods := []string{http.MethodOptions}
+	for m := range allowed {
+		if m != http.MethodOptions {
+			methods = append(methods, m)
+		}
+	}
+	sort.Strings(methods)
+	return strings.Join(methods, ", ")
+}
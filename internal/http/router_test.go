@@ -0,0 +1,57 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func namedHandler(name string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(name))
+	}
+}
+
+func TestRouter(t *testing.T) {
+	router := NewRouter()
+	router.HandleFunc("GET", "/user/{userId}", namedHandler("get user"))
+	router.HandleFunc("DELETE", "/user/{userId}", namedHandler("delete user"))
+	router.HandleFunc("GET", "/user/{userId}/balance", namedHandler("balance"))
+	router.HandleFunc("POST", "/user/{userId}/wallet/{walletId}/transaction", namedHandler("wallet transaction"))
+	router.HandleFunc("POST", "/user/{userId}/wallet/move", namedHandler("wallet move"))
+
+	tests := []struct {
+		method, path string
+		code         int
+		body, allow  string
+	}{
+		{"GET", "/user/1", http.StatusOK, "get user", ""},
+		{"DELETE", "/user/1/", http.StatusOK, "delete user", ""},
+		{"GET", "/user/1/balance", http.StatusOK, "balance", ""},
+		{"POST", "/user/1/wallet/2/transaction", http.StatusOK, "wallet transaction", ""},
+		// The literal segment wins over the parameter
+		{"POST", "/user/1/wallet/move/transaction", http.StatusOK, "wallet transaction", ""},
+		{"POST", "/user/1/wallet/move", http.StatusOK, "wallet move", ""},
+		{"OPTIONS", "/user/1", http.StatusNoContent, "", "DELETE, GET, OPTIONS"},
+		{"OPTIONS", "/user/1/balance", http.StatusNoContent, "", "GET, OPTIONS"},
+		{"POST", "/user/1", http.StatusMethodNotAllowed, "", "DELETE, GET, OPTIONS"},
+		{"PUT", "/user/1/wallet/move", http.StatusMethodNotAllowed, "", "OPTIONS, POST"},
+		{"GET", "/user", http.StatusNotFound, "", ""},
+		{"GET", "/user//balance", http.StatusNotFound, "", ""},
+		{"OPTIONS", "/unknown", http.StatusNotFound, "", ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+		if rec.Code != tt.code {
+			t.Errorf("%s %s: expected %d, got %d", tt.method, tt.path, tt.code, rec.Code)
+			continue
+		}
+		if tt.body != "" && rec.Body.String() != tt.body {
+			t.Errorf("%s %s: expected %q, got %q", tt.method, tt.path, tt.body, rec.Body.String())
+		}
+		if got := rec.Header().Get("Allow"); got != tt.allow {
+			t.Errorf("%s %s: expected Allow %q, got %q", tt.method, tt.path, tt.allow, got)
+		}
+	}
+}
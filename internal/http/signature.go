@@ -0,0 +1,59 @@
+package http
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+
+	"assignment/internal/core"
+)
+
+type signatures struct {
+	secrets map[string][]byte
+	next    http.Handler
+}
+
+// NewSignatureMiddleware wraps next with a middleware verifying the
+// Signature header of requests whose Source-Type has a secret in secrets:
+// the hex HMAC-SHA256 of the body keyed with the secret. Requests with a
+// valid signature are marked with core.WithVerifiedSignature for their
+// source type, those with an invalid signature are answered with 401
+// Unauthorized, and unsigned requests are passed on unmarked.
+func NewSignatureMiddleware(secrets map[string]string, next http.Handler) http.Handler {
+	s := &signatures{secrets: map[string][]byte{}, next: next}
+	for sourceType, secret := range secrets {
+		if secret != "" {
+			s.secrets[sourceType] = []byte(secret)
+		}
+	}
+	return s
+}
+
+func (s *signatures) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	sourceType := r.Header.Get("Source-Type")
+	secret := s.secrets[sourceType]
+	signature := r.Header.Get("Signature")
+	if secret == nil || signature == "" {
+		s.next.ServeHTTP(w, r)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	got, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(got, mac.Sum(nil)) {
+		respondError(w, http.StatusUnauthorized, "invalid signature")
+		return
+	}
+	s.next.ServeHTTP(w, r.WithContext(core.WithVerifiedSignature(r.Context(), sourceType)))
+}
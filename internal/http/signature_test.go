@@ -0,0 +1,58 @@
+package http
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"assignment/internal/core"
+)
+
+func TestSignatureMiddleware(t *testing.T) {
+	var verified bool
+	var body string
+	handler := NewSignatureMiddleware(map[string]string{"payment": "secret"}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		verified = core.SignatureVerified(r.Context(), r.Header.Get("Source-Type"))
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+	}))
+
+	payload := `{"state":"win","amount":"10.00","transactionId":"t1"}`
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(payload))
+	valid := hex.EncodeToString(mac.Sum(nil))
+
+	tests := []struct {
+		sourceType, signature string
+		wantStatus            int
+		wantVerified          bool
+	}{
+		{"payment", valid, http.StatusOK, true},
+		{"payment", "", http.StatusOK, false},
+		{"payment", strings.Repeat("0", 64), http.StatusUnauthorized, false},
+		{"payment", "not-hex", http.StatusUnauthorized, false},
+		// Source types without a secret are not verified
+		{"game", valid, http.StatusOK, false},
+	}
+	for _, tt := range tests {
+		verified, body = false, ""
+		req := httptest.NewRequest("POST", "/user/1/transaction", strings.NewReader(payload))
+		req.Header.Set("Source-Type", tt.sourceType)
+		if tt.signature != "" {
+			req.Header.Set("Signature", tt.signature)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != tt.wantStatus || verified != tt.wantVerified {
+			t.Errorf("%s %q: got %d verified=%v, want %d verified=%v", tt.sourceType, tt.signature, w.Code, verified, tt.wantStatus, tt.wantVerified)
+		}
+		if w.Code == http.StatusOK && body != payload {
+			t.Errorf("%s %q: expected the body to be passed on, got: %q", tt.sourceType, tt.signature, body)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package http
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+)
+
+//go:embed static
+var staticFiles embed.FS
+
+// staticCacheControl lets clients and proxies reuse static responses for a
+// few minutes and then revalidate them with their ETag; they only change
+// with a deploy.
+const staticCacheControl = "public, max-age=300"
+
+// staticAsset is a response built once, with its entity tag.
+type staticAsset struct {
+	body        []byte
+	contentType string
+	etag        string
+}
+
+func newStaticAsset(body []byte, contentType string) staticAsset {
+	sum := sha256.Sum256(body)
+	return staticAsset{body: body, contentType: contentType, etag: `"` + hex.EncodeToString(sum[:8]) + `"`}
+}
+
+// StaticHandler serves the OpenAPI document at /openapi.json and the docs UI
+// with its assets under /docs. Everything is built once, when the handler is
+// created, and served with Cache-Control and ETag headers, answering a
+// matching If-None-Match with 304 Not Modified.
+type StaticHandler struct {
+	assets map[string]staticAsset
+}
+
+// NewStaticHandler builds the OpenAPI document and loads the embedded docs.
+func NewStaticHandler() (*StaticHandler, error) {
+	doc, err := openAPIDocument()
+	if err != nil {
+		return nil, err
+	}
+	h := &StaticHandler{assets: map[string]staticAsset{
+		"/openapi.json": newStaticAsset(doc, "application/json"),
+	}}
+
+	entries, err := staticFiles.ReadDir("static")
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		body, err := staticFiles.ReadFile("static/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		urlPath := "/docs/" + entry.Name()
+		if entry.Name() == "docs.html" {
+			urlPath = "/docs"
+		}
+		h.assets[urlPath] = newStaticAsset(body, staticContentType(entry.Name()))
+	}
+	return h, nil
+}
+
+// Paths returns the paths of the handler's documents.
+func (h *StaticHandler) Paths() []string {
+	paths := make([]string, 0, len(h.assets))
+	for p := range h.assets {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func (h *StaticHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	asset, ok := h.assets[strings.TrimSuffix(r.URL.Path, "/")]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	w.Header().Set("Cache-Control", staticCacheControl)
+	w.Header().Set("ETag", asset.etag)
+	if etagMatches(r.Header.Get("If-None-Match"), asset.etag, true) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", asset.contentType)
+	if r.Method == http.MethodHead {
+		return
+	}
+	w.Write(asset.body)
+}
+
+func staticContentType(name string) string {
+	switch path.Ext(name) {
+	case ".html":
+		return "text/html; charset=utf-8"
+	case ".css":
+		return "text/css; charset=utf-8"
+	case ".js":
+		return "text/javascript; charset=utf-8"
+	}
+	return "application/octet-stream"
+}
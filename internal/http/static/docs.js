@@ -0,0 +1,36 @@
+// Renders the operations of /openapi.json with their request body schemas.
+(function () {
+  var main = document.getElementById("operations");
+  fetch("/openapi.json")
+    .then(function (resp) { return resp.json(); })
+    .then(function (doc) {
+      main.textContent = "";
+      Object.keys(doc.paths).sort().forEach(function (path) {
+        Object.keys(doc.paths[path]).forEach(function (method) {
+          var op = doc.paths[path][method];
+          var ref = op.requestBody.content["application/json"].schema.$ref;
+          var name = ref.split("/").pop();
+
+          var section = document.createElement("section");
+          var title = document.createElement("h2");
+          var badge = document.createElement("span");
+          badge.className = "method";
+          badge.textContent = method.toUpperCase();
+          title.appendChild(badge);
+          title.appendChild(document.createTextNode(" " + path));
+          var summary = document.createElement("p");
+          summary.textContent = op.summary;
+          var schema = document.createElement("pre");
+          schema.textContent = JSON.stringify(doc.components.schemas[name], null, 2);
+
+          section.appendChild(title);
+          section.appendChild(summary);
+          section.appendChild(schema);
+          main.appendChild(section);
+        });
+      });
+    })
+    .catch(function (err) {
+      main.textContent = "Failed to load /openapi.json: " + err;
+    });
+})();
@@ -0,0 +1,58 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStaticHandler(t *testing.T) {
+	static, err := NewStaticHandler()
+	if err != nil {
+		t.Fatalf("Failed to build static handler: %v", err)
+	}
+
+	tests := []struct {
+		path, contentType, contains string
+	}{
+		{"/openapi.json", "application/json", `"openapi":"3.1.0"`},
+		{"/docs", "text/html; charset=utf-8", "/docs/docs.js"},
+		{"/docs/", "text/html; charset=utf-8", "/docs/docs.js"},
+		{"/docs/docs.js", "text/javascript; charset=utf-8", "/openapi.json"},
+		{"/docs/docs.css", "text/css; charset=utf-8", ".method"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		static.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != tt.contentType || !strings.Contains(rec.Body.String(), tt.contains) {
+			t.Errorf("%s: got %d %s %.60s", tt.path, rec.Code, rec.Header().Get("Content-Type"), rec.Body)
+		}
+		if rec.Header().Get("Cache-Control") != staticCacheControl || rec.Header().Get("ETag") == "" {
+			t.Errorf("%s: missing cache headers: %v", tt.path, rec.Header())
+		}
+
+		// Revalidation with the tag saves the body
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.Header.Set("If-None-Match", rec.Header().Get("ETag"))
+		rec = httptest.NewRecorder()
+		static.ServeHTTP(rec, req)
+		if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+			t.Errorf("%s: expected 304 without body, got %d %d bytes", tt.path, rec.Code, rec.Body.Len())
+		}
+	}
+
+	if got := strings.Join(static.Paths(), " "); got != "/docs /docs/docs.css /docs/docs.js /openapi.json" {
+		t.Errorf("Unexpected static paths: %s", got)
+	}
+	rec := httptest.NewRecorder()
+	static.ServeHTTP(rec, httptest.NewRequest("GET", "/docs/missing.js", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a missing asset, got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	static.ServeHTTP(rec, httptest.NewRequest("POST", "/openapi.json", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for POST, got %d", rec.Code)
+	}
+}
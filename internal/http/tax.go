@@ -0,0 +1,73 @@
+package http
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"assignment/internal/core"
+	"assignment/internal/reporting"
+	"assignment/internal/utils"
+)
+
+type TaxHandlers struct {
+	transactionService *core.TransactionService
+	jurisdiction       reporting.Jurisdiction
+}
+
+func NewTaxHandlers(transactionService *core.TransactionService, jurisdiction reporting.Jurisdiction) *TaxHandlers {
+	return &TaxHandlers{
+		transactionService: transactionService,
+		jurisdiction:       jurisdiction,
+	}
+}
+
+// HandleGetTaxSummary returns a user's tax summary for the tax year starting
+// in year, as JSON or, with format=csv, in the jurisdiction's CSV format.
+func (h *TaxHandlers) HandleGetTaxSummary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, err := utils.ValidateUserID(extractUserID(r.URL.Path))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	query := r.URL.Query()
+	year, err := strconv.Atoi(query.Get("year"))
+	if err != nil || year < 1970 || year > 9998 {
+		respondError(w, http.StatusBadRequest, "invalid year: must be in YYYY format")
+		return
+	}
+	format := query.Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		respondError(w, http.StatusBadRequest, "invalid format: must be 'json' or 'csv'")
+		return
+	}
+
+	start, end, label := h.jurisdiction.TaxYear(year)
+	summary, err := h.transactionService.GetTaxSummary(userID, start, end)
+	if err != nil {
+		respondUserError(w, err)
+		return
+	}
+	summary.Jurisdiction, summary.TaxYear = h.jurisdiction.Code, label
+
+	if format != "csv" {
+		respondJSON(w, summary)
+		return
+	}
+	body, err := h.jurisdiction.TaxCSV(summary)
+	if err != nil {
+		log.Printf("Error writing tax summary: %v", err)
+		respondInternalError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="tax-summary-%d-%s.csv"`, userID, label))
+	w.Write(body)
+}
@@ -0,0 +1,144 @@
+package http
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"assignment/internal/core"
+	"assignment/internal/utils"
+)
+
+func (h *Handlers) HandleIssueAPIToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, err := utils.ValidateUserID(extractAdminUserID(r.URL.Path))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	token, err := h.transactionService.IssueAPIToken(userID)
+	if err != nil {
+		respondUserError(w, err)
+		return
+	}
+
+	respondJSON(w, token)
+}
+
+func (h *Handlers) HandleRevokeAPIToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, err := utils.ValidateUserID(extractAdminUserID(r.URL.Path))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Path format: /admin/users/{userId}/tokens/{tokenId}
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	tokenID, err := strconv.ParseInt(parts[len(parts)-1], 10, 64)
+	if err != nil || tokenID <= 0 {
+		respondError(w, http.StatusBadRequest, "invalid token ID: must be a positive integer")
+		return
+	}
+
+	token, err := h.transactionService.RevokeAPIToken(userID, tokenID)
+	if err != nil {
+		if errors.Is(err, core.ErrTokenNotFound) {
+			respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		respondUserError(w, err)
+		return
+	}
+
+	respondJSON(w, token)
+}
+
+// TokenAuthenticator resolves an API token to the user it is scoped to.
+type TokenAuthenticator interface {
+	AuthenticateAPIToken(token string) (int64, error)
+}
+
+type tokenScope struct {
+	auth     TokenAuthenticator
+	required bool
+	next     http.Handler
+}
+
+// NewTokenMiddleware wraps next with a middleware restricting requests that
+// carry a user-scoped API token ("Authorization: Bearer ut_...") to reading
+// that user's balance and history. With required set, reading any user's
+// data without a token is refused, see tokenGated; other requests without a
+// token are passed on unchanged.
+func NewTokenMiddleware(auth TokenAuthenticator, required bool, next http.Handler) http.Handler {
+	return &tokenScope{auth: auth, required: required, next: next}
+}
+
+// tokenReadablePaths are the suffixes of the /user/{userId}/... paths a
+// user-scoped token may GET.
+var tokenReadablePaths = map[string]bool{
+	"balance":           true,
+	"balance/history":   true,
+	"statement":         true,
+	"statement/summary": true,
+	"tax-summary":       true,
+}
+
+// tokenGated reports whether r reads user data, which requires a token when
+// tokens are required: GET /user/{userId} and any GET below it, e.g. wallet
+// balances, and the bulk balance lookup.
+func tokenGated(r *http.Request) bool {
+	if r.Method == http.MethodPost {
+		return strings.Trim(r.URL.Path, "/") == "balances"
+	}
+	parts := strings.SplitN(strings.Trim(r.URL.Path, "/"), "/", 2)
+	return r.Method == http.MethodGet && len(parts) >= 2 && parts[0] == "user"
+}
+
+func (t *tokenScope) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		if t.required && tokenGated(r) {
+			respondError(w, http.StatusUnauthorized, "token required")
+			return
+		}
+		t.next.ServeHTTP(w, r)
+		return
+	}
+
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || token == "" {
+		respondError(w, http.StatusUnauthorized, "invalid token")
+		return
+	}
+	userID, err := t.auth.AuthenticateAPIToken(token)
+	if err != nil {
+		if errors.Is(err, core.ErrInvalidToken) {
+			respondError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		log.Printf("Error authenticating token: %v", err)
+		respondInternalError(w, err)
+		return
+	}
+
+	// Path format: /user/{userId}/balance and the like
+	prefix := "/user/" + strconv.FormatInt(userID, 10) + "/"
+	if r.Method != http.MethodGet || !strings.HasPrefix(r.URL.Path, prefix) || !tokenReadablePaths[strings.TrimPrefix(r.URL.Path, prefix)] {
+		respondError(w, http.StatusForbidden, "token does not grant access to this resource")
+		return
+	}
+
+	t.next.ServeHTTP(w, r)
+}
@@ -0,0 +1,99 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"assignment/internal/core"
+)
+
+type fakeAuthenticator map[string]int64
+
+func (f fakeAuthenticator) AuthenticateAPIToken(token string) (int64, error) {
+	if userID, ok := f[token]; ok {
+		return userID, nil
+	}
+	return 0, core.ErrInvalidToken
+}
+
+func TestTokenMiddleware(t *testing.T) {
+	m := NewTokenMiddleware(fakeAuthenticator{"ut_user1": 1}, false, okHandler())
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		want   int
+	}{
+		{"no token", "POST", "/user/2/transaction", "", http.StatusOK},
+		{"no token read", "GET", "/user/2/balance", "", http.StatusOK},
+		{"own balance", "GET", "/user/1/balance", "ut_user1", http.StatusOK},
+		{"own history", "GET", "/user/1/balance/history", "ut_user1", http.StatusOK},
+		{"own statement", "GET", "/user/1/statement/summary", "ut_user1", http.StatusOK},
+		{"other user", "GET", "/user/2/balance", "ut_user1", http.StatusForbidden},
+		{"prefix of other user", "GET", "/user/12/balance", "ut_user1", http.StatusForbidden},
+		{"write", "POST", "/user/1/transaction", "ut_user1", http.StatusForbidden},
+		{"profile", "GET", "/user/1", "ut_user1", http.StatusForbidden},
+		{"admin", "GET", "/admin/stats", "ut_user1", http.StatusForbidden},
+		{"unknown token", "GET", "/user/1/balance", "ut_other", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			m.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("Expected status %d, got: %d", tt.want, w.Code)
+			}
+		})
+	}
+
+	req := httptest.NewRequest("GET", "/user/1/balance", nil)
+	req.Header.Set("Authorization", "Basic dXNlcjpwYXNz")
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for non-bearer credentials, got: %d", w.Code)
+	}
+}
+
+func TestTokenMiddleware_Required(t *testing.T) {
+	m := NewTokenMiddleware(fakeAuthenticator{"ut_user1": 1}, true, okHandler())
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		want   int
+	}{
+		{"no token balance", "GET", "/user/2/balance", "", http.StatusUnauthorized},
+		{"no token history", "GET", "/user/2/balance/history", "", http.StatusUnauthorized},
+		{"no token tax summary", "GET", "/user/2/tax-summary", "", http.StatusUnauthorized},
+		{"no token user", "GET", "/user/2", "", http.StatusUnauthorized},
+		{"no token wallet balance", "GET", "/user/2/wallet/w1/balance", "", http.StatusUnauthorized},
+		{"no token bulk balances", "POST", "/balances", "", http.StatusUnauthorized},
+		{"no token write", "POST", "/user/2/transaction", "", http.StatusOK},
+		{"no token admin", "GET", "/admin/stats", "", http.StatusOK},
+		{"own balance", "GET", "/user/1/balance", "ut_user1", http.StatusOK},
+		{"other user", "GET", "/user/2/balance", "ut_user1", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			m.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("Expected status %d, got: %d", tt.want, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package http
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"assignment/internal/core"
+	"assignment/internal/models"
+	"assignment/internal/utils"
+)
+
+func extractWalletID(path string) string {
+	// Path format: /user/{userId}/wallet/{walletId}/transaction or .../balance
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) >= 4 && parts[0] == "user" && parts[2] == "wallet" {
+		return parts[3]
+	}
+	return ""
+}
+
+// respondWalletError maps errors from wallet operations to a status.
+func respondWalletError(w http.ResponseWriter, err error) {
+	errMsg := err.Error()
+	switch {
+	case errors.Is(err, core.ErrUserNotFound) || errors.Is(err, core.ErrWalletNotFound):
+		respondError(w, http.StatusNotFound, errMsg)
+	case errors.Is(err, core.ErrValidation):
+		respondError(w, http.StatusBadRequest, errMsg)
+	case errors.Is(err, core.ErrSignatureRequired):
+		respondError(w, http.StatusUnauthorized, errMsg)
+	default:
+		log.Printf("Error serving wallet request: %v", err)
+		respondInternalError(w, err)
+	}
+}
+
+func (h *Handlers) HandleCreateWallet(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, err := utils.ValidateUserID(extractUserID(r.URL.Path))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req models.CreateWalletRequest
+	if err := decodeBody(r, "create_wallet", &req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	wallet, err := h.transactionService.CreateWallet(userID, req.WalletID)
+	if err != nil {
+		respondWalletError(w, err)
+		return
+	}
+
+	respondJSON(w, wallet)
+}
+
+func (h *Handlers) HandleGetWallets(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, err := utils.ValidateUserID(extractUserID(r.URL.Path))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	wallets, err := h.transactionService.GetWallets(userID)
+	if err != nil {
+		respondWalletError(w, err)
+		return
+	}
+
+	respondJSON(w, wallets)
+}
+
+func (h *Handlers) HandleWalletTransaction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, err := utils.ValidateUserID(extractUserID(r.URL.Path))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	sourceType := r.Header.Get("Source-Type")
+	if sourceType == "" {
+		respondError(w, http.StatusBadRequest, "Source-Type header is required")
+		return
+	}
+
+	var req models.TransactionRequest
+	if err := decodeBody(r, "transaction", &req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	if err := h.transactionService.ValidateTransactionID(req.TransactionID); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response, err := h.transactionService.ProcessWalletTransaction(r.Context(), userID, extractWalletID(r.URL.Path), req, sourceType)
+	if err != nil {
+		respondWalletError(w, err)
+		return
+	}
+
+	// Duplicates and insufficient funds are reported with 200 as well
+	respondTransaction(w, response)
+}
+
+func (h *Handlers) HandleGetWalletBalance(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, err := utils.ValidateUserID(extractUserID(r.URL.Path))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response, err := h.transactionService.GetWalletBalance(userID, extractWalletID(r.URL.Path))
+	if err != nil {
+		respondWalletError(w, err)
+		return
+	}
+
+	respondJSON(w, response)
+}
+
+func (h *Handlers) HandleWalletMove(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	userID, err := utils.ValidateUserID(extractUserID(r.URL.Path))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	sourceType := r.Header.Get("Source-Type")
+	if sourceType == "" {
+		respondError(w, http.StatusBadRequest, "Source-Type header is required")
+		return
+	}
+
+	var req models.WalletMoveRequest
+	if err := decodeBody(r, "wallet_move", &req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	response, err := h.transactionService.MoveBetweenWallets(r.Context(), userID, req, sourceType)
+	if err != nil {
+		respondWalletError(w, err)
+		return
+	}
+
+	// Duplicates and insufficient funds are reported with 200 as well
+	respondJSON(w, response)
+}
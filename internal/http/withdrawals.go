@@ -0,0 +1,58 @@
+package http
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"assignment/internal/core"
+	"assignment/internal/models"
+	"assignment/internal/payment"
+	"assignment/internal/utils"
+)
+
+type WithdrawalHandlers struct {
+	withdrawals *payment.Withdrawals
+}
+
+func NewWithdrawalHandlers(withdrawals *payment.Withdrawals) *WithdrawalHandlers {
+	return &WithdrawalHandlers{
+		withdrawals: withdrawals,
+	}
+}
+
+func (h *WithdrawalHandlers) HandleWithdrawal(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// Get user ID from path
+	userIDStr := extractUserID(r.URL.Path)
+	userID, err := utils.ValidateUserID(userIDStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req models.WithdrawalRequest
+	if err := decodeBody(r, "withdrawal", &req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	withdrawal, err := h.withdrawals.Withdraw(r.Context(), userID, req.WithdrawalID, req.Amount)
+	if err != nil {
+		if errors.Is(err, payment.ErrWithdrawalIDRequired) || errors.Is(err, core.ErrValidation) {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Printf("Error processing withdrawal: %v", err)
+		respondInternalError(w, err)
+		return
+	}
+
+	// Compensated and pending withdrawals are reported with 200 and their
+	// saga status
+	respondJSON(w, withdrawal)
+}
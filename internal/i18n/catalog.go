@@ -0,0 +1,177 @@
+package i18n
+
+// message is a catalog entry. Prefix entries match messages that continue
+// with details after the text.
+type message struct {
+	code   string
+	prefix bool
+	text   map[string]string
+}
+
+// catalog holds the messages of transaction outcomes and common errors.
+// Codes of transaction rejections are the reasons recorded for them.
+var catalog = []message{
+	// Transaction and transfer outcomes
+	{code: "applied", text: map[string]string{
+		"en": "Transaction applied successfully",
+		"de": "Transaktion erfolgreich verbucht",
+		"fr": "Transaction effectuée avec succès",
+	}},
+	{code: "clipped", text: map[string]string{
+		"en": "Transaction clipped to maximum balance",
+		"de": "Transaktion auf den Höchstsaldo gekürzt",
+		"fr": "Transaction réduite au solde maximal",
+	}},
+	{code: "duplicate", text: map[string]string{
+		"en": "Duplicate transaction ignored",
+		"de": "Doppelte Transaktion ignoriert",
+		"fr": "Transaction en double ignorée",
+	}},
+	{code: "duplicate_message", text: map[string]string{
+		"en": "Duplicate message ignored",
+		"de": "Doppelte Nachricht ignoriert",
+		"fr": "Message en double ignoré",
+	}},
+	{code: "transfer_applied", text: map[string]string{
+		"en": "Transfer applied successfully",
+		"de": "Überweisung erfolgreich verbucht",
+		"fr": "Virement effectué avec succès",
+	}},
+	{code: "duplicate_transfer", text: map[string]string{
+		"en": "Duplicate transfer ignored",
+		"de": "Doppelte Überweisung ignoriert",
+		"fr": "Virement en double ignoré",
+	}},
+	{code: "insufficient_funds", text: map[string]string{
+		"en": "Insufficient funds",
+		"de": "Unzureichendes Guthaben",
+		"fr": "Fonds insuffisants",
+	}},
+	{code: "max_balance", text: map[string]string{
+		"en": "Maximum balance exceeded",
+		"de": "Höchstsaldo überschritten",
+		"fr": "Solde maximal dépassé",
+	}},
+	{code: "kyc_single_limit", text: map[string]string{
+		"en": "KYC single transaction limit exceeded",
+		"de": "KYC-Limit pro Transaktion überschritten",
+		"fr": "Limite KYC par transaction dépassée",
+	}},
+	{code: "kyc_daily_limit", text: map[string]string{
+		"en": "KYC daily limit exceeded",
+		"de": "KYC-Tageslimit überschritten",
+		"fr": "Limite KYC journalière dépassée",
+	}},
+	{code: "user_suspended", text: map[string]string{
+		"en": "User suspended",
+		"de": "Benutzer gesperrt",
+		"fr": "Utilisateur suspendu",
+	}},
+	{code: "user_closed", text: map[string]string{
+		"en": "User closed",
+		"de": "Benutzerkonto geschlossen",
+		"fr": "Compte utilisateur clôturé",
+	}},
+	{code: "frozen", text: map[string]string{
+		"en": "Account frozen",
+		"de": "Konto eingefroren",
+		"fr": "Compte gelé",
+	}},
+
+	// Request errors
+	{code: "method_not_allowed", text: map[string]string{
+		"en": "Method not allowed",
+		"de": "Methode nicht erlaubt",
+		"fr": "Méthode non autorisée",
+	}},
+	{code: "invalid_request_body", prefix: true, text: map[string]string{
+		"en": "Invalid request body: ",
+		"de": "Ungültiger Anfrageinhalt: ",
+		"fr": "Corps de requête invalide : ",
+	}},
+	{code: "source_type_required", text: map[string]string{
+		"en": "Source-Type header is required",
+		"de": "Der Header Source-Type ist erforderlich",
+		"fr": "L'en-tête Source-Type est obligatoire",
+	}},
+	{code: "invalid_source_type", text: map[string]string{
+		"en": "invalid Source-Type header: must be 'game', 'server', or 'payment'",
+		"de": "ungültiger Source-Type-Header: muss 'game', 'server' oder 'payment' sein",
+		"fr": "en-tête Source-Type invalide : doit être 'game', 'server' ou 'payment'",
+	}},
+	{code: "invalid_state", text: map[string]string{
+		"en": "invalid state: must be 'win' or 'lose'",
+		"de": "ungültiger Status: muss 'win' oder 'lose' sein",
+		"fr": "état invalide : doit être 'win' ou 'lose'",
+	}},
+	{code: "invalid_amount_format", text: map[string]string{
+		"en": "invalid amount format: must be a string with up to 2 decimal places",
+		"de": "ungültiges Betragsformat: muss eine Zeichenkette mit höchstens 2 Nachkommastellen sein",
+		"fr": "format de montant invalide : doit être une chaîne avec au plus 2 décimales",
+	}},
+	{code: "invalid_amount", text: map[string]string{
+		"en": "invalid amount: cannot parse as number",
+		"de": "ungültiger Betrag: keine gültige Zahl",
+		"fr": "montant invalide : nombre illisible",
+	}},
+	{code: "negative_amount", text: map[string]string{
+		"en": "invalid amount: cannot be negative",
+		"de": "ungültiger Betrag: darf nicht negativ sein",
+		"fr": "montant invalide : ne peut pas être négatif",
+	}},
+	{code: "zero_amount", text: map[string]string{
+		"en": "invalid amount: must be greater than zero",
+		"de": "ungültiger Betrag: muss größer als null sein",
+		"fr": "montant invalide : doit être supérieur à zéro",
+	}},
+	{code: "amount_too_large", prefix: true, text: map[string]string{
+		"en": "invalid amount: exceeds the maximum transaction amount of ",
+		"de": "ungültiger Betrag: überschreitet den maximalen Transaktionsbetrag von ",
+		"fr": "montant invalide : dépasse le montant maximal par transaction de ",
+	}},
+	{code: "invalid_transaction_id", prefix: true, text: map[string]string{
+		"en": "invalid transaction ID: ",
+		"de": "ungültige Transaktions-ID: ",
+		"fr": "identifiant de transaction invalide : ",
+	}},
+	{code: "invalid_transfer_id", prefix: true, text: map[string]string{
+		"en": "invalid transfer ID: ",
+		"de": "ungültige Überweisungs-ID: ",
+		"fr": "identifiant de virement invalide : ",
+	}},
+	{code: "invalid_user_id", text: map[string]string{
+		"en": "invalid user ID: must be a positive integer",
+		"de": "ungültige Benutzer-ID: muss eine positive ganze Zahl sein",
+		"fr": "identifiant utilisateur invalide : doit être un entier positif",
+	}},
+	{code: "user_not_found", text: map[string]string{
+		"en": "user not found",
+		"de": "Benutzer nicht gefunden",
+		"fr": "utilisateur introuvable",
+	}},
+	{code: "precondition_failed", text: map[string]string{
+		"en": "precondition failed: balance has changed",
+		"de": "Vorbedingung fehlgeschlagen: Guthaben hat sich geändert",
+		"fr": "précondition non remplie : le solde a changé",
+	}},
+	{code: "invalid_token", text: map[string]string{
+		"en": "invalid token",
+		"de": "ungültiges Token",
+		"fr": "jeton invalide",
+	}},
+	{code: "token_forbidden", text: map[string]string{
+		"en": "token does not grant access to this resource",
+		"de": "Token gewährt keinen Zugriff auf diese Ressource",
+		"fr": "le jeton ne donne pas accès à cette ressource",
+	}},
+	{code: "unavailable", prefix: true, text: map[string]string{
+		"en": "Service unavailable",
+		"de": "Dienst nicht verfügbar",
+		"fr": "Service indisponible",
+	}},
+	{code: "internal_error", prefix: true, text: map[string]string{
+		"en": "Internal server error",
+		"de": "Interner Serverfehler",
+		"fr": "Erreur interne du serveur",
+	}},
+}
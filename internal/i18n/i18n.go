@@ -0,0 +1,92 @@
+// Package i18n translates the human-readable messages of API responses.
+// Every message in the catalog has a stable machine code, which clients
+// should match on instead of the text.
+package i18n
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Default is the language messages are written in, and the one used when
+// the client accepts none of the supported languages.
+const Default = "en"
+
+// Languages are the supported languages, as ISO 639-1 codes.
+var Languages = []string{"en", "de", "fr"}
+
+// Negotiate picks the supported language the client prefers from an
+// Accept-Language header such as "de-CH, de;q=0.9, en;q=0.5".
+func Negotiate(acceptLanguage string) string {
+	type preference struct {
+		lang string
+		q    float64
+	}
+	var prefs []preference
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		// Only the primary subtag matters: de-CH is served German
+		lang, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if q > 0 && supported(lang) {
+			prefs = append(prefs, preference{lang, q})
+		}
+	}
+	sort.SliceStable(prefs, func(i, j int) bool { return prefs[i].q > prefs[j].q })
+	if len(prefs) == 0 {
+		return Default
+	}
+	return prefs[0].lang
+}
+
+func supported(lang string) bool {
+	for _, l := range Languages {
+		if l == lang {
+			return true
+		}
+	}
+	return false
+}
+
+// Translate returns the code of a message and the message in lang. Messages
+// ending in details, such as "Invalid request body: unexpected EOF", are
+// matched by their start and keep the details untranslated. ok is false for
+// messages not in the catalog.
+func Translate(message, lang string) (code, text string, ok bool) {
+	if m, found := exact[message]; found {
+		return m.code, m.in(lang), true
+	}
+	for _, m := range catalog {
+		if m.prefix && strings.HasPrefix(message, m.text[Default]) {
+			return m.code, m.in(lang) + message[len(m.text[Default]):], true
+		}
+	}
+	return "", message, false
+}
+
+// in returns the message in lang, or in the default language if it has not
+// been translated.
+func (m message) in(lang string) string {
+	if text, ok := m.text[lang]; ok {
+		return text
+	}
+	return m.text[Default]
+}
+
+var exact = map[string]message{}
+
+func init() {
+	for _, m := range catalog {
+		if !m.prefix {
+			exact[m.text[Default]] = m
+		}
+	}
+}
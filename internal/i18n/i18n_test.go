@@ -0,0 +1,58 @@
+package i18n
+
+import "testing"
+
+func TestNegotiate(t *testing.T) {
+	tests := map[string]string{
+		"":                          "en",
+		"de":                        "de",
+		"de-CH, de;q=0.9, en;q=0.8": "de",
+		"ja, fr;q=0.7, de;q=0.5":    "fr",
+		"en;q=0.3, FR-ca;q=0.8":     "fr",
+		"fr;q=0, de;q=0.1":          "de",
+		"ja, zh-TW":                 "en",
+		"fr;q=abc, de":              "de",
+		"*":                         "en",
+	}
+	for header, want := range tests {
+		if got := Negotiate(header); got != want {
+			t.Errorf("Negotiate(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	tests := []struct {
+		message, lang      string
+		wantCode, wantText string
+		wantOK             bool
+	}{
+		{"Insufficient funds", "de", "insufficient_funds", "Unzureichendes Guthaben", true},
+		{"Insufficient funds", "en", "insufficient_funds", "Insufficient funds", true},
+		{"Invalid request body: unexpected EOF", "fr", "invalid_request_body", "Corps de requête invalide : unexpected EOF", true},
+		{"Internal server error", "de", "internal_error", "Interner Serverfehler", true},
+		{"invalid amount: exceeds the maximum transaction amount of 10000.00", "de", "amount_too_large", "ungültiger Betrag: überschreitet den maximalen Transaktionsbetrag von 10000.00", true},
+		{"escrow not found", "de", "", "escrow not found", false},
+	}
+	for _, tt := range tests {
+		code, text, ok := Translate(tt.message, tt.lang)
+		if code != tt.wantCode || text != tt.wantText || ok != tt.wantOK {
+			t.Errorf("Translate(%q, %q) = %q, %q, %v; want %q, %q, %v", tt.message, tt.lang, code, text, ok, tt.wantCode, tt.wantText, tt.wantOK)
+		}
+	}
+}
+
+func TestCatalogIsComplete(t *testing.T) {
+	codes := map[string]bool{}
+	for _, m := range catalog {
+		if codes[m.code] {
+			t.Errorf("Duplicate code %q", m.code)
+		}
+		codes[m.code] = true
+		for _, lang := range Languages {
+			if m.text[lang] == "" {
+				t.Errorf("Message %q has no %s translation", m.code, lang)
+			}
+		}
+	}
+}
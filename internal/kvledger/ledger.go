@@ -0,0 +1,244 @@
+// Package kvledger keeps users, balances and transactions in a kvstore.Store,
+// e.g. a DynamoDB table, for regions where managed Postgres is unavailable.
+// Its Store only persists them for core.WithStore: the service keeps
+// applying its validation, idempotency, hooks, validators, source
+// capabilities, freezes and KYC limits.
+//
+// Every change is one conditional transaction on the store: a transaction is
+// stored by creating the item of its transaction ID together with the update
+// of the user's item at the version it was read, so a retried or concurrent
+// duplicate cannot be applied twice, and concurrent transactions of a user
+// cannot overwrite each other's balance.
+package kvledger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"assignment/internal/core"
+	"assignment/internal/fixture"
+	"assignment/internal/kvstore"
+	"assignment/internal/models"
+	"assignment/internal/money"
+	"assignment/internal/utils"
+)
+
+// maxConflictRetries bounds the retries of a user creation losing races to
+// concurrent creations.
+const maxConflictRetries = 10
+
+// Keys of the items in the store.
+const (
+	userSequenceKey = "seq/user"
+	userPrefix      = "user/"
+	externalRefKey  = "ref/"
+	transactionKey  = "txn/"
+)
+
+// Store is a core.Store in a key-value store. It is safe for concurrent use,
+// also by several instances sharing the key-value store.
+type Store struct {
+	kv kvstore.Store
+}
+
+var _ core.Store = (*Store)(nil)
+
+// account is the item of a user.
+type account struct {
+	User models.User `json:"user"`
+	// Day is the UTC day, as 2006-01-02, of the transactions summed into
+	// DaySpent, in minor units, for the KYC daily limit
+	Day      string `json:"day,omitempty"`
+	DaySpent int64  `json:"daySpent,omitempty"`
+}
+
+// booking is the item of an applied transaction, keeping the response to
+// replay to retries.
+type booking struct {
+	UserID     int64                      `json:"userId"`
+	State      string                     `json:"state"`
+	Amount     string                     `json:"amount"`
+	SourceType string                     `json:"sourceType"`
+	CreatedAt  time.Time                  `json:"createdAt"`
+	Response   models.TransactionResponse `json:"response"`
+}
+
+// NewStore returns a store keeping its items in kv.
+func NewStore(kv kvstore.Store) *Store {
+	return &Store{kv: kv}
+}
+
+// Seed creates the users of f that do not exist yet, with KYC level full
+// like the service seeds them. Their transactions are applied by the
+// service, see core.TransactionService.ApplyFixture. Seeding is idempotent.
+func (s *Store) Seed(ctx context.Context, f *fixture.Fixture, now time.Time) error {
+	for _, u := range f.Users {
+		balance, err := utils.ParseAmount(string(u.Balance))
+		if err != nil {
+			return fmt.Errorf("invalid balance of user %d: %w", u.ID, err)
+		}
+		createdAt := u.CreatedAt
+		if createdAt.IsZero() {
+			createdAt = now
+		}
+		user := models.User{
+			ID:        u.ID,
+			Balance:   money.Format(balance, core.DefaultCurrency),
+			Currency:  core.DefaultCurrency,
+			Status:    core.UserActive,
+			KYCLevel:  core.KYCFull,
+			CreatedAt: createdAt,
+			UpdatedAt: createdAt,
+		}
+		if _, err := s.CreateAccount(ctx, user); err != nil && !errors.Is(err, core.ErrStoreConflict) {
+			return fmt.Errorf("failed to seed user %d: %w", u.ID, err)
+		}
+	}
+	return nil
+}
+
+// Account returns the account of a user that is not deleted, at the version
+// of its item.
+func (s *Store) Account(ctx context.Context, userID int64) (*core.StoredAccount, error) {
+	item, err := s.kv.Get(ctx, userKey(userID))
+	if errors.Is(err, kvstore.ErrNotFound) {
+		return nil, core.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	var acct account
+	if err := json.Unmarshal(item.Value, &acct); err != nil {
+		return nil, fmt.Errorf("failed to decode user: %w", err)
+	}
+	if acct.User.DeletedAt != nil {
+		return nil, core.ErrUserNotFound
+	}
+	return &core.StoredAccount{
+		User:     acct.User,
+		Day:      acct.Day,
+		DaySpent: acct.DaySpent,
+		Version:  item.Version,
+	}, nil
+}
+
+// CreateAccount stores a new user under the next ID of the user sequence,
+// or under user.ID if it is set, raising the sequence past it.
+func (s *Store) CreateAccount(ctx context.Context, user models.User) (*models.User, error) {
+	for attempt := 0; attempt < maxConflictRetries; attempt++ {
+		created, err := s.createUser(ctx, user)
+		if !errors.Is(err, kvstore.ErrConflict) {
+			return created, err
+		}
+		if user.ExternalRef != "" {
+			if _, err := s.kv.Get(ctx, externalRefKey+user.ExternalRef); err == nil {
+				return nil, core.ErrExternalRefInUse
+			}
+		}
+		if user.ID != 0 {
+			if _, err := s.kv.Get(ctx, userKey(user.ID)); err == nil {
+				return nil, core.ErrStoreConflict
+			}
+		}
+	}
+	return nil, &core.RetryableError{Err: errors.New("too many concurrent user creations")}
+}
+
+// createUser stores a new user like CreateAccount. It fails with
+// kvstore.ErrConflict if the ID or external reference is taken, or the
+// sequence moved on since it was read.
+func (s *Store) createUser(ctx context.Context, user models.User) (*models.User, error) {
+	var last int64
+	seq, err := s.kv.Get(ctx, userSequenceKey)
+	switch {
+	case err == nil:
+		if last, err = strconv.ParseInt(string(seq.Value), 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid user sequence: %w", err)
+		}
+	case !errors.Is(err, kvstore.ErrNotFound):
+		return nil, fmt.Errorf("failed to read user sequence: %w", err)
+	}
+	if user.ID == 0 {
+		user.ID = last + 1
+	}
+
+	value, err := json.Marshal(account{User: user})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode user: %w", err)
+	}
+	writes := []kvstore.Write{{Key: userKey(user.ID), Value: value}}
+	if user.ID > last {
+		writes = append(writes, kvstore.Write{Key: userSequenceKey, Value: []byte(strconv.FormatInt(user.ID, 10)), Version: seq.Version})
+	}
+	if user.ExternalRef != "" {
+		writes = append(writes, kvstore.Write{Key: externalRefKey + user.ExternalRef, Value: []byte(strconv.FormatInt(user.ID, 10))})
+	}
+	if err := s.kv.Transact(ctx, writes...); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Transaction returns the transaction stored under a transaction ID.
+func (s *Store) Transaction(ctx context.Context, transactionID string) (*core.StoredTransaction, error) {
+	item, err := s.kv.Get(ctx, transactionKey+transactionID)
+	if errors.Is(err, kvstore.ErrNotFound) {
+		return nil, core.ErrTransactionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
+	}
+	var b booking
+	if err := json.Unmarshal(item.Value, &b); err != nil {
+		return nil, fmt.Errorf("failed to decode stored transaction: %w", err)
+	}
+	return &core.StoredTransaction{
+		Transaction: models.Transaction{
+			UserID:        b.UserID,
+			TransactionID: transactionID,
+			State:         b.State,
+			Amount:        b.Amount,
+			SourceType:    b.SourceType,
+			Applied:       true,
+			CreatedAt:     b.CreatedAt,
+		},
+		Response: b.Response,
+	}, nil
+}
+
+// Apply creates the item of the transaction together with the update of the
+// user's item at the version the account was read at.
+func (s *Store) Apply(ctx context.Context, acct *core.StoredAccount, transaction *core.StoredTransaction) error {
+	accountValue, err := json.Marshal(account{User: acct.User, Day: acct.Day, DaySpent: acct.DaySpent})
+	if err != nil {
+		return fmt.Errorf("failed to encode user: %w", err)
+	}
+	t := transaction.Transaction
+	bookingValue, err := json.Marshal(booking{
+		UserID:     t.UserID,
+		State:      t.State,
+		Amount:     t.Amount,
+		SourceType: t.SourceType,
+		CreatedAt:  t.CreatedAt,
+		Response:   transaction.Response,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode transaction: %w", err)
+	}
+	err = s.kv.Transact(ctx,
+		kvstore.Write{Key: transactionKey + t.TransactionID, Value: bookingValue},
+		kvstore.Write{Key: userKey(acct.User.ID), Value: accountValue, Version: acct.Version},
+	)
+	if errors.Is(err, kvstore.ErrConflict) {
+		return core.ErrStoreConflict
+	}
+	return err
+}
+
+func userKey(userID int64) string {
+	return userPrefix + strconv.FormatInt(userID, 10)
+}
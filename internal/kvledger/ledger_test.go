@@ -0,0 +1,186 @@
+package kvledger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"assignment/internal/clock"
+	"assignment/internal/core"
+	"assignment/internal/fixture"
+	handlers "assignment/internal/http"
+	"assignment/internal/kvstore"
+	"assignment/internal/models"
+)
+
+var testNow = time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+func newTestService(t *testing.T, opts ...core.Option) *core.TransactionService {
+	store := NewStore(kvstore.NewMemory())
+	if err := store.Seed(context.Background(), fixture.Default(), testNow); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	service := core.NewTransactionService(nil, clock.NewFake(testNow), append(opts, core.WithStore(store))...)
+	if _, err := service.ApplyFixture(fixture.Default()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	return service
+}
+
+func TestStore_Transactions(t *testing.T) {
+	s := newTestService(t)
+
+	req := models.TransactionRequest{State: "win", Amount: "10.50", TransactionID: "t1"}
+	resp, err := s.ProcessTransaction(1, req, "game")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if resp.Balance != "110.50" || resp.Message != "Transaction applied successfully" || resp.Replayed {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+
+	// A retry is answered as before, even with another amount
+	resp, err = s.ProcessTransaction(1, models.TransactionRequest{State: "win", Amount: "99.00", TransactionID: "t1"}, "game")
+	if err != nil || !resp.Replayed || resp.Balance != "110.50" {
+		t.Errorf("Expected the replayed response, got: %+v, %v", resp, err)
+	}
+
+	resp, err = s.ProcessTransaction(1, models.TransactionRequest{State: "lose", Amount: "500.00", TransactionID: "t2"}, "game")
+	if err != nil || resp.Message != "Insufficient funds" || resp.Reason != core.ReasonInsufficientFunds || resp.Balance != "110.50" {
+		t.Errorf("Expected insufficient funds, got: %+v, %v", resp, err)
+	}
+
+	if _, err := s.ProcessTransaction(1, models.TransactionRequest{State: "draw", Amount: "1.00", TransactionID: "t3"}, "game"); !errors.Is(err, core.ErrValidation) {
+		t.Errorf("Expected a validation error, got: %v", err)
+	}
+	if _, err := s.ProcessTransaction(99, models.TransactionRequest{State: "win", Amount: "1.00", TransactionID: "t4"}, "game"); !errors.Is(err, core.ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound, got: %v", err)
+	}
+
+	balance, err := s.GetBalance(1)
+	if err != nil || balance.Balance != "110.50" {
+		t.Errorf("Expected balance 110.50, got: %+v, %v", balance, err)
+	}
+	balances, err := s.GetBalances([]int64{2, 99})
+	if err != nil || len(balances.Balances) != 1 || balances.Balances[0].Balance != "50.00" || len(balances.NotFound) != 1 {
+		t.Errorf("Unexpected balances: %+v, %v", balances, err)
+	}
+}
+
+func TestStore_KYCLimits(t *testing.T) {
+	s := newTestService(t)
+
+	user, err := s.CreateUser(models.CreateUserRequest{Balance: "500.00", ExternalRef: "crm-1"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if user.ID != 4 || user.KYCLevel != core.KYCUnverified || user.Currency != core.DefaultCurrency {
+		t.Errorf("Unexpected user: %+v", user)
+	}
+	if _, err := s.CreateUser(models.CreateUserRequest{ExternalRef: "crm-1"}); !errors.Is(err, core.ErrExternalRefInUse) {
+		t.Errorf("Expected ErrExternalRefInUse, got: %v", err)
+	}
+
+	limit := core.KYCLimits[core.KYCUnverified]
+	amount := fmt.Sprintf("%.2f", limit.MaxSingle)
+	if resp, _ := s.ProcessTransaction(user.ID, models.TransactionRequest{State: "lose", Amount: amount, TransactionID: "k1"}, "game"); resp.Message != "Transaction applied successfully" {
+		t.Fatalf("Expected the transaction applied, got: %+v", resp)
+	}
+	if resp, _ := s.ProcessTransaction(user.ID, models.TransactionRequest{State: "lose", Amount: "0.01", TransactionID: "k2"}, "game"); resp.Reason != core.ReasonKYCDailyLimit {
+		t.Errorf("Expected the daily limit, got: %+v", resp)
+	}
+}
+
+func TestStore_ServiceRules(t *testing.T) {
+	var applied []string
+	s := newTestService(t,
+		core.WithValidators(func(req models.TransactionRequest, user core.UserContext) error {
+			if req.TransactionID == "blocked" {
+				return errors.New("blocked by validator")
+			}
+			return nil
+		}),
+		core.WithHooks(core.Hooks{AfterTransaction: func(t models.Transaction, balance string) {
+			applied = append(applied, t.TransactionID+"="+balance)
+		}}),
+	)
+	s.SetSourceCapabilities("game", core.SourceCapabilities{CanDebit: true})
+
+	if _, err := s.ProcessTransaction(1, models.TransactionRequest{State: "lose", Amount: "1.00", TransactionID: "blocked"}, "game"); err == nil {
+		t.Error("Expected the validator to refuse the transaction")
+	}
+	if _, err := s.ProcessTransaction(1, models.TransactionRequest{State: "win", Amount: "1.00", TransactionID: "r1"}, "game"); !errors.Is(err, core.ErrValidation) {
+		t.Errorf("Expected the credit to be refused, got: %v", err)
+	}
+	if _, err := s.ProcessTransaction(1, models.TransactionRequest{State: "lose", Amount: "1.00", TransactionID: "r2"}, "game"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(applied) != 1 || applied[0] != "r2=99.00" {
+		t.Errorf("Expected the after hook to see r2, got: %v", applied)
+	}
+}
+
+func TestStore_ConcurrentTransactions(t *testing.T) {
+	s := newTestService(t)
+
+	// Every transaction is applied once however the writes race
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req := models.TransactionRequest{State: "win", Amount: "1.00", TransactionID: fmt.Sprintf("c%d", i%4)}
+			if _, err := s.ProcessTransaction(2, req, "game"); err != nil {
+				t.Errorf("Expected no error, got: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	balance, _ := s.GetBalance(2)
+	if balance.Balance != "54.00" {
+		t.Errorf("Expected balance 54.00, got: %s", balance.Balance)
+	}
+}
+
+func TestStore_HTTP(t *testing.T) {
+	h := handlers.NewHandlers(newTestService(t))
+	mux := handlers.NewRouter()
+	mux.HandleFunc("GET", "/user/{userId}", h.HandleGetUser)
+	mux.HandleFunc("POST", "/user/{userId}/transaction", h.HandleTransaction)
+	mux.HandleFunc("GET", "/user/{userId}/balance", h.HandleGetBalance)
+
+	body, _ := json.Marshal(models.TransactionRequest{State: "lose", Amount: "5.00", TransactionID: "h1"})
+	for _, wantReplayed := range []string{"", "true"} {
+		req := httptest.NewRequest("POST", "/user/1/transaction", bytes.NewReader(body))
+		req.Header.Set("Source-Type", "game")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != http.StatusOK || w.Header().Get("Idempotent-Replayed") != wantReplayed {
+			t.Fatalf("Expected 200 with Idempotent-Replayed %q, got: %d %v %s", wantReplayed, w.Code, w.Header(), w.Body)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/user/1/balance", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	var balance models.BalanceResponse
+	json.NewDecoder(w.Body).Decode(&balance)
+	if w.Code != http.StatusOK || balance.Balance != "95.00" || w.Header().Get("ETag") == "" {
+		t.Errorf("Expected balance 95.00, got: %d %+v", w.Code, balance)
+	}
+
+	req = httptest.NewRequest("GET", "/user/99", nil)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404, got: %d", w.Code)
+	}
+}
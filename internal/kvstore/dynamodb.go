@@ -0,0 +1,187 @@
+package kvstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"assignment/internal/clock"
+	"assignment/internal/sigv4"
+)
+
+// DynamoDBConfig configures a DynamoDB table used as a Store. The table has
+// the string partition key "pk"; items keep their value in the binary
+// attribute "value" and their version in the number attribute "version".
+type DynamoDBConfig struct {
+	// Endpoint is e.g. https://dynamodb.eu-west-1.amazonaws.com, or the
+	// address of a compatible store such as DynamoDB Local or ScyllaDB
+	// Alternator
+	Endpoint        string
+	Region          string
+	Table           string
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// DynamoDB is a Store in a DynamoDB table, written with conditional
+// TransactWriteItems calls over the JSON API, signed with AWS Signature
+// Version 4.
+type DynamoDB struct {
+	cfg        DynamoDBConfig
+	signer     sigv4.Signer
+	endpoint   *url.URL
+	clock      clock.Clock
+	httpClient *http.Client
+}
+
+func NewDynamoDB(cfg DynamoDBConfig, clk clock.Clock) (*DynamoDB, error) {
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid DynamoDB endpoint %q", cfg.Endpoint)
+	}
+	if cfg.Table == "" {
+		return nil, fmt.Errorf("DynamoDB table is required")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+
+	return &DynamoDB{
+		cfg: cfg,
+		signer: sigv4.Signer{
+			Service:         "dynamodb",
+			Region:          cfg.Region,
+			AccessKeyID:     cfg.AccessKeyID,
+			SecretAccessKey: cfg.SecretAccessKey,
+		},
+		endpoint:   endpoint,
+		clock:      clk,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// attributeValue is a DynamoDB attribute value of the types items use.
+type attributeValue struct {
+	S string `json:"S,omitempty"`
+	N string `json:"N,omitempty"`
+	B []byte `json:"B,omitempty"`
+}
+
+func (d *DynamoDB) Get(ctx context.Context, key string) (Item, error) {
+	var out struct {
+		Item map[string]attributeValue `json:"Item"`
+	}
+	err := d.call(ctx, "GetItem", map[string]interface{}{
+		"TableName":      d.cfg.Table,
+		"Key":            map[string]attributeValue{"pk": {S: key}},
+		"ConsistentRead": true,
+	}, &out)
+	if err != nil {
+		return Item{}, err
+	}
+	if out.Item == nil {
+		return Item{}, ErrNotFound
+	}
+	version, err := strconv.ParseInt(out.Item["version"].N, 10, 64)
+	if err != nil {
+		return Item{}, fmt.Errorf("invalid version of item %s: %w", key, err)
+	}
+	return Item{Key: key, Value: out.Item["value"].B, Version: version}, nil
+}
+
+func (d *DynamoDB) Transact(ctx context.Context, writes ...Write) error {
+	if err := checkWrites(writes); err != nil {
+		return err
+	}
+
+	items := make([]interface{}, len(writes))
+	for i, w := range writes {
+		put := map[string]interface{}{
+			"TableName": d.cfg.Table,
+			"Item": map[string]attributeValue{
+				"pk":      {S: w.Key},
+				"value":   {B: w.Value},
+				"version": {N: strconv.FormatInt(w.Version+1, 10)},
+			},
+			"ConditionExpression": "attribute_not_exists(pk)",
+		}
+		if w.Version > 0 {
+			// version is a reserved word in expressions
+			put["ConditionExpression"] = "#version = :version"
+			put["ExpressionAttributeNames"] = map[string]string{"#version": "version"}
+			put["ExpressionAttributeValues"] = map[string]attributeValue{":version": {N: strconv.FormatInt(w.Version, 10)}}
+		}
+		items[i] = map[string]interface{}{"Put": put}
+	}
+	return d.call(ctx, "TransactWriteItems", map[string]interface{}{"TransactItems": items}, nil)
+}
+
+// call invokes an operation of the DynamoDB API, decoding its output into
+// out unless it is nil. Transactions cancelled because a condition failed,
+// or another transaction wrote one of the items, are reported as
+// ErrConflict.
+func (d *DynamoDB) call(ctx context.Context, operation string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s request: %w", operation, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.endpoint.String(), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build %s request: %w", operation, err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.0")
+	req.Header.Set("X-Amz-Target", "DynamoDB_20120810."+operation)
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	d.signer.Sign(req, path, sigv4.PayloadHash(body), d.clock.Now(), "content-type", "x-amz-target")
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("DynamoDB %s failed: %w", operation, err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read DynamoDB %s response: %w", operation, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Type                string `json:"__type"`
+			Message             string `json:"message"`
+			CancellationReasons []struct {
+				Code string `json:"Code"`
+			} `json:"CancellationReasons"`
+		}
+		json.Unmarshal(respBody, &apiErr)
+		errType := apiErr.Type[strings.LastIndex(apiErr.Type, "#")+1:]
+		switch errType {
+		case "ConditionalCheckFailedException":
+			return ErrConflict
+		case "TransactionCanceledException":
+			// Transactions are also cancelled for reasons that are no
+			// conflict, e.g. throttling or an invalid item
+			for _, reason := range apiErr.CancellationReasons {
+				if reason.Code == "ConditionalCheckFailed" || reason.Code == "TransactionConflict" {
+					return ErrConflict
+				}
+			}
+		}
+		return fmt.Errorf("DynamoDB %s failed with status %d: %s %s", operation, resp.StatusCode, errType, strings.TrimSpace(apiErr.Message))
+	}
+	if out != nil {
+		if err := json.Unmarshal(respBody, out); err != nil {
+			return fmt.Errorf("failed to decode DynamoDB %s response: %w", operation, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+// Package kvstore stores versioned items in a key-value store with
+// conditional writes, e.g. DynamoDB, for deployments where managed Postgres
+// is not available. Every write names the version of the item it replaces,
+// so concurrent writers of an item cannot overwrite each other: one of them
+// fails with ErrConflict and retries on what the other wrote.
+package kvstore
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrNotFound is returned for keys without an item.
+var ErrNotFound = errors.New("item not found")
+
+// ErrConflict is returned when a write's condition does not hold, i.e. the
+// item changed since it was read. Nothing of the transaction is written.
+var ErrConflict = errors.New("conditional write failed")
+
+// MaxTransactItems bounds the writes of a transaction, as DynamoDB does.
+const MaxTransactItems = 100
+
+// Item is a stored value and its version, which counts its writes.
+type Item struct {
+	Key     string
+	Value   []byte
+	Version int64
+}
+
+// Write stores Value under Key if the item is still at Version, 0 for a key
+// that must not have an item yet. The item then has version Version+1.
+type Write struct {
+	Key     string
+	Value   []byte
+	Version int64
+}
+
+// Store is a key-value store with conditional writes.
+type Store interface {
+	// Get returns the item of key with a strongly consistent read, or
+	// ErrNotFound.
+	Get(ctx context.Context, key string) (Item, error)
+	// Transact applies writes, to distinct keys, all or none: if any
+	// condition does not hold, it returns ErrConflict.
+	Transact(ctx context.Context, writes ...Write) error
+}
+
+// Memory is a Store in memory, for tests and single-instance deployments.
+// It is safe for concurrent use.
+type Memory struct {
+	mu    sync.Mutex
+	items map[string]Item
+}
+
+func NewMemory() *Memory {
+	return &Memory{items: map[string]Item{}}
+}
+
+func (m *Memory) Get(ctx context.Context, key string) (Item, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item, ok := m.items[key]
+	if !ok {
+		return Item{}, ErrNotFound
+	}
+	item.Value = append([]byte(nil), item.Value...)
+	return item, nil
+}
+
+func (m *Memory) Transact(ctx context.Context, writes ...Write) error {
+	if err := checkWrites(writes); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, w := range writes {
+		if m.items[w.Key].Version != w.Version {
+			return ErrConflict
+		}
+	}
+	for _, w := range writes {
+		m.items[w.Key] = Item{Key: w.Key, Value: append([]byte(nil), w.Value...), Version: w.Version + 1}
+	}
+	return nil
+}
+
+func checkWrites(writes []Write) error {
+	if len(writes) == 0 || len(writes) > MaxTransactItems {
+		return errors.New("a transaction writes 1 to 100 items")
+	}
+	keys := make(map[string]bool, len(writes))
+	for _, w := range writes {
+		if keys[w.Key] {
+			return errors.New("a transaction writes each key once")
+		}
+		keys[w.Key] = true
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package kvstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"assignment/internal/clock"
+)
+
+// fakeDynamoDB answers GetItem and TransactWriteItems like DynamoDB for the
+// conditions DynamoDB stores write.
+type fakeDynamoDB struct {
+	mu      sync.Mutex
+	items   map[string]map[string]attributeValue
+	headers http.Header
+}
+
+func (f *fakeDynamoDB) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.headers = r.Header.Clone()
+
+	body, _ := io.ReadAll(r.Body)
+	switch r.Header.Get("X-Amz-Target") {
+	case "DynamoDB_20120810.GetItem":
+		var in struct {
+			Key map[string]attributeValue
+		}
+		json.Unmarshal(body, &in)
+		out := map[string]interface{}{}
+		if item, ok := f.items[in.Key["pk"].S]; ok {
+			out["Item"] = item
+		}
+		json.NewEncoder(w).Encode(out)
+	case "DynamoDB_20120810.TransactWriteItems":
+		var in struct {
+			TransactItems []struct {
+				Put struct {
+					Item                      map[string]attributeValue
+					ConditionExpression       string
+					ExpressionAttributeValues map[string]attributeValue
+				}
+			}
+		}
+		json.Unmarshal(body, &in)
+		reasons := make([]map[string]string, len(in.TransactItems))
+		cancelled := false
+		for i, ti := range in.TransactItems {
+			stored, exists := f.items[ti.Put.Item["pk"].S]
+			ok := !exists
+			if ti.Put.ConditionExpression == "#version = :version" {
+				ok = exists && stored["version"].N == ti.Put.ExpressionAttributeValues[":version"].N
+			}
+			reasons[i] = map[string]string{"Code": "None"}
+			if !ok {
+				reasons[i] = map[string]string{"Code": "ConditionalCheckFailed", "Message": "The conditional request failed"}
+				cancelled = true
+			}
+		}
+		if cancelled {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"__type":              "com.amazonaws.dynamodb.v20120810#TransactionCanceledException",
+				"message":             "Transaction cancelled",
+				"CancellationReasons": reasons,
+			})
+			return
+		}
+		for _, ti := range in.TransactItems {
+			f.items[ti.Put.Item["pk"].S] = ti.Put.Item
+		}
+		w.Write([]byte(`{}`))
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"__type":"com.amazon.coral.service#UnknownOperationException"}`))
+	}
+}
+
+func newTestDynamoDB(t *testing.T) (*DynamoDB, *fakeDynamoDB) {
+	fake := &fakeDynamoDB{items: map[string]map[string]attributeValue{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	store, err := NewDynamoDB(DynamoDBConfig{
+		Endpoint:        server.URL,
+		Region:          "eu-west-1",
+		Table:           "wallet",
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "secret",
+	}, clock.NewFake(time.Date(2024, 1, 16, 2, 0, 0, 0, time.UTC)))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	return store, fake
+}
+
+func TestStores(t *testing.T) {
+	dynamo, _ := newTestDynamoDB(t)
+	for name, store := range map[string]Store{"memory": NewMemory(), "dynamodb": dynamo} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			if _, err := store.Get(ctx, "a"); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("Expected ErrNotFound, got: %v", err)
+			}
+
+			if err := store.Transact(ctx, Write{Key: "a", Value: []byte("1")}, Write{Key: "b", Value: []byte("2")}); err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			item, err := store.Get(ctx, "a")
+			if err != nil || string(item.Value) != "1" || item.Version != 1 {
+				t.Fatalf("Expected a at version 1, got: %+v, %v", item, err)
+			}
+
+			// A stale write fails, and so does the rest of its transaction
+			err = store.Transact(ctx, Write{Key: "a", Value: []byte("3"), Version: 1}, Write{Key: "b", Value: []byte("4")})
+			if !errors.Is(err, ErrConflict) {
+				t.Fatalf("Expected ErrConflict, got: %v", err)
+			}
+			if item, _ := store.Get(ctx, "a"); string(item.Value) != "1" {
+				t.Errorf("Expected a unchanged, got: %q", item.Value)
+			}
+
+			if err := store.Transact(ctx, Write{Key: "a", Value: []byte("3"), Version: 1}); err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if err := store.Transact(ctx, Write{Key: "a", Value: []byte("5"), Version: 1}); !errors.Is(err, ErrConflict) {
+				t.Errorf("Expected ErrConflict for a lost update, got: %v", err)
+			}
+			if item, _ := store.Get(ctx, "a"); string(item.Value) != "3" || item.Version != 2 {
+				t.Errorf("Expected a at version 2, got: %+v", item)
+			}
+
+			if err := store.Transact(ctx, Write{Key: "c"}, Write{Key: "c"}); err == nil {
+				t.Error("Expected an error for a key written twice")
+			}
+		})
+	}
+}
+
+func TestDynamoDB_Signs(t *testing.T) {
+	store, fake := newTestDynamoDB(t)
+	store.Get(context.Background(), "a")
+
+	auth := fake.headers.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20240116/eu-west-1/dynamodb/aws4_request, SignedHeaders=content-type;host;x-amz-date;x-amz-target, Signature=") {
+		t.Errorf("Unexpected Authorization header: %s", auth)
+	}
+	if fake.headers.Get("X-Amz-Date") != "20240116T020000Z" || fake.headers.Get("Content-Type") != "application/x-amz-json-1.0" {
+		t.Errorf("Unexpected headers: %v", fake.headers)
+	}
+}
+
+func TestDynamoDB_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"__type":"com.amazonaws.dynamodb.v20120810#ResourceNotFoundException","message":"Requested resource not found"}`))
+	}))
+	defer server.Close()
+
+	store, _ := NewDynamoDB(DynamoDBConfig{Endpoint: server.URL, Table: "missing"}, clock.New())
+	_, err := store.Get(context.Background(), "a")
+	if err == nil || errors.Is(err, ErrNotFound) || !strings.Contains(err.Error(), "ResourceNotFoundException Requested resource not found") {
+		t.Errorf("Expected the API error, got: %v", err)
+	}
+	if _, err := NewDynamoDB(DynamoDBConfig{Endpoint: "dynamodb", Table: "wallet"}, clock.New()); err == nil {
+		t.Error("Expected an invalid endpoint to be refused")
+	}
+}
+
+func TestDynamoDB_CancelledNotConflict(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"__type":"com.amazonaws.dynamodb.v20120810#TransactionCanceledException","message":"Transaction cancelled, please refer cancellation reasons for specific reasons [ThrottlingError]","CancellationReasons":[{"Code":"ThrottlingError","Message":"Throughput exceeds the current capacity"}]}`))
+	}))
+	defer server.Close()
+
+	// A throttled transaction may succeed when retried; it conflicted with nothing
+	store, _ := NewDynamoDB(DynamoDBConfig{Endpoint: server.URL, Table: "wallet"}, clock.New())
+	err := store.Transact(context.Background(), Write{Key: "a", Value: []byte("1")})
+	if err == nil || errors.Is(err, ErrConflict) || !strings.Contains(err.Error(), "TransactionCanceledException") {
+		t.Errorf("Expected the API error, got: %v", err)
+	}
+}
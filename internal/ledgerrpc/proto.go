@@ -0,0 +1,192 @@
+package ledgerrpc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"assignment/internal/models"
+)
+
+// Protobuf wire types used by ledger.proto.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// StreamRequest is the StreamTransactionsRequest message.
+type StreamRequest struct {
+	Cursor string
+	Since  time.Time
+	Follow bool
+}
+
+// encoder appends proto3 fields, omitting default values as proto3 does.
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) tag(field, wireType int) {
+	e.buf = binary.AppendUvarint(e.buf, uint64(field<<3|wireType))
+}
+
+func (e *encoder) varint(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	e.tag(field, wireVarint)
+	e.buf = binary.AppendUvarint(e.buf, v)
+}
+
+func (e *encoder) bytes(field int, b []byte) {
+	if len(b) == 0 {
+		return
+	}
+	e.tag(field, wireBytes)
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *encoder) string(field int, s string) {
+	e.bytes(field, []byte(s))
+}
+
+func (e *encoder) bool(field int, v bool) {
+	if v {
+		e.varint(field, 1)
+	}
+}
+
+// timestamp writes a google.protobuf.Timestamp; the zero time is omitted.
+func (e *encoder) timestamp(field int, t time.Time) {
+	if t.IsZero() {
+		return
+	}
+	var ts encoder
+	ts.varint(1, uint64(t.Unix()))
+	ts.varint(2, uint64(t.Nanosecond()))
+	e.tag(field, wireBytes)
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(ts.buf)))
+	e.buf = append(e.buf, ts.buf...)
+}
+
+// encodeRequest encodes a StreamTransactionsRequest.
+func encodeRequest(req StreamRequest) []byte {
+	var e encoder
+	e.string(1, req.Cursor)
+	e.timestamp(2, req.Since)
+	e.bool(3, req.Follow)
+	return e.buf
+}
+
+// encodeTransaction encodes a Transaction message for a ledger entry.
+func encodeTransaction(entry models.LedgerEntry) []byte {
+	var e encoder
+	e.string(1, formatCursor(entry.TxID, entry.ID))
+	e.varint(2, uint64(entry.ID))
+	e.varint(3, uint64(entry.UserID))
+	e.string(4, entry.TransactionID)
+	e.string(5, entry.State)
+	e.string(6, entry.Amount)
+	e.string(7, entry.SourceType)
+	e.timestamp(8, entry.CreatedAt)
+	return e.buf
+}
+
+// field is a single decoded field. Varint and fixed-width values are held in
+// v, length-delimited ones in b.
+type field struct {
+	num      int
+	wireType int
+	v        uint64
+	b        []byte
+}
+
+// decodeFields splits a message into its fields.
+func decodeFields(buf []byte) ([]field, error) {
+	var fields []field
+	for len(buf) > 0 {
+		key, n := binary.Uvarint(buf)
+		if n <= 0 {
+			return nil, errors.New("malformed field key")
+		}
+		buf = buf[n:]
+
+		f := field{num: int(key >> 3), wireType: int(key & 7)}
+		switch f.wireType {
+		case wireVarint:
+			f.v, n = binary.Uvarint(buf)
+			if n <= 0 {
+				return nil, fmt.Errorf("malformed varint in field %d", f.num)
+			}
+			buf = buf[n:]
+		case wireFixed64:
+			if len(buf) < 8 {
+				return nil, fmt.Errorf("truncated field %d", f.num)
+			}
+			f.v = binary.LittleEndian.Uint64(buf)
+			buf = buf[8:]
+		case wireFixed32:
+			if len(buf) < 4 {
+				return nil, fmt.Errorf("truncated field %d", f.num)
+			}
+			f.v = uint64(binary.LittleEndian.Uint32(buf))
+			buf = buf[4:]
+		case wireBytes:
+			size, n := binary.Uvarint(buf)
+			if n <= 0 || size > uint64(len(buf)-n) {
+				return nil, fmt.Errorf("truncated field %d", f.num)
+			}
+			f.b = buf[n : n+int(size)]
+			buf = buf[n+int(size):]
+		default:
+			return nil, fmt.Errorf("unsupported wire type %d in field %d", f.wireType, f.num)
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// decodeRequest decodes a StreamTransactionsRequest, ignoring unknown fields.
+func decodeRequest(buf []byte) (StreamRequest, error) {
+	var req StreamRequest
+	fields, err := decodeFields(buf)
+	if err != nil {
+		return req, err
+	}
+
+	for _, f := range fields {
+		switch {
+		case f.num == 1 && f.wireType == wireBytes:
+			req.Cursor = string(f.b)
+		case f.num == 2 && f.wireType == wireBytes:
+			if req.Since, err = decodeTimestamp(f.b); err != nil {
+				return req, err
+			}
+		case f.num == 3 && f.wireType == wireVarint:
+			req.Follow = f.v != 0
+		}
+	}
+	return req, nil
+}
+
+func decodeTimestamp(buf []byte) (time.Time, error) {
+	fields, err := decodeFields(buf)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var seconds, nanos int64
+	for _, f := range fields {
+		switch {
+		case f.num == 1 && f.wireType == wireVarint:
+			seconds = int64(f.v)
+		case f.num == 2 && f.wireType == wireVarint:
+			nanos = int64(int32(f.v))
+		}
+	}
+	return time.Unix(seconds, nanos).UTC(), nil
+}
@@ -0,0 +1,190 @@
+// Package ledgerrpc serves the Ledger gRPC service described in ledger.proto.
+//
+// The gRPC protocol is implemented directly on the standard library's
+// HTTP/2 server, so the server must be started with TLS for clients to
+// negotiate HTTP/2.
+package ledgerrpc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"assignment/internal/core"
+)
+
+// StreamTransactionsMethod is the gRPC path of the StreamTransactions RPC.
+const StreamTransactionsMethod = "/ledger.v1.Ledger/StreamTransactions"
+
+// gRPC status codes used by the server.
+const (
+	codeOK              = 0
+	codeInvalidArgument = 3
+	codeUnimplemented   = 12
+	codeInternal        = 13
+)
+
+// batchSize bounds the transactions read from the database at once.
+const batchSize = 500
+
+// maxRequestSize bounds the request message.
+const maxRequestSize = 1 << 20
+
+type Server struct {
+	service      *core.TransactionService
+	pollInterval time.Duration
+}
+
+// NewServer returns a handler for the Ledger service. Streams that follow the
+// ledger poll for new transactions every pollInterval.
+func NewServer(service *core.TransactionService, pollInterval time.Duration) *Server {
+	return &Server{service: service, pollInterval: pollInterval}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.ProtoMajor != 2 {
+		http.Error(w, "gRPC requires HTTP/2", http.StatusHTTPVersionNotSupported)
+		return
+	}
+	if r.Method != http.MethodPost || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		http.Error(w, "unsupported media type", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	// Errors before the stream starts are sent as trailers-only responses,
+	// with the status in the headers
+	w.Header().Set("Content-Type", "application/grpc")
+
+	if r.URL.Path != StreamTransactionsMethod {
+		finish(w, codeUnimplemented, "unknown method "+r.URL.Path)
+		return
+	}
+
+	msg, err := readMessage(r.Body)
+	if err != nil {
+		finish(w, codeInvalidArgument, err.Error())
+		return
+	}
+	req, err := decodeRequest(msg)
+	if err != nil {
+		finish(w, codeInvalidArgument, "invalid request: "+err.Error())
+		return
+	}
+
+	s.streamTransactions(w, r, req)
+}
+
+// streamTransactions sends every applied transaction after the request
+// cursor and, when following, keeps sending new ones until the client goes
+// away.
+func (s *Server) streamTransactions(w http.ResponseWriter, r *http.Request, req StreamRequest) {
+	afterTxID, afterID, err := parseCursor(req.Cursor)
+	if err != nil {
+		finish(w, codeInvalidArgument, err.Error())
+		return
+	}
+
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+
+	for {
+		entries, err := s.service.ReadLedger(afterTxID, afterID, req.Since, batchSize)
+		if err != nil {
+			log.Printf("Error streaming ledger: %v", err)
+			finish(w, codeInternal, "failed to read ledger")
+			return
+		}
+
+		for _, entry := range entries {
+			if err := writeMessage(w, encodeTransaction(entry)); err != nil {
+				// The client has gone away
+				return
+			}
+			afterTxID, afterID = entry.TxID, entry.ID
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		if len(entries) == batchSize {
+			continue
+		}
+		if !req.Follow {
+			finish(w, codeOK, "")
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(s.pollInterval):
+		}
+	}
+}
+
+// readMessage reads a single length-prefixed, uncompressed message.
+func readMessage(body io.Reader) ([]byte, error) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(body, prefix[:]); err != nil {
+		return nil, fmt.Errorf("failed to read request: %w", err)
+	}
+	if prefix[0] != 0 {
+		return nil, fmt.Errorf("compressed messages are not supported")
+	}
+
+	size := binary.BigEndian.Uint32(prefix[1:])
+	if size > maxRequestSize {
+		return nil, fmt.Errorf("request of %d bytes exceeds the %d byte limit", size, maxRequestSize)
+	}
+
+	msg := make([]byte, size)
+	if _, err := io.ReadFull(body, msg); err != nil {
+		return nil, fmt.Errorf("failed to read request: %w", err)
+	}
+	return msg, nil
+}
+
+// writeMessage writes msg with the gRPC length prefix.
+func writeMessage(w io.Writer, msg []byte) error {
+	frame := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(msg)))
+	_, err := w.Write(append(frame, msg...))
+	return err
+}
+
+// finish sets the call status, which is sent as trailers once the stream
+// has started.
+func finish(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Grpc-Status", strconv.Itoa(code))
+	if message != "" {
+		w.Header().Set("Grpc-Message", url.PathEscape(message))
+	}
+}
+
+// formatCursor encodes a stream position as "<txid>-<id>".
+func formatCursor(txID, id int64) string {
+	return fmt.Sprintf("%d-%d", txID, id)
+}
+
+// parseCursor decodes a cursor produced by formatCursor. An empty cursor
+// starts at the beginning of the ledger.
+func parseCursor(cursor string) (int64, int64, error) {
+	if cursor == "" {
+		return 0, 0, nil
+	}
+
+	txPart, idPart, ok := strings.Cut(cursor, "-")
+	txID, txErr := strconv.ParseInt(txPart, 10, 64)
+	id, idErr := strconv.ParseInt(idPart, 10, 64)
+	if !ok || txErr != nil || idErr != nil || txID < 0 || id < 0 {
+		return 0, 0, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	return txID, id, nil
+}
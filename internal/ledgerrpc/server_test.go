@@ -0,0 +1,136 @@
+package ledgerrpc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"assignment/internal/clock"
+	"assignment/internal/core"
+	"assignment/internal/testutil"
+)
+
+func TestDecodeRequest_RoundTrip(t *testing.T) {
+	want := StreamRequest{
+		Cursor: "42-7",
+		Since:  time.Date(2024, 1, 15, 12, 0, 0, 500, time.UTC),
+		Follow: true,
+	}
+
+	got, err := decodeRequest(encodeRequest(want))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if got.Cursor != want.Cursor || !got.Since.Equal(want.Since) || got.Follow != want.Follow {
+		t.Errorf("Expected %+v, got: %+v", want, got)
+	}
+}
+
+func TestParseCursor(t *testing.T) {
+	if txID, id, err := parseCursor(formatCursor(42, 7)); err != nil || txID != 42 || id != 7 {
+		t.Errorf("Expected 42, 7, got: %d, %d, %v", txID, id, err)
+	}
+	for _, cursor := range []string{"42", "a-7", "42-", "-1-7"} {
+		if _, _, err := parseCursor(cursor); err == nil {
+			t.Errorf("Expected error for cursor %q", cursor)
+		}
+	}
+}
+
+// call makes a StreamTransactions call over HTTP/2 and returns the decoded
+// response messages and the call status.
+func call(t *testing.T, server *httptest.Server, req StreamRequest) ([][]byte, string) {
+	t.Helper()
+
+	msg := encodeRequest(req)
+	body := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(body[1:], uint32(len(msg)))
+	body = append(body, msg...)
+
+	httpReq, _ := http.NewRequest(http.MethodPost, server.URL+StreamTransactionsMethod, bytes.NewReader(body))
+	httpReq.Header.Set("Content-Type", "application/grpc")
+	resp, err := server.Client().Do(httpReq)
+	if err != nil {
+		t.Fatalf("Failed to call server: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.ProtoMajor != 2 {
+		t.Fatalf("Expected HTTP/2, got: %s", resp.Proto)
+	}
+
+	var messages [][]byte
+	for {
+		message, err := readMessage(resp.Body)
+		if err != nil {
+			break
+		}
+		messages = append(messages, message)
+	}
+	io.Copy(io.Discard, resp.Body)
+
+	status := resp.Trailer.Get("Grpc-Status")
+	if status == "" {
+		status = resp.Header.Get("Grpc-Status")
+	}
+	return messages, status
+}
+
+func newTestServer(service *core.TransactionService) *httptest.Server {
+	server := httptest.NewUnstartedServer(NewServer(service, 10*time.Millisecond))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	return server
+}
+
+func TestStreamTransactions_InvalidCursor(t *testing.T) {
+	server := newTestServer(core.NewTransactionService(nil, clock.New()))
+	defer server.Close()
+
+	messages, status := call(t, server, StreamRequest{Cursor: "nope"})
+	if len(messages) != 0 {
+		t.Errorf("Expected no messages, got: %d", len(messages))
+	}
+	if status != "3" {
+		t.Errorf("Expected status 3 (InvalidArgument), got: %q", status)
+	}
+}
+
+func TestStreamTransactions(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	clk := clock.NewFake(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	service := core.NewTransactionService(db, clk)
+	for _, state := range []string{"win", "lose", "win"} {
+		req := testutil.NewTransactionRequest(state, "1.00")
+		if _, err := service.ProcessTransaction(1, req, "game"); err != nil {
+			t.Fatalf("Failed to process transaction: %v", err)
+		}
+	}
+
+	server := newTestServer(service)
+	defer server.Close()
+
+	messages, status := call(t, server, StreamRequest{})
+	if status != "0" {
+		t.Fatalf("Expected status 0, got: %q", status)
+	}
+	if len(messages) != 3 {
+		t.Fatalf("Expected 3 transactions, got: %d", len(messages))
+	}
+
+	// Resuming from the first transaction's cursor skips it
+	fields, err := decodeFields(messages[0])
+	if err != nil || fields[0].num != 1 {
+		t.Fatalf("Expected cursor as the first field, got: %v, %v", fields, err)
+	}
+	messages, status = call(t, server, StreamRequest{Cursor: string(fields[0].b)})
+	if status != "0" || len(messages) != 2 {
+		t.Errorf("Expected 2 transactions after the cursor, got: %d (status %q)", len(messages), status)
+	}
+}
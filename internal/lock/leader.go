@@ -0,0 +1,96 @@
+package lock
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Locker hands out locks, e.g. Redis or Postgres.
+type Locker interface {
+	TryLock(ctx context.Context, name string) (*Lease, error)
+}
+
+// Elector elects one replica of the service as leader, the one holding the
+// election lock, and runs work meant for a single instance there. When the
+// leader dies or loses the lock, another replica takes the lock at its next
+// attempt and starts the work.
+type Elector struct {
+	locker   Locker
+	name     string
+	interval time.Duration
+
+	mu      sync.Mutex
+	workers []func(ctx context.Context)
+	leader  bool
+}
+
+// NewElector creates an elector campaigning for the lock name every interval
+// while it isn't the leader.
+func NewElector(locker Locker, name string, interval time.Duration) *Elector {
+	return &Elector{locker: locker, name: name, interval: interval}
+}
+
+// Go registers work to run while this replica leads. fn must return once its
+// context is cancelled, which happens when leadership is lost. Workers must
+// be registered before Run.
+func (e *Elector) Go(fn func(ctx context.Context)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.workers = append(e.workers, fn)
+}
+
+// IsLeader reports whether this replica currently leads.
+func (e *Elector) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.leader
+}
+
+// Run blocks until ctx is cancelled, campaigning for leadership and running
+// the workers while it holds it.
+func (e *Elector) Run(ctx context.Context) {
+	for {
+		lease, err := e.locker.TryLock(ctx, e.name)
+		if err != nil {
+			log.Printf("Leader election failed: %v", err)
+		}
+		if lease != nil {
+			e.lead(lease)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(e.interval):
+		}
+	}
+}
+
+// lead runs the workers until the lease ends, and waits for them to return
+// before giving it up.
+func (e *Elector) lead(lease *Lease) {
+	e.mu.Lock()
+	e.leader = true
+	workers := e.workers
+	e.mu.Unlock()
+	log.Printf("Elected leader: running %d background workers", len(workers))
+
+	var wg sync.WaitGroup
+	for _, fn := range workers {
+		wg.Add(1)
+		go func(fn func(ctx context.Context)) {
+			defer wg.Done()
+			fn(lease.Context())
+		}(fn)
+	}
+	<-lease.Context().Done()
+	wg.Wait()
+	lease.Release()
+
+	e.mu.Lock()
+	e.leader = false
+	e.mu.Unlock()
+	log.Printf("No longer leader: background workers stopped")
+}
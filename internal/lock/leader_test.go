@@ -0,0 +1,78 @@
+package lock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"assignment/internal/testutil"
+)
+
+// waitFor polls cond until it holds or a second has passed.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); !cond(); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+	}
+}
+
+func TestElector_Failover(t *testing.T) {
+	locker := NewLocal()
+	started := make(chan string, 4)
+	newReplica := func(name string) *Elector {
+		e := NewElector(locker, "leader", 5*time.Millisecond)
+		e.Go(func(ctx context.Context) {
+			started <- name
+			<-ctx.Done()
+		})
+		return e
+	}
+
+	ctxA, stopA := context.WithCancel(context.Background())
+	a := newReplica("a")
+	go a.Run(ctxA)
+	if got := <-started; got != "a" {
+		t.Fatalf("Expected a to lead, got: %s", got)
+	}
+	waitFor(t, "a to lead", a.IsLeader)
+
+	ctxB, stopB := context.WithCancel(context.Background())
+	defer stopB()
+	b := newReplica("b")
+	go b.Run(ctxB)
+	time.Sleep(20 * time.Millisecond)
+	if b.IsLeader() || len(started) != 0 {
+		t.Fatal("Expected b to follow while a leads")
+	}
+
+	// a dies; b takes over
+	stopA()
+	if got := <-started; got != "b" {
+		t.Fatalf("Expected b to take over, got: %s", got)
+	}
+	waitFor(t, "a to step down", func() bool { return !a.IsLeader() })
+	waitFor(t, "b to lead", b.IsLeader)
+}
+
+func TestPostgres_TryLock(t *testing.T) {
+	db := testutil.NewDB(t)
+	a, b := NewPostgres(db, 10*time.Millisecond), NewPostgres(db, 10*time.Millisecond)
+	ctx := context.Background()
+
+	lease, err := a.TryLock(ctx, "leader")
+	if err != nil || lease == nil {
+		t.Fatalf("Expected to take the lock, got: %v, %v", lease, err)
+	}
+	if other, err := b.TryLock(ctx, "leader"); err != nil || other != nil {
+		t.Fatalf("Expected the lock to be held, got: %v, %v", other, err)
+	}
+
+	lease.Release()
+	other, err := b.TryLock(ctx, "leader")
+	if err != nil || other == nil {
+		t.Fatalf("Expected a released lock to be taken again, got: %v, %v", other, err)
+	}
+	other.Release()
+}
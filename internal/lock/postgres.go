@@ -0,0 +1,82 @@
+package lock
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Postgres hands out session-level advisory locks, each held on a connection
+// of its own for as long as the lock is. A replica that dies loses its
+// locks as soon as Postgres notices its connection is gone.
+type Postgres struct {
+	db       *sql.DB
+	interval time.Duration
+}
+
+// NewPostgres creates a locker on db that checks every interval that the
+// connections holding its locks are still alive.
+func NewPostgres(db *sql.DB, interval time.Duration) *Postgres {
+	return &Postgres{db: db, interval: interval}
+}
+
+// TryLock takes the named lock, or returns a nil Lease if another session
+// holds it. Unlike Redis locks, a released lock can be taken again at once.
+func (p *Postgres) TryLock(ctx context.Context, name string) (*Lease, error) {
+	conn, err := p.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to take lock %s: %w", name, err)
+	}
+	var locked bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock(hashtext('lock:' || $1))`, name).Scan(&locked); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to take lock %s: %w", name, err)
+	}
+	if !locked {
+		conn.Close()
+		return nil, nil
+	}
+
+	leaseCtx, cancel := context.WithCancel(ctx)
+	lease := &Lease{ctx: leaseCtx, cancel: cancel, done: make(chan struct{})}
+	go p.hold(lease, conn, name)
+	return lease, nil
+}
+
+// hold checks the connection holding the lock every interval until the
+// lease is released, and cancels the lease once the connection is lost.
+func (p *Postgres) hold(lease *Lease, conn *sql.Conn, name string) {
+	defer close(lease.done)
+	defer conn.Close()
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-lease.ctx.Done():
+			ctx, cancel := context.WithTimeout(context.Background(), p.interval)
+			defer cancel()
+			if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock(hashtext('lock:' || $1))`, name); err != nil {
+				discard(conn)
+			}
+			return
+		case <-ticker.C:
+		}
+
+		if err := conn.PingContext(lease.ctx); err != nil && lease.ctx.Err() == nil {
+			log.Printf("Lost lock %s: connection holding it failed: %v", name, err)
+			discard(conn)
+			lease.cancel()
+			return
+		}
+	}
+}
+
+// discard closes conn instead of returning it to the pool, so a lock it may
+// still hold goes with it.
+func discard(conn *sql.Conn) {
+	conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+}
@@ -0,0 +1,234 @@
+// Package lock provides locks shared by the replicas of the service, so that
+// work meant to run once, e.g. a daily job, runs on one replica at a time.
+package lock
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Lease is a held lock. It is renewed in the background until Release; its
+// context is cancelled once the lock is lost, e.g. because Redis could not be
+// reached to renew it in time, so the holder can stop working.
+type Lease struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Context is cancelled when the lock is lost or released.
+func (l *Lease) Context() context.Context {
+	return l.ctx
+}
+
+// Release stops renewing the lock. The lock is not deleted but expires one
+// TTL later, so replicas whose clocks run slightly behind don't take it
+// again to repeat the work that was just done.
+func (l *Lease) Release() {
+	l.cancel()
+	<-l.done
+}
+
+// Redis hands out locks stored as Redis keys with a TTL: a lock is taken
+// with SET NX PX and renewed while held, so a replica that dies loses its
+// locks one TTL later.
+type Redis struct {
+	addr     string
+	password string
+	db       int
+	ttl      time.Duration
+	timeout  time.Duration
+}
+
+// NewRedis creates a locker for the Redis server at rawURL, e.g.
+// redis://:password@redis:6379/0. Locks expire ttl after their holder last
+// renewed them.
+func NewRedis(rawURL string, ttl time.Duration) (*Redis, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "redis" || u.Host == "" {
+		return nil, fmt.Errorf("invalid Redis URL %q: must be redis://[:password@]host[:port][/db]", rawURL)
+	}
+	if ttl < 3*time.Second {
+		return nil, fmt.Errorf("invalid lock TTL %s: must be at least 3s", ttl)
+	}
+	r := &Redis{addr: u.Host, ttl: ttl, timeout: 5 * time.Second}
+	if u.Port() == "" {
+		r.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if password, ok := u.User.Password(); ok {
+		r.password = password
+	}
+	if path := strings.TrimPrefix(u.Path, "/"); path != "" {
+		if r.db, err = strconv.Atoi(path); err != nil || r.db < 0 {
+			return nil, fmt.Errorf("invalid Redis URL %q: database must be a number", rawURL)
+		}
+	}
+	return r, nil
+}
+
+// renewScript extends the TTL of a lock only if it is still held with the
+// given token, so a holder that lost its lock never extends someone else's.
+const renewScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("PEXPIRE", KEYS[1], ARGV[2]) else return 0 end`
+
+// TryLock takes the named lock, or returns a nil Lease if another holder
+// has it.
+func (r *Redis) TryLock(ctx context.Context, name string) (*Lease, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	key := "lock:" + name
+	reply, err := r.do(ctx, "SET", key, token, "NX", "PX", strconv.FormatInt(r.ttl.Milliseconds(), 10))
+	if err != nil {
+		return nil, fmt.Errorf("failed to take lock %s: %w", name, err)
+	}
+	if reply == nil {
+		return nil, nil
+	}
+
+	leaseCtx, cancel := context.WithCancel(ctx)
+	lease := &Lease{ctx: leaseCtx, cancel: cancel, done: make(chan struct{})}
+	go r.renew(lease, name, key, token)
+	return lease, nil
+}
+
+// renew extends the lock every third of its TTL until the lease is released,
+// and cancels the lease once the lock has been lost or could not be renewed
+// before it expires.
+func (r *Redis) renew(lease *Lease, name, key, token string) {
+	defer close(lease.done)
+	ticker := time.NewTicker(r.ttl / 3)
+	defer ticker.Stop()
+
+	renewed := time.Now()
+	for {
+		select {
+		case <-lease.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		reply, err := r.do(lease.ctx, "EVAL", renewScript, "1", key, token, strconv.FormatInt(r.ttl.Milliseconds(), 10))
+		switch {
+		case err == nil && reply == int64(1):
+			renewed = time.Now()
+			continue
+		case err == nil:
+			log.Printf("Lost lock %s: taken over by another holder", name)
+		case lease.ctx.Err() != nil:
+			return
+		case time.Since(renewed) < r.ttl-r.ttl/3:
+			log.Printf("Failed to renew lock %s: %v", name, err)
+			continue
+		default:
+			log.Printf("Lost lock %s: failed to renew it before it expired: %v", name, err)
+		}
+		lease.cancel()
+		return
+	}
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate lock token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// do runs a command on a new connection, after authenticating and selecting
+// the database. Locks are taken rarely, so connections are not pooled.
+func (r *Redis) do(ctx context.Context, args ...string) (interface{}, error) {
+	dialer := net.Dialer{Timeout: r.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", r.addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	deadline := time.Now().Add(r.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+	if r.password != "" {
+		if _, err := command(rw, "AUTH", r.password); err != nil {
+			return nil, err
+		}
+	}
+	if r.db != 0 {
+		if _, err := command(rw, "SELECT", strconv.Itoa(r.db)); err != nil {
+			return nil, err
+		}
+	}
+	return command(rw, args...)
+}
+
+// command sends a command in the Redis serialization protocol (RESP) and
+// reads its reply.
+func command(rw *bufio.ReadWriter, args ...string) (interface{}, error) {
+	fmt.Fprintf(rw, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(rw, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if err := rw.Flush(); err != nil {
+		return nil, err
+	}
+	return readReply(rw.Reader)
+}
+
+// readReply reads a reply: a string, an int64, nil, or a []interface{} of
+// those. Error replies are returned as errors.
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || !strings.HasSuffix(line, "\r\n") {
+		return nil, fmt.Errorf("malformed Redis reply %q", line)
+	}
+	kind, value := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		return value, nil
+	case '-':
+		return nil, errors.New("redis: " + value)
+	case ':':
+		return strconv.ParseInt(value, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		b := make([]byte, n+2)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		return string(b[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = readReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("malformed Redis reply %q", line)
+}
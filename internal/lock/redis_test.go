@@ -0,0 +1,197 @@
+package lock
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis serves the commands the locker uses from an in-memory store.
+// TTLs are recorded but never expire.
+type fakeRedis struct {
+	ln       net.Listener
+	password string
+
+	mu   sync.Mutex
+	keys map[string]string
+	ttls map[string]string
+	down bool
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	f := &fakeRedis{ln: ln, password: password, keys: map[string]string{}, ttls: map[string]string{}}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authed := f.password == ""
+	for {
+		reply, err := readReply(r)
+		if err != nil {
+			return
+		}
+		var args []string
+		for _, arg := range reply.([]interface{}) {
+			args = append(args, arg.(string))
+		}
+
+		f.mu.Lock()
+		var out string
+		switch {
+		case f.down:
+			out = "-LOADING Redis is loading the dataset in memory\r\n"
+		case args[0] == "AUTH":
+			authed = args[1] == f.password
+			out = "+OK\r\n"
+			if !authed {
+				out = "-WRONGPASS invalid password\r\n"
+			}
+		case !authed:
+			out = "-NOAUTH Authentication required\r\n"
+		case args[0] == "SELECT":
+			out = "+OK\r\n"
+		case args[0] == "SET":
+			if _, ok := f.keys[args[1]]; ok {
+				out = "$-1\r\n"
+			} else {
+				f.keys[args[1]], f.ttls[args[1]] = args[2], args[5]
+				out = "+OK\r\n"
+			}
+		case args[0] == "EVAL" && args[1] == renewScript:
+			out = ":0\r\n"
+			if f.keys[args[3]] == args[4] {
+				f.ttls[args[3]] = args[5]
+				out = ":1\r\n"
+			}
+		default:
+			out = fmt.Sprintf("-ERR unknown command '%s'\r\n", args[0])
+		}
+		f.mu.Unlock()
+		conn.Write([]byte(out))
+	}
+}
+
+func (f *fakeRedis) url(password string) string {
+	if password != "" {
+		return "redis://:" + password + "@" + f.ln.Addr().String() + "/2"
+	}
+	return "redis://" + f.ln.Addr().String()
+}
+
+func TestNewRedis(t *testing.T) {
+	r, err := NewRedis("redis://:secret@cache/3", 30*time.Second)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if r.addr != "cache:6379" || r.password != "secret" || r.db != 3 {
+		t.Errorf("Unexpected locker: %+v", r)
+	}
+
+	for _, invalid := range []string{"cache:6379", "http://cache", "redis://cache/x"} {
+		if _, err := NewRedis(invalid, 30*time.Second); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+	if _, err := NewRedis("redis://cache", time.Second); err == nil {
+		t.Error("Expected an error for a TTL too short to renew")
+	}
+}
+
+func TestRedis_TryLock(t *testing.T) {
+	server := newFakeRedis(t, "secret")
+	a, _ := NewRedis(server.url("secret"), 30*time.Second)
+	b, _ := NewRedis(server.url("secret"), 30*time.Second)
+	ctx := context.Background()
+
+	lease, err := a.TryLock(ctx, "settlement")
+	if err != nil || lease == nil {
+		t.Fatalf("Expected to take the lock, got: %v, %v", lease, err)
+	}
+	if server.ttls["lock:settlement"] != "30000" {
+		t.Errorf("Expected a 30s TTL, got: %q", server.ttls["lock:settlement"])
+	}
+
+	other, err := b.TryLock(ctx, "settlement")
+	if err != nil || other != nil {
+		t.Errorf("Expected the lock to be held, got: %v, %v", other, err)
+	}
+
+	// Released locks are left to expire
+	lease.Release()
+	if lease.Context().Err() == nil {
+		t.Error("Expected the lease context to be cancelled")
+	}
+	if other, _ := b.TryLock(ctx, "settlement"); other != nil {
+		t.Error("Expected a released lock to be held until it expires")
+	}
+
+	wrong, _ := NewRedis(server.url("wrong"), 30*time.Second)
+	if _, err := wrong.TryLock(ctx, "export"); err == nil || !strings.Contains(err.Error(), "WRONGPASS") {
+		t.Errorf("Expected an authentication error, got: %v", err)
+	}
+}
+
+func TestRedis_Renewal(t *testing.T) {
+	server := newFakeRedis(t, "")
+	r, _ := NewRedis(server.url(""), 3*time.Second)
+	r.ttl = 30 * time.Millisecond
+
+	lease, err := r.TryLock(context.Background(), "retention")
+	if err != nil || lease == nil {
+		t.Fatalf("Expected to take the lock, got: %v, %v", lease, err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if lease.Context().Err() != nil {
+		t.Fatal("Expected the lock to be renewed")
+	}
+
+	// Another holder took over, e.g. after the lock expired during a pause
+	server.mu.Lock()
+	server.keys["lock:retention"] = "someone else"
+	server.mu.Unlock()
+	select {
+	case <-lease.Context().Done():
+	case <-time.After(time.Second):
+		t.Fatal("Expected the lease to be cancelled once the lock was lost")
+	}
+	lease.Release()
+}
+
+func TestRedis_RenewalFailure(t *testing.T) {
+	server := newFakeRedis(t, "")
+	r, _ := NewRedis(server.url(""), 3*time.Second)
+	r.ttl = 30 * time.Millisecond
+
+	lease, _ := r.TryLock(context.Background(), "export")
+	server.mu.Lock()
+	server.down = true
+	server.mu.Unlock()
+
+	select {
+	case <-lease.Context().Done():
+	case <-time.After(time.Second):
+		t.Fatal("Expected the lease to be cancelled once the lock could not be renewed")
+	}
+	lease.Release()
+}
@@ -0,0 +1,41 @@
+// Package metrics records service metrics through a backend chosen by
+// configuration: Prometheus, scraped from /metrics, or StatsD/DogStatsD,
+// pushed to an agent.
+package metrics
+
+import (
+	"sort"
+	"time"
+)
+
+// Labels qualify a metric, e.g. {"status": "200"}. They become Prometheus
+// labels and DogStatsD tags.
+type Labels map[string]string
+
+// Metrics records metrics. Implementations are safe for concurrent use.
+type Metrics interface {
+	// Inc increments a counter.
+	Inc(name string, labels Labels)
+	// Timing records a duration, e.g. of a request.
+	Timing(name string, d time.Duration, labels Labels)
+	// Gauge sets a value that can go up and down.
+	Gauge(name string, value float64, labels Labels)
+}
+
+// Nop discards all metrics.
+type Nop struct{}
+
+func (Nop) Inc(string, Labels)                   {}
+func (Nop) Timing(string, time.Duration, Labels) {}
+func (Nop) Gauge(string, float64, Labels)        {}
+
+// sortedKeys returns the label names in order, so series and tags are
+// formatted deterministically.
+func sortedKeys(labels Labels) []string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
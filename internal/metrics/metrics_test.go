@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrometheus(t *testing.T) {
+	p := NewPrometheus()
+	p.Inc("transactions_total", Labels{"outcome": "applied"})
+	p.Inc("transactions_total", Labels{"outcome": "applied"})
+	p.Inc("transactions_total", Labels{"outcome": `say "hi"`})
+	p.Gauge("queue_depth", 3, nil)
+	p.Timing("request_duration_seconds", 20*time.Millisecond, Labels{"route": "/user/{id}"})
+
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE transactions_total counter\n",
+		`transactions_total{outcome="applied"} 2` + "\n",
+		`transactions_total{outcome="say \"hi\""} 1` + "\n",
+		"# TYPE queue_depth gauge\nqueue_depth 3\n",
+		"# TYPE request_duration_seconds histogram\n",
+		`request_duration_seconds_bucket{route="/user/{id}",le="0.01"} 0` + "\n",
+		`request_duration_seconds_bucket{route="/user/{id}",le="0.025"} 1` + "\n",
+		`request_duration_seconds_bucket{route="/user/{id}",le="+Inf"} 1` + "\n",
+		`request_duration_seconds_sum{route="/user/{id}"} 0.02` + "\n",
+		`request_duration_seconds_count{route="/user/{id}"} 1` + "\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected exposition to contain %q, got:\n%s", want, body)
+		}
+	}
+}
+
+func TestStatsD(t *testing.T) {
+	agent, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer agent.Close()
+
+	s, err := NewStatsD(agent.LocalAddr().String(), "wallet")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	s.Inc("transactions_total", Labels{"outcome": "applied", "currency": "EUR"})
+	s.Timing("request_duration", 1500*time.Microsecond, nil)
+	s.Gauge("queue_depth", 2.5, nil)
+
+	agent.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 512)
+	for _, want := range []string{
+		"wallet.transactions_total:1|c|#currency:EUR,outcome:applied",
+		"wallet.request_duration:1.5|ms",
+		"wallet.queue_depth:2.5|g",
+	} {
+		n, _, err := agent.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("Failed to read metric: %v", err)
+		}
+		if got := string(buf[:n]); got != want {
+			t.Errorf("Expected %q, got %q", want, got)
+		}
+	}
+}
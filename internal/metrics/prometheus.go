@@ -0,0 +1,149 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// durationBuckets are the upper bounds, in seconds, of the histogram buckets
+// durations are counted in.
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type series struct {
+	labels  string
+	value   float64
+	buckets []uint64
+	count   uint64
+}
+
+type family struct {
+	kind   string
+	series map[string]*series
+}
+
+// Prometheus keeps metrics in memory and serves them in the Prometheus text
+// format. Timings are histograms in seconds, so their names should end in
+// _seconds.
+type Prometheus struct {
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+func NewPrometheus() *Prometheus {
+	return &Prometheus{families: map[string]*family{}}
+}
+
+// get returns the series of a metric, creating it if needed.
+func (p *Prometheus) get(name, kind string, labels Labels) *series {
+	f, ok := p.families[name]
+	if !ok {
+		f = &family{kind: kind, series: map[string]*series{}}
+		p.families[name] = f
+	}
+	key := formatLabels(labels)
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labels: key}
+		if kind == "histogram" {
+			s.buckets = make([]uint64, len(durationBuckets))
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+func (p *Prometheus) Inc(name string, labels Labels) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.get(name, "counter", labels).value++
+}
+
+func (p *Prometheus) Timing(name string, d time.Duration, labels Labels) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := p.get(name, "histogram", labels)
+	seconds := d.Seconds()
+	for i, bound := range durationBuckets {
+		if seconds <= bound {
+			s.buckets[i]++
+		}
+	}
+	s.value += seconds
+	s.count++
+}
+
+func (p *Prometheus) Gauge(name string, value float64, labels Labels) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.get(name, "gauge", labels).value = value
+}
+
+// ServeHTTP serves the metrics in the Prometheus text exposition format.
+func (p *Prometheus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	fmt.Fprint(w, p.format())
+}
+
+func (p *Prometheus) format() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	names := make([]string, 0, len(p.families))
+	for name := range p.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		f := p.families[name]
+		fmt.Fprintf(&b, "# TYPE %s %s\n", name, f.kind)
+		keys := make([]string, 0, len(f.series))
+		for key := range f.series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			s := f.series[key]
+			if f.kind != "histogram" {
+				fmt.Fprintf(&b, "%s%s %g\n", name, braces(s.labels), s.value)
+				continue
+			}
+			for i, bound := range durationBuckets {
+				fmt.Fprintf(&b, "%s_bucket%s %d\n", name, braces(join(s.labels, fmt.Sprintf(`le="%g"`, bound))), s.buckets[i])
+			}
+			fmt.Fprintf(&b, "%s_bucket%s %d\n", name, braces(join(s.labels, `le="+Inf"`)), s.count)
+			fmt.Fprintf(&b, "%s_sum%s %g\n", name, braces(s.labels), s.value)
+			fmt.Fprintf(&b, "%s_count%s %d\n", name, braces(s.labels), s.count)
+		}
+	}
+	return b.String()
+}
+
+// formatLabels formats labels as name="value" pairs, sorted by name.
+func formatLabels(labels Labels) string {
+	pairs := make([]string, 0, len(labels))
+	for _, k := range sortedKeys(labels) {
+		v := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(labels[k])
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, k, v))
+	}
+	return strings.Join(pairs, ",")
+}
+
+func join(labels, label string) string {
+	if labels == "" {
+		return label
+	}
+	return labels + "," + label
+}
+
+func braces(labels string) string {
+	if labels == "" {
+		return ""
+	}
+	return "{" + labels + "}"
+}
@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StatsD pushes metrics over UDP to a StatsD agent, with labels as
+// DogStatsD tags (name:1|c|#status:200), as the Datadog agent expects.
+// Timings are sent in milliseconds. Send errors are ignored: metrics must
+// never fail requests.
+type StatsD struct {
+	conn   net.Conn
+	prefix string
+}
+
+// NewStatsD creates a client for the agent at addr (host:port). prefix, if
+// set, is prepended to metric names with a dot.
+func NewStatsD(addr, prefix string) (*StatsD, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to StatsD agent: %w", err)
+	}
+	if prefix != "" {
+		prefix += "."
+	}
+	return &StatsD{conn: conn, prefix: prefix}, nil
+}
+
+func (s *StatsD) Inc(name string, labels Labels) {
+	s.send(name, "1", "c", labels)
+}
+
+func (s *StatsD) Timing(name string, d time.Duration, labels Labels) {
+	s.send(name, strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', -1, 64), "ms", labels)
+}
+
+func (s *StatsD) Gauge(name string, value float64, labels Labels) {
+	s.send(name, strconv.FormatFloat(value, 'f', -1, 64), "g", labels)
+}
+
+func (s *StatsD) send(name, value, kind string, labels Labels) {
+	line := s.prefix + name + ":" + value + "|" + kind
+	if len(labels) > 0 {
+		tags := make([]string, 0, len(labels))
+		for _, k := range sortedKeys(labels) {
+			tags = append(tags, k+":"+labels[k])
+		}
+		line += "|#" + strings.Join(tags, ",")
+	}
+	s.conn.Write([]byte(line))
+}
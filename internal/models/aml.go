@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// AMLFlag is a transaction that matched an AML rule, queued for review.
+type AMLFlag struct {
+	ID int64 `json:"id"`
+	// UserID is the user owning the account the transaction was applied to,
+	// AccountID the account itself: the user or one of its wallets
+	UserID        int64      `json:"userId"`
+	AccountID     int64      `json:"accountId"`
+	TransactionID string     `json:"transactionId"`
+	Rule          string     `json:"rule"`
+	Detail        string     `json:"detail"`
+	Status        string     `json:"status"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	Resolution    string     `json:"resolution,omitempty"`
+	ResolvedAt    *time.Time `json:"resolvedAt,omitempty"`
+}
+
+// AMLFlagList is a page of the AML review queue.
+type AMLFlagList struct {
+	Flags []AMLFlag `json:"flags"`
+	// Cursors to the next and previous pages, if any
+	NextCursor string `json:"nextCursor,omitempty"`
+	PrevCursor string `json:"prevCursor,omitempty"`
+}
+
+// ResolveAMLFlagRequest closes an AML flag with the outcome of its review.
+type ResolveAMLFlagRequest struct {
+	Resolution string `json:"resolution"`
+}
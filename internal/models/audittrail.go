@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// AuditTrail is everything recorded about a user over a period, for
+// regulators. Actor names the channel each change came through, e.g. the
+// Source-Type of a transaction.
+type AuditTrail struct {
+	User           *User               `json:"user"`
+	PeriodStart    time.Time           `json:"periodStart"`
+	PeriodEnd      time.Time           `json:"periodEnd"`
+	OpeningBalance string              `json:"openingBalance"`
+	ClosingBalance string              `json:"closingBalance"`
+	Transactions   []AuditTransaction  `json:"transactions"`
+	Rejections     []AuditRejection    `json:"rejections"`
+	Adjustments    []AuditAdjustment   `json:"adjustments"`
+	StatusChanges  []AuditStatusChange `json:"statusChanges"`
+}
+
+type AuditTransaction struct {
+	ID            int64     `json:"id"`
+	TransactionID string    `json:"transactionId"`
+	State         string    `json:"state"`
+	Amount        string    `json:"amount"`
+	BalanceAfter  string    `json:"balanceAfter"`
+	TransferID    string    `json:"transferId,omitempty"`
+	Actor         string    `json:"actor"`
+	Hash          string    `json:"hash,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+type AuditRejection struct {
+	TransactionID string    `json:"transactionId"`
+	State         string    `json:"state"`
+	Amount        string    `json:"amount"`
+	Reason        string    `json:"reason"`
+	Actor         string    `json:"actor"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// AuditAdjustment is a balance correction outside any transaction, e.g. by
+// a ledger repair.
+type AuditAdjustment struct {
+	Amount       string    `json:"amount"`
+	BalanceAfter string    `json:"balanceAfter"`
+	Actor        string    `json:"actor"`
+	RecordedAt   time.Time `json:"recordedAt"`
+}
+
+type AuditStatusChange struct {
+	FromStatus string    `json:"fromStatus"`
+	ToStatus   string    `json:"toStatus"`
+	Reason     string    `json:"reason"`
+	Actor      string    `json:"actor"`
+	ChangedAt  time.Time `json:"changedAt"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// DepositIntentRequest starts a deposit through the payment provider.
+type DepositIntentRequest struct {
+	DepositID string `json:"depositId"`
+	Amount    string `json:"amount"`
+}
+
+// Deposit is money collected by the payment provider and credited to the
+// balance of a user once the provider confirms the payment. ClientSecret,
+// which lets the app complete the payment with the provider, is only
+// returned when the intent is created.
+type Deposit struct {
+	ID                string `json:"id"`
+	UserID            int64  `json:"userId"`
+	Amount            string `json:"amount"`
+	Currency          string `json:"currency"`
+	Status            string `json:"status"`
+	ProviderPaymentID string `json:"providerPaymentId,omitempty"`
+	ClientSecret      string `json:"clientSecret,omitempty"`
+	// The chargeback reversing the deposit, if it was charged back
+	ChargebackReference string     `json:"chargebackReference,omitempty"`
+	ChargebackAmount    string     `json:"chargebackAmount,omitempty"`
+	ChargedBackAt       *time.Time `json:"chargedBackAt,omitempty"`
+	CreatedAt           time.Time  `json:"createdAt"`
+	UpdatedAt           time.Time  `json:"updatedAt"`
+}
+
+// ChargebackRequest reverses a deposit the payment provider charged back.
+// Amount defaults to the whole deposit.
+type ChargebackRequest struct {
+	Reference string `json:"reference"`
+	Amount    string `json:"amount"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// PushDevice is a mobile device registered for push notifications.
+type PushDevice struct {
+	UserID    int64     `json:"userId"`
+	Platform  string    `json:"platform"`
+	Token     string    `json:"token"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// PushDeviceRequest registers a device token issued by FCM or APNs.
+type PushDeviceRequest struct {
+	Platform string `json:"platform"`
+	Token    string `json:"token"`
+}
+
+// PushDeviceList are the devices registered by a user.
+type PushDeviceList struct {
+	UserID  int64        `json:"userId"`
+	Devices []PushDevice `json:"devices"`
+}
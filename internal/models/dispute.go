@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// Dispute contests the outcome of an applied transaction.
+type Dispute struct {
+	ID int64 `json:"id"`
+	// UserID is the account the disputed transaction was applied to: a user
+	// or one of its wallets
+	UserID        int64  `json:"userId"`
+	TransactionID string `json:"transactionId"`
+	Amount        string `json:"amount"`
+	Reason        string `json:"reason,omitempty"`
+	Held          bool   `json:"held"`
+	Status        string `json:"status"`
+	// Ledger entries applied for the dispute, if any: the hold taken when it
+	// was opened and the entry applying its resolution
+	HoldTransactionID       string     `json:"holdTransactionId,omitempty"`
+	ResolutionTransactionID string     `json:"resolutionTransactionId,omitempty"`
+	CreatedAt               time.Time  `json:"createdAt"`
+	ResolvedAt              *time.Time `json:"resolvedAt,omitempty"`
+}
+
+// DisputeList is a page of disputes.
+type DisputeList struct {
+	Disputes []Dispute `json:"disputes"`
+	// Cursors to the next and previous pages, if any
+	NextCursor string `json:"nextCursor,omitempty"`
+	PrevCursor string `json:"prevCursor,omitempty"`
+}
+
+// OpenDisputeRequest opens a dispute against a transaction. Amount defaults
+// to the transaction's; Hold takes it from the balance until the dispute is
+// resolved.
+type OpenDisputeRequest struct {
+	TransactionID string `json:"transactionId"`
+	Amount        string `json:"amount"`
+	Reason        string `json:"reason"`
+	Hold          bool   `json:"hold"`
+}
+
+// ResolveDisputeRequest resolves a dispute for or against the player, by
+// the status it moves to.
+type ResolveDisputeRequest struct {
+	Status string `json:"status"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+type EscrowAccount struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Balance   string    `json:"balance"`
+	Currency  string    `json:"currency"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type CreateEscrowRequest struct {
+	Name string `json:"name"`
+}
+
+// EscrowRequest moves funds between a user and an escrow account.
+type EscrowRequest struct {
+	UserID     int64  `json:"userId"`
+	Amount     string `json:"amount"`
+	TransferID string `json:"transferId"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// ExchangeRates are the rates of currencies against Base. Source is
+// "provider", or "fallback" when the provider has been unavailable for too
+// long.
+type ExchangeRates struct {
+	Base   string             `json:"base"`
+	Rates  map[string]float64 `json:"rates"`
+	AsOf   time.Time          `json:"asOf"`
+	Source string             `json:"source"`
+}
+
+// Conversion is an amount converted between currencies.
+type Conversion struct {
+	Amount    string    `json:"amount"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Rate      float64   `json:"rate"`
+	Converted string    `json:"converted"`
+	AsOf      time.Time `json:"asOf"`
+	Source    string    `json:"source"`
+}
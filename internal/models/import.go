@@ -0,0 +1,16 @@
+package models
+
+type ImportRowResult struct {
+	Row           int    `json:"row"`
+	UserID        int64  `json:"userId,omitempty"`
+	TransactionID string `json:"transactionId,omitempty"`
+	Status        string `json:"status"`
+	Message       string `json:"message"`
+	Balance       string `json:"balance,omitempty"`
+}
+
+type ImportReport struct {
+	Total   int               `json:"total"`
+	Counts  map[string]int    `json:"counts"`
+	Results []ImportRowResult `json:"results"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// NotificationPreferences are the alerts a user opted into. Empty thresholds
+// disable the corresponding alert, and no alert is sent without channels.
+type NotificationPreferences struct {
+	UserID int64 `json:"user_id"`
+	// BalanceBelow alerts when the balance drops below it
+	BalanceBelow string `json:"balance_below,omitempty"`
+	// LargeTransaction alerts on transactions of at least this amount
+	LargeTransaction string `json:"large_transaction,omitempty"`
+	// Phone is the E.164 number of the sms channel
+	Phone     string     `json:"phone,omitempty"`
+	Channels  []string   `json:"channels"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// NotificationPreferencesRequest replaces the notification preferences of a
+// user.
+type NotificationPreferencesRequest struct {
+	BalanceBelow     string   `json:"balance_below"`
+	LargeTransaction string   `json:"large_transaction"`
+	Phone            string   `json:"phone"`
+	Channels         []string `json:"channels"`
+}
+
+// Notification is an alert triggered by a transaction, delivered to the
+// notification gateway which sends it over the user's channels.
+type Notification struct {
+	ID            int64     `json:"id"`
+	UserID        int64     `json:"userId"`
+	WalletID      string    `json:"walletId,omitempty"`
+	Type          string    `json:"type"`
+	TransactionID string    `json:"transactionId"`
+	Amount        string    `json:"amount"`
+	Balance       string    `json:"balance"`
+	Threshold     string    `json:"threshold,omitempty"`
+	Phone         string    `json:"phone,omitempty"`
+	Channels      []string  `json:"channels"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// DataExport is everything stored about a user, in machine-readable form for
+// the user's right to data portability (GDPR Article 20). Wallet names the
+// wallet of wallet entries and is empty for the main account.
+type DataExport struct {
+	ExportedAt              time.Time                `json:"exported_at"`
+	User                    *User                    `json:"user"`
+	Wallets                 []Wallet                 `json:"wallets"`
+	Tags                    []string                 `json:"tags"`
+	NotificationPreferences *NotificationPreferences `json:"notification_preferences"`
+	PushDevices             []PushDevice             `json:"push_devices"`
+	Transactions            []ExportTransaction      `json:"transactions"`
+	Rejections              []ExportRejection        `json:"rejections"`
+	StatusChanges           []AuditStatusChange      `json:"status_changes"`
+	Deposits                []Deposit                `json:"deposits"`
+	Disputes                []Dispute                `json:"disputes"`
+}
+
+type ExportTransaction struct {
+	Wallet        string    `json:"wallet,omitempty"`
+	TransactionID string    `json:"transaction_id"`
+	State         string    `json:"state"`
+	Amount        string    `json:"amount"`
+	SourceType    string    `json:"source_type"`
+	Applied       bool      `json:"applied"`
+	TransferID    string    `json:"transfer_id,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type ExportRejection struct {
+	Wallet        string    `json:"wallet,omitempty"`
+	TransactionID string    `json:"transaction_id"`
+	State         string    `json:"state"`
+	Amount        string    `json:"amount"`
+	SourceType    string    `json:"source_type"`
+	Reason        string    `json:"reason"`
+	CreatedAt     time.Time `json:"created_at"`
+}
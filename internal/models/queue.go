@@ -0,0 +1,13 @@
+package models
+
+// QueueMessage is a transaction delivered by a message broker, positioned
+// by the partition offset it was read from.
+type QueueMessage struct {
+	Consumer    string             `json:"consumer"`
+	Topic       string             `json:"topic"`
+	Partition   int32              `json:"partition"`
+	Offset      int64              `json:"offset"`
+	UserID      int64              `json:"userId"`
+	SourceType  string             `json:"sourceType"`
+	Transaction TransactionRequest `json:"transaction"`
+}
@@ -0,0 +1,104 @@
+package models
+
+import "time"
+
+type SourceTotals struct {
+	// Currency is left out of statements, which are in the user's currency
+	Currency    string `json:"currency,omitempty"`
+	SourceType  string `json:"sourceType"`
+	Credits     string `json:"credits"`
+	CreditCount int64  `json:"creditCount"`
+	Debits      string `json:"debits"`
+	DebitCount  int64  `json:"debitCount"`
+	Net         string `json:"net"`
+}
+
+// CurrencyTotal is a sum of amounts in one currency. Amounts in different
+// currencies are never added up.
+type CurrencyTotal struct {
+	Currency string `json:"currency"`
+	Amount   string `json:"amount"`
+}
+
+type ReconciliationReport struct {
+	Date          string          `json:"date"`
+	Sources       []SourceTotals  `json:"sources"`
+	BalanceDeltas []CurrencyTotal `json:"balanceDeltas"`
+}
+
+type TransactionStats struct {
+	Currency   string `json:"currency"`
+	State      string `json:"state"`
+	SourceType string `json:"sourceType"`
+	Count      int64  `json:"count"`
+	Amount     string `json:"amount"`
+}
+
+type StatsResponse struct {
+	Window                      string             `json:"window"`
+	From                        time.Time          `json:"from"`
+	To                          time.Time          `json:"to"`
+	Transactions                []TransactionStats `json:"transactions"`
+	InsufficientFundsRejections int64              `json:"insufficientFundsRejections"`
+	UserCount                   int64              `json:"userCount"`
+	TotalBalances               []CurrencyTotal    `json:"totalBalances"`
+}
+
+type RankedUser struct {
+	Rank   int    `json:"rank"`
+	UserID int64  `json:"userId"`
+	Value  string `json:"value"`
+}
+
+type TopReport struct {
+	Metric   string       `json:"metric"`
+	Currency string       `json:"currency"`
+	Limit    int          `json:"limit"`
+	Users    []RankedUser `json:"users"`
+}
+
+type ProjectionRebuild struct {
+	RepairedUsers []int64 `json:"repairedUsers"`
+}
+
+type BalanceDivergence struct {
+	UserID     int64  `json:"userId"`
+	Balance    string `json:"balance"`
+	Replayed   string `json:"replayed"`
+	Difference string `json:"difference"`
+}
+
+type ReplayReport struct {
+	UsersChecked int                 `json:"usersChecked"`
+	Divergences  []BalanceDivergence `json:"divergences"`
+	// Users suspended because of their divergence, if any
+	Quarantined []int64 `json:"quarantined,omitempty"`
+}
+
+// TagFilter restricts a report to users tagged Tag, if set, and tagged none
+// of ExcludeTags.
+type TagFilter struct {
+	Tag         string
+	ExcludeTags []string
+}
+
+// HashChainReport is the outcome of verifying an account's hash chain.
+type HashChainReport struct {
+	UserID int64 `json:"userId"`
+	Valid  bool  `json:"valid"`
+	// Transactions whose hash and link were verified
+	Verified int `json:"verified"`
+	// Transactions recorded before hash chaining, which are not covered
+	Unchained int `json:"unchained"`
+	// Hash of the latest verified transaction, to compare with a copy kept
+	// elsewhere; empty if none was verified
+	Head string `json:"head"`
+	// The first transaction breaking the chain, if any
+	Break *HashChainBreak `json:"break,omitempty"`
+}
+
+type HashChainBreak struct {
+	ID            int64  `json:"id"`
+	TransactionID string `json:"transactionId"`
+	Reason        string `json:"reason"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// RetentionReport describes a retention run: the transactions older than
+// Cutoff that were, or in a dry run would be, purged or anonymized.
+type RetentionReport struct {
+	Mode   string    `json:"mode"`
+	Cutoff time.Time `json:"cutoff"`
+	DryRun bool      `json:"dryRun"`
+	// Transactions affected, the users they belong to and the oldest one
+	Transactions int64      `json:"transactions"`
+	Users        int64      `json:"users"`
+	Oldest       *time.Time `json:"oldest,omitempty"`
+	// Days whose settlement totals were stored before their transactions
+	// were removed
+	SettlementDays int `json:"settlementDays"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+type BalanceSnapshot struct {
+	Balance       string    `json:"balance"`
+	TransactionID string    `json:"transactionId,omitempty"`
+	RecordedAt    time.Time `json:"recordedAt"`
+}
+
+type BalanceHistoryResponse struct {
+	UserID    int64             `json:"userId"`
+	From      time.Time         `json:"from"`
+	To        time.Time         `json:"to"`
+	Snapshots []BalanceSnapshot `json:"snapshots"`
+	// Cursors to the next and previous pages, if any
+	NextCursor string `json:"nextCursor,omitempty"`
+	PrevCursor string `json:"prevCursor,omitempty"`
+}
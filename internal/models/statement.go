@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+type StatementLine struct {
+	TransactionID string    `json:"transactionId"`
+	State         string    `json:"state"`
+	SourceType    string    `json:"sourceType"`
+	Amount        string    `json:"amount"`
+	BalanceAfter  string    `json:"balanceAfter"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+type Statement struct {
+	UserID         int64           `json:"userId"`
+	Month          string          `json:"month"`
+	PeriodStart    time.Time       `json:"periodStart"`
+	PeriodEnd      time.Time       `json:"periodEnd"`
+	OpeningBalance string          `json:"openingBalance"`
+	ClosingBalance string          `json:"closingBalance"`
+	Transactions   []StatementLine `json:"transactions"`
+}
+
+type StatementSummary struct {
+	UserID         int64          `json:"userId"`
+	Month          string         `json:"month"`
+	PeriodStart    time.Time      `json:"periodStart"`
+	PeriodEnd      time.Time      `json:"periodEnd"`
+	OpeningBalance string         `json:"openingBalance"`
+	ClosingBalance string         `json:"closingBalance"`
+	Categories     []SourceTotals `json:"categories"`
+}
+
+// TaxSummary is a player's gambling activity over a tax year, across the
+// user's wallets: the amounts staked (debits), won (credits) and the
+// difference. Payments and transfers are not gambling and are left out.
+type TaxSummary struct {
+	UserID       int64     `json:"userId"`
+	Jurisdiction string    `json:"jurisdiction"`
+	TaxYear      string    `json:"taxYear"`
+	PeriodStart  time.Time `json:"periodStart"`
+	PeriodEnd    time.Time `json:"periodEnd"`
+	Currency     string    `json:"currency"`
+	Turnover     string    `json:"turnover"`
+	Winnings     string    `json:"winnings"`
+	// NetWinLoss is winnings less turnover, negative for a net loss
+	NetWinLoss       string `json:"netWinLoss"`
+	TransactionCount int    `json:"transactionCount"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// APIToken is a read-only token scoped to a single user. The token itself is
+// only returned when it is issued; the server stores a hash of it.
+type APIToken struct {
+	ID        int64      `json:"id"`
+	UserID    int64      `json:"userId"`
+	Token     string     `json:"token,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+}
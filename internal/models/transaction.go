@@ -8,6 +8,9 @@ type Transaction struct {
 	TransactionID string    `json:"transaction_id"`
 	State         string    `json:"state"`
 	Amount        string    `json:"amount"`
+	// Currency of Amount, only read where transactions of users of
+	// different currencies are listed together
+	Currency      string    `json:"currency,omitempty"`
 	SourceType    string    `json:"source_type"`
 	Applied       bool      `json:"applied"`
 	CreatedAt     time.Time `json:"created_at"`
@@ -21,13 +24,50 @@ type TransactionRequest struct {
 
 type TransactionResponse struct {
 	UserID        int64  `json:"userId"`
+	WalletID      string `json:"walletId,omitempty"`
 	TransactionID string `json:"transactionId"`
 	Balance       string `json:"balance"`
 	Message       string `json:"message"`
+	// Replayed is set when the transaction ID had been processed before and
+	// this is the response given then
+	Replayed bool `json:"-"`
+	// Reason is the code of why the transaction was not applied, e.g.
+	// "insufficient_funds" or "duplicate"; empty when it was applied
+	Reason string `json:"-"`
 }
 
 type BalanceResponse struct {
 	UserID  int64  `json:"userId"`
 	Balance string `json:"balance"`
+	// The moment a reconstructed balance is as of
+	At *time.Time `json:"at,omitempty"`
 }
 
+// BulkBalanceRequest asks for the balances of several users at once.
+type BulkBalanceRequest struct {
+	UserIDs []int64 `json:"userIds"`
+}
+
+// BulkBalanceResponse holds the balances of the users found, in the order
+// they were asked for, and the IDs of those not found.
+type BulkBalanceResponse struct {
+	Balances []BalanceResponse `json:"balances"`
+	NotFound []int64           `json:"notFound"`
+}
+
+// TransactionList is a page of stored transactions across users, newest
+// first.
+type TransactionList struct {
+	Transactions []Transaction `json:"transactions"`
+	// Cursors to the next (older) and previous (newer) pages, if any
+	NextCursor string `json:"nextCursor,omitempty"`
+	PrevCursor string `json:"prevCursor,omitempty"`
+}
+
+
+// LedgerEntry is a stored transaction together with the ID of the database
+// transaction that inserted it, which positions it in the ledger stream.
+type LedgerEntry struct {
+	Transaction
+	TxID int64 `json:"-"`
+}
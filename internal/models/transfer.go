@@ -0,0 +1,18 @@
+package models
+
+type TransferRequest struct {
+	FromUserID int64  `json:"fromUserId"`
+	ToUserID   int64  `json:"toUserId"`
+	Amount     string `json:"amount"`
+	TransferID string `json:"transferId"`
+}
+
+type TransferResponse struct {
+	TransferID  string `json:"transferId"`
+	FromUserID  int64  `json:"fromUserId"`
+	ToUserID    int64  `json:"toUserId"`
+	Amount      string `json:"amount"`
+	FromBalance string `json:"fromBalance"`
+	ToBalance   string `json:"toBalance"`
+	Message     string `json:"message"`
+}
@@ -3,9 +3,77 @@ package models
 import "time"
 
 type User struct {
-	ID        int64     `json:"id"`
-	Balance   string    `json:"balance"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID          int64  `json:"id"`
+	Balance     string `json:"balance"`
+	Currency    string `json:"currency"`
+	Status      string `json:"status"`
+	KYCLevel    string `json:"kyc_level"`
+	ExternalRef string `json:"external_ref,omitempty"`
+	DisplayName string `json:"display_name,omitempty"`
+	Country     string `json:"country,omitempty"`
+	MaxBalance  string `json:"max_balance,omitempty"`
+	// Frozen is "debits" or "all" while the user is frozen
+	Frozen       string     `json:"frozen,omitempty"`
+	FrozenReason string     `json:"frozen_reason,omitempty"`
+	FrozenAt     *time.Time `json:"frozen_at,omitempty"`
+	// ChargebackAt flags a user whose deposit was charged back; its
+	// balance may be negative
+	ChargebackAt *time.Time `json:"chargeback_at,omitempty"`
+	// AnonymizedAt is when the user's personal data was erased
+	AnonymizedAt *time.Time `json:"anonymized_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty"`
 }
 
+type CreateUserRequest struct {
+	Balance     string `json:"balance"`
+	Currency    string `json:"currency"`
+	ExternalRef string `json:"external_ref"`
+	DisplayName string `json:"display_name"`
+	Country     string `json:"country"`
+}
+
+// UpdateUserRequest changes the profile fields that are set; an empty string
+// clears a field.
+type UpdateUserRequest struct {
+	Currency    *string `json:"currency"`
+	ExternalRef *string `json:"external_ref"`
+	DisplayName *string `json:"display_name"`
+	Country     *string `json:"country"`
+}
+
+// UserStatusRequest moves a user to another lifecycle status.
+type UserStatusRequest struct {
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+}
+
+// FreezeRequest freezes a user's debits, and its credits too when Credits is
+// set.
+type FreezeRequest struct {
+	Credits bool   `json:"credits"`
+	Reason  string `json:"reason"`
+}
+
+// KYCLevelRequest changes the KYC level of a user.
+type KYCLevelRequest struct {
+	KYCLevel string `json:"kyc_level"`
+}
+
+// UserTags are the tags attached to a user, e.g. vip or test_account.
+type UserTags struct {
+	UserID int64    `json:"userId"`
+	Tags   []string `json:"tags"`
+}
+
+// AddUserTagsRequest attaches tags to a user.
+type AddUserTagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+// MaxBalanceRequest sets the maximum balance of a user; an empty maximum
+// removes it.
+type MaxBalanceRequest struct {
+	MaxBalance string `json:"max_balance"`
+}
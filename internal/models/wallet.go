@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+type Wallet struct {
+	WalletID  string    `json:"walletId"`
+	Balance   string    `json:"balance"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type WalletList struct {
+	UserID  int64    `json:"userId"`
+	Wallets []Wallet `json:"wallets"`
+}
+
+type CreateWalletRequest struct {
+	WalletID string `json:"walletId"`
+}
+
+type WalletBalanceResponse struct {
+	UserID   int64  `json:"userId"`
+	WalletID string `json:"walletId"`
+	Balance  string `json:"balance"`
+}
+
+// WalletMoveRequest moves funds between two wallets of the same user.
+type WalletMoveRequest struct {
+	FromWalletID string `json:"fromWalletId"`
+	ToWalletID   string `json:"toWalletId"`
+	Amount       string `json:"amount"`
+	MoveID       string `json:"moveId"`
+}
+
+type WalletMoveResponse struct {
+	MoveID       string `json:"moveId"`
+	UserID       int64  `json:"userId"`
+	FromWalletID string `json:"fromWalletId"`
+	ToWalletID   string `json:"toWalletId"`
+	Amount       string `json:"amount"`
+	FromBalance  string `json:"fromBalance"`
+	ToBalance    string `json:"toBalance"`
+	Message      string `json:"message"`
+}
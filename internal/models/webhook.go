@@ -0,0 +1,25 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// WebhookDeadLetter is a webhook delivery that failed every attempt.
+type WebhookDeadLetter struct {
+	ID         int64           `json:"id"`
+	URL        string          `json:"url"`
+	Payload    json.RawMessage `json:"payload"`
+	Attempts   int             `json:"attempts"`
+	LastError  string          `json:"lastError"`
+	CreatedAt  time.Time       `json:"createdAt"`
+	ReplayedAt *time.Time      `json:"replayedAt,omitempty"`
+}
+
+// WebhookDeadLetterList are the dead letters waiting to be replayed.
+type WebhookDeadLetterList struct {
+	DeadLetters []WebhookDeadLetter `json:"deadLetters"`
+	// Cursors to the next and previous pages, if any
+	NextCursor string `json:"nextCursor,omitempty"`
+	PrevCursor string `json:"prevCursor,omitempty"`
+}
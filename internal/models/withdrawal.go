@@ -0,0 +1,6 @@
+package models
+
+type WithdrawalRequest struct {
+	WithdrawalID string `json:"withdrawalId"`
+	Amount       string `json:"amount"`
+}
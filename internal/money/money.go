@@ -0,0 +1,86 @@
+// Package money knows how many decimals amounts of each currency have: the
+// minor unit exponent of ISO 4217, 2 for most currencies, 0 for e.g. JPY and
+// 3 for e.g. KWD. Amounts are validated and formatted with the exponent of
+// their currency.
+package money
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"assignment/internal/validation"
+)
+
+// MaxExponent is the largest exponent of the currencies supported.
+const MaxExponent = 3
+
+// DefaultExponent is the exponent of currencies not in the table.
+const DefaultExponent = 2
+
+// exponents are the ISO 4217 currencies whose minor unit exponent is not
+// DefaultExponent. Currencies with exponents over MaxExponent (CLF, UYW) are
+// not supported.
+var exponents = map[string]int{
+	// No minor unit
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0,
+	"KRW": 0, "PYG": 0, "RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0,
+	"XAF": 0, "XOF": 0, "XPF": 0,
+	// Thousandths
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+}
+
+// amountFormats match amounts with up to as many decimals as their index.
+var amountFormats = [MaxExponent + 1]*regexp.Regexp{
+	regexp.MustCompile(`^\d+$`),
+	regexp.MustCompile(`^\d+(\.\d)?$`),
+	regexp.MustCompile(`^\d+(\.\d{1,2})?$`),
+	regexp.MustCompile(`^\d+(\.\d{1,3})?$`),
+}
+
+// Exponent returns the number of decimals of amounts of currency, an ISO
+// 4217 code in either case.
+func Exponent(currency string) int {
+	if exp, ok := exponents[strings.ToUpper(currency)]; ok {
+		return exp
+	}
+	return DefaultExponent
+}
+
+// ValidateAmount checks that amount is a non-negative decimal string with at
+// most the decimals of currency. An empty currency accepts the decimals of
+// any currency, for amounts validated before their currency is known.
+func ValidateAmount(amount, currency string) error {
+	exp := MaxExponent
+	if currency != "" {
+		exp = Exponent(currency)
+	}
+	if amountFormats[exp].MatchString(amount) {
+		return nil
+	}
+	switch {
+	case currency == "":
+		return validation.Errorf("amount", "invalid amount format: must be a string with up to %d decimal places", exp)
+	case exp == 0:
+		return validation.Errorf("amount", "invalid amount format: %s amounts must be whole numbers", strings.ToUpper(currency))
+	default:
+		return validation.Errorf("amount", "invalid amount format: %s amounts must be strings with up to %d decimal places", strings.ToUpper(currency), exp)
+	}
+}
+
+// Format formats value with the decimals of currency, e.g. "12.50" for EUR
+// and "13" for JPY.
+func Format(value float64, currency string) string {
+	return strconv.FormatFloat(value, 'f', Exponent(currency), 64)
+}
+
+// ToMinor converts value to minor units of currency, e.g. cents.
+func ToMinor(value float64, currency string) int64 {
+	return int64(math.Round(value * math.Pow10(Exponent(currency))))
+}
+
+// FromMinor converts minor units of currency to a value.
+func FromMinor(minor int64, currency string) float64 {
+	return float64(minor) / math.Pow10(Exponent(currency))
+}
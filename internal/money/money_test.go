@@ -0,0 +1,65 @@
+package money
+
+import "testing"
+
+func TestExponent(t *testing.T) {
+	for currency, want := range map[string]int{"EUR": 2, "usd": 2, "JPY": 0, "KWD": 3, "XYZ": DefaultExponent} {
+		if got := Exponent(currency); got != want {
+			t.Errorf("Exponent(%s) = %d, want %d", currency, got, want)
+		}
+	}
+}
+
+func TestValidateAmount(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+		wantErr  bool
+	}{
+		{"10.50", "EUR", false},
+		{"10.5", "EUR", false},
+		{"10.500", "EUR", true},
+		{"1500", "JPY", false},
+		{"1500.0", "JPY", true},
+		{"1.250", "KWD", false},
+		{"1.2500", "KWD", true},
+		{"1.250", "", false},
+		{"-1.00", "EUR", true},
+		{"1e3", "EUR", true},
+		{"", "EUR", true},
+	}
+	for _, tt := range tests {
+		if err := ValidateAmount(tt.amount, tt.currency); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateAmount(%q, %q) error = %v, wantErr %v", tt.amount, tt.currency, err, tt.wantErr)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	for _, tt := range []struct {
+		value    float64
+		currency string
+		want     string
+	}{
+		{12.5, "EUR", "12.50"},
+		{1500, "JPY", "1500"},
+		{1.25, "KWD", "1.250"},
+		{-3, "EUR", "-3.00"},
+	} {
+		if got := Format(tt.value, tt.currency); got != tt.want {
+			t.Errorf("Format(%v, %s) = %s, want %s", tt.value, tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestMinorUnits(t *testing.T) {
+	if got := ToMinor(25.5, "EUR"); got != 2550 {
+		t.Errorf("Expected 2550 cents, got: %d", got)
+	}
+	if got := ToMinor(1500, "JPY"); got != 1500 {
+		t.Errorf("Expected 1500 yen, got: %d", got)
+	}
+	if got := FromMinor(1250, "KWD"); got != 1.25 {
+		t.Errorf("Expected 1.25, got: %v", got)
+	}
+}
@@ -0,0 +1,141 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// apnsTokenLifetime is how long a provider token is reused; APNs rejects
+// tokens older than an hour and refreshes more often than every 20 minutes.
+const apnsTokenLifetime = 50 * time.Minute
+
+// APNs sends push notifications to iOS devices through the Apple Push
+// Notification service, authenticating with a token signing key.
+type APNs struct {
+	key        *ecdsa.PrivateKey
+	keyID      string
+	teamID     string
+	topic      string
+	baseURL    string
+	httpClient *http.Client
+
+	mu       sync.Mutex
+	jwt      string
+	issuedAt time.Time
+}
+
+// NewAPNs creates an APNs provider from a .p8 signing key and its key ID, the
+// team ID and the app bundle ID. Production selects the production
+// environment rather than the sandbox used by development builds.
+func NewAPNs(p8 []byte, keyID, teamID, bundleID string, production bool) (*APNs, error) {
+	key, err := parsePKCS8Key(p8)
+	if err != nil {
+		return nil, err
+	}
+	ecKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("APNs key is not an ECDSA key")
+	}
+	baseURL := "https://api.sandbox.push.apple.com"
+	if production {
+		baseURL = "https://api.push.apple.com"
+	}
+	return &APNs{
+		key:     ecKey,
+		keyID:   keyID,
+		teamID:  teamID,
+		topic:   bundleID,
+		baseURL: baseURL,
+		// APNs only speaks HTTP/2, which the default transport negotiates
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (a *APNs) Push(ctx context.Context, token string, msg PushMessage) error {
+	jwt, err := a.token()
+	if err != nil {
+		return err
+	}
+
+	payload := map[string]interface{}{
+		"aps": map[string]interface{}{
+			"alert": map[string]string{"title": msg.Title, "body": msg.Body},
+			"sound": "default",
+		},
+	}
+	for k, v := range msg.Data {
+		payload[k] = v
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode APNs payload: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.baseURL+"/3/device/"+url.PathEscape(token), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build APNs request: %w", err)
+	}
+	req.Header.Set("Authorization", "bearer "+jwt)
+	req.Header.Set("apns-topic", a.topic)
+	req.Header.Set("apns-push-type", "alert")
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send push notification: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return nil
+	}
+	var reply struct {
+		Reason string `json:"reason"`
+	}
+	json.NewDecoder(resp.Body).Decode(&reply)
+	if resp.StatusCode == http.StatusGone || reply.Reason == "BadDeviceToken" {
+		return ErrUnregistered
+	}
+	return fmt.Errorf("APNs returned status %d: %s", resp.StatusCode, reply.Reason)
+}
+
+// token returns the provider token, signing a new one when it gets stale.
+func (a *APNs) token() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	if a.jwt != "" && now.Sub(a.issuedAt) < apnsTokenLifetime {
+		return a.jwt, nil
+	}
+
+	jwt, err := signJWT(
+		map[string]string{"alg": "ES256", "kid": a.keyID},
+		map[string]interface{}{"iss": a.teamID, "iat": now.Unix()},
+		func(input []byte) ([]byte, error) {
+			digest := sha256.Sum256(input)
+			r, s, err := ecdsa.Sign(rand.Reader, a.key, digest[:])
+			if err != nil {
+				return nil, err
+			}
+			// JWS wants the raw R || S, not ASN.1
+			signature := make([]byte, 64)
+			r.FillBytes(signature[:32])
+			s.FillBytes(signature[32:])
+			return signature, nil
+		},
+	)
+	if err != nil {
+		return "", err
+	}
+	a.jwt, a.issuedAt = jwt, now
+	return jwt, nil
+}
@@ -0,0 +1,88 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/smtp"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"assignment/internal/models"
+)
+
+// defaultEmailTemplate renders a notification as the subject line and body
+// of an email.
+const defaultEmailTemplate = `Subject: Large transaction: {{.Amount}} for user {{.UserID}}
+
+Transaction {{.TransactionID}} of {{.Amount}} was applied to user {{.UserID}}{{if .WalletID}} (wallet {{.WalletID}}){{end}} at {{.CreatedAt.Format "2006-01-02 15:04:05 MST"}}.
+The alert threshold is {{.Threshold}}; the balance after the transaction is {{.Balance}}.
+`
+
+// LoadEmailTemplate parses the email template in the file at path, or the
+// default template if path is empty. Templates are executed with a
+// models.Notification and must render a "Subject:" header line, an empty
+// line and the body.
+func LoadEmailTemplate(path string) (*template.Template, error) {
+	text := defaultEmailTemplate
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read email template: %w", err)
+		}
+		text = string(data)
+	}
+	tmpl, err := template.New("email").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse email template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// SMTPConfig configures an SMTP relay, e.g. smtp.sendgrid.net:587 with the
+// username "apikey" and an API key as password.
+type SMTPConfig struct {
+	Addr     string
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+// EmailSender emails notifications to a fixed list of recipients.
+type EmailSender struct {
+	cfg      SMTPConfig
+	tmpl     *template.Template
+	sendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+func NewEmailSender(cfg SMTPConfig, tmpl *template.Template) *EmailSender {
+	return &EmailSender{cfg: cfg, tmpl: tmpl, sendMail: smtp.SendMail}
+}
+
+// Send renders payload, a models.Notification, with the template and mails
+// it. SMTP delivery cannot be cancelled, so ctx is not used.
+func (e *EmailSender) Send(ctx context.Context, payload interface{}) error {
+	notification, ok := payload.(models.Notification)
+	if !ok {
+		return fmt.Errorf("unsupported email payload %T", payload)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\nTo: %s\r\nDate: %s\r\n", e.cfg.From, strings.Join(e.cfg.To, ", "), time.Now().Format(time.RFC1123Z))
+	if err := e.tmpl.Execute(&msg, notification); err != nil {
+		return fmt.Errorf("failed to render email: %w", err)
+	}
+
+	var auth smtp.Auth
+	if e.cfg.Username != "" {
+		host, _, _ := strings.Cut(e.cfg.Addr, ":")
+		auth = smtp.PlainAuth("", e.cfg.Username, e.cfg.Password, host)
+	}
+	if err := e.sendMail(e.cfg.Addr, auth, e.cfg.From, e.cfg.To, msg.Bytes()); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package notify
+
+import (
+	"context"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	"assignment/internal/models"
+)
+
+func TestEmailSender(t *testing.T) {
+	tmpl, err := LoadEmailTemplate("")
+	if err != nil {
+		t.Fatalf("Failed to load default template: %v", err)
+	}
+	sender := NewEmailSender(SMTPConfig{
+		Addr: "smtp.example.com:587",
+		From: "wallet@example.com",
+		To:   []string{"ops@example.com", "risk@example.com"},
+	}, tmpl)
+
+	var gotTo []string
+	var gotMsg string
+	sender.sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		gotTo, gotMsg = to, string(msg)
+		return nil
+	}
+
+	err = sender.Send(context.Background(), models.Notification{
+		UserID:        7,
+		Type:          "large_transaction",
+		TransactionID: "tx-big",
+		Amount:        "5000.00",
+		Balance:       "5100.00",
+		Threshold:     "1000.00",
+		CreatedAt:     time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(gotTo) != 2 {
+		t.Errorf("Expected 2 recipients, got: %v", gotTo)
+	}
+	for _, want := range []string{"To: ops@example.com, risk@example.com", "Subject: Large transaction: 5000.00 for user 7", "Transaction tx-big"} {
+		if !strings.Contains(gotMsg, want) {
+			t.Errorf("Expected email to contain %q, got:\n%s", want, gotMsg)
+		}
+	}
+
+	if err := sender.Send(context.Background(), "not a notification"); err == nil {
+		t.Error("Expected error for unsupported payload")
+	}
+}
@@ -0,0 +1,107 @@
+// Package notify runs the notifier that delivers queued user alerts to the
+// notification gateway.
+package notify
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"assignment/internal/core"
+	"assignment/internal/models"
+)
+
+// batchSize bounds the number of notifications delivered per database
+// transaction.
+const batchSize = 100
+
+// Sender delivers a notification payload, e.g. a webhook.Client.
+type Sender interface {
+	Send(ctx context.Context, payload interface{}) error
+}
+
+type Notifier struct {
+	service  *core.TransactionService
+	sender   Sender
+	channels map[string]Sender
+	interval time.Duration
+}
+
+// NewNotifier creates a notifier that polls the notification queue every
+// interval and hands notifications to sender, the notification gateway,
+// which may be nil.
+func NewNotifier(service *core.TransactionService, sender Sender, interval time.Duration) *Notifier {
+	return &Notifier{
+		service:  service,
+		sender:   sender,
+		channels: map[string]Sender{},
+		interval: interval,
+	}
+}
+
+// Route delivers notifications for channel through sender instead of the
+// notification gateway. Call it before Run.
+func (n *Notifier) Route(channel string, sender Sender) {
+	n.channels[channel] = sender
+}
+
+// Run blocks until ctx is cancelled, draining the queue at every tick.
+func (n *Notifier) Run(ctx context.Context) {
+	ticker := time.NewTicker(n.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n.Drain(ctx)
+		}
+	}
+}
+
+// Drain delivers queued notifications until the queue is empty or a delivery
+// fails, and returns the number delivered. Failed notifications stay queued
+// and are retried at the next tick.
+func (n *Notifier) Drain(ctx context.Context) int {
+	total := 0
+	for {
+		delivered, err := n.service.DeliverNotifications(batchSize, func(notification models.Notification) error {
+			return n.deliver(ctx, notification)
+		})
+		total += delivered
+		if err != nil {
+			log.Printf("Notification delivery failed: %v", err)
+			return total
+		}
+		if delivered < batchSize {
+			return total
+		}
+	}
+}
+
+// deliver sends a notification through the sender routed for each of its
+// channels, and once to the notification gateway for the other channels. A
+// notification failing on one channel is retried on all of them.
+func (n *Notifier) deliver(ctx context.Context, notification models.Notification) error {
+	var rest []string
+	for _, channel := range notification.Channels {
+		sender, ok := n.channels[channel]
+		if !ok {
+			rest = append(rest, channel)
+			continue
+		}
+		if err := sender.Send(ctx, notification); err != nil {
+			return err
+		}
+	}
+	if len(rest) == 0 {
+		return nil
+	}
+	if n.sender == nil {
+		log.Printf("Notification dropped: id=%d, no sender for channels %v", notification.ID, rest)
+		return nil
+	}
+	notification.Channels = rest
+	return n.sender.Send(ctx, notification)
+}
@@ -0,0 +1,78 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"assignment/internal/clock"
+	"assignment/internal/core"
+	"assignment/internal/models"
+	"assignment/internal/testutil"
+)
+
+type fakeSender struct {
+	sent []interface{}
+	fail bool
+}
+
+func (f *fakeSender) Send(ctx context.Context, payload interface{}) error {
+	if f.fail {
+		return errors.New("gateway unavailable")
+	}
+	f.sent = append(f.sent, payload)
+	return nil
+}
+
+func TestDrain(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := core.NewTransactionService(db, clock.New())
+	service.EnableNotifications()
+	_, err := service.SetNotificationPreferences(2, models.NotificationPreferencesRequest{
+		LargeTransaction: "1.00",
+		Channels:         []string{"sms"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to set preferences: %v", err)
+	}
+	if _, err := service.ProcessTransaction(2, testutil.NewTransactionRequest("win", "1.00"), "game"); err != nil {
+		t.Fatalf("Failed to process transaction: %v", err)
+	}
+
+	// Failed deliveries stay queued
+	sender := &fakeSender{fail: true}
+	notifier := NewNotifier(service, sender, 0)
+	if n := notifier.Drain(context.Background()); n != 0 {
+		t.Errorf("Expected nothing delivered, got: %d", n)
+	}
+
+	sender.fail = false
+	if n := notifier.Drain(context.Background()); n != 1 || len(sender.sent) != 1 {
+		t.Fatalf("Expected 1 notification delivered, got: %d", n)
+	}
+	notification := sender.sent[0].(models.Notification)
+	if notification.UserID != 2 || notification.Channels[0] != "sms" {
+		t.Errorf("Unexpected notification: %+v", notification)
+	}
+}
+
+func TestDeliverRoutesChannels(t *testing.T) {
+	gateway, email := &fakeSender{}, &fakeSender{}
+	notifier := NewNotifier(nil, gateway, 0)
+	notifier.Route(core.OpsEmailChannel, email)
+
+	if err := notifier.deliver(context.Background(), models.Notification{Channels: []string{core.OpsEmailChannel}}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if err := notifier.deliver(context.Background(), models.Notification{Channels: []string{"email", "push"}}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(email.sent) != 1 || len(gateway.sent) != 1 {
+		t.Fatalf("Expected one notification per sender, got: %d email, %d gateway", len(email.sent), len(gateway.sent))
+	}
+	if channels := gateway.sent[0].(models.Notification).Channels; len(channels) != 2 {
+		t.Errorf("Expected gateway to get both user channels, got: %v", channels)
+	}
+}
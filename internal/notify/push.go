@@ -0,0 +1,92 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"assignment/internal/models"
+)
+
+// ErrUnregistered is returned by a PushProvider for device tokens the
+// platform no longer accepts, e.g. after the app was uninstalled.
+var ErrUnregistered = errors.New("device token unregistered")
+
+// PushMessage is a push notification as shown on the device.
+type PushMessage struct {
+	Title string
+	Body  string
+	// Data is passed to the app along with the notification
+	Data map[string]string
+}
+
+// PushProvider sends a push notification to a device token of its platform.
+type PushProvider interface {
+	Push(ctx context.Context, token string, msg PushMessage) error
+}
+
+// DeviceStore looks up the devices of a user, e.g. a core.TransactionService.
+type DeviceStore interface {
+	GetPushDevices(userID int64) (*models.PushDeviceList, error)
+	UnregisterPushDevice(userID int64, token string) error
+}
+
+// PushSender pushes user alerts to the registered devices of their user.
+type PushSender struct {
+	devices   DeviceStore
+	providers map[string]PushProvider
+}
+
+func NewPushSender(devices DeviceStore) *PushSender {
+	return &PushSender{devices: devices, providers: map[string]PushProvider{}}
+}
+
+// Platform sends pushes to the devices of platform ("fcm" or "apns") through
+// provider. Devices of platforms without a provider are skipped. Call it
+// before the notifier runs.
+func (s *PushSender) Platform(platform string, provider PushProvider) {
+	s.providers[platform] = provider
+}
+
+// Send pushes payload, a models.Notification, to every device of its user.
+// Devices the platform reports as unregistered are removed.
+func (s *PushSender) Send(ctx context.Context, payload interface{}) error {
+	notification, ok := payload.(models.Notification)
+	if !ok {
+		return fmt.Errorf("unsupported push payload %T", payload)
+	}
+	list, err := s.devices.GetPushDevices(notification.UserID)
+	if err != nil {
+		return err
+	}
+
+	msg := PushMessage{
+		Title: "Balance update",
+		Body:  alertText(notification),
+		Data: map[string]string{
+			"type":          notification.Type,
+			"transactionId": notification.TransactionID,
+			"balance":       notification.Balance,
+			"walletId":      notification.WalletID,
+		},
+	}
+	for _, device := range list.Devices {
+		provider, ok := s.providers[device.Platform]
+		if !ok {
+			continue
+		}
+		err := provider.Push(ctx, device.Token, msg)
+		if errors.Is(err, ErrUnregistered) {
+			log.Printf("Push device unregistered by %s: userID=%d", device.Platform, device.UserID)
+			if err := s.devices.UnregisterPushDevice(device.UserID, device.Token); err != nil {
+				log.Printf("Failed to remove push device: %v", err)
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
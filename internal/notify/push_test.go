@@ -0,0 +1,175 @@
+package notify
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"assignment/internal/core"
+	"assignment/internal/models"
+)
+
+type fakeDevices struct {
+	devices []models.PushDevice
+	removed []string
+}
+
+func (f *fakeDevices) GetPushDevices(userID int64) (*models.PushDeviceList, error) {
+	return &models.PushDeviceList{UserID: userID, Devices: f.devices}, nil
+}
+
+func (f *fakeDevices) UnregisterPushDevice(userID int64, token string) error {
+	f.removed = append(f.removed, token)
+	return nil
+}
+
+type fakePush struct {
+	pushed []string
+	err    error
+}
+
+func (f *fakePush) Push(ctx context.Context, token string, msg PushMessage) error {
+	f.pushed = append(f.pushed, token+": "+msg.Body)
+	return f.err
+}
+
+func TestPushSender(t *testing.T) {
+	devices := &fakeDevices{devices: []models.PushDevice{
+		{UserID: 1, Platform: "fcm", Token: "android"},
+		{UserID: 1, Platform: "apns", Token: "iphone"},
+		{UserID: 1, Platform: "apns", Token: "old-iphone"},
+	}}
+	fcm := &fakePush{}
+	sender := NewPushSender(devices)
+	sender.Platform("fcm", fcm)
+
+	notification := models.Notification{UserID: 1, Type: core.NotifyBalanceChanged, Balance: "105.00"}
+	if err := sender.Send(context.Background(), notification); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	// Platforms without a provider are skipped
+	if len(fcm.pushed) != 1 || fcm.pushed[0] != "android: Your balance is 105.00." {
+		t.Errorf("Unexpected pushes: %v", fcm.pushed)
+	}
+
+	// Unregistered devices are removed, other errors fail the delivery
+	sender.Platform("apns", &fakePush{err: ErrUnregistered})
+	if err := sender.Send(context.Background(), notification); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(devices.removed) != 2 {
+		t.Errorf("Expected 2 devices removed, got: %v", devices.removed)
+	}
+	sender.Platform("apns", &fakePush{err: errors.New("unavailable")})
+	if err := sender.Send(context.Background(), notification); err == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestFCM(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+
+	var message map[string]map[string]interface{}
+	var tokens int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			r.ParseForm()
+			if strings.Count(r.PostForm.Get("assertion"), ".") != 2 {
+				t.Errorf("Unexpected assertion: %s", r.PostForm.Get("assertion"))
+			}
+			tokens++
+			w.Write([]byte(`{"access_token":"ya29.test","expires_in":3600}`))
+		case "/v1/projects/casino-app/messages:send":
+			if r.Header.Get("Authorization") != "Bearer ya29.test" {
+				t.Errorf("Unexpected authorization: %s", r.Header.Get("Authorization"))
+			}
+			json.NewDecoder(r.Body).Decode(&message)
+			if message["message"]["token"] == "stale" {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	account, _ := json.Marshal(map[string]string{
+		"project_id":   "casino-app",
+		"client_email": "push@casino-app.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":    server.URL + "/token",
+	})
+	fcm, err := NewFCM(account)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	fcm.baseURL = server.URL
+
+	msg := PushMessage{Title: "Balance update", Body: "Your balance is 105.00."}
+	if err := fcm.Push(context.Background(), "device", msg); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if message["message"]["token"] != "device" {
+		t.Errorf("Unexpected message: %v", message)
+	}
+	if err := fcm.Push(context.Background(), "stale", msg); !errors.Is(err, ErrUnregistered) {
+		t.Errorf("Expected ErrUnregistered, got: %v", err)
+	}
+	// The access token is reused until it expires
+	if tokens != 1 {
+		t.Errorf("Expected 1 access token request, got: %d", tokens)
+	}
+}
+
+func TestAPNs(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+
+	var got *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		if r.URL.Path == "/3/device/stale" {
+			w.WriteHeader(http.StatusGone)
+			w.Write([]byte(`{"reason":"Unregistered"}`))
+		}
+	}))
+	defer server.Close()
+
+	apns, err := NewAPNs(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), "KEY123", "TEAM123", "com.example.casino", false)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	apns.baseURL = server.URL
+
+	msg := PushMessage{Title: "Balance update", Body: "Your balance is 105.00."}
+	if err := apns.Push(context.Background(), "device", msg); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if got.URL.Path != "/3/device/device" || got.Header.Get("apns-topic") != "com.example.casino" {
+		t.Errorf("Unexpected request: %s %v", got.URL.Path, got.Header)
+	}
+	if parts := strings.Split(strings.TrimPrefix(got.Header.Get("Authorization"), "bearer "), "."); len(parts) != 3 {
+		t.Errorf("Unexpected authorization: %s", got.Header.Get("Authorization"))
+	}
+	if err := apns.Push(context.Background(), "stale", msg); !errors.Is(err, ErrUnregistered) {
+		t.Errorf("Expected ErrUnregistered, got: %v", err)
+	}
+}
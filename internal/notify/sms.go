@@ -0,0 +1,107 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"assignment/internal/core"
+	"assignment/internal/models"
+)
+
+// SMSProvider sends a text message to an E.164 phone number.
+type SMSProvider interface {
+	SendSMS(ctx context.Context, to, body string) error
+}
+
+// SMSSender sends user alerts as text messages through a provider.
+type SMSSender struct {
+	provider SMSProvider
+}
+
+func NewSMSSender(provider SMSProvider) *SMSSender {
+	return &SMSSender{provider: provider}
+}
+
+// Send texts payload, a models.Notification, to the phone number of its
+// user. Notifications of users without one are dropped.
+func (s *SMSSender) Send(ctx context.Context, payload interface{}) error {
+	notification, ok := payload.(models.Notification)
+	if !ok {
+		return fmt.Errorf("unsupported SMS payload %T", payload)
+	}
+	if notification.Phone == "" {
+		log.Printf("SMS dropped: id=%d, userID=%d has no phone number", notification.ID, notification.UserID)
+		return nil
+	}
+	return s.provider.SendSMS(ctx, notification.Phone, alertText(notification))
+}
+
+// alertText is the text of a notification, short enough for a single SMS or
+// the body of a push notification.
+func alertText(n models.Notification) string {
+	account := "Your balance"
+	if n.WalletID != "" {
+		account = "Your " + n.WalletID + " wallet balance"
+	}
+	switch n.Type {
+	case core.NotifyLowBalance:
+		return fmt.Sprintf("%s is %s, below your alert threshold of %s.", account, n.Balance, n.Threshold)
+	case core.NotifyLargeTransaction:
+		return fmt.Sprintf("A transaction of %s was applied. %s is %s.", n.Amount, account, n.Balance)
+	}
+	return fmt.Sprintf("%s is %s.", account, n.Balance)
+}
+
+// Twilio sends text messages through the Twilio Messages API.
+type Twilio struct {
+	accountSID string
+	authToken  string
+	from       string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewTwilio creates a Twilio provider sending from the given number or
+// messaging service SID.
+func NewTwilio(accountSID, authToken, from string) *Twilio {
+	return &Twilio{
+		accountSID: accountSID,
+		authToken:  authToken,
+		from:       from,
+		baseURL:    "https://api.twilio.com",
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (t *Twilio) SendSMS(ctx context.Context, to, body string) error {
+	form := url.Values{"To": {to}, "Body": {body}}
+	if strings.HasPrefix(t.from, "MG") {
+		form.Set("MessagingServiceSid", t.from)
+	} else {
+		form.Set("From", t.from)
+	}
+
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", t.baseURL, url.PathEscape(t.accountSID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to build Twilio request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(t.accountSID, t.authToken)
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send SMS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("SMS provider returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"assignment/internal/core"
+	"assignment/internal/models"
+)
+
+func TestTwilioSMS(t *testing.T) {
+	var got *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		got = r
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	twilio := NewTwilio("AC123", "secret", "+15005550006")
+	twilio.baseURL = server.URL
+	sender := NewSMSSender(twilio)
+
+	err := sender.Send(context.Background(), models.Notification{
+		UserID:    1,
+		Type:      core.NotifyLowBalance,
+		Balance:   "9.50",
+		Threshold: "10.00",
+		Phone:     "+4915112345678",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if got.URL.Path != "/2010-04-01/Accounts/AC123/Messages.json" {
+		t.Errorf("Unexpected path: %s", got.URL.Path)
+	}
+	if user, pass, _ := got.BasicAuth(); user != "AC123" || pass != "secret" {
+		t.Errorf("Unexpected credentials: %s:%s", user, pass)
+	}
+	if got.PostForm.Get("To") != "+4915112345678" || got.PostForm.Get("From") != "+15005550006" {
+		t.Errorf("Unexpected form: %v", got.PostForm)
+	}
+	if body := got.PostForm.Get("Body"); body != "Your balance is 9.50, below your alert threshold of 10.00." {
+		t.Errorf("Unexpected body: %s", body)
+	}
+
+	// Users without a phone number are skipped
+	got = nil
+	if err := sender.Send(context.Background(), models.Notification{Type: core.NotifyLowBalance}); err != nil || got != nil {
+		t.Errorf("Expected notification without phone to be dropped, got: %v", err)
+	}
+}
+
+func TestTwilioSMSError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	twilio := NewTwilio("AC123", "secret", "MG456")
+	twilio.baseURL = server.URL
+	if err := twilio.SendSMS(context.Background(), "+4915112345678", "hello"); err == nil {
+		t.Error("Expected error for failed request")
+	}
+}
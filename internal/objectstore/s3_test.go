@@ -0,0 +1,92 @@
+package objectstore
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"assignment/internal/clock"
+	"assignment/internal/sigv4"
+)
+
+func TestEscapePath(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"transactions/date=2024-01-15/part.parquet", "transactions/date%3D2024-01-15/part.parquet"},
+		{"a b+c", "a%20b%2Bc"},
+		{"safe-_.~/", "safe-_.~/"},
+	}
+
+	for _, tt := range tests {
+		if got := escapePath(tt.in); got != tt.want {
+			t.Errorf("escapePath(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPut(t *testing.T) {
+	var gotPath, gotAuth, gotDate, gotHash string
+	var gotBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.EscapedPath()
+		gotAuth = r.Header.Get("Authorization")
+		gotDate = r.Header.Get("X-Amz-Date")
+		gotHash = r.Header.Get("X-Amz-Content-Sha256")
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	store, err := NewS3(Config{
+		Endpoint:        server.URL,
+		Region:          "eu-west-1",
+		Bucket:          "exports",
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "secret",
+	}, clock.NewFake(time.Date(2024, 1, 16, 2, 0, 0, 0, time.UTC)))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if err := store.Put(context.Background(), "transactions/date=2024-01-15/transactions.parquet", []byte("data"), "application/octet-stream"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if gotPath != "/exports/transactions/date%3D2024-01-15/transactions.parquet" {
+		t.Errorf("Unexpected path: %s", gotPath)
+	}
+	if string(gotBody) != "data" {
+		t.Errorf("Unexpected body: %q", gotBody)
+	}
+	if gotDate != "20240116T020000Z" {
+		t.Errorf("Unexpected X-Amz-Date: %s", gotDate)
+	}
+	if gotHash != sigv4.PayloadHash([]byte("data")) {
+		t.Errorf("Unexpected payload hash: %s", gotHash)
+	}
+	if !strings.HasPrefix(gotAuth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20240116/eu-west-1/s3/aws4_request, SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date, Signature=") {
+		t.Errorf("Unexpected Authorization header: %s", gotAuth)
+	}
+}
+
+func TestPut_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "AccessDenied", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	store, err := NewS3(Config{Endpoint: server.URL, Bucket: "exports"}, clock.New())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if err := store.Put(context.Background(), "key", nil, "text/plain"); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Expected 403 error, got: %v", err)
+	}
+}
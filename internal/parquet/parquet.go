@@ -0,0 +1,248 @@
+// Package parquet writes flat tables as Apache Parquet files. It supports a
+// single row group of REQUIRED columns with PLAIN encoding and GZIP
+// compression, which is all the warehouse exports need.
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+type Type int
+
+const (
+	// Int64 values are int64.
+	Int64 Type = iota
+	// String values are string, stored as UTF-8 byte arrays.
+	String
+	// Boolean values are bool.
+	Boolean
+	// Decimal values are int64 unscaled amounts, e.g. 1050 for 10.50 with
+	// scale 2.
+	Decimal
+	// Timestamp values are time.Time, stored as UTC microseconds.
+	Timestamp
+)
+
+type Column struct {
+	Name  string
+	Type  Type
+	Scale int32
+}
+
+const magic = "PAR1"
+
+// Parquet enum values
+const (
+	physicalBoolean   = 0
+	physicalInt64     = 2
+	physicalByteArray = 6
+
+	repetitionRequired = 0
+
+	convertedUTF8            = 0
+	convertedDecimal         = 5
+	convertedTimestampMicros = 10
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	codecGzip = 2
+
+	pageTypeData = 0
+
+	decimalPrecision = 18
+)
+
+type chunk struct {
+	offset           int64
+	uncompressedSize int64
+	compressedSize   int64
+}
+
+// Write encodes rows as a Parquet file. Each row must have one value per
+// column, of the Go type documented on the column's Type.
+func Write(w io.Writer, columns []Column, rows [][]interface{}) error {
+	for i, row := range rows {
+		if len(row) != len(columns) {
+			return fmt.Errorf("row %d has %d values, expected %d", i, len(row), len(columns))
+		}
+	}
+
+	var out bytes.Buffer
+	out.WriteString(magic)
+
+	chunks := make([]chunk, len(columns))
+	for c, column := range columns {
+		values, err := encodePlain(column, rows, c)
+		if err != nil {
+			return err
+		}
+
+		var compressed bytes.Buffer
+		zw := gzip.NewWriter(&compressed)
+		zw.Write(values)
+		if err := zw.Close(); err != nil {
+			return fmt.Errorf("failed to compress column %s: %w", column.Name, err)
+		}
+
+		header := pageHeader(len(rows), len(values), compressed.Len())
+
+		chunks[c] = chunk{
+			offset:           int64(out.Len()),
+			uncompressedSize: int64(len(header) + len(values)),
+			compressedSize:   int64(len(header) + compressed.Len()),
+		}
+		out.Write(header)
+		out.Write(compressed.Bytes())
+	}
+
+	footer := fileMetadata(columns, chunks, int64(len(rows)))
+	out.Write(footer)
+	binary.Write(&out, binary.LittleEndian, uint32(len(footer)))
+	out.WriteString(magic)
+
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+func encodePlain(column Column, rows [][]interface{}, c int) ([]byte, error) {
+	var buf bytes.Buffer
+	var bits byte
+
+	for i, row := range rows {
+		value := row[c]
+		wrongType := func() error {
+			return fmt.Errorf("row %d column %s: unexpected value type %T", i, column.Name, value)
+		}
+
+		switch column.Type {
+		case Int64, Decimal:
+			v, ok := value.(int64)
+			if !ok {
+				return nil, wrongType()
+			}
+			binary.Write(&buf, binary.LittleEndian, v)
+		case Timestamp:
+			v, ok := value.(time.Time)
+			if !ok {
+				return nil, wrongType()
+			}
+			binary.Write(&buf, binary.LittleEndian, v.UnixMicro())
+		case String:
+			v, ok := value.(string)
+			if !ok {
+				return nil, wrongType()
+			}
+			if len(v) > math.MaxInt32 {
+				return nil, fmt.Errorf("row %d column %s: value too long", i, column.Name)
+			}
+			binary.Write(&buf, binary.LittleEndian, uint32(len(v)))
+			buf.WriteString(v)
+		case Boolean:
+			v, ok := value.(bool)
+			if !ok {
+				return nil, wrongType()
+			}
+			// Booleans are bit-packed, least significant bit first
+			if v {
+				bits |= 1 << (i % 8)
+			}
+			if i%8 == 7 || i == len(rows)-1 {
+				buf.WriteByte(bits)
+				bits = 0
+			}
+		default:
+			return nil, fmt.Errorf("column %s: unsupported type %d", column.Name, column.Type)
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+func pageHeader(numValues, uncompressedSize, compressedSize int) []byte {
+	var t thriftWriter
+	t.beginStruct()
+	t.i32(1, pageTypeData)
+	t.i32(2, int32(uncompressedSize))
+	t.i32(3, int32(compressedSize))
+	t.structField(5)
+	t.i32(1, int32(numValues))
+	t.i32(2, encodingPlain)
+	t.i32(3, encodingRLE)
+	t.i32(4, encodingRLE)
+	t.endStruct()
+	t.endStruct()
+	return t.buf.Bytes()
+}
+
+func physicalType(typ Type) int32 {
+	switch typ {
+	case String:
+		return physicalByteArray
+	case Boolean:
+		return physicalBoolean
+	default:
+		return physicalInt64
+	}
+}
+
+func fileMetadata(columns []Column, chunks []chunk, numRows int64) []byte {
+	var t thriftWriter
+	t.beginStruct()
+
+	t.i32(1, 1)
+	t.structList(2, len(columns)+1, func(i int) {
+		if i == 0 {
+			t.binary(4, "schema")
+			t.i32(5, int32(len(columns)))
+			return
+		}
+		column := columns[i-1]
+		t.i32(1, physicalType(column.Type))
+		t.i32(3, repetitionRequired)
+		t.binary(4, column.Name)
+		switch column.Type {
+		case String:
+			t.i32(6, convertedUTF8)
+		case Decimal:
+			t.i32(6, convertedDecimal)
+			t.i32(7, column.Scale)
+			t.i32(8, decimalPrecision)
+		case Timestamp:
+			t.i32(6, convertedTimestampMicros)
+		}
+	})
+	t.i64(3, numRows)
+
+	var totalSize int64
+	for _, c := range chunks {
+		totalSize += c.uncompressedSize
+	}
+	t.structList(4, 1, func(int) {
+		t.structList(1, len(columns), func(i int) {
+			t.i64(2, chunks[i].offset)
+			t.structField(3)
+			t.i32(1, physicalType(columns[i].Type))
+			t.i32List(2, []int32{encodingPlain, encodingRLE})
+			t.binaryList(3, []string{columns[i].Name})
+			t.i32(4, codecGzip)
+			t.i64(5, numRows)
+			t.i64(6, chunks[i].uncompressedSize)
+			t.i64(7, chunks[i].compressedSize)
+			t.i64(9, chunks[i].offset)
+			t.endStruct()
+		})
+		t.i64(2, totalSize)
+		t.i64(3, numRows)
+	})
+	t.binary(6, "assignment")
+
+	t.endStruct()
+	return t.buf.Bytes()
+}
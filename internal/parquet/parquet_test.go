@@ -0,0 +1,144 @@
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"testing"
+	"time"
+)
+
+// readPageHeader decodes a compact-protocol PageHeader made only of i32
+// fields and nested structs. Fields of the nested DataPageHeader are keyed
+// as 100+id. It returns the fields and the encoded header length.
+func readPageHeader(t *testing.T, data []byte) (map[int16]int64, int) {
+	t.Helper()
+
+	r := bytes.NewReader(data)
+	fields := map[int16]int64{}
+	var lastID int16
+	depth := 0
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			t.Fatalf("Unexpected end of page header: %v", err)
+		}
+		if b == 0 {
+			depth--
+			if depth < 0 {
+				break
+			}
+			continue
+		}
+		lastID += int16(b >> 4)
+		switch b & 0x0F {
+		case thriftI32:
+			v, err := binary.ReadUvarint(r)
+			if err != nil {
+				t.Fatalf("Invalid varint: %v", err)
+			}
+			fields[lastID+int16(depth)*100] = int64(v>>1) ^ -int64(v&1)
+		case thriftStruct:
+			depth++
+			lastID = 0
+		default:
+			t.Fatalf("Unexpected field type %d", b&0x0F)
+		}
+	}
+	return fields, len(data) - r.Len()
+}
+
+func TestWrite_Layout(t *testing.T) {
+	columns := []Column{
+		{Name: "id", Type: Int64},
+		{Name: "name", Type: String},
+	}
+	rows := [][]interface{}{
+		{int64(1), "alice"},
+		{int64(2), "bob"},
+		{int64(-3), ""},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, columns, rows); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	data := buf.Bytes()
+
+	if string(data[:4]) != magic || string(data[len(data)-4:]) != magic {
+		t.Fatalf("File must start and end with %q", magic)
+	}
+	footerLen := binary.LittleEndian.Uint32(data[len(data)-8 : len(data)-4])
+	if int(footerLen) >= len(data)-12 {
+		t.Fatalf("Footer length %d out of range", footerLen)
+	}
+
+	// First column chunk starts right after the magic bytes
+	header, n := readPageHeader(t, data[4:])
+	if header[1] != pageTypeData {
+		t.Errorf("Expected data page, got type %d", header[1])
+	}
+	if header[101] != 3 {
+		t.Errorf("Expected 3 values in page, got %d", header[101])
+	}
+
+	compressed := data[4+n : 4+n+int(header[3])]
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatalf("Page is not gzip compressed: %v", err)
+	}
+	values, _ := io.ReadAll(zr)
+	if int64(len(values)) != header[2] {
+		t.Fatalf("Expected %d uncompressed bytes, got %d", header[2], len(values))
+	}
+
+	for i, want := range []int64{1, 2, -3} {
+		if got := int64(binary.LittleEndian.Uint64(values[i*8:])); got != want {
+			t.Errorf("Value %d = %d, want %d", i, got, want)
+		}
+	}
+}
+
+func TestEncodePlain(t *testing.T) {
+	ts := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		column Column
+		values []interface{}
+		want   []byte
+	}{
+		{"booleans are bit-packed", Column{Name: "b", Type: Boolean}, []interface{}{true, false, true, true, false, false, false, false, true}, []byte{0x0D, 0x01}},
+		{"strings are length-prefixed", Column{Name: "s", Type: String}, []interface{}{"ab"}, []byte{2, 0, 0, 0, 'a', 'b'}},
+		{"decimals are unscaled int64", Column{Name: "d", Type: Decimal, Scale: 2}, []interface{}{int64(1050)}, []byte{0x1A, 0x04, 0, 0, 0, 0, 0, 0}},
+		{"timestamps are microseconds", Column{Name: "t", Type: Timestamp}, []interface{}{ts}, binary.LittleEndian.AppendUint64(nil, uint64(ts.UnixMicro()))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows := make([][]interface{}, len(tt.values))
+			for i, v := range tt.values {
+				rows[i] = []interface{}{v}
+			}
+			got, err := encodePlain(tt.column, rows, 0)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("encodePlain() = %x, want %x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrite_RejectsWrongTypes(t *testing.T) {
+	columns := []Column{{Name: "id", Type: Int64}}
+
+	if err := Write(io.Discard, columns, [][]interface{}{{"not a number"}}); err == nil {
+		t.Error("Expected error for wrong value type, got none")
+	}
+	if err := Write(io.Discard, columns, [][]interface{}{{int64(1), int64(2)}}); err == nil {
+		t.Error("Expected error for wrong row width, got none")
+	}
+}
@@ -0,0 +1,151 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"assignment/internal/core"
+	"assignment/internal/models"
+)
+
+// Errors of deposits. ErrInvalidCallback and ErrDepositRejected are
+// matched by errors with more detail.
+var (
+	ErrDepositIDRequired = errors.New("depositId is required")
+	// ErrInvalidCallback is a callback that cannot be verified or does not
+	// match its deposit
+	ErrInvalidCallback = errors.New("invalid callback")
+	// ErrDepositRejected is a deposit the balance rules refused to credit
+	ErrDepositRejected = errors.New("deposit rejected")
+)
+
+// DepositProvider collects money from a user for a deposit, e.g. Stripe.
+type DepositProvider interface {
+	// CreatePayment creates the provider payment for a deposit. It must be
+	// idempotent on the deposit ID.
+	CreatePayment(ctx context.Context, deposit models.Deposit) (*ProviderPayment, error)
+	// ParseCallback verifies and decodes a callback the provider sent about
+	// a payment.
+	ParseCallback(payload []byte, header http.Header) (*PaymentEvent, error)
+}
+
+// ProviderPayment is a payment created by the provider.
+type ProviderPayment struct {
+	ID           string
+	ClientSecret string
+}
+
+// PaymentEvent is a change of a provider payment. Status is
+// core.DepositSucceeded, core.DepositFailed or core.DepositChargedBack, or
+// empty for events that do not affect deposits. Chargebacks carry the
+// provider's reference of the chargeback and the amount charged back.
+type PaymentEvent struct {
+	PaymentID string
+	Status    string
+	Amount    string
+	Currency  string
+	Reference string
+}
+
+type Deposits struct {
+	service  *core.TransactionService
+	provider DepositProvider
+}
+
+func NewDeposits(service *core.TransactionService, provider DepositProvider) *Deposits {
+	return &Deposits{
+		service:  service,
+		provider: provider,
+	}
+}
+
+// CreateIntent starts a deposit of amount for the user and returns it with
+// the client secret the app completes the payment with. Retrying with the
+// same deposit ID returns the same provider payment.
+func (d *Deposits) CreateIntent(ctx context.Context, userID int64, depositID, amount string) (*models.Deposit, error) {
+	if depositID == "" {
+		return nil, ErrDepositIDRequired
+	}
+	if _, err := d.service.ValidateTransactionAmount(amount); err != nil {
+		return nil, err
+	}
+
+	// Deposit IDs are chosen by clients, so scope them to the user
+	deposit, err := d.service.CreateDeposit(fmt.Sprintf("deposit-%d-%s", userID, depositID), userID, amount)
+	if err != nil {
+		return nil, err
+	}
+	if deposit.Status == core.DepositSucceeded {
+		return deposit, nil
+	}
+
+	payment, err := d.provider.CreatePayment(ctx, *deposit)
+	if err != nil {
+		return nil, err
+	}
+	if deposit.ProviderPaymentID == "" {
+		if err := d.service.SetDepositPayment(deposit.ID, payment.ID); err != nil {
+			return nil, err
+		}
+	}
+	deposit.ProviderPaymentID = payment.ID
+	deposit.ClientSecret = payment.ClientSecret
+	return deposit, nil
+}
+
+// Confirm applies a provider callback: a succeeded payment credits the
+// deposit to the user's balance, a failed one marks the deposit failed and a
+// chargeback reverses it. Callbacks are idempotent, as providers deliver them
+// at least once. It returns nil for callbacks that do not concern a deposit.
+func (d *Deposits) Confirm(payload []byte, header http.Header) (*models.Deposit, error) {
+	event, err := d.provider.ParseCallback(payload, header)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCallback, err)
+	}
+	if event.Status == "" {
+		return nil, nil
+	}
+
+	deposit, err := d.service.GetDepositByPayment(event.PaymentID)
+	if err != nil {
+		if errors.Is(err, core.ErrDepositNotFound) {
+			// Payments of other integrations sharing the provider account
+			log.Printf("Ignoring callback for unknown payment %s", event.PaymentID)
+			return nil, nil
+		}
+		return nil, err
+	}
+	if event.Status == core.DepositChargedBack && strings.EqualFold(event.Currency, deposit.Currency) {
+		// Chargebacks may be partial
+		return d.service.ChargeBackDeposit(deposit.ID, models.ChargebackRequest{Reference: event.Reference, Amount: event.Amount})
+	}
+	if event.Amount != deposit.Amount || !strings.EqualFold(event.Currency, deposit.Currency) {
+		return nil, fmt.Errorf("%w: payment of %s %s does not match deposit of %s %s",
+			ErrInvalidCallback, event.Amount, event.Currency, deposit.Amount, deposit.Currency)
+	}
+
+	if event.Status == core.DepositFailed {
+		return d.service.SetDepositStatus(deposit.ID, core.DepositFailed)
+	}
+
+	// The deposit ID is the transaction ID, so a repeated callback is a
+	// duplicate transaction rather than a second credit
+	resp, err := d.service.ProcessTransaction(deposit.UserID, models.TransactionRequest{
+		State:         "win",
+		Amount:        deposit.Amount,
+		TransactionID: deposit.ID,
+	}, "payment")
+	if err != nil {
+		return nil, err
+	}
+	rejection := core.TransactionError(resp)
+	if rejection == nil || errors.Is(rejection, core.ErrDuplicateTransaction) {
+		return d.service.SetDepositStatus(deposit.ID, core.DepositSucceeded)
+	}
+	// Rejected, e.g. for a blocked user or the balance cap
+	return nil, fmt.Errorf("%w: %w", ErrDepositRejected, rejection)
+}
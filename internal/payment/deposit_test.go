@@ -0,0 +1,184 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"assignment/internal/clock"
+	"assignment/internal/core"
+	"assignment/internal/models"
+	"assignment/internal/testutil"
+)
+
+type fakeDepositProvider struct {
+	payments []models.Deposit
+	event    *PaymentEvent
+}
+
+func (p *fakeDepositProvider) CreatePayment(ctx context.Context, deposit models.Deposit) (*ProviderPayment, error) {
+	p.payments = append(p.payments, deposit)
+	return &ProviderPayment{ID: "pi_" + deposit.ID, ClientSecret: "secret"}, nil
+}
+
+func (p *fakeDepositProvider) ParseCallback(payload []byte, header http.Header) (*PaymentEvent, error) {
+	return p.event, nil
+}
+
+func TestDeposit(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := core.NewTransactionService(db, clock.New())
+	provider := &fakeDepositProvider{}
+	deposits := NewDeposits(service, provider)
+
+	deposit, err := deposits.CreateIntent(context.Background(), 1, "d-1", "25")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if deposit.ID != "deposit-1-d-1" || deposit.Amount != "25.00" || deposit.Currency != "EUR" || deposit.Status != core.DepositPending || deposit.ClientSecret != "secret" {
+		t.Errorf("Unexpected deposit: %+v", deposit)
+	}
+	if _, err := deposits.CreateIntent(context.Background(), 1, "d-1", "30.00"); err == nil {
+		t.Error("Expected error for reused deposit ID with another amount")
+	}
+
+	// Repeated callbacks credit the deposit once
+	provider.event = &PaymentEvent{PaymentID: deposit.ProviderPaymentID, Status: core.DepositSucceeded, Amount: "25.00", Currency: "eur"}
+	for i := 0; i < 2; i++ {
+		confirmed, err := deposits.Confirm(nil, nil)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if confirmed.Status != core.DepositSucceeded {
+			t.Errorf("Expected succeeded, got: %s", confirmed.Status)
+		}
+	}
+	expectBalance(t, service, 1, "125.00")
+
+	// A late failure does not undo a succeeded deposit
+	provider.event.Status = core.DepositFailed
+	if confirmed, err := deposits.Confirm(nil, nil); err != nil || confirmed.Status != core.DepositSucceeded {
+		t.Errorf("Expected deposit to stay succeeded, got: %+v, %v", confirmed, err)
+	}
+
+	// A chargeback after the money was spent takes the balance negative, once
+	if _, err := service.ProcessTransaction(1, testutil.NewTransactionRequest("lose", "110.00"), "game"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	provider.event = &PaymentEvent{PaymentID: deposit.ProviderPaymentID, Status: core.DepositChargedBack, Amount: "25.00", Currency: "eur", Reference: "dp_1"}
+	for i := 0; i < 2; i++ {
+		charged, err := deposits.Confirm(nil, nil)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if charged.Status != core.DepositChargedBack || charged.ChargebackReference != "dp_1" || charged.ChargedBackAt == nil {
+			t.Errorf("Unexpected charged back deposit: %+v", charged)
+		}
+	}
+	expectBalance(t, service, 1, "-10.00")
+	if user, err := service.GetUser(1); err != nil || user.ChargebackAt == nil {
+		t.Errorf("Expected user flagged for the chargeback, got: %+v, %v", user, err)
+	}
+	provider.event.Reference = "dp_2"
+	if _, err := deposits.Confirm(nil, nil); err == nil || err.Error() != "deposit already charged back" {
+		t.Errorf("Expected already charged back, got: %v", err)
+	}
+	if resp, err := service.ProcessTransaction(1, testutil.NewTransactionRequest("lose", "1.00"), "game"); err != nil || resp.Balance != "-10.00" {
+		t.Errorf("Expected debit of a negative balance to be rejected, got: %+v, %v", resp, err)
+	}
+
+	provider.event = &PaymentEvent{PaymentID: "pi_unknown", Status: core.DepositSucceeded, Amount: "25.00", Currency: "eur"}
+	if confirmed, err := deposits.Confirm(nil, nil); err != nil || confirmed != nil {
+		t.Errorf("Expected unknown payment to be ignored, got: %+v, %v", confirmed, err)
+	}
+
+	mismatch, _ := deposits.CreateIntent(context.Background(), 2, "d-2", "10.00")
+	provider.event = &PaymentEvent{PaymentID: mismatch.ProviderPaymentID, Status: core.DepositSucceeded, Amount: "1000.00", Currency: "eur"}
+	if _, err := deposits.Confirm(nil, nil); err == nil {
+		t.Error("Expected error for amount mismatch")
+	}
+	expectBalance(t, service, 2, "50.00")
+
+	if _, err := deposits.CreateIntent(context.Background(), 999, "d-3", "10.00"); err == nil || err.Error() != "user not found" {
+		t.Errorf("Expected user not found, got: %v", err)
+	}
+}
+
+func signStripe(secret string, t time.Time, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.%s", t.Unix(), payload)
+	return fmt.Sprintf("t=%d,v1=%s", t.Unix(), hex.EncodeToString(mac.Sum(nil)))
+}
+
+func TestStripeCreatePayment(t *testing.T) {
+	var got *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		got = r
+		w.Write([]byte(`{"id":"pi_123","client_secret":"pi_123_secret_abc"}`))
+	}))
+	defer server.Close()
+
+	stripe := NewStripe("sk_test_123", "whsec_123")
+	stripe.baseURL = server.URL
+
+	payment, err := stripe.CreatePayment(context.Background(), models.Deposit{ID: "deposit-1-d-1", UserID: 1, Amount: "25.50", Currency: "EUR"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if payment.ID != "pi_123" || payment.ClientSecret != "pi_123_secret_abc" {
+		t.Errorf("Unexpected payment: %+v", payment)
+	}
+	if got.URL.Path != "/v1/payment_intents" || got.Header.Get("Authorization") != "Bearer sk_test_123" || got.Header.Get("Idempotency-Key") != "deposit-1-d-1" {
+		t.Errorf("Unexpected request: %s %v", got.URL.Path, got.Header)
+	}
+	if got.PostForm.Get("amount") != "2550" || got.PostForm.Get("currency") != "eur" {
+		t.Errorf("Unexpected form: %v", got.PostForm)
+	}
+}
+
+func TestStripeParseCallback(t *testing.T) {
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	stripe := NewStripe("sk_test_123", "whsec_123")
+	stripe.now = func() time.Time { return now }
+
+	payload := `{"type":"payment_intent.succeeded","data":{"object":{"id":"pi_123","amount":2550,"currency":"eur"}}}`
+	header := http.Header{"Stripe-Signature": {signStripe("whsec_123", now, payload)}}
+	event, err := stripe.ParseCallback([]byte(payload), header)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if event.PaymentID != "pi_123" || event.Status != core.DepositSucceeded || event.Amount != "25.50" {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+
+	for name, header := range map[string]string{
+		"wrong secret": signStripe("whsec_other", now, payload),
+		"too old":      signStripe("whsec_123", now.Add(-time.Hour), payload),
+		"malformed":    "v1=abc",
+	} {
+		if _, err := stripe.ParseCallback([]byte(payload), http.Header{"Stripe-Signature": {header}}); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	dispute := `{"type":"charge.dispute.funds_withdrawn","data":{"object":{"id":"dp_1","amount":1000,"currency":"eur","payment_intent":"pi_123"}}}`
+	event, err = stripe.ParseCallback([]byte(dispute), http.Header{"Stripe-Signature": {signStripe("whsec_123", now, dispute)}})
+	if err != nil || event.PaymentID != "pi_123" || event.Reference != "dp_1" || event.Status != core.DepositChargedBack || event.Amount != "10.00" {
+		t.Errorf("Unexpected chargeback event: %+v, %v", event, err)
+	}
+
+	other := `{"type":"charge.refunded","data":{"object":{"id":"ch_1"}}}`
+	event, err = stripe.ParseCallback([]byte(other), http.Header{"Stripe-Signature": {signStripe("whsec_123", now, other)}})
+	if err != nil || event.Status != "" {
+		t.Errorf("Expected ignored event, got: %+v, %v", event, err)
+	}
+}
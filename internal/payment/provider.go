@@ -0,0 +1,77 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"assignment/internal/requestid"
+)
+
+// HTTPProvider posts payouts as JSON to a payment provider endpoint, using
+// the payout ID as the Idempotency-Key.
+type HTTPProvider struct {
+	url        string
+	httpClient *http.Client
+}
+
+func NewHTTPProvider(url string) *HTTPProvider {
+	return &HTTPProvider{
+		url:        url,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Payout sends the payout. A 4xx response, except for those a retry may
+// resolve (408, 409, 425 and 429), and a 2xx response with the status
+// "declined" are declines. Other non-2xx responses and transport errors
+// leave the outcome unknown.
+func (p *HTTPProvider) Payout(ctx context.Context, payout Payout) error {
+	body, err := json.Marshal(payout)
+	if err != nil {
+		return fmt.Errorf("failed to encode payout: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build payout request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", payout.ID)
+	if id := requestid.FromContext(ctx); id != "" {
+		req.Header.Set(requestid.Header, id)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call payment provider: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+		var result struct {
+			Status string `json:"status"`
+		}
+		// The body is optional; only an explicit decline is an error
+		if json.NewDecoder(resp.Body).Decode(&result) == nil && result.Status == "declined" {
+			return fmt.Errorf("%w: payment provider returned status declined", ErrPayoutDeclined)
+		}
+		return nil
+	case resp.StatusCode >= 400 && resp.StatusCode <= 499 && !retryableStatus(resp.StatusCode):
+		return fmt.Errorf("%w: payment provider returned status %d", ErrPayoutDeclined, resp.StatusCode)
+	default:
+		return fmt.Errorf("payment provider returned status %d", resp.StatusCode)
+	}
+}
+
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusConflict, http.StatusTooEarly, http.StatusTooManyRequests:
+		return true
+	}
+	return false
+}
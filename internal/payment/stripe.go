@@ -0,0 +1,195 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"assignment/internal/core"
+	"assignment/internal/models"
+	"assignment/internal/money"
+	"assignment/internal/utils"
+)
+
+// stripeTolerance is how old a signed callback may be, against replays.
+const stripeTolerance = 5 * time.Minute
+
+// stripeZeroDecimal are the currencies Stripe takes amounts of in whole
+// units rather than cents.
+var stripeZeroDecimal = map[string]bool{
+	"bif": true, "clp": true, "djf": true, "gnf": true, "jpy": true, "kmf": true,
+	"krw": true, "mga": true, "pyg": true, "rwf": true, "ugx": true, "vnd": true,
+	"vuv": true, "xaf": true, "xof": true, "xpf": true,
+}
+
+// Stripe collects deposits as Stripe PaymentIntents, confirmed by Stripe
+// webhook events.
+type Stripe struct {
+	secretKey     string
+	webhookSecret string
+	baseURL       string
+	httpClient    *http.Client
+	now           func() time.Time
+}
+
+// NewStripe creates a Stripe provider with an API secret key and the signing
+// secret of the webhook endpoint Stripe sends events to.
+func NewStripe(secretKey, webhookSecret string) *Stripe {
+	return &Stripe{
+		secretKey:     secretKey,
+		webhookSecret: webhookSecret,
+		baseURL:       "https://api.stripe.com",
+		httpClient:    &http.Client{Timeout: 30 * time.Second},
+		now:           time.Now,
+	}
+}
+
+func (s *Stripe) CreatePayment(ctx context.Context, deposit models.Deposit) (*ProviderPayment, error) {
+	currency := strings.ToLower(deposit.Currency)
+	amount, err := stripeAmount(deposit.Amount, currency)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{
+		"amount":                             {strconv.FormatInt(amount, 10)},
+		"currency":                           {currency},
+		"automatic_payment_methods[enabled]": {"true"},
+		"metadata[deposit_id]":               {deposit.ID},
+		"metadata[user_id]":                  {strconv.FormatInt(deposit.UserID, 10)},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+"/v1/payment_intents", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build Stripe request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer "+s.secretKey)
+	req.Header.Set("Idempotency-Key", deposit.ID)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Stripe: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var reply struct {
+		ID           string `json:"id"`
+		ClientSecret string `json:"client_secret"`
+		Error        struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return nil, fmt.Errorf("failed to decode Stripe response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("Stripe returned status %d: %s", resp.StatusCode, reply.Error.Message)
+	}
+	return &ProviderPayment{ID: reply.ID, ClientSecret: reply.ClientSecret}, nil
+}
+
+func (s *Stripe) ParseCallback(payload []byte, header http.Header) (*PaymentEvent, error) {
+	if err := s.verifySignature(payload, header.Get("Stripe-Signature")); err != nil {
+		return nil, err
+	}
+
+	var event struct {
+		Type string `json:"type"`
+		Data struct {
+			Object struct {
+				ID       string `json:"id"`
+				Amount   int64  `json:"amount"`
+				Currency string `json:"currency"`
+				// PaymentIntent is the payment a dispute is about
+				PaymentIntent string `json:"payment_intent"`
+			} `json:"object"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("failed to decode event: %w", err)
+	}
+
+	intent := event.Data.Object
+	parsed := &PaymentEvent{PaymentID: intent.ID, Currency: intent.Currency}
+	switch event.Type {
+	case "payment_intent.succeeded":
+		parsed.Status = core.DepositSucceeded
+	case "payment_intent.payment_failed":
+		parsed.Status = core.DepositFailed
+	case "charge.dispute.funds_withdrawn":
+		// The object is the dispute, i.e. the chargeback
+		parsed.PaymentID, parsed.Reference = intent.PaymentIntent, intent.ID
+		parsed.Status = core.DepositChargedBack
+	default:
+		return &PaymentEvent{}, nil
+	}
+	value := float64(intent.Amount) / math.Pow10(stripeExponent(intent.Currency))
+	parsed.Amount = money.Format(value, intent.Currency)
+	return parsed, nil
+}
+
+// verifySignature checks the Stripe-Signature header, an HMAC-SHA256 of the
+// timestamp and payload with the webhook signing secret.
+func (s *Stripe) verifySignature(payload []byte, header string) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	t, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return errors.New("malformed Stripe-Signature header")
+	}
+	if age := s.now().Sub(time.Unix(t, 0)); age > stripeTolerance || age < -stripeTolerance {
+		return errors.New("signature timestamp outside tolerance")
+	}
+
+	mac := hmac.New(sha256.New, []byte(s.webhookSecret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	expected := mac.Sum(nil)
+	for _, signature := range signatures {
+		if sig, err := hex.DecodeString(signature); err == nil && hmac.Equal(sig, expected) {
+			return nil
+		}
+	}
+	return errors.New("signature mismatch")
+}
+
+// stripeAmount converts an amount to the smallest currency unit.
+func stripeAmount(amount, currency string) (int64, error) {
+	value, err := utils.ParseAmount(amount)
+	if err != nil {
+		return 0, err
+	}
+	return int64(math.Round(value * math.Pow10(stripeExponent(currency)))), nil
+}
+
+// stripeExponent is the exponent of the unit Stripe takes amounts of
+// currency in. It mostly follows ISO 4217, but Stripe has its own list of
+// zero-decimal currencies, and takes e.g. ISK in hundredths.
+func stripeExponent(currency string) int {
+	if stripeZeroDecimal[currency] {
+		return 0
+	}
+	if exp := money.Exponent(currency); exp > money.DefaultExponent {
+		return exp
+	}
+	return money.DefaultExponent
+}
@@ -0,0 +1,167 @@
+// Package payment moves user funds in and out through external payment
+// providers. Deposits are credited once the provider confirms the payment.
+// Each withdrawal runs as a saga: the funds are reserved by debiting the
+// balance, the provider is called, and the reservation is released again if
+// the provider declines the payout. A payout whose outcome is unknown, e.g.
+// after a timeout, is retried and never released, so funds the provider may
+// have sent are not credited back.
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"assignment/internal/core"
+	"assignment/internal/models"
+	"assignment/internal/saga"
+)
+
+// WithdrawalSaga is the saga definition name of withdrawals.
+const WithdrawalSaga = "withdrawal"
+
+// payoutAttempts is how often a payout with an unknown outcome is sent
+// before the withdrawal is left pending.
+const payoutAttempts = 3
+
+// ErrWithdrawalIDRequired is returned for withdrawals without an ID.
+var ErrWithdrawalIDRequired = errors.New("withdrawalId is required")
+
+// ErrPayoutDeclined is returned (wrapped) by providers that definitely did
+// not send a payout.
+var ErrPayoutDeclined = errors.New("payout declined")
+
+// Provider sends money to a user outside the wallet. Payout must be
+// idempotent on Payout.ID. It returns an error wrapping ErrPayoutDeclined
+// when the provider refused the payout; any other error leaves the outcome
+// unknown.
+type Provider interface {
+	Payout(ctx context.Context, payout Payout) error
+}
+
+type Payout struct {
+	ID     string `json:"id"`
+	UserID int64  `json:"userId"`
+	Amount string `json:"amount"`
+}
+
+type Withdrawals struct {
+	service      *core.TransactionService
+	orchestrator *saga.Orchestrator
+	provider     Provider
+	// retryDelay is the wait before the second payout attempt; it doubles
+	// with every further attempt
+	retryDelay time.Duration
+}
+
+// NewWithdrawals registers the withdrawal saga with orchestrator.
+func NewWithdrawals(service *core.TransactionService, orchestrator *saga.Orchestrator, provider Provider) *Withdrawals {
+	w := &Withdrawals{
+		service:      service,
+		orchestrator: orchestrator,
+		provider:     provider,
+		retryDelay:   time.Second,
+	}
+
+	orchestrator.Register(saga.Definition{
+		Name: WithdrawalSaga,
+		Steps: []saga.Step{
+			{Name: "reserve_funds", Action: w.reserveFunds, Compensate: w.releaseFunds},
+			{Name: "payout", Action: w.payout},
+		},
+	})
+	return w
+}
+
+// Withdraw pays amount out to the user and returns the saga: completed,
+// compensated with the reason in its Error, or pending when the payout
+// outcome is unknown. Pending withdrawals are retried by Resume and by
+// calling Withdraw again. Retrying with the same withdrawal ID returns the
+// existing withdrawal instead of paying twice.
+func (w *Withdrawals) Withdraw(ctx context.Context, userID int64, withdrawalID, amount string) (*saga.Instance, error) {
+	if withdrawalID == "" {
+		return nil, ErrWithdrawalIDRequired
+	}
+	if _, err := w.service.ValidateTransactionAmount(amount); err != nil {
+		return nil, err
+	}
+
+	// A client disconnecting must not interrupt a payout half way
+	ctx = context.WithoutCancel(ctx)
+
+	// Withdrawal IDs are chosen by clients, so scope them to the user
+	id := fmt.Sprintf("withdrawal-%d-%s", userID, withdrawalID)
+	return w.orchestrator.Start(ctx, WithdrawalSaga, id, Payout{
+		ID:     id,
+		UserID: userID,
+		Amount: amount,
+	})
+}
+
+func (w *Withdrawals) reserveFunds(ctx context.Context, s *saga.Instance) error {
+	var p Payout
+	if err := s.Decode(&p); err != nil {
+		return err
+	}
+
+	resp, err := w.service.ProcessTransaction(p.UserID, models.TransactionRequest{
+		State:         "lose",
+		Amount:        p.Amount,
+		TransactionID: p.ID + ":reserve",
+	}, "payment")
+	if err != nil {
+		return err
+	}
+	// Rejected, e.g. for insufficient funds or a KYC limit
+	if err := core.TransactionError(resp); err != nil && !errors.Is(err, core.ErrDuplicateTransaction) {
+		return err
+	}
+	return nil
+}
+
+func (w *Withdrawals) releaseFunds(ctx context.Context, s *saga.Instance) error {
+	var p Payout
+	if err := s.Decode(&p); err != nil {
+		return err
+	}
+
+	resp, err := w.service.ProcessTransaction(p.UserID, models.TransactionRequest{
+		State:         "win",
+		Amount:        p.Amount,
+		TransactionID: p.ID + ":release",
+	}, "payment")
+	if err != nil {
+		return err
+	}
+	// Rejected, e.g. for a maximum balance; the saga fails for manual
+	// attention instead of reporting the funds as released
+	if err := core.TransactionError(resp); err != nil && !errors.Is(err, core.ErrDuplicateTransaction) {
+		return err
+	}
+	return nil
+}
+
+// payout sends the payout, retrying with the same payout ID while the
+// outcome is unknown. Only a decline is returned as a plain error, which
+// releases the reserved funds.
+func (w *Withdrawals) payout(ctx context.Context, s *saga.Instance) error {
+	var p Payout
+	if err := s.Decode(&p); err != nil {
+		return err
+	}
+
+	delay := w.retryDelay
+	var err error
+	for attempt := 1; attempt <= payoutAttempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+		err = w.provider.Payout(ctx, p)
+		if err == nil || errors.Is(err, ErrPayoutDeclined) {
+			return err
+		}
+	}
+	return saga.Uncertain(err)
+}
@@ -0,0 +1,167 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"assignment/internal/clock"
+	"assignment/internal/core"
+	"assignment/internal/saga"
+	"assignment/internal/testutil"
+)
+
+type fakeProvider struct {
+	err     error
+	payouts []Payout
+}
+
+func (p *fakeProvider) Payout(ctx context.Context, payout Payout) error {
+	p.payouts = append(p.payouts, payout)
+	return p.err
+}
+
+func setupWithdrawals(t *testing.T, provider Provider) (*Withdrawals, *core.TransactionService) {
+	t.Helper()
+	db := testutil.NewDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	service := core.NewTransactionService(db, clock.New())
+	withdrawals := NewWithdrawals(service, saga.NewOrchestrator(db, clock.New()), provider)
+	withdrawals.retryDelay = 0
+	return withdrawals, service
+}
+
+func expectBalance(t *testing.T, service *core.TransactionService, userID int64, want string) {
+	t.Helper()
+	balance, err := service.GetBalance(userID)
+	if err != nil {
+		t.Fatalf("Failed to get balance: %v", err)
+	}
+	if balance.Balance != want {
+		t.Errorf("Expected balance %s, got: %s", want, balance.Balance)
+	}
+}
+
+func TestWithdraw_Success(t *testing.T) {
+	provider := &fakeProvider{}
+	withdrawals, service := setupWithdrawals(t, provider)
+
+	inst, err := withdrawals.Withdraw(context.Background(), 1, "w-1", "40.00")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if inst.Status != saga.StatusCompleted {
+		t.Errorf("Expected completed, got: %s (%s)", inst.Status, inst.Error)
+	}
+	expectBalance(t, service, 1, "60.00")
+
+	// Retrying does not pay out twice
+	if _, err := withdrawals.Withdraw(context.Background(), 1, "w-1", "40.00"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(provider.payouts) != 1 || provider.payouts[0].ID != "withdrawal-1-w-1" {
+		t.Errorf("Expected a single payout, got: %+v", provider.payouts)
+	}
+	expectBalance(t, service, 1, "60.00")
+}
+
+func TestWithdraw_ProviderDeclineReleasesFunds(t *testing.T) {
+	withdrawals, service := setupWithdrawals(t, &fakeProvider{err: ErrPayoutDeclined})
+
+	inst, err := withdrawals.Withdraw(context.Background(), 1, "w-2", "40.00")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if inst.Status != saga.StatusCompensated || inst.Error != "payout: payout declined" {
+		t.Errorf("Expected compensated with 'payout: payout declined', got: %s %q", inst.Status, inst.Error)
+	}
+	expectBalance(t, service, 1, "100.00")
+}
+
+func TestWithdraw_UnknownPayoutOutcomeIsLeftPending(t *testing.T) {
+	provider := &fakeProvider{err: errors.New("timeout")}
+	withdrawals, service := setupWithdrawals(t, provider)
+
+	inst, err := withdrawals.Withdraw(context.Background(), 1, "w-4", "40.00")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if inst.Status != saga.StatusPending || inst.Error != "payout: timeout" {
+		t.Errorf("Expected pending with 'payout: timeout', got: %s %q", inst.Status, inst.Error)
+	}
+	// Retried with the same ID, and the reservation is kept
+	if len(provider.payouts) != payoutAttempts {
+		t.Errorf("Expected %d payout attempts, got: %d", payoutAttempts, len(provider.payouts))
+	}
+	for _, payout := range provider.payouts {
+		if payout.ID != "withdrawal-1-w-4" {
+			t.Errorf("Expected payout ID withdrawal-1-w-4, got: %s", payout.ID)
+		}
+	}
+	expectBalance(t, service, 1, "60.00")
+
+	// Retrying the withdrawal completes it once the provider answers
+	provider.err = nil
+	inst, err = withdrawals.Withdraw(context.Background(), 1, "w-4", "40.00")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if inst.Status != saga.StatusCompleted {
+		t.Errorf("Expected completed, got: %s (%s)", inst.Status, inst.Error)
+	}
+	expectBalance(t, service, 1, "60.00")
+}
+
+func TestWithdraw_InsufficientFunds(t *testing.T) {
+	provider := &fakeProvider{}
+	withdrawals, service := setupWithdrawals(t, provider)
+
+	inst, err := withdrawals.Withdraw(context.Background(), 2, "w-3", "80.00")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if inst.Status != saga.StatusCompensated || inst.Error != "reserve_funds: insufficient funds" {
+		t.Errorf("Expected compensated with insufficient funds, got: %s %q", inst.Status, inst.Error)
+	}
+	if len(provider.payouts) != 0 {
+		t.Errorf("Expected no payout, got: %+v", provider.payouts)
+	}
+	expectBalance(t, service, 2, "50.00")
+}
+
+func TestHTTPProvider(t *testing.T) {
+	var idempotencyKey string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idempotencyKey = r.Header.Get("Idempotency-Key")
+		switch r.URL.Path {
+		case "/fail":
+			w.WriteHeader(http.StatusBadGateway)
+		case "/reject":
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		case "/declined":
+			w.Write([]byte(`{"status":"declined"}`))
+		}
+	}))
+	defer server.Close()
+
+	payout := Payout{ID: "withdrawal-1-w-1", UserID: 1, Amount: "10.00"}
+	if err := NewHTTPProvider(server.URL).Payout(context.Background(), payout); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if idempotencyKey != payout.ID {
+		t.Errorf("Expected Idempotency-Key %s, got: %s", payout.ID, idempotencyKey)
+	}
+
+	// 5xx leaves the outcome unknown, 4xx and a declined status are declines
+	if err := NewHTTPProvider(server.URL+"/fail").Payout(context.Background(), payout); err == nil || errors.Is(err, ErrPayoutDeclined) {
+		t.Errorf("Expected an error other than a decline for a 5xx response, got: %v", err)
+	}
+	for _, path := range []string{"/reject", "/declined"} {
+		if err := NewHTTPProvider(server.URL+path).Payout(context.Background(), payout); !errors.Is(err, ErrPayoutDeclined) {
+			t.Errorf("Expected a decline for %s, got: %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"plain", "plain"},
+		{"a (b) c\\d", "a \\(b\\) c\\\\d"},
+		{"café \U0001F600", "caf? ?"},
+		{"tab\there", "tab?here"},
+	}
+
+	for _, tt := range tests {
+		if got := escape(tt.in); got != tt.want {
+			t.Errorf("escape(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestBytes_CrossReferenceTable(t *testing.T) {
+	doc := New()
+	doc.AddPage()
+	doc.Text(50, 800, 12, Bold, "Hello (world)")
+	doc.AddPage()
+	doc.Text(50, 800, 12, Regular, "Second page")
+	doc.TextOnPage(0, 50, 30, 8, Regular, "Page 1 of 2")
+
+	data := doc.Bytes()
+
+	if !bytes.HasPrefix(data, []byte("%PDF-1.4")) || !bytes.HasSuffix(data, []byte("%%EOF\n")) {
+		t.Fatal("Missing PDF header or trailer")
+	}
+	if !bytes.Contains(data, []byte("/Count 2")) {
+		t.Error("Expected 2 pages")
+	}
+	if !bytes.Contains(data, []byte("(Hello \\(world\\)) Tj")) {
+		t.Error("Expected escaped text on first page")
+	}
+
+	// Every xref entry must point at the start of its object
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(data)
+	if startxref == nil {
+		t.Fatal("Missing startxref")
+	}
+	xref, _ := strconv.Atoi(string(startxref[1]))
+	if !bytes.HasPrefix(data[xref:], []byte("xref\n")) {
+		t.Fatalf("startxref %d does not point at the xref table", xref)
+	}
+
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(data[xref:], -1)
+	if len(entries) != 8 {
+		t.Fatalf("Expected 8 objects, got: %d", len(entries))
+	}
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		if want := fmt.Sprintf("%d 0 obj", i+1); !bytes.HasPrefix(data[offset:], []byte(want)) {
+			t.Errorf("xref entry %d points at %q, want %q", i+1, data[offset:offset+10], want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+// Package readmodel runs the projector that keeps the balance read model up
+// to date with the balance event stream.
+package readmodel
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"assignment/internal/core"
+)
+
+// batchSize bounds the number of events projected per statement.
+const batchSize = 500
+
+type Projector struct {
+	service  *core.TransactionService
+	interval time.Duration
+}
+
+// NewProjector creates a projector that polls the outbox every interval.
+func NewProjector(service *core.TransactionService, interval time.Duration) *Projector {
+	return &Projector{
+		service:  service,
+		interval: interval,
+	}
+}
+
+// Run blocks until ctx is cancelled, draining the outbox at every tick.
+func (p *Projector) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.Drain()
+		}
+	}
+}
+
+// Drain projects outbox batches until the outbox is empty or a batch fails,
+// and returns the number of events projected.
+func (p *Projector) Drain() int {
+	total := 0
+	for {
+		n, err := p.service.ProjectReadModel(batchSize)
+		if err != nil {
+			log.Printf("Read model projection failed: %v", err)
+			return total
+		}
+		total += n
+		if n < batchSize {
+			return total
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package readmodel
+
+import (
+	"testing"
+
+	"assignment/internal/clock"
+	"assignment/internal/core"
+	"assignment/internal/testutil"
+)
+
+func TestDrain(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	service := core.NewTransactionService(db, clock.New())
+	service.UseReadModel()
+	if _, err := service.CatchUpReadModel(); err != nil {
+		t.Fatalf("Failed to catch up read model: %v", err)
+	}
+
+	// More events than fit in one batch
+	for i := 0; i < batchSize+1; i++ {
+		if _, err := service.ProcessTransaction(2, testutil.NewTransactionRequest("win", "1.00"), "game"); err != nil {
+			t.Fatalf("Failed to process transaction: %v", err)
+		}
+	}
+
+	projector := NewProjector(service, 0)
+	if n := projector.Drain(); n != batchSize+1 {
+		t.Errorf("Expected %d events projected, got: %d", batchSize+1, n)
+	}
+
+	balance, err := service.GetBalance(2)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if balance.Balance != "551.00" {
+		t.Errorf("Expected balance 551.00, got: %s", balance.Balance)
+	}
+}
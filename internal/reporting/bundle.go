@@ -0,0 +1,356 @@
+// Package reporting builds the signed audit bundles regulators request: a
+// ZIP archive of everything recorded about a user over a period, in CSV or
+// XML, with a manifest listing the SHA-256 hash of every file and an Ed25519
+// signature of the manifest, so the bundle can be checked for completeness
+// and tampering with nothing but the public key.
+package reporting
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"assignment/internal/models"
+)
+
+// Schema identifies the bundle layout, so consumers can tell when it changes
+// incompatibly.
+const Schema = "wallet.audit-trail.v1"
+
+// Bundle formats
+const (
+	FormatCSV = "csv"
+	FormatXML = "xml"
+)
+
+// Names of the manifest and its signature in a bundle.
+const (
+	ManifestName  = "manifest.json"
+	SignatureName = "manifest.sig"
+)
+
+// Manifest describes a bundle and is what its signature covers.
+type Manifest struct {
+	Schema      string    `json:"schema"`
+	UserID      int64     `json:"userId"`
+	PeriodStart time.Time `json:"periodStart"`
+	PeriodEnd   time.Time `json:"periodEnd"`
+	GeneratedAt time.Time `json:"generatedAt"`
+	Format      string    `json:"format"`
+	// PublicKey is the base64 Ed25519 key the bundle was signed with; check
+	// it against the key published by the operator, not just the signature
+	PublicKey string `json:"publicKey"`
+	Files     []File `json:"files"`
+}
+
+type File struct {
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256"`
+	Size   int    `json:"size"`
+}
+
+// Signer signs bundles with an Ed25519 key.
+type Signer struct {
+	key ed25519.PrivateKey
+}
+
+// NewSigner creates a signer from a base64 Ed25519 seed of 32 bytes.
+func NewSigner(seed string) (*Signer, error) {
+	raw, err := base64.StdEncoding.DecodeString(seed)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signing key: %w", err)
+	}
+	if len(raw) != ed25519.SeedSize {
+		return nil, fmt.Errorf("invalid signing key: must be a %d-byte Ed25519 seed", ed25519.SeedSize)
+	}
+	return &Signer{key: ed25519.NewKeyFromSeed(raw)}, nil
+}
+
+// PublicKey returns the base64 public key bundles are verified with.
+func (s *Signer) PublicKey() string {
+	return base64.StdEncoding.EncodeToString(s.key.Public().(ed25519.PublicKey))
+}
+
+// Bundle writes the audit trail as a signed bundle in the given format.
+func (s *Signer) Bundle(trail *models.AuditTrail, format string, generatedAt time.Time) ([]byte, error) {
+	var files map[string][]byte
+	var names []string
+	switch format {
+	case FormatCSV:
+		files, names = csvFiles(trail)
+	case FormatXML:
+		body, err := xmlFile(trail)
+		if err != nil {
+			return nil, err
+		}
+		files, names = map[string][]byte{"audit-trail.xml": body}, []string{"audit-trail.xml"}
+	default:
+		return nil, errors.New("invalid format: must be 'csv' or 'xml'")
+	}
+
+	manifest := Manifest{
+		Schema:      Schema,
+		UserID:      trail.User.ID,
+		PeriodStart: trail.PeriodStart.UTC(),
+		PeriodEnd:   trail.PeriodEnd.UTC(),
+		GeneratedAt: generatedAt.UTC(),
+		Format:      format,
+		PublicKey:   s.PublicKey(),
+	}
+	for _, name := range names {
+		sum := sha256.Sum256(files[name])
+		manifest.Files = append(manifest.Files, File{Name: name, SHA256: hex.EncodeToString(sum[:]), Size: len(files[name])})
+	}
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(s.key, manifestJSON))
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	add := func(name string, body []byte) error {
+		w, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: manifest.GeneratedAt})
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(body)
+		return err
+	}
+	for _, name := range names {
+		if err := add(name, files[name]); err != nil {
+			return nil, fmt.Errorf("failed to write bundle: %w", err)
+		}
+	}
+	if err := add(ManifestName, manifestJSON); err != nil {
+		return nil, fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if err := add(SignatureName, []byte(signature+"\n")); err != nil {
+		return nil, fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if err := archive.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Verify checks that bundle was signed with publicKey and holds exactly the
+// files its manifest lists, unchanged, and returns the manifest.
+func Verify(bundle []byte, publicKey ed25519.PublicKey) (*Manifest, error) {
+	archive, err := zip.NewReader(bytes.NewReader(bundle), int64(len(bundle)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid bundle: %w", err)
+	}
+	contents := map[string][]byte{}
+	for _, f := range archive.File {
+		r, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("invalid bundle: %w", err)
+		}
+		body, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return nil, fmt.Errorf("invalid bundle: %w", err)
+		}
+		contents[f.Name] = body
+	}
+
+	manifestJSON, ok := contents[ManifestName]
+	if !ok {
+		return nil, errors.New("invalid bundle: missing " + ManifestName)
+	}
+	signature, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(contents[SignatureName])))
+	if err != nil || !ed25519.Verify(publicKey, manifestJSON, signature) {
+		return nil, errors.New("invalid bundle: bad signature")
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(manifestJSON, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid bundle: %w", err)
+	}
+	if len(archive.File) != len(manifest.Files)+2 {
+		return nil, errors.New("invalid bundle: holds files the manifest does not list")
+	}
+	for _, f := range manifest.Files {
+		body, ok := contents[f.Name]
+		if !ok {
+			return nil, fmt.Errorf("invalid bundle: missing %s", f.Name)
+		}
+		if sum := sha256.Sum256(body); hex.EncodeToString(sum[:]) != f.SHA256 {
+			return nil, fmt.Errorf("invalid bundle: %s was modified", f.Name)
+		}
+	}
+	return &manifest, nil
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// csvFiles lays the audit trail out as one CSV file per record type, each
+// with a header row.
+func csvFiles(trail *models.AuditTrail) (map[string][]byte, []string) {
+	files := map[string][]byte{}
+	var names []string
+	write := func(name string, header []string, rows [][]string) {
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		w.Write(header)
+		w.WriteAll(rows)
+		files[name] = buf.Bytes()
+		names = append(names, name)
+	}
+
+	u := trail.User
+	deletedAt := ""
+	if u.DeletedAt != nil {
+		deletedAt = formatTime(*u.DeletedAt)
+	}
+	write("user.csv",
+		[]string{"user_id", "external_ref", "display_name", "country", "currency", "status", "kyc_level", "created_at", "deleted_at", "period_start", "period_end", "opening_balance", "closing_balance"},
+		[][]string{{strconv.FormatInt(u.ID, 10), u.ExternalRef, u.DisplayName, u.Country, u.Currency, u.Status, u.KYCLevel, formatTime(u.CreatedAt), deletedAt, formatTime(trail.PeriodStart), formatTime(trail.PeriodEnd), trail.OpeningBalance, trail.ClosingBalance}},
+	)
+
+	var rows [][]string
+	for _, t := range trail.Transactions {
+		rows = append(rows, []string{strconv.FormatInt(t.ID, 10), t.TransactionID, formatTime(t.CreatedAt), t.State, t.Amount, t.BalanceAfter, t.TransferID, t.Actor, t.Hash})
+	}
+	write("transactions.csv", []string{"id", "transaction_id", "created_at", "state", "amount", "balance_after", "transfer_id", "actor", "hash"}, rows)
+
+	rows = nil
+	for _, r := range trail.Rejections {
+		rows = append(rows, []string{r.TransactionID, formatTime(r.CreatedAt), r.State, r.Amount, r.Reason, r.Actor})
+	}
+	write("rejections.csv", []string{"transaction_id", "created_at", "state", "amount", "reason", "actor"}, rows)
+
+	rows = nil
+	for _, a := range trail.Adjustments {
+		rows = append(rows, []string{formatTime(a.RecordedAt), a.Amount, a.BalanceAfter, a.Actor})
+	}
+	write("adjustments.csv", []string{"recorded_at", "amount", "balance_after", "actor"}, rows)
+
+	rows = nil
+	for _, c := range trail.StatusChanges {
+		rows = append(rows, []string{formatTime(c.ChangedAt), c.FromStatus, c.ToStatus, c.Reason, c.Actor})
+	}
+	write("status_changes.csv", []string{"changed_at", "from_status", "to_status", "reason", "actor"}, rows)
+
+	return files, names
+}
+
+// xmlAuditTrail is the XML layout of an audit trail.
+type xmlAuditTrail struct {
+	XMLName        xml.Name          `xml:"AuditTrail"`
+	Schema         string            `xml:"schema,attr"`
+	PeriodStart    string            `xml:"Period>Start"`
+	PeriodEnd      string            `xml:"Period>End"`
+	OpeningBalance string            `xml:"Period>OpeningBalance"`
+	ClosingBalance string            `xml:"Period>ClosingBalance"`
+	User           xmlUser           `xml:"User"`
+	Transactions   []xmlTransaction  `xml:"Transactions>Transaction"`
+	Rejections     []xmlRejection    `xml:"Rejections>Rejection"`
+	Adjustments    []xmlAdjustment   `xml:"Adjustments>Adjustment"`
+	StatusChanges  []xmlStatusChange `xml:"StatusChanges>StatusChange"`
+}
+
+type xmlUser struct {
+	ID          int64  `xml:"ID"`
+	ExternalRef string `xml:"ExternalRef,omitempty"`
+	DisplayName string `xml:"DisplayName,omitempty"`
+	Country     string `xml:"Country,omitempty"`
+	Currency    string `xml:"Currency"`
+	Status      string `xml:"Status"`
+	KYCLevel    string `xml:"KYCLevel"`
+	CreatedAt   string `xml:"CreatedAt"`
+	DeletedAt   string `xml:"DeletedAt,omitempty"`
+}
+
+type xmlTransaction struct {
+	ID            int64  `xml:"id,attr"`
+	TransactionID string `xml:"TransactionID"`
+	CreatedAt     string `xml:"CreatedAt"`
+	State         string `xml:"State"`
+	Amount        string `xml:"Amount"`
+	BalanceAfter  string `xml:"BalanceAfter"`
+	TransferID    string `xml:"TransferID,omitempty"`
+	Actor         string `xml:"Actor"`
+	Hash          string `xml:"Hash,omitempty"`
+}
+
+type xmlRejection struct {
+	TransactionID string `xml:"TransactionID"`
+	CreatedAt     string `xml:"CreatedAt"`
+	State         string `xml:"State"`
+	Amount        string `xml:"Amount"`
+	Reason        string `xml:"Reason"`
+	Actor         string `xml:"Actor"`
+}
+
+type xmlAdjustment struct {
+	RecordedAt   string `xml:"RecordedAt"`
+	Amount       string `xml:"Amount"`
+	BalanceAfter string `xml:"BalanceAfter"`
+	Actor        string `xml:"Actor"`
+}
+
+type xmlStatusChange struct {
+	ChangedAt  string `xml:"ChangedAt"`
+	FromStatus string `xml:"FromStatus"`
+	ToStatus   string `xml:"ToStatus"`
+	Reason     string `xml:"Reason"`
+	Actor      string `xml:"Actor"`
+}
+
+// xmlFile lays the audit trail out as a single XML document.
+func xmlFile(trail *models.AuditTrail) ([]byte, error) {
+	u := trail.User
+	doc := xmlAuditTrail{
+		Schema:         Schema,
+		PeriodStart:    formatTime(trail.PeriodStart),
+		PeriodEnd:      formatTime(trail.PeriodEnd),
+		OpeningBalance: trail.OpeningBalance,
+		ClosingBalance: trail.ClosingBalance,
+		User: xmlUser{
+			ID:          u.ID,
+			ExternalRef: u.ExternalRef,
+			DisplayName: u.DisplayName,
+			Country:     u.Country,
+			Currency:    u.Currency,
+			Status:      u.Status,
+			KYCLevel:    u.KYCLevel,
+			CreatedAt:   formatTime(u.CreatedAt),
+		},
+	}
+	if u.DeletedAt != nil {
+		doc.User.DeletedAt = formatTime(*u.DeletedAt)
+	}
+	for _, t := range trail.Transactions {
+		doc.Transactions = append(doc.Transactions, xmlTransaction{t.ID, t.TransactionID, formatTime(t.CreatedAt), t.State, t.Amount, t.BalanceAfter, t.TransferID, t.Actor, t.Hash})
+	}
+	for _, r := range trail.Rejections {
+		doc.Rejections = append(doc.Rejections, xmlRejection{r.TransactionID, formatTime(r.CreatedAt), r.State, r.Amount, r.Reason, r.Actor})
+	}
+	for _, a := range trail.Adjustments {
+		doc.Adjustments = append(doc.Adjustments, xmlAdjustment{formatTime(a.RecordedAt), a.Amount, a.BalanceAfter, a.Actor})
+	}
+	for _, c := range trail.StatusChanges {
+		doc.StatusChanges = append(doc.StatusChanges, xmlStatusChange{formatTime(c.ChangedAt), c.FromStatus, c.ToStatus, c.Reason, c.Actor})
+	}
+
+	body, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit trail: %w", err)
+	}
+	return append([]byte(xml.Header), append(body, '\n')...), nil
+}
@@ -0,0 +1,166 @@
+package reporting
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"assignment/internal/models"
+)
+
+var testSeed = base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, ed25519.SeedSize))
+
+func testTrail() *models.AuditTrail {
+	at := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	return &models.AuditTrail{
+		User:           &models.User{ID: 1, Currency: "EUR", Status: "suspended", KYCLevel: "full", DisplayName: "Ann, \"Tester\"", CreatedAt: at.AddDate(-1, 0, 0)},
+		PeriodStart:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		PeriodEnd:      time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		OpeningBalance: "100.00",
+		ClosingBalance: "105.00",
+		Transactions: []models.AuditTransaction{
+			{ID: 7, TransactionID: "t-1", State: "win", Amount: "10.00", BalanceAfter: "110.00", Actor: "source_type:game", Hash: "ab", CreatedAt: at},
+		},
+		Rejections: []models.AuditRejection{
+			{TransactionID: "t-2", State: "lose", Amount: "500.00", Reason: "insufficient_funds", Actor: "source_type:game", CreatedAt: at},
+		},
+		Adjustments: []models.AuditAdjustment{
+			{Amount: "-5.00", BalanceAfter: "105.00", Actor: "ledger_repair", RecordedAt: at},
+		},
+		StatusChanges: []models.AuditStatusChange{
+			{FromStatus: "active", ToStatus: "suspended", Reason: "ledger drift: balance 110.00, ledger 105.00", Actor: "consistency_checker", ChangedAt: at},
+		},
+	}
+}
+
+func unzip(t *testing.T, bundle []byte) map[string]string {
+	t.Helper()
+	archive, err := zip.NewReader(bytes.NewReader(bundle), int64(len(bundle)))
+	if err != nil {
+		t.Fatalf("Expected a ZIP archive, got: %v", err)
+	}
+	files := map[string]string{}
+	for _, f := range archive.File {
+		r, _ := f.Open()
+		body, _ := io.ReadAll(r)
+		r.Close()
+		files[f.Name] = string(body)
+	}
+	return files
+}
+
+// rezip rewrites a bundle with change applied to its files.
+func rezip(t *testing.T, bundle []byte, change func(files map[string]string)) []byte {
+	t.Helper()
+	files := unzip(t, bundle)
+	change(files)
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, body := range files {
+		f, _ := w.Create(name)
+		f.Write([]byte(body))
+	}
+	w.Close()
+	return buf.Bytes()
+}
+
+func TestBundle_CSV(t *testing.T) {
+	signer, err := NewSigner(testSeed)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	bundle, err := signer.Bundle(testTrail(), FormatCSV, time.Date(2024, 2, 2, 9, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	public, _ := base64.StdEncoding.DecodeString(signer.PublicKey())
+	manifest, err := Verify(bundle, public)
+	if err != nil {
+		t.Fatalf("Expected the bundle to verify, got: %v", err)
+	}
+	if manifest.UserID != 1 || manifest.Format != FormatCSV || len(manifest.Files) != 5 {
+		t.Errorf("Unexpected manifest: %+v", manifest)
+	}
+
+	files := unzip(t, bundle)
+	for name, want := range map[string]string{
+		"user.csv":           `1,,"Ann, ""Tester""",,EUR,suspended,full,2023-01-15T12:00:00Z,,2024-01-01T00:00:00Z,2024-02-01T00:00:00Z,100.00,105.00`,
+		"transactions.csv":   "7,t-1,2024-01-15T12:00:00Z,win,10.00,110.00,,source_type:game,ab",
+		"rejections.csv":     "t-2,2024-01-15T12:00:00Z,lose,500.00,insufficient_funds,source_type:game",
+		"adjustments.csv":    "2024-01-15T12:00:00Z,-5.00,105.00,ledger_repair",
+		"status_changes.csv": `2024-01-15T12:00:00Z,active,suspended,"ledger drift: balance 110.00, ledger 105.00",consistency_checker`,
+	} {
+		if !strings.Contains(files[name], want) {
+			t.Errorf("Expected %s to contain %q, got:\n%s", name, want, files[name])
+		}
+	}
+}
+
+func TestBundle_XML(t *testing.T) {
+	signer, _ := NewSigner(testSeed)
+	bundle, err := signer.Bundle(testTrail(), FormatXML, time.Now())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	public, _ := base64.StdEncoding.DecodeString(signer.PublicKey())
+	if _, err := Verify(bundle, public); err != nil {
+		t.Fatalf("Expected the bundle to verify, got: %v", err)
+	}
+
+	doc := unzip(t, bundle)["audit-trail.xml"]
+	for _, want := range []string{
+		`<AuditTrail schema="wallet.audit-trail.v1">`,
+		`<Transaction id="7">`,
+		`<Actor>ledger_repair</Actor>`,
+		`<ToStatus>suspended</ToStatus>`,
+	} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("Expected the XML to contain %q, got:\n%s", want, doc)
+		}
+	}
+
+	if _, err := signer.Bundle(testTrail(), "pdf", time.Now()); err == nil {
+		t.Error("Expected an unknown format to be refused")
+	}
+}
+
+func TestVerify_DetectsTampering(t *testing.T) {
+	signer, _ := NewSigner(testSeed)
+	bundle, _ := signer.Bundle(testTrail(), FormatCSV, time.Now())
+	public, _ := base64.StdEncoding.DecodeString(signer.PublicKey())
+
+	for name, change := range map[string]func(map[string]string){
+		"edited file": func(files map[string]string) {
+			files["transactions.csv"] = strings.Replace(files["transactions.csv"], "10.00", "1.00", 1)
+		},
+		"removed file": func(files map[string]string) { delete(files, "rejections.csv") },
+		"added file":   func(files map[string]string) { files["extra.csv"] = "x" },
+		"edited manifest": func(files map[string]string) {
+			files[ManifestName] = strings.Replace(files[ManifestName], `"userId": 1`, `"userId": 2`, 1)
+		},
+	} {
+		if _, err := Verify(rezip(t, bundle, change), public); err == nil {
+			t.Errorf("Expected the %s to be detected", name)
+		}
+	}
+
+	other, _ := NewSigner(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{8}, ed25519.SeedSize)))
+	otherPublic, _ := base64.StdEncoding.DecodeString(other.PublicKey())
+	if _, err := Verify(bundle, otherPublic); err == nil {
+		t.Error("Expected a bundle signed with another key to be refused")
+	}
+}
+
+func TestNewSigner_InvalidKey(t *testing.T) {
+	for _, seed := range []string{"not base64!", base64.StdEncoding.EncodeToString([]byte("short"))} {
+		if _, err := NewSigner(seed); err == nil {
+			t.Errorf("Expected seed %q to be refused", seed)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package reporting
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"assignment/internal/models"
+)
+
+// Jurisdiction is how a market wants player tax statements: when its tax
+// year starts and how numbers are written in CSV. Tax years run between
+// UTC midnights.
+type Jurisdiction struct {
+	Code string
+	// YearStartMonth and YearStartDay are the first day of a tax year
+	YearStartMonth time.Month
+	YearStartDay   int
+	// Delimiter separates CSV fields; DecimalComma writes amounts with a
+	// decimal comma
+	Delimiter    rune
+	DecimalComma bool
+}
+
+// Jurisdictions are the supported tax statement formats by code. The
+// default is the calendar year with plain CSV.
+var Jurisdictions = map[string]Jurisdiction{
+	"default": {Code: "default", YearStartMonth: time.January, YearStartDay: 1, Delimiter: ','},
+	"GB":      {Code: "GB", YearStartMonth: time.April, YearStartDay: 6, Delimiter: ','},
+	"DE":      {Code: "DE", YearStartMonth: time.January, YearStartDay: 1, Delimiter: ';', DecimalComma: true},
+	"FR":      {Code: "FR", YearStartMonth: time.January, YearStartDay: 1, Delimiter: ';', DecimalComma: true},
+}
+
+// TaxYear returns the tax year starting in year: its bounds and its label,
+// the year itself, or e.g. "2024-25" for years starting after January 1st.
+func (j Jurisdiction) TaxYear(year int) (start, end time.Time, label string) {
+	start = time.Date(year, j.YearStartMonth, j.YearStartDay, 0, 0, 0, 0, time.UTC)
+	end = start.AddDate(1, 0, 0)
+	label = strconv.Itoa(year)
+	if j.YearStartMonth != time.January || j.YearStartDay != 1 {
+		label = fmt.Sprintf("%d-%02d", year, (year+1)%100)
+	}
+	return start, end, label
+}
+
+// TaxCSV writes a tax summary as a CSV header and row in the jurisdiction's
+// format. The period is written as its first and last day.
+func (j Jurisdiction) TaxCSV(summary *models.TaxSummary) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Comma = j.Delimiter
+	w.Write([]string{"user_id", "jurisdiction", "tax_year", "period_start", "period_end", "currency", "turnover", "winnings", "net_win_loss", "transactions"})
+	w.Write([]string{
+		strconv.FormatInt(summary.UserID, 10),
+		j.Code,
+		summary.TaxYear,
+		summary.PeriodStart.UTC().Format("2006-01-02"),
+		summary.PeriodEnd.UTC().AddDate(0, 0, -1).Format("2006-01-02"),
+		summary.Currency,
+		j.amount(summary.Turnover),
+		j.amount(summary.Winnings),
+		j.amount(summary.NetWinLoss),
+		strconv.Itoa(summary.TransactionCount),
+	})
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, fmt.Errorf("failed to write tax summary: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (j Jurisdiction) amount(a string) string {
+	if j.DecimalComma {
+		return strings.Replace(a, ".", ",", 1)
+	}
+	return a
+}
@@ -0,0 +1,36 @@
+package reporting
+
+import (
+	"testing"
+	"time"
+
+	"assignment/internal/models"
+)
+
+func TestJurisdictionTaxYear(t *testing.T) {
+	start, end, label := Jurisdictions["default"].TaxYear(2024)
+	if !start.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) || !end.Equal(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)) || label != "2024" {
+		t.Errorf("Unexpected default tax year: %v - %v %s", start, end, label)
+	}
+	start, end, label = Jurisdictions["GB"].TaxYear(2024)
+	if !start.Equal(time.Date(2024, 4, 6, 0, 0, 0, 0, time.UTC)) || !end.Equal(time.Date(2025, 4, 6, 0, 0, 0, 0, time.UTC)) || label != "2024-25" {
+		t.Errorf("Unexpected GB tax year: %v - %v %s", start, end, label)
+	}
+}
+
+func TestJurisdictionTaxCSV(t *testing.T) {
+	j := Jurisdictions["DE"]
+	start, end, label := j.TaxYear(2024)
+	summary := &models.TaxSummary{UserID: 1, TaxYear: label, PeriodStart: start, PeriodEnd: end, Currency: "EUR",
+		Turnover: "1200.00", Winnings: "950.50", NetWinLoss: "-249.50", TransactionCount: 42}
+
+	body, err := j.TaxCSV(summary)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	want := "user_id;jurisdiction;tax_year;period_start;period_end;currency;turnover;winnings;net_win_loss;transactions\n" +
+		"1;DE;2024;2024-01-01;2024-12-31;EUR;1200,00;950,50;-249,50;42\n"
+	if string(body) != want {
+		t.Errorf("Unexpected CSV:\n%s", body)
+	}
+}
@@ -0,0 +1,58 @@
+// Package requestid carries the ID correlating a request across services:
+// taken from the X-Request-ID header of the request, or generated, and
+// passed on to the services it calls.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"assignment/internal/ulid"
+)
+
+// Header is the header the ID travels in, on requests and responses.
+const Header = "X-Request-ID"
+
+// maxLength bounds IDs accepted from clients.
+const maxLength = 128
+
+type contextKey struct{}
+
+// New generates an ID, a ULID so IDs sort by time.
+func New() string {
+	id, err := ulid.New(time.Now())
+	if err != nil {
+		// The generator is exhausted for this millisecond
+		b := make([]byte, 16)
+		rand.Read(b)
+		return hex.EncodeToString(b)
+	}
+	return id
+}
+
+// Valid reports whether an ID supplied by a client can be used as is: 1 to
+// 128 printable ASCII characters without spaces, so it is safe to log.
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// WithID returns a copy of ctx carrying id.
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the ID ctx carries, or "".
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
@@ -0,0 +1,34 @@
+package requestid
+
+import (
+	"context"
+	"testing"
+)
+
+func TestValid(t *testing.T) {
+	tests := map[string]bool{
+		"":                           false,
+		"01HM6FWAG0R8QZ4M3V7KXB2D5N": true,
+		"gateway-123:abc":            true,
+		"has space":                  false,
+		"line\nbreak":                false,
+		"café":                       false,
+	}
+	for id, want := range tests {
+		if got := Valid(id); got != want {
+			t.Errorf("Valid(%q) = %v, want %v", id, got, want)
+		}
+	}
+	if id := New(); !Valid(id) {
+		t.Errorf("Expected generated ID %q to be valid", id)
+	}
+}
+
+func TestContext(t *testing.T) {
+	if id := FromContext(context.Background()); id != "" {
+		t.Errorf("Expected no ID, got %q", id)
+	}
+	if id := FromContext(WithID(context.Background(), "abc")); id != "abc" {
+		t.Errorf("Expected abc, got %q", id)
+	}
+}
@@ -0,0 +1,340 @@
+// Package saga orchestrates multi-step flows whose steps call systems outside
+// the database transaction. Progress is persisted after every step, and when
+// a step fails the completed steps are compensated in reverse order. A step
+// whose outcome is unknown (see Uncertain) is not compensated: the saga is
+// left pending and the step is retried when the saga is next continued.
+//
+// A step may run more than once if the process stops between running it and
+// recording its outcome, so actions and compensations must be idempotent.
+package saga
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"assignment/internal/clock"
+)
+
+// Saga statuses.
+const (
+	StatusRunning      = "running"
+	StatusCompleted    = "completed"
+	StatusCompensating = "compensating"
+	StatusCompensated  = "compensated"
+	// StatusPending means the outcome of a step is unknown; the step is
+	// retried by Start and Resume, or resolved manually.
+	StatusPending = "pending"
+	// StatusFailed means a compensation failed and needs manual attention.
+	StatusFailed = "failed"
+)
+
+// Uncertain marks err as a failure whose outcome is unknown, e.g. a timeout
+// of a remote call that may still have taken effect. A step returning it
+// leaves the saga pending instead of compensating it.
+func Uncertain(err error) error {
+	return &uncertainError{err}
+}
+
+type uncertainError struct {
+	err error
+}
+
+func (e *uncertainError) Error() string { return e.err.Error() }
+func (e *uncertainError) Unwrap() error { return e.err }
+
+// IsUncertain reports whether err was marked with Uncertain.
+func IsUncertain(err error) bool {
+	var uncertain *uncertainError
+	return errors.As(err, &uncertain)
+}
+
+type Step struct {
+	Name   string
+	Action func(ctx context.Context, s *Instance) error
+	// Compensate undoes Action; nil when there is nothing to undo.
+	Compensate func(ctx context.Context, s *Instance) error
+}
+
+type Definition struct {
+	Name  string
+	Steps []Step
+}
+
+type Instance struct {
+	ID         string          `json:"id"`
+	Definition string          `json:"definition"`
+	Payload    json.RawMessage `json:"payload"`
+	Status     string          `json:"status"`
+	// Step is the index of the next step to run or, while compensating, the
+	// number of completed steps still to compensate.
+	Step      int       `json:"step"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Decode unmarshals the saga payload into v.
+func (s *Instance) Decode(v interface{}) error {
+	return json.Unmarshal(s.Payload, v)
+}
+
+type Orchestrator struct {
+	db          *sql.DB
+	clock       clock.Clock
+	definitions map[string]Definition
+}
+
+func NewOrchestrator(db *sql.DB, clk clock.Clock) *Orchestrator {
+	return &Orchestrator{
+		db:          db,
+		clock:       clk,
+		definitions: map[string]Definition{},
+	}
+}
+
+// Register makes a definition available to Start and Resume.
+func (o *Orchestrator) Register(def Definition) {
+	o.definitions[def.Name] = def
+}
+
+// Start runs a new saga with the given ID until it completes or has been
+// compensated, or until a step leaves it pending. If a saga with that ID
+// already exists it is continued (when unfinished) and returned instead, so
+// callers may safely retry Start.
+func (o *Orchestrator) Start(ctx context.Context, definition, id string, payload interface{}) (*Instance, error) {
+	if _, ok := o.definitions[definition]; !ok {
+		return nil, fmt.Errorf("unknown saga definition %q", definition)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode saga payload: %w", err)
+	}
+
+	_, err = o.db.Exec(
+		`INSERT INTO sagas (id, definition, payload, status, step, error, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, 0, '', $5, $5)
+		 ON CONFLICT (id) DO NOTHING`,
+		id,
+		definition,
+		string(data),
+		StatusRunning,
+		o.clock.Now(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create saga: %w", err)
+	}
+
+	inst, err := o.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if inst.Definition != definition {
+		return nil, fmt.Errorf("saga %s already exists with definition %q", id, inst.Definition)
+	}
+
+	return o.continueSaga(ctx, id)
+}
+
+// Resume continues every unfinished saga, e.g. after a restart, retrying
+// the steps of pending sagas.
+func (o *Orchestrator) Resume(ctx context.Context) error {
+	rows, err := o.db.Query(
+		`SELECT id FROM sagas WHERE status IN ($1, $2, $3) ORDER BY created_at, id`,
+		StatusRunning,
+		StatusCompensating,
+		StatusPending,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to query unfinished sagas: %w", err)
+	}
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan saga: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read unfinished sagas: %w", err)
+	}
+
+	for _, id := range ids {
+		inst, err := o.continueSaga(ctx, id)
+		if err != nil {
+			return err
+		}
+		log.Printf("Resumed saga %s: %s", inst.ID, inst.Status)
+	}
+	return nil
+}
+
+// Get loads a saga by ID.
+func (o *Orchestrator) Get(id string) (*Instance, error) {
+	var inst Instance
+	var payload []byte
+	err := o.db.QueryRow(
+		`SELECT id, definition, payload, status, step, error, created_at, updated_at FROM sagas WHERE id = $1`,
+		id,
+	).Scan(&inst.ID, &inst.Definition, &payload, &inst.Status, &inst.Step, &inst.Error, &inst.CreatedAt, &inst.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("saga not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saga: %w", err)
+	}
+	inst.Payload = payload
+	return &inst, nil
+}
+
+// continueSaga runs the saga from its persisted position while holding an
+// advisory lock on it, so that concurrent callers never run the same saga's
+// steps at once. If another caller holds the lock, the saga is returned as
+// currently stored.
+func (o *Orchestrator) continueSaga(ctx context.Context, id string) (*Instance, error) {
+	conn, err := o.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	var locked bool
+	err = conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock(hashtext('saga:' || $1))`, id).Scan(&locked)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock saga: %w", err)
+	}
+	if !locked {
+		return o.Get(id)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock(hashtext('saga:' || $1))`, id)
+
+	// Reload under the lock; another caller may have advanced the saga
+	inst, err := o.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.run(ctx, inst); err != nil {
+		return nil, err
+	}
+	return inst, nil
+}
+
+// run drives inst forward from its persisted position. It only returns an
+// error when progress cannot be persisted; step failures are recorded on the
+// instance.
+func (o *Orchestrator) run(ctx context.Context, inst *Instance) error {
+	def, ok := o.definitions[inst.Definition]
+	if !ok {
+		return fmt.Errorf("unknown saga definition %q", inst.Definition)
+	}
+
+	// Retry the step a pending saga stopped at
+	if inst.Status == StatusPending {
+		inst.Status = StatusRunning
+		inst.Error = ""
+	}
+
+	for inst.Status == StatusRunning {
+		if inst.Step == len(def.Steps) {
+			inst.Status = StatusCompleted
+			return o.record(inst, "", "", nil)
+		}
+
+		step := def.Steps[inst.Step]
+		if err := step.Action(ctx, inst); err != nil {
+			inst.Status = StatusCompensating
+			if IsUncertain(err) {
+				inst.Status = StatusPending
+			}
+			inst.Error = fmt.Sprintf("%s: %v", step.Name, err)
+			if err := o.record(inst, step.Name, "action", err); err != nil {
+				return err
+			}
+			break
+		}
+
+		inst.Step++
+		if err := o.record(inst, step.Name, "action", nil); err != nil {
+			return err
+		}
+	}
+
+	for inst.Status == StatusCompensating {
+		if inst.Step == 0 {
+			inst.Status = StatusCompensated
+			return o.record(inst, "", "", nil)
+		}
+
+		step := def.Steps[inst.Step-1]
+		if step.Compensate != nil {
+			if err := step.Compensate(ctx, inst); err != nil {
+				inst.Status = StatusFailed
+				inst.Error = fmt.Sprintf("%s; compensating %s: %v", inst.Error, step.Name, err)
+				return o.record(inst, step.Name, "compensate", err)
+			}
+		}
+
+		inst.Step--
+		if err := o.record(inst, step.Name, "compensate", nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// record persists the saga position together with the outcome of a step
+// phase. An empty step only persists the position.
+func (o *Orchestrator) record(inst *Instance, step, phase string, stepErr error) error {
+	inst.UpdatedAt = o.clock.Now()
+
+	tx, err := o.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(
+		`UPDATE sagas SET status = $1, step = $2, error = $3, updated_at = $4 WHERE id = $5`,
+		inst.Status,
+		inst.Step,
+		inst.Error,
+		inst.UpdatedAt,
+		inst.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update saga: %w", err)
+	}
+
+	if step != "" {
+		errText := ""
+		if stepErr != nil {
+			errText = stepErr.Error()
+		}
+		_, err = tx.Exec(
+			`INSERT INTO saga_steps (saga_id, step, phase, error, recorded_at) VALUES ($1, $2, $3, $4, $5)`,
+			inst.ID,
+			step,
+			phase,
+			errText,
+			inst.UpdatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to record saga step: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit saga step: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package saga
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"assignment/internal/clock"
+	"assignment/internal/testutil"
+)
+
+// recorder builds steps that append their calls to a log.
+type recorder struct {
+	calls []string
+	fail  map[string]bool
+}
+
+func (r *recorder) step(name string) Step {
+	return Step{
+		Name: name,
+		Action: func(ctx context.Context, s *Instance) error {
+			r.calls = append(r.calls, name)
+			if r.fail[name] {
+				return errors.New("boom")
+			}
+			return nil
+		},
+		Compensate: func(ctx context.Context, s *Instance) error {
+			r.calls = append(r.calls, "undo "+name)
+			if r.fail["undo "+name] {
+				return errors.New("boom")
+			}
+			return nil
+		},
+	}
+}
+
+func newOrchestrator(t *testing.T, r *recorder) *Orchestrator {
+	t.Helper()
+	db := testutil.NewDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	o := NewOrchestrator(db, clock.New())
+	o.Register(Definition{Name: "test", Steps: []Step{r.step("a"), r.step("b"), r.step("c")}})
+	return o
+}
+
+func TestStart_Completes(t *testing.T) {
+	r := &recorder{}
+	o := newOrchestrator(t, r)
+
+	inst, err := o.Start(context.Background(), "test", "saga-1", map[string]int{"n": 1})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if inst.Status != StatusCompleted || inst.Step != 3 {
+		t.Errorf("Expected completed at step 3, got: %s at %d", inst.Status, inst.Step)
+	}
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(r.calls, want) {
+		t.Errorf("Expected calls %v, got: %v", want, r.calls)
+	}
+
+	// Starting again returns the finished saga without re-running it
+	again, err := o.Start(context.Background(), "test", "saga-1", map[string]int{"n": 1})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if again.Status != StatusCompleted || len(r.calls) != 3 {
+		t.Errorf("Expected no further calls, got: %v", r.calls)
+	}
+}
+
+func TestStart_CompensatesInReverse(t *testing.T) {
+	r := &recorder{fail: map[string]bool{"c": true}}
+	o := newOrchestrator(t, r)
+
+	inst, err := o.Start(context.Background(), "test", "saga-2", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if inst.Status != StatusCompensated || inst.Error != "c: boom" {
+		t.Errorf("Expected compensated with error 'c: boom', got: %s %q", inst.Status, inst.Error)
+	}
+	if want := []string{"a", "b", "c", "undo b", "undo a"}; !reflect.DeepEqual(r.calls, want) {
+		t.Errorf("Expected calls %v, got: %v", want, r.calls)
+	}
+
+	stored, err := o.Get("saga-2")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if stored.Status != StatusCompensated || stored.Step != 0 {
+		t.Errorf("Expected stored compensated saga at step 0, got: %s at %d", stored.Status, stored.Step)
+	}
+}
+
+func TestStart_FailedCompensation(t *testing.T) {
+	r := &recorder{fail: map[string]bool{"c": true, "undo b": true}}
+	o := newOrchestrator(t, r)
+
+	inst, err := o.Start(context.Background(), "test", "saga-3", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if inst.Status != StatusFailed || inst.Step != 2 {
+		t.Errorf("Expected failed at step 2, got: %s at %d", inst.Status, inst.Step)
+	}
+	if want := []string{"a", "b", "c", "undo b"}; !reflect.DeepEqual(r.calls, want) {
+		t.Errorf("Expected calls %v, got: %v", want, r.calls)
+	}
+}
+
+func TestResume(t *testing.T) {
+	r := &recorder{}
+	o := newOrchestrator(t, r)
+
+	// Simulate a crash after the first step completed
+	_, err := o.db.Exec(
+		`INSERT INTO sagas (id, definition, payload, status, step, created_at, updated_at)
+		 VALUES ('saga-4', 'test', '{}', $1, 1, NOW(), NOW())`,
+		StatusRunning,
+	)
+	if err != nil {
+		t.Fatalf("Failed to insert saga: %v", err)
+	}
+
+	if err := o.Resume(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if want := []string{"b", "c"}; !reflect.DeepEqual(r.calls, want) {
+		t.Errorf("Expected calls %v, got: %v", want, r.calls)
+	}
+
+	inst, err := o.Get("saga-4")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if inst.Status != StatusCompleted {
+		t.Errorf("Expected completed, got: %s", inst.Status)
+	}
+}
+
+func TestStart_UncertainStepIsLeftPending(t *testing.T) {
+	r := &recorder{}
+	o := newOrchestrator(t, r)
+	timeouts := 1
+	o.Register(Definition{Name: "uncertain", Steps: []Step{
+		r.step("a"),
+		{
+			Name: "b",
+			Action: func(ctx context.Context, s *Instance) error {
+				r.calls = append(r.calls, "b")
+				if timeouts > 0 {
+					timeouts--
+					return Uncertain(errors.New("timeout"))
+				}
+				return nil
+			},
+		},
+	}})
+
+	inst, err := o.Start(context.Background(), "uncertain", "saga-5", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if inst.Status != StatusPending || inst.Step != 1 || inst.Error != "b: timeout" {
+		t.Errorf("Expected pending at step 1 with 'b: timeout', got: %s at %d %q", inst.Status, inst.Step, inst.Error)
+	}
+
+	// Nothing is compensated; resuming retries the uncertain step
+	if err := o.Resume(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if want := []string{"a", "b", "b"}; !reflect.DeepEqual(r.calls, want) {
+		t.Errorf("Expected calls %v, got: %v", want, r.calls)
+	}
+	stored, err := o.Get("saga-5")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if stored.Status != StatusCompleted || stored.Error != "" {
+		t.Errorf("Expected completed without error, got: %s %q", stored.Status, stored.Error)
+	}
+}
@@ -0,0 +1,65 @@
+// Package schedule runs background work at a fixed time of day.
+package schedule
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"assignment/internal/clock"
+	"assignment/internal/lock"
+)
+
+// NextDaily returns the first time strictly after now that is at the given
+// offset from UTC midnight.
+func NextDaily(now time.Time, at time.Duration) time.Time {
+	now = now.UTC()
+	next := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).Add(at)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// Daily calls fn once a day at the given offset from UTC midnight until ctx
+// is cancelled. fn receives the UTC day that has just ended.
+//
+// With a locker, only the replica that takes the lock named after the job
+// and day runs fn; the others skip the day, as do all replicas if the lock
+// can't be taken. The context passed to fn is cancelled if the lock is lost
+// during the run. locker may be nil for a single instance.
+func Daily(ctx context.Context, clk clock.Clock, locker lock.Locker, name string, at time.Duration, fn func(ctx context.Context, day time.Time)) {
+	for {
+		now := clk.Now()
+		next := NextDaily(now, at)
+		log.Printf("Next %s run at %s", name, next.Format(time.RFC3339))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(next.Sub(now)):
+		}
+
+		RunExclusive(ctx, locker, name, next.AddDate(0, 0, -1), fn)
+	}
+}
+
+// RunExclusive runs a daily job for day, if locker is nil or its lock for
+// the day is taken.
+func RunExclusive(ctx context.Context, locker lock.Locker, name string, day time.Time, fn func(ctx context.Context, day time.Time)) {
+	if locker == nil {
+		fn(ctx, day)
+		return
+	}
+	lease, err := locker.TryLock(ctx, "schedule:"+name+":"+day.Format("2006-01-02"))
+	if err != nil {
+		log.Printf("Skipping %s run: %v", name, err)
+		return
+	}
+	if lease == nil {
+		log.Printf("Skipping %s run: another instance runs it", name)
+		return
+	}
+	defer lease.Release()
+	fn(lease.Context(), day)
+}
@@ -0,0 +1,73 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"assignment/internal/lock"
+)
+
+func TestNextDaily(t *testing.T) {
+	at := 2*time.Hour + 30*time.Minute
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{"before run time", time.Date(2024, 1, 15, 1, 0, 0, 0, time.UTC), time.Date(2024, 1, 15, 2, 30, 0, 0, time.UTC)},
+		{"at run time", time.Date(2024, 1, 15, 2, 30, 0, 0, time.UTC), time.Date(2024, 1, 16, 2, 30, 0, 0, time.UTC)},
+		{"after run time", time.Date(2024, 1, 15, 23, 0, 0, 0, time.UTC), time.Date(2024, 1, 16, 2, 30, 0, 0, time.UTC)},
+		{"month boundary", time.Date(2024, 1, 31, 3, 0, 0, 0, time.UTC), time.Date(2024, 2, 1, 2, 30, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NextDaily(tt.now, at); !got.Equal(tt.want) {
+				t.Errorf("NextDaily() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type failingLocker struct{}
+
+func (failingLocker) TryLock(ctx context.Context, name string) (*lock.Lease, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestRunExclusive(t *testing.T) {
+	day := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	runs := 0
+	count := func(ctx context.Context, day time.Time) { runs++ }
+
+	RunExclusive(context.Background(), nil, "settlement", day, count)
+	if runs != 1 {
+		t.Fatalf("Expected a run without a locker, got: %d", runs)
+	}
+
+	// Another replica holds the day's lock
+	locker := lock.NewLocal()
+	lease, _ := locker.TryLock(context.Background(), "schedule:settlement:2024-01-15")
+	RunExclusive(context.Background(), locker, "settlement", day, count)
+	if runs != 1 {
+		t.Errorf("Expected no run while the lock is held, got: %d", runs)
+	}
+	RunExclusive(context.Background(), locker, "settlement", day.AddDate(0, 0, 1), func(ctx context.Context, day time.Time) {
+		if ctx.Err() != nil {
+			t.Error("Expected the lease context to be live during the run")
+		}
+		runs++
+	})
+	if runs != 2 {
+		t.Errorf("Expected a run for the next day, got: %d", runs)
+	}
+	lease.Release()
+
+	RunExclusive(context.Background(), failingLocker{}, "settlement", day, count)
+	if runs != 2 {
+		t.Errorf("Expected no run when the lock can't be taken, got: %d", runs)
+	}
+}
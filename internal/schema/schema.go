@@ -0,0 +1,263 @@
+// Package schema validates request bodies against the JSON Schemas in
+// schemas/, which also describe the request bodies in the OpenAPI document.
+// It implements the subset of JSON Schema the schemas use: type, properties,
+// required, additionalProperties, items, enum, minLength, maxLength and
+// pattern.
+package schema
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+//go:embed schemas/*.json
+var files embed.FS
+
+// Schema is a parsed JSON Schema.
+type Schema struct {
+	Type                 types              `json:"type"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	Enum                 []interface{}      `json:"enum"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	Pattern              string             `json:"pattern"`
+
+	pattern *regexp.Regexp
+}
+
+// types is the "type" of a schema, either one type or a list of them.
+type types []string
+
+func (t *types) UnmarshalJSON(data []byte) error {
+	var one string
+	if json.Unmarshal(data, &one) == nil {
+		*t = types{one}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(t))
+}
+
+var (
+	schemas = map[string]*Schema{}
+	raw     = map[string]json.RawMessage{}
+)
+
+func init() {
+	entries, err := files.ReadDir("schemas")
+	if err != nil {
+		panic(err)
+	}
+	for _, entry := range entries {
+		data, err := files.ReadFile("schemas/" + entry.Name())
+		if err != nil {
+			panic(err)
+		}
+		var s Schema
+		if err := json.Unmarshal(data, &s); err != nil {
+			panic(fmt.Sprintf("schema %s: %v", entry.Name(), err))
+		}
+		if err := s.compile(); err != nil {
+			panic(fmt.Sprintf("schema %s: %v", entry.Name(), err))
+		}
+		name := strings.TrimSuffix(entry.Name(), path.Ext(entry.Name()))
+		schemas[name] = &s
+		raw[name] = data
+	}
+}
+
+func (s *Schema) compile() error {
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return err
+		}
+		s.pattern = re
+	}
+	for _, p := range s.Properties {
+		if err := p.compile(); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.compile()
+	}
+	return nil
+}
+
+// Documents returns the schemas by name, as written, e.g. to publish them in
+// the OpenAPI document.
+func Documents() map[string]json.RawMessage {
+	return raw
+}
+
+// Validate checks a JSON body against the named schema. Malformed JSON is
+// reported with the decoder's error; violations of the schema with the path
+// of each offending value, e.g. "amount: must be a string, got number".
+func Validate(name string, body []byte) error {
+	s, ok := schemas[name]
+	if !ok {
+		return fmt.Errorf("unknown schema %q", name)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("unexpected data after the JSON value")
+	}
+
+	var violations []string
+	s.validate("", doc, &violations)
+	if len(violations) > 0 {
+		return errors.New(strings.Join(violations, "; "))
+	}
+	return nil
+}
+
+func (s *Schema) validate(at string, value interface{}, violations *[]string) {
+	fail := func(format string, args ...interface{}) {
+		msg := fmt.Sprintf(format, args...)
+		if at != "" {
+			msg = at + ": " + msg
+		}
+		*violations = append(*violations, msg)
+	}
+
+	if len(s.Type) > 0 && !s.Type.match(value) {
+		fail("must be %s, got %s", s.Type.describe(), typeOf(value))
+		return
+	}
+	if len(s.Enum) > 0 && !s.inEnum(value) {
+		fail("must be one of %s", s.describeEnum())
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				fail("%s is required", name)
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			p, ok := s.Properties[name]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					fail("unknown field %s", name)
+				}
+				continue
+			}
+			p.validate(join(at, name), v[name], violations)
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(fmt.Sprintf("%s[%d]", at, i), item, violations)
+			}
+		}
+	case string:
+		n := utf8.RuneCountInString(v)
+		if s.MinLength != nil && n < *s.MinLength {
+			fail("must be at least %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			fail("must be at most %d characters", *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			fail("must match %s", s.Pattern)
+		}
+	}
+}
+
+func join(at, name string) string {
+	if at == "" {
+		return name
+	}
+	return at + "." + name
+}
+
+// typeOf is the JSON Schema type of a decoded value.
+func typeOf(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+func (t types) match(value interface{}) bool {
+	actual := typeOf(value)
+	for _, want := range t {
+		if want == actual || (want == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// describe lists the types with articles, e.g. "a string or null".
+func (t types) describe() string {
+	described := make([]string, len(t))
+	for i, name := range t {
+		switch name {
+		case "null":
+			described[i] = "null"
+		case "array", "integer", "object":
+			described[i] = "an " + name
+		default:
+			described[i] = "a " + name
+		}
+	}
+	return strings.Join(described, " or ")
+}
+
+// inEnum compares values by their JSON encoding, so 1 and "1" differ.
+func (s *Schema) inEnum(value interface{}) bool {
+	encoded, _ := json.Marshal(value)
+	for _, allowed := range s.Enum {
+		if data, _ := json.Marshal(allowed); bytes.Equal(data, encoded) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Schema) describeEnum() string {
+	quoted := make([]string, len(s.Enum))
+	for i, allowed := range s.Enum {
+		data, _ := json.Marshal(allowed)
+		quoted[i] = string(data)
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,84 @@
+package schema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		schema  string
+		body    string
+		wantErr string
+	}{
+		{"transaction", `{"state":"win","amount":"10.50","transactionId":"t1"}`, ""},
+		{"transaction", `{"state":"win","amount":"10.50","extra":1}`, ""},
+		{"transaction", `{"state":"win","amount":10.5}`, "amount: must be a string, got number"},
+		{"transaction", `{"state":null,"amount":"1.00","transactionId":7}`, "state: must be a string, got null; transactionId: must be a string, got integer"},
+		{"transaction", `["win"]`, "must be an object, got array"},
+		{"transaction", `{`, "unexpected EOF"},
+		{"transaction", ``, "EOF"},
+		{"transaction", `{} {}`, "unexpected data after the JSON value"},
+		{"transfer", `{"fromUserId":1,"toUserId":2.5,"amount":"1.00"}`, "toUserId: must be an integer, got number"},
+		{"update_user", `{"currency":null,"country":"DE"}`, ""},
+		{"update_user", `{"country":49}`, "country: must be a string or null, got integer"},
+		{"add_user_tags", `{"tags":["vip",3]}`, "tags[1]: must be a string, got integer"},
+		{"unknown", `{}`, `unknown schema "unknown"`},
+	}
+	for _, tt := range tests {
+		err := Validate(tt.schema, []byte(tt.body))
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tt.wantErr {
+			t.Errorf("Validate(%s, %s) error = %q, want %q", tt.schema, tt.body, got, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateKeywords(t *testing.T) {
+	var s Schema
+	if err := json.Unmarshal([]byte(`{
+		"type": "object",
+		"required": ["id"],
+		"additionalProperties": false,
+		"properties": {
+			"id": {"type": "string", "minLength": 2, "maxLength": 4, "pattern": "^[a-z]+$"},
+			"kind": {"enum": ["a", 1]}
+		}
+	}`), &s); err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+	if err := s.compile(); err != nil {
+		t.Fatalf("Failed to compile schema: %v", err)
+	}
+
+	tests := map[string]string{
+		`{"id":"abc","kind":1}`:    "",
+		`{"kind":"a"}`:             "id is required",
+		`{"id":"a"}`:               "id: must be at least 2 characters",
+		`{"id":"abcde"}`:           "id: must be at most 4 characters",
+		`{"id":"AB"}`:              "id: must match ^[a-z]+$",
+		`{"id":"ab","kind":"1"}`:   `kind: must be one of "a", 1`,
+		`{"id":"ab","other":true}`: "unknown field other",
+	}
+	for body, want := range tests {
+		var doc interface{}
+		dec := json.NewDecoder(strings.NewReader(body))
+		dec.UseNumber()
+		if err := dec.Decode(&doc); err != nil {
+			t.Fatalf("Failed to decode %s: %v", body, err)
+		}
+		var violations []string
+		s.validate("", doc, &violations)
+		got := ""
+		if len(violations) > 0 {
+			got = violations[0]
+		}
+		if got != want {
+			t.Errorf("validate(%s) = %q, want %q", body, got, want)
+		}
+	}
+}
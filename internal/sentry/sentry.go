@@ -0,0 +1,189 @@
+// Package sentry reports server errors to Sentry, or any service accepting
+// Sentry envelopes, configured by a DSN. Events are sent in the background so
+// reporting never slows down requests.
+package sentry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"assignment/internal/requestid"
+)
+
+// queueSize bounds the events waiting to be sent; events beyond it are
+// dropped rather than blocking requests during an error storm.
+const queueSize = 100
+
+// sensitiveHeaders are not sent with the request context.
+var sensitiveHeaders = map[string]bool{
+	"Authorization":    true,
+	"Cookie":           true,
+	"Stripe-Signature": true,
+}
+
+type Client struct {
+	dsn         string
+	endpoint    string
+	publicKey   string
+	release     string
+	environment string
+	httpClient  *http.Client
+	events      chan map[string]interface{}
+}
+
+// New creates a client reporting to the project of dsn
+// (https://{key}@{host}/{projectId}), tagging events with release and
+// environment.
+func New(dsn, release, environment string) (*Client, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse DSN: %w", err)
+	}
+	slash := strings.LastIndex(u.Path, "/")
+	projectID := u.Path[slash+1:]
+	if u.User == nil || u.User.Username() == "" || projectID == "" || u.Host == "" {
+		return nil, errors.New("DSN must look like https://{key}@{host}/{projectId}")
+	}
+	return &Client{
+		dsn:         dsn,
+		endpoint:    fmt.Sprintf("%s://%s%s/api/%s/envelope/", u.Scheme, u.Host, u.Path[:slash], projectID),
+		publicKey:   u.User.Username(),
+		release:     release,
+		environment: environment,
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+		events:      make(chan map[string]interface{}, queueSize),
+	}, nil
+}
+
+// DefaultRelease is the VCS revision the binary was built from, if known.
+func DefaultRelease() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" {
+			return setting.Value
+		}
+	}
+	return ""
+}
+
+// CaptureRequestError queues an event for a request that failed with status,
+// with the stack of the panic that caused it, if any.
+func (c *Client) CaptureRequestError(r *http.Request, status int, message string, stack []byte) {
+	id := make([]byte, 16)
+	rand.Read(id)
+
+	headers := map[string]string{}
+	for name := range r.Header {
+		if !sensitiveHeaders[name] {
+			headers[name] = r.Header.Get(name)
+		}
+	}
+	// Group events by route rather than by user
+	segments := strings.Split(r.URL.Path, "/")
+	for i, segment := range segments {
+		if _, err := strconv.ParseInt(segment, 10, 64); err == nil {
+			segments[i] = "{id}"
+		}
+	}
+	route := strings.Join(segments, "/")
+	event := map[string]interface{}{
+		"event_id":    hex.EncodeToString(id),
+		"timestamp":   time.Now().UTC().Format(time.RFC3339Nano),
+		"level":       "error",
+		"platform":    "go",
+		"logger":      "http",
+		"release":     c.release,
+		"environment": c.environment,
+		"transaction": r.Method + " " + route,
+		"message":     map[string]string{"formatted": message},
+		"tags": map[string]string{
+			"status_code": strconv.Itoa(status),
+			"method":      r.Method,
+		},
+		"request": map[string]interface{}{
+			"method":       r.Method,
+			"url":          r.URL.Path,
+			"query_string": r.URL.RawQuery,
+			"headers":      headers,
+		},
+	}
+	if id := r.Header.Get(requestid.Header); id != "" {
+		event["tags"].(map[string]string)["request_id"] = id
+	}
+	// Path format: /user/{userId}/...
+	if parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/"); len(parts) >= 2 && parts[0] == "user" {
+		event["user"] = map[string]string{"id": parts[1]}
+	}
+	if stack != nil {
+		event["level"] = "fatal"
+		event["extra"] = map[string]string{"stack": string(stack)}
+	}
+
+	select {
+	case c.events <- event:
+	default:
+		log.Printf("Error report dropped, queue full: %s", message)
+	}
+}
+
+// Run blocks until ctx is cancelled, sending queued events.
+func (c *Client) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-c.events:
+			if err := c.send(ctx, event); err != nil {
+				log.Printf("Failed to send error report: %v", err)
+			}
+		}
+	}
+}
+
+// send posts an event as an envelope.
+func (c *Client) send(ctx context.Context, event map[string]interface{}) error {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, item := range []interface{}{
+		map[string]string{"event_id": event["event_id"].(string), "dsn": c.dsn},
+		map[string]string{"type": "event"},
+		event,
+	} {
+		if err := encoder.Encode(item); err != nil {
+			return fmt.Errorf("failed to encode event: %w", err)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, &body)
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", "Sentry sentry_version=7, sentry_client=assignment/1.0, sentry_key="+c.publicKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send event: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("Sentry returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package sentry
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewParsesDSN(t *testing.T) {
+	c, err := New("https://abc123@o1.ingest.sentry.io/42", "v1.2.3", "staging")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if c.endpoint != "https://o1.ingest.sentry.io/api/42/envelope/" || c.publicKey != "abc123" {
+		t.Errorf("Unexpected endpoint %s or key %s", c.endpoint, c.publicKey)
+	}
+	for _, dsn := range []string{"https://o1.ingest.sentry.io/42", "https://abc@o1.ingest.sentry.io/", "::"} {
+		if _, err := New(dsn, "", ""); err == nil {
+			t.Errorf("Expected %q to be invalid", dsn)
+		}
+	}
+}
+
+func TestCaptureRequestError(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	var lines []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scanner := bufio.NewScanner(r.Body)
+		scanner.Buffer(nil, 1<<20)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		received <- r
+	}))
+	defer server.Close()
+
+	c, err := New(strings.Replace(server.URL, "http://", "http://key@", 1)+"/7", "v1.2.3", "staging")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Run(ctx)
+
+	req := httptest.NewRequest("POST", "/user/12/transaction?debug=1", nil)
+	req.Header.Set("Authorization", "Bearer ut_secret")
+	req.Header.Set("Source-Type", "game")
+	c.CaptureRequestError(req, 500, "Internal server error: connection refused", nil)
+
+	r := <-received
+	if r.URL.Path != "/api/7/envelope/" || !strings.Contains(r.Header.Get("X-Sentry-Auth"), "sentry_key=key") {
+		t.Errorf("Unexpected request: %s %v", r.URL.Path, r.Header)
+	}
+	if len(lines) != 3 {
+		t.Fatalf("Expected an envelope of 3 lines, got: %v", lines)
+	}
+	var event struct {
+		Release     string            `json:"release"`
+		Environment string            `json:"environment"`
+		Transaction string            `json:"transaction"`
+		Tags        map[string]string `json:"tags"`
+		User        map[string]string `json:"user"`
+		Request     struct {
+			Headers map[string]string `json:"headers"`
+		} `json:"request"`
+	}
+	if err := json.Unmarshal([]byte(lines[2]), &event); err != nil {
+		t.Fatalf("Failed to decode event: %v", err)
+	}
+	if event.Release != "v1.2.3" || event.Environment != "staging" || event.Transaction != "POST /user/{id}/transaction" {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+	if event.Tags["status_code"] != "500" || event.User["id"] != "12" {
+		t.Errorf("Unexpected tags or user: %v %v", event.Tags, event.User)
+	}
+	if _, ok := event.Request.Headers["Authorization"]; ok || event.Request.Headers["Source-Type"] != "game" {
+		t.Errorf("Unexpected headers: %v", event.Request.Headers)
+	}
+}
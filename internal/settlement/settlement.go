@@ -0,0 +1,73 @@
+// Package settlement runs the daily settlement summary: once a day it stores
+// the previous day's per-source-type totals and optionally posts them to a
+// webhook.
+package settlement
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"assignment/internal/clock"
+	"assignment/internal/core"
+	"assignment/internal/lock"
+	"assignment/internal/models"
+	"assignment/internal/schedule"
+	"assignment/internal/webhook"
+)
+
+type Job struct {
+	service *core.TransactionService
+	clock   clock.Clock
+	at      time.Duration
+	webhook *webhook.Client
+	locker  lock.Locker
+}
+
+// NewJob creates a job that runs every day at the given offset from UTC
+// midnight. hook may be nil when no webhook is configured, and locker when
+// a single instance runs the job.
+func NewJob(service *core.TransactionService, clk clock.Clock, at time.Duration, hook *webhook.Client, locker lock.Locker) *Job {
+	return &Job{
+		service: service,
+		clock:   clk,
+		at:      at,
+		webhook: hook,
+		locker:  locker,
+	}
+}
+
+// Run blocks until ctx is cancelled, settling the previous day at each
+// scheduled time.
+func (j *Job) Run(ctx context.Context) {
+	schedule.Daily(ctx, j.clock, j.locker, "settlement", j.at, func(ctx context.Context, day time.Time) {
+		if _, err := j.Settle(ctx, day); err != nil {
+			log.Printf("Settlement for %s failed: %v", day.Format("2006-01-02"), err)
+		}
+	})
+}
+
+// Settle computes and stores the totals for day and posts them to the
+// webhook if one is configured. Re-running it for the same day replaces the
+// stored totals.
+func (j *Job) Settle(ctx context.Context, day time.Time) (*models.ReconciliationReport, error) {
+	report, err := j.service.GetReconciliation(day, models.TagFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := j.service.SaveSettlementReport(report); err != nil {
+		return nil, err
+	}
+	log.Printf("Settlement for %s stored: %d source totals, balance deltas %v",
+		report.Date, len(report.Sources), report.BalanceDeltas)
+
+	if j.webhook != nil {
+		if err := j.webhook.Send(ctx, report); err != nil {
+			// The report is already stored; a failed notification must not undo it
+			log.Printf("Failed to post settlement for %s: %v", report.Date, err)
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,61 @@
+package settlement
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"assignment/internal/clock"
+	"assignment/internal/core"
+	"assignment/internal/models"
+	"assignment/internal/testutil"
+	"assignment/internal/webhook"
+)
+
+func TestSettle(t *testing.T) {
+	db := testutil.NewDB(t)
+	defer db.Close()
+
+	day := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(day)
+	service := core.NewTransactionService(db, clk)
+
+	if _, err := service.ProcessTransaction(1, testutil.NewTransactionRequest("win", "10.00"), "game"); err != nil {
+		t.Fatalf("Failed to process transaction: %v", err)
+	}
+
+	posted := make(chan models.ReconciliationReport, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var report models.ReconciliationReport
+		json.NewDecoder(r.Body).Decode(&report)
+		posted <- report
+	}))
+	defer server.Close()
+
+	clk.Advance(24 * time.Hour)
+	job := NewJob(service, clk, 0, webhook.NewClient(server.URL), nil)
+
+	// Running twice for the same day must not duplicate stored rows
+	for i := 0; i < 2; i++ {
+		if _, err := job.Settle(context.Background(), day); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+
+	var count int
+	var credits string
+	if err := db.QueryRow(`SELECT COUNT(*), MAX(credits) FROM settlement_reports WHERE report_date = '2024-01-15'`).Scan(&count, &credits); err != nil {
+		t.Fatalf("Failed to read settlement reports: %v", err)
+	}
+	if count != 1 || credits != "10.00" {
+		t.Errorf("Expected 1 stored row with credits 10.00, got: %d rows, credits %s", count, credits)
+	}
+
+	report := <-posted
+	if report.Date != "2024-01-15" || len(report.BalanceDeltas) != 1 || report.BalanceDeltas[0] != (models.CurrencyTotal{Currency: "EUR", Amount: "10.00"}) {
+		t.Errorf("Unexpected webhook payload: %+v", report)
+	}
+}
@@ -0,0 +1,111 @@
+// Package statement renders player account statements.
+package statement
+
+import (
+	"fmt"
+
+	"assignment/internal/models"
+	"assignment/internal/pdf"
+)
+
+const (
+	margin      = 50.0
+	rowHeight   = 14.0
+	bodySize    = 9.0
+	footerY     = 30.0
+	bottomLimit = 60.0
+)
+
+// Table columns: left edge of each column; amounts are right-aligned to
+// the right edge of their column.
+var (
+	colDate         = margin
+	colTransaction  = margin + 95
+	colType         = margin + 265
+	colSource       = margin + 305
+	colAmountRight  = margin + 420
+	colBalanceRight = pdf.PageWidth - margin
+)
+
+// RenderPDF lays out a statement as an A4 PDF: header with period and
+// opening balance, one row per transaction, and the closing balance.
+func RenderPDF(st *models.Statement) []byte {
+	doc := pdf.New()
+	y := newPage(doc, st)
+
+	doc.Text(margin, y, 11, pdf.Bold, "Opening balance: "+st.OpeningBalance)
+	y -= 2 * rowHeight
+
+	y = tableHeader(doc, y)
+	if len(st.Transactions) == 0 {
+		doc.Text(margin, y, bodySize, pdf.Regular, "No transactions in this period.")
+		y -= rowHeight
+	}
+
+	for _, line := range st.Transactions {
+		if y < bottomLimit {
+			y = tableHeader(doc, newPage(doc, st))
+		}
+
+		amount := "+" + line.Amount
+		if line.State == "lose" {
+			amount = "-" + line.Amount
+		}
+
+		doc.Text(colDate, y, bodySize, pdf.Regular, line.CreatedAt.UTC().Format("2006-01-02 15:04"))
+		doc.Text(colTransaction, y, bodySize, pdf.Regular, truncate(line.TransactionID, 30))
+		doc.Text(colType, y, bodySize, pdf.Regular, line.State)
+		doc.Text(colSource, y, bodySize, pdf.Regular, line.SourceType)
+		rightAlign(doc, colAmountRight, y, amount)
+		rightAlign(doc, colBalanceRight, y, line.BalanceAfter)
+		y -= rowHeight
+	}
+
+	if y < bottomLimit {
+		y = newPage(doc, st)
+	}
+	doc.Line(margin, pdf.PageWidth-margin, y+rowHeight-4)
+	doc.Text(margin, y-rowHeight, 11, pdf.Bold, "Closing balance: "+st.ClosingBalance)
+
+	total := doc.PageCount()
+	for i := 0; i < total; i++ {
+		doc.TextOnPage(i, margin, footerY, 8, pdf.Regular, fmt.Sprintf("Page %d of %d", i+1, total))
+	}
+
+	return doc.Bytes()
+}
+
+func newPage(doc *pdf.Document, st *models.Statement) float64 {
+	doc.AddPage()
+	y := pdf.PageHeight - margin - 10
+
+	doc.Text(margin, y, 16, pdf.Bold, "Account Statement")
+	y -= 22
+	doc.Text(margin, y, 10, pdf.Regular, fmt.Sprintf("User ID: %d", st.UserID))
+	y -= rowHeight
+	doc.Text(margin, y, 10, pdf.Regular, fmt.Sprintf("Period: %s to %s (UTC)",
+		st.PeriodStart.Format("2006-01-02"), st.PeriodEnd.AddDate(0, 0, -1).Format("2006-01-02")))
+	return y - 2*rowHeight
+}
+
+func tableHeader(doc *pdf.Document, y float64) float64 {
+	doc.Text(colDate, y, bodySize, pdf.Bold, "Date")
+	doc.Text(colTransaction, y, bodySize, pdf.Bold, "Transaction")
+	doc.Text(colType, y, bodySize, pdf.Bold, "Type")
+	doc.Text(colSource, y, bodySize, pdf.Bold, "Source")
+	rightAlign(doc, colAmountRight, y, "Amount")
+	rightAlign(doc, colBalanceRight, y, "Balance")
+	doc.Line(margin, pdf.PageWidth-margin, y-4)
+	return y - rowHeight - 2
+}
+
+func rightAlign(doc *pdf.Document, right, y float64, s string) {
+	doc.Text(right-pdf.TextWidth(s, bodySize), y, bodySize, pdf.Regular, s)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-3] + "..."
+}
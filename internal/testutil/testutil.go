@@ -0,0 +1,99 @@
+// Package testutil provides database fixtures shared by the integration tests.
+package testutil
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"assignment/internal/db"
+	"assignment/internal/models"
+	_ "github.com/lib/pq"
+)
+
+// DefaultDSN points at the local test database. Set TEST_DATABASE_URL to
+// override it.
+const DefaultDSN = "host=localhost user=postgres password=postgres dbname=assignment_test sslmode=disable"
+
+// Epoch is the creation time of users created by CreateUser.
+var Epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+var transactionCounter int64
+
+// NewDB connects to the test database, recreates the schema and seeds the
+// default users (1: 100.00, 2: 50.00, 3: 0.00). The test is skipped when no
+// database is reachable. Callers are responsible for closing the connection.
+func NewDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	connStr := os.Getenv("TEST_DATABASE_URL")
+	if connStr == "" {
+		connStr = DefaultDSN
+	}
+
+	conn, err := sql.Open("postgres", connStr)
+	if err != nil {
+		t.Skipf("Skipping test: database not available: %v", err)
+	}
+	if err := conn.Ping(); err != nil {
+		conn.Close()
+		t.Skipf("Skipping test: database not available: %v", err)
+	}
+
+	ResetSchema(t, conn)
+	CreateUser(t, conn, 1, "100.00")
+	CreateUser(t, conn, 2, "50.00")
+	CreateUser(t, conn, 3, "0.00")
+
+	return conn
+}
+
+// ResetSchema drops every table and re-runs the application migrations, so
+// tests always run against the same schema as production.
+func ResetSchema(t *testing.T, conn *sql.DB) {
+	t.Helper()
+
+	if _, err := conn.Exec("DROP SCHEMA IF EXISTS public CASCADE"); err != nil {
+		t.Fatalf("Failed to drop schema: %v", err)
+	}
+	if _, err := conn.Exec("CREATE SCHEMA public"); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+
+	if err := (&db.DB{DB: conn}).Migrate(); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+}
+
+// CreateUser inserts a user with the given ID and balance, along with the
+// baseline balance snapshot and opened event stream the application seeds
+// for every user. The user ID sequence is moved past the new ID.
+func CreateUser(t *testing.T, conn *sql.DB, id int64, balance string) {
+	t.Helper()
+
+	if _, err := conn.Exec(`INSERT INTO users (id, balance, created_at, updated_at) VALUES ($1, $2, $3, $3)`, id, balance, Epoch); err != nil {
+		t.Fatalf("Failed to create user %d: %v", id, err)
+	}
+	if _, err := conn.Exec(`INSERT INTO balance_snapshots (user_id, balance, recorded_at) VALUES ($1, $2, $3)`, id, balance, Epoch); err != nil {
+		t.Fatalf("Failed to create balance snapshot for user %d: %v", id, err)
+	}
+	if _, err := conn.Exec(`INSERT INTO balance_events (user_id, version, type, amount, balance, recorded_at) VALUES ($1, 1, 'opened', $2, $2, $3)`, id, balance, Epoch); err != nil {
+		t.Fatalf("Failed to open balance event stream for user %d: %v", id, err)
+	}
+	if _, err := conn.Exec(`SELECT setval('users_id_seq', (SELECT MAX(id) FROM users))`); err != nil {
+		t.Fatalf("Failed to advance user ID sequence: %v", err)
+	}
+}
+
+// NewTransactionRequest builds a valid request with a transaction ID that is
+// unique within the test binary.
+func NewTransactionRequest(state, amount string) models.TransactionRequest {
+	return models.TransactionRequest{
+		State:         state,
+		Amount:        amount,
+		TransactionID: fmt.Sprintf("test-txn-%d", atomic.AddInt64(&transactionCounter, 1)),
+	}
+}
@@ -0,0 +1,105 @@
+// Package ulid generates ULIDs: 128-bit identifiers made of a 48-bit
+// millisecond timestamp and 80 random bits, encoded as 26 Crockford base32
+// characters that sort in creation order.
+package ulid
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+const alphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// maxTime is the largest timestamp a ULID can hold.
+const maxTime = 1<<48 - 1
+
+// Generator produces strictly increasing ULIDs. Within a millisecond, and
+// when the clock moves backwards, the random part of the previous ULID is
+// incremented instead of drawn again, so a single generator never repeats
+// an ID.
+type Generator struct {
+	mu      sync.Mutex
+	entropy io.Reader
+	lastMs  uint64
+	last    [10]byte
+}
+
+// NewGenerator returns a Generator drawing randomness from entropy, or from
+// crypto/rand when entropy is nil.
+func NewGenerator(entropy io.Reader) *Generator {
+	if entropy == nil {
+		entropy = rand.Reader
+	}
+	return &Generator{entropy: entropy}
+}
+
+// New returns a ULID for time t.
+func (g *Generator) New(t time.Time) (string, error) {
+	ms := uint64(t.UnixMilli())
+	if t.UnixMilli() < 0 || ms > maxTime {
+		return "", fmt.Errorf("time %v cannot be encoded in a ULID", t)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if ms <= g.lastMs && g.lastMs != 0 {
+		ms = g.lastMs
+		if !increment(&g.last) {
+			return "", errors.New("ulid random space exhausted for this millisecond")
+		}
+	} else {
+		if _, err := io.ReadFull(g.entropy, g.last[:]); err != nil {
+			return "", fmt.Errorf("failed to read entropy: %w", err)
+		}
+		g.lastMs = ms
+	}
+
+	var id [16]byte
+	for i := 0; i < 6; i++ {
+		id[i] = byte(ms >> (40 - 8*i))
+	}
+	copy(id[6:], g.last[:])
+	return encode(id), nil
+}
+
+var defaultGenerator = NewGenerator(nil)
+
+// New returns a ULID for time t from a process-wide generator.
+func New(t time.Time) (string, error) {
+	return defaultGenerator.New(t)
+}
+
+// increment adds one to b as a big-endian integer and reports false on
+// overflow.
+func increment(b *[10]byte) bool {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i]++
+		if b[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// encode writes the 128 bits of id, left-padded to 130, as 26 base32
+// characters.
+func encode(id [16]byte) string {
+	var hi, lo uint64
+	for i := 0; i < 8; i++ {
+		hi = hi<<8 | uint64(id[i])
+		lo = lo<<8 | uint64(id[8+i])
+	}
+
+	var out [26]byte
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = alphabet[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
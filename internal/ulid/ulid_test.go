@@ -0,0 +1,57 @@
+package ulid
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestNew_Encoding(t *testing.T) {
+	g := NewGenerator(bytes.NewReader(make([]byte, 10)))
+
+	// 2024-01-15T12:00:00Z is 1705320000000 ms
+	id, err := g.New(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if id != "01HM6FWAG00000000000000000" {
+		t.Errorf("Expected zero entropy ULID, got: %s", id)
+	}
+}
+
+func TestNew_MonotonicWithinMillisecond(t *testing.T) {
+	g := NewGenerator(nil)
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	prev, err := g.New(now)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	for i := 0; i < 1000; i++ {
+		// Same millisecond, then a clock step backwards
+		at := now
+		if i == 500 {
+			at = now.Add(-time.Second)
+		}
+		id, err := g.New(at)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if id <= prev {
+			t.Fatalf("Expected %s to sort after %s", id, prev)
+		}
+		prev = id
+	}
+}
+
+func TestNew_Exhausted(t *testing.T) {
+	g := NewGenerator(bytes.NewReader(bytes.Repeat([]byte{0xff}, 10)))
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	if _, err := g.New(now); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := g.New(now); err == nil {
+		t.Error("Expected error once the millisecond's random space is exhausted")
+	}
+}
@@ -4,6 +4,12 @@ import (
 	"errors"
 	"regexp"
 	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"assignment/internal/money"
+	"assignment/internal/validation"
 )
 
 var (
@@ -16,34 +22,63 @@ var (
 		"win":  true,
 		"lose": true,
 	}
-	amountRegex = regexp.MustCompile(`^\d+(\.\d{1,2})?$`)
+	validTopMetrics = map[string]bool{
+		"balance": true,
+		"net_win": true,
+	}
+	validUserStatuses = map[string]bool{
+		"active":    true,
+		"suspended": true,
+		"closed":    true,
+	}
+	validPushPlatforms = map[string]bool{
+		"fcm":  true,
+		"apns": true,
+	}
+	validNotificationChannels = map[string]bool{
+		"email": true,
+		"sms":   true,
+		"push":  true,
+	}
+	escrowNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,63}$`)
+	currencyRegex   = regexp.MustCompile(`^[A-Z]{3}$`)
+	countryRegex    = regexp.MustCompile(`^[A-Z]{2}$`)
+	tagRegex        = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+	walletIDRegex   = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+	phoneRegex      = regexp.MustCompile(`^\+[1-9][0-9]{7,14}$`)
 )
 
+// MaxReportWindow bounds the time windows accepted by reporting endpoints.
+const MaxReportWindow = 366 * 24 * time.Hour
+
+// MaxReportLimit bounds the number of rows returned by ranking reports.
+const MaxReportLimit = 100
+
 func ValidateSourceType(sourceType string) error {
 	if !validSourceTypes[sourceType] {
-		return errors.New("invalid Source-Type header: must be 'game', 'server', or 'payment'")
+		return validation.Errorf("Source-Type", "invalid Source-Type header: must be 'game', 'server', or 'payment'")
 	}
 	return nil
 }
 
 func ValidateState(state string) error {
 	if !validStates[state] {
-		return errors.New("invalid state: must be 'win' or 'lose'")
+		return validation.Errorf("state", "invalid state: must be 'win' or 'lose'")
 	}
 	return nil
 }
 
+// ValidateAmount checks the format of an amount whose currency is not known
+// yet, with up to the decimals of any currency; see money.ValidateAmount for
+// the decimals of a given currency.
 func ValidateAmount(amountStr string) error {
-	if !amountRegex.MatchString(amountStr) {
-		return errors.New("invalid amount format: must be a string with up to 2 decimal places")
-	}
-	return nil
+	return money.ValidateAmount(amountStr, "")
 }
 
 func ValidateUserID(userIDStr string) (int64, error) {
 	userID, err := strconv.ParseInt(userIDStr, 10, 64)
 	if err != nil || userID <= 0 {
-		return 0, errors.New("invalid user ID: must be a positive integer")
+		return 0, validation.Errorf("userId", "invalid user ID: must be a positive integer")
 	}
 	return userID, nil
 }
@@ -51,15 +86,159 @@ func ValidateUserID(userIDStr string) (int64, error) {
 func ParseAmount(amountStr string) (float64, error) {
 	amount, err := strconv.ParseFloat(amountStr, 64)
 	if err != nil {
-		return 0, errors.New("invalid amount: cannot parse as number")
+		return 0, validation.Errorf("amount", "invalid amount: cannot parse as number")
 	}
 	if amount < 0 {
-		return 0, errors.New("invalid amount: cannot be negative")
+		return 0, validation.Errorf("amount", "invalid amount: cannot be negative")
 	}
 	return amount, nil
 }
 
+// ParseBalance parses a stored balance. Unlike amounts, balances may be
+// negative, after a chargeback.
+func ParseBalance(balanceStr string) (float64, error) {
+	balance, err := strconv.ParseFloat(balanceStr, 64)
+	if err != nil {
+		return 0, errors.New("invalid balance: cannot parse as number")
+	}
+	return balance, nil
+}
+
+// FormatBalance formats an amount not tied to a currency, such as a
+// configured limit, with money.DefaultExponent decimals, or up to
+// money.MaxExponent if it has more, so it is never rounded; see money.Format
+// for amounts of a currency.
 func FormatBalance(balance float64) string {
-	return strconv.FormatFloat(balance, 'f', 2, 64)
+	formatted := strconv.FormatFloat(balance, 'f', money.MaxExponent, 64)
+	for i := money.MaxExponent; i > money.DefaultExponent && strings.HasSuffix(formatted, "0"); i-- {
+		formatted = strings.TrimSuffix(formatted, "0")
+	}
+	return formatted
+}
+
+// ParseWindow parses a reporting window such as "15m", "24h" or "7d".
+func ParseWindow(window string) (time.Duration, error) {
+	var d time.Duration
+	var err error
+	if days := strings.TrimSuffix(window, "d"); days != window {
+		var n int
+		n, err = strconv.Atoi(days)
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		d, err = time.ParseDuration(window)
+	}
+	if err != nil || d <= 0 || d > MaxReportWindow {
+		return 0, validation.Errorf("window", "invalid window: must be a positive duration such as 1h, 24h or 7d, at most 366d")
+	}
+	return d, nil
+}
+
+func ValidateTopMetric(metric string) error {
+	if !validTopMetrics[metric] {
+		return validation.Errorf("metric", "invalid metric: must be 'balance' or 'net_win'")
+	}
+	return nil
+}
+
+func ValidateUserStatus(status string) error {
+	if !validUserStatuses[status] {
+		return validation.Errorf("status", "invalid status: must be 'active', 'suspended' or 'closed'")
+	}
+	return nil
+}
+
+func ValidateNotificationChannel(channel string) error {
+	if !validNotificationChannels[channel] {
+		return validation.Errorf("channel", "invalid channel: must be 'email', 'sms' or 'push'")
+	}
+	return nil
+}
+
+func ValidatePushPlatform(platform string) error {
+	if !validPushPlatforms[platform] {
+		return validation.Errorf("platform", "invalid platform: must be 'fcm' or 'apns'")
+	}
+	return nil
+}
+
+func ValidateDeviceToken(token string) error {
+	if token == "" || len(token) > 4096 || strings.ContainsAny(token, "/ ") {
+		return validation.Errorf("token", "invalid device token: must be 1-4096 characters without '/' or spaces")
+	}
+	return nil
 }
 
+func ValidatePhone(phone string) error {
+	if !phoneRegex.MatchString(phone) {
+		return validation.Errorf("phone", "invalid phone: must be an E.164 number such as +4915112345678")
+	}
+	return nil
+}
+
+func ValidateTag(tag string) error {
+	if !tagRegex.MatchString(tag) {
+		return validation.Errorf("tag", "invalid tag: must be 1-32 lowercase letters, digits, '_' or '-'")
+	}
+	return nil
+}
+
+func ValidateWalletID(walletID string) error {
+	if !walletIDRegex.MatchString(walletID) {
+		return validation.Errorf("walletId", "invalid wallet ID: must be 1-32 lowercase letters, digits, '_' or '-'")
+	}
+	return nil
+}
+
+func ValidateEscrowName(name string) error {
+	if !escrowNameRegex.MatchString(name) {
+		return validation.Errorf("name", "invalid escrow name: must be 1-64 lowercase letters, digits, '.', '_' or '-'")
+	}
+	return nil
+}
+
+func ValidateCurrency(currency string) error {
+	if !currencyRegex.MatchString(currency) {
+		return validation.Errorf("currency", "invalid currency: must be a 3-letter ISO 4217 code")
+	}
+	return nil
+}
+
+func ValidateCountry(country string) error {
+	if !countryRegex.MatchString(country) {
+		return validation.Errorf("country", "invalid country: must be a 2-letter ISO 3166-1 code")
+	}
+	return nil
+}
+
+// MaxDisplayNameLength and MaxExternalRefLength bound user profile fields,
+// in characters.
+const (
+	MaxDisplayNameLength = 100
+	MaxExternalRefLength = 128
+)
+
+func ValidateDisplayName(name string) error {
+	if utf8.RuneCountInString(name) > MaxDisplayNameLength || strings.TrimSpace(name) != name {
+		return validation.Errorf("display_name", "invalid display name: must be at most 100 characters without surrounding spaces")
+	}
+	return nil
+}
+
+func ValidateExternalRef(ref string) error {
+	if utf8.RuneCountInString(ref) > MaxExternalRefLength || strings.TrimSpace(ref) != ref {
+		return validation.Errorf("external_ref", "invalid external reference: must be at most 128 characters without surrounding spaces")
+	}
+	return nil
+}
+
+// ParseLimit parses a report row limit, returning def when limit is empty.
+func ParseLimit(limit string, def int) (int, error) {
+	if limit == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(limit)
+	if err != nil || n <= 0 || n > MaxReportLimit {
+		return 0, validation.Errorf("limit", "invalid limit: must be an integer between 1 and 100")
+	}
+	return n, nil
+}
@@ -1,6 +1,10 @@
 package utils
 
-import "testing"
+import (
+	"strings"
+	"testing"
+	"time"
+)
 
 func TestValidateSourceType(t *testing.T) {
 	tests := []struct {
@@ -56,7 +60,8 @@ func TestValidateAmount(t *testing.T) {
 		{"valid integer", "100", false},
 		{"valid one decimal", "10.5", false},
 		{"valid two decimals", "10.50", false},
-		{"invalid three decimals", "10.500", true},
+		{"valid three decimals", "10.500", false},
+		{"invalid four decimals", "10.5000", true},
 		{"invalid format", "10.5.5", true},
 		{"invalid characters", "abc", true},
 	}
@@ -104,6 +109,7 @@ func TestFormatBalance(t *testing.T) {
 		{"one decimal", 100.5, "100.50"},
 		{"two decimals", 100.55, "100.55"},
 		{"zero", 0.0, "0.00"},
+		{"three decimals", 0.125, "0.125"},
 	}
 
 	for _, tt := range tests {
@@ -116,3 +122,192 @@ func TestFormatBalance(t *testing.T) {
 	}
 }
 
+func TestParseBalance(t *testing.T) {
+	if balance, err := ParseBalance("-12.50"); err != nil || balance != -12.5 {
+		t.Errorf("ParseBalance(-12.50) = %v, %v", balance, err)
+	}
+	if _, err := ParseBalance("abc"); err == nil {
+		t.Error("Expected error for invalid balance")
+	}
+}
+
+func TestParseWindow(t *testing.T) {
+	tests := []struct {
+		name    string
+		window  string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"minutes", "15m", 15 * time.Minute, false},
+		{"hours", "24h", 24 * time.Hour, false},
+		{"days", "7d", 7 * 24 * time.Hour, false},
+		{"maximum", "366d", MaxReportWindow, false},
+		{"too long", "367d", 0, true},
+		{"zero", "0h", 0, true},
+		{"negative", "-1d", 0, true},
+		{"invalid", "week", 0, true},
+		{"empty", "", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseWindow(tt.window)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseWindow() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseWindow() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   string
+		want    int
+		wantErr bool
+	}{
+		{"default", "", 10, false},
+		{"explicit", "25", 25, false},
+		{"maximum", "100", MaxReportLimit, false},
+		{"too large", "101", 0, true},
+		{"zero", "0", 0, true},
+		{"not a number", "ten", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLimit(tt.limit, 10)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseLimit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLimit() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateEscrowName(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{"simple", "tournament-42", false},
+		{"dotted", "league.2024_q1", false},
+		{"empty", "", true},
+		{"uppercase", "Tournament", true},
+		{"leading dash", "-pool", true},
+		{"slash", "a/b", true},
+		{"too long", strings.Repeat("a", 65), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateEscrowName(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateEscrowName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateCurrency(t *testing.T) {
+	for _, currency := range []string{"EUR", "USD"} {
+		if err := ValidateCurrency(currency); err != nil {
+			t.Errorf("Expected %q to be valid, got: %v", currency, err)
+		}
+	}
+	for _, currency := range []string{"", "eur", "EURO", "E1R"} {
+		if err := ValidateCurrency(currency); err == nil {
+			t.Errorf("Expected %q to be invalid", currency)
+		}
+	}
+}
+
+func TestValidateUserStatus(t *testing.T) {
+	for _, status := range []string{"active", "suspended", "closed"} {
+		if err := ValidateUserStatus(status); err != nil {
+			t.Errorf("Expected %q to be valid, got: %v", status, err)
+		}
+	}
+	for _, status := range []string{"", "Active", "deleted"} {
+		if err := ValidateUserStatus(status); err == nil {
+			t.Errorf("Expected %q to be invalid", status)
+		}
+	}
+}
+
+func TestValidateTag(t *testing.T) {
+	for _, tag := range []string{"vip", "test_account", "fraud-watch", "2024"} {
+		if err := ValidateTag(tag); err != nil {
+			t.Errorf("Expected %q to be valid, got: %v", tag, err)
+		}
+	}
+	for _, tag := range []string{"", "VIP", "_vip", "test account", strings.Repeat("a", 33)} {
+		if err := ValidateTag(tag); err == nil {
+			t.Errorf("Expected %q to be invalid", tag)
+		}
+	}
+}
+
+func TestValidateWalletID(t *testing.T) {
+	for _, walletID := range []string{"main", "casino", "sports_book", "poker-2"} {
+		if err := ValidateWalletID(walletID); err != nil {
+			t.Errorf("Expected %q to be valid, got: %v", walletID, err)
+		}
+	}
+	for _, walletID := range []string{"", "Casino", "-casino", "sports book", strings.Repeat("a", 33)} {
+		if err := ValidateWalletID(walletID); err == nil {
+			t.Errorf("Expected %q to be invalid", walletID)
+		}
+	}
+}
+
+func TestValidateNotificationChannel(t *testing.T) {
+	for _, channel := range []string{"email", "sms", "push"} {
+		if err := ValidateNotificationChannel(channel); err != nil {
+			t.Errorf("Expected %q to be valid, got: %v", channel, err)
+		}
+	}
+	for _, channel := range []string{"", "Email", "fax"} {
+		if err := ValidateNotificationChannel(channel); err == nil {
+			t.Errorf("Expected %q to be invalid", channel)
+		}
+	}
+}
+
+func TestValidatePhone(t *testing.T) {
+	for _, phone := range []string{"+4915112345678", "+15005550006"} {
+		if err := ValidatePhone(phone); err != nil {
+			t.Errorf("Expected %q to be valid, got: %v", phone, err)
+		}
+	}
+	for _, phone := range []string{"", "015112345678", "+0151123456", "+49 151 12345678", "+1234567"} {
+		if err := ValidatePhone(phone); err == nil {
+			t.Errorf("Expected %q to be invalid", phone)
+		}
+	}
+}
+
+func TestValidatePushDevice(t *testing.T) {
+	for _, platform := range []string{"fcm", "apns"} {
+		if err := ValidatePushPlatform(platform); err != nil {
+			t.Errorf("Expected %q to be valid, got: %v", platform, err)
+		}
+	}
+	if err := ValidatePushPlatform("gcm"); err == nil {
+		t.Error("Expected gcm to be invalid")
+	}
+	for _, token := range []string{"", "a/b", "a b", strings.Repeat("a", 4097)} {
+		if err := ValidateDeviceToken(token); err == nil {
+			t.Errorf("Expected %q to be invalid", token)
+		}
+	}
+	if err := ValidateDeviceToken("dQw4w9WgXcQ:APA91bH-x_y"); err != nil {
+		t.Errorf("Expected FCM token to be valid, got: %v", err)
+	}
+}
@@ -0,0 +1,35 @@
+// Package validation holds the error of requests failing validation, so
+// callers tell them from other failures with errors.Is and errors.As instead
+// of by their message. core re-exports it as core.ValidationError.
+package validation
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalid is matched by every *Error.
+var ErrInvalid = errors.New("invalid request")
+
+// Error is a request failing validation. Field names the offending input as
+// clients send it, e.g. "amount" or "Source-Type"; Message is what they are
+// told, e.g. "invalid amount: cannot be negative".
+type Error struct {
+	Field   string
+	Message string
+}
+
+// Errorf returns an *Error of field with the message formatted like
+// fmt.Sprintf.
+func Errorf(field, format string, args ...interface{}) error {
+	return &Error{Field: field, Message: fmt.Sprintf(format, args...)}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is makes the error match ErrInvalid.
+func (e *Error) Is(target error) bool {
+	return target == ErrInvalid
+}
@@ -0,0 +1,164 @@
+// Package webhook posts JSON payloads to partner endpoints, retrying failed
+// deliveries with exponential backoff and parking those that keep failing in
+// a dead-letter queue for replay.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"assignment/internal/requestid"
+)
+
+// Policy sets how failed deliveries are retried. The zero Policy makes a
+// single attempt.
+type Policy struct {
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry; it doubles with
+	// every retry up to MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// DeadLetterStore keeps deliveries that failed every attempt, e.g. a
+// core.TransactionService.
+type DeadLetterStore interface {
+	RecordWebhookDeadLetter(url string, payload []byte, attempts int, lastError string) error
+}
+
+// Client posts JSON payloads to a single configured URL.
+type Client struct {
+	url         string
+	httpClient  *http.Client
+	policy      Policy
+	deadLetters DeadLetterStore
+	sleep       func(ctx context.Context, d time.Duration) error
+}
+
+func NewClient(url string) *Client {
+	return &Client{
+		url:        url,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		sleep:      sleep,
+	}
+}
+
+// SetRetryPolicy retries failed deliveries according to policy. Call it
+// before the client is used.
+func (c *Client) SetRetryPolicy(policy Policy) {
+	c.policy = policy
+}
+
+// SetDeadLetters records deliveries that failed every attempt in store, for
+// an operator to replay. Call it before the client is used.
+func (c *Client) SetDeadLetters(store DeadLetterStore) {
+	c.deadLetters = store
+}
+
+// Send delivers payload as a JSON POST. Any non-2xx response is an error;
+// network errors, 408, 429 and 5xx responses are retried. A delivery that
+// fails every attempt is returned as an error, unless it is recorded as a
+// dead letter, which then owns it.
+func (c *Client) Send(ctx context.Context, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	maxAttempts := max(c.policy.MaxAttempts, 1)
+	backoff := c.policy.InitialBackoff
+	attempt := 1
+	for ; ; attempt++ {
+		err = post(ctx, c.httpClient, c.url, body)
+		if err == nil {
+			return nil
+		}
+		if attempt == maxAttempts || !retryable(err) {
+			break
+		}
+		log.Printf("Webhook delivery failed (attempt %d of %d), retrying in %s: %v", attempt, maxAttempts, backoff, err)
+		if c.sleep(ctx, backoff) != nil {
+			break
+		}
+		backoff *= 2
+		if c.policy.MaxBackoff > 0 {
+			backoff = min(backoff, c.policy.MaxBackoff)
+		}
+	}
+
+	if c.deadLetters == nil {
+		return err
+	}
+	if dlErr := c.deadLetters.RecordWebhookDeadLetter(c.url, body, attempt, err.Error()); dlErr != nil {
+		log.Printf("Failed to record webhook dead letter: %v", dlErr)
+		return err
+	}
+	log.Printf("Webhook delivery dead-lettered after %d attempts: %v", attempt, err)
+	return nil
+}
+
+// Post delivers an encoded JSON payload to url in a single attempt, e.g. to
+// replay a dead letter.
+func Post(ctx context.Context, url string, body []byte) error {
+	return post(ctx, defaultClient, url, body)
+}
+
+var defaultClient = &http.Client{Timeout: 10 * time.Second}
+
+// statusError is a non-2xx response of the endpoint.
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("webhook endpoint returned status %d", e.code)
+}
+
+// retryable reports whether a failed delivery may succeed when retried:
+// anything but a client error other than a timeout or rate limit.
+func retryable(err error) bool {
+	status, ok := err.(*statusError)
+	if !ok {
+		return true
+	}
+	return status.code >= 500 || status.code == http.StatusRequestTimeout || status.code == http.StatusTooManyRequests
+}
+
+func post(ctx context.Context, httpClient *http.Client, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if id := requestid.FromContext(ctx); id != "" {
+		req.Header.Set(requestid.Header, id)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to deliver webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &statusError{code: resp.StatusCode}
+	}
+	return nil
+}
+
+// sleep waits for d, or until ctx is cancelled.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
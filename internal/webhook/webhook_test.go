@@ -0,0 +1,119 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"assignment/internal/requestid"
+)
+
+type fakeDeadLetters struct {
+	attempts  int
+	lastError string
+	payload   string
+}
+
+func (f *fakeDeadLetters) RecordWebhookDeadLetter(url string, payload []byte, attempts int, lastError string) error {
+	f.payload, f.attempts, f.lastError = string(payload), attempts, lastError
+	return nil
+}
+
+// newTestClient returns a client posting to server that records its
+// backoffs instead of sleeping.
+func newTestClient(server *httptest.Server, policy Policy, waits *[]time.Duration) *Client {
+	c := NewClient(server.URL)
+	c.SetRetryPolicy(policy)
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		*waits = append(*waits, d)
+		return nil
+	}
+	return c
+}
+
+func TestSendRetries(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	var waits []time.Duration
+	c := newTestClient(server, Policy{MaxAttempts: 5, InitialBackoff: time.Second, MaxBackoff: time.Minute}, &waits)
+	if err := c.Send(context.Background(), map[string]string{"text": "hi"}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if calls != 3 || len(waits) != 2 || waits[0] != time.Second || waits[1] != 2*time.Second {
+		t.Errorf("Expected 3 calls with 1s and 2s backoff, got: %d, %v", calls, waits)
+	}
+}
+
+func TestSendDeadLetters(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	var waits []time.Duration
+	c := newTestClient(server, Policy{MaxAttempts: 4, InitialBackoff: time.Second, MaxBackoff: 3 * time.Second}, &waits)
+
+	// Without a dead-letter store the last error is returned
+	if err := c.Send(context.Background(), map[string]string{"text": "hi"}); err == nil {
+		t.Fatal("Expected error")
+	}
+	if calls != 4 || len(waits) != 3 || waits[2] != 3*time.Second {
+		t.Errorf("Expected 4 calls with backoff capped at 3s, got: %d, %v", calls, waits)
+	}
+
+	store := &fakeDeadLetters{}
+	c.SetDeadLetters(store)
+	if err := c.Send(context.Background(), map[string]string{"text": "hi"}); err != nil {
+		t.Fatalf("Expected dead-lettered delivery to succeed, got: %v", err)
+	}
+	if store.attempts != 4 || store.payload != `{"text":"hi"}` || store.lastError != "webhook endpoint returned status 502" {
+		t.Errorf("Unexpected dead letter: %+v", store)
+	}
+}
+
+func TestSendClientErrorNotRetried(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	var waits []time.Duration
+	c := newTestClient(server, Policy{MaxAttempts: 5, InitialBackoff: time.Second}, &waits)
+	store := &fakeDeadLetters{}
+	c.SetDeadLetters(store)
+	if err := c.Send(context.Background(), map[string]string{"text": "hi"}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if calls != 1 || store.attempts != 1 {
+		t.Errorf("Expected a single attempt, got: %d calls, %d attempts", calls, store.attempts)
+	}
+}
+
+func TestSendPassesRequestID(t *testing.T) {
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("X-Request-ID")
+	}))
+	defer server.Close()
+
+	ctx := requestid.WithID(context.Background(), "req-42")
+	if err := NewClient(server.URL).Send(ctx, map[string]string{"text": "hi"}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if got != "req-42" {
+		t.Errorf("Expected X-Request-ID req-42, got %q", got)
+	}
+}
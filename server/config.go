@@ -0,0 +1,152 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"assignment/internal/core"
+	"assignment/internal/models"
+	"assignment/internal/utils"
+)
+
+// Types of the service that Config refers to. They alias the module's
+// internal packages, which embedders in other modules cannot import.
+type (
+	// TransactionProcessor is the transaction service as the HTTP handlers
+	// use it
+	TransactionProcessor = core.TransactionProcessor
+	TransactionRequest   = models.TransactionRequest
+	TransactionResponse  = models.TransactionResponse
+	// Hooks are called by the service as it processes transactions
+	Hooks       = core.Hooks
+	Transaction = models.Transaction
+	// Validator enforces a deployment's own rule on transactions; a
+	// *ValidationError it returns is answered with 400 Bad Request
+	Validator       = core.Validator
+	UserContext     = core.UserContext
+	ValidationError = core.ValidationError
+)
+
+// Config configures a Server.
+type Config struct {
+	// DatabaseURL is the connection string of the Postgres database
+	DatabaseURL string
+	// Addr is the address Start serves the API on. Embedders serving
+	// Handler from a server of their own leave it empty
+	Addr string
+	// Getenv returns the value of a setting, or "" if it is unset. Settings
+	// have the names and formats of the environment variables configuring
+	// the app binary, e.g. MAX_BALANCE or LEDGER_MODE (see README). Nil reads
+	// the environment of the process.
+	Getenv func(key string) string
+	// WrapProcessor, if set, wraps the transaction service the HTTP handlers
+	// use, e.g. in a decorator adding caching, metrics or tracing that
+	// embeds the TransactionProcessor and overrides the methods it wraps
+	WrapProcessor func(TransactionProcessor) TransactionProcessor
+	// Hooks are added to the transaction service, e.g. to observe the
+	// outcome of every transaction or veto transactions before they are
+	// validated; see Hooks for what they cover
+	Hooks Hooks
+	// Validators run, in order, on every new transaction once its request
+	// is valid and its account is locked; see Validator
+	Validators []Validator
+}
+
+// settings reads the settings of a Config. The first invalid value is kept
+// in err; the readers then return their fallback.
+type settings struct {
+	getenv func(key string) string
+	err    error
+}
+
+func newSettings(getenv func(key string) string) *settings {
+	if getenv == nil {
+		getenv = os.Getenv
+	}
+	return &settings{getenv: getenv}
+}
+
+func (s *settings) fail(key string, err error) {
+	if s.err == nil {
+		s.err = fmt.Errorf("invalid %s: %w", key, err)
+	}
+}
+
+func (s *settings) get(key string) string {
+	return s.getenv(key)
+}
+
+func (s *settings) String(key, fallback string) string {
+	if value := s.getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func (s *settings) Float(key string) float64 {
+	value := s.getenv(key)
+	if value == "" {
+		return 0
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		s.fail(key, err)
+	}
+	return f
+}
+
+// Amount parses an amount in the format transactions use, 0 if unset.
+func (s *settings) Amount(key string) float64 {
+	value := s.getenv(key)
+	if value == "" {
+		return 0
+	}
+	if err := utils.ValidateAmount(value); err != nil {
+		s.fail(key, err)
+		return 0
+	}
+	amount, _ := utils.ParseAmount(value)
+	return amount
+}
+
+func (s *settings) Int(key string, fallback int) int {
+	value := s.getenv(key)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		s.fail(key, err)
+		return fallback
+	}
+	return n
+}
+
+func (s *settings) Duration(key string, fallback time.Duration) time.Duration {
+	value := s.getenv(key)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		s.fail(key, err)
+		return fallback
+	}
+	return d
+}
+
+// TimeOfDay parses an HH:MM (UTC) value into an offset from midnight.
+func (s *settings) TimeOfDay(key string) (time.Duration, bool) {
+	value := s.getenv(key)
+	if value == "" {
+		return 0, false
+	}
+	at, err := time.Parse("15:04", value)
+	if err != nil {
+		s.fail(key, err)
+		return 0, false
+	}
+	return time.Duration(at.Hour())*time.Hour + time.Duration(at.Minute())*time.Minute, true
+}
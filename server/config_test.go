@@ -0,0 +1,52 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSettings(t *testing.T) {
+	values := map[string]string{
+		"INTERVAL": "5s",
+		"LIMIT":    "12",
+		"AMOUNT":   "10.50",
+		"AT":       "02:30",
+	}
+	env := newSettings(func(key string) string { return values[key] })
+
+	if got := env.Duration("INTERVAL", time.Second); got != 5*time.Second {
+		t.Errorf("Expected 5s, got: %v", got)
+	}
+	if got := env.Duration("UNSET", time.Second); got != time.Second {
+		t.Errorf("Expected the fallback, got: %v", got)
+	}
+	if got := env.Int("LIMIT", 0); got != 12 {
+		t.Errorf("Expected 12, got: %d", got)
+	}
+	if got := env.Amount("AMOUNT"); got != 10.5 {
+		t.Errorf("Expected 10.5, got: %v", got)
+	}
+	if at, ok := env.TimeOfDay("AT"); !ok || at != 2*time.Hour+30*time.Minute {
+		t.Errorf("Expected 02:30, got: %v, %v", at, ok)
+	}
+	if env.err != nil {
+		t.Fatalf("Expected no error, got: %v", env.err)
+	}
+
+	// The first invalid value is reported
+	values["LIMIT"] = "many"
+	values["INTERVAL"] = "soon"
+	if got := env.Int("LIMIT", 3); got != 3 {
+		t.Errorf("Expected the fallback, got: %d", got)
+	}
+	env.Duration("INTERVAL", time.Second)
+	if env.err == nil || env.err.Error() != `invalid LIMIT: strconv.Atoi: parsing "many": invalid syntax` {
+		t.Errorf("Expected invalid LIMIT, got: %v", env.err)
+	}
+}
+
+func TestNewRequiresDatabase(t *testing.T) {
+	if _, err := New(Config{}); err == nil {
+		t.Error("Expected an error without a database URL")
+	}
+}
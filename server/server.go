@@ -0,0 +1,871 @@
+// Package server runs the wallet service: the HTTP API, the optional gRPC
+// ledger stream and the background workers. Other Go services can embed it
+// to mount the wallet in-process instead of running the app binary, which is
+// a thin wrapper around it.
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"assignment/internal/anomaly"
+	"assignment/internal/audit"
+	"assignment/internal/cdc"
+	"assignment/internal/clock"
+	"assignment/internal/consistency"
+	"assignment/internal/core"
+	"assignment/internal/db"
+	"assignment/internal/export"
+	"assignment/internal/fixture"
+	"assignment/internal/fx"
+	handlers "assignment/internal/http"
+	"assignment/internal/ledgerrpc"
+	"assignment/internal/lock"
+	"assignment/internal/metrics"
+	"assignment/internal/notify"
+	"assignment/internal/objectstore"
+	"assignment/internal/payment"
+	"assignment/internal/readmodel"
+	"assignment/internal/reporting"
+	"assignment/internal/saga"
+	"assignment/internal/schedule"
+	"assignment/internal/sentry"
+	"assignment/internal/settlement"
+	"assignment/internal/utils"
+	"assignment/internal/webhook"
+)
+
+// Server is a configured wallet service. New connects to the database and
+// wires the service; Start serves it and starts its background workers, and
+// Shutdown stops them.
+type Server struct {
+	database *db.DB
+	// router routes requests to the handlers, scoping API tokens to their
+	// user; handler wraps it in the middleware chain
+	router   http.Handler
+	handler  http.Handler
+	http     *http.Server
+	grpc     *http.Server
+	grpcCert string
+	grpcKey  string
+
+	// tasks are the background workers, started by Start
+	tasks  []func(ctx context.Context)
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New connects to the database, migrates it and wires the service as cfg
+// configures it. Nothing is served until Start.
+func New(cfg Config) (_ *Server, err error) {
+	env := newSettings(cfg.Getenv)
+	if cfg.DatabaseURL == "" {
+		return nil, errors.New("database URL is required")
+	}
+	connStr := cfg.DatabaseURL
+
+	// Keep a tenant's data in a schema of its own
+	schema := env.get("DB_SCHEMA")
+	if schema != "" {
+		if err := db.CreateSchema(connStr, schema); err != nil {
+			return nil, fmt.Errorf("failed to create schema: %w", err)
+		}
+	}
+
+	// Bound how long statements may run and wait for row locks, so a stuck
+	// lock on a hot user fails the request instead of stalling it
+	connStr, err = db.SessionConfig{
+		StatementTimeout: env.Duration("DB_STATEMENT_TIMEOUT", 30*time.Second),
+		LockTimeout:      env.Duration("DB_LOCK_TIMEOUT", 5*time.Second),
+		Schema:           schema,
+	}.DSN(connStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid database settings: %w", err)
+	}
+	if env.err != nil {
+		return nil, env.err
+	}
+
+	clk := clock.New()
+
+	// Seed users from a fixture file, e.g. a demo or load-test dataset,
+	// instead of the default users
+	var seed *fixture.Fixture
+	if path := env.get("SEED_FIXTURE"); path != "" {
+		loaded, err := fixture.Load(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SEED_FIXTURE: %w", err)
+		}
+		seed = loaded
+	}
+
+	// Initialize database
+	database, err := db.NewDB(connStr, clk, seed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			database.Close()
+		}
+	}()
+	database.SetCircuitBreaker(db.CircuitBreakerConfig{
+		Failures: env.Int("DB_BREAKER_FAILURES", 5),
+		Cooldown: env.Duration("DB_BREAKER_COOLDOWN", 10*time.Second),
+	})
+
+	s := &Server{database: database}
+	goRun := func(fn func(ctx context.Context)) {
+		s.tasks = append(s.tasks, fn)
+	}
+
+	// Initialize services
+	transactionService := core.NewTransactionService(database.DB, clk,
+		core.WithHooks(cfg.Hooks),
+		core.WithValidators(cfg.Validators...),
+	)
+	if seed != nil {
+		applied, err := transactionService.ApplyFixture(seed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to seed transactions: %w", err)
+		}
+		log.Printf("Applied %d fixture transactions", applied)
+	}
+
+	// Ledger mode: "state" (default) or "event_sourced"
+	switch mode := env.get("LEDGER_MODE"); mode {
+	case "", "state":
+	case "event_sourced":
+		transactionService.EnableEventSourcing()
+		drifted, err := transactionService.CheckProjections()
+		if err != nil {
+			return nil, fmt.Errorf("failed to check balance projections: %w", err)
+		}
+		if len(drifted) > 0 {
+			log.Printf("WARNING: balances of users %v disagree with their event streams; POST /admin/projections/rebuild to repair", drifted)
+		}
+	default:
+		return nil, fmt.Errorf("invalid LEDGER_MODE %q: must be state or event_sourced", mode)
+	}
+
+	// Optional global maximum balance; users may have their own
+	if maxBalance := env.get("MAX_BALANCE"); maxBalance != "" || env.get("MAX_BALANCE_MODE") != "" {
+		var limit float64
+		if maxBalance != "" {
+			if err := utils.ValidateAmount(maxBalance); err != nil {
+				return nil, fmt.Errorf("invalid MAX_BALANCE: %w", err)
+			}
+			limit, _ = utils.ParseAmount(maxBalance)
+		}
+		if err := transactionService.SetBalanceCap(limit, env.String("MAX_BALANCE_MODE", core.CapReject)); err != nil {
+			return nil, fmt.Errorf("invalid MAX_BALANCE_MODE: %w", err)
+		}
+	}
+
+	// Optional maximum amount of a single transaction, transfer, deposit or
+	// withdrawal
+	if maxAmount := env.get("MAX_TRANSACTION_AMOUNT"); maxAmount != "" {
+		if err := utils.ValidateAmount(maxAmount); err != nil {
+			return nil, fmt.Errorf("invalid MAX_TRANSACTION_AMOUNT: %w", err)
+		}
+		limit, _ := utils.ParseAmount(maxAmount)
+		transactionService.SetMaxTransactionAmount(limit)
+	}
+
+	// Optional AML rules flagging transactions for review
+	amlRules := core.AMLRules{
+		SingleTransaction: env.Amount("AML_SINGLE_TRANSACTION"),
+		DailyVolume:       env.Amount("AML_DAILY_VOLUME"),
+		CycleCount:        env.Int("AML_CYCLE_COUNT", 0),
+		CycleWindow:       env.Duration("AML_CYCLE_WINDOW", time.Hour),
+	}
+	transactionService.SetAMLRules(amlRules)
+
+	// Format of transaction IDs chosen by clients
+	if err := transactionService.SetTransactionIDPolicy(env.String("TRANSACTION_ID_FORMAT", core.TxIDAny), env.get("TRANSACTION_ID_PATTERN"), env.Int("TRANSACTION_ID_MAX_LENGTH", core.DefaultMaxTransactionIDLength)); err != nil {
+		return nil, fmt.Errorf("invalid transaction ID policy: %w", err)
+	}
+
+	// Key of the transaction hash chain, kept outside the database so that
+	// database access alone cannot rewrite the chain
+	if key := env.get("HASH_CHAIN_KEY"); key != "" {
+		raw, err := base64.StdEncoding.DecodeString(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid HASH_CHAIN_KEY: %w", err)
+		}
+		if err := transactionService.SetHashChainKey(raw); err != nil {
+			return nil, fmt.Errorf("invalid HASH_CHAIN_KEY: %w", err)
+		}
+	}
+
+	// Optional capabilities of each source type, e.g. game servers that may
+	// only debit, and the secrets of the sources signing their requests
+	signingSecrets := map[string]string{}
+	for _, sourceType := range []string{"game", "server", "payment"} {
+		prefix := "SOURCE_" + strings.ToUpper(sourceType) + "_"
+		signingSecrets[sourceType] = env.get(prefix + "SIGNING_SECRET")
+		list, maxAmount := env.get(prefix+"CAPABILITIES"), env.Amount(prefix+"MAX_AMOUNT")
+		if list == "" && maxAmount == 0 {
+			continue
+		}
+		if list == "" {
+			list = "credit,debit"
+		}
+		caps, err := core.ParseSourceCapabilities(list)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %sCAPABILITIES: %w", prefix, err)
+		}
+		caps.MaxAmount = maxAmount
+		if err := transactionService.SetSourceCapabilities(sourceType, caps); err != nil {
+			return nil, err
+		}
+	}
+
+	// Coordinate daily jobs between replicas with Redis locks, so each runs
+	// on one of them
+	var locker lock.Locker
+	if url := env.get("REDIS_URL"); url != "" {
+		redisLocker, err := lock.NewRedis(url, env.Duration("JOB_LOCK_TTL", 30*time.Second))
+		if err != nil {
+			return nil, fmt.Errorf("invalid REDIS_URL: %w", err)
+		}
+		locker = redisLocker
+	}
+
+	// Run the background workers meant for a single instance on one elected
+	// replica, with failover to another when it dies
+	var elector *lock.Elector
+	electionInterval := env.Duration("LEADER_ELECTION_INTERVAL", 5*time.Second)
+	switch election := env.get("LEADER_ELECTION"); election {
+	case "":
+	case "postgres":
+		elector = lock.NewElector(lock.NewPostgres(database.DB, electionInterval), "leader", electionInterval)
+	case "redis":
+		if locker == nil {
+			return nil, errors.New("REDIS_URL is required when LEADER_ELECTION is redis")
+		}
+		elector = lock.NewElector(locker, "leader", electionInterval)
+	default:
+		return nil, fmt.Errorf("invalid LEADER_ELECTION %q: must be postgres or redis", election)
+	}
+	singleton := func(fn func(ctx context.Context)) {
+		if elector != nil {
+			elector.Go(fn)
+			return
+		}
+		goRun(fn)
+	}
+
+	// Serve balance reads from the CQRS read model, projected every interval
+	if interval := env.Duration("READ_MODEL_INTERVAL", 0); interval > 0 {
+		transactionService.UseReadModel()
+		n, err := transactionService.CatchUpReadModel()
+		if err != nil {
+			return nil, fmt.Errorf("failed to catch up read model: %w", err)
+		}
+		log.Printf("Read model caught up with %d events", n)
+		singleton(readmodel.NewProjector(transactionService, interval).Run)
+	}
+
+	// Retry failed webhook deliveries with exponential backoff; deliveries
+	// failing every attempt are dead-lettered for replay
+	webhookPolicy := webhook.Policy{
+		MaxAttempts:    env.Int("WEBHOOK_MAX_ATTEMPTS", 5),
+		InitialBackoff: env.Duration("WEBHOOK_INITIAL_BACKOFF", time.Second),
+		MaxBackoff:     env.Duration("WEBHOOK_MAX_BACKOFF", time.Minute),
+	}
+	newWebhook := func(url string) *webhook.Client {
+		client := webhook.NewClient(url)
+		client.SetRetryPolicy(webhookPolicy)
+		client.SetDeadLetters(transactionService)
+		return client
+	}
+
+	// Deliver alerts users opted into to the notification gateway, by SMS or
+	// push, and large transaction alerts to the ops mailing list
+	var gateway notify.Sender
+	if url := env.get("NOTIFY_WEBHOOK_URL"); url != "" {
+		gateway = newWebhook(url)
+	}
+	notifier := notify.NewNotifier(transactionService, gateway, env.Duration("NOTIFY_INTERVAL", time.Second))
+	if sid := env.get("TWILIO_ACCOUNT_SID"); sid != "" {
+		twilio := notify.NewTwilio(sid, env.get("TWILIO_AUTH_TOKEN"), env.get("TWILIO_FROM"))
+		notifier.Route("sms", notify.NewSMSSender(twilio))
+	}
+	push := notify.NewPushSender(transactionService)
+	pushEnabled := false
+	if file := env.get("FCM_SERVICE_ACCOUNT_FILE"); file != "" {
+		serviceAccount, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read FCM_SERVICE_ACCOUNT_FILE: %w", err)
+		}
+		fcm, err := notify.NewFCM(serviceAccount)
+		if err != nil {
+			return nil, fmt.Errorf("invalid FCM_SERVICE_ACCOUNT_FILE: %w", err)
+		}
+		push.Platform("fcm", fcm)
+		pushEnabled = true
+	}
+	if file := env.get("APNS_KEY_FILE"); file != "" {
+		key, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read APNS_KEY_FILE: %w", err)
+		}
+		apns, err := notify.NewAPNs(key, env.get("APNS_KEY_ID"), env.get("APNS_TEAM_ID"), env.get("APNS_BUNDLE_ID"), env.get("APNS_PRODUCTION") == "true")
+		if err != nil {
+			return nil, fmt.Errorf("invalid APNS_KEY_FILE: %w", err)
+		}
+		push.Platform("apns", apns)
+		pushEnabled = true
+	}
+	if pushEnabled {
+		notifier.Route(core.PushChannel, push)
+	}
+	userAlerts := gateway != nil || env.get("TWILIO_ACCOUNT_SID") != "" || pushEnabled
+	if userAlerts {
+		transactionService.EnableNotifications()
+	}
+	if threshold := env.get("LARGE_TRANSACTION_EMAIL_THRESHOLD"); threshold != "" {
+		if err := utils.ValidateAmount(threshold); err != nil {
+			return nil, fmt.Errorf("invalid LARGE_TRANSACTION_EMAIL_THRESHOLD: %w", err)
+		}
+		limit, _ := utils.ParseAmount(threshold)
+		tmpl, err := notify.LoadEmailTemplate(env.get("EMAIL_TEMPLATE_FILE"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid EMAIL_TEMPLATE_FILE: %w", err)
+		}
+		transactionService.SetLargeTransactionAlert(limit)
+		notifier.Route(core.OpsEmailChannel, notify.NewEmailSender(notify.SMTPConfig{
+			Addr:     env.get("SMTP_ADDR"),
+			Username: env.get("SMTP_USERNAME"),
+			Password: env.get("SMTP_PASSWORD"),
+			From:     env.get("SMTP_FROM"),
+			To:       strings.Split(env.get("LARGE_TRANSACTION_EMAIL_TO"), ","),
+		}, tmpl))
+	}
+	if userAlerts || env.get("LARGE_TRANSACTION_EMAIL_THRESHOLD") != "" {
+		singleton(notifier.Run)
+	}
+
+	// Alert ops in Slack when transaction outcomes look anomalous
+	if url := env.get("ANOMALY_SLACK_WEBHOOK_URL"); url != "" {
+		detector := anomaly.NewDetector(anomaly.Config{
+			Window:                env.Duration("ANOMALY_WINDOW", 5*time.Minute),
+			MinSamples:            env.Int("ANOMALY_MIN_SAMPLES", 20),
+			ErrorRate:             env.Float("ANOMALY_ERROR_RATE"),
+			InsufficientFundsRate: env.Float("ANOMALY_INSUFFICIENT_FUNDS_RATE"),
+			DuplicateRate:         env.Float("ANOMALY_DUPLICATE_RATE"),
+		}, clk, newWebhook(url))
+		transactionService.ObserveOutcomes(detector.Observe)
+		checkInterval := env.Duration("ANOMALY_CHECK_INTERVAL", 30*time.Second)
+		goRun(func(ctx context.Context) { detector.Run(ctx, checkInterval) })
+	}
+
+	// Schedule the daily settlement summary (HH:MM, UTC)
+	if at, ok := env.TimeOfDay("SETTLEMENT_TIME"); ok {
+		var hook *webhook.Client
+		if url := env.get("SETTLEMENT_WEBHOOK_URL"); url != "" {
+			hook = newWebhook(url)
+		}
+		singleton(settlement.NewJob(transactionService, clk, at, hook, locker).Run)
+	}
+
+	// Schedule the daily Parquet export to object storage (HH:MM, UTC)
+	if at, ok := env.TimeOfDay("EXPORT_TIME"); ok {
+		store, err := objectstore.NewS3(objectstore.Config{
+			Endpoint:        env.get("EXPORT_S3_ENDPOINT"),
+			Region:          env.get("EXPORT_S3_REGION"),
+			Bucket:          env.get("EXPORT_S3_BUCKET"),
+			AccessKeyID:     env.get("EXPORT_S3_ACCESS_KEY_ID"),
+			SecretAccessKey: env.get("EXPORT_S3_SECRET_ACCESS_KEY"),
+		}, clk)
+		if err != nil {
+			return nil, fmt.Errorf("invalid export configuration: %w", err)
+		}
+		singleton(export.NewExporter(transactionService, store, clk, env.get("EXPORT_PREFIX"), at, locker).Run)
+	}
+
+	// Checkpoint replayed balances daily (HH:MM, UTC) to bound ledger replay time
+	if at, ok := env.TimeOfDay("LEDGER_CHECKPOINT_TIME"); ok {
+		singleton(func(ctx context.Context) {
+			schedule.Daily(ctx, clk, locker, "ledger checkpoint", at, func(ctx context.Context, day time.Time) {
+				n, err := transactionService.CreateLedgerCheckpoints()
+				if err != nil {
+					log.Printf("Ledger checkpoint failed: %v", err)
+					return
+				}
+				log.Printf("Created %d ledger checkpoints", n)
+			})
+		})
+	}
+
+	// Purge or anonymize transactions past the retention period daily (HH:MM, UTC)
+	if at, ok := env.TimeOfDay("RETENTION_TIME"); ok {
+		years := env.Int("RETENTION_YEARS", 0)
+		if years <= 0 {
+			return nil, errors.New("RETENTION_YEARS must be a positive number of years when RETENTION_TIME is set")
+		}
+		mode := env.String("RETENTION_MODE", core.RetentionPurge)
+		if !core.ValidRetentionMode(mode) {
+			return nil, fmt.Errorf("invalid RETENTION_MODE %q: must be %q or %q", mode, core.RetentionPurge, core.RetentionAnonymize)
+		}
+		dryRun := env.get("RETENTION_DRY_RUN") == "true"
+		singleton(func(ctx context.Context) {
+			schedule.Daily(ctx, clk, locker, "retention", at, func(ctx context.Context, day time.Time) {
+				report, err := transactionService.ApplyRetention(core.RetentionCutoff(clk.Now(), years), mode, dryRun)
+				if err != nil {
+					log.Printf("Retention failed: %v", err)
+					return
+				}
+				verb := "Applied"
+				if dryRun {
+					verb = "Dry run of"
+				}
+				log.Printf("%s retention (%s) before %s: %d transactions of %d users, %d days settled first",
+					verb, report.Mode, report.Cutoff.Format(time.RFC3339), report.Transactions, report.Users, report.SettlementDays)
+			})
+		})
+	}
+
+	// Verify stored balances against the ledger, on demand and optionally
+	// periodically, alerting ops about drift
+	var driftSender consistency.Sender
+	if url := env.get("LEDGER_VERIFY_SLACK_WEBHOOK_URL"); url != "" {
+		driftSender = newWebhook(url)
+	}
+	checker := consistency.NewChecker(transactionService, driftSender, env.get("LEDGER_VERIFY_QUARANTINE") == "true")
+	if interval := env.Duration("LEDGER_VERIFY_INTERVAL", 0); interval > 0 {
+		singleton(func(ctx context.Context) { checker.Run(ctx, interval) })
+	}
+
+	// Stream committed user and transaction changes to a webhook
+	if url := env.get("CDC_WEBHOOK_URL"); url != "" {
+		// Changes must arrive in order, so failed batches are retried from the
+		// replication slot rather than dead-lettered
+		publisher := webhook.NewClient(url)
+		publisher.SetRetryPolicy(webhookPolicy)
+		streamer, err := cdc.NewStreamer(database.DB, cdc.Config{
+			Slot:        env.String("CDC_SLOT", "wallet_cdc"),
+			Publication: env.String("CDC_PUBLICATION", "wallet_cdc"),
+		}, publisher)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up change data capture: %w", err)
+		}
+		cdcInterval := env.Duration("CDC_INTERVAL", time.Second)
+		singleton(func(ctx context.Context) { streamer.Run(ctx, cdcInterval) })
+	}
+
+	// Serve the ledger stream over gRPC; the standard library only speaks
+	// HTTP/2 over TLS
+	if grpcAddr := env.get("GRPC_ADDR"); grpcAddr != "" {
+		s.grpc = &http.Server{
+			Addr:    grpcAddr,
+			Handler: ledgerrpc.NewServer(transactionService, env.Duration("GRPC_POLL_INTERVAL", time.Second)),
+		}
+		s.grpcCert, s.grpcKey = env.get("GRPC_TLS_CERT"), env.get("GRPC_TLS_KEY")
+	}
+
+	// Initialize handlers
+	var processor core.TransactionProcessor = transactionService
+	if cfg.WrapProcessor != nil {
+		processor = cfg.WrapProcessor(processor)
+	}
+	h := handlers.NewHandlers(processor)
+	ch := handlers.NewConsistencyHandlers(checker)
+
+	// Withdrawals run as sagas against the configured payment provider
+	var wh *handlers.WithdrawalHandlers
+	if url := env.get("PAYMENT_PROVIDER_URL"); url != "" {
+		orchestrator := saga.NewOrchestrator(database.DB, clk)
+		withdrawals := payment.NewWithdrawals(transactionService, orchestrator, payment.NewHTTPProvider(url))
+		wh = handlers.NewWithdrawalHandlers(withdrawals)
+		goRun(func(ctx context.Context) {
+			if err := orchestrator.Resume(ctx); err != nil {
+				log.Printf("Failed to resume sagas: %v", err)
+			}
+		})
+	}
+
+	// Deposits are collected through Stripe and credited on its callbacks
+	var dh *handlers.DepositHandlers
+	if key := env.get("STRIPE_SECRET_KEY"); key != "" {
+		stripe := payment.NewStripe(key, env.get("STRIPE_WEBHOOK_SECRET"))
+		dh = handlers.NewDepositHandlers(payment.NewDeposits(transactionService, stripe))
+	}
+
+	// Signed audit bundles for regulators
+	var rh *handlers.ReportingHandlers
+	if key := env.get("REPORT_SIGNING_KEY"); key != "" {
+		signer, err := reporting.NewSigner(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid REPORT_SIGNING_KEY: %w", err)
+		}
+		log.Printf("Audit bundles are signed with Ed25519 public key %s", signer.PublicKey())
+		rh = handlers.NewReportingHandlers(transactionService, signer)
+	}
+
+	// Player tax statements in the format of the market served
+	jurisdiction, ok := reporting.Jurisdictions[env.String("TAX_JURISDICTION", "default")]
+	if !ok {
+		return nil, fmt.Errorf("invalid TAX_JURISDICTION: %s", env.get("TAX_JURISDICTION"))
+	}
+	th := handlers.NewTaxHandlers(transactionService, jurisdiction)
+
+	// Exchange rates between user currencies, from the ECB unless another
+	// API is configured
+	var fxProvider fx.Provider = fx.NewECB()
+	if url := env.get("FX_PROVIDER_URL"); url != "" {
+		fxProvider = fx.NewHTTPProvider(url)
+	}
+	fallback := fx.DefaultFallback
+	if rates := env.get("FX_FALLBACK_RATES"); rates != "" {
+		if fallback, err = fx.ParseFallback(rates); err != nil {
+			return nil, fmt.Errorf("invalid FX_FALLBACK_RATES: %w", err)
+		}
+	}
+	fxh := handlers.NewFXHandlers(fx.NewRates(fxProvider, clk, fx.Config{
+		TTL:      env.Duration("FX_TTL", time.Hour),
+		MaxAge:   env.Duration("FX_MAX_AGE", 72*time.Hour),
+		Fallback: fallback,
+	}))
+
+	// Metrics, scraped from /metrics or pushed to a StatsD/DogStatsD agent
+	var m metrics.Metrics
+	var prometheus *metrics.Prometheus
+	switch backend := env.get("METRICS_BACKEND"); backend {
+	case "":
+	case "prometheus":
+		prometheus = metrics.NewPrometheus()
+		m = prometheus
+	case "statsd":
+		if m, err = metrics.NewStatsD(env.String("STATSD_ADDR", "127.0.0.1:8125"), env.get("STATSD_PREFIX")); err != nil {
+			return nil, fmt.Errorf("invalid STATSD_ADDR: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("invalid METRICS_BACKEND %q: must be prometheus or statsd", backend)
+	}
+	if m != nil {
+		transactionService.ObserveOutcomes(func(outcome string) {
+			m.Inc("transactions_total", metrics.Labels{"outcome": outcome})
+		})
+		database.SetMetrics(m)
+	}
+
+	// The OpenAPI document and docs UI are built once and served with cache headers
+	static, err := handlers.NewStaticHandler()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build API docs: %w", err)
+	}
+
+	// Setup routes; OPTIONS and wrong methods are answered by the router
+	mux := handlers.NewRouter()
+
+	// Users
+	mux.HandleFunc("POST", "/user", h.HandleCreateUser)
+	mux.HandleFunc("GET", "/user/{userId}", h.HandleGetUser)
+	mux.HandleFunc("PATCH", "/user/{userId}", h.HandleUpdateUser)
+	mux.HandleFunc("DELETE", "/user/{userId}", h.HandleDeleteUser)
+
+	// Transactions and balances
+	mux.HandleFunc("POST", "/user/{userId}/transaction", h.HandleTransaction)
+	mux.HandleFunc("GET", "/user/{userId}/balance", h.HandleGetBalance)
+	mux.HandleFunc("GET", "/user/{userId}/balance/history", h.HandleGetBalanceHistory)
+	mux.HandleFunc("POST", "/balances", h.HandleGetBalances)
+	mux.HandleFunc("POST", "/transfer", h.HandleTransfer)
+	mux.HandleFunc("GET", "/user/{userId}/statement", h.HandleGetStatement)
+	mux.HandleFunc("GET", "/user/{userId}/statement/summary", h.HandleGetStatementSummary)
+	mux.HandleFunc("GET", "/user/{userId}/tax-summary", th.HandleGetTaxSummary)
+
+	// Wallets
+	mux.HandleFunc("POST", "/user/{userId}/wallet", h.HandleCreateWallet)
+	mux.HandleFunc("GET", "/user/{userId}/wallet", h.HandleGetWallets)
+	mux.HandleFunc("POST", "/user/{userId}/wallet/move", h.HandleWalletMove)
+	mux.HandleFunc("POST", "/user/{userId}/wallet/{walletId}/transaction", h.HandleWalletTransaction)
+	mux.HandleFunc("GET", "/user/{userId}/wallet/{walletId}/balance", h.HandleGetWalletBalance)
+
+	// Notifications
+	mux.HandleFunc("GET", "/user/{userId}/notifications", h.HandleGetNotificationPreferences)
+	mux.HandleFunc("PUT", "/user/{userId}/notifications", h.HandleSetNotificationPreferences)
+	mux.HandleFunc("POST", "/user/{userId}/devices", h.HandleRegisterPushDevice)
+	mux.HandleFunc("GET", "/user/{userId}/devices", h.HandleGetPushDevices)
+	mux.HandleFunc("DELETE", "/user/{userId}/devices/{token}", h.HandleUnregisterPushDevice)
+
+	// Escrow
+	mux.HandleFunc("POST", "/escrow", h.HandleCreateEscrow)
+	mux.HandleFunc("GET", "/escrow/{name}", h.HandleGetEscrow)
+	mux.HandleFunc("POST", "/escrow/{name}/hold", h.HandleEscrowHold)
+	mux.HandleFunc("POST", "/escrow/{name}/release", h.HandleEscrowRelease)
+
+	// Payments
+	if dh != nil {
+		mux.HandleFunc("POST", "/user/{userId}/deposit/intent", dh.HandleDepositIntent)
+		mux.HandleFunc("POST", "/payments/callback", dh.HandlePaymentCallback)
+	}
+	if wh != nil {
+		mux.HandleFunc("POST", "/user/{userId}/withdrawal", wh.HandleWithdrawal)
+	}
+
+	// FX
+	mux.HandleFunc("GET", "/fx/rates", fxh.HandleGetRates)
+	mux.HandleFunc("GET", "/fx/convert", fxh.HandleConvert)
+
+	// Admin
+	mux.HandleFunc("GET", "/admin/reconciliation", h.HandleReconciliation)
+	mux.HandleFunc("GET", "/admin/transactions", h.HandleGetTransactionFeed)
+	mux.HandleFunc("GET", "/admin/stats", h.HandleStats)
+	mux.HandleFunc("GET", "/admin/reports/top", h.HandleTopReport)
+	mux.HandleFunc("POST", "/admin/projections/rebuild", h.HandleRebuildProjections)
+	mux.HandleFunc("POST", "/admin/ledger/verify", ch.HandleVerifyLedger)
+	mux.HandleFunc("POST", "/admin/import", h.HandleImport)
+	mux.HandleFunc("GET", "/admin/webhooks/dead-letters", h.HandleGetWebhookDeadLetters)
+	mux.HandleFunc("POST", "/admin/webhooks/dead-letters/{id}/replay", h.HandleReplayWebhookDeadLetter)
+	mux.HandleFunc("GET", "/admin/aml/flags", h.HandleGetAMLFlags)
+	mux.HandleFunc("POST", "/admin/aml/flags/{id}/resolve", h.HandleResolveAMLFlag)
+	mux.HandleFunc("POST", "/admin/disputes", h.HandleOpenDispute)
+	mux.HandleFunc("GET", "/admin/disputes", h.HandleGetDisputes)
+	mux.HandleFunc("GET", "/admin/disputes/{id}", h.HandleGetDispute)
+	mux.HandleFunc("POST", "/admin/disputes/{id}/resolve", h.HandleResolveDispute)
+	mux.HandleFunc("POST", "/admin/deposits/{depositId}/chargeback", h.HandleChargeBackDeposit)
+	mux.HandleFunc("POST", "/admin/users/{userId}/status", h.HandleSetUserStatus)
+	mux.HandleFunc("POST", "/admin/users/{userId}/kyc", h.HandleSetKYCLevel)
+	mux.HandleFunc("POST", "/admin/users/{userId}/max-balance", h.HandleSetMaxBalance)
+	mux.HandleFunc("POST", "/admin/users/{userId}/freeze", h.HandleFreezeUser)
+	mux.HandleFunc("POST", "/admin/users/{userId}/unfreeze", h.HandleUnfreezeUser)
+	mux.HandleFunc("GET", "/admin/users/{userId}/hash-chain", h.HandleVerifyHashChain)
+	if rh != nil {
+		mux.HandleFunc("GET", "/admin/users/{userId}/audit-trail", rh.HandleGetAuditTrail)
+	}
+	mux.HandleFunc("DELETE", "/admin/users/{userId}/personal-data", h.HandleErasePersonalData)
+	mux.HandleFunc("GET", "/admin/users/{userId}/data-export", h.HandleExportPersonalData)
+	mux.HandleFunc("GET", "/admin/users/{userId}/tags", h.HandleGetUserTags)
+	mux.HandleFunc("POST", "/admin/users/{userId}/tags", h.HandleAddUserTags)
+	mux.HandleFunc("DELETE", "/admin/users/{userId}/tags/{tag}", h.HandleRemoveUserTag)
+	mux.HandleFunc("POST", "/admin/users/{userId}/tokens", h.HandleIssueAPIToken)
+	mux.HandleFunc("DELETE", "/admin/users/{userId}/tokens/{tokenId}", h.HandleRevokeAPIToken)
+
+	// Operations and docs
+	if prometheus != nil {
+		mux.Handle("GET", "/metrics", prometheus)
+	}
+	mux.HandleFunc("GET", "/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+	})
+	for _, path := range static.Paths() {
+		mux.Handle("GET", path, static)
+		mux.Handle("HEAD", path, static)
+	}
+
+	// Requests with a user-scoped API token may only read that user's data,
+	// and with API_TOKEN_REQUIRED reading it requires one; signed requests
+	// are verified first
+	tokenRequired := env.get("API_TOKEN_REQUIRED") == "true"
+	var handler http.Handler = handlers.NewTokenMiddleware(transactionService, tokenRequired, handlers.NewSignatureMiddleware(signingSecrets, mux))
+	s.router = handler
+
+	// Export auth failures, denied access and admin changes to the SIEM
+	var auditSink audit.Sink
+	switch sink := env.get("AUDIT_SINK"); sink {
+	case "":
+	case "file":
+		if auditSink, err = audit.NewFileSink(env.String("AUDIT_FILE", "audit.log")); err != nil {
+			return nil, fmt.Errorf("invalid AUDIT_FILE: %w", err)
+		}
+	case "syslog":
+		if auditSink, err = audit.NewSyslogSink(env.String("AUDIT_SYSLOG_NETWORK", "udp"), env.get("AUDIT_SYSLOG_ADDR")); err != nil {
+			return nil, fmt.Errorf("invalid AUDIT_SYSLOG_ADDR: %w", err)
+		}
+	case "http":
+		url := env.get("AUDIT_HTTP_URL")
+		if url == "" {
+			return nil, errors.New("AUDIT_HTTP_URL is required when AUDIT_SINK is http")
+		}
+		auditSink = audit.NewHTTPSink(newWebhook(url))
+	default:
+		return nil, fmt.Errorf("invalid AUDIT_SINK %q: must be file, syslog or http", sink)
+	}
+	if auditSink != nil {
+		auditor := audit.NewLogger(auditSink, clk)
+		goRun(auditor.Run)
+		handler = handlers.NewAuditMiddleware(auditor, handler)
+	}
+
+	// Report panics and 5xx responses to Sentry
+	if dsn := env.get("SENTRY_DSN"); dsn != "" {
+		reporter, err := sentry.New(dsn, env.String("SENTRY_RELEASE", sentry.DefaultRelease()), env.String("SENTRY_ENVIRONMENT", "production"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid SENTRY_DSN: %w", err)
+		}
+		goRun(reporter.Run)
+		handler = handlers.NewErrorReportingMiddleware(reporter, handler)
+	}
+
+	// Bound the requests each endpoint serves at once, shedding the excess
+	concurrencyConfig := handlers.ConcurrencyConfig{
+		Limit:        env.Int("CONCURRENCY_LIMIT", 64),
+		Queue:        env.Int("CONCURRENCY_QUEUE", 128),
+		QueueTimeout: env.Duration("CONCURRENCY_QUEUE_TIMEOUT", 2*time.Second),
+	}
+	if concurrencyConfig.Enabled() {
+		handler = handlers.NewConcurrencyLimitMiddleware(concurrencyConfig, handler)
+	}
+
+	// Partition capacity by Source-Type, so a flood from one source cannot
+	// take the concurrency limiter's slots from the others
+	bulkheadConfig := handlers.BulkheadConfig{Sizes: map[string]int{
+		"game":    env.Int("BULKHEAD_GAME", 48),
+		"server":  env.Int("BULKHEAD_SERVER", 16),
+		"payment": env.Int("BULKHEAD_PAYMENT", 16),
+	}}
+	if bulkheadConfig.Enabled() {
+		handler = handlers.NewBulkheadMiddleware(bulkheadConfig, m, handler)
+	}
+
+	// Stop working on requests once the client's deadline has passed, also
+	// while they wait for the concurrency limiter
+	handler = handlers.NewDeadlineMiddleware(handler)
+
+	// Trim responses to the fields the client asks for
+	handler = handlers.NewSparseFieldsMiddleware(handler)
+
+	// Translate response messages into the language the client accepts;
+	// the middlewares above record them in English
+	handler = handlers.NewLocalizationMiddleware(handler)
+
+	// Answer errors as RFC 7807 Problem Details when the client asks for them
+	handler = handlers.NewProblemDetailsMiddleware(handler)
+
+	// Count and time requests by route and status
+	if m != nil {
+		handler = handlers.NewMetricsMiddleware(m, handler)
+	}
+
+	// Optional fault injection for resilience testing
+	chaosConfig := handlers.ChaosConfig{
+		LatencyRate: env.Float("CHAOS_LATENCY_RATE"),
+		Latency:     env.Duration("CHAOS_LATENCY", time.Second),
+		DropRate:    env.Float("CHAOS_DROP_RATE"),
+		ErrorRate:   env.Float("CHAOS_ERROR_RATE"),
+	}
+	if chaosConfig.Enabled() {
+		log.Printf("WARNING: chaos middleware enabled: %+v", chaosConfig)
+		handler = handlers.NewChaosMiddleware(chaosConfig, handler)
+	}
+
+	// Tag every request with an ID, echoed in responses and logs and passed
+	// on to webhooks and payouts
+	handler = handlers.NewRequestIDMiddleware(handler)
+
+	if elector != nil {
+		goRun(elector.Run)
+	}
+
+	if env.err != nil {
+		return nil, env.err
+	}
+	s.handler = handler
+	if cfg.Addr != "" {
+		s.http = &http.Server{Addr: cfg.Addr, Handler: handler}
+	}
+	return s, nil
+}
+
+// Handler returns the API: every route, behind the middlewares configured
+// (request IDs, localization, concurrency limits, audit, ...). Mount it in
+// another server's mux to serve the API from there, e.g. under a prefix with
+// http.StripPrefix; Start still has to be called for the background workers.
+func (s *Server) Handler() http.Handler {
+	return s.handler
+}
+
+// Router returns the routes of the API without the configured middlewares,
+// for embedders wrapping it in middlewares of their own. Requests with a
+// user-scoped API token are still restricted to their user.
+func (s *Server) Router() http.Handler {
+	return s.router
+}
+
+// Start starts the background workers and serves the API on the configured
+// address, if any, and the gRPC ledger stream if configured, in the
+// background. It returns once the API address is listened on.
+func (s *Server) Start() error {
+	var ln net.Listener
+	if s.http != nil {
+		var err error
+		if ln, err = net.Listen("tcp", s.http.Addr); err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", s.http.Addr, err)
+		}
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	for _, task := range s.tasks {
+		s.wg.Add(1)
+		go func(task func(ctx context.Context)) {
+			defer s.wg.Done()
+			task(s.ctx)
+		}(task)
+	}
+
+	if s.grpc != nil {
+		go func() {
+			log.Printf("gRPC server starting on %s", s.grpc.Addr)
+			if err := s.grpc.ListenAndServeTLS(s.grpcCert, s.grpcKey); err != nil && err != http.ErrServerClosed {
+				log.Printf("gRPC server failed: %v", err)
+			}
+		}()
+	}
+
+	if ln != nil {
+		log.Printf("Server starting on %s", ln.Addr())
+		go func() {
+			if err := s.http.Serve(ln); err != nil && err != http.ErrServerClosed {
+				log.Printf("Server failed: %v", err)
+			}
+		}()
+	}
+	return nil
+}
+
+// Shutdown stops serving, letting in-flight requests finish, stops the
+// background workers and closes the database. If ctx ends first, Shutdown
+// returns its error without waiting for the rest.
+func (s *Server) Shutdown(ctx context.Context) error {
+	var err error
+	if s.http != nil {
+		err = s.http.Shutdown(ctx)
+	}
+	if s.grpc != nil {
+		if grpcErr := s.grpc.Shutdown(ctx); err == nil {
+			err = grpcErr
+		}
+	}
+
+	if s.cancel != nil {
+		s.cancel()
+	}
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if closeErr := s.database.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}